@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleListApprovals 当前用户能访问的trader的开仓审批（?status=pending|approved|failed|rejected|expired）
+func (s *Server) handleListApprovals(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", trader.ApprovalPending, trader.ApprovalApproved, trader.ApprovalFailed, trader.ApprovalRejected, trader.ApprovalExpired:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status必须是pending、approved、failed、rejected或expired"})
+		return
+	}
+	c.JSON(http.StatusOK, s.traderManager.ListApprovals(s.accessibleTraderIDs(c), status))
+}
+
+// handleApproveApproval 批准开仓审批并立即执行
+func (s *Server) handleApproveApproval(c *gin.Context) {
+	s.resolveApproval(c, true)
+}
+
+// handleRejectApproval 拒绝开仓审批
+func (s *Server) handleRejectApproval(c *gin.Context) {
+	s.resolveApproval(c, false)
+}
+
+// resolveApproval 批准或拒绝审批（无权访问所属trader时和审批不存在一样返回404）
+func (s *Server) resolveApproval(c *gin.Context, approve bool) {
+	id := c.Param("id")
+	t, err := s.traderManager.ApprovalTrader(id)
+	if err != nil || !s.canAccessTrader(c, t.GetID()) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "审批不存在"})
+		return
+	}
+
+	approval, err := t.ResolveApproval(id, approve, c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "approval": approval})
+		return
+	}
+	c.JSON(http.StatusOK, approval)
+}
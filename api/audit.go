@@ -0,0 +1,156 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"nofx/auth"
+	"nofx/logger"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAuditBody 审计记录保存的请求体上限（字节），超过时只保存截断的文本
+const maxAuditBody = 8 * 1024
+
+// sensitiveFieldHints 字段名包含这些词时在审计记录中隐藏其值
+var sensitiveFieldHints = []string{"key", "secret", "password", "token", "passphrase", "private", "code"}
+
+// auditWriter 记录响应状态，出错时保留响应体以提取错误信息
+type auditWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditWriter) Write(data []byte) (int, error) {
+	if w.Status() >= http.StatusBadRequest && w.body.Len() < maxAuditBody {
+		w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// auditMiddleware 记录所有修改类请求（谁、何时、从哪个IP、改了什么、结果），查询类请求不记录
+func (s *Server) auditMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			body, _ = io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		writer := &auditWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		entry := logger.AuditEntry{
+			Time:     time.Now(),
+			UserID:   c.GetString("user_id"),
+			Username: c.GetString("username"),
+			IP:       c.ClientIP(),
+			Action:   c.Request.Method + " " + c.FullPath(),
+			Target:   c.Param("id"),
+			Changes:  auditChanges(body),
+			Status:   writer.Status(),
+		}
+		if entry.Target == "" {
+			entry.Target = c.Param("name") // 模板名称
+		}
+		if key, ok := c.Get("api_key"); ok {
+			entry.APIKeyID = key.(*auth.APIKey).ID
+		}
+		if entry.Status >= http.StatusBadRequest {
+			var resp struct {
+				Error string `json:"error"`
+			}
+			if json.Unmarshal(writer.body.Bytes(), &resp) == nil {
+				entry.Error = resp.Error
+			}
+		}
+		if err := logger.RecordAudit(entry); err != nil {
+			log.Printf("⚠️  %v（%s %s by %s）", err, entry.Action, entry.Target, entry.Username)
+		}
+	}
+}
+
+// auditChanges 审计记录中保存的请求内容：JSON隐藏密钥类字段，非JSON按截断后的文本保存
+func auditChanges(body []byte) json.RawMessage {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	var v interface{}
+	if len(body) <= maxAuditBody && json.Unmarshal(body, &v) == nil {
+		data, _ := json.Marshal(redactSecrets(v))
+		return data
+	}
+	if len(body) > maxAuditBody {
+		body = body[:maxAuditBody]
+	}
+	data, _ := json.Marshal(string(body))
+	return data
+}
+
+// redactSecrets 把字段名像密钥的值替换为 ***
+func redactSecrets(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			if isSensitiveField(k) && item != nil && item != "" {
+				val[k] = "***"
+			} else {
+				val[k] = redactSecrets(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = redactSecrets(item)
+		}
+	}
+	return v
+}
+
+// isSensitiveField 字段名是否像密钥
+func isSensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	for _, hint := range sensitiveFieldHints {
+		if strings.Contains(name, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleAuditLog 查询审计记录（管理员，?user=&target=&action=&from=&to=&limit=&offset=，最新的在前）
+func (s *Server) handleAuditLog(c *gin.Context) {
+	recordFilter, err := parseRecordFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries, total, err := logger.QueryAudit(logger.AuditFilter{
+		RecordFilter: recordFilter,
+		Username:     c.Query("user"),
+		Target:       c.Query("target"),
+		Action:       c.Query("action"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if entries == nil {
+		entries = []logger.AuditEntry{}
+	}
+
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,408 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"nofx/auth"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthConfig API登录鉴权配置（为nil时为管理员模式，所有接口无需登录）
+type AuthConfig struct {
+	Users         *auth.UserStore
+	JWTSecret     []byte
+	AllowRegister bool // 是否开放注册（没有任何用户时始终允许注册第一个账号）
+}
+
+// credentialsRequest 登录/注册请求
+type credentialsRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	OTPCode  string `json:"otp_code"` // 已开启两步验证时登录必填
+}
+
+// createAPIKeyRequest 创建API Key请求
+type createAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes"` // 默认只读
+}
+
+// otpVerifyRequest 两步验证确认请求
+type otpVerifyRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// authMiddleware 校验登录（Authorization: Bearer <token>；脚本可用 X-API-Key）
+// allowQueryToken 只在 /ws 升级路由开启：浏览器WebSocket无法设置请求头，其他接口的URL会进入访问日志和浏览器历史，不接受 ?token=
+func (s *Server) authMiddleware(allowQueryToken bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.auth == nil {
+			// 管理员模式：不校验登录
+			c.Set("username", "admin")
+			c.Set("role", auth.RoleAdmin)
+			c.Next()
+			return
+		}
+
+		if rawKey := c.GetHeader("X-API-Key"); rawKey != "" {
+			key, err := s.auth.Users.AuthenticateAPIKey(rawKey)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+			user, err := s.auth.Users.GetByID(key.UserID)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+			c.Set("user_id", user.ID)
+			c.Set("username", user.Username)
+			c.Set("role", user.Role)
+			c.Set("api_key", key)
+			c.Next()
+			return
+		}
+
+		var token string
+		if header := c.GetHeader("Authorization"); strings.HasPrefix(header, "Bearer ") {
+			token = strings.TrimPrefix(header, "Bearer ")
+		} else if allowQueryToken {
+			token = c.Query("token")
+		}
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+			return
+		}
+
+		claims, err := auth.ParseToken(s.auth.JWTSecret, token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		// 每次请求都查询用户，角色调整立即生效
+		user, err := s.auth.Users.GetByID(claims.UserID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Set("user_id", user.ID)
+		c.Set("username", user.Username)
+		c.Set("role", user.Role)
+		c.Next()
+	}
+}
+
+// requireScope 通过API Key访问时要求具备指定权限（登录会话和管理员模式不受限制）
+func requireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if v, ok := c.Get("api_key"); ok {
+			if key := v.(*auth.APIKey); !key.HasScope(scope) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("API Key缺少 '%s' 权限", scope)})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// requireRole 要求当前用户达到指定角色（API Key按所属用户的角色计算）
+func requireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !auth.RoleAtLeast(c.GetString("role"), role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("需要 '%s' 及以上角色", role)})
+			return
+		}
+		c.Next()
+	}
+}
+
+// requireSession 仅允许登录会话访问（API Key不能管理账号安全设置和其他API Key）
+func requireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("api_key"); ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "该接口需要登录会话，不支持API Key"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// handleRegister 注册账号并返回token
+func (s *Server) handleRegister(c *gin.Context) {
+	if s.auth == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "管理员模式下无需注册"})
+		return
+	}
+	if !s.auth.AllowRegister && s.auth.Users.Count() > 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "注册已关闭"})
+		return
+	}
+
+	var req credentialsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "用户名和密码不能为空"})
+		return
+	}
+	if len(req.Password) < 8 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "密码至少8位"})
+		return
+	}
+
+	user, err := s.auth.Users.Create(req.Username, req.Password)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, auth.ErrUserExists) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("👤 新用户注册: %s", user.Username)
+	s.respondToken(c, http.StatusCreated, user)
+}
+
+// handleLogin 用户名密码登录
+func (s *Server) handleLogin(c *gin.Context) {
+	if s.auth == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "管理员模式下无需登录"})
+		return
+	}
+
+	var req credentialsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "用户名和密码不能为空"})
+		return
+	}
+
+	user, err := s.auth.Users.Authenticate(req.Username, req.Password)
+	if err != nil {
+		// 不区分用户不存在和密码错误
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户名或密码错误"})
+		return
+	}
+
+	if user.OTPEnabled {
+		if req.OTPCode == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "需要两步验证码", "otp_required": true})
+			return
+		}
+		if !auth.VerifyOTP(user.OTPSecret, req.OTPCode) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "两步验证码错误", "otp_required": true})
+			return
+		}
+	}
+
+	s.respondToken(c, http.StatusOK, user)
+}
+
+// handleOTPSetup 生成两步验证密钥（需调用 /api/auth/otp/verify 确认后才生效）
+func (s *Server) handleOTPSetup(c *gin.Context) {
+	if s.auth == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "管理员模式下不支持两步验证"})
+		return
+	}
+
+	var secret, qrURL string
+	_, err := s.auth.Users.Update(c.GetString("user_id"), func(u *auth.User) error {
+		if u.OTPEnabled {
+			return fmt.Errorf("两步验证已开启")
+		}
+		var err error
+		secret, qrURL, err = auth.GenerateOTPSecret(u.Username)
+		if err != nil {
+			return err
+		}
+		u.OTPPending = secret
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret": secret,
+		"qr_url": qrURL,
+	})
+}
+
+// handleOTPVerify 用验证器App生成的验证码确认开启两步验证
+func (s *Server) handleOTPVerify(c *gin.Context) {
+	if s.auth == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "管理员模式下不支持两步验证"})
+		return
+	}
+
+	var req otpVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "验证码不能为空"})
+		return
+	}
+
+	_, err := s.auth.Users.Update(c.GetString("user_id"), func(u *auth.User) error {
+		if u.OTPEnabled {
+			return fmt.Errorf("两步验证已开启")
+		}
+		if u.OTPPending == "" {
+			return fmt.Errorf("请先调用 /api/auth/otp/setup 生成密钥")
+		}
+		if !auth.VerifyOTP(u.OTPPending, req.Code) {
+			return fmt.Errorf("验证码错误")
+		}
+		u.OTPSecret = u.OTPPending
+		u.OTPPending = ""
+		u.OTPEnabled = true
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("🔐 用户 %s 已开启两步验证", c.GetString("username"))
+	c.JSON(http.StatusOK, gin.H{"otp_enabled": true})
+}
+
+// handleMe 当前登录用户
+func (s *Server) handleMe(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"user_id":    c.GetString("user_id"),
+		"username":   c.GetString("username"),
+		"role":       c.GetString("role"),
+		"admin_mode": s.auth == nil,
+	})
+}
+
+// respondToken 签发token并返回
+func (s *Server) respondToken(c *gin.Context, status int, user *auth.User) {
+	token, expiresAt, err := auth.GenerateToken(s.auth.JWTSecret, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(status, gin.H{
+		"token":      token,
+		"expires_at": expiresAt.Format(time.RFC3339),
+		"user": gin.H{
+			"id":       user.ID,
+			"username": user.Username,
+			"role":     user.Role,
+		},
+	})
+}
+
+// handleListAPIKeys 列出当前用户的API Key（不含明文）
+func (s *Server) handleListAPIKeys(c *gin.Context) {
+	if s.auth == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "管理员模式下不支持API Key"})
+		return
+	}
+	c.JSON(http.StatusOK, s.auth.Users.ListAPIKeys(c.GetString("user_id")))
+}
+
+// handleCreateAPIKey 创建API Key（明文只在此次响应中返回）
+func (s *Server) handleCreateAPIKey(c *gin.Context) {
+	if s.auth == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "管理员模式下不支持API Key"})
+		return
+	}
+
+	var req createAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name不能为空"})
+		return
+	}
+	if len(req.Scopes) == 0 {
+		req.Scopes = []string{auth.ScopeRead}
+	}
+	for _, scope := range req.Scopes {
+		if required := auth.ScopeRole(scope); !auth.RoleAtLeast(c.GetString("role"), required) {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("'%s' 权限需要 '%s' 及以上角色", scope, required)})
+			return
+		}
+	}
+
+	raw, key, err := s.auth.Users.CreateAPIKey(c.GetString("user_id"), req.Name, req.Scopes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("🔑 用户 %s 创建API Key: %s %v", c.GetString("username"), key.Name, key.Scopes)
+	c.JSON(http.StatusCreated, gin.H{
+		"key":     raw,
+		"api_key": key.Public(),
+	})
+}
+
+// handleDeleteAPIKey 删除API Key
+func (s *Server) handleDeleteAPIKey(c *gin.Context) {
+	if s.auth == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "管理员模式下不支持API Key"})
+		return
+	}
+	if err := s.auth.Users.DeleteAPIKey(c.GetString("user_id"), c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": true})
+}
+
+// setRoleRequest 修改角色请求
+type setRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// handleListUsers 列出所有用户（管理员）
+func (s *Server) handleListUsers(c *gin.Context) {
+	if s.auth == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "管理员模式下没有用户"})
+		return
+	}
+
+	users := s.auth.Users.List()
+	result := make([]gin.H, 0, len(users))
+	for _, u := range users {
+		result = append(result, gin.H{
+			"id":          u.ID,
+			"username":    u.Username,
+			"role":        u.Role,
+			"otp_enabled": u.OTPEnabled,
+			"created_at":  u.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// handleSetUserRole 修改用户角色（管理员）
+func (s *Server) handleSetUserRole(c *gin.Context) {
+	if s.auth == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "管理员模式下没有用户"})
+		return
+	}
+
+	var req setRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role不能为空"})
+		return
+	}
+
+	user, err := s.auth.Users.SetRole(c.Param("id"), req.Role)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, auth.ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("👤 %s 将用户 %s 的角色设为 %s", c.GetString("username"), user.Username, user.Role)
+	c.JSON(http.StatusOK, gin.H{"id": user.ID, "username": user.Username, "role": user.Role})
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"nofx/auth"
+	"nofx/config"
+	"nofx/logger"
+	"nofx/manager"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuthMiddlewareQueryToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	users, err := auth.NewUserStore(filepath.Join(t.TempDir(), "users.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := users.Create("alice", "password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte("test-secret")
+	token, _, err := auth.GenerateToken(secret, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{auth: &AuthConfig{Users: users, JWTSecret: secret}}
+	router := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/ws", s.authMiddleware(true), ok)
+	router.GET("/api/stream", s.authMiddleware(false), ok)
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		want   int
+	}{
+		{"ws query token", "/ws?token=" + token, "", http.StatusOK},
+		{"ws header", "/ws", "Bearer " + token, http.StatusOK},
+		{"ws no token", "/ws", "", http.StatusUnauthorized},
+		{"api query token rejected", "/api/stream?token=" + token, "", http.StatusUnauthorized},
+		{"api header", "/api/stream", "Bearer " + token, http.StatusOK},
+		{"api bad header", "/api/stream", "Bearer nope", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d (%s)", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+// newScopeTestServer 创建带一个模拟盘trader的登录模式服务器，返回管理员的只读和交易API Key
+func newScopeTestServer(t *testing.T) (s *Server, readKey, tradeKey string) {
+	t.Helper()
+	dir := t.TempDir()
+	logger.SetDatabasePath(filepath.Join(dir, "nofx.db"))
+	users, err := auth.NewUserStore(filepath.Join(dir, "users.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := users.Create("admin", "password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readKey, _, err = users.CreateAPIKey(admin.ID, "read", []string{auth.ScopeRead}); err != nil {
+		t.Fatal(err)
+	}
+	if tradeKey, _, err = users.CreateAPIKey(admin.ID, "trade", []string{auth.ScopeTrade}); err != nil {
+		t.Fatal(err)
+	}
+
+	tm := manager.NewTraderManager()
+	err = tm.AddTrader(config.TraderConfig{ID: "paper1", Name: "paper1", Exchange: "paper", AIModel: "deepseek", DeepSeekKey: "test",
+		InitialBalance: 1000, ScanIntervalMinutes: 3}, "", 10, 20, 60, config.LeverageConfig{BTCETHLeverage: 5, AltcoinLeverage: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = NewServer(tm, 0, &AuthConfig{Users: users, JWTSecret: []byte("test-secret")}, config.APILimits{RequestsPerMinute: -1, MaxBodyKB: 64})
+	return s, readKey, tradeKey
+}
+
+// TestAdminRoutesRequireTradeScope 管理员的只读API Key不能调用破坏性的管理接口
+func TestAdminRoutesRequireTradeScope(t *testing.T) {
+	s, readKey, _ := newScopeTestServer(t)
+	tests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPost, "/api/seasons/end", ""},
+		{http.MethodPut, "/api/traders/paper1/owner", `{"owner":"mallory"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-API-Key", readKey)
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, req)
+			if w.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want 403 (%s)", w.Code, w.Body.String())
+			}
+		})
+	}
+	if owner := s.traderManager.GetOwner("paper1"); owner != "" {
+		t.Errorf("owner changed by read-only key: %q", owner)
+	}
+}
+
+// TestUpdateOwnerWithTradeKey 交易权限的管理员API Key可以修改所属用户
+func TestUpdateOwnerWithTradeKey(t *testing.T) {
+	s, _, tradeKey := newScopeTestServer(t)
+	req := httptest.NewRequest(http.MethodPut, "/api/traders/paper1/owner", strings.NewReader(`{"owner":"admin"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", tradeKey)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (%s)", w.Code, w.Body.String())
+	}
+	if owner := s.traderManager.GetOwner("paper1"); owner != "admin" {
+		t.Errorf("owner = %q, want admin", owner)
+	}
+}
+
+// TestCORSAllowsAPIKeyHeader 浏览器带X-API-Key的跨域请求需要通过预检
+func TestCORSAllowsAPIKeyHeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(corsMiddleware())
+	req := httptest.NewRequest(http.MethodOptions, "/api/status", nil)
+	req.Header.Set("Origin", "https://dashboard.example.com")
+	req.Header.Set("Access-Control-Request-Headers", "x-api-key")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	allowed := strings.Split(w.Header().Get("Access-Control-Allow-Headers"), ",")
+	for _, h := range allowed {
+		if strings.EqualFold(strings.TrimSpace(h), "X-API-Key") {
+			return
+		}
+	}
+	t.Errorf("Access-Control-Allow-Headers = %q, want X-API-Key", w.Header().Get("Access-Control-Allow-Headers"))
+}
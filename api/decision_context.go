@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"nofx/logger"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// decisionContextResponse 决策记录及其完整上下文快照
+type decisionContextResponse struct {
+	*logger.DecisionRecord
+	Context json.RawMessage `json:"context"` // decision.ContextSnapshot
+}
+
+// handleDecisionContext 指定周期的决策记录和决策时的完整上下文（行情数据、订单簿、prompt素材等），用于审计决策依据
+func (s *Server) handleDecisionContext(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	cycle, err := strconv.Atoi(c.Param("cycle"))
+	if err != nil || cycle <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的周期编号"})
+		return
+	}
+
+	record, err := trader.GetDecisionLogger().DecisionContext(cycle)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("获取决策记录失败: %v", err),
+		})
+		return
+	}
+	if record == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("周期 #%d 不存在", cycle)})
+		return
+	}
+	if len(record.Context) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("周期 #%d 没有保存上下文快照", cycle)})
+		return
+	}
+
+	c.JSON(http.StatusOK, decisionContextResponse{DecisionRecord: record, Context: record.Context})
+}
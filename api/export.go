@@ -0,0 +1,230 @@
+package api
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"nofx/backtest"
+	"nofx/exchange"
+	"nofx/logger"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// journalHeader 交易日志导出的列
+var journalHeader = []string{
+	"symbol", "side", "status", "quantity", "leverage",
+	"open_time", "open_price", "close_time", "close_price",
+	"entry_fee", "exit_fee", "funding_paid", "gross_pnl", "net_pnl",
+	"confidence", "reasoning", "close_reasoning",
+}
+
+// journalTextColumns 文本列（导出时防止被表格软件当作公式），xlsx中其余列写成数值单元格
+var journalTextColumns = map[int]bool{0: true, 1: true, 2: true, 5: true, 7: true, 15: true, 16: true}
+
+// handleExportTrades 导出交易日志（?trader_id=xxx&format=csv|xlsx，from/to同/api/decisions）
+// 优先使用成交时记录的手续费和资金费，没有记录的按taker费率和币安历史资金费率估算
+func (s *Server) handleExportTrades(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "xlsx" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format必须是csv或xlsx"})
+		return
+	}
+
+	var filter logger.RecordFilter
+	if filter.From, err = parseTimeParam(c.Query("from")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from格式无效"})
+		return
+	}
+	if filter.To, err = parseTimeParam(c.Query("to")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to格式无效"})
+		return
+	}
+
+	var funding logger.FundingHistoryFunc
+	if !exchange.IsSpot(trader.GetExchange()) {
+		funding = binanceFundingHistory
+	}
+	entries, err := trader.GetDecisionLogger().TradeJournal(filter, funding)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("生成交易日志失败: %v", err),
+		})
+		return
+	}
+
+	rows := make([][]string, 0, len(entries)+1)
+	rows = append(rows, journalHeader)
+	for _, e := range entries {
+		rows = append(rows, journalRow(e))
+	}
+
+	filename := fmt.Sprintf("trades_%s_%s.%s", traderID, time.Now().Format("20060102"), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	if format == "xlsx" {
+		c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		err = writeXLSX(c.Writer, rows, journalTextColumns)
+	} else {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		err = writeCSV(c.Writer, rows, journalTextColumns)
+	}
+	if err != nil {
+		log.Printf("⚠️  导出交易日志失败: %v", err)
+	}
+}
+
+// binanceFundingHistory 从币安获取历史资金费率
+func binanceFundingHistory(symbol string, from, to time.Time) ([]logger.FundingRate, error) {
+	points, err := backtest.FetchFundingHistory(symbol, from, to)
+	if err != nil {
+		return nil, err
+	}
+	rates := make([]logger.FundingRate, len(points))
+	for i, p := range points {
+		rates[i] = logger.FundingRate{Time: time.UnixMilli(p.Time), Rate: p.Rate}
+	}
+	return rates, nil
+}
+
+// journalRow 一笔交易对应的导出行（未平仓的交易平仓字段留空）
+func journalRow(e logger.JournalEntry) []string {
+	num := func(v float64) string { return formatFinite(v, -1) }
+	money := func(v float64) string { return formatFinite(v, 4) }
+
+	closeTime, closePrice, grossPnL := "", "", ""
+	if e.Status == "closed" {
+		closeTime = e.CloseTime.Format(time.RFC3339)
+		closePrice = num(e.ClosePrice)
+		grossPnL = money(e.GrossPnL)
+	}
+	return []string{
+		e.Symbol, e.Side, e.Status, num(e.Quantity), strconv.Itoa(e.Leverage),
+		e.OpenTime.Format(time.RFC3339), num(e.OpenPrice), closeTime, closePrice,
+		money(e.EntryFee), money(e.ExitFee), money(e.FundingPaid), grossPnL, money(e.NetPnL),
+		strconv.Itoa(e.Confidence), e.Reasoning, e.CloseReasoning,
+	}
+}
+
+// formatFinite 格式化数值（NaN和±Inf返回空字符串，导出为空单元格）
+func formatFinite(v float64, prec int) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', prec, 64)
+}
+
+// escapeFormula 以=、+、-、@、制表符或回车开头的文本前加单引号，防止在Excel等软件中被当作公式执行（如AI给出的理由或币种名）
+func escapeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// writeCSV 写出CSV（带UTF-8 BOM，Excel直接打开中文不乱码；textColumns中的单元格转义公式前缀）
+func writeCSV(w io.Writer, rows [][]string, textColumns map[int]bool) error {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+	escaped := make([][]string, len(rows))
+	for r, row := range rows {
+		escaped[r] = row
+		if r == 0 {
+			continue
+		}
+		escaped[r] = make([]string, len(row))
+		for c, cell := range row {
+			if textColumns[c] {
+				cell = escapeFormula(cell)
+			}
+			escaped[r][c] = cell
+		}
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(escaped); err != nil {
+		return fmt.Errorf("写入CSV失败: %w", err)
+	}
+	return nil
+}
+
+// xlsxFiles 最小化xlsx工作簿的固定部分（单个工作表，字符串使用内联字符串，不需要sharedStrings）
+var xlsxFiles = []struct{ name, body string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Trades" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+// writeXLSX 写出xlsx（第一行为表头，textColumns以外的列写成数值单元格，方便在Excel里直接求和；
+// 不是有限数值的单元格写为空单元格，文本单元格转义公式前缀）
+func writeXLSX(w io.Writer, rows [][]string, textColumns map[int]bool) error {
+	zw := zip.NewWriter(w)
+	for _, f := range xlsxFiles {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return fmt.Errorf("写入xlsx失败: %w", err)
+		}
+		if _, err := io.WriteString(fw, f.body); err != nil {
+			return fmt.Errorf("写入xlsx失败: %w", err)
+		}
+	}
+
+	var sheet strings.Builder
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for r, row := range rows {
+		fmt.Fprintf(&sheet, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			if r > 0 && !textColumns[c] {
+				if v, err := strconv.ParseFloat(cell, 64); err == nil && !math.IsNaN(v) && !math.IsInf(v, 0) {
+					fmt.Fprintf(&sheet, `<c><v>%s</v></c>`, cell)
+				} else {
+					sheet.WriteString(`<c/>`)
+				}
+				continue
+			}
+			if r > 0 {
+				cell = escapeFormula(cell)
+			}
+			sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+			if err := xml.EscapeText(&sheet, []byte(cell)); err != nil {
+				return fmt.Errorf("写入xlsx失败: %w", err)
+			}
+			sheet.WriteString(`</t></is></c>`)
+		}
+		sheet.WriteString(`</row>`)
+	}
+	sheet.WriteString(`</sheetData></worksheet>`)
+
+	fw, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return fmt.Errorf("写入xlsx失败: %w", err)
+	}
+	if _, err := io.WriteString(fw, sheet.String()); err != nil {
+		return fmt.Errorf("写入xlsx失败: %w", err)
+	}
+	return zw.Close()
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestEscapeFormula(t *testing.T) {
+	tests := map[string]string{
+		"":                       "",
+		"BTCUSDT":                "BTCUSDT",
+		"=HYPERLINK(\"x\")":      "'=HYPERLINK(\"x\")",
+		"+1":                     "'+1",
+		"-cmd|' /C calc'!A0":     "'-cmd|' /C calc'!A0",
+		"@SUM(A1:A2)":            "'@SUM(A1:A2)",
+		"\t=1":                   "'\t=1",
+		"趋势向上 =1":                "趋势向上 =1",
+		"reason with - and = in": "reason with - and = in",
+	}
+	for in, want := range tests {
+		if got := escapeFormula(in); got != want {
+			t.Errorf("escapeFormula(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFormatFinite(t *testing.T) {
+	for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if got := formatFinite(v, 4); got != "" {
+			t.Errorf("formatFinite(%v) = %q, want empty", v, got)
+		}
+	}
+	if got := formatFinite(-1.5, 4); got != "-1.5000" {
+		t.Errorf("formatFinite(-1.5) = %q", got)
+	}
+}
+
+var exportRows = [][]string{
+	{"symbol", "net_pnl", "reasoning"},
+	{"=EVIL()", "-12.5", "@SUM(A1)"},
+	{"BTCUSDT", "NaN", "ok"},
+	{"ETHUSDT", "+Inf", "-看空"},
+}
+
+var exportText = map[int]bool{0: true, 2: true}
+
+func TestWriteCSVEscapesTextOnly(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeCSV(&buf, exportRows, exportText); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), "\ufeff"))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if records[1][0] != "'=EVIL()" || records[1][2] != "'@SUM(A1)" || records[3][2] != "'-看空" {
+		t.Errorf("text cells not escaped: %v", records)
+	}
+	if records[1][1] != "-12.5" {
+		t.Errorf("numeric cell changed: %q", records[1][1])
+	}
+	if exportRows[1][0] != "=EVIL()" {
+		t.Errorf("writeCSV modified its input")
+	}
+}
+
+func TestWriteXLSXValid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeXLSX(&buf, exportRows, exportText); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sheet string
+	for _, f := range zr.File {
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		sheet = string(data)
+	}
+	if err := xml.Unmarshal([]byte(sheet), new(struct{})); err != nil {
+		t.Fatalf("sheet is not valid XML: %v", err)
+	}
+	for _, bad := range []string{"<v>NaN</v>", "<v>+Inf</v>", ">=EVIL()<", ">@SUM(A1)<"} {
+		if strings.Contains(sheet, bad) {
+			t.Errorf("sheet contains %s", bad)
+		}
+	}
+	if !strings.Contains(sheet, "<v>-12.5</v>") || !strings.Contains(sheet, "&#39;=EVIL()") {
+		t.Errorf("unexpected sheet: %s", sheet)
+	}
+}
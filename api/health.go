@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"nofx/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetDraining 设置排空状态：排空期间 /readyz 返回503，编排系统不再把流量转发到本实例（其余接口照常服务）
+func (s *Server) SetDraining(draining bool) {
+	if s.draining.Swap(draining) != draining {
+		if draining {
+			log.Printf("🚰 进入排空状态，/readyz 返回未就绪")
+		} else {
+			log.Printf("✓ 退出排空状态")
+		}
+	}
+}
+
+// handleLiveness 存活探针：进程能响应HTTP即为存活
+func (s *Server) handleLiveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// handleReadiness 就绪探针：未排空、数据库可用、至少一个trader的交易所可访问时就绪，否则返回503和原因
+func (s *Server) handleReadiness(c *gin.Context) {
+	if s.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining"})
+		return
+	}
+
+	checks := gin.H{}
+	ready := true
+	if err := logger.PingDatabase(); err != nil {
+		checks["database"] = err.Error()
+		ready = false
+	} else {
+		checks["database"] = "ok"
+	}
+
+	exchanges := s.traderManager.CheckExchanges()
+	reachable := 0
+	for _, e := range exchanges {
+		if e.Reachable {
+			reachable++
+		}
+	}
+	checks["exchanges"] = exchanges
+	if reachable == 0 {
+		ready = false
+	}
+
+	status, code := "ready", http.StatusOK
+	if !ready {
+		status, code = "not_ready", http.StatusServiceUnavailable
+	}
+	c.JSON(code, gin.H{"status": status, "checks": checks})
+}
+
+// handleDrain 手动进入排空状态（POST）或恢复（DELETE），用于滚动发布前摘除流量
+func (s *Server) handleDrain(c *gin.Context) {
+	s.SetDraining(c.Request.Method == http.MethodPost)
+	c.JSON(http.StatusOK, gin.H{"draining": s.draining.Load()})
+}
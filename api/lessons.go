@@ -0,0 +1,45 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"nofx/logger"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleLessons 交易复盘（?symbol=过滤币种，from/to按平仓时间，limit/offset同/api/decisions，按平仓时间从新到旧，总条数在X-Total-Count中返回）
+func (s *Server) handleLessons(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter, err := parseRecordFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lessons, total, err := trader.GetDecisionLogger().QueryLessons(filter, c.Query("symbol"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("获取交易复盘失败: %v", err),
+		})
+		return
+	}
+	if lessons == nil {
+		lessons = []logger.Lesson{}
+	}
+
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.JSON(http.StatusOK, lessons)
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"nofx/logger"
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleManualOpen 操作员手动开仓（决策记录来源为manual）
+func (s *Server) handleManualOpen(c *gin.Context) {
+	s.manualOrder(c, (*trader.AutoTrader).ManualOpen)
+}
+
+// handleManualClose 操作员手动平仓（决策记录来源为manual）
+func (s *Server) handleManualClose(c *gin.Context) {
+	s.manualOrder(c, (*trader.AutoTrader).ManualClose)
+}
+
+// manualOrder 解析手动下单请求并执行，返回执行结果（下单失败时同时返回执行记录）
+func (s *Server) manualOrder(c *gin.Context, execute func(*trader.AutoTrader, trader.ManualOrder, string) (*logger.DecisionAction, error)) {
+	t, err := s.traderManager.GetTrader(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	var req trader.ManualOrder
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	action, err := execute(t, req, c.GetString("username"))
+	if err != nil {
+		if action == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "action": action})
+		return
+	}
+	c.JSON(http.StatusOK, action)
+}
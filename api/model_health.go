@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"nofx/mcp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// traderModelRoute 一个trader的AI模型链
+type traderModelRoute struct {
+	TraderID string           `json:"trader_id"`
+	Name     string           `json:"name"`
+	Models   []mcp.RouteEntry `json:"models"` // 主模型在前，之后按切换顺序排列备用模型
+}
+
+// modelHealthResponse AI提供商健康状态和各trader的模型链
+type modelHealthResponse struct {
+	Providers []mcp.ProviderHealth `json:"providers"`
+	Traders   []traderModelRoute   `json:"traders"`
+}
+
+// handleModelHealth 各AI提供商的健康状态（熔断状态、错误率、延迟）和当前用户可见trader的模型链
+// 非管理员只能看到自己的trader使用的提供商
+func (s *Server) handleModelHealth(c *gin.Context) {
+	resp := modelHealthResponse{Providers: []mcp.ProviderHealth{}, Traders: []traderModelRoute{}}
+	used := make(map[string]bool) // 提供商 + 地址
+	for _, id := range s.accessibleTraderIDs(c) {
+		t, err := s.traderManager.GetTrader(id)
+		if err != nil {
+			continue
+		}
+		route := t.ModelRoute()
+		for _, m := range route {
+			used[string(m.Provider)+" "+m.BaseURL] = true
+		}
+		resp.Traders = append(resp.Traders, traderModelRoute{TraderID: id, Name: t.GetName(), Models: route})
+	}
+
+	for _, h := range mcp.Health() {
+		if isAdmin(c) || used[string(h.Provider)+" "+h.BaseURL] {
+			resp.Providers = append(resp.Providers, h)
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"nofx/news"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleNews 近期新闻（最新的在前）、宏观事件日历和当前是否处于事件暂停开仓时段
+func (s *Server) handleNews(c *gin.Context) {
+	feed := news.Active()
+	if feed == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "新闻源未启动"})
+		return
+	}
+	blackout, _ := feed.Blackout(time.Now())
+	c.JSON(http.StatusOK, gin.H{
+		"items":    feed.Recent(24*time.Hour, 0),
+		"events":   feed.Events(),
+		"blackout": blackout,
+	})
+}
+
+// pushNewsRequest 推送新闻和宏观事件（webhook）
+type pushNewsRequest struct {
+	Items  []news.Item  `json:"items"`
+	Events []news.Event `json:"events"`
+}
+
+// handlePushNews 推送新闻和宏观事件（外部新闻服务的webhook入口，重复的新闻和事件会被忽略）
+func (s *Server) handlePushNews(c *gin.Context) {
+	feed := news.Active()
+	if feed == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "新闻源未启动"})
+		return
+	}
+
+	var req pushNewsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Items) == 0 && len(req.Events) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "items和events不能同时为空"})
+		return
+	}
+	for i := range req.Items {
+		if err := req.Items[i].Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("items[%d]: %v", i, err)})
+			return
+		}
+	}
+	for i, e := range req.Events {
+		if err := e.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("events[%d]: %v", i, err)})
+			return
+		}
+	}
+
+	added := feed.Ingest(req.Items...)
+	feed.AddEvents(req.Events...)
+	log.Printf("📰 收到推送: %d 条新闻（新增 %d 条）, %d 个事件", len(req.Items), added, len(req.Events))
+	c.JSON(http.StatusOK, gin.H{"added": added, "events": len(feed.Events())})
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPISpec 接口的OpenAPI 3描述（新增或修改接口时同步更新api/openapi.json）
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// swaggerUIPage Swagger UI页面（从CDN加载，读取 /api/openapi.json）
+const swaggerUIPage = `<!doctype html>
+<html><head><meta charset="utf-8"/><title>NOFX API</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css"/></head>
+<body><div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+<script>SwaggerUIBundle({url: "/api/openapi.json", dom_id: "#swagger-ui", persistAuthorization: true});</script>
+</body></html>`
+
+// handleOpenAPI 返回OpenAPI描述
+func (s *Server) handleOpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", openAPISpec)
+}
+
+// handleAPIDocs Swagger UI
+func (s *Server) handleAPIDocs(c *gin.Context) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.String(http.StatusOK, swaggerUIPage)
+}
+
+// checkOpenAPICoverage 启动时检查已注册但未写进OpenAPI描述的接口（只打印警告）
+func (s *Server) checkOpenAPICoverage() {
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		log.Printf("⚠️  解析OpenAPI描述失败: %v", err)
+		return
+	}
+
+	var missing []string
+	for _, r := range s.router.Routes() {
+		if !strings.HasPrefix(r.Path, "/api/") && r.Path != "/ws" && !strings.HasPrefix(r.Path, "/health") && r.Path != "/readyz" {
+			continue
+		}
+		// /health 接受任意方法，只描述GET
+		if r.Path == "/api/docs" || (r.Path == "/health" && r.Method != http.MethodGet) {
+			continue
+		}
+		// gin的 :id 对应OpenAPI的 {id}
+		segments := strings.Split(r.Path, "/")
+		for i, seg := range segments {
+			if strings.HasPrefix(seg, ":") {
+				segments[i] = "{" + seg[1:] + "}"
+			}
+		}
+		path := strings.Join(segments, "/")
+		if _, ok := spec.Paths[path][strings.ToLower(r.Method)]; !ok {
+			missing = append(missing, r.Method+" "+r.Path)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		log.Printf("⚠️  以下接口没有写进 api/openapi.json: %v", missing)
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"nofx/auth"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// isAdmin 当前请求是否为管理员（管理员模式下始终为管理员）
+func isAdmin(c *gin.Context) bool {
+	return auth.RoleAtLeast(c.GetString("role"), auth.RoleAdmin)
+}
+
+// canAccessTrader 当前用户能否查看和操作该trader
+func (s *Server) canAccessTrader(c *gin.Context, traderID string) bool {
+	return s.traderManager.CanAccess(traderID, c.GetString("username"), isAdmin(c))
+}
+
+// accessibleTraderIDs 当前用户能访问的trader ID列表
+func (s *Server) accessibleTraderIDs(c *gin.Context) []string {
+	return s.traderManager.AccessibleTraderIDs(c.GetString("username"), isAdmin(c))
+}
+
+// traderAccessMiddleware 校验路径 /api/traders/:id 和参数 ?trader_id= 指定的trader属于当前用户或共享
+// 无权访问时和trader不存在一样返回404，不暴露其他用户的trader
+func (s *Server) traderAccessMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ids []string
+		if strings.HasPrefix(c.FullPath(), "/api/traders/:id") {
+			ids = append(ids, c.Param("id"))
+		}
+		if id := c.Query("trader_id"); id != "" {
+			ids = append(ids, id)
+		}
+		for _, id := range ids {
+			if !s.canAccessTrader(c, id) {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("trader ID '%s' 不存在", id)})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// handleGetOwner 查询trader的所属用户
+func (s *Server) handleGetOwner(c *gin.Context) {
+	id := c.Param("id")
+	c.JSON(http.StatusOK, gin.H{"trader_id": id, "owner": s.traderManager.GetOwner(id)})
+}
+
+// handleUpdateOwner 修改trader的所属用户（管理员，owner为空表示所有用户共享，立即生效并持久化）
+func (s *Server) handleUpdateOwner(c *gin.Context) {
+	id := c.Param("id")
+	var req struct {
+		Owner string `json:"owner"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误"})
+		return
+	}
+	req.Owner = strings.TrimSpace(req.Owner)
+
+	if req.Owner != "" && s.auth != nil && !s.userExists(req.Owner) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("用户 '%s' 不存在", req.Owner)})
+		return
+	}
+	if err := s.traderManager.SetOwner(id, req.Owner); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"trader_id": id, "owner": req.Owner})
+}
+
+// userExists 用户名是否存在
+func (s *Server) userExists(username string) bool {
+	for _, u := range s.auth.Users.List() {
+		if u.Username == username {
+			return true
+		}
+	}
+	return false
+}
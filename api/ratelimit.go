@@ -0,0 +1,77 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"nofx/auth"
+	"nofx/config"
+	"nofx/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitMiddleware 按客户端IP限流（健康检查探针除外），limits.RequestsPerMinute<0 时不限流
+func rateLimitMiddleware(limits config.APILimits) gin.HandlerFunc {
+	if limits.RequestsPerMinute < 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	limiter := ratelimit.NewKeyedLimiter(limits.RequestsPerMinute, limits.Burst)
+	return func(c *gin.Context) {
+		switch c.Request.URL.Path {
+		case "/health", "/healthz", "/readyz":
+			c.Next()
+			return
+		}
+		if !allowRequest(c, limiter, "ip:"+c.ClientIP()) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// tokenRateLimitMiddleware 按登录用户/API Key限流（放在authMiddleware之后；管理员模式没有用户，只按IP限流）
+func tokenRateLimitMiddleware(limits config.APILimits) gin.HandlerFunc {
+	if limits.RequestsPerMinute < 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	limiter := ratelimit.NewKeyedLimiter(limits.RequestsPerMinute, limits.Burst)
+	return func(c *gin.Context) {
+		var key string
+		if apiKey, ok := c.Get("api_key"); ok {
+			key = "key:" + apiKey.(*auth.APIKey).ID
+		} else if userID := c.GetString("user_id"); userID != "" {
+			key = "user:" + userID
+		}
+		if key != "" && !allowRequest(c, limiter, key) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// allowRequest 消耗key的一个令牌，被限流时返回429和Retry-After
+func allowRequest(c *gin.Context, limiter *ratelimit.KeyedLimiter, key string) bool {
+	ok, retryAfter := limiter.Allow(key)
+	if ok {
+		return true
+	}
+	c.Header("Retry-After", fmt.Sprintf("%d", int(math.Ceil(retryAfter.Seconds()))))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "请求过于频繁，请稍后再试"})
+	return false
+}
+
+// bodyLimitMiddleware 限制请求体大小（超过maxBodyKB直接返回413，未声明长度的请求读取超限时解析失败）
+func bodyLimitMiddleware(maxBodyKB int) gin.HandlerFunc {
+	maxBytes := int64(maxBodyKB) * 1024
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > maxBytes {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("请求体过大（上限 %dKB）", maxBodyKB)})
+			return
+		}
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http"
+	"nofx/logger"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleRatings 各AI模型的ELO评分（按评分从高到低）
+func (s *Server) handleRatings(c *gin.Context) {
+	ratings, err := logger.LoadModelRatings()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, ratings)
+}
+
+// handleRatingHistory 每个评分周期的评分变化（?model=&from=&to=&limit=&offset=，最新的在前）
+func (s *Server) handleRatingHistory(c *gin.Context) {
+	recordFilter, err := parseRecordFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	changes, total, err := logger.QueryRatingHistory(logger.RatingFilter{RecordFilter: recordFilter, Model: c.Query("model")})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.JSON(http.StatusOK, changes)
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"nofx/logger"
+	"nofx/market"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleRegime 当前市场状态（BTC的ATR和EMA斜率）和恐惧贪婪指数（获取失败的一项为null）
+func (s *Server) handleRegime(c *gin.Context) {
+	regime, regimeErr := market.GetRegime()
+	fearGreed, fearGreedErr := market.GetFearGreed()
+	if regimeErr != nil && fearGreedErr != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": fmt.Sprintf("%v; %v", regimeErr, fearGreedErr)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"regime": regime, "fear_greed": fearGreed})
+}
+
+// handleModelRegimeStats 当前用户能访问的所有trader按AI模型和开仓时的市场状态汇总的已平仓交易结果
+func (s *Server) handleModelRegimeStats(c *gin.Context) {
+	var traderIDs []string // 管理员统计所有trader（包括已删除trader的历史交易）
+	if !isAdmin(c) {
+		traderIDs = s.accessibleTraderIDs(c)
+	}
+	stats, err := logger.ModelRegimeStats(traderIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if stats == nil {
+		stats = []logger.RegimeStats{}
+	}
+	c.JSON(http.StatusOK, stats)
+}
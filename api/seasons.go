@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"nofx/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleSeasons 进行中的赛季和已结束赛季的最终排行榜（最新的在前）
+func (s *Server) handleSeasons(c *gin.Context) {
+	current, _, err := s.traderManager.CurrentSeason()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	seasons, err := logger.LoadSeasons()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range seasons {
+		seasons[i].Leaderboard = s.visibleLeaderboard(c, seasons[i].Leaderboard)
+	}
+	c.JSON(http.StatusOK, gin.H{"current": current, "seasons": seasons})
+}
+
+// handleEndSeason 提前结算进行中的赛季（平仓、归档并重置所有trader，在后台执行）
+func (s *Server) handleEndSeason(c *gin.Context) {
+	current, ok, err := s.traderManager.CurrentSeason()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有进行中的赛季"})
+		return
+	}
+
+	go func() {
+		if _, err := s.traderManager.EndSeason(); err != nil {
+			log.Printf("❌ 赛季结算失败: %v", err)
+		}
+	}()
+	c.JSON(http.StatusAccepted, gin.H{"message": "赛季结算已开始", "season": current})
+}
@@ -2,37 +2,63 @@ package api
 
 import (
     "fmt"
-    "log"
     "net/http"
+    "nofx/auth"
+    "nofx/config"
+    "nofx/decision"
+    "nofx/exchange"
+    "nofx/logger"
     "nofx/manager"
+    "nofx/pool"
+    "nofx/schedule"
+    "nofx/trader"
     "os"
     "path/filepath"
+    "strconv"
     "strings"
+    "sync/atomic"
+    "time"
 
     "github.com/gin-gonic/gin"
 )
 
+var log = logger.Module("api")
+
 // Server HTTP API服务器
 type Server struct {
 	router        *gin.Engine
 	traderManager *manager.TraderManager
 	port          int
+	auth          *AuthConfig     // 为nil时为管理员模式
+	draining      atomic.Bool     // 排空中（/readyz 返回未就绪）
+	tokenLimit    gin.HandlerFunc // 按登录用户/API Key限流
 }
 
-// NewServer 创建API服务器
-func NewServer(traderManager *manager.TraderManager, port int) *Server {
+// NewServer 创建API服务器（authCfg为nil时为管理员模式，接口无需登录）
+func NewServer(traderManager *manager.TraderManager, port int, authCfg *AuthConfig, limits config.APILimits) *Server {
 	// 设置为Release模式（减少日志输出）
 	gin.SetMode(gin.ReleaseMode)
 
 	router := gin.Default()
 
+	// 只信任反向代理传来的X-Forwarded-For，避免客户端伪造IP绕过限流
+	if err := router.SetTrustedProxies(limits.TrustedProxies); err != nil {
+		log.Printf("⚠️  trusted_proxies 配置无效，不信任任何代理: %v", err)
+		_ = router.SetTrustedProxies(nil)
+	}
+
 	// 启用CORS
 	router.Use(corsMiddleware())
 
+	// 按IP限流和请求体大小限制
+	router.Use(rateLimitMiddleware(limits), bodyLimitMiddleware(limits.MaxBodyKB))
+
 	s := &Server{
 		router:        router,
 		traderManager: traderManager,
 		port:          port,
+		auth:          authCfg,
+		tokenLimit:    tokenRateLimitMiddleware(limits),
 	}
 
     // 设置路由
@@ -41,6 +67,8 @@ func NewServer(traderManager *manager.TraderManager, port int) *Server {
     // 托管前端静态文件（如果存在）
     s.setupFrontend()
 
+	s.checkOpenAPICoverage()
+
     return s
 }
 
@@ -49,7 +77,8 @@ func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Total-Count")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusOK)
@@ -65,11 +94,103 @@ func (s *Server) setupRoutes() {
 	// 健康检查
 	s.router.Any("/health", s.handleHealth)
 
-	// API路由组
-	api := s.router.Group("/api")
+	// 容器编排探针：存活（/healthz）和就绪（/readyz）
+	s.router.GET("/healthz", s.handleLiveness)
+	s.router.GET("/readyz", s.handleReadiness)
+
+	// 接口文档（无需token）
+	s.router.GET("/api/openapi.json", s.handleOpenAPI)
+	s.router.GET("/api/docs", s.handleAPIDocs)
+
+	// 登录注册（无需token）
+	s.router.POST("/api/auth/register", s.handleRegister)
+	s.router.POST("/api/auth/login", s.handleLogin)
+
+	// 实时推送（WebSocket，?trader_id=xxx 可选）
+	s.router.GET("/ws", s.authMiddleware(true), s.tokenLimit, s.traderAccessMiddleware(), s.handleWebSocket)
+
+	// API路由组（需要登录，管理员模式除外）
+	api := s.router.Group("/api", s.authMiddleware(false), s.tokenLimit, requireScope(auth.ScopeRead), s.auditMiddleware(), s.traderAccessMiddleware())
 	{
+		// 当前用户
+		api.GET("/auth/me", s.handleMe)
+
+		// 两步验证（TOTP，仅登录会话）
+		api.POST("/auth/otp/setup", requireSession(), s.handleOTPSetup)
+		api.POST("/auth/otp/verify", requireSession(), s.handleOTPVerify)
+
+		// API Key管理（仅登录会话）
+		api.GET("/api-keys", requireSession(), s.handleListAPIKeys)
+		api.POST("/api-keys", requireSession(), s.handleCreateAPIKey)
+		api.DELETE("/api-keys/:id", requireSession(), s.handleDeleteAPIKey)
+
+		// 用户和角色管理（管理员）
+		api.GET("/users", requireSession(), requireRole(auth.RoleAdmin), s.handleListUsers)
+		api.PUT("/users/:id/role", requireSession(), requireRole(auth.RoleAdmin), s.handleSetUserRole)
+
+		// 审计记录（管理员）
+		api.GET("/audit", requireRole(auth.RoleAdmin), s.handleAuditLog)
+
+		// 排空（管理员，滚动发布前摘除流量）
+		api.POST("/drain", requireRole(auth.RoleAdmin), requireScope(auth.ScopeTrade), s.handleDrain)
+		api.DELETE("/drain", requireRole(auth.RoleAdmin), requireScope(auth.ScopeTrade), s.handleDrain)
+
+		// Trader启停（操作员及以上，API Key还需要trade权限）
+		api.POST("/traders/:id/start", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handleStartTrader)
+		api.POST("/traders/:id/stop", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handleStopTrader)
+		api.POST("/traders/:id/panic", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handlePanicTrader)
+		api.POST("/traders/:id/unlock", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handleUnlockTrader)
+		api.POST("/traders/:id/decide", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handleTriggerCycle)
+		api.POST("/traders/:id/open-position", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handleManualOpen)
+		api.POST("/traders/:id/close-position", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handleManualClose)
+		api.POST("/panic-all", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handlePanicAll)
+		api.PUT("/traders/:id", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handleUpdateTrader)
+		api.DELETE("/traders/:id", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handleDeleteTrader)
+		api.POST("/traders/:id/clone", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handleCloneTrader)
+		api.GET("/traders/:id/risk", s.handleGetRiskLimits)
+		api.PUT("/traders/:id/risk", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handleUpdateRiskLimits)
+		api.GET("/traders/:id/strategy", s.handleGetStrategy)
+		api.PUT("/traders/:id/strategy", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handleUpdateStrategy)
+		api.GET("/traders/:id/symbols", s.handleGetSymbolFilter)
+		api.PUT("/traders/:id/symbols", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handleUpdateSymbolFilter)
+		api.GET("/traders/:id/schedule", s.handleGetSchedule)
+		api.PUT("/traders/:id/schedule", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handleUpdateSchedule)
+		api.GET("/traders/:id/exchange", s.handleGetExchangeSettings)
+		api.PUT("/traders/:id/exchange", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handleUpdateExchangeSettings)
+		api.GET("/traders/:id/dry-run", s.handleGetDryRun)
+		api.PUT("/traders/:id/dry-run", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handleUpdateDryRun)
+		api.GET("/traders/:id/prompt", s.handleGetPrompt)
+		api.PUT("/traders/:id/prompt", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handleUpdatePrompt)
+		api.GET("/traders/:id/ai-usage", s.handleAIUsage)
+		api.GET("/traders/:id/shadow", s.handleShadowComparison)
+		api.GET("/traders/:id/owner", s.handleGetOwner)
+		api.PUT("/traders/:id/owner", requireRole(auth.RoleAdmin), requireScope(auth.ScopeTrade), s.handleUpdateOwner)
+
+		// 开仓人工审批
+		api.GET("/approvals", s.handleListApprovals)
+		api.POST("/approvals/:id/approve", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handleApproveApproval)
+		api.POST("/approvals/:id/reject", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handleRejectApproval)
+
+		// trader模板（模型+交易所+prompt+风控预设）
+		api.GET("/templates", s.handleListTemplates)
+		api.POST("/templates", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handleSaveTemplate)
+		api.DELETE("/templates/:name", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handleDeleteTemplate)
+		api.POST("/templates/:name/traders", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handleCreateFromTemplate)
+
 		// 竞赛总览
 		api.GET("/competition", s.handleCompetition)
+		api.GET("/leaderboard", s.handleLeaderboard)
+		api.GET("/leaderboard/history", s.handleLeaderboardHistory)
+		api.GET("/seasons", s.handleSeasons)
+		api.POST("/seasons/end", requireRole(auth.RoleAdmin), requireScope(auth.ScopeTrade), s.handleEndSeason)
+		api.GET("/ratings", s.handleRatings)
+		api.GET("/ratings/history", s.handleRatingHistory)
+		api.GET("/news", s.handleNews)
+		api.POST("/news", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handlePushNews)
+		api.GET("/calibration/models", s.handleModelCalibration)
+		api.GET("/models/health", s.handleModelHealth)
+		api.GET("/regime", s.handleRegime)
+		api.GET("/regime/models", s.handleModelRegimeStats)
 
 		// Trader列表
 		api.GET("/traders", s.handleTraderList)
@@ -80,9 +201,17 @@ func (s *Server) setupRoutes() {
 		api.GET("/positions", s.handlePositions)
 		api.GET("/decisions", s.handleDecisions)
 		api.GET("/decisions/latest", s.handleLatestDecisions)
+		api.GET("/decisions/:cycle/context", s.handleDecisionContext)
+		api.POST("/replay", requireRole(auth.RoleOperator), requireScope(auth.ScopeTrade), s.handleReplay)
+		api.POST("/simulate", s.handleSimulate)
 		api.GET("/statistics", s.handleStatistics)
 		api.GET("/equity-history", s.handleEquityHistory)
 		api.GET("/performance", s.handlePerformance)
+		api.GET("/trades", s.handleTrades)
+		api.GET("/calibration", s.handleCalibration)
+		api.GET("/lessons", s.handleLessons)
+		api.GET("/export/trades", s.handleExportTrades)
+		api.GET("/stream", s.handleStream)
 	}
 }
 
@@ -122,7 +251,7 @@ func (s *Server) setupFrontend() {
     // SPA 回退：非 /api 和 /health 的未命中路由返回 index.html
     s.router.NoRoute(func(c *gin.Context) {
         p := c.Request.URL.Path
-        if strings.HasPrefix(p, "/api") || p == "/health" {
+        if strings.HasPrefix(p, "/api") || p == "/health" || p == "/healthz" || p == "/readyz" || p == "/ws" {
             c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
             return
         }
@@ -149,8 +278,8 @@ func (s *Server) handleHealth(c *gin.Context) {
 func (s *Server) getTraderFromQuery(c *gin.Context) (*manager.TraderManager, string, error) {
 	traderID := c.Query("trader_id")
 	if traderID == "" {
-		// 如果没有指定trader_id，返回第一个trader
-		ids := s.traderManager.GetTraderIDs()
+		// 如果没有指定trader_id，返回当前用户能访问的第一个trader
+		ids := s.accessibleTraderIDs(c)
 		if len(ids) == 0 {
 			return nil, "", fmt.Errorf("没有可用的trader")
 		}
@@ -159,6 +288,59 @@ func (s *Server) getTraderFromQuery(c *gin.Context) (*manager.TraderManager, str
 	return s.traderManager, traderID, nil
 }
 
+// maxRecordLimit 决策/净值接口单次最多返回的条数（也是未指定limit时的默认值）
+const maxRecordLimit = 10000
+
+// parseRecordFilter 解析分页和时间范围参数: limit, offset, from, to
+// from/to 支持 RFC3339、YYYY-MM-DD 或 Unix毫秒时间戳
+func parseRecordFilter(c *gin.Context) (logger.RecordFilter, error) {
+	f := logger.RecordFilter{Limit: maxRecordLimit}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return f, fmt.Errorf("limit必须是正整数")
+		}
+		if limit > maxRecordLimit {
+			limit = maxRecordLimit
+		}
+		f.Limit = limit
+	}
+	if v := c.Query("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return f, fmt.Errorf("offset必须是非负整数")
+		}
+		f.Offset = offset
+	}
+
+	var err error
+	if f.From, err = parseTimeParam(c.Query("from")); err != nil {
+		return f, fmt.Errorf("from格式错误: %w", err)
+	}
+	if f.To, err = parseTimeParam(c.Query("to")); err != nil {
+		return f, fmt.Errorf("to格式错误: %w", err)
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && f.To.Before(f.From) {
+		return f, fmt.Errorf("to不能早于from")
+	}
+	return f, nil
+}
+
+// parseTimeParam 解析时间参数（空字符串返回零值）
+func parseTimeParam(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", v, time.Local)
+}
+
 // handleCompetition 竞赛总览（对比所有trader）
 func (s *Server) handleCompetition(c *gin.Context) {
 	comparison, err := s.traderManager.GetComparisonData()
@@ -168,25 +350,491 @@ func (s *Server) handleCompetition(c *gin.Context) {
 		})
 		return
 	}
+
+	// 只对比当前用户能访问的trader
+	traders, _ := comparison["traders"].([]map[string]interface{})
+	visible := make([]map[string]interface{}, 0, len(traders))
+	for _, t := range traders {
+		if id, _ := t["trader_id"].(string); s.canAccessTrader(c, id) {
+			visible = append(visible, t)
+		}
+	}
+	comparison["traders"] = visible
+	comparison["count"] = len(visible)
 	c.JSON(http.StatusOK, comparison)
 }
 
+// handleLeaderboard 竞赛排行榜（?metric=pnl_pct|sharpe|max_drawdown|win_rate|alpha，不指定则使用配置的默认指标）
+func (s *Server) handleLeaderboard(c *gin.Context) {
+	entries, err := s.traderManager.GetLeaderboard(c.Query("metric"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, s.visibleLeaderboard(c, entries))
+}
+
+// handleLeaderboardHistory 排行榜历史快照（?metric=&from=&to=，默认最近7天）
+func (s *Server) handleLeaderboardHistory(c *gin.Context) {
+	from, err := parseTimeParam(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("from格式错误: %v", err)})
+		return
+	}
+	to, err := parseTimeParam(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("to格式错误: %v", err)})
+		return
+	}
+	if from.IsZero() {
+		from = time.Now().AddDate(0, 0, -7)
+	}
+	metric := c.Query("metric")
+	if metric != "" && !manager.ValidLeaderboardMetric(metric) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("不支持的排行指标: %s", metric)})
+		return
+	}
+
+	snapshots, err := s.traderManager.GetLeaderboardHistory(metric, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("获取排行榜历史失败: %v", err),
+		})
+		return
+	}
+	for i := range snapshots {
+		snapshots[i].Entries = s.visibleLeaderboard(c, snapshots[i].Entries)
+	}
+	c.JSON(http.StatusOK, snapshots)
+}
+
+// visibleLeaderboard 只保留当前用户能访问的trader（名次保持全局排名）
+func (s *Server) visibleLeaderboard(c *gin.Context, entries []logger.LeaderboardEntry) []logger.LeaderboardEntry {
+	visible := make([]logger.LeaderboardEntry, 0, len(entries))
+	for _, e := range entries {
+		if s.canAccessTrader(c, e.TraderID) {
+			visible = append(visible, e)
+		}
+	}
+	return visible
+}
+
 // handleTraderList trader列表
 func (s *Server) handleTraderList(c *gin.Context) {
 	traders := s.traderManager.GetAllTraders()
 	result := make([]map[string]interface{}, 0, len(traders))
 
 	for _, t := range traders {
+		if !s.canAccessTrader(c, t.GetID()) {
+			continue
+		}
 		result = append(result, map[string]interface{}{
 			"trader_id":   t.GetID(),
 			"trader_name": t.GetName(),
 			"ai_model":    t.GetAIModel(),
+			"owner":       s.traderManager.GetOwner(t.GetID()),
 		})
 	}
 
 	c.JSON(http.StatusOK, result)
 }
 
+// handleStartTrader 启动指定trader
+func (s *Server) handleStartTrader(c *gin.Context) {
+	traderID := c.Param("id")
+	if err := s.traderManager.StartTrader(traderID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("▶️  %s 通过API启动trader: %s", c.GetString("username"), traderID)
+	c.JSON(http.StatusOK, gin.H{"trader_id": traderID, "is_running": true})
+}
+
+// handleStopTrader 停止指定trader
+func (s *Server) handleStopTrader(c *gin.Context) {
+	traderID := c.Param("id")
+	if err := s.traderManager.StopTrader(traderID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("⏹  %s 通过API停止trader: %s", c.GetString("username"), traderID)
+	c.JSON(http.StatusOK, gin.H{"trader_id": traderID, "is_running": false})
+}
+
+// handlePanicTrader 紧急平仓：撤销挂单、市价平掉全部持仓并锁定trader
+func (s *Server) handlePanicTrader(c *gin.Context) {
+	result, err := s.traderManager.PanicTrader(c.Param("id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if result == nil {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error(), "result": result})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// handlePanicAll 对当前用户能访问的所有trader执行紧急平仓
+func (s *Server) handlePanicAll(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"results": s.traderManager.PanicTraders(s.accessibleTraderIDs(c))})
+}
+
+// handleUnlockTrader 解除紧急锁定
+func (s *Server) handleUnlockTrader(c *gin.Context) {
+	if err := s.traderManager.UnlockTrader(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已解除锁定"})
+}
+
+// handleTriggerCycle 立即触发一次决策周期（限频，trader须在运行中）
+func (s *Server) handleTriggerCycle(c *gin.Context) {
+	t, err := s.traderManager.GetTrader(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	retryAfter, err := t.TriggerCycle(c.GetString("username"))
+	if err != nil {
+		status := http.StatusConflict
+		if retryAfter > 0 {
+			status = http.StatusTooManyRequests
+			c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"trader_id": t.GetID(), "message": "已触发决策周期"})
+}
+
+// handleGetRiskLimits 查询trader的风控参数
+func (s *Server) handleGetRiskLimits(c *gin.Context) {
+	t, err := s.traderManager.GetTrader(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, t.GetRiskLimits())
+}
+
+// handleUpdateRiskLimits 修改trader的风控参数（下个周期生效，无需重启）
+func (s *Server) handleUpdateRiskLimits(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := s.traderManager.GetTrader(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req trader.RiskLimits
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误"})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.traderManager.UpdateRiskLimits(id, req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+// handleGetStrategy 查询trader当前的决策策略
+func (s *Server) handleGetStrategy(c *gin.Context) {
+	t, err := s.traderManager.GetTrader(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"strategy": t.GetStrategy().Name()})
+}
+
+// handleUpdateStrategy 切换trader的决策策略（ai/rule/hybrid/ensemble，下个周期生效）
+func (s *Server) handleUpdateStrategy(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := s.traderManager.GetTrader(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		Strategy string `json:"strategy"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误"})
+		return
+	}
+	if !decision.ValidStrategy(req.Strategy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("不支持的策略: %s（可选 ai, rule, hybrid, ensemble）", req.Strategy)})
+		return
+	}
+	if err := s.traderManager.UpdateStrategy(id, req.Strategy); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"strategy": req.Strategy})
+}
+
+// handleGetSymbolFilter 查询trader的币种黑白名单
+func (s *Server) handleGetSymbolFilter(c *gin.Context) {
+	t, err := s.traderManager.GetTrader(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, t.GetSymbolFilter())
+}
+
+// handleUpdateSymbolFilter 修改trader的币种黑白名单（下个周期生效，无需重启）
+func (s *Server) handleUpdateSymbolFilter(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := s.traderManager.GetTrader(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req pool.SymbolFilter
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误"})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.traderManager.UpdateSymbolFilter(id, req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, req.Normalize())
+}
+
+// handleGetSchedule 查询trader的交易时段
+func (s *Server) handleGetSchedule(c *gin.Context) {
+	t, err := s.traderManager.GetTrader(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	reason, closed := t.GetSchedule().Closed(time.Now())
+	c.JSON(http.StatusOK, gin.H{
+		"schedule":     t.GetSchedule(),
+		"trading_open": !closed,
+		"reason":       reason,
+	})
+}
+
+// handleUpdateSchedule 修改trader的交易时段（下个周期生效，无需重启）
+func (s *Server) handleUpdateSchedule(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := s.traderManager.GetTrader(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req schedule.Schedule
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误"})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.traderManager.UpdateSchedule(id, req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+// handleUpdateTrader 修改trader的名称、初始余额和扫描间隔（只修改请求中给出的字段，立即生效，无需重新创建trader）
+func (s *Server) handleUpdateTrader(c *gin.Context) {
+	id := c.Param("id")
+	t, err := s.traderManager.GetTrader(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		Name                *string  `json:"name"`
+		InitialBalance      *float64 `json:"initial_balance"`
+		ScanIntervalMinutes *int     `json:"scan_interval_minutes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误"})
+		return
+	}
+
+	profile := t.GetProfile()
+	if req.Name != nil {
+		profile.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.InitialBalance != nil {
+		profile.InitialBalance = *req.InitialBalance
+	}
+	if req.ScanIntervalMinutes != nil {
+		profile.ScanIntervalMinutes = *req.ScanIntervalMinutes
+	}
+	if err := profile.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.traderManager.UpdateProfile(id, profile); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// handleGetExchangeSettings 查询trader的交易所连接设置
+func (s *Server) handleGetExchangeSettings(c *gin.Context) {
+	t, err := s.traderManager.GetTrader(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"exchange":         t.GetExchange(),
+		"testnet":          t.GetExchangeSettings().Testnet,
+		"supports_testnet": exchange.SupportsTestnet(t.GetExchange()),
+		"running":          t.IsRunning(),
+	})
+}
+
+// handleUpdateExchangeSettings 切换测试网/主网（trader须先停止，切换后重新创建交易所客户端）
+func (s *Server) handleUpdateExchangeSettings(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := s.traderManager.GetTrader(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req trader.ExchangeSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误"})
+		return
+	}
+	if err := s.traderManager.UpdateExchangeSettings(id, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+// dryRunRequest 观察模式开关
+type dryRunRequest struct {
+	DryRun bool `json:"dry_run"`
+}
+
+// handleGetDryRun 查询trader是否处于观察模式
+func (s *Server) handleGetDryRun(c *gin.Context) {
+	t, err := s.traderManager.GetTrader(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dryRunRequest{DryRun: t.IsDryRun()})
+}
+
+// handleUpdateDryRun 开启/关闭观察模式（下个周期生效，运行中也可切换）
+func (s *Server) handleUpdateDryRun(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := s.traderManager.GetTrader(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req dryRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误"})
+		return
+	}
+	if err := s.traderManager.UpdateDryRun(id, req.DryRun); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+// handleGetPrompt 查询trader当前使用的prompt模板（未自定义的部分返回默认模板）
+func (s *Server) handleGetPrompt(c *gin.Context) {
+	t, err := s.traderManager.GetTrader(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	custom := t.GetPromptTemplates()
+	prompts := decision.DefaultPromptTemplates()
+	if t.IsSpot() {
+		prompts = decision.DefaultSpotPromptTemplates()
+	}
+	if custom.System != "" {
+		prompts.System = custom.System
+	}
+	if custom.User != "" {
+		prompts.User = custom.User
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"system":        prompts.System,
+		"user":          prompts.User,
+		"custom_system": custom.System != "",
+		"custom_user":   custom.User != "",
+	})
+}
+
+// handleAIUsage 查询trader最近N天的AI调用用量和估算成本（?days=，默认7，最多90）
+func (s *Server) handleAIUsage(c *gin.Context) {
+	t, err := s.traderManager.GetTrader(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days < 1 || days > 90 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days必须是1到90之间的整数"})
+		return
+	}
+
+	usage, err := t.GetAIUsage(days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
+
+// handleUpdatePrompt 修改trader的prompt模板（Go text/template语法，字段为空恢复默认模板，下个周期生效）
+func (s *Server) handleUpdatePrompt(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := s.traderManager.GetTrader(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req decision.PromptTemplates
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误"})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.traderManager.UpdatePromptTemplates(id, req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
 // handleStatus 系统状态
 func (s *Server) handleStatus(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
@@ -277,8 +925,14 @@ func (s *Server) handleDecisions(c *gin.Context) {
 		return
 	}
 
-	// 获取所有历史决策记录（无限制）
-	records, err := trader.GetDecisionLogger().GetLatestRecords(10000)
+	filter, err := parseRecordFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 按时间范围分页获取决策记录（总条数通过 X-Total-Count 返回）
+	records, total, err := trader.GetDecisionLogger().QueryRecords(filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": fmt.Sprintf("获取决策日志失败: %v", err),
@@ -286,6 +940,7 @@ func (s *Server) handleDecisions(c *gin.Context) {
 		return
 	}
 
+	c.Header("X-Total-Count", strconv.Itoa(total))
 	c.JSON(http.StatusOK, records)
 }
 
@@ -345,6 +1000,123 @@ func (s *Server) handleStatistics(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
+// handleTrades 交易记录（?status=open|closed，from/to/limit/offset同/api/decisions，总条数在X-Total-Count中返回）
+func (s *Server) handleTrades(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter, err := parseRecordFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	status := c.Query("status")
+	if status != "" && status != "open" && status != "closed" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status必须是open或closed"})
+		return
+	}
+
+	trades, total, err := trader.GetDecisionLogger().QueryTrades(filter, status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("获取交易记录失败: %v", err),
+		})
+		return
+	}
+	if trades == nil {
+		trades = []logger.Trade{}
+	}
+
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.JSON(http.StatusOK, trades)
+}
+
+// handleCalibration 信心度校准：按AI模型和开仓信心度分组的胜率和平均盈亏（默认分组<70/70-79/80-89/90+，?bucket=10 改为等宽分组）
+func (s *Server) handleCalibration(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	bounds, err := calibrationBounds(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	buckets, err := trader.GetDecisionLogger().ConfidenceCalibration(bounds)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("获取信心度校准失败: %v", err),
+		})
+		return
+	}
+	if buckets == nil {
+		buckets = []logger.CalibrationBucket{}
+	}
+
+	c.JSON(http.StatusOK, buckets)
+}
+
+// handleModelCalibration 当前用户能访问的所有trader按AI模型汇总的信心度校准（对比不同模型是否过度自信）
+func (s *Server) handleModelCalibration(c *gin.Context) {
+	bounds, err := calibrationBounds(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var traderIDs []string // 管理员统计所有trader（包括已删除trader的历史交易）
+	if !isAdmin(c) {
+		traderIDs = s.accessibleTraderIDs(c)
+	}
+	buckets, err := logger.ModelCalibration(bounds, traderIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("获取信心度校准失败: %v", err),
+		})
+		return
+	}
+	if buckets == nil {
+		buckets = []logger.CalibrationBucket{}
+	}
+
+	c.JSON(http.StatusOK, buckets)
+}
+
+// calibrationBounds 解析?bucket=N（每组的信心度跨度），不指定时使用默认分组
+func calibrationBounds(c *gin.Context) ([]int, error) {
+	param := c.Query("bucket")
+	if param == "" {
+		return logger.CalibrationBounds, nil
+	}
+	bucket, err := strconv.Atoi(param)
+	if err != nil || bucket <= 0 || bucket > 100 {
+		return nil, fmt.Errorf("bucket必须是1-100之间的整数")
+	}
+	var bounds []int
+	for b := 0; b <= 100; b += bucket {
+		bounds = append(bounds, b)
+	}
+	return bounds, nil
+}
+
 // handleEquityHistory 收益率历史数据
 func (s *Server) handleEquityHistory(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
@@ -359,9 +1131,25 @@ func (s *Server) handleEquityHistory(c *gin.Context) {
 		return
 	}
 
-	// 获取尽可能多的历史数据（几天的数据）
-	// 每3分钟一个周期：10000条 = 约20天的数据
-	records, err := trader.GetDecisionLogger().GetLatestRecords(10000)
+	filter, err := parseRecordFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 指定resolution时按时间桶聚合（长周期图表不必传输上万个点）
+	if res := c.Query("resolution"); res != "" {
+		resolution, ok := equityResolutions[res]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "resolution必须是5m、1h或1d"})
+			return
+		}
+		s.handleEquityCandles(c, trader, filter, resolution)
+		return
+	}
+
+	// 直接读取净值快照表（每3分钟一个周期：默认最近10000条 = 约20天的数据）
+	snapshots, total, err := trader.GetDecisionLogger().QueryEquityHistory(filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": fmt.Sprintf("获取历史数据失败: %v", err),
@@ -382,17 +1170,12 @@ func (s *Server) handleEquityHistory(c *gin.Context) {
 	}
 
 	// 从AutoTrader获取初始余额（用于计算盈亏百分比）
-	initialBalance := 0.0
-	if status := trader.GetStatus(); status != nil {
-		if ib, ok := status["initial_balance"].(float64); ok && ib > 0 {
-			initialBalance = ib
-		}
-	}
+	initialBalance := traderInitialBalance(trader)
 
 	// 如果无法从status获取，且有历史记录，则从第一条记录获取
-	if initialBalance == 0 && len(records) > 0 {
+	if initialBalance == 0 && len(snapshots) > 0 {
 		// 第一条记录的equity作为初始余额
-		initialBalance = records[0].AccountState.TotalBalance
+		initialBalance = snapshots[0].TotalEquity
 	}
 
 	// 如果还是无法获取，返回错误
@@ -404,30 +1187,101 @@ func (s *Server) handleEquityHistory(c *gin.Context) {
 	}
 
 	var history []EquityPoint
-	for _, record := range records {
-		// TotalBalance字段实际存储的是TotalEquity
-		totalEquity := record.AccountState.TotalBalance
-		// TotalUnrealizedProfit字段实际存储的是TotalPnL（相对初始余额）
-		totalPnL := record.AccountState.TotalUnrealizedProfit
-
+	for _, snap := range snapshots {
 		// 计算盈亏百分比
 		totalPnLPct := 0.0
 		if initialBalance > 0 {
-			totalPnLPct = (totalPnL / initialBalance) * 100
+			totalPnLPct = (snap.TotalPnL / initialBalance) * 100
 		}
 
 		history = append(history, EquityPoint{
-			Timestamp:        record.Timestamp.Format("2006-01-02 15:04:05"),
-			TotalEquity:      totalEquity,
-			AvailableBalance: record.AccountState.AvailableBalance,
-			TotalPnL:         totalPnL,
+			Timestamp:        snap.Timestamp.Format("2006-01-02 15:04:05"),
+			TotalEquity:      snap.TotalEquity,
+			AvailableBalance: snap.AvailableBalance,
+			TotalPnL:         snap.TotalPnL,
 			TotalPnLPct:      totalPnLPct,
-			PositionCount:    record.AccountState.PositionCount,
-			MarginUsedPct:    record.AccountState.MarginUsedPct,
-			CycleNumber:      record.CycleNumber,
+			PositionCount:    snap.PositionCount,
+			MarginUsedPct:    snap.MarginUsedPct,
+			CycleNumber:      snap.CycleNumber,
 		})
 	}
 
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.JSON(http.StatusOK, history)
+}
+
+// equityResolutions /api/equity-history 支持的聚合周期
+var equityResolutions = map[string]time.Duration{
+	"5m": 5 * time.Minute,
+	"1h": time.Hour,
+	"1d": 24 * time.Hour,
+}
+
+// traderInitialBalance 从AutoTrader状态获取初始余额（获取不到时返回0）
+func traderInitialBalance(t *trader.AutoTrader) float64 {
+	if status := t.GetStatus(); status != nil {
+		if ib, ok := status["initial_balance"].(float64); ok && ib > 0 {
+			return ib
+		}
+	}
+	return 0
+}
+
+// handleEquityCandles 按时间桶聚合的净值OHLC（total_equity等字段取桶内最后一个快照）
+func (s *Server) handleEquityCandles(c *gin.Context, t *trader.AutoTrader, filter logger.RecordFilter, resolution time.Duration) {
+	candles, total, err := t.GetDecisionLogger().QueryEquityCandles(filter, resolution)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("获取历史数据失败: %v", err),
+		})
+		return
+	}
+
+	type EquityCandlePoint struct {
+		Timestamp        string  `json:"timestamp"` // 桶起始时间
+		Open             float64 `json:"open"`
+		High             float64 `json:"high"`
+		Low              float64 `json:"low"`
+		Close            float64 `json:"close"`
+		TotalEquity      float64 `json:"total_equity"` // 同close，兼容未聚合的数据格式
+		AvailableBalance float64 `json:"available_balance"`
+		TotalPnL         float64 `json:"total_pnl"`
+		TotalPnLPct      float64 `json:"total_pnl_pct"`
+		PositionCount    int     `json:"position_count"`
+		MarginUsedPct    float64 `json:"margin_used_pct"`
+		CycleNumber      int     `json:"cycle_number"`
+		Samples          int     `json:"samples"` // 桶内快照数
+	}
+
+	initialBalance := traderInitialBalance(t)
+	if initialBalance == 0 && len(candles) > 0 {
+		initialBalance = candles[0].Open
+	}
+
+	history := make([]EquityCandlePoint, 0, len(candles))
+	for _, candle := range candles {
+		totalPnLPct := 0.0
+		if initialBalance > 0 {
+			totalPnLPct = (candle.TotalPnL / initialBalance) * 100
+		}
+		history = append(history, EquityCandlePoint{
+			Timestamp:        candle.Timestamp.Format("2006-01-02 15:04:05"),
+			Open:             candle.Open,
+			High:             candle.High,
+			Low:              candle.Low,
+			Close:            candle.Close,
+			TotalEquity:      candle.Close,
+			AvailableBalance: candle.AvailableBalance,
+			TotalPnL:         candle.TotalPnL,
+			TotalPnLPct:      totalPnLPct,
+			PositionCount:    candle.PositionCount,
+			MarginUsedPct:    candle.MarginUsedPct,
+			CycleNumber:      candle.CycleNumber,
+			Samples:          candle.Samples,
+		})
+	}
+
+	c.Header("X-Total-Count", strconv.Itoa(total))
 	c.JSON(http.StatusOK, history)
 }
 
@@ -455,25 +1309,84 @@ func (s *Server) handlePerformance(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, performance)
+	// 与BTC/ETH买入持有对比（获取失败时不影响表现分析）
+	resp := performanceResponse{PerformanceAnalysis: performance}
+	if account, err := trader.GetAccountInfo(); err == nil {
+		totalPnLPct, _ := account["total_pnl_pct"].(float64)
+		if resp.Benchmarks, err = trader.GetBenchmarks(totalPnLPct); err != nil {
+			log.Printf("⚠️  计算基准收益失败: %v", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// performanceResponse /api/performance 的返回：表现分析 + 买入持有基准对比
+type performanceResponse struct {
+	*logger.PerformanceAnalysis
+	Benchmarks []trader.BenchmarkReturn `json:"benchmarks"`
 }
 
 // Start 启动服务器
 func (s *Server) Start() error {
 	addr := fmt.Sprintf(":%d", s.port)
 	log.Printf("🌐 API服务器启动在 http://localhost%s", addr)
+	if s.auth == nil {
+		log.Printf("🔓 管理员模式：API无需登录")
+	} else {
+		log.Printf("🔐 已启用登录鉴权：/api 和 /ws 需要携带 Authorization: Bearer <token>")
+	}
 	log.Printf("📊 API文档:")
+	log.Printf("  • POST /api/auth/register    - 注册账号")
+	log.Printf("  • POST /api/auth/login       - 登录获取token")
+	log.Printf("  • GET  /api/auth/me          - 当前登录用户")
+	log.Printf("  • POST /api/auth/otp/setup   - 生成两步验证密钥（返回二维码地址）")
+	log.Printf("  • POST /api/auth/otp/verify  - 确认开启两步验证")
+	log.Printf("  • GET/POST/DELETE /api/api-keys - 管理API Key（X-API-Key访问，权限 read/trade）")
+	log.Printf("  • POST /api/traders/:id/start|stop - 启停指定trader（operator及以上）")
+	log.Printf("  • PUT  /api/traders/:id - 修改名称、初始余额和扫描间隔，立即生效（operator及以上）")
+	log.Printf("  • POST /api/traders/:id/clone, DELETE /api/traders/:id - 克隆trader/删除克隆的trader（operator及以上）")
+	log.Printf("  • GET|POST /api/templates, POST /api/templates/:name/traders - trader模板：保存预设、从模板创建trader")
+	log.Printf("  • PUT  /api/traders/:id/risk - 修改指定trader的风控参数（operator及以上）")
+	log.Printf("  • PUT  /api/traders/:id/strategy - 切换决策策略 ai/rule/hybrid/ensemble（operator及以上）")
+	log.Printf("  • GET|PUT /api/traders/:id/symbols - 查询/修改币种黑白名单（修改需operator及以上）")
+	log.Printf("  • GET|PUT /api/traders/:id/schedule - 查询/修改交易时段（修改需operator及以上）")
+	log.Printf("  • GET|PUT /api/traders/:id/prompt - 查询/修改prompt模板（修改需operator及以上）")
+	log.Printf("  • GET  /api/traders/:id/ai-usage - AI调用token用量和估算成本（?days=7）")
+	log.Printf("  • GET  /api/approvals, POST /api/approvals/:id/approve|reject - 开仓人工审批（批准/拒绝需operator及以上）")
+	log.Printf("  • GET|PUT /api/traders/:id/owner - 查询/修改trader的所属用户（修改需admin）")
+	log.Printf("  • POST /api/traders/:id/panic | /api/panic-all - 紧急平仓并锁定（/unlock 解锁）")
+	log.Printf("  • POST /api/traders/:id/decide - 立即触发一次决策周期（每分钟最多一次，operator及以上）")
+	log.Printf("  • POST /api/traders/:id/open-position|close-position - 手动开仓/平仓，记入决策日志（operator及以上）")
+	log.Printf("  • GET  /api/users, PUT /api/users/:id/role - 用户角色管理（admin）")
+	log.Printf("  • GET  /api/audit?user=&target=&action= - 控制面操作审计记录（admin）")
 	log.Printf("  • GET  /api/competition      - 竞赛总览（对比所有trader）")
+	log.Printf("  • GET  /api/leaderboard?metric=pnl_pct - 竞赛排行榜（pnl_pct/sharpe/max_drawdown/win_rate/alpha）")
+	log.Printf("  • GET  /api/seasons, POST /api/seasons/end - 竞赛赛季和历届最终排行榜，提前结算当前赛季（admin）")
+	log.Printf("  • GET  /api/ratings, /api/ratings/history?model= - AI模型的ELO评分（每个赛季或每周更新）")
+	log.Printf("  • GET  /api/regime, /api/regime/models - 当前市场状态和恐惧贪婪指数，各AI模型在不同市场状态下的交易结果")
+	log.Printf("  • GET  /api/news, POST /api/news - 近期新闻和宏观事件日历，推送新闻和事件（webhook）")
 	log.Printf("  • GET  /api/traders          - Trader列表")
 	log.Printf("  • GET  /api/status?trader_id=xxx     - 指定trader的系统状态")
 	log.Printf("  • GET  /api/account?trader_id=xxx    - 指定trader的账户信息")
 	log.Printf("  • GET  /api/positions?trader_id=xxx  - 指定trader的持仓列表")
 	log.Printf("  • GET  /api/decisions?trader_id=xxx  - 指定trader的决策日志")
 	log.Printf("  • GET  /api/decisions/latest?trader_id=xxx - 指定trader的最新决策")
+	log.Printf("  • GET  /api/decisions/:cycle/context?trader_id=xxx - 指定周期的决策记录和完整上下文快照")
+	log.Printf("  • POST /api/replay?trader_id=xxx - 用新的模型/策略/prompt重放历史周期，对比决策")
 	log.Printf("  • GET  /api/statistics?trader_id=xxx - 指定trader的统计信息")
 	log.Printf("  • GET  /api/equity-history?trader_id=xxx - 指定trader的收益率历史数据")
 	log.Printf("  • GET  /api/performance?trader_id=xxx - 指定trader的AI学习表现分析")
+	log.Printf("  • GET  /api/trades?trader_id=xxx&status=open|closed - 交易记录（开仓决策到平仓，含止损止盈触发）")
+	log.Printf("  • GET  /api/calibration?trader_id=xxx - 信心度校准（各信心度区间的胜率和盈亏，/api/calibration/models 按模型汇总所有trader）")
+	log.Printf("  • GET  /api/lessons?trader_id=xxx&symbol=BTCUSDT - 交易复盘（每笔已平仓交易自动生成，相关的复盘写入prompt）")
+	log.Printf("  • GET  /api/export/trades?trader_id=xxx&format=csv|xlsx - 导出交易日志（报税/外部分析）")
+	log.Printf("  • WS   /ws?trader_id=xxx     - 实时推送账户/持仓/决策/AI流式输出（不指定trader_id则推送全部）")
+	log.Printf("  • GET  /api/stream?trader_id=xxx - SSE推送每个决策周期的思维链、决策和账户快照（ai_stream开启时实时推送思维链）")
+	log.Printf("  • GET  /api/openapi.json     - OpenAPI 3 接口描述（Swagger UI: /api/docs）")
 	log.Printf("  • GET  /health               - 健康检查")
+	log.Printf("  • GET  /healthz | /readyz    - 存活/就绪探针（就绪: 未排空、数据库可用、至少一个交易所可访问）")
+	log.Printf("  • POST|DELETE /api/drain     - 进入/退出排空状态（admin）")
 	log.Println()
 
 	return s.router.Run(addr)
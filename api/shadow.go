@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleShadowComparison 对比trader与其影子trader的收益率、已平仓交易（按币种和市场状态）和收益率曲线
+func (s *Server) handleShadowComparison(c *gin.Context) {
+	t, err := s.traderManager.GetTrader(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if !t.HasShadow() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "该trader未配置影子trader"})
+		return
+	}
+
+	filter, err := parseRecordFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	comparison, err := t.ShadowComparison(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, comparison)
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"nofx/logger"
+	"nofx/trader"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sseHeartbeatInterval SSE心跳间隔（防止反向代理因空闲断开连接）
+const sseHeartbeatInterval = 15 * time.Second
+
+// cycleEvent 决策周期结束时推送的内容（不包含体积较大的输入prompt）
+type cycleEvent struct {
+	TraderID     string                    `json:"trader_id"`
+	CycleNumber  int                       `json:"cycle_number"`
+	Timestamp    time.Time                 `json:"timestamp"`
+	CoTTrace     string                    `json:"cot_trace"`
+	Decisions    []logger.DecisionAction   `json:"decisions"`
+	AccountState logger.AccountSnapshot    `json:"account_state"`
+	Positions    []logger.PositionSnapshot `json:"positions"`
+	Success      bool                      `json:"success"`
+	ErrorMessage string                    `json:"error_message,omitempty"`
+}
+
+// handleStream SSE推送每个决策周期的结果和AI流式输出（?trader_id=xxx，不指定则推送当前用户能访问的所有trader）
+func (s *Server) handleStream(c *gin.Context) {
+	traderID := c.Query("trader_id")
+	if traderID != "" {
+		if _, err := s.traderManager.GetTrader(traderID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	events, unsubscribe := trader.Events().Subscribe(traderID)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭nginx缓冲
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if !s.canAccessTrader(c, event.TraderID) {
+				continue
+			}
+			// AI流式输出的增量（trader开启ai_stream时）
+			if delta, isCoT := event.Data.(trader.CoTDelta); isCoT && event.Type == trader.EventCoT {
+				data, err := json.Marshal(gin.H{"trader_id": event.TraderID, "cycle": delta.Cycle, "delta": delta.Delta, "done": delta.Done})
+				if err != nil {
+					continue
+				}
+				if _, err := fmt.Fprintf(c.Writer, "event: cot\ndata: %s\n\n", data); err != nil {
+					return
+				}
+				c.Writer.Flush()
+				continue
+			}
+			record, isDecision := event.Data.(*logger.DecisionRecord)
+			if event.Type != trader.EventDecision || !isDecision {
+				continue
+			}
+			data, err := json.Marshal(cycleEvent{
+				TraderID:     event.TraderID,
+				CycleNumber:  record.CycleNumber,
+				Timestamp:    record.Timestamp,
+				CoTTrace:     record.CoTTrace,
+				Decisions:    record.Decisions,
+				AccountState: record.AccountState,
+				Positions:    record.Positions,
+				Success:      record.Success,
+				ErrorMessage: record.ErrorMessage,
+			})
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(c.Writer, "event: cycle\nid: %s-%d\ndata: %s\n\n", event.TraderID, record.CycleNumber, data); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
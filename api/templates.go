@@ -0,0 +1,175 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"nofx/config"
+	"nofx/manager"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTraderRequest 克隆或从模板创建trader的请求
+type newTraderRequest struct {
+	ID        string                     `json:"id" binding:"required"`
+	Name      string                     `json:"name"`
+	Overrides map[string]json.RawMessage `json:"overrides"` // 覆盖的配置项，如 {"ai_model":"qwen","exchange":"paper"}
+	Start     bool                       `json:"start"`     // 创建后立即启动
+}
+
+// newTraderOwner 新trader/模板的所属用户：沿用来源的所属用户；来源是共享的时，非管理员创建的归自己所有
+func newTraderOwner(c *gin.Context, sourceOwner string) string {
+	if sourceOwner != "" || isAdmin(c) {
+		return sourceOwner
+	}
+	return c.GetString("username")
+}
+
+// canAccessTemplate 当前用户能否使用该模板（管理员、模板所有者，或共享模板）
+func canAccessTemplate(c *gin.Context, t manager.Template) bool {
+	return isAdmin(c) || t.Owner == "" || t.Owner == c.GetString("username")
+}
+
+// handleCloneTrader 以现有trader的配置、prompt和风控等设置创建新trader（A/B对比）
+func (s *Server) handleCloneTrader(c *gin.Context) {
+	sourceID := c.Param("id")
+	var req newTraderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误"})
+		return
+	}
+
+	cfg, err := s.traderManager.CloneTrader(sourceID, manager.NewTraderRequest{
+		ID:        strings.TrimSpace(req.ID),
+		Name:      strings.TrimSpace(req.Name),
+		Overrides: req.Overrides,
+		Owner:     newTraderOwner(c, s.traderManager.GetOwner(sourceID)),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	s.respondNewTrader(c, cfg, req.Start)
+}
+
+// handleDeleteTrader 删除通过API创建的trader（须已停止，配置文件中的trader不能删除）
+func (s *Server) handleDeleteTrader(c *gin.Context) {
+	id := c.Param("id")
+	if err := s.traderManager.RemoveTrader(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"trader_id": id, "deleted": true})
+}
+
+// handleListTemplates 当前用户能使用的trader模板
+func (s *Server) handleListTemplates(c *gin.Context) {
+	templates, err := s.traderManager.Templates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	visible := make([]manager.Template, 0, len(templates))
+	for _, t := range templates {
+		if canAccessTemplate(c, t) {
+			visible = append(visible, t)
+		}
+	}
+	c.JSON(http.StatusOK, visible)
+}
+
+// handleSaveTemplate 把trader当前的AI模型、交易所、prompt和风控等设置保存为命名模板（同名模板被覆盖）
+func (s *Server) handleSaveTemplate(c *gin.Context) {
+	var req struct {
+		Name     string `json:"name" binding:"required"`
+		TraderID string `json:"trader_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误"})
+		return
+	}
+	if !s.canAccessTrader(c, req.TraderID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "trader ID '" + req.TraderID + "' 不存在"})
+		return
+	}
+	if existing, err := s.traderManager.GetTemplate(req.Name); err == nil && !canAccessTemplate(c, existing) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "模板 '" + req.Name + "' 属于其他用户"})
+		return
+	}
+
+	template, err := s.traderManager.SaveTemplate(req.Name, req.TraderID, newTraderOwner(c, s.traderManager.GetOwner(req.TraderID)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+// handleDeleteTemplate 删除trader模板
+func (s *Server) handleDeleteTemplate(c *gin.Context) {
+	template, ok := s.templateFromPath(c)
+	if !ok {
+		return
+	}
+	if err := s.traderManager.DeleteTemplate(template.Name); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"name": template.Name, "deleted": true})
+}
+
+// handleCreateFromTemplate 从模板创建新trader
+func (s *Server) handleCreateFromTemplate(c *gin.Context) {
+	template, ok := s.templateFromPath(c)
+	if !ok {
+		return
+	}
+	var req newTraderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误"})
+		return
+	}
+
+	cfg, err := s.traderManager.CreateFromTemplate(template.Name, manager.NewTraderRequest{
+		ID:        strings.TrimSpace(req.ID),
+		Name:      strings.TrimSpace(req.Name),
+		Overrides: req.Overrides,
+		Owner:     newTraderOwner(c, template.Owner),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	s.respondNewTrader(c, cfg, req.Start)
+}
+
+// templateFromPath 路径中 :name 指定的模板（不存在或无权使用时返回404）
+func (s *Server) templateFromPath(c *gin.Context) (manager.Template, bool) {
+	template, err := s.traderManager.GetTemplate(c.Param("name"))
+	if err != nil || !canAccessTemplate(c, template) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "模板 '" + c.Param("name") + "' 不存在"})
+		return manager.Template{}, false
+	}
+	return template, true
+}
+
+// respondNewTrader 返回新建的trader（start为true时同时启动）
+func (s *Server) respondNewTrader(c *gin.Context, cfg config.TraderConfig, start bool) {
+	resp := gin.H{
+		"trader_id":   cfg.ID,
+		"trader_name": cfg.Name,
+		"ai_model":    cfg.AIModel,
+		"exchange":    cfg.Exchange,
+		"owner":       cfg.Owner,
+		"is_running":  false,
+	}
+	if start {
+		if err := s.traderManager.StartTrader(cfg.ID); err != nil {
+			resp["error"] = err.Error()
+		} else {
+			resp["is_running"] = true
+		}
+	}
+	c.JSON(http.StatusCreated, resp)
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"net/http"
+	"nofx/trader"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteTimeout = 10 * time.Second // 单次写超时
+	wsPongTimeout  = 60 * time.Second // 超过该时间未收到pong视为断开
+	wsPingInterval = 30 * time.Second // 心跳间隔（需小于pong超时）
+)
+
+// wsUpgrader WebSocket升级器（与CORS策略一致，允许任意来源）
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// handleWebSocket 实时推送账户净值、持仓变化和AI决策（?trader_id=xxx，不指定则推送当前用户能访问的所有trader）
+func (s *Server) handleWebSocket(c *gin.Context) {
+	traderID := c.Query("trader_id")
+	if traderID != "" {
+		if _, err := s.traderManager.GetTrader(traderID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("⚠️ WebSocket升级失败: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	events, unsubscribe := trader.Events().Subscribe(traderID)
+	defer unsubscribe()
+
+	// 连接建立后先推送一次当前状态，客户端无需额外轮询
+	for _, t := range s.traderManager.GetAllTraders() {
+		if (traderID != "" && t.GetID() != traderID) || !s.canAccessTrader(c, t.GetID()) {
+			continue
+		}
+		snapshot := trader.Event{
+			Type:      "status",
+			TraderID:  t.GetID(),
+			Timestamp: time.Now(),
+			Data:      t.GetStatus(),
+		}
+		conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		if err := conn.WriteJSON(snapshot); err != nil {
+			return
+		}
+	}
+
+	// 读协程：处理pong和客户端关闭
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if !s.canAccessTrader(c, event.TraderID) {
+				continue
+			}
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// API Key权限范围
+const (
+	ScopeRead  = "read"  // 只读：查询状态、持仓、决策、统计
+	ScopeTrade = "trade" // 交易控制：启停trader（包含只读权限）
+)
+
+const apiKeyPrefix = "nofx_"
+
+var ErrAPIKeyNotFound = errors.New("API Key无效")
+
+// APIKey 供外部脚本使用的访问密钥（只保存哈希，明文仅在创建时返回一次）
+type APIKey struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	Name       string    `json:"name"`
+	Prefix     string    `json:"prefix"` // 明文前几位，便于用户辨认
+	Hash       string    `json:"hash,omitempty"`
+	Scopes     []string  `json:"scopes"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at,omitempty"`
+}
+
+// ScopeRole 使用该权限所需的最低角色
+func ScopeRole(scope string) string {
+	if scope == ScopeTrade {
+		return RoleOperator
+	}
+	return RoleViewer
+}
+
+// HasScope 判断是否拥有权限（trade包含read）
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || (s == ScopeTrade && scope == ScopeRead) {
+			return true
+		}
+	}
+	return false
+}
+
+// Public 返回不含哈希的副本（用于API响应）
+func (k *APIKey) Public() *APIKey {
+	copied := *k
+	copied.Hash = ""
+	return &copied
+}
+
+// ValidateScopes 校验权限范围
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("至少需要一个权限范围")
+	}
+	for _, s := range scopes {
+		if s != ScopeRead && s != ScopeTrade {
+			return fmt.Errorf("不支持的权限范围: %s（可选 '%s', '%s'）", s, ScopeRead, ScopeTrade)
+		}
+	}
+	return nil
+}
+
+// hashAPIKey API Key为高熵随机串，直接用SHA256保存即可
+func hashAPIKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKey 为用户创建API Key，返回明文（只返回这一次）
+func (s *UserStore) CreateAPIKey(userID, name string, scopes []string) (string, *APIKey, error) {
+	if err := ValidateScopes(scopes); err != nil {
+		return "", nil, err
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("生成API Key失败: %w", err)
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, fmt.Errorf("生成API Key失败: %w", err)
+	}
+	raw := apiKeyPrefix + hex.EncodeToString(secret)
+
+	key := &APIKey{
+		ID:        hex.EncodeToString(id),
+		UserID:    userID,
+		Name:      name,
+		Prefix:    raw[:len(apiKeyPrefix)+6],
+		Hash:      hashAPIKey(raw),
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.apiKeys[key.ID] = key
+	if err := s.saveLocked(); err != nil {
+		delete(s.apiKeys, key.ID)
+		return "", nil, err
+	}
+	return raw, key, nil
+}
+
+// AuthenticateAPIKey 校验API Key，返回对应的记录（不持久化最近使用时间，避免每个请求都写文件）
+func (s *UserStore) AuthenticateAPIKey(raw string) (*APIKey, error) {
+	if !strings.HasPrefix(raw, apiKeyPrefix) {
+		return nil, ErrAPIKeyNotFound
+	}
+	hash := hashAPIKey(raw)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, k := range s.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(k.Hash), []byte(hash)) == 1 {
+			k.LastUsedAt = time.Now()
+			return k, nil
+		}
+	}
+	return nil, ErrAPIKeyNotFound
+}
+
+// ListAPIKeys 列出用户的API Key
+func (s *UserStore) ListAPIKeys(userID string) []*APIKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]*APIKey, 0)
+	for _, k := range s.apiKeys {
+		if k.UserID == userID {
+			keys = append(keys, k.Public())
+		}
+	}
+	return keys
+}
+
+// DeleteAPIKey 删除用户的API Key
+func (s *UserStore) DeleteAPIKey(userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, exists := s.apiKeys[id]
+	if !exists || key.UserID != userID {
+		return ErrAPIKeyNotFound
+	}
+	delete(s.apiKeys, id)
+	if err := s.saveLocked(); err != nil {
+		s.apiKeys[id] = key
+		return err
+	}
+	return nil
+}
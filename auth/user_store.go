@@ -0,0 +1,296 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUserExists   = errors.New("用户名已存在")
+	ErrUserNotFound = errors.New("用户不存在")
+)
+
+// 用户角色
+const (
+	RoleAdmin    = "admin"    // 管理员：管理用户和角色，拥有全部权限
+	RoleOperator = "operator" // 操作员：启停trader
+	RoleViewer   = "viewer"   // 观察者：只能查看统计数据
+)
+
+// roleRank 角色等级（高等级包含低等级的权限）
+var roleRank = map[string]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// ValidRole 是否为合法角色
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// RoleAtLeast 判断角色是否达到要求的等级
+func RoleAtLeast(role, required string) bool {
+	return roleRank[role] >= roleRank[required]
+}
+
+// User 登录用户
+type User struct {
+	ID           string    `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"password_hash"`
+	Role         string    `json:"role"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	// 两步验证（TOTP）
+	OTPEnabled bool   `json:"otp_enabled,omitempty"`
+	OTPSecret  string `json:"otp_secret,omitempty"`
+	OTPPending string `json:"otp_pending,omitempty"` // 已生成但尚未验证的密钥
+}
+
+// userFile 用户文件结构
+type userFile struct {
+	Users   []*User   `json:"users"`
+	APIKeys []*APIKey `json:"api_keys,omitempty"`
+}
+
+// UserStore 用户和API Key存储（JSON文件持久化）
+type UserStore struct {
+	path    string
+	users   map[string]*User   // username -> user
+	apiKeys map[string]*APIKey // id -> key
+	mu      sync.RWMutex
+}
+
+// NewUserStore 加载用户文件（文件不存在时创建空存储）
+func NewUserStore(path string) (*UserStore, error) {
+	s := &UserStore{
+		path:    path,
+		users:   make(map[string]*User),
+		apiKeys: make(map[string]*APIKey),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("读取用户文件失败: %w", err)
+	}
+
+	var f userFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("解析用户文件失败: %w", err)
+	}
+	for _, u := range f.Users {
+		s.users[strings.ToLower(u.Username)] = u
+	}
+	for _, k := range f.APIKeys {
+		s.apiKeys[k.ID] = k
+	}
+	s.migrateRoles()
+	return s, nil
+}
+
+// migrateRoles 兼容没有角色字段的旧用户文件：最早注册的用户设为管理员，其余为观察者
+func (s *UserStore) migrateRoles() {
+	var earliest *User
+	hasAdmin := false
+	for _, u := range s.users {
+		if u.Role == "" {
+			u.Role = RoleViewer
+		}
+		if u.Role == RoleAdmin {
+			hasAdmin = true
+		}
+		if earliest == nil || u.CreatedAt.Before(earliest.CreatedAt) {
+			earliest = u
+		}
+	}
+	if !hasAdmin && earliest != nil {
+		earliest.Role = RoleAdmin
+	}
+}
+
+// Count 用户数量
+func (s *UserStore) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.users)
+}
+
+// Create 创建用户（用户名不区分大小写；第一个用户为管理员，之后注册的为观察者）
+func (s *UserStore) Create(username, password string) (*User, error) {
+	hash, err := HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strings.ToLower(username)
+	if _, exists := s.users[key]; exists {
+		return nil, ErrUserExists
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("生成用户ID失败: %w", err)
+	}
+	role := RoleViewer
+	if len(s.users) == 0 {
+		role = RoleAdmin
+	}
+	user := &User{
+		ID:           hex.EncodeToString(id),
+		Username:     username,
+		PasswordHash: hash,
+		Role:         role,
+		CreatedAt:    time.Now(),
+	}
+	s.users[key] = user
+
+	if err := s.saveLocked(); err != nil {
+		delete(s.users, key)
+		return nil, err
+	}
+	return user, nil
+}
+
+// Authenticate 校验用户名和密码
+func (s *UserStore) Authenticate(username, password string) (*User, error) {
+	s.mu.RLock()
+	user, exists := s.users[strings.ToLower(username)]
+	s.mu.RUnlock()
+
+	// 用户不存在时也计算一次哈希，避免通过响应时间探测用户名
+	if !exists {
+		CheckPassword(password, "pbkdf2-sha256$210000$AAAAAAAAAAAAAAAAAAAAAA$AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA")
+		return nil, ErrUserNotFound
+	}
+	if !CheckPassword(password, user.PasswordHash) {
+		return nil, fmt.Errorf("密码错误")
+	}
+	return user, nil
+}
+
+// GetByID 按ID查找用户
+func (s *UserStore) GetByID(id string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, u := range s.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+// List 列出所有用户
+func (s *UserStore) List() []*User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]*User, 0, len(s.users))
+	for _, u := range s.users {
+		copied := *u
+		users = append(users, &copied)
+	}
+	return users
+}
+
+// SetRole 修改用户角色（不允许移除最后一个管理员）
+func (s *UserStore) SetRole(id, role string) (*User, error) {
+	if !ValidRole(role) {
+		return nil, fmt.Errorf("不支持的角色: %s（可选 '%s', '%s', '%s'）", role, RoleAdmin, RoleOperator, RoleViewer)
+	}
+
+	return s.Update(id, func(u *User) error {
+		// Update持有写锁，这里可以直接遍历
+		admins := 0
+		for _, other := range s.users {
+			if other.Role == RoleAdmin {
+				admins++
+			}
+		}
+		if u.Role == RoleAdmin && role != RoleAdmin && admins <= 1 {
+			return fmt.Errorf("不能移除最后一个管理员")
+		}
+		u.Role = role
+		return nil
+	})
+}
+
+// Update 修改用户并保存（fn返回错误时不做任何修改）
+func (s *UserStore) Update(id string, fn func(u *User) error) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var user *User
+	for _, u := range s.users {
+		if u.ID == id {
+			user = u
+			break
+		}
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	updated := *user
+	if err := fn(&updated); err != nil {
+		return nil, err
+	}
+
+	key := strings.ToLower(user.Username)
+	s.users[key] = &updated
+	if err := s.saveLocked(); err != nil {
+		s.users[key] = user
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// saveLocked 保存到文件（调用方需持有写锁）
+func (s *UserStore) saveLocked() error {
+	f := userFile{
+		Users:   make([]*User, 0, len(s.users)),
+		APIKeys: make([]*APIKey, 0, len(s.apiKeys)),
+	}
+	for _, u := range s.users {
+		f.Users = append(f.Users, u)
+	}
+	for _, k := range s.apiKeys {
+		f.APIKeys = append(f.APIKeys, k)
+	}
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化用户文件失败: %w", err)
+	}
+	if dir := filepath.Dir(s.path); dir != "." {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("创建用户目录失败: %w", err)
+		}
+	}
+
+	// 先写临时文件再重命名，避免写到一半崩溃导致文件损坏
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("写入用户文件失败: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("写入用户文件失败: %w", err)
+	}
+	return nil
+}
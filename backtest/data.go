@@ -0,0 +1,286 @@
+package backtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"nofx/market"
+	"nofx/ratelimit"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// httpClient 历史数据请求共用的客户端（与实时交易共享Binance权重限制）
+var httpClient = ratelimit.NewBinanceClient(30 * time.Second)
+
+const (
+	lookback3m = 40 // 与实时行情一致：3分钟K线40根
+	lookback4h = 60 // 与实时行情一致：4小时K线60根
+	oiAvgCount = 12 // OI均值取最近12个5分钟点（1小时）
+)
+
+// FundingPoint 历史资金费率
+type FundingPoint struct {
+	Time int64   `json:"time"` // 结算时间（毫秒）
+	Rate float64 `json:"rate"`
+}
+
+// OIPoint 历史持仓量
+type OIPoint struct {
+	Time  int64   `json:"time"` // 毫秒
+	Value float64 `json:"value"`
+}
+
+// History 单个币种的历史数据
+type History struct {
+	Symbol   string         `json:"symbol"`
+	Klines3m []market.Kline `json:"klines_3m"`
+	Klines4h []market.Kline `json:"klines_4h"`
+	Funding  []FundingPoint `json:"funding"`
+	OI       []OIPoint      `json:"oi"`
+}
+
+// LoadHistory 加载币种历史数据（优先读取本地缓存，没有则从币安下载并缓存）
+func LoadHistory(symbol string, from, to time.Time, cacheDir string) (*History, error) {
+	cacheFile := filepath.Join(cacheDir, fmt.Sprintf("%s_%s_%s.json",
+		symbol, from.UTC().Format("20060102T1504"), to.UTC().Format("20060102T1504")))
+
+	if data, err := os.ReadFile(cacheFile); err == nil {
+		var h History
+		if err := json.Unmarshal(data, &h); err == nil {
+			log.Printf("📂 使用缓存的历史数据: %s", cacheFile)
+			return &h, nil
+		}
+	}
+
+	log.Printf("🔄 正在下载 %s 历史数据 (%s ~ %s)...", symbol, from.Format("2006-01-02 15:04"), to.Format("2006-01-02 15:04"))
+
+	// 预留指标计算所需的历史窗口
+	klines3m, err := market.GetKlinesBetween(symbol, "3m", from.Add(-lookback3m*3*time.Minute), to)
+	if err != nil {
+		return nil, fmt.Errorf("获取%s 3分钟K线失败: %w", symbol, err)
+	}
+	if len(klines3m) == 0 {
+		return nil, fmt.Errorf("%s 在该时间段内没有K线数据", symbol)
+	}
+	klines4h, err := market.GetKlinesBetween(symbol, "4h", from.Add(-lookback4h*4*time.Hour), to)
+	if err != nil {
+		return nil, fmt.Errorf("获取%s 4小时K线失败: %w", symbol, err)
+	}
+
+	funding, err := FetchFundingHistory(symbol, from.Add(-8*time.Hour), to)
+	if err != nil {
+		log.Printf("⚠️  获取%s资金费率历史失败（按0处理）: %v", symbol, err)
+	}
+
+	oi, err := fetchOIHistory(symbol, from.Add(-time.Hour), to)
+	if err != nil || len(oi) == 0 {
+		// 币安仅提供最近30天的持仓量历史
+		log.Printf("⚠️  获取%s持仓量历史失败（币安仅保留最近30天，按0处理）: %v", symbol, err)
+	}
+
+	h := &History{
+		Symbol:   symbol,
+		Klines3m: klines3m,
+		Klines4h: klines4h,
+		Funding:  funding,
+		OI:       oi,
+	}
+
+	if err := os.MkdirAll(cacheDir, 0755); err == nil {
+		if data, err := json.Marshal(h); err == nil {
+			if err := os.WriteFile(cacheFile, data, 0644); err != nil {
+				log.Printf("⚠️  保存历史数据缓存失败: %v", err)
+			}
+		}
+	}
+
+	return h, nil
+}
+
+// FetchFundingHistory 获取币安历史资金费率
+func FetchFundingHistory(symbol string, start, end time.Time) ([]FundingPoint, error) {
+	var points []FundingPoint
+	startMs := start.UnixMilli()
+	for startMs < end.UnixMilli() {
+		url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/fundingRate?symbol=%s&startTime=%d&endTime=%d&limit=1000",
+			symbol, startMs, end.UnixMilli())
+
+		var raw []struct {
+			FundingRate string `json:"fundingRate"`
+			FundingTime int64  `json:"fundingTime"`
+		}
+		if err := getJSON(url, &raw); err != nil {
+			return points, err
+		}
+		if len(raw) == 0 {
+			break
+		}
+		for _, r := range raw {
+			rate, _ := strconv.ParseFloat(r.FundingRate, 64)
+			points = append(points, FundingPoint{Time: r.FundingTime, Rate: rate})
+		}
+		startMs = raw[len(raw)-1].FundingTime + 1
+	}
+	return points, nil
+}
+
+// fetchOIHistory 获取5分钟粒度的历史持仓量
+func fetchOIHistory(symbol string, start, end time.Time) ([]OIPoint, error) {
+	var points []OIPoint
+	startMs := start.UnixMilli()
+	for startMs < end.UnixMilli() {
+		url := fmt.Sprintf("https://fapi.binance.com/futures/data/openInterestHist?symbol=%s&period=5m&startTime=%d&endTime=%d&limit=500",
+			symbol, startMs, end.UnixMilli())
+
+		var raw []struct {
+			SumOpenInterest string `json:"sumOpenInterest"`
+			Timestamp       int64  `json:"timestamp"`
+		}
+		if err := getJSON(url, &raw); err != nil {
+			return points, err
+		}
+		if len(raw) == 0 {
+			break
+		}
+		for _, r := range raw {
+			value, _ := strconv.ParseFloat(r.SumOpenInterest, 64)
+			points = append(points, OIPoint{Time: r.Timestamp, Value: value})
+		}
+		startMs = raw[len(raw)-1].Timestamp + 1
+	}
+	return points, nil
+}
+
+// getJSON GET请求并解析JSON
+func getJSON(url string, v interface{}) error {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+	}
+	return json.Unmarshal(body, v)
+}
+
+// DataAt 计算t时刻可见的市场数据（只使用t之前已收盘的K线，避免未来函数）
+func (h *History) DataAt(t time.Time) (*market.Data, bool) {
+	tMs := t.UnixMilli()
+
+	// 3分钟K线：只取已收盘的
+	end3m := 0
+	for end3m < len(h.Klines3m) && h.Klines3m[end3m].CloseTime < tMs {
+		end3m++
+	}
+	if end3m == 0 {
+		return nil, false
+	}
+	start3m := end3m - lookback3m
+	if start3m < 0 {
+		start3m = 0
+	}
+	klines3m := h.Klines3m[start3m:end3m]
+
+	// 4小时K线：已收盘的 + 用3分钟K线拼出的当前未收盘K线
+	var klines4h []market.Kline
+	for _, k := range h.Klines4h {
+		if k.CloseTime < tMs {
+			klines4h = append(klines4h, k)
+			continue
+		}
+		if k.OpenTime <= tMs {
+			partial := market.Kline{OpenTime: k.OpenTime, CloseTime: k.CloseTime}
+			for _, k3 := range h.Klines3m[:end3m] {
+				if k3.OpenTime < k.OpenTime {
+					continue
+				}
+				if partial.Open == 0 {
+					partial.Open = k3.Open
+					partial.High = k3.High
+					partial.Low = k3.Low
+				}
+				if k3.High > partial.High {
+					partial.High = k3.High
+				}
+				if k3.Low < partial.Low {
+					partial.Low = k3.Low
+				}
+				partial.Close = k3.Close
+				partial.Volume += k3.Volume
+			}
+			if partial.Open > 0 {
+				klines4h = append(klines4h, partial)
+			}
+		}
+		break
+	}
+	if len(klines4h) > lookback4h {
+		klines4h = klines4h[len(klines4h)-lookback4h:]
+	}
+
+	// 持仓量：取t之前最近的点，均值取最近1小时
+	oiData := &market.OIData{}
+	var recentOI []float64
+	for _, p := range h.OI {
+		if p.Time > tMs {
+			break
+		}
+		recentOI = append(recentOI, p.Value)
+	}
+	if len(recentOI) > 0 {
+		oiData.Latest = recentOI[len(recentOI)-1]
+		if len(recentOI) > oiAvgCount {
+			recentOI = recentOI[len(recentOI)-oiAvgCount:]
+		}
+		sum := 0.0
+		for _, v := range recentOI {
+			sum += v
+		}
+		oiData.Average = sum / float64(len(recentOI))
+	}
+
+	// 资金费率：取t之前最近一次结算
+	fundingRate := 0.0
+	for _, f := range h.Funding {
+		if f.Time > tMs {
+			break
+		}
+		fundingRate = f.Rate
+	}
+
+	return market.BuildData(h.Symbol, klines3m, klines4h, oiData, fundingRate), true
+}
+
+// BarsBetween 返回收盘时间在 (from, to] 内的3分钟K线（用于模拟止盈止损触发）
+func (h *History) BarsBetween(from, to time.Time) []market.Kline {
+	var bars []market.Kline
+	fromMs, toMs := from.UnixMilli(), to.UnixMilli()
+	for _, k := range h.Klines3m {
+		if k.CloseTime > fromMs && k.CloseTime <= toMs {
+			bars = append(bars, k)
+		}
+	}
+	return bars
+}
+
+// FundingBetween 返回结算时间在 (from, to] 内的资金费
+func (h *History) FundingBetween(from, to time.Time) []FundingPoint {
+	var points []FundingPoint
+	fromMs, toMs := from.UnixMilli(), to.UnixMilli()
+	for _, f := range h.Funding {
+		if f.Time > fromMs && f.Time <= toMs {
+			points = append(points, f)
+		}
+	}
+	return points
+}
@@ -0,0 +1,420 @@
+package backtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"nofx/mcp"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+var log = logger.Module("backtest")
+
+// Config 回测配置
+type Config struct {
+	From            time.Time
+	To              time.Time
+	Symbols         []string
+	Interval        time.Duration // 决策间隔（默认3分钟，与实盘扫描间隔一致）
+	InitialBalance  float64
+	BTCETHLeverage  int
+	AltcoinLeverage int
+	OutputDir       string // 回测结果目录（决策记录写入 OutputDir/decisions.db）
+	CacheDir        string // 历史数据缓存目录
+}
+
+// Result 回测结果
+type Result struct {
+	From           time.Time                   `json:"from"`
+	To             time.Time                   `json:"to"`
+	Symbols        []string                    `json:"symbols"`
+	Cycles         int                         `json:"cycles"`
+	FailedCycles   int                         `json:"failed_cycles"`
+	InitialBalance float64                     `json:"initial_balance"`
+	FinalEquity    float64                     `json:"final_equity"`
+	ReturnPct      float64                     `json:"return_pct"`
+	MaxDrawdownPct float64                     `json:"max_drawdown_pct"`
+	FeesPaid       float64                     `json:"fees_paid"`
+	FundingPaid    float64                     `json:"funding_paid"`
+	OpenPositions  int                         `json:"open_positions"` // 回测结束时未平仓数量（按最后价格计入净值）
+	Performance    *logger.PerformanceAnalysis `json:"performance"`
+	OutputDir      string                      `json:"output_dir"`
+}
+
+// Run 执行回测：按历史K线逐周期调用AI决策，并用模拟撮合引擎执行
+func Run(cfg Config, mcpClient *mcp.Client) (*Result, error) {
+	if !cfg.To.After(cfg.From) {
+		return nil, fmt.Errorf("回测结束时间必须晚于开始时间")
+	}
+	if len(cfg.Symbols) == 0 {
+		return nil, fmt.Errorf("至少需要一个回测币种")
+	}
+	if cfg.InitialBalance <= 0 {
+		return nil, fmt.Errorf("初始金额必须大于0")
+	}
+	if cfg.Interval < 3*time.Minute {
+		cfg.Interval = 3 * time.Minute
+	}
+	if cfg.OutputDir == "" {
+		cfg.OutputDir = filepath.Join("backtest_results", time.Now().Format("20060102_150405"))
+	}
+	if cfg.CacheDir == "" {
+		cfg.CacheDir = "backtest_cache"
+	}
+	if cfg.BTCETHLeverage <= 0 {
+		cfg.BTCETHLeverage = 5
+	}
+	if cfg.AltcoinLeverage <= 0 {
+		cfg.AltcoinLeverage = 5
+	}
+
+	// 1. 加载历史数据
+	histories := make(map[string]*History)
+	for _, symbol := range cfg.Symbols {
+		symbol = market.Normalize(symbol)
+		h, err := LoadHistory(symbol, cfg.From, cfg.To, cfg.CacheDir)
+		if err != nil {
+			return nil, err
+		}
+		histories[symbol] = h
+	}
+
+	decisionLogger, err := logger.NewDecisionLoggerWithDB(filepath.Join(cfg.OutputDir, "decisions.db"), "backtest")
+	if err != nil {
+		return nil, fmt.Errorf("初始化决策日志失败: %w", err)
+	}
+	sim := NewSimulator(cfg.InitialBalance)
+
+	result := &Result{
+		From:           cfg.From,
+		To:             cfg.To,
+		InitialBalance: cfg.InitialBalance,
+		OutputDir:      cfg.OutputDir,
+	}
+	for symbol := range histories {
+		result.Symbols = append(result.Symbols, symbol)
+	}
+	sort.Strings(result.Symbols)
+
+	totalCycles := int(cfg.To.Sub(cfg.From) / cfg.Interval)
+	peakEquity := cfg.InitialBalance
+	prevTime := cfg.From
+	prices := make(map[string]float64)
+
+	log.Printf("🧪 开始回测: %s ~ %s, %d个币种, 间隔 %v, 共约 %d 个周期",
+		cfg.From.Format("2006-01-02 15:04"), cfg.To.Format("2006-01-02 15:04"), len(histories), cfg.Interval, totalCycles)
+
+	for t := cfg.From.Truncate(3 * time.Minute); !t.After(cfg.To); t = t.Add(cfg.Interval) {
+		result.Cycles++
+
+		record := &logger.DecisionRecord{
+			Timestamp:    t,
+			ExecutionLog: []string{},
+			Success:      true,
+		}
+
+		// 2. 回放上个周期到现在的K线：止盈止损/强平、资金费
+		for _, symbol := range result.Symbols {
+			h := histories[symbol]
+			for _, bar := range h.BarsBetween(prevTime, t) {
+				for _, fill := range sim.ProcessBar(symbol, bar) {
+					record.Decisions = append(record.Decisions, fillAction(fill))
+					record.ExecutionLog = append(record.ExecutionLog,
+						fmt.Sprintf("🎯 %s %s 触发%s @ %.4f, 盈亏 %.2f USDT", fill.Symbol, fill.Side, fill.Reason, fill.Price, fill.PnL))
+				}
+				prices[symbol] = bar.Close
+			}
+			for _, f := range h.FundingBetween(prevTime, t) {
+				if paid := sim.ApplyFunding(symbol, f.Rate, prices[symbol]); paid != 0 {
+					record.ExecutionLog = append(record.ExecutionLog,
+						fmt.Sprintf("💸 %s 资金费结算 %.4f USDT (费率 %.4f%%)", symbol, -paid, f.Rate*100))
+				}
+			}
+		}
+		prevTime = t
+
+		// 3. 构建t时刻的市场数据（只使用已收盘K线）
+		marketDataMap := make(map[string]*market.Data)
+		for _, symbol := range result.Symbols {
+			if data, ok := histories[symbol].DataAt(t); ok {
+				marketDataMap[symbol] = data
+				prices[symbol] = data.CurrentPrice
+			}
+		}
+		if len(marketDataMap) == 0 {
+			continue
+		}
+
+		ctx := buildContext(cfg, t, result.Cycles, sim, prices, marketDataMap, decisionLogger)
+
+		record.AccountState = logger.AccountSnapshot{
+			TotalBalance:          ctx.Account.TotalEquity,
+			AvailableBalance:      ctx.Account.AvailableBalance,
+			TotalUnrealizedProfit: ctx.Account.TotalPnL,
+			PositionCount:         ctx.Account.PositionCount,
+			MarginUsedPct:         ctx.Account.MarginUsedPct,
+		}
+		for _, pos := range ctx.Positions {
+			record.Positions = append(record.Positions, logger.PositionSnapshot{
+				Symbol:           pos.Symbol,
+				Side:             pos.Side,
+				PositionAmt:      pos.Quantity,
+				EntryPrice:       pos.EntryPrice,
+				MarkPrice:        pos.MarkPrice,
+				UnrealizedProfit: pos.UnrealizedPnL,
+				Leverage:         float64(pos.Leverage),
+				LiquidationPrice: pos.LiquidationPrice,
+			})
+		}
+		for _, coin := range ctx.CandidateCoins {
+			record.CandidateCoins = append(record.CandidateCoins, coin.Symbol)
+		}
+		if ctx.Regime != nil {
+			record.Regime = ctx.Regime.Label
+		}
+
+		// 回撤统计
+		if ctx.Account.TotalEquity > peakEquity {
+			peakEquity = ctx.Account.TotalEquity
+		}
+		if peakEquity > 0 {
+			drawdown := (peakEquity - ctx.Account.TotalEquity) / peakEquity * 100
+			result.MaxDrawdownPct = math.Max(result.MaxDrawdownPct, drawdown)
+		}
+
+		log.Printf("🧪 回测周期 [%d/%d] %s | 净值 %.2f USDT | 持仓 %d",
+			result.Cycles, totalCycles+1, t.Format("2006-01-02 15:04"), ctx.Account.TotalEquity, len(ctx.Positions))
+
+		// 4. 调用AI决策
+		fullDecision, err := decision.GetFullDecision(ctx, mcpClient)
+		if fullDecision != nil {
+			record.InputPrompt = fullDecision.UserPrompt
+			record.CoTTrace = fullDecision.CoTTrace
+			if len(fullDecision.Decisions) > 0 {
+				decisionJSON, _ := json.MarshalIndent(fullDecision.Decisions, "", "  ")
+				record.DecisionJSON = string(decisionJSON)
+			}
+		}
+		if err != nil {
+			result.FailedCycles++
+			record.Success = false
+			record.ErrorMessage = fmt.Sprintf("获取AI决策失败: %v", err)
+			log.Printf("⚠️  %s", record.ErrorMessage)
+			if err := decisionLogger.LogDecision(record); err != nil {
+				log.Printf("⚠ 保存决策记录失败: %v", err)
+			}
+			continue
+		}
+
+		// 5. 执行决策（先平仓后开仓）
+		decisions := fullDecision.Decisions
+		sort.SliceStable(decisions, func(i, j int) bool {
+			return actionPriority(decisions[i].Action) < actionPriority(decisions[j].Action)
+		})
+		for _, d := range decisions {
+			action, err := execute(sim, &d, prices, t)
+			if action == nil {
+				continue // hold/wait
+			}
+			if err != nil {
+				action.Error = err.Error()
+				record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", d.Symbol, d.Action, err))
+			} else {
+				action.Success = true
+				record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功", d.Symbol, d.Action))
+			}
+			record.Decisions = append(record.Decisions, *action)
+		}
+
+		if err := decisionLogger.LogDecision(record); err != nil {
+			log.Printf("⚠ 保存决策记录失败: %v", err)
+		}
+	}
+
+	// 6. 汇总结果（未平仓位按最后价格计入净值）
+	finalEquity, _, _ := sim.Equity(prices)
+	result.FinalEquity = finalEquity
+	result.ReturnPct = (finalEquity - cfg.InitialBalance) / cfg.InitialBalance * 100
+	result.FeesPaid = sim.FeesPaid
+	result.FundingPaid = sim.FundingPaid
+	result.OpenPositions = len(sim.Positions)
+	if peakEquity > 0 {
+		result.MaxDrawdownPct = math.Max(result.MaxDrawdownPct, (peakEquity-finalEquity)/peakEquity*100)
+	}
+
+	performance, err := decisionLogger.AnalyzePerformance(result.Cycles)
+	if err != nil {
+		log.Printf("⚠️  分析回测表现失败: %v", err)
+	}
+	result.Performance = performance
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return result, fmt.Errorf("序列化回测结果失败: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(cfg.OutputDir, "summary.json"), data, 0644); err != nil {
+		return result, fmt.Errorf("保存回测结果失败: %w", err)
+	}
+
+	return result, nil
+}
+
+// buildContext 构建t时刻的交易上下文（与实盘AutoTrader的上下文结构一致）
+func buildContext(cfg Config, t time.Time, cycle int, sim *Simulator, prices map[string]float64,
+	marketDataMap map[string]*market.Data, decisionLogger *logger.DecisionLogger) *decision.Context {
+
+	equity, unrealized, margin := sim.Equity(prices)
+	available := math.Max(sim.Balance+math.Min(unrealized, 0)-margin, 0)
+
+	var positions []decision.PositionInfo
+	for _, pos := range sim.Positions {
+		price := prices[pos.Symbol]
+		pnl := pos.UnrealizedPnL(price)
+		pnlPct := 0.0
+		if pos.Margin() > 0 {
+			pnlPct = pnl / pos.Margin() * 100
+		}
+		positions = append(positions, decision.PositionInfo{
+			Symbol:           pos.Symbol,
+			Side:             pos.Side,
+			EntryPrice:       pos.EntryPrice,
+			MarkPrice:        price,
+			Quantity:         pos.Quantity,
+			Leverage:         pos.Leverage,
+			UnrealizedPnL:    pnl,
+			UnrealizedPnLPct: pnlPct,
+			LiquidationPrice: pos.LiquidationPrice(),
+			MarginUsed:       pos.Margin(),
+			UpdateTime:       pos.OpenTime.UnixMilli(),
+		})
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		return positions[i].Symbol+positions[i].Side < positions[j].Symbol+positions[j].Side
+	})
+
+	var candidateCoins []decision.CandidateCoin
+	for symbol := range marketDataMap {
+		candidateCoins = append(candidateCoins, decision.CandidateCoin{
+			Symbol:  symbol,
+			Sources: []string{"backtest"},
+		})
+	}
+	sort.Slice(candidateCoins, func(i, j int) bool {
+		return candidateCoins[i].Symbol < candidateCoins[j].Symbol
+	})
+
+	marginUsedPct := 0.0
+	if equity > 0 {
+		marginUsedPct = margin / equity * 100
+	}
+
+	ctx := &decision.Context{
+		CurrentTime:     t.Format("2006-01-02 15:04:05"),
+		RuntimeMinutes:  int(t.Sub(cfg.From).Minutes()),
+		CallCount:       cycle,
+		BTCETHLeverage:  cfg.BTCETHLeverage,
+		AltcoinLeverage: cfg.AltcoinLeverage,
+		Account: decision.AccountInfo{
+			TotalEquity:      equity,
+			AvailableBalance: available,
+			TotalPnL:         equity - cfg.InitialBalance,
+			TotalPnLPct:      (equity - cfg.InitialBalance) / cfg.InitialBalance * 100,
+			MarginUsed:       margin,
+			MarginUsedPct:    marginUsedPct,
+			PositionCount:    len(positions),
+		},
+		Positions:      positions,
+		CandidateCoins: candidateCoins,
+		MarketDataMap:  marketDataMap,
+		Regime:         market.ClassifyRegime(marketDataMap["BTCUSDT"]), // 回测币种包含BTC时按历史数据判断市场状态
+	}
+
+	// 历史表现反馈（与实盘一致，取最近100个周期）
+	if performance, err := decisionLogger.AnalyzePerformance(100); err == nil {
+		ctx.Performance = performance
+	}
+
+	return ctx
+}
+
+// execute 在模拟引擎上执行单个决策，hold/wait返回nil
+func execute(sim *Simulator, d *decision.Decision, prices map[string]float64, t time.Time) (*logger.DecisionAction, error) {
+	price := prices[d.Symbol]
+	action := &logger.DecisionAction{
+		Action:    d.Action,
+		Symbol:    d.Symbol,
+		Leverage:  d.Leverage,
+		Price:     price,
+		Timestamp: t,
+	}
+
+	if price <= 0 && d.Action != "hold" && d.Action != "wait" {
+		return action, fmt.Errorf("没有 %s 的回测价格数据", d.Symbol)
+	}
+
+	switch d.Action {
+	case "open_long", "open_short":
+		side := "long"
+		if d.Action == "open_short" {
+			side = "short"
+		}
+		// 与实盘一致：按当前价格校验止损止盈价位（止损超出强平价时降低杠杆）
+		if _, err := decision.CheckPriceLevels(d, price, 0, decision.LiquidationRules{}); err != nil {
+			return action, err
+		}
+		action.Leverage = d.Leverage
+		action.Quantity = d.PositionSizeUSD / price
+		if err := sim.Open(d.Symbol, side, action.Quantity, price, d.Leverage, d.StopLoss, d.TakeProfit, t, prices); err != nil {
+			return action, err
+		}
+		action.Fee = action.Quantity * price * takerFeeRate
+		return action, nil
+	case "close_long", "close_short":
+		side := "long"
+		if d.Action == "close_short" {
+			side = "short"
+		}
+		fill, err := sim.Close(d.Symbol, side, price, "AI平仓", t)
+		if err != nil {
+			return action, err
+		}
+		action.Quantity = fill.Quantity
+		action.Fee = fill.Fee
+		action.Funding = fill.Funding
+		return action, nil
+	default:
+		return nil, nil
+	}
+}
+
+// fillAction 将止盈止损/强平成交转换为决策动作记录（便于表现分析配对开平仓）
+func fillAction(fill *Fill) logger.DecisionAction {
+	return logger.DecisionAction{
+		Action:    "close_" + fill.Side,
+		Symbol:    fill.Symbol,
+		Quantity:  fill.Quantity,
+		Price:     fill.Price,
+		Timestamp: fill.Time,
+		Success:   true,
+		Fee:       fill.Fee,
+		Funding:   fill.Funding,
+	}
+}
+
+// actionPriority 决策执行优先级：先平仓，后开仓
+func actionPriority(action string) int {
+	switch action {
+	case "close_long", "close_short":
+		return 1
+	case "open_long", "open_short":
+		return 2
+	default:
+		return 3
+	}
+}
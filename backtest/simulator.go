@@ -0,0 +1,213 @@
+package backtest
+
+import (
+	"fmt"
+	"nofx/market"
+	"time"
+)
+
+const (
+	takerFeeRate      = 0.0004 // 模拟手续费（币安taker 0.04%）
+	maintenanceMargin = 0.005  // 维持保证金率（用于估算强平价）
+)
+
+// Position 模拟持仓
+type Position struct {
+	Symbol      string
+	Side        string // "long" or "short"
+	Quantity    float64
+	EntryPrice  float64
+	Leverage    int
+	StopLoss    float64
+	TakeProfit  float64
+	OpenTime    time.Time
+	FundingPaid float64 // 持仓期间的资金费净支出（负数表示收取）
+}
+
+// LiquidationPrice 估算逐仓强平价
+func (p *Position) LiquidationPrice() float64 {
+	lev := float64(p.Leverage)
+	if p.Side == "long" {
+		return p.EntryPrice * (1 - 1/lev + maintenanceMargin)
+	}
+	return p.EntryPrice * (1 + 1/lev - maintenanceMargin)
+}
+
+// UnrealizedPnL 计算未实现盈亏
+func (p *Position) UnrealizedPnL(price float64) float64 {
+	if p.Side == "long" {
+		return (price - p.EntryPrice) * p.Quantity
+	}
+	return (p.EntryPrice - price) * p.Quantity
+}
+
+// Margin 占用保证金
+func (p *Position) Margin() float64 {
+	return p.Quantity * p.EntryPrice / float64(p.Leverage)
+}
+
+// Fill 模拟成交结果
+type Fill struct {
+	Symbol   string
+	Side     string
+	Quantity float64
+	Price    float64
+	PnL      float64 // 扣除手续费后的已实现盈亏（仅平仓）
+	Fee      float64 // 平仓手续费
+	Funding  float64 // 持仓期间的资金费净支出（仅平仓）
+	Reason   string  // 平仓原因：AI平仓/止损/止盈/强平
+	Time     time.Time
+}
+
+// Simulator 模拟撮合引擎
+type Simulator struct {
+	Balance     float64 // 钱包余额（已实现盈亏、手续费、资金费已计入）
+	FeesPaid    float64
+	FundingPaid float64
+	Positions   map[string]*Position // symbol_side -> 持仓
+}
+
+// NewSimulator 创建模拟撮合引擎
+func NewSimulator(initialBalance float64) *Simulator {
+	return &Simulator{
+		Balance:   initialBalance,
+		Positions: make(map[string]*Position),
+	}
+}
+
+// Equity 按给定价格计算账户净值、未实现盈亏和占用保证金
+func (s *Simulator) Equity(prices map[string]float64) (equity, unrealized, margin float64) {
+	for _, pos := range s.Positions {
+		margin += pos.Margin()
+		if price, ok := prices[pos.Symbol]; ok {
+			unrealized += pos.UnrealizedPnL(price)
+		}
+	}
+	return s.Balance + unrealized, unrealized, margin
+}
+
+// Open 按给定价格开仓
+func (s *Simulator) Open(symbol, side string, quantity, price float64, leverage int, stopLoss, takeProfit float64, t time.Time, prices map[string]float64) error {
+	key := symbol + "_" + side
+	if _, exists := s.Positions[key]; exists {
+		return fmt.Errorf("❌ %s 已有%s仓，拒绝开仓以防止仓位叠加超限", symbol, side)
+	}
+	if quantity <= 0 || price <= 0 {
+		return fmt.Errorf("开仓数量或价格无效")
+	}
+	if leverage <= 0 {
+		leverage = 1
+	}
+
+	_, unrealized, margin := s.Equity(prices)
+	available := s.Balance + min(unrealized, 0) - margin
+	fee := quantity * price * takerFeeRate
+	required := quantity*price/float64(leverage) + fee
+	if required > available {
+		return fmt.Errorf("可用余额不足: 需要 %.2f USDT, 可用 %.2f USDT", required, available)
+	}
+
+	s.Balance -= fee
+	s.FeesPaid += fee
+	s.Positions[key] = &Position{
+		Symbol:     symbol,
+		Side:       side,
+		Quantity:   quantity,
+		EntryPrice: price,
+		Leverage:   leverage,
+		StopLoss:   stopLoss,
+		TakeProfit: takeProfit,
+		OpenTime:   t,
+	}
+	return nil
+}
+
+// Close 按给定价格全部平仓
+func (s *Simulator) Close(symbol, side string, price float64, reason string, t time.Time) (*Fill, error) {
+	key := symbol + "_" + side
+	pos, exists := s.Positions[key]
+	if !exists {
+		return nil, fmt.Errorf("没有找到 %s 的%s仓", symbol, side)
+	}
+
+	fee := pos.Quantity * price * takerFeeRate
+	pnl := pos.UnrealizedPnL(price) - fee
+	s.Balance += pnl
+	s.FeesPaid += fee
+	delete(s.Positions, key)
+
+	return &Fill{
+		Symbol:   symbol,
+		Side:     side,
+		Quantity: pos.Quantity,
+		Price:    price,
+		PnL:      pnl,
+		Fee:      fee,
+		Funding:  pos.FundingPaid,
+		Reason:   reason,
+		Time:     t,
+	}, nil
+}
+
+// ProcessBar 用一根K线检查该币种持仓的强平/止损/止盈（同一根K线内同时触及时按最坏情况处理）
+func (s *Simulator) ProcessBar(symbol string, bar market.Kline) []*Fill {
+	var fills []*Fill
+	barTime := time.UnixMilli(bar.CloseTime)
+
+	for _, side := range []string{"long", "short"} {
+		pos, exists := s.Positions[symbol+"_"+side]
+		if !exists {
+			continue
+		}
+
+		var price float64
+		var reason string
+		liq := pos.LiquidationPrice()
+		if side == "long" {
+			switch {
+			case bar.Low <= liq && (pos.StopLoss <= 0 || pos.StopLoss <= liq):
+				price, reason = liq, "强平"
+			case pos.StopLoss > 0 && bar.Low <= pos.StopLoss:
+				price, reason = pos.StopLoss, "止损"
+			case pos.TakeProfit > 0 && bar.High >= pos.TakeProfit:
+				price, reason = pos.TakeProfit, "止盈"
+			}
+		} else {
+			switch {
+			case bar.High >= liq && (pos.StopLoss <= 0 || pos.StopLoss >= liq):
+				price, reason = liq, "强平"
+			case pos.StopLoss > 0 && bar.High >= pos.StopLoss:
+				price, reason = pos.StopLoss, "止损"
+			case pos.TakeProfit > 0 && bar.Low <= pos.TakeProfit:
+				price, reason = pos.TakeProfit, "止盈"
+			}
+		}
+
+		if reason != "" {
+			if fill, err := s.Close(symbol, side, price, reason, barTime); err == nil {
+				fills = append(fills, fill)
+			}
+		}
+	}
+	return fills
+}
+
+// ApplyFunding 结算资金费（正费率多头支付、空头收取），返回本次资金费净支出
+func (s *Simulator) ApplyFunding(symbol string, rate, price float64) float64 {
+	total := 0.0
+	for _, side := range []string{"long", "short"} {
+		pos, exists := s.Positions[symbol+"_"+side]
+		if !exists {
+			continue
+		}
+		funding := pos.Quantity * price * rate
+		if side == "short" {
+			funding = -funding
+		}
+		pos.FundingPaid += funding
+		total += funding
+	}
+	s.Balance -= total
+	s.FundingPaid += total
+	return total
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"nofx/backtest"
+	"nofx/config"
+	"nofx/logger"
+	"nofx/trader"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// runBacktest 回测模式入口：nofx backtest --from 2025-01-01 --to 2025-01-03 --symbols BTCUSDT,ETHUSDT
+func runBacktest(args []string) {
+	fs := flag.NewFlagSet("backtest", flag.ExitOnError)
+	configFile := fs.String("config", "config.json", "配置文件路径（用于读取AI模型和杠杆配置）")
+	traderID := fs.String("trader", "", "使用哪个trader的AI配置（默认第一个）")
+	fromStr := fs.String("from", "", "开始时间（2006-01-02 或 2006-01-02T15:04，UTC）")
+	toStr := fs.String("to", "", "结束时间（2006-01-02 或 2006-01-02T15:04，UTC）")
+	symbolsStr := fs.String("symbols", "BTCUSDT,ETHUSDT", "回测币种，逗号分隔")
+	intervalMin := fs.Int("interval", 0, "决策间隔（分钟，默认使用trader的scan_interval_minutes）")
+	balance := fs.Float64("balance", 0, "初始资金（默认使用trader的initial_balance）")
+	outputDir := fs.String("output", "", "结果目录（默认 backtest_results/<trader>_<时间>）")
+	fs.Parse(args)
+
+	from, err := parseBacktestTime(*fromStr)
+	if err != nil {
+		log.Fatalf("❌ --from 参数无效: %v", err)
+	}
+	to, err := parseBacktestTime(*toStr)
+	if err != nil {
+		log.Fatalf("❌ --to 参数无效: %v", err)
+	}
+
+	cfg, err := config.LoadConfig(*configFile)
+	if err != nil {
+		log.Fatalf("❌ 加载配置失败: %v", err)
+	}
+	if err := logger.Setup(cfg.LogFormat, cfg.LogLevel); err != nil {
+		log.Fatalf("❌ 初始化日志失败: %v", err)
+	}
+
+	var traderCfg *config.TraderConfig
+	for i := range cfg.Traders {
+		if *traderID == "" || cfg.Traders[i].ID == *traderID {
+			traderCfg = &cfg.Traders[i]
+			break
+		}
+	}
+	if traderCfg == nil {
+		log.Fatalf("❌ 未找到trader: %s", *traderID)
+	}
+
+	interval := traderCfg.GetScanInterval()
+	if *intervalMin > 0 {
+		interval = time.Duration(*intervalMin) * time.Minute
+	}
+	initialBalance := traderCfg.InitialBalance
+	if *balance > 0 {
+		initialBalance = *balance
+	}
+	if *outputDir == "" {
+		*outputDir = filepath.Join("backtest_results", fmt.Sprintf("%s_%s", traderCfg.ID, time.Now().Format("20060102_150405")))
+	}
+
+	var symbols []string
+	for _, s := range strings.Split(*symbolsStr, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			symbols = append(symbols, s)
+		}
+	}
+
+	mcpClient := trader.NewAIClient(trader.AutoTraderConfig{
+		Name:            traderCfg.Name,
+		AIModel:         traderCfg.AIModel,
+		UseQwen:         traderCfg.AIModel == "qwen",
+		DeepSeekKey:     traderCfg.DeepSeekKey,
+		QwenKey:         traderCfg.QwenKey,
+		OpenAIKey:       traderCfg.OpenAIKey,
+		OpenAIBaseURL:   traderCfg.OpenAIBaseURL,
+		OpenAIModel:     traderCfg.OpenAIModel,
+		ClaudeKey:       traderCfg.ClaudeKey,
+		ClaudeModel:     traderCfg.ClaudeModel,
+		CustomAPIURL:    traderCfg.CustomAPIURL,
+		CustomAPIKey:    traderCfg.CustomAPIKey,
+		CustomModelName: traderCfg.CustomModelName,
+	})
+
+	result, err := backtest.Run(backtest.Config{
+		From:            from,
+		To:              to,
+		Symbols:         symbols,
+		Interval:        interval,
+		InitialBalance:  initialBalance,
+		BTCETHLeverage:  cfg.Leverage.BTCETHLeverage,
+		AltcoinLeverage: cfg.Leverage.AltcoinLeverage,
+		OutputDir:       *outputDir,
+	}, mcpClient)
+	if err != nil {
+		log.Fatalf("❌ 回测失败: %v", err)
+	}
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("📊 回测完成: %s ~ %s (%d个周期, 失败%d)\n",
+		result.From.Format("2006-01-02 15:04"), result.To.Format("2006-01-02 15:04"), result.Cycles, result.FailedCycles)
+	fmt.Printf("  • 初始资金: %.2f USDT → 最终净值: %.2f USDT (%+.2f%%)\n",
+		result.InitialBalance, result.FinalEquity, result.ReturnPct)
+	fmt.Printf("  • 最大回撤: %.2f%% | 手续费: %.2f USDT | 资金费: %.2f USDT\n",
+		result.MaxDrawdownPct, result.FeesPaid, result.FundingPaid)
+	if p := result.Performance; p != nil {
+		fmt.Printf("  • 交易数: %d | 胜率: %.1f%% | 盈亏比: %.2f | 夏普: %.2f\n",
+			p.TotalTrades, p.WinRate, p.ProfitFactor, p.SharpeRatio)
+	}
+	fmt.Printf("  • 结果目录: %s\n", result.OutputDir)
+	fmt.Println(strings.Repeat("=", 60))
+}
+
+// parseBacktestTime 解析回测时间参数（UTC）
+func parseBacktestTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, fmt.Errorf("不能为空")
+	}
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法解析时间: %s（格式: 2006-01-02 或 2006-01-02T15:04）", s)
+}
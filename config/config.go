@@ -6,7 +6,13 @@ import (
     "encoding/base64"
     "net/http"
     "io"
+    "nofx/exchange"
+    "nofx/market"
+    "nofx/news"
+    "nofx/pool"
+    "nofx/schedule"
     "os"
+    "strings"
     "time"
 )
 
@@ -15,10 +21,11 @@ type TraderConfig struct {
 	ID      string `json:"id"`
 	Name    string `json:"name"`
 	Enabled bool   `json:"enabled"` // 是否启用该trader
-	AIModel string `json:"ai_model"` // "qwen" or "deepseek"
+	AIModel string `json:"ai_model"` // "qwen", "deepseek", "openai", "claude" or "custom"
+	Owner   string `json:"owner"`    // 所属用户名（只有该用户和管理员能查看和操作，连同其交易所和AI密钥；为空表示所有用户共享）
 
 	// 交易平台选择（二选一）
-	Exchange string `json:"exchange"` // "binance" or "hyperliquid"
+	Exchange string `json:"exchange"` // "binance", "binance_spot"（现货）, "hyperliquid", "aster", "okx", "bybit", "dydx" or "paper"（模拟盘）
 
 	// 币安配置
 	BinanceAPIKey    string `json:"binance_api_key,omitempty"`
@@ -34,17 +41,158 @@ type TraderConfig struct {
 	AsterSigner     string `json:"aster_signer,omitempty"`      // Aster API钱包地址
 	AsterPrivateKey string `json:"aster_private_key,omitempty"` // Aster API钱包私钥
 
+	// OKX配置
+	OKXAPIKey     string `json:"okx_api_key,omitempty"`
+	OKXSecretKey  string `json:"okx_secret_key,omitempty"`
+	OKXPassphrase string `json:"okx_passphrase,omitempty"`
+	OKXTestnet    bool   `json:"okx_testnet,omitempty"` // 使用OKX模拟盘
+
+	// Bybit配置
+	BybitAPIKey    string `json:"bybit_api_key,omitempty"`
+	BybitSecretKey string `json:"bybit_secret_key,omitempty"`
+	BybitTestnet   bool   `json:"bybit_testnet,omitempty"`
+
+	// dYdX v4配置
+	DydxMnemonic   string `json:"dydx_mnemonic,omitempty"`   // 钱包助记词（用于派生dydx1...地址并签名交易）
+	DydxSubaccount int    `json:"dydx_subaccount,omitempty"` // 子账户编号，默认0
+	DydxTestnet    bool   `json:"dydx_testnet,omitempty"`
+	DydxNodeURL    string `json:"dydx_node_url,omitempty"` // 验证节点REST地址，默认使用公共节点
+
 	// AI配置
 	QwenKey     string `json:"qwen_key,omitempty"`
 	DeepSeekKey string `json:"deepseek_key,omitempty"`
 
+	// OpenAI配置（base_url可指向Azure等OpenAI兼容网关）
+	OpenAIKey     string `json:"openai_key,omitempty"`
+	OpenAIBaseURL string `json:"openai_base_url,omitempty"` // 默认 https://api.openai.com/v1
+	OpenAIModel   string `json:"openai_model,omitempty"`    // 默认 gpt-4o，可选 o1 等
+
+	// Anthropic Claude配置（原生messages接口）
+	ClaudeKey   string `json:"claude_key,omitempty"`
+	ClaudeModel string `json:"claude_model,omitempty"` // 默认 claude-sonnet-4-5
+
 	// 自定义AI API配置（支持任何OpenAI格式的API）
 	CustomAPIURL    string `json:"custom_api_url,omitempty"`
 	CustomAPIKey    string `json:"custom_api_key,omitempty"`
 	CustomModelName string `json:"custom_model_name,omitempty"`
 
+	// 流式输出AI响应（思维链通过SSE/WebSocket实时推送，超时改为两段输出之间的空闲超时）
+	AIStream bool `json:"ai_stream,omitempty"`
+
+	// 结构化输出：通过函数调用（OpenAI tools / Claude tool_use）获取决策，不支持或调用失败时回退到文本解析；开启后不再流式输出
+	StructuredOutput bool `json:"structured_output,omitempty"`
+
+	// 备用AI模型链（使用上面对应的密钥，最多4个）：主AI熔断或服务故障时按顺序切换，为空时不切换
+	FallbackModels []string `json:"fallback_models,omitempty"`
+	BackupAIModel  string   `json:"backup_ai_model,omitempty"` // 只有一个备用模型时的简写，等同于 fallback_models: [model]
+
+	// AI响应缓存（秒，0表示不缓存）：观察模式和模拟盘trader在此时间内复用同一模型对相同prompt（不计时间、周期编号和运行时长）的响应，
+	// 用于批量A/B实验中配置相同的trader分摊AI成本；实盘下单的周期始终不使用缓存
+	AICacheSeconds int `json:"ai_cache_seconds,omitempty"`
+
+	// 观察模式：完整运行决策周期，决策经过验证后按当前价格记录模拟成交，但不向交易所下单（可通过API运行时切换）
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// 下单重试：超时、时间戳偏差、限流等临时性错误导致开平仓失败时的最大重试次数（0使用默认3次，负数表示不重试）
+	OrderRetryAttempts int `json:"order_retry_attempts,omitempty"`
+
+	// 交易复盘：每个周期写入prompt的历史交易复盘条数（0使用默认5条，负数表示不写入）
+	PromptLessons int `json:"prompt_lessons,omitempty"`
+
+	// 相似形态：每个候选币种写入prompt的历史上行情最相似的已平仓交易条数（0使用默认3条，负数表示不检索）
+	SimilarSetups int `json:"similar_setups,omitempty"`
+
+	// 人工审批：仓位金额不低于该值（USDT）的开仓先进入审批队列，通过API或Telegram按钮批准后才执行（0表示不启用）
+	ApprovalThresholdUSD   float64 `json:"approval_threshold_usd,omitempty"`
+	ApprovalTimeoutMinutes int     `json:"approval_timeout_minutes,omitempty"` // 审批有效期（分钟），超时视为拒绝，默认15
+
+	// AI调用预算：当日估算成本（美元）达到上限后暂停AI决策周期，次日自动恢复（0表示不限制）
+	MaxDailyAICost float64 `json:"max_daily_ai_cost,omitempty"`
+	// 价格表中没有的模型（如自定义API）按此价格估算成本（美元/百万token）
+	AIInputPrice  float64 `json:"ai_input_price,omitempty"`
+	AIOutputPrice float64 `json:"ai_output_price,omitempty"`
+
 	InitialBalance      float64 `json:"initial_balance"`
 	ScanIntervalMinutes int     `json:"scan_interval_minutes"`
+
+	// 分析使用的K线周期（默认3m + 4h，波段交易可用如15m + 1d），周期名称会写入prompt
+	IntradayInterval   string `json:"intraday_interval,omitempty"`
+	LongerTermInterval string `json:"longer_term_interval,omitempty"`
+
+	// 自适应扫描间隔（空仓且市场平静时自动延长扫描间隔，节省AI调用）
+	AdaptiveInterval       bool    `json:"adaptive_interval,omitempty"`
+	MaxScanIntervalMinutes int     `json:"max_scan_interval_minutes,omitempty"` // 自适应模式下的最大扫描间隔（默认为基础间隔的4倍）
+	QuietVolatilityPct     float64 `json:"quiet_volatility_pct,omitempty"`      // 判定市场平静的1小时涨跌幅阈值（%，默认1.0）
+
+	// 止损后同向重新开仓冷却（分钟，0表示不限制）
+	StopOutCooldownMinutes int `json:"stop_out_cooldown_minutes,omitempty"`
+
+	// 限价/post_only开仓单超时未成交自动撤单（分钟，默认为一个扫描间隔）
+	LimitOrderTTLMinutes int `json:"limit_order_ttl_minutes,omitempty"`
+
+	// 决策策略: ai（默认）| rule（规则扫描，不调用AI）| hybrid（AI决策+规则信号过滤开仓）| ensemble（多模型投票），可通过API运行时切换
+	Strategy string `json:"strategy,omitempty"`
+
+	// 委员会模式（strategy=ensemble）：参与投票的模型（2-5个，使用上面对应的密钥）和执行交易所需的同意票数（默认过半数）
+	EnsembleModels []string `json:"ensemble_models,omitempty"`
+	EnsembleQuorum int      `json:"ensemble_quorum,omitempty"`
+
+	// 候选币种来源（为空时使用AI500前20 + OI Top），多个来源按weight比例分配coin_pool_size个候选名额
+	CoinSources  []pool.SourceConfig `json:"coin_sources,omitempty"`
+	CoinPoolSize int                 `json:"coin_pool_size,omitempty"` // 默认20
+
+	// 币种黑白名单（白名单非空时只交易白名单中的币种，黑名单中的币种禁止开仓），可通过API运行时修改
+	SymbolWhitelist []string `json:"symbol_whitelist,omitempty"`
+	SymbolBlacklist []string `json:"symbol_blacklist,omitempty"`
+
+	// 交易时段（时段外禁止开仓，空仓时跳过决策周期），可通过API运行时修改
+	Schedule schedule.Schedule `json:"schedule,omitempty"`
+
+	// 风险控制（覆盖全局的max_daily_loss/max_drawdown/stop_trading_minutes，0表示使用全局配置）
+	MaxDailyLoss       float64 `json:"max_daily_loss,omitempty"`
+	MaxDrawdown        float64 `json:"max_drawdown,omitempty"`
+	StopTradingMinutes int     `json:"stop_trading_minutes,omitempty"`
+
+	// 连续亏损冷却（独立于日亏损限制）：连续亏损max_consecutive_losses笔，或loss_window_minutes分钟内
+	// 已平仓亏损达到净值的loss_window_pct%时，暂停开仓loss_cooldown_minutes分钟，可通过风控API运行时修改
+	MaxConsecutiveLosses int     `json:"max_consecutive_losses,omitempty"`
+	LossWindowPct        float64 `json:"loss_window_pct,omitempty"`
+	LossWindowMinutes    int     `json:"loss_window_minutes,omitempty"`
+	LossCooldownMinutes  int     `json:"loss_cooldown_minutes,omitempty"`
+
+	// 相关性敞口限制：开仓后同方向与新币种72小时收益率相关系数≥correlation_threshold（默认0.8）的仓位
+	// 超过max_correlated_positions个时拒绝开仓，可通过风控API运行时修改
+	MaxCorrelatedPositions int     `json:"max_correlated_positions,omitempty"`
+	CorrelationThreshold   float64 `json:"correlation_threshold,omitempty"`
+
+	// 集中度限制（按账户净值的倍数，0表示不限制）：单币种名义价值不超过max_symbol_exposure倍净值，
+	// 多头减空头的净名义价值不超过max_net_exposure倍净值；下单前检查，可通过风控API运行时修改
+	MaxSymbolExposure float64 `json:"max_symbol_exposure,omitempty"`
+	MaxNetExposure    float64 `json:"max_net_exposure,omitempty"`
+
+	// 强平距离保护：止损价与按交易所杠杆分层估算的强平价之间至少相距入场价的min_liquidation_buffer%，
+	// 不满足（含止损超出强平价）时降低杠杆，1倍杠杆也不满足时拒绝开仓；可通过风控API运行时修改
+	MinLiquidationBuffer float64 `json:"min_liquidation_buffer,omitempty"`
+
+	// Telegram通知（开平仓、止损止盈、风控触发、AI连续失败时推送）
+	TelegramBotToken string `json:"telegram_bot_token,omitempty"`
+	TelegramChatID   string `json:"telegram_chat_id,omitempty"`
+
+	// 出站Webhook（每个执行的决策POST一次签名JSON，便于接入外部分析/交易日志工具）
+	WebhookURL    string `json:"webhook_url,omitempty"`
+	WebhookSecret string `json:"webhook_secret,omitempty"` // HMAC-SHA256签名密钥（可选）
+
+	// 影子trader：每个周期使用相同的候选币种，换一个模型或prompt在模拟盘上决策，用于切换模型前对比表现
+	Shadow *ShadowConfig `json:"shadow,omitempty"`
+}
+
+// ShadowConfig 影子trader与主trader的区别（未设置的项沿用主trader，模型密钥也沿用主trader的配置）
+type ShadowConfig struct {
+	AIModel      string `json:"ai_model,omitempty"`      // deepseek / qwen / openai / claude / custom
+	Model        string `json:"model,omitempty"`         // 模型名称（覆盖openai_model、claude_model或custom_model_name）
+	Strategy     string `json:"strategy,omitempty"`      // ai / rule / hybrid / ensemble
+	SystemPrompt string `json:"system_prompt,omitempty"` // 自定义System Prompt模板
+	UserPrompt   string `json:"user_prompt,omitempty"`   // 自定义User Prompt模板
 }
 
 // LeverageConfig 杠杆配置
@@ -53,6 +201,72 @@ type LeverageConfig struct {
 	AltcoinLeverage int `json:"altcoin_leverage"` // 山寨币的杠杆倍数（主账户建议5-20，子账户≤5）
 }
 
+// PortfolioLimits 组合风控（按交易所账户汇总所有trader的持仓和未成交限价单，0表示不限制）
+type PortfolioLimits struct {
+	MaxSymbolNotional float64            `json:"max_symbol_notional"`  // 单个币种的合计名义价值上限（USDT）
+	SymbolNotional    map[string]float64 `json:"symbol_notional"`      // 指定币种的名义价值上限（覆盖max_symbol_notional）
+	MaxTotalMarginPct float64            `json:"max_total_margin_pct"` // 合计保证金占账户净值的上限（%）
+}
+
+// IsEmpty 是否未设置任何组合限制
+func (p PortfolioLimits) IsEmpty() bool {
+	return p.MaxSymbolNotional == 0 && len(p.SymbolNotional) == 0 && p.MaxTotalMarginPct == 0
+}
+
+// SymbolLimit 币种的名义价值上限（0表示不限制）
+func (p PortfolioLimits) SymbolLimit(symbol string) float64 {
+	if limit, ok := p.SymbolNotional[symbol]; ok {
+		return limit
+	}
+	return p.MaxSymbolNotional
+}
+
+// APILimits API服务器的限流和请求大小限制
+type APILimits struct {
+	RequestsPerMinute int      `json:"requests_per_minute"` // 每个IP、每个登录用户/API Key每分钟的请求数（默认600，-1表示不限流）
+	Burst             int      `json:"burst"`               // 允许的突发请求数（默认100）
+	MaxBodyKB         int      `json:"max_body_kb"`         // 请求体大小上限（KB，默认1024）
+	TrustedProxies    []string `json:"trusted_proxies"`     // 信任其X-Forwarded-For的反向代理（默认本机和内网地址）
+}
+
+// AIBreaker AI提供商熔断：同一提供商连续失败达到阈值后熔断，冷却期内所有trader直接切换到备用AI（未配置备用AI时跳过本周期）
+type AIBreaker struct {
+	FailureThreshold int `json:"failure_threshold"` // 连续失败多少次后熔断（默认3，-1表示不熔断）
+	CooldownSeconds  int `json:"cooldown_seconds"`  // 熔断多久后放行一次试探请求（默认60秒）
+}
+
+// Cooldown 熔断冷却时间
+func (b AIBreaker) Cooldown() time.Duration {
+	return time.Duration(b.CooldownSeconds) * time.Second
+}
+
+// Competition 竞赛赛季：到达结束时间后自动结算（平仓、保存最终排行榜、归档决策日志）并以新的初始余额重新开始（start为空表示不启用）
+type Competition struct {
+	Name   string `json:"name"`   // 赛季名称（默认 "Season"，第N个赛季显示为 "Season N"）
+	Start  string `json:"start"`  // 第一个赛季的开始时间（RFC3339）
+	End    string `json:"end"`    // 第一个赛季的结束时间（RFC3339）
+	Repeat bool   `json:"repeat"` // 赛季结束后按相同时长自动开始下一个赛季
+}
+
+// Enabled 是否启用赛季
+func (c Competition) Enabled() bool {
+	return c.Start != ""
+}
+
+// Bounds 第一个赛季的开始和结束时间
+func (c Competition) Bounds() (start, end time.Time, err error) {
+	if start, err = time.Parse(time.RFC3339, c.Start); err != nil {
+		return start, end, fmt.Errorf("competition.start格式错误（需为RFC3339，如 2025-01-01T00:00:00Z）: %w", err)
+	}
+	if end, err = time.Parse(time.RFC3339, c.End); err != nil {
+		return start, end, fmt.Errorf("competition.end格式错误（需为RFC3339，如 2025-01-08T00:00:00Z）: %w", err)
+	}
+	if !end.After(start) {
+		return start, end, fmt.Errorf("competition.end必须晚于competition.start")
+	}
+	return start, end, nil
+}
+
 // Config 总配置
 type Config struct {
 	Traders            []TraderConfig `json:"traders"`
@@ -65,6 +279,43 @@ type Config struct {
 	MaxDrawdown        float64        `json:"max_drawdown"`
 	StopTradingMinutes int            `json:"stop_trading_minutes"`
 	Leverage           LeverageConfig `json:"leverage"` // 杠杆配置
+	LogFormat          string         `json:"log_format"` // 日志格式: "text"（默认）或 "json"（便于接入日志平台）
+	LogLevel           string         `json:"log_level"`  // 日志级别: "debug", "info"（默认）, "warn", "error"
+	DatabasePath       string         `json:"database_path"` // 决策日志数据库（默认 decision_logs/nofx.db）
+	MarketDataSource   string         `json:"market_data_source"` // 行情来源: "websocket"（默认，Binance组合流实时维护）或 "rest"（每次查询时请求REST接口）
+
+	// 组合风控：限制共用同一交易所账户的所有trader的合计敞口
+	PortfolioLimits PortfolioLimits `json:"portfolio_limits"`
+
+	// 退出
+	ShutdownTimeoutSeconds int  `json:"shutdown_timeout_seconds"`  // 退出时等待当前决策周期完成的最长时间（默认60秒）
+	CancelOrdersOnShutdown bool `json:"cancel_orders_on_shutdown"` // 退出时撤销止损止盈单和限价开仓单（默认保留）
+	DrainSeconds           int  `json:"drain_seconds"`             // 收到退出信号后先将/readyz置为未就绪，等待编排系统摘除流量的秒数（默认0）
+
+	// 配置热加载（收到SIGHUP时也会立即重新加载）
+	ConfigReloadSeconds int `json:"config_reload_seconds"` // 检查配置文件是否修改的间隔（默认10秒，-1表示只在收到SIGHUP时重新加载）
+
+	// 竞赛排行榜
+	LeaderboardMetric          string `json:"leaderboard_metric"`           // 默认排序指标: "pnl_pct"（默认）, "sharpe", "max_drawdown", "win_rate", "alpha"
+	LeaderboardSnapshotMinutes int    `json:"leaderboard_snapshot_minutes"` // 排行榜快照间隔（默认15分钟）
+
+	// 竞赛赛季（默认不启用）
+	Competition Competition `json:"competition"`
+
+	// 新闻和宏观事件（默认不抓取新闻、不暂停开仓）
+	News news.Config `json:"news"`
+
+	// API限流
+	APILimits APILimits `json:"api_limits"`
+
+	// AI提供商熔断
+	AIBreaker AIBreaker `json:"ai_breaker"`
+
+	// 登录鉴权
+	AdminMode     bool   `json:"admin_mode"`     // 管理员模式：API无需登录（未配置jwt_secret时自动启用）
+	JWTSecret     string `json:"jwt_secret"`     // JWT签名密钥（至少32个字符）
+	UsersFile     string `json:"users_file"`     // 用户文件（默认 users.json）
+	AllowRegister bool   `json:"allow_register"` // 是否开放注册（没有任何用户时始终允许注册第一个账号）
 }
 
 // LoadConfig 从文件加载配置
@@ -121,6 +372,11 @@ func LoadConfig(filename string) (*Config, error) {
         return nil, fmt.Errorf("解析配置失败: %w", err)
     }
 
+	// NOFX_* 环境变量优先于配置文件（部署时密钥不必写进config.json）
+	if err := applyEnvOverrides(&config); err != nil {
+		return nil, err
+	}
+
 	// 设置默认值：如果use_default_coins未设置（为false）且没有配置coin_pool_api_url，则默认使用默认币种列表
 	if !config.UseDefaultCoins && config.CoinPoolAPIURL == "" {
 		config.UseDefaultCoins = true
@@ -155,72 +411,101 @@ func (c *Config) Validate() error {
 	}
 
 	traderIDs := make(map[string]bool)
-	for i, trader := range c.Traders {
-		if trader.ID == "" {
-			return fmt.Errorf("trader[%d]: ID不能为空", i)
-		}
+	for i := range c.Traders {
+		trader := &c.Traders[i] // 使用指针，确保默认值写回配置
 		if traderIDs[trader.ID] {
 			return fmt.Errorf("trader[%d]: ID '%s' 重复", i, trader.ID)
 		}
 		traderIDs[trader.ID] = true
 
-		if trader.Name == "" {
-			return fmt.Errorf("trader[%d]: Name不能为空", i)
-		}
-		if trader.AIModel != "qwen" && trader.AIModel != "deepseek" && trader.AIModel != "custom" {
-			return fmt.Errorf("trader[%d]: ai_model必须是 'qwen', 'deepseek' 或 'custom'", i)
+		if err := trader.Validate(); err != nil {
+			return fmt.Errorf("trader[%d]: %w", i, err)
 		}
+	}
 
-		// 验证交易平台配置
-		if trader.Exchange == "" {
-			trader.Exchange = "binance" // 默认使用币安
-		}
-		if trader.Exchange != "binance" && trader.Exchange != "hyperliquid" && trader.Exchange != "aster" {
-			return fmt.Errorf("trader[%d]: exchange必须是 'binance', 'hyperliquid' 或 'aster'", i)
-		}
+	if c.APIServerPort <= 0 {
+		c.APIServerPort = 8080 // 默认8080端口
+	}
 
-		// 根据平台验证对应的密钥
-		if trader.Exchange == "binance" {
-			if trader.BinanceAPIKey == "" || trader.BinanceSecretKey == "" {
-				return fmt.Errorf("trader[%d]: 使用币安时必须配置binance_api_key和binance_secret_key", i)
-			}
-		} else if trader.Exchange == "hyperliquid" {
-			if trader.HyperliquidPrivateKey == "" {
-				return fmt.Errorf("trader[%d]: 使用Hyperliquid时必须配置hyperliquid_private_key", i)
-			}
-		} else if trader.Exchange == "aster" {
-			if trader.AsterUser == "" || trader.AsterSigner == "" || trader.AsterPrivateKey == "" {
-				return fmt.Errorf("trader[%d]: 使用Aster时必须配置aster_user, aster_signer和aster_private_key", i)
-			}
+	// 登录鉴权：未配置jwt_secret时保持原有的开放访问（管理员模式）
+	if !c.AdminMode {
+		if c.JWTSecret == "" {
+			c.AdminMode = true
+			fmt.Printf("⚠️  警告: 未配置jwt_secret，以管理员模式运行（API无需登录）\n")
+		} else if len(c.JWTSecret) < 32 {
+			return fmt.Errorf("jwt_secret至少需要32个字符")
 		}
+	}
+	if c.UsersFile == "" {
+		c.UsersFile = "users.json"
+	}
 
-		if trader.AIModel == "qwen" && trader.QwenKey == "" {
-			return fmt.Errorf("trader[%d]: 使用Qwen时必须配置qwen_key", i)
-		}
-		if trader.AIModel == "deepseek" && trader.DeepSeekKey == "" {
-			return fmt.Errorf("trader[%d]: 使用DeepSeek时必须配置deepseek_key", i)
-		}
-		if trader.AIModel == "custom" {
-			if trader.CustomAPIURL == "" {
-				return fmt.Errorf("trader[%d]: 使用自定义API时必须配置custom_api_url", i)
-			}
-			if trader.CustomAPIKey == "" {
-				return fmt.Errorf("trader[%d]: 使用自定义API时必须配置custom_api_key", i)
-			}
-			if trader.CustomModelName == "" {
-				return fmt.Errorf("trader[%d]: 使用自定义API时必须配置custom_model_name", i)
-			}
+	if c.APILimits.RequestsPerMinute == 0 {
+		c.APILimits.RequestsPerMinute = 600
+	}
+	if c.APILimits.Burst <= 0 {
+		c.APILimits.Burst = 100
+	}
+	if c.APILimits.MaxBodyKB <= 0 {
+		c.APILimits.MaxBodyKB = 1024
+	}
+	if len(c.APILimits.TrustedProxies) == 0 {
+		c.APILimits.TrustedProxies = []string{"127.0.0.1/8", "::1/128", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"}
+	}
+
+	if c.AIBreaker.FailureThreshold == 0 {
+		c.AIBreaker.FailureThreshold = 3
+	}
+	if c.AIBreaker.CooldownSeconds <= 0 {
+		c.AIBreaker.CooldownSeconds = 60
+	}
+
+	switch c.LeaderboardMetric {
+	case "":
+		c.LeaderboardMetric = "pnl_pct"
+	case "pnl_pct", "sharpe", "max_drawdown", "win_rate", "alpha":
+	default:
+		return fmt.Errorf("leaderboard_metric必须是 'pnl_pct', 'sharpe', 'max_drawdown', 'win_rate' 或 'alpha'")
+	}
+	if c.LeaderboardSnapshotMinutes <= 0 {
+		c.LeaderboardSnapshotMinutes = 15
+	}
+	if c.Competition.Enabled() {
+		if _, _, err := c.Competition.Bounds(); err != nil {
+			return err
 		}
-		if trader.InitialBalance <= 0 {
-			return fmt.Errorf("trader[%d]: initial_balance必须大于0", i)
+		if c.Competition.Name == "" {
+			c.Competition.Name = "Season"
 		}
-		if trader.ScanIntervalMinutes <= 0 {
-			trader.ScanIntervalMinutes = 3 // 默认3分钟
+	}
+	if err := c.News.Validate(); err != nil {
+		return err
+	}
+	if c.ShutdownTimeoutSeconds <= 0 {
+		c.ShutdownTimeoutSeconds = 60
+	}
+	if c.DrainSeconds < 0 {
+		return fmt.Errorf("drain_seconds不能为负数")
+	}
+	if c.ConfigReloadSeconds == 0 {
+		c.ConfigReloadSeconds = 10
+	}
+
+	if c.PortfolioLimits.MaxSymbolNotional < 0 || c.PortfolioLimits.MaxTotalMarginPct < 0 || c.PortfolioLimits.MaxTotalMarginPct > 100 {
+		return fmt.Errorf("portfolio_limits: max_symbol_notional不能为负数，max_total_margin_pct必须在0-100之间")
+	}
+	for symbol, limit := range c.PortfolioLimits.SymbolNotional {
+		if limit < 0 {
+			return fmt.Errorf("portfolio_limits.symbol_notional: %s 的上限不能为负数", symbol)
 		}
 	}
 
-	if c.APIServerPort <= 0 {
-		c.APIServerPort = 8080 // 默认8080端口
+	switch c.MarketDataSource {
+	case "":
+		c.MarketDataSource = "websocket"
+	case "websocket", "rest":
+	default:
+		return fmt.Errorf("market_data_source必须是 'websocket' 或 'rest'")
 	}
 
 	// 设置杠杆默认值（适配币安子账户限制，最大5倍）
@@ -240,7 +525,347 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// Validate 校验单个trader的配置并填充默认值（ID是否重复由Config.Validate检查）
+func (tc *TraderConfig) Validate() error {
+	if tc.ID == "" {
+		return fmt.Errorf("ID不能为空")
+	}
+	if tc.Name == "" {
+		return fmt.Errorf("Name不能为空")
+	}
+	if tc.AIModel != "qwen" && tc.AIModel != "deepseek" && tc.AIModel != "openai" &&
+		tc.AIModel != "claude" && tc.AIModel != "custom" {
+		return fmt.Errorf("ai_model必须是 'qwen', 'deepseek', 'openai', 'claude' 或 'custom'")
+	}
+
+	// 验证交易平台配置
+	if tc.Exchange == "" {
+		tc.Exchange = "binance" // 默认使用币安
+	}
+	if _, ok := exchange.Lookup(tc.Exchange); !ok {
+		return fmt.Errorf("exchange必须是以下之一: %s", strings.Join(exchange.Names(), ", "))
+	}
+
+	// 由交易所自己校验密钥等必填配置
+	if err := exchange.Validate(tc.Exchange, tc.ExchangeConfig()); err != nil {
+		return err
+	}
+
+	if tc.AIModel == "qwen" && tc.QwenKey == "" {
+		return fmt.Errorf("使用Qwen时必须配置qwen_key")
+	}
+	if tc.AIModel == "deepseek" && tc.DeepSeekKey == "" {
+		return fmt.Errorf("使用DeepSeek时必须配置deepseek_key")
+	}
+	if tc.AIModel == "openai" && tc.OpenAIKey == "" {
+		return fmt.Errorf("使用OpenAI时必须配置openai_key")
+	}
+	if tc.AIModel == "claude" && tc.ClaudeKey == "" {
+		return fmt.Errorf("使用Claude时必须配置claude_key")
+	}
+	if tc.AIModel == "custom" {
+		if tc.CustomAPIURL == "" {
+			return fmt.Errorf("使用自定义API时必须配置custom_api_url")
+		}
+		if tc.CustomAPIKey == "" {
+			return fmt.Errorf("使用自定义API时必须配置custom_api_key")
+		}
+		if tc.CustomModelName == "" {
+			return fmt.Errorf("使用自定义API时必须配置custom_model_name")
+		}
+	}
+	if tc.InitialBalance <= 0 {
+		return fmt.Errorf("initial_balance必须大于0")
+	}
+	if tc.ScanIntervalMinutes <= 0 {
+		tc.ScanIntervalMinutes = 3 // 默认3分钟
+	}
+	if tc.StopOutCooldownMinutes < 0 {
+		return fmt.Errorf("stop_out_cooldown_minutes不能为负数")
+	}
+	if tc.LimitOrderTTLMinutes < 0 {
+		return fmt.Errorf("limit_order_ttl_minutes不能为负数")
+	}
+	if tc.LimitOrderTTLMinutes == 0 {
+		tc.LimitOrderTTLMinutes = tc.ScanIntervalMinutes // 默认一个扫描间隔
+	}
+	if tc.Strategy == "" {
+		tc.Strategy = "ai"
+	}
+	if tc.Strategy != "ai" && tc.Strategy != "rule" && tc.Strategy != "hybrid" && tc.Strategy != "ensemble" {
+		return fmt.Errorf("strategy必须是 'ai', 'rule', 'hybrid' 或 'ensemble'")
+	}
+	if err := tc.GetTimeframes().Validate(); err != nil {
+		return err
+	}
+	if err := tc.validateFallbackModels(); err != nil {
+		return err
+	}
+	if len(tc.EnsembleModels) > 0 || tc.Strategy == "ensemble" {
+		if err := tc.validateEnsemble(); err != nil {
+			return err
+		}
+	}
+	if tc.Shadow != nil {
+		if err := tc.validateShadow(); err != nil {
+			return err
+		}
+	}
+	if err := pool.ValidateSources(tc.CoinSources); err != nil {
+		return err
+	}
+	if tc.CoinPoolSize < 0 {
+		return fmt.Errorf("coin_pool_size不能为负数")
+	}
+	if err := tc.GetSymbolFilter().Validate(); err != nil {
+		return err
+	}
+	if err := tc.Schedule.Validate(); err != nil {
+		return fmt.Errorf("schedule: %w", err)
+	}
+	if tc.MaxDailyLoss < 0 || tc.MaxDrawdown < 0 || tc.StopTradingMinutes < 0 {
+		return fmt.Errorf("max_daily_loss/max_drawdown/stop_trading_minutes不能为负数")
+	}
+	if tc.MaxConsecutiveLosses < 0 || tc.LossWindowPct < 0 || tc.LossWindowMinutes < 0 || tc.LossCooldownMinutes < 0 {
+		return fmt.Errorf("max_consecutive_losses/loss_window_pct/loss_window_minutes/loss_cooldown_minutes不能为负数")
+	}
+	if tc.LossWindowPct > 0 && tc.LossWindowMinutes == 0 {
+		return fmt.Errorf("设置loss_window_pct时必须设置loss_window_minutes")
+	}
+	if (tc.MaxConsecutiveLosses > 0 || tc.LossWindowPct > 0) && tc.LossCooldownMinutes == 0 {
+		return fmt.Errorf("启用连续亏损冷却时必须设置loss_cooldown_minutes")
+	}
+	if tc.MaxCorrelatedPositions < 0 {
+		return fmt.Errorf("max_correlated_positions不能为负数")
+	}
+	if tc.CorrelationThreshold < 0 || tc.CorrelationThreshold > 1 {
+		return fmt.Errorf("correlation_threshold必须在0-1之间")
+	}
+	if tc.MaxSymbolExposure < 0 || tc.MaxNetExposure < 0 {
+		return fmt.Errorf("max_symbol_exposure/max_net_exposure不能为负数")
+	}
+	if tc.MinLiquidationBuffer < 0 || tc.MinLiquidationBuffer >= 100 {
+		return fmt.Errorf("min_liquidation_buffer必须在0-100之间")
+	}
+	if tc.ApprovalThresholdUSD < 0 || tc.ApprovalTimeoutMinutes < 0 {
+		return fmt.Errorf("approval_threshold_usd/approval_timeout_minutes不能为负数")
+	}
+	if tc.MaxDailyAICost < 0 || tc.AIInputPrice < 0 || tc.AIOutputPrice < 0 {
+		return fmt.Errorf("max_daily_ai_cost/ai_input_price/ai_output_price不能为负数")
+	}
+	if (tc.TelegramBotToken == "") != (tc.TelegramChatID == "") {
+		return fmt.Errorf("telegram_bot_token和telegram_chat_id必须同时配置")
+	}
+	if tc.WebhookURL != "" && !strings.HasPrefix(tc.WebhookURL, "http://") && !strings.HasPrefix(tc.WebhookURL, "https://") {
+		return fmt.Errorf("webhook_url必须以http://或https://开头")
+	}
+	if tc.AdaptiveInterval {
+		if tc.MaxScanIntervalMinutes <= 0 {
+			tc.MaxScanIntervalMinutes = tc.ScanIntervalMinutes * 4 // 默认最多延长到4倍
+		}
+		if tc.MaxScanIntervalMinutes < tc.ScanIntervalMinutes {
+			return fmt.Errorf("max_scan_interval_minutes不能小于scan_interval_minutes")
+		}
+		if tc.QuietVolatilityPct <= 0 {
+			tc.QuietVolatilityPct = 1.0 // 默认1小时涨跌幅<1%视为平静
+		}
+	}
+	return nil
+}
+
+// ExchangeConfig 交易所连接配置（交易所按配置文件中的键名读取自己的配置项）
+func (tc *TraderConfig) ExchangeConfig() exchange.Config {
+	cfg := exchange.Config{TraderID: tc.ID, InitialBalance: tc.InitialBalance}
+	data, err := json.Marshal(tc)
+	if err == nil {
+		_ = json.Unmarshal(data, &cfg.Settings)
+	}
+	return cfg
+}
+
 // GetScanInterval 获取扫描间隔
 func (tc *TraderConfig) GetScanInterval() time.Duration {
 	return time.Duration(tc.ScanIntervalMinutes) * time.Minute
 }
+
+// GetStopOutCooldown 获取止损后同向重新开仓的冷却时长
+func (tc *TraderConfig) GetStopOutCooldown() time.Duration {
+	return time.Duration(tc.StopOutCooldownMinutes) * time.Minute
+}
+
+// GetLimitOrderTTL 获取限价开仓单的挂单有效期
+func (tc *TraderConfig) GetLimitOrderTTL() time.Duration {
+	return time.Duration(tc.LimitOrderTTLMinutes) * time.Minute
+}
+
+// GetAICacheTTL 获取AI响应缓存时间（负数视为不缓存）
+func (tc *TraderConfig) GetAICacheTTL() time.Duration {
+	if tc.AICacheSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(tc.AICacheSeconds) * time.Second
+}
+
+// GetOrderRetryAttempts 获取下单失败后的最大重试次数（未设置时默认3次）
+func (tc *TraderConfig) GetOrderRetryAttempts() int {
+	switch {
+	case tc.OrderRetryAttempts < 0:
+		return 0
+	case tc.OrderRetryAttempts == 0:
+		return 3
+	}
+	return tc.OrderRetryAttempts
+}
+
+// GetPromptLessons 获取每个周期写入prompt的交易复盘条数（未设置时默认5条）
+func (tc *TraderConfig) GetPromptLessons() int {
+	switch {
+	case tc.PromptLessons < 0:
+		return 0
+	case tc.PromptLessons == 0:
+		return 5
+	}
+	return tc.PromptLessons
+}
+
+// GetSimilarSetups 获取每个候选币种写入prompt的历史相似形态条数（未设置时默认3条）
+func (tc *TraderConfig) GetSimilarSetups() int {
+	switch {
+	case tc.SimilarSetups < 0:
+		return 0
+	case tc.SimilarSetups == 0:
+		return 3
+	}
+	return tc.SimilarSetups
+}
+
+// GetApprovalTimeout 获取开仓审批的有效期
+func (tc *TraderConfig) GetApprovalTimeout() time.Duration {
+	return time.Duration(tc.ApprovalTimeoutMinutes) * time.Minute
+}
+
+// GetMaxScanInterval 获取自适应模式下的最大扫描间隔
+func (tc *TraderConfig) GetMaxScanInterval() time.Duration {
+	return time.Duration(tc.MaxScanIntervalMinutes) * time.Minute
+}
+
+// GetTimeframes 获取分析使用的K线周期（未配置的使用默认值）
+func (tc *TraderConfig) GetTimeframes() market.Timeframes {
+	return market.Timeframes{Intraday: tc.IntradayInterval, LongerTerm: tc.LongerTermInterval}.WithDefaults()
+}
+
+// GetSymbolFilter 获取币种黑白名单
+func (tc *TraderConfig) GetSymbolFilter() pool.SymbolFilter {
+	return pool.SymbolFilter{Whitelist: tc.SymbolWhitelist, Blacklist: tc.SymbolBlacklist}.Normalize()
+}
+
+// modelConfigured 模型是否已配置对应的API密钥（known为false表示不支持的模型）
+func (tc *TraderConfig) modelConfigured(model string) (configured, known bool) {
+	switch model {
+	case "qwen":
+		return tc.QwenKey != "", true
+	case "deepseek":
+		return tc.DeepSeekKey != "", true
+	case "openai":
+		return tc.OpenAIKey != "", true
+	case "claude":
+		return tc.ClaudeKey != "", true
+	case "custom":
+		return tc.CustomAPIURL != "" && tc.CustomAPIKey != "" && tc.CustomModelName != "", true
+	}
+	return false, false
+}
+
+// validateShadow 验证影子trader配置（至少要换模型、策略或prompt之一，模型需已配置密钥）
+func (tc *TraderConfig) validateShadow() error {
+	s := tc.Shadow
+	if exchange.IsSpot(tc.Exchange) {
+		return fmt.Errorf("现货trader不支持影子trader")
+	}
+	model := tc.AIModel
+	if s.AIModel != "" {
+		model = s.AIModel
+		configured, known := tc.modelConfigured(model)
+		if !known {
+			return fmt.Errorf("shadow.ai_model必须是 'qwen', 'deepseek', 'openai', 'claude' 或 'custom': %s", model)
+		}
+		if !configured {
+			return fmt.Errorf("影子trader的模型 '%s' 未配置对应的API密钥", model)
+		}
+	}
+	if s.Model != "" && model != "openai" && model != "claude" && model != "custom" {
+		return fmt.Errorf("shadow.model只适用于openai、claude和custom模型")
+	}
+	if s.Strategy != "" && s.Strategy != "ai" && s.Strategy != "rule" && s.Strategy != "hybrid" && s.Strategy != "ensemble" {
+		return fmt.Errorf("shadow.strategy必须是 'ai', 'rule', 'hybrid' 或 'ensemble'")
+	}
+	if s.Strategy == "ensemble" && len(tc.EnsembleModels) == 0 {
+		return fmt.Errorf("影子trader使用ensemble策略时需要配置ensemble_models")
+	}
+	if (s.AIModel == "" || s.AIModel == tc.AIModel) && s.Model == "" && (s.Strategy == "" || s.Strategy == tc.Strategy) &&
+		s.SystemPrompt == "" && s.UserPrompt == "" {
+		return fmt.Errorf("影子trader需要设置与主trader不同的ai_model、model、strategy或prompt")
+	}
+	return nil
+}
+
+// GetFallbackModels 备用AI模型链（未设置fallback_models时使用backup_ai_model）
+func (tc *TraderConfig) GetFallbackModels() []string {
+	if len(tc.FallbackModels) == 0 && tc.BackupAIModel != "" {
+		return []string{tc.BackupAIModel}
+	}
+	return tc.FallbackModels
+}
+
+// validateFallbackModels 验证备用AI模型链（模型不重复、不同于主模型且已配置对应密钥）
+func (tc *TraderConfig) validateFallbackModels() error {
+	if len(tc.FallbackModels) > 0 && tc.BackupAIModel != "" {
+		return fmt.Errorf("fallback_models和backup_ai_model只能设置一个")
+	}
+	models := tc.GetFallbackModels()
+	if len(models) > 4 {
+		return fmt.Errorf("fallback_models最多4个模型，当前: %d", len(models))
+	}
+	seen := map[string]bool{tc.AIModel: true}
+	for _, model := range models {
+		if seen[model] {
+			return fmt.Errorf("备用模型 '%s' 与主模型或其他备用模型重复", model)
+		}
+		seen[model] = true
+
+		configured, known := tc.modelConfigured(model)
+		if !known {
+			return fmt.Errorf("fallback_models中的模型必须是 'qwen', 'deepseek', 'openai', 'claude' 或 'custom': %s", model)
+		}
+		if !configured {
+			return fmt.Errorf("备用模型 '%s' 未配置对应的API密钥", model)
+		}
+	}
+	return nil
+}
+
+// validateEnsemble 验证委员会模式配置（模型不重复且已配置对应密钥）
+func (tc *TraderConfig) validateEnsemble() error {
+	if len(tc.EnsembleModels) < 2 || len(tc.EnsembleModels) > 5 {
+		return fmt.Errorf("ensemble_models需要2-5个模型，当前: %d", len(tc.EnsembleModels))
+	}
+	seen := make(map[string]bool)
+	for _, model := range tc.EnsembleModels {
+		if seen[model] {
+			return fmt.Errorf("ensemble_models中模型 '%s' 重复", model)
+		}
+		seen[model] = true
+
+		configured, known := tc.modelConfigured(model)
+		if !known {
+			return fmt.Errorf("ensemble_models中的模型必须是 'qwen', 'deepseek', 'openai', 'claude' 或 'custom': %s", model)
+		}
+		if !configured {
+			return fmt.Errorf("委员会模型 '%s' 未配置对应的API密钥", model)
+		}
+	}
+	if tc.EnsembleQuorum != 0 && (tc.EnsembleQuorum < 2 || tc.EnsembleQuorum > len(tc.EnsembleModels)) {
+		return fmt.Errorf("ensemble_quorum必须在2到%d之间", len(tc.EnsembleModels))
+	}
+	return nil
+}
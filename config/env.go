@@ -0,0 +1,91 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// envPrefix 覆盖配置的环境变量前缀
+const envPrefix = "NOFX_"
+
+// applyEnvOverrides 用环境变量覆盖配置文件中的值（在校验之前调用，密钥可以只通过环境变量提供）
+// 全局配置项: NOFX_<键名>，如 NOFX_JWT_SECRET、NOFX_DATABASE_PATH、NOFX_MAX_DAILY_LOSS
+// trader配置项: NOFX_TRADER_<trader ID>_<键名>，如 ID为binance-ds的 NOFX_TRADER_BINANCE_DS_DEEPSEEK_KEY
+// 键名和ID转成大写，非字母数字字符替换为下划线；字符串列表可以用逗号分隔，结构体等其余类型用JSON
+func applyEnvOverrides(c *Config) error {
+	overridden, err := overrideFields(reflect.ValueOf(c).Elem(), envPrefix)
+	if err != nil {
+		return err
+	}
+	for i := range c.Traders {
+		prefix := envPrefix + "TRADER_" + envName(c.Traders[i].ID) + "_"
+		names, err := overrideFields(reflect.ValueOf(&c.Traders[i]).Elem(), prefix)
+		if err != nil {
+			return fmt.Errorf("trader[%d]: %w", i, err)
+		}
+		overridden = append(overridden, names...)
+	}
+
+	// 只打印变量名，不打印值（多为密钥）
+	if len(overridden) > 0 {
+		log.Printf("🔧 以下配置项使用环境变量: %s", strings.Join(overridden, ", "))
+	}
+	return nil
+}
+
+// overrideFields 用prefix+键名的环境变量覆盖结构体的字段，返回使用的环境变量名
+func overrideFields(v reflect.Value, prefix string) ([]string, error) {
+	var names []string
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+		name := prefix + envName(key)
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setFieldFromEnv(v.Field(i), raw); err != nil {
+			return nil, fmt.Errorf("环境变量 %s 无效: %w", name, err)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// setFieldFromEnv 把环境变量的值写入字段（字符串直接赋值，逗号分隔的字符串列表拆分，其余按JSON解析）
+func setFieldFromEnv(field reflect.Value, raw string) error {
+	switch {
+	case field.Kind() == reflect.String:
+		field.SetString(raw)
+		return nil
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(raw), "["):
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items).Convert(field.Type()))
+		return nil
+	}
+	return json.Unmarshal([]byte(raw), field.Addr().Interface())
+}
+
+// envName 配置键名或trader ID对应的环境变量名片段（大写，非字母数字字符替换为下划线）
+func envName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"encoding/json"
+	"nofx/logger"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+)
+
+var log = logger.Module("config")
+
+// HotReloadKeys 修改后无需重启即可生效的全局配置项，其余配置项修改后需要重启
+var HotReloadKeys = map[string]bool{
+	"use_default_coins":    true,
+	"default_coins":        true,
+	"coin_pool_api_url":    true,
+	"oi_top_api_url":       true,
+	"max_daily_loss":       true,
+	"max_drawdown":         true,
+	"stop_trading_minutes": true,
+	"log_format":           true,
+	"log_level":            true,
+	"ai_breaker":           true,
+}
+
+// Change 一个配置项的变化（Old/New为JSON值）
+type Change struct {
+	Key string
+	Old string
+	New string
+}
+
+// Diff 比较两份配置，返回可热加载的配置项变化和需要重启才能生效的配置项名称（均按名称排序）
+func Diff(old, new *Config) (changes []Change, restartKeys []string) {
+	oldFields, newFields := configFields(old), configFields(new)
+	for key, newValue := range newFields {
+		oldValue := oldFields[key]
+		if oldValue == newValue {
+			continue
+		}
+		if HotReloadKeys[key] {
+			changes = append(changes, Change{Key: key, Old: oldValue, New: newValue})
+		} else {
+			restartKeys = append(restartKeys, key)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	sort.Strings(restartKeys)
+	return changes, restartKeys
+}
+
+// configFields 配置的顶层字段（JSON键 -> JSON值）
+func configFields(c *Config) map[string]string {
+	data, _ := json.Marshal(c)
+	var raw map[string]json.RawMessage
+	_ = json.Unmarshal(data, &raw)
+	fields := make(map[string]string, len(raw))
+	for key, value := range raw {
+		fields[key] = string(value)
+	}
+	return fields
+}
+
+// Watcher 配置热加载：收到SIGHUP或配置文件修改时间变化时重新加载配置，可热加载的配置项变化交给apply处理
+type Watcher struct {
+	filename string
+	interval time.Duration // 检查文件修改时间的间隔（<=0 表示只响应SIGHUP）
+	current  *Config
+	modTime  time.Time
+	apply    func(cfg *Config, changes []Change)
+}
+
+// NewWatcher 创建配置热加载器（cfg为启动时从filename加载的配置）
+func NewWatcher(filename string, cfg *Config, interval time.Duration, apply func(cfg *Config, changes []Change)) *Watcher {
+	w := &Watcher{filename: configPath(filename), interval: interval, current: cfg, apply: apply}
+	w.modTime, _ = w.fileModTime()
+	return w
+}
+
+// Run 等待SIGHUP或配置文件修改并重新加载，直到stop关闭
+func (w *Watcher) Run(stop <-chan struct{}) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if w.interval > 0 && w.filename != "" {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-hup:
+			log.Printf("🔄 收到SIGHUP，重新加载配置")
+			w.modTime, _ = w.fileModTime()
+			w.Reload()
+		case <-tick:
+			modTime, ok := w.fileModTime()
+			if !ok || modTime.Equal(w.modTime) {
+				continue
+			}
+			w.modTime = modTime
+			log.Printf("🔄 检测到配置文件 %s 已修改，重新加载配置", w.filename)
+			w.Reload()
+		}
+	}
+}
+
+// Reload 重新加载配置并应用变化（加载或校验失败时保留当前配置）
+func (w *Watcher) Reload() {
+	cfg, err := LoadConfig(w.filename)
+	if err != nil {
+		log.Printf("❌ 重新加载配置失败，继续使用当前配置: %v", err)
+		return
+	}
+
+	changes, restartKeys := Diff(w.current, cfg)
+	for _, c := range changes {
+		log.Printf("⚙️  配置已更新: %s = %s（原值 %s）", c.Key, c.New, c.Old)
+	}
+	if len(restartKeys) > 0 {
+		log.Printf("⚠️  以下配置项已修改，需要重启才能生效: %v", restartKeys)
+	}
+	if len(changes) == 0 && len(restartKeys) == 0 {
+		log.Printf("✓ 配置没有变化")
+	} else {
+		recordReloadAudit(changes, restartKeys)
+	}
+
+	// 以最新加载的配置作为比较基准，需要重启的配置项不会重复提示
+	w.current = cfg
+	if len(changes) > 0 {
+		w.apply(cfg, changes)
+	}
+}
+
+// recordReloadAudit 把配置文件的修改写入审计记录（只记录配置项名称，值可能包含密钥）
+func recordReloadAudit(changes []Change, restartKeys []string) {
+	applied := make([]string, 0, len(changes))
+	for _, c := range changes {
+		applied = append(applied, c.Key)
+	}
+	data, _ := json.Marshal(map[string][]string{"applied": applied, "restart_required": restartKeys})
+	err := logger.RecordAudit(logger.AuditEntry{
+		Time:     time.Now(),
+		Username: "system",
+		Action:   "RELOAD config",
+		Changes:  data,
+		Status:   200,
+	})
+	if err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+}
+
+// fileModTime 配置文件的修改时间（配置来自环境变量或URL时不检查文件）
+func (w *Watcher) fileModTime() (time.Time, bool) {
+	if w.filename == "" {
+		return time.Time{}, false
+	}
+	info, err := os.Stat(w.filename)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return info.ModTime(), true
+}
+
+// configPath LoadConfig实际读取的配置文件路径（配置来自环境变量或URL时返回空）
+func configPath(filename string) string {
+	if os.Getenv("CONFIG_JSON") != "" || os.Getenv("CONFIG_JSON_B64") != "" || os.Getenv("CONFIG_URL") != "" {
+		return ""
+	}
+	if path := os.Getenv("CONFIG_PATH"); path != "" {
+		return path
+	}
+	return filename
+}
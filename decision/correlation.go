@@ -0,0 +1,141 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/market"
+	"sort"
+	"strings"
+)
+
+// defaultCorrelationThreshold 未设置阈值时视为高度相关的相关系数
+const defaultCorrelationThreshold = 0.8
+
+// correlationWarnPositions 已有该数量的高度相关持仓时，在prompt中提醒再开仓会进一步集中风险
+const correlationWarnPositions = 2
+
+// correlationBenchmarks 所有币种都与之比较相关性的基准币种
+var correlationBenchmarks = []string{"BTCUSDT", "ETHUSDT"}
+
+// CorrelationLimit 相关性敞口限制：开仓后同一方向与新币种高度相关的仓位（含新仓位）最多MaxPositions个（0表示不限制）
+type CorrelationLimit struct {
+	MaxPositions int     // 同方向高度相关仓位的数量上限（含新开仓位）
+	Threshold    float64 // 相关系数达到该值视为高度相关（0表示默认0.8）
+}
+
+// threshold 生效的相关系数阈值
+func (l CorrelationLimit) threshold() float64 {
+	if l.Threshold > 0 {
+		return l.Threshold
+	}
+	return defaultCorrelationThreshold
+}
+
+// fetchCorrelations 计算持仓、候选币种与BTC/ETH之间的收益率相关性（写入ctx.Correlations）
+func fetchCorrelations(ctx *Context) {
+	seen := make(map[string]bool)
+	var symbols []string
+	add := func(symbol string) {
+		if !seen[symbol] {
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	for _, symbol := range correlationBenchmarks {
+		add(symbol)
+	}
+	for _, pos := range ctx.Positions {
+		add(pos.Symbol)
+	}
+	for symbol := range ctx.MarketDataMap {
+		add(symbol)
+	}
+	ctx.Correlations = market.GetCorrelations(ctx.requestContext(), symbols)
+}
+
+// correlatedPositions 与symbol同方向且高度相关的持仓（按相关系数从高到低，格式如 "SOLUSDT(0.91)"）
+func correlatedPositions(matrix market.CorrelationMatrix, threshold float64, symbol, side string, positions []PositionInfo) []string {
+	type pair struct {
+		symbol string
+		corr   float64
+	}
+	var pairs []pair
+	for _, pos := range positions {
+		if pos.Symbol == symbol || (side != "" && pos.Side != side) {
+			continue
+		}
+		if c, ok := matrix.Get(symbol, pos.Symbol); ok && c >= threshold {
+			pairs = append(pairs, pair{pos.Symbol, c})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].corr > pairs[j].corr })
+
+	result := make([]string, 0, len(pairs))
+	for _, p := range pairs {
+		result = append(result, fmt.Sprintf("%s(%.2f)", p.symbol, p.corr))
+	}
+	return result
+}
+
+// correlationNote 写入prompt的相关性说明：与BTC/ETH的相关系数，以及与现有持仓高度相关时的提醒（没有数据时为空）
+func correlationNote(ctx *Context, symbol string) string {
+	if len(ctx.Correlations) == 0 {
+		return ""
+	}
+
+	var parts []string
+	for _, benchmark := range correlationBenchmarks {
+		if c, ok := ctx.Correlations.Get(symbol, benchmark); ok {
+			parts = append(parts, fmt.Sprintf("%s %.2f", strings.TrimSuffix(benchmark, "USDT"), c))
+		}
+	}
+	note := ""
+	if len(parts) > 0 {
+		note = "72小时收益率相关性: " + strings.Join(parts, " | ")
+	}
+
+	threshold := ctx.CorrelationLimit.threshold()
+	correlated := correlatedPositions(ctx.Correlations, threshold, symbol, "", ctx.Positions)
+	if len(correlated) > 0 {
+		note += fmt.Sprintf("\n⚠️ 与持仓 %s 高度相关（≥%.2f）", strings.Join(correlated, "、"), threshold)
+		if len(correlated) >= correlationWarnPositions {
+			note += fmt.Sprintf("：再开同方向仓位将同时持有%d个高度相关的仓位，相当于放大同一笔押注", len(correlated)+1)
+		}
+	}
+	return strings.TrimPrefix(note, "\n")
+}
+
+// correlationCheck 决策验证时的相关性敞口检查
+type correlationCheck struct {
+	limit  CorrelationLimit
+	matrix market.CorrelationMatrix
+}
+
+// correlationCheck 当前上下文的相关性敞口检查
+func (ctx *Context) correlationCheck() correlationCheck {
+	return correlationCheck{limit: ctx.CorrelationLimit, matrix: ctx.Correlations}
+}
+
+// allows 检查新开仓位后同方向高度相关的仓位是否超过上限（opened为同一批决策中已通过验证的开仓）
+// 没有相关性数据时不限制；对已有持仓加仓不算新仓位
+func (c correlationCheck) allows(d *Decision, positions, opened []PositionInfo) error {
+	if c.limit.MaxPositions <= 0 || len(c.matrix) == 0 {
+		return nil
+	}
+	side := "long"
+	if d.Action == "open_short" {
+		side = "short"
+	}
+	for _, pos := range positions {
+		if pos.Symbol == d.Symbol && pos.Side == side {
+			return nil
+		}
+	}
+
+	threshold := c.limit.threshold()
+	correlated := correlatedPositions(c.matrix, threshold, d.Symbol, side, append(append([]PositionInfo{}, positions...), opened...))
+	if len(correlated)+1 > c.limit.MaxPositions {
+		return fmt.Errorf("%s 与同方向仓位 %s 高度相关（≥%.2f），最多同时持有%d个高度相关的同方向仓位",
+			d.Symbol, strings.Join(correlated, "、"), threshold, c.limit.MaxPositions)
+	}
+	return nil
+}
@@ -1,9 +1,11 @@
 package decision
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"math"
+	"nofx/logger"
 	"nofx/market"
 	"nofx/mcp"
 	"nofx/pool"
@@ -11,6 +13,8 @@ import (
 	"time"
 )
 
+var log = logger.Module("decision")
+
 // PositionInfo 持仓信息
 type PositionInfo struct {
 	Symbol           string  `json:"symbol"`
@@ -23,7 +27,19 @@ type PositionInfo struct {
 	UnrealizedPnLPct float64 `json:"unrealized_pnl_pct"`
 	LiquidationPrice float64 `json:"liquidation_price"`
 	MarginUsed       float64 `json:"margin_used"`
-	UpdateTime       int64   `json:"update_time"` // 持仓更新时间戳（毫秒）
+	MarginMode       string  `json:"margin_mode,omitempty"` // "isolated" / "cross"（交易所未提供时为空）
+	UpdateTime       int64   `json:"update_time"`           // 持仓更新时间戳（毫秒）
+
+	Thesis *PositionThesis `json:"thesis,omitempty"` // 开仓决策的交易逻辑（没有开仓记录时为nil，如在交易所手动开的仓）
+}
+
+// PositionThesis 开仓决策时的交易逻辑（写入后续周期的prompt，供AI判断开仓理由是否仍然成立）
+type PositionThesis struct {
+	Reasoning  string    `json:"reasoning"`
+	StopLoss   float64   `json:"stop_loss"` // 加仓时给出的新止损止盈会覆盖
+	TakeProfit float64   `json:"take_profit"`
+	Confidence int       `json:"confidence"`
+	OpenedAt   time.Time `json:"opened_at"`
 }
 
 // AccountInfo 账户信息
@@ -55,17 +71,44 @@ type OITopData struct {
 
 // Context 交易上下文（传递给AI的完整信息）
 type Context struct {
-	CurrentTime     string                  `json:"current_time"`
-	RuntimeMinutes  int                     `json:"runtime_minutes"`
-	CallCount       int                     `json:"call_count"`
-	Account         AccountInfo             `json:"account"`
-	Positions       []PositionInfo          `json:"positions"`
-	CandidateCoins  []CandidateCoin         `json:"candidate_coins"`
-	MarketDataMap   map[string]*market.Data `json:"-"` // 不序列化，但内部使用
-	OITopDataMap    map[string]*OITopData   `json:"-"` // OI Top数据映射
-	Performance     interface{}             `json:"-"` // 历史表现分析（logger.PerformanceAnalysis）
-	BTCETHLeverage  int                     `json:"-"` // BTC/ETH杠杆倍数（从配置读取）
-	AltcoinLeverage int                     `json:"-"` // 山寨币杠杆倍数（从配置读取）
+	CurrentTime      string                       `json:"current_time"`
+	RuntimeMinutes   int                          `json:"runtime_minutes"`
+	CallCount        int                          `json:"call_count"`
+	Account          AccountInfo                  `json:"account"`
+	Positions        []PositionInfo               `json:"positions"`
+	CandidateCoins   []CandidateCoin              `json:"candidate_coins"`
+	MarketDataMap    map[string]*market.Data      `json:"-"` // 不序列化，但内部使用
+	OrderBookMap     map[string]*market.OrderBook `json:"-"` // 订单簿流动性指标（获取失败的币种没有）
+	OITopDataMap     map[string]*OITopData        `json:"-"` // OI Top数据映射
+	Performance      interface{}                  `json:"-"` // 历史表现分析（logger.PerformanceAnalysis）
+	BTCETHLeverage   int                          `json:"-"` // BTC/ETH杠杆倍数（从配置读取）
+	AltcoinLeverage  int                          `json:"-"` // 山寨币杠杆倍数（从配置读取）
+	Prompts          *PromptTemplates             `json:"-"` // trader自定义的prompt模板（nil时使用默认模板）
+	Timeframes       market.Timeframes            `json:"-"` // 分析使用的K线周期（零值使用默认的3m + 4h）
+	SymbolFilter     pool.SymbolFilter            `json:"-"` // 币种黑白名单（禁止开仓的币种，零值表示不限制）
+	RiskNotices      []string                     `json:"-"` // 当前生效的风控限制说明（如亏损冷却，写入prompt）
+	Spot             bool                         `json:"-"` // 现货交易（使用现货版prompt，不能做空）
+	FundingRates     map[string]float64           `json:"-"` // 交易所自己的资金费率（覆盖市场数据中的币安资金费率，为空时不覆盖）
+	Slippage         map[string]float64           `json:"-"` // 近期平均滑点超过警告阈值的币种（基点，写入prompt提醒AI）
+	Correlations     market.CorrelationMatrix     `json:"-"` // 持仓、候选币种与BTC/ETH之间的收益率相关性（为空时不检查相关性敞口）
+	CorrelationLimit CorrelationLimit             `json:"-"` // 相关性敞口限制（从配置读取）
+	Calibration      []string                     `json:"-"` // 信心度校准摘要（各信心度区间的实际胜率，写入prompt纠正过度自信）
+	Lessons          []string                     `json:"-"` // 与持仓和候选币种最相关的历史交易复盘
+	SetupMemory      SetupMemory                  `json:"-"` // 历史形态（为每个候选币种检索当前行情最相似的历史交易）
+	Regime           *market.Regime               `json:"-"` // 市场状态（由BTC的ATR和EMA斜率判断，可为nil）
+	FearGreed        *market.FearGreed            `json:"-"` // 恐惧贪婪指数（获取失败时为nil）
+	News             []string                     `json:"-"` // 近期新闻标题和即将发布的宏观事件（写入prompt）
+	OnStream         func(delta string)           `json:"-"` // AI流式输出回调（AI客户端开启stream时逐段调用，可为nil）
+	Ctx              context.Context              `json:"-"` // 取消时中止正在进行的行情和AI请求（trader停止时取消，nil表示不可取消）
+	ShareResponses   bool                         `json:"-"` // 允许复用其他trader相同prompt的AI响应（仅观察模式和模拟盘，AI客户端还需开启CacheTTL）
+}
+
+// requestContext 行情和AI请求使用的context（未设置时不可取消）
+func (ctx *Context) requestContext() context.Context {
+	if ctx.Ctx == nil {
+		return context.Background()
+	}
+	return ctx.Ctx
 }
 
 // Decision AI的交易决策
@@ -79,6 +122,22 @@ type Decision struct {
 	Confidence      int     `json:"confidence,omitempty"` // 信心度 (0-100)
 	RiskUSD         float64 `json:"risk_usd,omitempty"`   // 最大美元风险
 	Reasoning       string  `json:"reasoning"`
+
+	TrailingStop *TrailingStop `json:"trailing_stop,omitempty"` // 移动止损（可选，开仓时有效）
+
+	CloseFraction float64 `json:"close_fraction,omitempty"`  // 平仓比例（0-1，平仓时有效；不填或1表示全部平仓）
+	AddToPosition bool    `json:"add_to_position,omitempty"` // 加仓（开仓时有效；已有同向持仓时追加而不是拒绝）
+
+	OrderType  string  `json:"order_type,omitempty"`  // 开仓订单类型: market（默认）| limit | post_only
+	LimitPrice float64 `json:"limit_price,omitempty"` // 限价单挂单价（limit/post_only时必填）
+
+	MarginMode string `json:"margin_mode,omitempty"` // 保证金模式: isolated（默认，逐仓）| cross（全仓），开仓时有效
+}
+
+// TrailingStop 移动止损设置：价格向有利方向运行时，止损价跟随最优价格移动
+type TrailingStop struct {
+	Mode  string  `json:"mode"`  // "percent": 距最优价格的百分比; "atr": 长期周期（默认4小时）ATR的倍数
+	Value float64 `json:"value"` // 百分比或ATR倍数
 }
 
 // FullDecision AI的完整决策（包含思维链）
@@ -91,23 +150,55 @@ type FullDecision struct {
 
 // GetFullDecision 获取AI的完整交易决策（批量分析所有币种和持仓）
 func GetFullDecision(ctx *Context, mcpClient *mcp.Client) (*FullDecision, error) {
-	// 1. 为所有币种获取市场数据
-	if err := fetchMarketDataForContext(ctx); err != nil {
-		return nil, fmt.Errorf("获取市场数据失败: %w", err)
+	// 1. 为所有币种获取市场数据（调用方已预先填充时跳过，例如回测使用历史数据）
+	if len(ctx.MarketDataMap) == 0 {
+		if err := fetchMarketDataForContext(ctx); err != nil {
+			return nil, fmt.Errorf("获取市场数据失败: %w", err)
+		}
+	}
+	if ctx.OITopDataMap == nil {
+		ctx.OITopDataMap = make(map[string]*OITopData)
 	}
 
-	// 2. 构建 System Prompt（固定规则）和 User Prompt（动态数据）
-	systemPrompt := buildSystemPrompt(ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage)
-	userPrompt := buildUserPrompt(ctx)
+	// 2. 按模板构建 System Prompt（固定规则）和 User Prompt（动态数据）
+	systemPrompt, userPrompt, err := buildPrompts(ctx, ctx.Prompts)
+	if err != nil {
+		return nil, fmt.Errorf("构建prompt失败: %w", err)
+	}
+
+	// 相同prompt的AI响应可以在trader之间复用时，带上不含时间等易变内容的缓存键
+	aiCtx := ctx.requestContext()
+	if ctx.ShareResponses && mcpClient.CacheTTL > 0 {
+		if key, err := responseCacheKey(ctx); err == nil {
+			aiCtx = mcp.WithCacheKey(aiCtx, key)
+		}
+	}
+
+	// 3. 开启结构化输出且模型支持函数调用时，直接获取结构化决策（调用失败回退到文本解析）
+	if mcpClient.StructuredOutput && mcpClient.SupportsTools() {
+		decision, err := getStructuredDecision(aiCtx, ctx, mcpClient, systemPrompt, userPrompt)
+		if decision != nil {
+			if err != nil {
+				return nil, fmt.Errorf("解析AI响应失败: %w", err)
+			}
+			decision.Timestamp = time.Now()
+			decision.UserPrompt = userPrompt
+			return decision, nil
+		}
+		log.Printf("⚠️  结构化输出失败，回退到文本解析: %v", err)
+	}
 
-	// 3. 调用AI API（使用 system + user prompt）
-	aiResponse, err := mcpClient.CallWithMessages(systemPrompt, userPrompt)
+	// 4. 调用AI API（使用 system + user prompt）
+	aiResponse, err := mcpClient.CallWithMessagesStream(aiCtx, systemPrompt, userPrompt, ctx.OnStream)
 	if err != nil {
 		return nil, fmt.Errorf("调用AI API失败: %w", err)
 	}
 
-	// 4. 解析AI响应
-	decision, err := parseFullDecisionResponse(aiResponse, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage)
+	// 5. 解析AI响应
+	repair := func(response string, parseErr error) (string, error) {
+		return requestRepair(ctx.requestContext(), mcpClient, response, parseErr)
+	}
+	decision, err := parseFullDecisionResponse(aiResponse, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage, ctx.Positions, ctx.SymbolFilter, ctx.correlationCheck(), repair)
 	if err != nil {
 		return nil, fmt.Errorf("解析AI响应失败: %w", err)
 	}
@@ -117,9 +208,13 @@ func GetFullDecision(ctx *Context, mcpClient *mcp.Client) (*FullDecision, error)
 	return decision, nil
 }
 
-// fetchMarketDataForContext 为上下文中的所有币种获取市场数据和OI数据
+// maxDepthSpreadBps 候选币种允许的最大深度加权价差（基点，超过视为流动性不足）
+const maxDepthSpreadBps = 30
+
+// fetchMarketDataForContext 为上下文中的所有币种获取市场数据、订单簿和OI数据
 func fetchMarketDataForContext(ctx *Context) error {
 	ctx.MarketDataMap = make(map[string]*market.Data)
+	ctx.OrderBookMap = make(map[string]*market.OrderBook)
 	ctx.OITopDataMap = make(map[string]*OITopData)
 
 	// 收集所有需要获取数据的币种
@@ -139,25 +234,24 @@ func fetchMarketDataForContext(ctx *Context) error {
 		symbolSet[coin.Symbol] = true
 	}
 
-	// 并发获取市场数据
 	// 持仓币种集合（用于判断是否跳过OI检查）
 	positionSymbols := make(map[string]bool)
 	for _, pos := range ctx.Positions {
 		positionSymbols[pos.Symbol] = true
 	}
 
+	symbols := make([]string, 0, len(symbolSet))
 	for symbol := range symbolSet {
-		data, err := market.Get(symbol)
-		if err != nil {
-			// 单个币种失败不影响整体，只记录错误
-			continue
-		}
+		symbols = append(symbols, symbol)
+	}
 
+	// 并发获取市场数据（单个币种失败不影响整体）
+	start := time.Now()
+	for symbol, data := range market.GetBatch(ctx.requestContext(), symbols, ctx.Timeframes) {
 		// ⚠️ 流动性过滤：持仓价值低于15M USD的币种不做（多空都不做）
 		// 持仓价值 = 持仓量 × 当前价格
 		// 但现有持仓必须保留（需要决策是否平仓）
-		isExistingPosition := positionSymbols[symbol]
-		if !isExistingPosition && data.OpenInterest != nil && data.CurrentPrice > 0 {
+		if !positionSymbols[symbol] && data.OpenInterest != nil && data.CurrentPrice > 0 {
 			// 计算持仓价值（USD）= 持仓量 × 当前价格
 			oiValue := data.OpenInterest.Latest * data.CurrentPrice
 			oiValueInMillions := oiValue / 1_000_000 // 转换为百万美元单位
@@ -167,10 +261,39 @@ func fetchMarketDataForContext(ctx *Context) error {
 				continue
 			}
 		}
-
+		// 非币安交易所的资金费率与币安不同，使用实际交易所的费率
+		if rate, ok := ctx.FundingRates[symbol]; ok {
+			copied := *data
+			copied.FundingRate = rate
+			data = &copied
+		}
 		ctx.MarketDataMap[symbol] = data
 	}
 
+	// 并发获取订单簿：价差和深度（获取失败不影响该币种）
+	bookSymbols := make([]string, 0, len(ctx.MarketDataMap))
+	for symbol := range ctx.MarketDataMap {
+		bookSymbols = append(bookSymbols, symbol)
+	}
+	for symbol, orderBook := range market.GetOrderBookBatch(ctx.requestContext(), bookSymbols) {
+		// ⚠️ 流动性过滤：深度加权价差过大的币种开平仓滑点成本高，同样跳过（现有持仓保留）
+		if !positionSymbols[symbol] && orderBook.DepthSpreadBps > maxDepthSpreadBps {
+			log.Printf("⚠️  %s 深度加权价差过大(%.1f bps > %d bps)，跳过此币种", symbol, orderBook.DepthSpreadBps, maxDepthSpreadBps)
+			delete(ctx.MarketDataMap, symbol)
+			continue
+		}
+		ctx.OrderBookMap[symbol] = orderBook
+	}
+	log.Debugf("获取 %d/%d 个币种的市场数据耗时 %v", len(ctx.MarketDataMap), len(symbols), time.Since(start).Round(time.Millisecond))
+
+	// 已取消时不再继续（取消前拿到的部分数据不完整，不能用于决策）
+	if err := ctx.requestContext().Err(); err != nil {
+		return fmt.Errorf("已取消: %w", err)
+	}
+
+	// 收益率相关性（写入prompt并用于相关性敞口检查）
+	fetchCorrelations(ctx)
+
 	// 加载OI Top数据（不影响主流程）
 	oiPositions, err := pool.GetOITopPositions()
 	if err == nil {
@@ -199,230 +322,24 @@ func calculateMaxCandidates(ctx *Context) int {
 	return len(ctx.CandidateCoins)
 }
 
-// buildSystemPrompt 构建 System Prompt（固定规则，可缓存）
-func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage int) string {
-	var sb strings.Builder
-
-	// === 核心使命 ===
-	sb.WriteString("你是专业的加密货币交易AI，在币安合约市场进行自主交易。\n\n")
-	sb.WriteString("# 🎯 核心目标\n\n")
-	sb.WriteString("**最大化夏普比率（Sharpe Ratio）**\n\n")
-	sb.WriteString("夏普比率 = 平均收益 / 收益波动率\n\n")
-	sb.WriteString("**这意味着**：\n")
-	sb.WriteString("- ✅ 高质量交易（高胜率、大盈亏比）→ 提升夏普\n")
-	sb.WriteString("- ✅ 稳定收益、控制回撤 → 提升夏普\n")
-	sb.WriteString("- ✅ 耐心持仓、让利润奔跑 → 提升夏普\n")
-	sb.WriteString("- ❌ 频繁交易、小盈小亏 → 增加波动，严重降低夏普\n")
-	sb.WriteString("- ❌ 过度交易、手续费损耗 → 直接亏损\n")
-	sb.WriteString("- ❌ 过早平仓、频繁进出 → 错失大行情\n\n")
-	sb.WriteString("**关键认知**: 系统每3分钟扫描一次，但不意味着每次都要交易！\n")
-	sb.WriteString("大多数时候应该是 `wait` 或 `hold`，只在极佳机会时才开仓。\n\n")
-
-	// === 硬约束（风险控制）===
-	sb.WriteString("# ⚖️ 硬约束（风险控制）\n\n")
-	sb.WriteString("1. **风险回报比**: 必须 ≥ 1:3（冒1%风险，赚3%+收益）\n")
-	sb.WriteString("2. **最多持仓**: 3个币种（质量>数量）\n")
-	sb.WriteString(fmt.Sprintf("3. **单币仓位**: 山寨%.0f-%.0f U(%dx杠杆) | BTC/ETH %.0f-%.0f U(%dx杠杆)\n",
-		accountEquity*0.8, accountEquity*1.5, altcoinLeverage, accountEquity*5, accountEquity*10, btcEthLeverage))
-	sb.WriteString("4. **保证金**: 总使用率 ≤ 90%\n\n")
-
-	// === 做空激励 ===
-	sb.WriteString("# 📉 做多做空平衡\n\n")
-	sb.WriteString("**重要**: 下跌趋势做空的利润 = 上涨趋势做多的利润\n\n")
-	sb.WriteString("- 上涨趋势 → 做多\n")
-	sb.WriteString("- 下跌趋势 → 做空\n")
-	sb.WriteString("- 震荡市场 → 观望\n\n")
-	sb.WriteString("**不要有做多偏见！做空是你的核心工具之一**\n\n")
-
-	// === 交易频率认知 ===
-	sb.WriteString("# ⏱️ 交易频率认知\n\n")
-	sb.WriteString("**量化标准**:\n")
-	sb.WriteString("- 优秀交易员：每天2-4笔 = 每小时0.1-0.2笔\n")
-	sb.WriteString("- 过度交易：每小时>2笔 = 严重问题\n")
-	sb.WriteString("- 最佳节奏：开仓后持有至少30-60分钟\n\n")
-	sb.WriteString("**自查**:\n")
-	sb.WriteString("如果你发现自己每个周期都在交易 → 说明标准太低\n")
-	sb.WriteString("如果你发现持仓<30分钟就平仓 → 说明太急躁\n\n")
-
-	// === 开仓信号强度 ===
-	sb.WriteString("# 🎯 开仓标准（严格）\n\n")
-	sb.WriteString("只在**强信号**时开仓，不确定就观望。\n\n")
-	sb.WriteString("**你拥有的完整数据**：\n")
-	sb.WriteString("- 📊 **原始序列**：3分钟价格序列(MidPrices数组) + 4小时K线序列\n")
-	sb.WriteString("- 📈 **技术序列**：EMA20序列、MACD序列、RSI7序列、RSI14序列\n")
-	sb.WriteString("- 💰 **资金序列**：成交量序列、持仓量(OI)序列、资金费率\n")
-	sb.WriteString("- 🎯 **筛选标记**：AI500评分 / OI_Top排名（如果有标注）\n\n")
-	sb.WriteString("**分析方法**（完全由你自主决定）：\n")
-	sb.WriteString("- 自由运用序列数据，你可以做但不限于趋势分析、形态识别、支撑阻力、技术阻力位、斐波那契、波动带计算\n")
-	sb.WriteString("- 多维度交叉验证（价格+量+OI+指标+序列形态）\n")
-	sb.WriteString("- 用你认为最有效的方法发现高确定性机会\n")
-	sb.WriteString("- 综合信心度 ≥ 75 才开仓\n\n")
-	sb.WriteString("**避免低质量信号**：\n")
-	sb.WriteString("- 单一维度（只看一个指标）\n")
-	sb.WriteString("- 相互矛盾（涨但量萎缩）\n")
-	sb.WriteString("- 横盘震荡\n")
-	sb.WriteString("- 刚平仓不久（<15分钟）\n\n")
-
-	// === 夏普比率自我进化 ===
-	sb.WriteString("# 🧬 夏普比率自我进化\n\n")
-	sb.WriteString("每次你会收到**夏普比率**作为绩效反馈（周期级别）：\n\n")
-	sb.WriteString("**夏普比率 < -0.5** (持续亏损):\n")
-	sb.WriteString("  → 🛑 停止交易，连续观望至少6个周期（18分钟）\n")
-	sb.WriteString("  → 🔍 深度反思：\n")
-	sb.WriteString("     • 交易频率过高？（每小时>2次就是过度）\n")
-	sb.WriteString("     • 持仓时间过短？（<30分钟就是过早平仓）\n")
-	sb.WriteString("     • 信号强度不足？（信心度<75）\n")
-	sb.WriteString("     • 是否在做空？（单边做多是错误的）\n\n")
-	sb.WriteString("**夏普比率 -0.5 ~ 0** (轻微亏损):\n")
-	sb.WriteString("  → ⚠️ 严格控制：只做信心度>80的交易\n")
-	sb.WriteString("  → 减少交易频率：每小时最多1笔新开仓\n")
-	sb.WriteString("  → 耐心持仓：至少持有30分钟以上\n\n")
-	sb.WriteString("**夏普比率 0 ~ 0.7** (正收益):\n")
-	sb.WriteString("  → ✅ 维持当前策略\n\n")
-	sb.WriteString("**夏普比率 > 0.7** (优异表现):\n")
-	sb.WriteString("  → 🚀 可适度扩大仓位\n\n")
-	sb.WriteString("**关键**: 夏普比率是唯一指标，它会自然惩罚频繁交易和过度进出。\n\n")
-
-	// === 决策流程 ===
-	sb.WriteString("# 📋 决策流程\n\n")
-	sb.WriteString("1. **分析夏普比率**: 当前策略是否有效？需要调整吗？\n")
-	sb.WriteString("2. **评估持仓**: 趋势是否改变？是否该止盈/止损？\n")
-	sb.WriteString("3. **寻找新机会**: 有强信号吗？多空机会？\n")
-	sb.WriteString("4. **输出决策**: 思维链分析 + JSON\n\n")
-
-	// === 输出格式 ===
-	sb.WriteString("# 📤 输出格式\n\n")
-	sb.WriteString("**第一步: 思维链（纯文本）**\n")
-	sb.WriteString("简洁分析你的思考过程\n\n")
-	sb.WriteString("**第二步: JSON决策数组**\n\n")
-	sb.WriteString("```json\n[\n")
-	sb.WriteString(fmt.Sprintf("  {\"symbol\": \"BTCUSDT\", \"action\": \"open_short\", \"leverage\": %d, \"position_size_usd\": %.0f, \"stop_loss\": 97000, \"take_profit\": 91000, \"confidence\": 85, \"risk_usd\": 300, \"reasoning\": \"下跌趋势+MACD死叉\"},\n", btcEthLeverage, accountEquity*5))
-	sb.WriteString("  {\"symbol\": \"ETHUSDT\", \"action\": \"close_long\", \"reasoning\": \"止盈离场\"}\n")
-	sb.WriteString("]\n```\n\n")
-	sb.WriteString("**字段说明**:\n")
-	sb.WriteString("- `action`: open_long | open_short | close_long | close_short | hold | wait\n")
-	sb.WriteString("- `confidence`: 0-100（开仓建议≥75）\n")
-	sb.WriteString("- 开仓时必填: leverage, position_size_usd, stop_loss, take_profit, confidence, risk_usd, reasoning\n\n")
-
-	// === 关键提醒 ===
-	sb.WriteString("---\n\n")
-	sb.WriteString("**记住**: \n")
-	sb.WriteString("- 目标是夏普比率，不是交易频率\n")
-	sb.WriteString("- 做空 = 做多，都是赚钱工具\n")
-	sb.WriteString("- 宁可错过，不做低质量交易\n")
-	sb.WriteString("- 风险回报比1:3是底线\n")
-
-	return sb.String()
-}
-
-// buildUserPrompt 构建 User Prompt（动态数据）
-func buildUserPrompt(ctx *Context) string {
-	var sb strings.Builder
-
-	// 系统状态
-	sb.WriteString(fmt.Sprintf("**时间**: %s | **周期**: #%d | **运行**: %d分钟\n\n",
-		ctx.CurrentTime, ctx.CallCount, ctx.RuntimeMinutes))
-
-	// BTC 市场
-	if btcData, hasBTC := ctx.MarketDataMap["BTCUSDT"]; hasBTC {
-		sb.WriteString(fmt.Sprintf("**BTC**: %.2f (1h: %+.2f%%, 4h: %+.2f%%) | MACD: %.4f | RSI: %.2f\n\n",
-			btcData.CurrentPrice, btcData.PriceChange1h, btcData.PriceChange4h,
-			btcData.CurrentMACD, btcData.CurrentRSI7))
-	}
-
-	// 账户
-	sb.WriteString(fmt.Sprintf("**账户**: 净值%.2f | 余额%.2f (%.1f%%) | 盈亏%+.2f%% | 保证金%.1f%% | 持仓%d个\n\n",
-		ctx.Account.TotalEquity,
-		ctx.Account.AvailableBalance,
-		(ctx.Account.AvailableBalance/ctx.Account.TotalEquity)*100,
-		ctx.Account.TotalPnLPct,
-		ctx.Account.MarginUsedPct,
-		ctx.Account.PositionCount))
-
-	// 持仓（完整市场数据）
-	if len(ctx.Positions) > 0 {
-		sb.WriteString("## 当前持仓\n")
-		for i, pos := range ctx.Positions {
-			// 计算持仓时长
-			holdingDuration := ""
-			if pos.UpdateTime > 0 {
-				durationMs := time.Now().UnixMilli() - pos.UpdateTime
-				durationMin := durationMs / (1000 * 60) // 转换为分钟
-				if durationMin < 60 {
-					holdingDuration = fmt.Sprintf(" | 持仓时长%d分钟", durationMin)
-				} else {
-					durationHour := durationMin / 60
-					durationMinRemainder := durationMin % 60
-					holdingDuration = fmt.Sprintf(" | 持仓时长%d小时%d分钟", durationHour, durationMinRemainder)
-				}
-			}
-
-			sb.WriteString(fmt.Sprintf("%d. %s %s | 入场价%.4f 当前价%.4f | 盈亏%+.2f%% | 杠杆%dx | 保证金%.0f | 强平价%.4f%s\n\n",
-				i+1, pos.Symbol, strings.ToUpper(pos.Side),
-				pos.EntryPrice, pos.MarkPrice, pos.UnrealizedPnLPct,
-				pos.Leverage, pos.MarginUsed, pos.LiquidationPrice, holdingDuration))
-
-			// 使用FormatMarketData输出完整市场数据
-			if marketData, ok := ctx.MarketDataMap[pos.Symbol]; ok {
-				sb.WriteString(market.Format(marketData))
-				sb.WriteString("\n")
-			}
-		}
-	} else {
-		sb.WriteString("**当前持仓**: 无\n\n")
-	}
-
-	// 候选币种（完整市场数据）
-	sb.WriteString(fmt.Sprintf("## 候选币种 (%d个)\n\n", len(ctx.MarketDataMap)))
-	displayedCount := 0
-	for _, coin := range ctx.CandidateCoins {
-		marketData, hasData := ctx.MarketDataMap[coin.Symbol]
-		if !hasData {
-			continue
-		}
-		displayedCount++
-
-		sourceTags := ""
-		if len(coin.Sources) > 1 {
-			sourceTags = " (AI500+OI_Top双重信号)"
-		} else if len(coin.Sources) == 1 && coin.Sources[0] == "oi_top" {
-			sourceTags = " (OI_Top持仓增长)"
-		}
-
-		// 使用FormatMarketData输出完整市场数据
-		sb.WriteString(fmt.Sprintf("### %d. %s%s\n\n", displayedCount, coin.Symbol, sourceTags))
-		sb.WriteString(market.Format(marketData))
-		sb.WriteString("\n")
-	}
-	sb.WriteString("\n")
-
-	// 夏普比率（直接传值，不要复杂格式化）
-	if ctx.Performance != nil {
-		// 直接从interface{}中提取SharpeRatio
-		type PerformanceData struct {
-			SharpeRatio float64 `json:"sharpe_ratio"`
-		}
-		var perfData PerformanceData
-		if jsonData, err := json.Marshal(ctx.Performance); err == nil {
-			if err := json.Unmarshal(jsonData, &perfData); err == nil {
-				sb.WriteString(fmt.Sprintf("## 📊 夏普比率: %.2f\n\n", perfData.SharpeRatio))
-			}
-		}
-	}
-
-	sb.WriteString("---\n\n")
-	sb.WriteString("现在请分析并输出决策（思维链 + JSON）\n")
-
-	return sb.String()
-}
-
-// parseFullDecisionResponse 解析AI的完整决策响应
-func parseFullDecisionResponse(aiResponse string, accountEquity float64, btcEthLeverage, altcoinLeverage int) (*FullDecision, error) {
+// parseFullDecisionResponse 解析AI的完整决策响应（repair不为nil时，决策JSON无法解析会请求模型修复一次）
+func parseFullDecisionResponse(aiResponse string, accountEquity float64, btcEthLeverage, altcoinLeverage int, positions []PositionInfo, filter pool.SymbolFilter, corr correlationCheck, repair func(response string, parseErr error) (string, error)) (*FullDecision, error) {
 	// 1. 提取思维链
 	cotTrace := extractCoTTrace(aiResponse)
 
-	// 2. 提取JSON决策列表
+	// 2. 提取JSON决策列表（失败时请求模型修复一次）
 	decisions, err := extractDecisions(aiResponse)
+	if err != nil && repair != nil {
+		log.Printf("🔧 决策JSON解析失败，请求模型修复: %v", err)
+		if repaired, repairErr := repair(aiResponse, err); repairErr != nil {
+			err = fmt.Errorf("%w（请求模型修复失败: %v）", err, repairErr)
+		} else if fixed, fixErr := extractDecisions(repaired); fixErr != nil {
+			err = fmt.Errorf("%w（模型修复后仍无法解析: %v）", err, fixErr)
+		} else {
+			log.Printf("✓ 模型修复决策JSON成功")
+			decisions, err = fixed, nil
+		}
+	}
 	if err != nil {
 		return &FullDecision{
 			CoTTrace:  cotTrace,
@@ -431,7 +348,7 @@ func parseFullDecisionResponse(aiResponse string, accountEquity float64, btcEthL
 	}
 
 	// 3. 验证决策
-	if err := validateDecisions(decisions, accountEquity, btcEthLeverage, altcoinLeverage); err != nil {
+	if err := validateDecisions(decisions, accountEquity, btcEthLeverage, altcoinLeverage, positions, filter, corr); err != nil {
 		return &FullDecision{
 			CoTTrace:  cotTrace,
 			Decisions: decisions,
@@ -444,94 +361,61 @@ func parseFullDecisionResponse(aiResponse string, accountEquity float64, btcEthL
 	}, nil
 }
 
-// extractCoTTrace 提取思维链分析
+// extractCoTTrace 提取思维链分析（决策JSON之前的内容，找不到JSON时整个响应都是思维链）
 func extractCoTTrace(response string) string {
-	// 查找JSON数组的开始位置
-	jsonStart := strings.Index(response, "[")
-
-	if jsonStart > 0 {
-		// 思维链是JSON数组之前的内容
-		return strings.TrimSpace(response[:jsonStart])
-	}
-
-	// 如果找不到JSON，整个响应都是思维链
-	return strings.TrimSpace(response)
+	_, jsonStart, _ := locateDecisionArray(response)
+	return strings.TrimSpace(response[:jsonStart])
 }
 
-// extractDecisions 提取JSON决策列表
+// extractDecisions 提取JSON决策列表（容错修复后按决策Schema校验）
 func extractDecisions(response string) ([]Decision, error) {
-	// 直接查找JSON数组 - 找第一个完整的JSON数组
-	arrayStart := strings.Index(response, "[")
-	if arrayStart == -1 {
-		return nil, fmt.Errorf("无法找到JSON数组起始")
-	}
-
-	// 从 [ 开始，匹配括号找到对应的 ]
-	arrayEnd := findMatchingBracket(response, arrayStart)
-	if arrayEnd == -1 {
-		return nil, fmt.Errorf("无法找到JSON数组结束")
+	jsonContent, _, err := locateDecisionArray(response)
+	if err != nil {
+		return nil, err
 	}
 
-	jsonContent := strings.TrimSpace(response[arrayStart : arrayEnd+1])
+	// 🔧 修复常见的JSON格式错误（单引号、中文引号、尾随逗号、NaN等）
+	jsonContent = repairJSON(strings.TrimSpace(jsonContent))
 
-	// 🔧 修复常见的JSON格式错误：缺少引号的字段值
-	// 匹配: "reasoning": 内容"}  或  "reasoning": 内容}  (没有引号)
-	// 修复为: "reasoning": "内容"}
-	// 使用简单的字符串扫描而不是正则表达式
-	jsonContent = fixMissingQuotes(jsonContent)
+	var raw interface{}
+	if err := json.Unmarshal([]byte(jsonContent), &raw); err != nil {
+		return nil, fmt.Errorf("JSON解析失败: %w\nJSON内容: %s", err, jsonContent)
+	}
+	if err := validateSchema(raw, decisionListSchema, "decisions"); err != nil {
+		return nil, fmt.Errorf("决策格式错误: %w", err)
+	}
 
-	// 解析JSON
 	var decisions []Decision
 	if err := json.Unmarshal([]byte(jsonContent), &decisions); err != nil {
 		return nil, fmt.Errorf("JSON解析失败: %w\nJSON内容: %s", err, jsonContent)
 	}
-
 	return decisions, nil
 }
 
-// fixMissingQuotes 替换中文引号为英文引号（避免输入法自动转换）
-func fixMissingQuotes(jsonStr string) string {
-	jsonStr = strings.ReplaceAll(jsonStr, "\u201c", "\"") // "
-	jsonStr = strings.ReplaceAll(jsonStr, "\u201d", "\"") // "
-	jsonStr = strings.ReplaceAll(jsonStr, "\u2018", "'")  // '
-	jsonStr = strings.ReplaceAll(jsonStr, "\u2019", "'")  // '
-	return jsonStr
-}
-
-// validateDecisions 验证所有决策（需要账户信息和杠杆配置）
-func validateDecisions(decisions []Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int) error {
+// validateDecisions 验证所有决策（需要账户信息和杠杆配置），同一批决策中的开仓合并计算相关性敞口
+func validateDecisions(decisions []Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int, positions []PositionInfo, filter pool.SymbolFilter, corr correlationCheck) error {
+	var opened []PositionInfo
 	for i, decision := range decisions {
-		if err := validateDecision(&decision, accountEquity, btcEthLeverage, altcoinLeverage); err != nil {
+		if err := validateDecision(&decision, accountEquity, btcEthLeverage, altcoinLeverage, positions, filter); err != nil {
 			return fmt.Errorf("决策 #%d 验证失败: %w", i+1, err)
 		}
-	}
-	return nil
-}
-
-// findMatchingBracket 查找匹配的右括号
-func findMatchingBracket(s string, start int) int {
-	if start >= len(s) || s[start] != '[' {
-		return -1
-	}
-
-	depth := 0
-	for i := start; i < len(s); i++ {
-		switch s[i] {
-		case '[':
-			depth++
-		case ']':
-			depth--
-			if depth == 0 {
-				return i
-			}
+		if decision.Action != "open_long" && decision.Action != "open_short" {
+			continue
 		}
+		if err := corr.allows(&decision, positions, opened); err != nil {
+			return fmt.Errorf("决策 #%d 验证失败: %w", i+1, err)
+		}
+		side := "long"
+		if decision.Action == "open_short" {
+			side = "short"
+		}
+		opened = append(opened, PositionInfo{Symbol: decision.Symbol, Side: side})
 	}
-
-	return -1
+	return nil
 }
 
 // validateDecision 验证单个决策的有效性
-func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int) error {
+func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int, positions []PositionInfo, filter pool.SymbolFilter) error {
 	// 验证action
 	validActions := map[string]bool{
 		"open_long":   true,
@@ -546,8 +430,56 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 		return fmt.Errorf("无效的action: %s", d.Action)
 	}
 
+	// 部分平仓比例
+	if d.CloseFraction != 0 {
+		if d.Action != "close_long" && d.Action != "close_short" {
+			return fmt.Errorf("close_fraction只能用于平仓操作")
+		}
+		if d.CloseFraction < 0 || d.CloseFraction > 1 {
+			return fmt.Errorf("close_fraction必须在0-1之间: %.2f", d.CloseFraction)
+		}
+	}
+	if d.AddToPosition && d.Action != "open_long" && d.Action != "open_short" {
+		return fmt.Errorf("add_to_position只能用于开仓操作")
+	}
+
+	// 订单类型（限价/只做Maker仅用于开仓，平仓始终使用市价单）
+	switch d.OrderType {
+	case "", "market":
+	case "limit", "post_only":
+		if d.Action != "open_long" && d.Action != "open_short" {
+			return fmt.Errorf("order_type=%s只能用于开仓操作", d.OrderType)
+		}
+		if d.LimitPrice <= 0 {
+			return fmt.Errorf("order_type=%s时limit_price必须大于0", d.OrderType)
+		}
+	default:
+		return fmt.Errorf("无效的order_type: %s（可选 market, limit, post_only）", d.OrderType)
+	}
+
+	// 保证金模式（同一币种的多空两个方向共用保证金模式，已有另一方向持仓时必须一致）
+	switch d.MarginMode {
+	case "":
+	case "isolated", "cross":
+		if d.Action != "open_long" && d.Action != "open_short" {
+			return fmt.Errorf("margin_mode只能用于开仓操作")
+		}
+		for _, pos := range positions {
+			if pos.Symbol == d.Symbol && pos.MarginMode != "" && pos.MarginMode != d.MarginMode {
+				return fmt.Errorf("%s 已有%s持仓使用%s模式，margin_mode=%s冲突（同一币种多空共用保证金模式）", d.Symbol, pos.Side, pos.MarginMode, d.MarginMode)
+			}
+		}
+	default:
+		return fmt.Errorf("无效的margin_mode: %s（可选 isolated, cross）", d.MarginMode)
+	}
+
 	// 开仓操作必须提供完整参数
 	if d.Action == "open_long" || d.Action == "open_short" {
+		// 黑白名单（平仓不受限制）
+		if !filter.Allows(d.Symbol) {
+			return fmt.Errorf("%s 不在允许交易的币种范围内（黑白名单）", d.Symbol)
+		}
+
 		// 根据币种使用配置的杠杆上限
 		maxLeverage := altcoinLeverage          // 山寨币使用配置的杠杆
 		maxPositionValue := accountEquity * 1.5 // 山寨币最多1.5倍账户净值
@@ -562,19 +494,55 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 		if d.PositionSizeUSD <= 0 {
 			return fmt.Errorf("仓位大小必须大于0: %.2f", d.PositionSizeUSD)
 		}
+		// 交易所规则：只能开正常交易中的合约，仓位不能低于最小名义价值
+		if info, ok := market.GetSymbolInfo(d.Symbol); ok {
+			if !info.IsTrading() {
+				return fmt.Errorf("%s 当前不可交易（状态: %s）", d.Symbol, info.Status)
+			}
+			if d.PositionSizeUSD < info.MinNotional {
+				return fmt.Errorf("仓位大小 %.2f USDT 低于 %s 的最小名义价值 %.2f USDT", d.PositionSizeUSD, d.Symbol, info.MinNotional)
+			}
+		}
+		// 加仓时合并计算已有持仓的价值
+		positionValue := d.PositionSizeUSD
+		if d.AddToPosition {
+			side, sideName := "long", "多"
+			if d.Action == "open_short" {
+				side, sideName = "short", "空"
+			}
+			found := false
+			for _, pos := range positions {
+				if pos.Symbol == d.Symbol && pos.Side == side {
+					positionValue += pos.Quantity * pos.MarkPrice
+					found = true
+				}
+			}
+			if !found {
+				return fmt.Errorf("add_to_position: %s 没有%s仓可加", d.Symbol, sideName)
+			}
+		}
+
 		// 验证仓位价值上限（加1%容差以避免浮点数精度问题）
 		tolerance := maxPositionValue * 0.01 // 1%容差
-		if d.PositionSizeUSD > maxPositionValue+tolerance {
+		if positionValue > maxPositionValue+tolerance {
 			if d.Symbol == "BTCUSDT" || d.Symbol == "ETHUSDT" {
-				return fmt.Errorf("BTC/ETH单币种仓位价值不能超过%.0f USDT（10倍账户净值），实际: %.0f", maxPositionValue, d.PositionSizeUSD)
+				return fmt.Errorf("BTC/ETH单币种仓位价值不能超过%.0f USDT（10倍账户净值），实际: %.0f", maxPositionValue, positionValue)
 			} else {
-				return fmt.Errorf("山寨币单币种仓位价值不能超过%.0f USDT（1.5倍账户净值），实际: %.0f", maxPositionValue, d.PositionSizeUSD)
+				return fmt.Errorf("山寨币单币种仓位价值不能超过%.0f USDT（1.5倍账户净值），实际: %.0f", maxPositionValue, positionValue)
 			}
 		}
 		if d.StopLoss <= 0 || d.TakeProfit <= 0 {
 			return fmt.Errorf("止损和止盈必须大于0")
 		}
 
+		// 限价单挂单价必须在止损和止盈之间
+		if d.LimitPrice > 0 {
+			low, high := math.Min(d.StopLoss, d.TakeProfit), math.Max(d.StopLoss, d.TakeProfit)
+			if d.LimitPrice <= low || d.LimitPrice >= high {
+				return fmt.Errorf("limit_price(%.4f)必须在止损和止盈之间", d.LimitPrice)
+			}
+		}
+
 		// 验证止损止盈的合理性
 		if d.Action == "open_long" {
 			if d.StopLoss >= d.TakeProfit {
@@ -617,6 +585,21 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 			return fmt.Errorf("风险回报比过低(%.2f:1)，必须≥3.0:1 [风险:%.2f%% 收益:%.2f%%] [止损:%.2f 止盈:%.2f]",
 				riskRewardRatio, riskPercent, rewardPercent, d.StopLoss, d.TakeProfit)
 		}
+
+		if ts := d.TrailingStop; ts != nil {
+			switch ts.Mode {
+			case "percent":
+				if ts.Value <= 0 || ts.Value > 50 {
+					return fmt.Errorf("移动止损百分比必须在0-50之间: %.2f", ts.Value)
+				}
+			case "atr":
+				if ts.Value <= 0 || ts.Value > 10 {
+					return fmt.Errorf("移动止损ATR倍数必须在0-10之间: %.2f", ts.Value)
+				}
+			default:
+				return fmt.Errorf("无效的移动止损模式: %s（可选 percent, atr）", ts.Mode)
+			}
+		}
 	}
 
 	return nil
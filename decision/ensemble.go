@@ -0,0 +1,208 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/mcp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EnsembleMember 委员会成员
+type EnsembleMember struct {
+	Name   string      // 模型名称（deepseek/qwen/openai/claude/custom）
+	Client *mcp.Client // 对应的AI客户端
+}
+
+// EnsembleStrategy 委员会模式：同一上下文同时发给多个模型，同一币种的交易动作达到法定票数才执行
+type EnsembleStrategy struct {
+	members []EnsembleMember
+	quorum  int
+}
+
+// memberResult 单个成员的决策结果
+type memberResult struct {
+	name     string
+	decision *FullDecision
+	err      error
+}
+
+// NewEnsembleStrategy 创建委员会策略（quorum为0时取过半数）
+func NewEnsembleStrategy(members []EnsembleMember, quorum int) (*EnsembleStrategy, error) {
+	if len(members) < 2 {
+		return nil, fmt.Errorf("委员会模式至少需要2个模型，当前: %d", len(members))
+	}
+	if quorum == 0 {
+		quorum = len(members)/2 + 1
+	}
+	if quorum < 2 || quorum > len(members) {
+		return nil, fmt.Errorf("法定票数必须在2到%d之间: %d", len(members), quorum)
+	}
+	return &EnsembleStrategy{members: members, quorum: quorum}, nil
+}
+
+// Name 策略类型
+func (s *EnsembleStrategy) Name() string {
+	return StrategyEnsemble
+}
+
+// Decide 并发请求所有成员，按币种统计交易动作票数，达到法定票数的动作才执行
+func (s *EnsembleStrategy) Decide(ctx *Context) (*FullDecision, error) {
+	// 市场数据只获取一次，所有成员使用相同的上下文
+	if len(ctx.MarketDataMap) == 0 {
+		if err := fetchMarketDataForContext(ctx); err != nil {
+			return nil, fmt.Errorf("获取市场数据失败: %w", err)
+		}
+		if len(ctx.MarketDataMap) == 0 {
+			return nil, fmt.Errorf("获取市场数据失败: 没有可用的币种数据")
+		}
+	}
+	if ctx.OITopDataMap == nil {
+		ctx.OITopDataMap = make(map[string]*OITopData)
+	}
+
+	results := make([]memberResult, len(s.members))
+	var wg sync.WaitGroup
+	for i, m := range s.members {
+		wg.Add(1)
+		go func(i int, m EnsembleMember) {
+			defer wg.Done()
+			// 多个成员并发输出会交错，委员会模式不转发流式输出
+			memberCtx := *ctx
+			memberCtx.OnStream = nil
+			d, err := GetFullDecision(&memberCtx, m.Client)
+			results[i] = memberResult{name: m.Name, decision: d, err: err}
+		}(i, m)
+	}
+	wg.Wait()
+
+	var trace strings.Builder
+	trace.WriteString(fmt.Sprintf("[委员会模式] 成员: %s | 法定票数: %d\n", strings.Join(s.memberNames(), ", "), s.quorum))
+
+	full := &FullDecision{Timestamp: time.Now()}
+	valid := 0
+	for _, r := range results {
+		trace.WriteString(fmt.Sprintf("\n## %s\n", r.name))
+		if r.err != nil {
+			trace.WriteString(fmt.Sprintf("（决策失败，视为弃权: %v）\n", r.err))
+			continue
+		}
+		valid++
+		if full.UserPrompt == "" {
+			full.UserPrompt = r.decision.UserPrompt
+		}
+		trace.WriteString(r.decision.CoTTrace + "\n")
+	}
+	if valid < s.quorum {
+		full.CoTTrace = trace.String()
+		return full, fmt.Errorf("委员会有效成员 %d 个，不足法定票数 %d", valid, s.quorum)
+	}
+
+	full.Decisions = s.tally(results, &trace)
+	full.CoTTrace = trace.String()
+	return full, nil
+}
+
+// tally 统计投票：每个成员对每个币种只取第一条决策，未表态视为观望
+func (s *EnsembleStrategy) tally(results []memberResult, trace *strings.Builder) []Decision {
+	// symbol -> 成员名 -> 决策
+	votes := make(map[string]map[string]Decision)
+	var symbols []string
+	for _, r := range results {
+		if r.err != nil {
+			continue
+		}
+		for _, d := range r.decision.Decisions {
+			if !isTradeAction(d.Action) {
+				continue
+			}
+			if votes[d.Symbol] == nil {
+				votes[d.Symbol] = make(map[string]Decision)
+				symbols = append(symbols, d.Symbol)
+			}
+			if _, voted := votes[d.Symbol][r.name]; !voted {
+				votes[d.Symbol][r.name] = d
+			}
+		}
+	}
+
+	trace.WriteString("\n[投票结果]\n")
+	var decisions []Decision
+	for _, symbol := range symbols {
+		// 统计各动作的支持者（按成员顺序，便于复现）
+		supporters := make(map[string][]string)
+		bestAction := ""
+		for _, r := range results {
+			d, ok := votes[symbol][r.name]
+			if !ok {
+				continue
+			}
+			supporters[d.Action] = append(supporters[d.Action], r.name)
+			if bestAction == "" || len(supporters[d.Action]) > len(supporters[bestAction]) {
+				bestAction = d.Action
+			}
+		}
+
+		agreed := supporters[bestAction]
+		passed := len(agreed) >= s.quorum
+		status := "未通过"
+		if passed {
+			status = "通过"
+		}
+		trace.WriteString(fmt.Sprintf("- %s %s: %d/%d %s（%s）\n", symbol, bestAction, len(agreed), len(s.members), status, strings.Join(agreed, ", ")))
+
+		// 记录反对意见（包括未表态的成员）
+		for _, r := range results {
+			if r.err != nil {
+				continue
+			}
+			d, ok := votes[symbol][r.name]
+			if ok && d.Action == bestAction {
+				continue
+			}
+			action, reasoning := "wait", "未对该币种给出交易决策"
+			if ok {
+				action, reasoning = d.Action, d.Reasoning
+			}
+			trace.WriteString(fmt.Sprintf("  反对 %s(%s): %s\n", r.name, action, reasoning))
+			log.Printf("🗳 委员会 %s %s: %s 反对(%s) - %s", symbol, bestAction, r.name, action, reasoning)
+		}
+
+		if !passed {
+			continue
+		}
+
+		// 开仓取同意者中仓位最小的方案（参数保持同一模型的一致性）
+		chosen := votes[symbol][agreed[0]]
+		for _, name := range agreed[1:] {
+			if d := votes[symbol][name]; d.PositionSizeUSD < chosen.PositionSizeUSD {
+				chosen = d
+			}
+		}
+		chosen.Reasoning = fmt.Sprintf("[委员会 %d/%d 同意: %s] %s", len(agreed), len(s.members), strings.Join(agreed, ", "), chosen.Reasoning)
+		decisions = append(decisions, chosen)
+	}
+
+	if len(decisions) == 0 {
+		decisions = append(decisions, Decision{Symbol: "ALL", Action: "wait", Reasoning: "委员会未就任何交易达成一致"})
+	}
+	return decisions
+}
+
+// memberNames 成员名称列表
+func (s *EnsembleStrategy) memberNames() []string {
+	names := make([]string, len(s.members))
+	for i, m := range s.members {
+		names[i] = m.Name
+	}
+	return names
+}
+
+// isTradeAction 是否为开平仓动作
+func isTradeAction(action string) bool {
+	switch action {
+	case "open_long", "open_short", "close_long", "close_short":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,275 @@
+package decision
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"nofx/mcp"
+	"strings"
+	"unicode"
+)
+
+// repairMaxResponseLen 请求模型修复时附带的原始输出最大长度（保留末尾，JSON通常在最后）
+const repairMaxResponseLen = 8000
+
+// repairSystemPrompt 请求模型修复决策JSON时使用的System Prompt
+const repairSystemPrompt = "你是JSON修复助手。用户会给出一段无法解析的交易决策输出和错误信息，" +
+	"请修复后只输出一个JSON数组，不要输出任何解释、思维链或markdown代码块。"
+
+// decisionListSchema 决策数组的JSON Schema
+var decisionListSchema = map[string]interface{}{
+	"type":  "array",
+	"items": decisionSchema,
+}
+
+// locateDecisionArray 定位响应中的决策JSON数组，返回数组文本和思维链结束位置
+// 优先使用以[开头的markdown代码块，其次是第一个以"[{"或"[]"开头的数组（跳过思维链中的[标注]）
+func locateDecisionArray(response string) (string, int, error) {
+	// 1. markdown代码块（```json ... ```，允许未闭合）
+	for offset := 0; offset < len(response); {
+		open := strings.Index(response[offset:], "```")
+		if open == -1 {
+			break
+		}
+		open += offset
+		bodyStart := open + 3
+		nl := strings.IndexByte(response[bodyStart:], '\n')
+		if nl == -1 {
+			break
+		}
+		bodyStart += nl + 1 // 跳过语言标记
+		bodyEnd := len(response)
+		if end := strings.Index(response[bodyStart:], "```"); end != -1 {
+			bodyEnd = bodyStart + end
+		}
+
+		body := response[bodyStart:bodyEnd]
+		if i := strings.IndexByte(body, '['); i != -1 && strings.TrimSpace(body[:i]) == "" {
+			end := matchBracket(body, i)
+			if end == -1 {
+				return "", open, fmt.Errorf("无法找到JSON数组结束")
+			}
+			return body[i : end+1], open, nil
+		}
+		offset = bodyEnd + 3
+	}
+
+	// 2. 裸数组
+	for i := 0; i < len(response); i++ {
+		if response[i] != '[' {
+			continue
+		}
+		rest := strings.TrimLeft(response[i+1:], " \t\r\n")
+		if rest == "" || (rest[0] != '{' && rest[0] != ']') {
+			continue
+		}
+		end := matchBracket(response, i)
+		if end == -1 {
+			return "", i, fmt.Errorf("无法找到JSON数组结束")
+		}
+		return response[i : end+1], i, nil
+	}
+	return "", len(response), fmt.Errorf("无法找到JSON数组起始")
+}
+
+// matchBracket 查找与start处[匹配的]（忽略字符串中的括号），找不到返回-1
+func matchBracket(s string, start int) int {
+	depth := 0
+	var quote rune
+	escaped := false
+	for i, r := range s[start:] {
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case isClosingQuote(quote, r):
+				quote = 0
+			}
+			continue
+		}
+		switch {
+		case isOpeningQuote(r):
+			quote = r
+		case r == '[':
+			depth++
+		case r == ']':
+			depth--
+			if depth == 0 {
+				return start + i
+			}
+		}
+	}
+	return -1
+}
+
+// isOpeningQuote 字符串起始引号（模型偶尔输出单引号或中文引号）
+func isOpeningQuote(r rune) bool {
+	return r == '"' || r == '\'' || r == '“' || r == '”'
+}
+
+// isClosingQuote 是否为对应的字符串结束引号
+func isClosingQuote(quote, r rune) bool {
+	switch quote {
+	case '“', '”':
+		return r == '“' || r == '”'
+	}
+	return r == quote
+}
+
+// repairJSON 修复模型常见的JSON格式错误：
+// 单引号/中文引号字符串、字符串中的换行、尾随逗号、NaN/Infinity/undefined（替换为null）
+func repairJSON(s string) string {
+	runes := []rune(s)
+	var sb strings.Builder
+	var quote rune // 当前字符串的引号（0表示不在字符串中）
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if quote != 0 {
+			switch {
+			case r == '\\' && i+1 < len(runes):
+				i++
+				if runes[i] == '\'' {
+					sb.WriteRune('\'') // JSON中\'不是合法转义
+				} else {
+					sb.WriteRune(r)
+					sb.WriteRune(runes[i])
+				}
+			case isClosingQuote(quote, r):
+				sb.WriteRune('"')
+				quote = 0
+			case r == '"':
+				sb.WriteString(`\"`) // 单引号字符串中的双引号
+			case r == '\n':
+				sb.WriteString(`\n`)
+			case r == '\r':
+				sb.WriteString(`\r`)
+			case r == '\t':
+				sb.WriteString(`\t`)
+			default:
+				sb.WriteRune(r)
+			}
+			continue
+		}
+
+		switch {
+		case isOpeningQuote(r):
+			quote = r
+			sb.WriteRune('"')
+		case r == ',':
+			j := i + 1
+			for j < len(runes) && unicode.IsSpace(runes[j]) {
+				j++
+			}
+			if j < len(runes) && (runes[j] == ']' || runes[j] == '}') {
+				continue // 尾随逗号
+			}
+			sb.WriteRune(r)
+		case unicode.IsLetter(r) || (r == '-' && i+1 < len(runes) && unicode.IsLetter(runes[i+1])):
+			j := i + 1
+			for j < len(runes) && unicode.IsLetter(runes[j]) {
+				j++
+			}
+			word := string(runes[i:j])
+			switch word {
+			case "NaN", "-NaN", "Infinity", "-Infinity", "undefined":
+				sb.WriteString("null")
+			default:
+				sb.WriteString(word)
+			}
+			i = j - 1
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// validateSchema 按JSON Schema子集（type/enum/required/properties/items/minimum/maximum）校验解析后的JSON值
+func validateSchema(value interface{}, schema map[string]interface{}, path string) error {
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s 必须是对象", path)
+		}
+		if required, ok := schema["required"].([]string); ok {
+			for _, key := range required {
+				if v, exists := obj[key]; !exists || v == nil {
+					return fmt.Errorf("%s 缺少必填字段 %s", path, key)
+				}
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for key, v := range obj {
+			propSchema, ok := properties[key].(map[string]interface{})
+			if !ok || v == nil {
+				continue // 未知字段忽略，可选字段允许null
+			}
+			if err := validateSchema(v, propSchema, path+"."+key); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s 必须是数组", path)
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		for i, item := range arr {
+			if err := validateSchema(item, items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s 必须是字符串", path)
+		}
+		if enum, ok := schema["enum"].([]string); ok {
+			valid := false
+			for _, e := range enum {
+				if str == e {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				return fmt.Errorf("%s 的值 %q 无效（可选: %s）", path, str, strings.Join(enum, ", "))
+			}
+		}
+	case "number", "integer":
+		num, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("%s 必须是数字", path)
+		}
+		if schema["type"] == "integer" && num != math.Trunc(num) {
+			return fmt.Errorf("%s 必须是整数: %v", path, num)
+		}
+		if min, ok := schema["minimum"].(int); ok && num < float64(min) {
+			return fmt.Errorf("%s 不能小于 %d: %v", path, min, num)
+		}
+		if max, ok := schema["maximum"].(int); ok && num > float64(max) {
+			return fmt.Errorf("%s 不能大于 %d: %v", path, max, num)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s 必须是布尔值", path)
+		}
+	}
+	return nil
+}
+
+// requestRepair 把解析错误反馈给模型，要求只输出修复后的决策JSON数组
+func requestRepair(reqCtx context.Context, mcpClient *mcp.Client, response string, parseErr error) (string, error) {
+	if len(response) > repairMaxResponseLen {
+		response = response[len(response)-repairMaxResponseLen:]
+	}
+	userPrompt := fmt.Sprintf("以下交易决策输出无法解析:\n\n错误: %v\n\n原始输出:\n%s\n\n"+
+		"请修复为合法的JSON数组：每个元素必须包含 symbol、action、reasoning，"+
+		"action 只能是 open_long | open_short | close_long | close_short | hold | wait，数值字段使用数字而不是字符串。",
+		parseErr, response)
+	return mcpClient.CallWithMessages(reqCtx, repairSystemPrompt, userPrompt)
+}
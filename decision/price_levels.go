@@ -0,0 +1,119 @@
+package decision
+
+import (
+	"fmt"
+	"math"
+)
+
+// 止损止盈价位校验参数
+const (
+	minStopDistancePct    = 0.1   // 止损/止盈距入场价的最小距离（%）
+	maintenanceMarginRate = 0.005 // 交易所未提供维持保证金率时估算强平价使用的默认值
+)
+
+// LiquidationRules 估算强平价使用的维持保证金参数（来自交易所杠杆分层）和强平距离保护
+type LiquidationRules struct {
+	MaintMarginRate float64 // 维持保证金率（0表示使用默认的0.5%）
+	MaintAmount     float64 // 维持保证金速算额（币安分层的cum，其他交易所为0）
+	MinBufferPct    float64 // 止损价与强平价之间的最小距离（入场价的%，0表示只要求止损在强平价之前）
+}
+
+// LiquidationPrice 估算逐仓强平价：保证金为名义价值/杠杆，亏损到只剩维持保证金（名义价值×维持保证金率-速算额）时强平
+// 做多在1倍杠杆等不会强平的情况下返回0
+func (r LiquidationRules) LiquidationPrice(entry float64, leverage int, notional float64, long bool) float64 {
+	if entry <= 0 || leverage <= 0 {
+		return 0
+	}
+	mmr := r.MaintMarginRate
+	if mmr <= 0 {
+		mmr = maintenanceMarginRate
+	}
+	amountRatio := 0.0
+	if notional > 0 {
+		amountRatio = r.MaintAmount / notional
+	}
+	if long {
+		return math.Max(0, entry*(1-1/float64(leverage)-amountRatio)/(1-mmr))
+	}
+	return entry * (1 + 1/float64(leverage) + amountRatio) / (1 + mmr)
+}
+
+// CheckPriceLevels 按最新价格校验开仓决策的止损止盈，防止AI给出幻觉价位：
+// 止损/止盈在入场价错误一侧、或距离小于买卖价差（spread为卖一-买一，未知时传0）时返回错误；
+// 止损超出按杠杆分层估算的强平价、或与强平价的距离小于liq.MinBufferPct时降低杠杆，
+// 降到满足要求的最高杠杆并返回调整说明，1倍杠杆也不满足时返回错误
+func CheckPriceLevels(d *Decision, markPrice, spread float64, liq LiquidationRules) (string, error) {
+	if d.Action != "open_long" && d.Action != "open_short" {
+		return "", nil
+	}
+	if markPrice <= 0 {
+		return "", fmt.Errorf("%s 当前价格无效", d.Symbol)
+	}
+
+	// 限价单以挂单价作为入场价
+	entry := markPrice
+	if d.LimitPrice > 0 {
+		entry = d.LimitPrice
+	}
+	minDistance := math.Max(spread, entry*minStopDistancePct/100)
+	long := d.Action == "open_long"
+
+	// 方向：做多止损在入场价下方、止盈在上方，做空相反
+	if long && d.StopLoss >= entry {
+		return "", fmt.Errorf("做多止损价(%.4f)必须低于入场价(%.4f)", d.StopLoss, entry)
+	}
+	if long && d.TakeProfit <= entry {
+		return "", fmt.Errorf("做多止盈价(%.4f)必须高于入场价(%.4f)", d.TakeProfit, entry)
+	}
+	if !long && d.StopLoss <= entry {
+		return "", fmt.Errorf("做空止损价(%.4f)必须高于入场价(%.4f)", d.StopLoss, entry)
+	}
+	if !long && d.TakeProfit >= entry {
+		return "", fmt.Errorf("做空止盈价(%.4f)必须低于入场价(%.4f)", d.TakeProfit, entry)
+	}
+
+	// 距离：在买卖价差以内的止损开仓即触发
+	if math.Abs(entry-d.StopLoss) < minDistance {
+		return "", fmt.Errorf("止损价(%.4f)距入场价(%.4f)过近（最小距离%.4f，价差%.4f）", d.StopLoss, entry, minDistance, spread)
+	}
+	if math.Abs(d.TakeProfit-entry) < minDistance {
+		return "", fmt.Errorf("止盈价(%.4f)距入场价(%.4f)过近（最小距离%.4f，价差%.4f）", d.TakeProfit, entry, minDistance, spread)
+	}
+
+	// 强平价：止损在强平价之外时仓位会先被强平，止损形同虚设；离强平价太近时止损滑点也可能导致强平
+	if d.Leverage <= 0 {
+		return "", nil
+	}
+	minBuffer := entry * liq.MinBufferPct / 100
+	// buffer 止损价在强平价之前的距离（负数表示止损超出强平价，不会强平时为+Inf）
+	buffer := func(leverage int) float64 {
+		liqPrice := liq.LiquidationPrice(entry, leverage, d.PositionSizeUSD, long)
+		if liqPrice <= 0 {
+			return math.Inf(1)
+		}
+		if long {
+			return d.StopLoss - liqPrice
+		}
+		return liqPrice - d.StopLoss
+	}
+	if b := buffer(d.Leverage); b > 0 && b >= minBuffer {
+		return "", nil
+	}
+
+	liqPrice := liq.LiquidationPrice(entry, d.Leverage, d.PositionSizeUSD, long)
+	problem := fmt.Sprintf("止损价 %.4f 超出估算强平价 %.4f（%dx杠杆）", d.StopLoss, liqPrice, d.Leverage)
+	if buffer(d.Leverage) > 0 {
+		problem = fmt.Sprintf("止损价 %.4f 距估算强平价 %.4f 不足入场价的 %.2f%%（%dx杠杆）", d.StopLoss, liqPrice, liq.MinBufferPct, d.Leverage)
+	}
+	for leverage := d.Leverage - 1; leverage >= 1; leverage-- {
+		if b := buffer(leverage); b > 0 && b >= minBuffer {
+			adjustment := fmt.Sprintf("%s，杠杆降为 %dx（不会强平）", problem, leverage)
+			if newLiq := liq.LiquidationPrice(entry, leverage, d.PositionSizeUSD, long); newLiq > 0 {
+				adjustment = fmt.Sprintf("%s，杠杆降为 %dx（估算强平价 %.4f）", problem, leverage, newLiq)
+			}
+			d.Leverage = leverage
+			return adjustment, nil
+		}
+	}
+	return "", fmt.Errorf("%s，降到1倍杠杆也无法满足强平距离要求", problem)
+}
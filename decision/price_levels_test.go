@@ -0,0 +1,93 @@
+package decision
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestLiquidationPrice(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    LiquidationRules
+		leverage int
+		notional float64
+		long     bool
+		want     float64
+	}{
+		{"long default mmr", LiquidationRules{}, 10, 1000, true, 100 * 0.9 / 0.995},
+		{"short default mmr", LiquidationRules{}, 10, 1000, false, 100 * 1.1 / 1.005},
+		{"long tier with cum", LiquidationRules{MaintMarginRate: 0.01, MaintAmount: 50}, 10, 10000, true, 100 * (0.9 - 0.005) / 0.99},
+		{"short tier with cum", LiquidationRules{MaintMarginRate: 0.01, MaintAmount: 50}, 10, 10000, false, 100 * (1.1 + 0.005) / 1.01},
+		{"long 1x never liquidated", LiquidationRules{}, 1, 1000, true, 0},
+		{"zero leverage", LiquidationRules{}, 0, 1000, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.rules.LiquidationPrice(100, tt.leverage, tt.notional, tt.long)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("LiquidationPrice = %.6f, want %.6f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPriceLevels(t *testing.T) {
+	tests := []struct {
+		name         string
+		action       string
+		stopLoss     float64
+		takeProfit   float64
+		leverage     int
+		spread       float64
+		minBuffer    float64
+		wantLeverage int
+		wantAdjusted bool
+		wantErr      string
+	}{
+		{name: "long ok", action: "open_long", stopLoss: 95, takeProfit: 120, leverage: 10, wantLeverage: 10},
+		{name: "short ok", action: "open_short", stopLoss: 105, takeProfit: 80, leverage: 10, wantLeverage: 10},
+		{name: "long stop above entry", action: "open_long", stopLoss: 101, takeProfit: 120, leverage: 10, wantErr: "做多止损价"},
+		{name: "short take profit above entry", action: "open_short", stopLoss: 105, takeProfit: 101, leverage: 10, wantErr: "做空止盈价"},
+		{name: "stop inside spread", action: "open_long", stopLoss: 99.5, takeProfit: 120, leverage: 10, spread: 1, wantErr: "止损价"},
+		// 10x强平价≈90.45，9x≈89.33，8x≈87.94
+		{name: "long stop beyond liquidation", action: "open_long", stopLoss: 88, takeProfit: 120, leverage: 10, wantLeverage: 8, wantAdjusted: true},
+		// 10x时止损距强平价≈4.55，不足5%
+		{name: "long stop within buffer", action: "open_long", stopLoss: 95, takeProfit: 120, leverage: 10, minBuffer: 5, wantLeverage: 9, wantAdjusted: true},
+		// 做空10x强平价≈109.45，9x≈110.56，8x≈111.94，7x≈113.72
+		{name: "short stop beyond liquidation", action: "open_short", stopLoss: 112, takeProfit: 80, leverage: 10, wantLeverage: 7, wantAdjusted: true},
+		{name: "short stop beyond 1x liquidation", action: "open_short", stopLoss: 250, takeProfit: 80, leverage: 10, wantErr: "降到1倍杠杆"},
+		{name: "long 1x never liquidated", action: "open_long", stopLoss: 10, takeProfit: 120, leverage: 3, minBuffer: 50, wantLeverage: 1, wantAdjusted: true},
+		{name: "zero leverage skips liquidation check", action: "open_long", stopLoss: 50, takeProfit: 120, leverage: 0, wantLeverage: 0},
+		{name: "close ignored", action: "close_long", leverage: 10, wantLeverage: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Decision{Symbol: "BTCUSDT", Action: tt.action, StopLoss: tt.stopLoss, TakeProfit: tt.takeProfit, Leverage: tt.leverage, PositionSizeUSD: 1000}
+			adjustment, err := CheckPriceLevels(d, 100, tt.spread, LiquidationRules{MinBufferPct: tt.minBuffer})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d.Leverage != tt.wantLeverage {
+				t.Errorf("leverage = %d, want %d", d.Leverage, tt.wantLeverage)
+			}
+			if (adjustment != "") != tt.wantAdjusted {
+				t.Errorf("adjustment = %q, want adjusted=%v", adjustment, tt.wantAdjusted)
+			}
+		})
+	}
+}
+
+func TestCheckPriceLevelsUsesLimitPrice(t *testing.T) {
+	// 限价200做多时止损150在入场价下方，按当前价100会被判为方向错误
+	d := &Decision{Symbol: "BTCUSDT", Action: "open_long", LimitPrice: 200, StopLoss: 150, TakeProfit: 260, Leverage: 2, PositionSizeUSD: 1000}
+	if _, err := CheckPriceLevels(d, 100, 0, LiquidationRules{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,322 @@
+package decision
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"nofx/market"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// 默认prompt模板（可通过API按trader覆盖）
+var (
+	//go:embed prompts/system.tmpl
+	defaultSystemTemplate string
+	//go:embed prompts/user.tmpl
+	defaultUserTemplate string
+
+	// 现货版本（没有做空、杠杆、保证金和强平的概念）
+	//go:embed prompts/system_spot.tmpl
+	spotSystemTemplate string
+	//go:embed prompts/user_spot.tmpl
+	spotUserTemplate string
+)
+
+// 风控规则（写入prompt的模板变量，validateDecision中的硬约束与之一致）
+const (
+	promptMaxPositions      = 3
+	promptMaxMarginUsagePct = 90
+	promptMinRiskReward     = 3
+	promptMinConfidence     = 75
+)
+
+// promptFuncs 模板中可用的函数
+var promptFuncs = template.FuncMap{
+	"formatMarket":    market.Format,
+	"formatOrderBook": market.FormatOrderBook,
+	"upper":           strings.ToUpper,
+}
+
+// 默认模板启动时解析，模板有误直接panic
+var (
+	defaultSystemTmpl = template.Must(template.New("system").Funcs(promptFuncs).Parse(defaultSystemTemplate))
+	defaultUserTmpl   = template.Must(template.New("user").Funcs(promptFuncs).Parse(defaultUserTemplate))
+	spotSystemTmpl    = template.Must(template.New("system").Funcs(promptFuncs).Parse(spotSystemTemplate))
+	spotUserTmpl      = template.Must(template.New("user").Funcs(promptFuncs).Parse(spotUserTemplate))
+)
+
+// PromptTemplates trader自定义的prompt模板（Go text/template语法，为空时使用默认模板）
+type PromptTemplates struct {
+	System string `json:"system,omitempty"` // System Prompt（交易规则）
+	User   string `json:"user,omitempty"`   // User Prompt（每周期的市场数据）
+}
+
+// DefaultPromptTemplates 获取默认模板（用于API展示和作为自定义的起点）
+func DefaultPromptTemplates() PromptTemplates {
+	return PromptTemplates{System: defaultSystemTemplate, User: defaultUserTemplate}
+}
+
+// DefaultSpotPromptTemplates 获取现货trader的默认模板
+func DefaultSpotPromptTemplates() PromptTemplates {
+	return PromptTemplates{System: spotSystemTemplate, User: spotUserTemplate}
+}
+
+// Validate 解析模板并用示例数据试渲染，提前发现语法错误和不存在的变量
+func (p PromptTemplates) Validate() error {
+	sample := &Context{
+		CurrentTime:     time.Now().Format("2006-01-02 15:04:05"),
+		BTCETHLeverage:  5,
+		AltcoinLeverage: 5,
+		Account:         AccountInfo{TotalEquity: 1000, AvailableBalance: 1000, PositionCount: 1},
+		Positions:       []PositionInfo{{Symbol: "BTCUSDT", Side: "long", Leverage: 5}},
+		CandidateCoins:  []CandidateCoin{{Symbol: "BTCUSDT", Sources: []string{"ai500"}}},
+		MarketDataMap:   map[string]*market.Data{"BTCUSDT": {Symbol: "BTCUSDT"}},
+		Performance:     map[string]float64{"sharpe_ratio": 0},
+	}
+	_, _, err := buildPrompts(sample, &p)
+	return err
+}
+
+// PromptData 模板变量
+type PromptData struct {
+	// 账户与风控规则
+	AccountEquity     float64 // 账户净值
+	BTCETHLeverage    int     // BTC/ETH杠杆上限
+	AltcoinLeverage   int     // 山寨币杠杆上限
+	AltMinSize        float64 // 山寨币建议最小仓位（0.8倍净值）
+	AltMaxSize        float64 // 山寨币仓位上限（1.5倍净值）
+	BTCETHMinSize     float64 // BTC/ETH建议最小仓位（5倍净值）
+	BTCETHMaxSize     float64 // BTC/ETH仓位上限（10倍净值）
+	SpotMinSize       float64 // 现货建议最小仓位（0.2倍净值）
+	SpotMaxSize       float64 // 现货建议最大仓位（0.5倍净值）
+	MaxPositions      int     // 最多持仓数
+	MaxMarginUsagePct int     // 保证金使用率上限（%）
+	MinRiskReward     int     // 最低风险回报比（1:N）
+	MinConfidence     int     // 开仓最低信心度
+	IntradayLabel     string  // 日内K线周期（如"3分钟"）
+	LongerTermLabel   string  // 长期K线周期（如"4小时"）
+
+	// 周期数据
+	CurrentTime    string
+	CallCount      int
+	RuntimeMinutes int
+	Account        AccountInfo
+	AvailablePct   float64      // 可用余额占净值百分比
+	RiskNotices    []string     // 当前生效的风控限制说明
+	Calibration    []string     // 信心度校准摘要
+	Lessons        []string     // 相关的历史交易复盘
+	News           []string     // 近期新闻和宏观事件
+	MarketState    string       // 市场状态和恐惧贪婪指数（都没有时为空）
+	BTC            *market.Data // BTC行情（没有数据时为nil）
+	Positions      []PositionPromptData
+	Candidates     []CandidatePromptData
+	CandidateCount int     // 有市场数据的币种数
+	HasSharpe      bool    // 是否有历史表现数据
+	SharpeRatio    float64 // 夏普比率
+	SortinoRatio   float64 // 索提诺比率
+	CalmarRatio    float64 // 卡玛比率
+	MaxDrawdownPct float64 // 最大回撤（%）
+	AvgHoldingMin  float64 // 平均持仓时长（分钟）
+	ExposurePct    float64 // 有持仓的周期占比（%）
+	TotalFees      float64 // 近期已平仓交易的手续费合计
+	TotalFunding   float64 // 近期已平仓交易的资金费合计
+}
+
+// PositionPromptData 模板中的持仓
+type PositionPromptData struct {
+	PositionInfo
+	Index           int               // 序号（从1开始）
+	HoldingDuration string            // 持仓时长描述（如 " | 持仓时长35分钟"）
+	Data            *market.Data      // 市场数据（可能为nil）
+	OrderBook       *market.OrderBook // 订单簿流动性指标（可能为nil）
+	SlippageBps     float64           // 近期平均滑点（基点，未超过警告阈值时为0）
+	Correlation     string            // 与BTC/ETH和其他持仓的相关性说明（没有数据时为空）
+}
+
+// CandidatePromptData 模板中的候选币种（只包含有市场数据的币种）
+type CandidatePromptData struct {
+	Index       int    // 序号（从1开始）
+	Symbol      string // 币种
+	SourceTags  string // 来源标记（如 " (OI_Top持仓增长)"）
+	Data        *market.Data
+	OrderBook   *market.OrderBook // 订单簿流动性指标（可能为nil）
+	SlippageBps float64           // 近期平均滑点（基点，未超过警告阈值时为0）
+	Correlation string            // 与BTC/ETH和现有持仓的相关性说明（没有数据时为空）
+	Similar     []string          // 历史上行情最相似的交易及其结果（没有足够相似的形态时为空）
+}
+
+// newPromptData 从交易上下文构建模板变量
+func newPromptData(ctx *Context) *PromptData {
+	equity := ctx.Account.TotalEquity
+	tf := ctx.Timeframes.WithDefaults()
+	data := &PromptData{
+		AccountEquity:     equity,
+		BTCETHLeverage:    ctx.BTCETHLeverage,
+		AltcoinLeverage:   ctx.AltcoinLeverage,
+		AltMinSize:        equity * 0.8,
+		AltMaxSize:        equity * 1.5,
+		BTCETHMinSize:     equity * 5,
+		BTCETHMaxSize:     equity * 10,
+		SpotMinSize:       equity * 0.2,
+		SpotMaxSize:       equity * 0.5,
+		MaxPositions:      promptMaxPositions,
+		MaxMarginUsagePct: promptMaxMarginUsagePct,
+		MinRiskReward:     promptMinRiskReward,
+		MinConfidence:     promptMinConfidence,
+		IntradayLabel:     market.IntervalLabelCN(tf.Intraday),
+		LongerTermLabel:   market.IntervalLabelCN(tf.LongerTerm),
+		CurrentTime:       ctx.CurrentTime,
+		CallCount:         ctx.CallCount,
+		RuntimeMinutes:    ctx.RuntimeMinutes,
+		Account:           ctx.Account,
+		AvailablePct:      (ctx.Account.AvailableBalance / equity) * 100,
+		RiskNotices:       ctx.RiskNotices,
+		Calibration:       ctx.Calibration,
+		Lessons:           ctx.Lessons,
+		News:              ctx.News,
+		MarketState:       formatMarketState(ctx.Regime, ctx.FearGreed),
+		BTC:               ctx.MarketDataMap["BTCUSDT"],
+		CandidateCount:    len(ctx.MarketDataMap),
+	}
+
+	for i, pos := range ctx.Positions {
+		data.Positions = append(data.Positions, PositionPromptData{
+			PositionInfo:    pos,
+			Index:           i + 1,
+			HoldingDuration: holdingDuration(pos.UpdateTime),
+			Data:            ctx.MarketDataMap[pos.Symbol],
+			OrderBook:       ctx.OrderBookMap[pos.Symbol],
+			SlippageBps:     ctx.Slippage[pos.Symbol],
+			Correlation:     correlationNote(ctx, pos.Symbol),
+		})
+	}
+
+	for _, coin := range ctx.CandidateCoins {
+		marketData, hasData := ctx.MarketDataMap[coin.Symbol]
+		if !hasData {
+			continue
+		}
+		data.Candidates = append(data.Candidates, CandidatePromptData{
+			Index:       len(data.Candidates) + 1,
+			Symbol:      coin.Symbol,
+			SourceTags:  formatSourceTags(coin.Sources),
+			Data:        marketData,
+			OrderBook:   ctx.OrderBookMap[coin.Symbol],
+			SlippageBps: ctx.Slippage[coin.Symbol],
+			Correlation: correlationNote(ctx, coin.Symbol),
+			Similar:     similarSetups(ctx, coin.Symbol),
+		})
+	}
+
+	// 绩效指标（从logger.PerformanceAnalysis中提取）
+	if ctx.Performance != nil {
+		var perfData struct {
+			SharpeRatio       float64 `json:"sharpe_ratio"`
+			SortinoRatio      float64 `json:"sortino_ratio"`
+			CalmarRatio       float64 `json:"calmar_ratio"`
+			MaxDrawdownPct    float64 `json:"max_drawdown_pct"`
+			AvgHoldingMinutes float64 `json:"avg_holding_minutes"`
+			ExposurePct       float64 `json:"exposure_pct"`
+			TotalFees         float64 `json:"total_fees"`
+			TotalFunding      float64 `json:"total_funding"`
+		}
+		if jsonData, err := json.Marshal(ctx.Performance); err == nil {
+			if err := json.Unmarshal(jsonData, &perfData); err == nil {
+				data.HasSharpe = true
+				data.SharpeRatio = perfData.SharpeRatio
+				data.SortinoRatio = perfData.SortinoRatio
+				data.CalmarRatio = perfData.CalmarRatio
+				data.MaxDrawdownPct = perfData.MaxDrawdownPct
+				data.AvgHoldingMin = perfData.AvgHoldingMinutes
+				data.ExposurePct = perfData.ExposurePct
+				data.TotalFees = perfData.TotalFees
+				data.TotalFunding = perfData.TotalFunding
+			}
+		}
+	}
+	return data
+}
+
+// holdingDuration 持仓时长描述（updateTime为毫秒时间戳，0时返回空字符串）
+func holdingDuration(updateTime int64) string {
+	if updateTime <= 0 {
+		return ""
+	}
+	durationMin := (time.Now().UnixMilli() - updateTime) / (1000 * 60)
+	if durationMin < 60 {
+		return fmt.Sprintf(" | 持仓时长%d分钟", durationMin)
+	}
+	return fmt.Sprintf(" | 持仓时长%d小时%d分钟", durationMin/60, durationMin%60)
+}
+
+// buildPrompts 构建 System Prompt（固定规则）和 User Prompt（动态数据），custom为nil或字段为空时使用默认模板
+func buildPrompts(ctx *Context, custom *PromptTemplates) (string, string, error) {
+	systemTmpl, userTmpl := defaultSystemTmpl, defaultUserTmpl
+	if ctx.Spot {
+		systemTmpl, userTmpl = spotSystemTmpl, spotUserTmpl
+	}
+	if custom != nil && custom.System != "" {
+		t, err := template.New("system").Funcs(promptFuncs).Parse(custom.System)
+		if err != nil {
+			return "", "", fmt.Errorf("解析system模板失败: %w", err)
+		}
+		systemTmpl = t
+	}
+	if custom != nil && custom.User != "" {
+		t, err := template.New("user").Funcs(promptFuncs).Parse(custom.User)
+		if err != nil {
+			return "", "", fmt.Errorf("解析user模板失败: %w", err)
+		}
+		userTmpl = t
+	}
+
+	data := newPromptData(ctx)
+	var system, user strings.Builder
+	if err := systemTmpl.Execute(&system, data); err != nil {
+		return "", "", fmt.Errorf("渲染system模板失败: %w", err)
+	}
+	if err := userTmpl.Execute(&user, data); err != nil {
+		return "", "", fmt.Errorf("渲染user模板失败: %w", err)
+	}
+	return system.String(), user.String(), nil
+}
+
+// responseCacheKey AI响应缓存键：去掉时间、周期编号和运行时长后重新渲染的prompt
+// （除此之外完全相同的上下文才能复用同一个响应）
+func responseCacheKey(ctx *Context) (string, error) {
+	neutral := *ctx
+	neutral.CurrentTime, neutral.CallCount, neutral.RuntimeMinutes = "", 0, 0
+	system, user, err := buildPrompts(&neutral, ctx.Prompts)
+	if err != nil {
+		return "", err
+	}
+	return system + "\x00" + user, nil
+}
+
+// formatMarketState 市场状态和恐惧贪婪指数（如 "上涨趋势 (4h ATR 1.8%, EMA20斜率 +2.1%) | 恐惧贪婪指数 72 (Greed)"）
+func formatMarketState(regime *market.Regime, fearGreed *market.FearGreed) string {
+	var parts []string
+	if regime != nil {
+		parts = append(parts, fmt.Sprintf("%s (ATR %.2f%%, ATR3/ATR14 %.2f, EMA20斜率 %+.2f%%)",
+			regime.Description(), regime.ATRPct, regime.ATRRatio, regime.EMASlopePct))
+	}
+	if fearGreed != nil {
+		parts = append(parts, fmt.Sprintf("恐惧贪婪指数 %d (%s)", fearGreed.Value, fearGreed.Classification))
+	}
+	return strings.Join(parts, " | ")
+}
+
+// formatSourceTags 候选币种的来源标记（只来自AI500时不标记）
+func formatSourceTags(sources []string) string {
+	switch {
+	case len(sources) == 2 && sources[0] == "ai500" && sources[1] == "oi_top":
+		return " (AI500+OI_Top双重信号)"
+	case len(sources) == 1 && sources[0] == "oi_top":
+		return " (OI_Top持仓增长)"
+	case len(sources) == 0 || len(sources) == 1 && sources[0] == "ai500":
+		return ""
+	}
+	return " (" + strings.Join(sources, "+") + ")"
+}
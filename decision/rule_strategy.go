@@ -0,0 +1,168 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/market"
+	"strings"
+	"time"
+)
+
+// 规则策略参数
+const (
+	ruleMaxPositions  = 3    // 最多同时持仓数
+	ruleStopATR       = 2.0  // 止损距离（4小时ATR14倍数）
+	ruleRewardRisk    = 3.0  // 止盈距离/止损距离
+	ruleAltSizeRatio  = 1.0  // 山寨币单笔仓位价值（账户净值倍数，上限1.5）
+	ruleMainSizeRatio = 3.0  // BTC/ETH单笔仓位价值（账户净值倍数，上限10）
+	ruleMaxMarginPct  = 0.9  // 开仓后保证金使用率上限
+	ruleConfidence    = 75   // 规则信号的信心度
+	ruleRSILongMin    = 50.0 // 做多时3分钟RSI7区间（避免追高）
+	ruleRSILongMax    = 75.0
+	ruleRSIShortMin   = 25.0 // 做空时3分钟RSI7区间（避免追空）
+	ruleRSIShortMax   = 50.0
+)
+
+// RuleStrategy 规则扫描策略（不调用AI）：4小时趋势与3分钟动量同向时开仓，4小时趋势反转时平仓
+type RuleStrategy struct{}
+
+// NewRuleStrategy 创建规则策略
+func NewRuleStrategy() *RuleStrategy {
+	return &RuleStrategy{}
+}
+
+// Name 策略类型
+func (s *RuleStrategy) Name() string {
+	return StrategyRule
+}
+
+// ruleSignal 根据指标给出方向信号（long/short，无信号返回空字符串）和说明
+func ruleSignal(data *market.Data) (string, string) {
+	tf := data.Timeframes.WithDefaults()
+	lt := data.LongerTermContext
+	if lt == nil || lt.EMA20 <= 0 || lt.EMA50 <= 0 || lt.ATR14 <= 0 {
+		return "", fmt.Sprintf("缺少%s指标", market.IntervalLabelCN(tf.LongerTerm))
+	}
+
+	price := data.CurrentPrice
+	switch {
+	case lt.EMA20 > lt.EMA50 && price > lt.EMA20:
+		if data.CurrentMACD > 0 && price > data.CurrentEMA20 && data.CurrentRSI7 > ruleRSILongMin && data.CurrentRSI7 < ruleRSILongMax {
+			return "long", fmt.Sprintf("%s上升趋势(EMA20 %.4f > EMA50 %.4f)，%s MACD %.4f > 0，RSI7 %.1f", tf.LongerTerm, lt.EMA20, lt.EMA50, tf.Intraday, data.CurrentMACD, data.CurrentRSI7)
+		}
+		return "", fmt.Sprintf("%s上升趋势但%s动量不足(MACD %.4f, RSI7 %.1f)", tf.LongerTerm, tf.Intraday, data.CurrentMACD, data.CurrentRSI7)
+	case lt.EMA20 < lt.EMA50 && price < lt.EMA20:
+		if data.CurrentMACD < 0 && price < data.CurrentEMA20 && data.CurrentRSI7 > ruleRSIShortMin && data.CurrentRSI7 < ruleRSIShortMax {
+			return "short", fmt.Sprintf("%s下降趋势(EMA20 %.4f < EMA50 %.4f)，%s MACD %.4f < 0，RSI7 %.1f", tf.LongerTerm, lt.EMA20, lt.EMA50, tf.Intraday, data.CurrentMACD, data.CurrentRSI7)
+		}
+		return "", fmt.Sprintf("%s下降趋势但%s动量不足(MACD %.4f, RSI7 %.1f)", tf.LongerTerm, tf.Intraday, data.CurrentMACD, data.CurrentRSI7)
+	}
+	return "", fmt.Sprintf("%s趋势不明确", tf.LongerTerm)
+}
+
+// Decide 扫描持仓和候选币种，按规则生成决策
+func (s *RuleStrategy) Decide(ctx *Context) (*FullDecision, error) {
+	if len(ctx.MarketDataMap) == 0 {
+		if err := fetchMarketDataForContext(ctx); err != nil {
+			return nil, fmt.Errorf("获取市场数据失败: %w", err)
+		}
+	}
+
+	var decisions []Decision
+	var trace strings.Builder
+	trace.WriteString("规则策略扫描:\n")
+
+	// 1. 持仓：4小时趋势反转时平仓
+	held := make(map[string]bool)
+	openCount := len(ctx.Positions)
+	for _, pos := range ctx.Positions {
+		held[pos.Symbol] = true
+		data, ok := ctx.MarketDataMap[pos.Symbol]
+		if !ok || data.LongerTermContext == nil {
+			continue
+		}
+		lt := data.LongerTermContext
+		reversed := (pos.Side == "long" && lt.EMA20 < lt.EMA50) || (pos.Side == "short" && lt.EMA20 > lt.EMA50)
+		if !reversed {
+			trace.WriteString(fmt.Sprintf("- %s %s: 趋势未反转，继续持有\n", pos.Symbol, pos.Side))
+			continue
+		}
+		reason := fmt.Sprintf("4h趋势反转(EMA20 %.4f / EMA50 %.4f)", lt.EMA20, lt.EMA50)
+		trace.WriteString(fmt.Sprintf("- %s %s: %s，平仓\n", pos.Symbol, pos.Side, reason))
+		decisions = append(decisions, Decision{Symbol: pos.Symbol, Action: "close_" + pos.Side, Reasoning: reason})
+		openCount--
+	}
+
+	// 2. 候选币种：按币种池顺序开仓，直到达到持仓上限或保证金上限
+	marginUsed := ctx.Account.MarginUsed
+	for _, coin := range ctx.CandidateCoins {
+		if openCount >= ruleMaxPositions {
+			break
+		}
+		if held[coin.Symbol] {
+			continue
+		}
+		data, ok := ctx.MarketDataMap[coin.Symbol]
+		if !ok || data.CurrentPrice <= 0 {
+			continue
+		}
+
+		side, reason := ruleSignal(data)
+		if side == "" {
+			trace.WriteString(fmt.Sprintf("- %s: %s，观望\n", coin.Symbol, reason))
+			continue
+		}
+
+		leverage, sizeRatio := ctx.AltcoinLeverage, ruleAltSizeRatio
+		if coin.Symbol == "BTCUSDT" || coin.Symbol == "ETHUSDT" {
+			leverage, sizeRatio = ctx.BTCETHLeverage, ruleMainSizeRatio
+		}
+		if leverage <= 0 {
+			leverage = 1
+		}
+		positionSize := ctx.Account.TotalEquity * sizeRatio
+		margin := positionSize / float64(leverage)
+		if ctx.Account.TotalEquity <= 0 || marginUsed+margin > ctx.Account.TotalEquity*ruleMaxMarginPct {
+			trace.WriteString(fmt.Sprintf("- %s: %s，但保证金不足，跳过\n", coin.Symbol, reason))
+			continue
+		}
+
+		price := data.CurrentPrice
+		stopDistance := data.LongerTermContext.ATR14 * ruleStopATR
+		if stopDistance >= price {
+			continue
+		}
+		d := Decision{
+			Symbol:          coin.Symbol,
+			Action:          "open_" + side,
+			Leverage:        leverage,
+			PositionSizeUSD: positionSize,
+			Confidence:      ruleConfidence,
+			RiskUSD:         positionSize * stopDistance / price,
+			Reasoning:       reason,
+		}
+		if side == "long" {
+			d.StopLoss, d.TakeProfit = price-stopDistance, price+stopDistance*ruleRewardRisk
+		} else {
+			d.StopLoss, d.TakeProfit = price+stopDistance, price-stopDistance*ruleRewardRisk
+		}
+
+		trace.WriteString(fmt.Sprintf("- %s: %s，开%s\n", coin.Symbol, reason, map[string]string{"long": "多", "short": "空"}[side]))
+		decisions = append(decisions, d)
+		marginUsed += margin
+		openCount++
+	}
+
+	if len(decisions) == 0 {
+		decisions = append(decisions, Decision{Symbol: "ALL", Action: "wait", Reasoning: "无规则信号"})
+	}
+
+	full := &FullDecision{
+		CoTTrace:  trace.String(),
+		Decisions: decisions,
+		Timestamp: time.Now(),
+	}
+	if err := validateDecisions(decisions, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage, ctx.Positions, ctx.SymbolFilter, ctx.correlationCheck()); err != nil {
+		return full, fmt.Errorf("规则决策验证失败: %w", err)
+	}
+	return full, nil
+}
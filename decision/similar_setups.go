@@ -0,0 +1,78 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/logger"
+	"nofx/market"
+	"sort"
+)
+
+// maxSetupDistance 归一化距离（各指标平均偏离几个典型波动幅度）超过该值的历史形态不算相似
+const maxSetupDistance = 1.0
+
+// SetupMemory 历史形态检索（从配置读取，K为0或没有历史形态时不检索）
+type SetupMemory struct {
+	K       int                      `json:"k"`       // 每个候选币种最多列出的相似形态数
+	History []logger.HistoricalSetup `json:"history"` // 已平仓交易开仓时的形态向量和结果
+}
+
+// setupMatch 一个相似的历史形态及其与当前行情的距离
+type setupMatch struct {
+	setup    *logger.HistoricalSetup
+	distance float64
+}
+
+// nearestSetups 与币种当前行情距离最近的K个历史形态（按距离从近到远，超过maxSetupDistance的不算）
+func nearestSetups(ctx *Context, symbol string) []setupMatch {
+	mem := ctx.SetupMemory
+	if mem.K <= 0 || len(mem.History) == 0 {
+		return nil
+	}
+	current := market.SetupVector(ctx.MarketDataMap[symbol])
+	if current == nil {
+		return nil
+	}
+
+	var matches []setupMatch
+	for i := range mem.History {
+		if d := market.SetupDistance(current, mem.History[i].Vector); d <= maxSetupDistance {
+			matches = append(matches, setupMatch{&mem.History[i], d})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].distance < matches[j].distance })
+	if len(matches) > mem.K {
+		matches = matches[:mem.K]
+	}
+	return matches
+}
+
+// similarSetups 与候选币种当前行情最相似的K个历史形态（按相似度从高到低，格式如 "ETHUSDT做多 10-12（相似度85%）: RSI7 71 | ... → 亏损 -2.10%（止损触发）"）
+func similarSetups(ctx *Context, symbol string) []string {
+	matches := nearestSetups(ctx, symbol)
+	lines := make([]string, 0, len(matches))
+	for _, m := range matches {
+		s := m.setup
+		sideName := map[string]string{"long": "做多", "short": "做空"}[s.Side]
+		outcome := "盈利"
+		if s.PnLPct <= 0 {
+			outcome = "亏损"
+		}
+		lines = append(lines, fmt.Sprintf("%s%s %s（相似度%.0f%%）: %s → %s %+.2f%%%s",
+			s.Symbol, sideName, s.Time.Format("01-02"), 100/(1+m.distance), market.DescribeSetup(s.Vector),
+			outcome, s.PnLPct, setupCloseReason(s.CloseReason)))
+	}
+	return lines
+}
+
+// setupCloseReason 平仓方式说明（AI平仓不标注）
+func setupCloseReason(reason string) string {
+	switch reason {
+	case logger.CloseReasonStopLoss:
+		return "（止损触发）"
+	case logger.CloseReasonTakeProfit:
+		return "（止盈触发）"
+	case logger.CloseReasonManual:
+		return "（人工平仓）"
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+package decision
+
+import (
+	"fmt"
+	"math"
+)
+
+// Simulation 假设性开仓的测算结果（按当前价格估算，不下单）
+type Simulation struct {
+	Symbol           string   `json:"symbol"`
+	Side             string   `json:"side"`
+	MarkPrice        float64  `json:"mark_price"`  // 当前价格
+	EntryPrice       float64  `json:"entry_price"` // 估算入场价（限价单为挂单价）
+	Quantity         float64  `json:"quantity"`
+	PositionSizeUSD  float64  `json:"position_size_usd"`
+	Leverage         int      `json:"leverage"`          // 实际会使用的杠杆（止损离强平价过近时已降低）
+	MarginRequired   float64  `json:"margin_required"`   // 占用保证金 = 仓位价值 / 杠杆
+	LiquidationPrice float64  `json:"liquidation_price"` // 估算强平价（逐仓，按交易所杠杆分层的维持保证金率）
+	StopLoss         float64  `json:"stop_loss"`
+	TakeProfit       float64  `json:"take_profit"`
+	FeesUSD          float64  `json:"fees_usd"`    // 开平仓手续费估算
+	RiskUSD          float64  `json:"risk_usd"`    // 止损触发时的亏损（含手续费）
+	RewardUSD        float64  `json:"reward_usd"`  // 止盈触发时的盈利（扣除手续费）
+	RiskReward       float64  `json:"risk_reward"` // 盈利 / 亏损
+	RiskPct          float64  `json:"risk_pct"`    // 亏损占账户净值的比例（%，净值未知时为0）
+	MarginPct        float64  `json:"margin_pct"`  // 保证金占账户净值的比例（%，净值未知时为0）
+	Warnings         []string `json:"warnings"`    // 执行时会被拒绝或调整的原因
+}
+
+// Simulate 测算开仓决策的保证金、强平价、风险和风险回报比（d需为open_long/open_short，feeRate为单边手续费率）
+// 止损止盈价位和强平距离按CheckPriceLevels校验，校验失败和低于最低风险回报比写入Warnings而不是返回错误
+func Simulate(d Decision, markPrice, spread, equity, feeRate float64, liq LiquidationRules) (*Simulation, error) {
+	if d.Action != "open_long" && d.Action != "open_short" {
+		return nil, fmt.Errorf("只能测算开仓决策: %s", d.Action)
+	}
+	if markPrice <= 0 {
+		return nil, fmt.Errorf("%s 当前价格无效", d.Symbol)
+	}
+	if d.Leverage <= 0 || d.PositionSizeUSD <= 0 {
+		return nil, fmt.Errorf("杠杆和仓位大小必须大于0")
+	}
+	if d.StopLoss <= 0 || d.TakeProfit <= 0 {
+		return nil, fmt.Errorf("止损和止盈必须大于0")
+	}
+
+	long := d.Action == "open_long"
+	s := &Simulation{
+		Symbol:          d.Symbol,
+		Side:            "long",
+		MarkPrice:       markPrice,
+		EntryPrice:      markPrice,
+		PositionSizeUSD: d.PositionSizeUSD,
+		Warnings:        []string{},
+	}
+	if !long {
+		s.Side = "short"
+	}
+	if d.LimitPrice > 0 {
+		s.EntryPrice = d.LimitPrice
+	}
+	s.Quantity = d.PositionSizeUSD / s.EntryPrice
+
+	adjustment, err := CheckPriceLevels(&d, markPrice, spread, liq)
+	if err != nil {
+		s.Warnings = append(s.Warnings, err.Error())
+	} else if adjustment != "" {
+		s.Warnings = append(s.Warnings, adjustment)
+	}
+	s.StopLoss, s.TakeProfit = d.StopLoss, d.TakeProfit
+	s.Leverage = d.Leverage
+	s.MarginRequired = d.PositionSizeUSD / float64(d.Leverage)
+	s.LiquidationPrice = liq.LiquidationPrice(s.EntryPrice, d.Leverage, d.PositionSizeUSD, long)
+
+	// 按止损/止盈价平仓的盈亏（做空方向相反），手续费按开仓和平仓各一次估算
+	direction := 1.0
+	if !long {
+		direction = -1
+	}
+	s.FeesUSD = d.PositionSizeUSD * feeRate * 2
+	s.RiskUSD = math.Max(0, -direction*(s.StopLoss-s.EntryPrice)*s.Quantity) + s.FeesUSD
+	s.RewardUSD = direction*(s.TakeProfit-s.EntryPrice)*s.Quantity - s.FeesUSD
+	if s.RiskUSD > 0 {
+		s.RiskReward = s.RewardUSD / s.RiskUSD
+	}
+	if equity > 0 {
+		s.RiskPct = s.RiskUSD / equity * 100
+		s.MarginPct = s.MarginRequired / equity * 100
+	}
+	if s.RiskReward < promptMinRiskReward {
+		s.Warnings = append(s.Warnings, fmt.Sprintf("风险回报比 %.2f 低于要求的 %d:1", s.RiskReward, promptMinRiskReward))
+	}
+	return s, nil
+}
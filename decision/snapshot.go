@@ -0,0 +1,72 @@
+package decision
+
+import (
+	"context"
+	"nofx/logger"
+	"nofx/market"
+	"nofx/pool"
+)
+
+// ContextSnapshot 决策时交易上下文的完整快照，用于审计某个周期的决策依据，以及用同样的数据重新运行该周期
+// 字段与Context一一对应（只是序列化了Context中json:"-"的行情、订单簿和prompt素材），两者可以直接相互转换，
+// Context增删字段时这里必须同步修改，否则无法编译
+type ContextSnapshot struct {
+	CurrentTime      string                       `json:"current_time"`
+	RuntimeMinutes   int                          `json:"runtime_minutes"`
+	CallCount        int                          `json:"call_count"`
+	Account          AccountInfo                  `json:"account"`
+	Positions        []PositionInfo               `json:"positions"`
+	CandidateCoins   []CandidateCoin              `json:"candidate_coins"`
+	MarketDataMap    map[string]*market.Data      `json:"market_data"`
+	OrderBookMap     map[string]*market.OrderBook `json:"order_books,omitempty"`
+	OITopDataMap     map[string]*OITopData        `json:"oi_top,omitempty"`
+	Performance      interface{}                  `json:"performance,omitempty"`
+	BTCETHLeverage   int                          `json:"btc_eth_leverage"`
+	AltcoinLeverage  int                          `json:"altcoin_leverage"`
+	Prompts          *PromptTemplates             `json:"-"` // prompt模板不保存（发送给AI的prompt已保存在决策记录中）
+	Timeframes       market.Timeframes            `json:"timeframes"`
+	SymbolFilter     pool.SymbolFilter            `json:"symbol_filter"`
+	RiskNotices      []string                     `json:"risk_notices,omitempty"`
+	Spot             bool                         `json:"spot,omitempty"`
+	FundingRates     map[string]float64           `json:"funding_rates,omitempty"`
+	Slippage         map[string]float64           `json:"slippage,omitempty"`
+	Correlations     market.CorrelationMatrix     `json:"correlations,omitempty"`
+	CorrelationLimit CorrelationLimit             `json:"correlation_limit"`
+	Calibration      []string                     `json:"calibration,omitempty"`
+	Lessons          []string                     `json:"lessons,omitempty"`
+	SetupMemory      SetupMemory                  `json:"setup_memory"`
+	Regime           *market.Regime               `json:"regime,omitempty"`
+	FearGreed        *market.FearGreed            `json:"fear_greed,omitempty"`
+	News             []string                     `json:"news,omitempty"`
+	OnStream         func(delta string)           `json:"-"`
+	Ctx              context.Context              `json:"-"`
+	ShareResponses   bool                         `json:"-"`
+}
+
+// NewContextSnapshot 保存交易上下文（历史形态只保留本周期写入prompt的那些）
+func NewContextSnapshot(ctx *Context) *ContextSnapshot {
+	s := ContextSnapshot(*ctx)
+	s.Prompts = nil
+	s.OnStream = nil
+	s.Ctx = nil
+	s.ShareResponses = false
+
+	var used []logger.HistoricalSetup
+	seen := make(map[int64]bool)
+	for _, coin := range ctx.CandidateCoins {
+		for _, m := range nearestSetups(ctx, coin.Symbol) {
+			if !seen[m.setup.TradeID] {
+				seen[m.setup.TradeID] = true
+				used = append(used, *m.setup)
+			}
+		}
+	}
+	s.SetupMemory.History = used
+	return &s
+}
+
+// Restore 还原为交易上下文（行情数据已预先填充，决策时不会重新获取）
+func (s *ContextSnapshot) Restore() *Context {
+	ctx := Context(*s)
+	return &ctx
+}
@@ -0,0 +1,131 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/mcp"
+	"strings"
+)
+
+// 策略类型
+const (
+	StrategyAI       = "ai"       // 纯AI决策（默认）
+	StrategyRule     = "rule"     // 规则扫描（不调用AI）
+	StrategyHybrid   = "hybrid"   // AI决策 + 规则信号过滤开仓
+	StrategyEnsemble = "ensemble" // 多个AI模型投票，达到法定票数才执行
+)
+
+// Strategy 决策来源接口（AutoTrader每个周期调用一次Decide）
+type Strategy interface {
+	// Name 策略类型（ai/rule/hybrid/ensemble）
+	Name() string
+
+	// Decide 根据交易上下文给出完整决策
+	Decide(ctx *Context) (*FullDecision, error)
+}
+
+// ValidStrategy 是否为支持的策略类型
+func ValidStrategy(name string) bool {
+	switch name {
+	case StrategyAI, StrategyRule, StrategyHybrid, StrategyEnsemble:
+		return true
+	}
+	return false
+}
+
+// StrategyOptions 创建策略所需的依赖
+type StrategyOptions struct {
+	MCPClient      *mcp.Client      // 主AI客户端（ai/hybrid策略使用）
+	EnsembleModels []EnsembleMember // 委员会成员（ensemble策略使用）
+	EnsembleQuorum int              // 执行交易所需的最少同意票数（0表示过半数）
+}
+
+// NewStrategy 按名称创建策略
+func NewStrategy(name string, opts StrategyOptions) (Strategy, error) {
+	switch name {
+	case "", StrategyAI:
+		return NewAIStrategy(opts.MCPClient), nil
+	case StrategyRule:
+		return NewRuleStrategy(), nil
+	case StrategyHybrid:
+		return NewHybridStrategy(opts.MCPClient), nil
+	case StrategyEnsemble:
+		return NewEnsembleStrategy(opts.EnsembleModels, opts.EnsembleQuorum)
+	}
+	return nil, fmt.Errorf("不支持的策略: %s（可选 '%s', '%s', '%s', '%s'）", name, StrategyAI, StrategyRule, StrategyHybrid, StrategyEnsemble)
+}
+
+// AIStrategy 由AI模型给出全部决策
+type AIStrategy struct {
+	mcpClient *mcp.Client
+}
+
+// NewAIStrategy 创建AI策略
+func NewAIStrategy(mcpClient *mcp.Client) *AIStrategy {
+	return &AIStrategy{mcpClient: mcpClient}
+}
+
+// Name 策略类型
+func (s *AIStrategy) Name() string {
+	return StrategyAI
+}
+
+// Decide 调用AI获取完整决策
+func (s *AIStrategy) Decide(ctx *Context) (*FullDecision, error) {
+	return GetFullDecision(ctx, s.mcpClient)
+}
+
+// HybridStrategy AI给出决策，但开仓方向必须与规则扫描信号一致，否则改为观望
+type HybridStrategy struct {
+	ai   *AIStrategy
+	rule *RuleStrategy
+}
+
+// NewHybridStrategy 创建混合策略
+func NewHybridStrategy(mcpClient *mcp.Client) *HybridStrategy {
+	return &HybridStrategy{ai: NewAIStrategy(mcpClient), rule: NewRuleStrategy()}
+}
+
+// Name 策略类型
+func (s *HybridStrategy) Name() string {
+	return StrategyHybrid
+}
+
+// Decide 先由AI决策，再用规则信号过滤开仓（平仓和观望不受影响）
+func (s *HybridStrategy) Decide(ctx *Context) (*FullDecision, error) {
+	full, err := s.ai.Decide(ctx)
+	if err != nil {
+		return full, err
+	}
+
+	var vetoed []string
+	for i, d := range full.Decisions {
+		side := ""
+		switch d.Action {
+		case "open_long":
+			side = "long"
+		case "open_short":
+			side = "short"
+		default:
+			continue
+		}
+
+		signal, reason := "", "无市场数据"
+		if data, ok := ctx.MarketDataMap[d.Symbol]; ok {
+			signal, reason = ruleSignal(data)
+		}
+		if signal == side {
+			continue
+		}
+		vetoed = append(vetoed, fmt.Sprintf("%s %s（规则: %s）", d.Symbol, d.Action, reason))
+		full.Decisions[i] = Decision{
+			Symbol:    d.Symbol,
+			Action:    "wait",
+			Reasoning: fmt.Sprintf("规则信号不一致，放弃%s（%s）。AI理由: %s", d.Action, reason, d.Reasoning),
+		}
+	}
+
+	if len(vetoed) > 0 {
+		full.CoTTrace += "\n\n[规则过滤] 以下开仓与规则信号不一致，已改为观望:\n- " + strings.Join(vetoed, "\n- ")
+	}
+	return full, nil
+}
@@ -0,0 +1,88 @@
+package decision
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"nofx/mcp"
+)
+
+// structuredPromptSuffix 结构化输出模式追加到System Prompt的说明
+const structuredPromptSuffix = "\n\n# 🔧 提交方式\n\n" +
+	"本次请调用 `submit_decisions` 函数提交结果：`thinking` 填写思维链分析，`decisions` 填写上述格式的决策数组（字段含义不变）。\n"
+
+// decisionTool 提交决策的函数定义（参数结构与Decision一致）
+var decisionTool = mcp.Tool{
+	Name:        "submit_decisions",
+	Description: "提交本周期的思维链分析和交易决策",
+	Parameters: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"thinking": map[string]interface{}{
+				"type":        "string",
+				"description": "思维链分析（纯文本）",
+			},
+			"decisions": map[string]interface{}{
+				"type":  "array",
+				"items": decisionSchema,
+			},
+		},
+		"required": []string{"thinking", "decisions"},
+	},
+}
+
+// decisionSchema 单个决策的JSON Schema
+var decisionSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"symbol": map[string]interface{}{"type": "string", "description": "币种，如BTCUSDT；观望可填ALL"},
+		"action": map[string]interface{}{
+			"type": "string",
+			"enum": []string{"open_long", "open_short", "close_long", "close_short", "hold", "wait"},
+		},
+		"leverage":          map[string]interface{}{"type": "integer"},
+		"position_size_usd": map[string]interface{}{"type": "number"},
+		"stop_loss":         map[string]interface{}{"type": "number"},
+		"take_profit":       map[string]interface{}{"type": "number"},
+		"confidence":        map[string]interface{}{"type": "integer", "minimum": 0, "maximum": 100},
+		"risk_usd":          map[string]interface{}{"type": "number"},
+		"reasoning":         map[string]interface{}{"type": "string"},
+		"trailing_stop": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"mode":  map[string]interface{}{"type": "string", "enum": []string{"percent", "atr"}},
+				"value": map[string]interface{}{"type": "number"},
+			},
+			"required": []string{"mode", "value"},
+		},
+		"close_fraction":  map[string]interface{}{"type": "number", "minimum": 0, "maximum": 1},
+		"add_to_position": map[string]interface{}{"type": "boolean"},
+		"order_type":      map[string]interface{}{"type": "string", "enum": []string{"market", "limit", "post_only"}},
+		"limit_price":     map[string]interface{}{"type": "number"},
+		"margin_mode":     map[string]interface{}{"type": "string", "enum": []string{"isolated", "cross"}},
+	},
+	"required": []string{"symbol", "action", "reasoning"},
+}
+
+// getStructuredDecision 通过函数调用获取决策
+// 调用失败或参数无法解析时返回nil决策（调用方回退到文本解析），验证失败时同时返回决策和错误
+func getStructuredDecision(reqCtx context.Context, ctx *Context, mcpClient *mcp.Client, systemPrompt, userPrompt string) (*FullDecision, error) {
+	arguments, err := mcpClient.CallWithTool(reqCtx, systemPrompt+structuredPromptSuffix, userPrompt, decisionTool)
+	if err != nil {
+		return nil, fmt.Errorf("函数调用失败: %w", err)
+	}
+
+	var result struct {
+		Thinking  string     `json:"thinking"`
+		Decisions []Decision `json:"decisions"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &result); err != nil {
+		return nil, fmt.Errorf("解析函数参数失败: %w", err)
+	}
+
+	full := &FullDecision{CoTTrace: result.Thinking, Decisions: result.Decisions}
+	if err := validateDecisions(result.Decisions, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage, ctx.Positions, ctx.SymbolFilter, ctx.correlationCheck()); err != nil {
+		return full, fmt.Errorf("决策验证失败: %w\n\n=== AI思维链分析 ===\n%s", err, result.Thinking)
+	}
+	return full, nil
+}
@@ -1,4 +1,4 @@
-package trader
+package exchange
 
 import (
 	"context"
@@ -8,7 +8,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"math"
 	"math/big"
 	"net/http"
@@ -75,6 +74,32 @@ func NewAsterTrader(user, signer, privateKeyHex string) (*AsterTrader, error) {
 	}, nil
 }
 
+// asterConfig Aster配置
+type asterConfig struct {
+	User       string `json:"aster_user"`        // Aster主钱包地址
+	Signer     string `json:"aster_signer"`      // Aster API钱包地址
+	PrivateKey string `json:"aster_private_key"` // Aster API钱包私钥
+}
+
+func init() {
+	Register(Driver{
+		Name:        "aster",
+		Description: "Aster",
+		New: func(cfg Config) (Exchange, error) {
+			c := decodeConfig[asterConfig](cfg)
+			return NewAsterTrader(c.User, c.Signer, c.PrivateKey)
+		},
+		Validate: func(cfg Config) error {
+			c := decodeConfig[asterConfig](cfg)
+			if c.User == "" || c.Signer == "" || c.PrivateKey == "" {
+				return fmt.Errorf("使用Aster时必须配置aster_user, aster_signer和aster_private_key")
+			}
+			return nil
+		},
+		AccountKey: func(cfg Config) string { return decodeConfig[asterConfig](cfg).User },
+	})
+}
+
 // genNonce 生成微秒时间戳
 func (t *AsterTrader) genNonce() uint64 {
 	return uint64(time.Now().UnixMicro())
@@ -425,8 +450,8 @@ func (t *AsterTrader) doRequest(method, endpoint string, params map[string]inter
 	}
 }
 
-// GetBalance 获取账户余额
-func (t *AsterTrader) GetBalance() (map[string]interface{}, error) {
+// GetAccount 获取账户余额
+func (t *AsterTrader) GetAccount() (*Account, error) {
 	params := make(map[string]interface{})
 	body, err := t.request("GET", "/fapi/v3/balance", params)
 	if err != nil {
@@ -458,16 +483,11 @@ func (t *AsterTrader) GetBalance() (map[string]interface{}, error) {
 		}
 	}
 
-	// 返回与Binance相同的字段名，确保AutoTrader能正确解析
-	return map[string]interface{}{
-		"totalWalletBalance":    totalBalance,
-		"availableBalance":      availableBalance,
-		"totalUnrealizedProfit": crossUnPnl,
-	}, nil
+	return &Account{WalletBalance: totalBalance, AvailableBalance: availableBalance, UnrealizedProfit: crossUnPnl}, nil
 }
 
 // GetPositions 获取持仓信息
-func (t *AsterTrader) GetPositions() ([]map[string]interface{}, error) {
+func (t *AsterTrader) GetPositions() ([]Position, error) {
 	params := make(map[string]interface{})
 	body, err := t.request("GET", "/fapi/v3/positionRisk", params)
 	if err != nil {
@@ -479,7 +499,7 @@ func (t *AsterTrader) GetPositions() ([]map[string]interface{}, error) {
 		return nil, err
 	}
 
-	result := []map[string]interface{}{}
+	result := []Position{}
 	for _, pos := range positions {
 		posAmtStr, ok := pos["positionAmt"].(string)
 		if !ok {
@@ -498,30 +518,64 @@ func (t *AsterTrader) GetPositions() ([]map[string]interface{}, error) {
 		liquidationPrice, _ := strconv.ParseFloat(pos["liquidationPrice"].(string), 64)
 
 		// 判断方向（与Binance一致）
-		side := "long"
+		side := SideLong
 		if posAmt < 0 {
-			side = "short"
+			side = SideShort
 			posAmt = -posAmt
 		}
 
-		// 返回与Binance相同的字段名
-		result = append(result, map[string]interface{}{
-			"symbol":            pos["symbol"],
-			"side":              side,
-			"positionAmt":       posAmt,
-			"entryPrice":        entryPrice,
-			"markPrice":         markPrice,
-			"unRealizedProfit":  unRealizedProfit,
-			"leverage":          leverageVal,
-			"liquidationPrice":  liquidationPrice,
+		symbol, _ := pos["symbol"].(string)
+		result = append(result, Position{
+			Symbol:           symbol,
+			Side:             side,
+			Quantity:         posAmt,
+			EntryPrice:       entryPrice,
+			MarkPrice:        markPrice,
+			UnrealizedProfit: unRealizedProfit,
+			Leverage:         leverageVal,
+			LiquidationPrice: liquidationPrice,
 		})
 	}
 
 	return result, nil
 }
 
-// OpenLong 开多单
-func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+// PlaceOrder 下单（不支持限价开仓）
+func (t *AsterTrader) PlaceOrder(req OrderRequest) (*Order, error) {
+	switch req.Type {
+	case OrderTypeMarket:
+		if req.Side == SideShort {
+			return t.openShort(req.Symbol, req.Quantity, req.Leverage)
+		}
+		return t.openLong(req.Symbol, req.Quantity, req.Leverage)
+	case OrderTypeStopLoss:
+		return protectiveOrder(req, t.setStopLoss(req.Symbol, strings.ToUpper(req.Side), req.Quantity, req.Price))
+	case OrderTypeTakeProfit:
+		return protectiveOrder(req, t.setTakeProfit(req.Symbol, strings.ToUpper(req.Side), req.Quantity, req.Price))
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedOrderType, req.Type)
+}
+
+// ClosePosition 平仓
+func (t *AsterTrader) ClosePosition(symbol, side string, quantity float64) (*Order, error) {
+	if side == SideShort {
+		return t.closeShort(symbol, quantity)
+	}
+	return t.closeLong(symbol, quantity)
+}
+
+// asterOrder 转换Aster下单结果（JSON解析后orderId为float64）
+func asterOrder(symbol string, result map[string]interface{}) *Order {
+	order := &Order{Symbol: symbol}
+	if id, ok := result["orderId"].(float64); ok {
+		order.OrderID = strconv.FormatInt(int64(id), 10)
+	}
+	order.Status, _ = result["status"].(string)
+	return order
+}
+
+// openLong 开多单
+func (t *AsterTrader) openLong(symbol string, quantity float64, leverage int) (*Order, error) {
 	// 开仓前先取消所有挂单,防止残留挂单导致仓位叠加
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消挂单失败(继续开仓): %v", err)
@@ -584,11 +638,11 @@ func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int) (m
 		return nil, err
 	}
 
-	return result, nil
+	return asterOrder(symbol, result), nil
 }
 
-// OpenShort 开空单
-func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+// openShort 开空单
+func (t *AsterTrader) openShort(symbol string, quantity float64, leverage int) (*Order, error) {
 	// 开仓前先取消所有挂单,防止残留挂单导致仓位叠加
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消挂单失败(继续开仓): %v", err)
@@ -651,11 +705,11 @@ func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int) (
 		return nil, err
 	}
 
-	return result, nil
+	return asterOrder(symbol, result), nil
 }
 
-// CloseLong 平多单
-func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+// closeLong 平多单
+func (t *AsterTrader) closeLong(symbol string, quantity float64) (*Order, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -664,8 +718,8 @@ func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]int
 		}
 
 		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "long" {
-				quantity = pos["positionAmt"].(float64)
+			if pos.Symbol == symbol && pos.Side == SideLong {
+				quantity = pos.Quantity
 				break
 			}
 		}
@@ -714,6 +768,7 @@ func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]int
 		"timeInForce":  "GTC",
 		"quantity":     qtyStr,
 		"price":        priceStr,
+		"reduceOnly":   "true", // 只减仓，部分平仓数量超出持仓时不会反向开仓
 	}
 
 	body, err := t.request("POST", "/fapi/v3/order", params)
@@ -733,11 +788,11 @@ func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]int
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
-	return result, nil
+	return asterOrder(symbol, result), nil
 }
 
-// CloseShort 平空单
-func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+// closeShort 平空单
+func (t *AsterTrader) closeShort(symbol string, quantity float64) (*Order, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -746,9 +801,8 @@ func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]in
 		}
 
 		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "short" {
-				// Aster的GetPositions已经将空仓数量转换为正数，直接使用
-				quantity = pos["positionAmt"].(float64)
+			if pos.Symbol == symbol && pos.Side == SideShort {
+				quantity = pos.Quantity
 				break
 			}
 		}
@@ -797,6 +851,7 @@ func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]in
 		"timeInForce":  "GTC",
 		"quantity":     qtyStr,
 		"price":        priceStr,
+		"reduceOnly":   "true", // 只减仓，部分平仓数量超出持仓时不会反向开仓
 	}
 
 	body, err := t.request("POST", "/fapi/v3/order", params)
@@ -816,7 +871,7 @@ func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]in
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
-	return result, nil
+	return asterOrder(symbol, result), nil
 }
 
 // SetLeverage 设置杠杆倍数
@@ -857,8 +912,8 @@ func (t *AsterTrader) GetMarketPrice(symbol string) (float64, error) {
 	return strconv.ParseFloat(priceStr, 64)
 }
 
-// SetStopLoss 设置止损
-func (t *AsterTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+// setStopLoss 设置止损
+func (t *AsterTrader) setStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
 	side := "SELL"
 	if positionSide == "SHORT" {
 		side = "BUY"
@@ -898,8 +953,8 @@ func (t *AsterTrader) SetStopLoss(symbol string, positionSide string, quantity,
 	return err
 }
 
-// SetTakeProfit 设置止盈
-func (t *AsterTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+// setTakeProfit 设置止盈
+func (t *AsterTrader) setTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
 	side := "SELL"
 	if positionSide == "SHORT" {
 		side = "BUY"
@@ -949,7 +1004,7 @@ func (t *AsterTrader) CancelAllOrders(symbol string) error {
 	return err
 }
 
-// FormatQuantity 格式化数量（实现Trader接口）
+// FormatQuantity 格式化数量
 func (t *AsterTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
 	formatted, err := t.formatQuantity(symbol, quantity)
 	if err != nil {
@@ -957,3 +1012,8 @@ func (t *AsterTrader) FormatQuantity(symbol string, quantity float64) (string, e
 	}
 	return fmt.Sprintf("%v", formatted), nil
 }
+
+// GetSymbolFilters Aster不提供下单规则校验（数量在下单时按精度格式化）
+func (t *AsterTrader) GetSymbolFilters(symbol string) (*SymbolFilters, error) {
+	return nil, nil
+}
@@ -0,0 +1,1004 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"nofx/market"
+	"nofx/ratelimit"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// FuturesTrader 币安合约交易器
+type FuturesTrader struct {
+	client *futures.Client
+
+	// 余额缓存
+	cachedBalance     *Account
+	balanceCacheTime  time.Time
+	balanceCacheMutex sync.RWMutex
+
+	// 持仓缓存
+	cachedPositions     []Position
+	positionsCacheTime  time.Time
+	positionsCacheMutex sync.RWMutex
+
+	// 缓存有效期（15秒）
+	cacheDuration time.Duration
+
+	// 下单规则缓存（交易规则和杠杆分层很少变化，缓存1小时）
+	symbolRules     map[string]*SymbolFilters
+	symbolRulesTime map[string]time.Time
+	symbolRulesMu   sync.Mutex
+
+	// 各币种开仓使用的保证金模式（未设置时为逐仓）
+	marginModes   map[string]futures.MarginType
+	marginModesMu sync.Mutex
+}
+
+// symbolRulesCacheDuration 下单规则缓存有效期
+const symbolRulesCacheDuration = time.Hour
+
+// binanceSetupTimeout 创建合约交易器时检查和切换持仓模式的超时
+const binanceSetupTimeout = 15 * time.Second
+
+// NewFuturesTrader 创建合约交易器（不访问交易所，下单前需通过setupDualSidePosition确认持仓模式）
+func NewFuturesTrader(apiKey, secretKey string) *FuturesTrader {
+	client := futures.NewClient(apiKey, secretKey)
+	client.HTTPClient = ratelimit.NewBinanceClient(0) // 与其他trader共享Binance权重限制
+	t := &FuturesTrader{
+		client:          client,
+		cacheDuration:   15 * time.Second, // 15秒缓存
+		symbolRules:     make(map[string]*SymbolFilters),
+		symbolRulesTime: make(map[string]time.Time),
+		marginModes:     make(map[string]futures.MarginType),
+	}
+	return t
+}
+
+// setupDualSidePosition 确保账户为双向持仓模式：系统按多空分别管理持仓，下单都带PositionSide，单向持仓账户会拒单
+// 单向持仓时尝试切换；账户有持仓或挂单时币安拒绝切换，此时返回错误，不能继续创建trader
+func (t *FuturesTrader) setupDualSidePosition(ctx context.Context) error {
+	mode, err := t.client.NewGetPositionModeService().Do(ctx)
+	if err != nil {
+		return fmt.Errorf("查询币安持仓模式失败: %w", err)
+	}
+	if mode.DualSidePosition {
+		return nil
+	}
+
+	if err := t.client.NewChangePositionModeService().DualSide(true).Do(ctx); err != nil && !contains(err.Error(), "No need to change") {
+		return fmt.Errorf("切换币安双向持仓模式失败（账户有持仓或挂单时无法切换，请先平仓撤单或在币安手动切换）: %w", err)
+	}
+	mode, err = t.client.NewGetPositionModeService().Do(ctx)
+	if err != nil {
+		return fmt.Errorf("确认币安持仓模式失败: %w", err)
+	}
+	if !mode.DualSidePosition {
+		return fmt.Errorf("币安账户仍为单向持仓模式，请在币安手动切换为双向持仓")
+	}
+	log.Printf("✓ 币安账户已切换为双向持仓模式")
+	return nil
+}
+
+// binanceConfig 币安配置（合约和现货共用同一对API密钥）
+type binanceConfig struct {
+	APIKey    string `json:"binance_api_key"`
+	SecretKey string `json:"binance_secret_key"`
+}
+
+// validateBinanceConfig 校验币安API密钥
+func validateBinanceConfig(cfg Config) error {
+	c := decodeConfig[binanceConfig](cfg)
+	if c.APIKey == "" || c.SecretKey == "" {
+		return fmt.Errorf("使用币安时必须配置binance_api_key和binance_secret_key")
+	}
+	return nil
+}
+
+// binanceAccountKey 币安账户按API密钥区分（现货与合约账户由交易所名称区分）
+func binanceAccountKey(cfg Config) string {
+	return decodeConfig[binanceConfig](cfg).APIKey
+}
+
+func init() {
+	Register(Driver{
+		Name:        "binance",
+		Description: "币安合约",
+		New: func(cfg Config) (Exchange, error) {
+			c := decodeConfig[binanceConfig](cfg)
+			t := NewFuturesTrader(c.APIKey, c.SecretKey)
+			ctx, cancel := context.WithTimeout(context.Background(), binanceSetupTimeout)
+			defer cancel()
+			if err := t.setupDualSidePosition(ctx); err != nil {
+				return nil, err
+			}
+			return t, nil
+		},
+		Validate:   validateBinanceConfig,
+		AccountKey: binanceAccountKey,
+	})
+}
+
+// GetAccount 获取账户余额（带缓存）
+func (t *FuturesTrader) GetAccount() (*Account, error) {
+	// 先检查缓存是否有效
+	t.balanceCacheMutex.RLock()
+	if t.cachedBalance != nil && time.Since(t.balanceCacheTime) < t.cacheDuration {
+		cacheAge := time.Since(t.balanceCacheTime)
+		t.balanceCacheMutex.RUnlock()
+		log.Printf("✓ 使用缓存的账户余额（缓存时间: %.1f秒前）", cacheAge.Seconds())
+		return t.cachedBalance, nil
+	}
+	t.balanceCacheMutex.RUnlock()
+
+	// 缓存过期或不存在，调用API
+	log.Printf("🔄 缓存过期，正在调用币安API获取账户余额...")
+	account, err := t.client.NewGetAccountService().Do(context.Background())
+	if err != nil {
+		log.Printf("❌ 币安API调用失败: %v", err)
+		return nil, fmt.Errorf("获取账户信息失败: %w", err)
+	}
+
+	result := &Account{}
+	result.WalletBalance, _ = strconv.ParseFloat(account.TotalWalletBalance, 64)
+	result.AvailableBalance, _ = strconv.ParseFloat(account.AvailableBalance, 64)
+	result.UnrealizedProfit, _ = strconv.ParseFloat(account.TotalUnrealizedProfit, 64)
+
+	log.Printf("✓ 币安API返回: 总余额=%s, 可用=%s, 未实现盈亏=%s",
+		account.TotalWalletBalance,
+		account.AvailableBalance,
+		account.TotalUnrealizedProfit)
+
+	// 更新缓存
+	t.balanceCacheMutex.Lock()
+	t.cachedBalance = result
+	t.balanceCacheTime = time.Now()
+	t.balanceCacheMutex.Unlock()
+
+	return result, nil
+}
+
+// GetPositions 获取所有持仓（带缓存）
+func (t *FuturesTrader) GetPositions() ([]Position, error) {
+	// 先检查缓存是否有效
+	t.positionsCacheMutex.RLock()
+	if t.cachedPositions != nil && time.Since(t.positionsCacheTime) < t.cacheDuration {
+		cacheAge := time.Since(t.positionsCacheTime)
+		t.positionsCacheMutex.RUnlock()
+		log.Printf("✓ 使用缓存的持仓信息（缓存时间: %.1f秒前）", cacheAge.Seconds())
+		return t.cachedPositions, nil
+	}
+	t.positionsCacheMutex.RUnlock()
+
+	// 缓存过期或不存在，调用API
+	log.Printf("🔄 缓存过期，正在调用币安API获取持仓信息...")
+	positions, err := t.client.NewGetPositionRiskService().Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	var result []Position
+	for _, pos := range positions {
+		posAmt, _ := strconv.ParseFloat(pos.PositionAmt, 64)
+		if posAmt == 0 {
+			continue // 跳过无持仓的
+		}
+
+		p := Position{Symbol: pos.Symbol, MarginMode: pos.MarginType} // isolated / cross
+		p.EntryPrice, _ = strconv.ParseFloat(pos.EntryPrice, 64)
+		p.MarkPrice, _ = strconv.ParseFloat(pos.MarkPrice, 64)
+		p.UnrealizedProfit, _ = strconv.ParseFloat(pos.UnRealizedProfit, 64)
+		p.Leverage, _ = strconv.ParseFloat(pos.Leverage, 64)
+		p.LiquidationPrice, _ = strconv.ParseFloat(pos.LiquidationPrice, 64)
+
+		// 判断方向（空仓数量为负）
+		if posAmt > 0 {
+			p.Side, p.Quantity = SideLong, posAmt
+		} else {
+			p.Side, p.Quantity = SideShort, -posAmt
+		}
+
+		result = append(result, p)
+	}
+
+	// 更新缓存
+	t.positionsCacheMutex.Lock()
+	t.cachedPositions = result
+	t.positionsCacheTime = time.Now()
+	t.positionsCacheMutex.Unlock()
+
+	return result, nil
+}
+
+// SetLeverage 设置杠杆（智能判断+冷却期）
+func (t *FuturesTrader) SetLeverage(symbol string, leverage int) error {
+	// 先尝试获取当前杠杆（从持仓信息）
+	currentLeverage := 0
+	positions, err := t.GetPositions()
+	if err == nil {
+		for _, pos := range positions {
+			if pos.Symbol == symbol {
+				currentLeverage = int(pos.Leverage)
+				break
+			}
+		}
+	}
+
+	// 如果当前杠杆已经是目标杠杆，跳过
+	if currentLeverage == leverage && currentLeverage > 0 {
+		log.Printf("  ✓ %s 杠杆已是 %dx，无需切换", symbol, leverage)
+		return nil
+	}
+
+	// 切换杠杆
+	_, err = t.client.NewChangeLeverageService().
+		Symbol(symbol).
+		Leverage(leverage).
+		Do(context.Background())
+
+	if err != nil {
+		// 如果错误信息包含"No need to change"，说明杠杆已经是目标值
+		if contains(err.Error(), "No need to change") {
+			log.Printf("  ✓ %s 杠杆已是 %dx", symbol, leverage)
+			return nil
+		}
+		return fmt.Errorf("设置杠杆失败: %w", err)
+	}
+
+	log.Printf("  ✓ %s 杠杆已切换为 %dx", symbol, leverage)
+
+	// 切换杠杆后等待5秒（避免冷却期错误）
+	log.Printf("  ⏱ 等待5秒冷却期...")
+	time.Sleep(5 * time.Second)
+
+	return nil
+}
+
+// SetMarginType 设置保证金模式
+func (t *FuturesTrader) SetMarginType(symbol string, marginType futures.MarginType) error {
+	err := t.client.NewChangeMarginTypeService().
+		Symbol(symbol).
+		MarginType(marginType).
+		Do(context.Background())
+
+	if err != nil {
+		// 如果已经是该模式，不算错误
+		if contains(err.Error(), "No need to change") {
+			log.Printf("  ✓ %s 保证金模式已是 %s", symbol, marginType)
+			return nil
+		}
+		// 另一方向已有持仓时无法切换（同一币种多空共用保证金模式），沿用当前模式
+		if contains(err.Error(), "-4048") {
+			log.Printf("  ⚠ %s 另一方向已有持仓，无法切换为 %s，沿用当前保证金模式", symbol, marginType)
+			return nil
+		}
+		return fmt.Errorf("设置保证金模式失败: %w", err)
+	}
+
+	log.Printf("  ✓ %s 保证金模式已切换为 %s", symbol, marginType)
+
+	// 切换保证金模式后等待3秒（避免冷却期错误）
+	log.Printf("  ⏱ 等待3秒冷却期...")
+	time.Sleep(3 * time.Second)
+
+	return nil
+}
+
+// SetMarginMode 设置该币种后续开仓使用的保证金模式（实现MarginModeSetter，开仓时生效）
+func (t *FuturesTrader) SetMarginMode(symbol, mode string) error {
+	var marginType futures.MarginType
+	switch mode {
+	case "isolated":
+		marginType = futures.MarginTypeIsolated
+	case "cross":
+		marginType = futures.MarginTypeCrossed
+	default:
+		return fmt.Errorf("无效的保证金模式: %s", mode)
+	}
+	t.marginModesMu.Lock()
+	t.marginModes[symbol] = marginType
+	t.marginModesMu.Unlock()
+	return nil
+}
+
+// marginType 该币种开仓使用的保证金模式（默认逐仓）
+func (t *FuturesTrader) marginType(symbol string) futures.MarginType {
+	t.marginModesMu.Lock()
+	defer t.marginModesMu.Unlock()
+	if marginType, ok := t.marginModes[symbol]; ok {
+		return marginType
+	}
+	return futures.MarginTypeIsolated
+}
+
+// PlaceOrder 下单
+func (t *FuturesTrader) PlaceOrder(req OrderRequest) (*Order, error) {
+	switch req.Type {
+	case OrderTypeMarket:
+		if req.Side == SideShort {
+			return t.openShort(req.Symbol, req.Quantity, req.Leverage, req.ClientOrderID)
+		}
+		return t.openLong(req.Symbol, req.Quantity, req.Leverage, req.ClientOrderID)
+	case OrderTypeLimit, OrderTypePostOnly:
+		return t.openLimit(req.Symbol, req.Side, req.Quantity, req.Price, req.Leverage, req.Type == OrderTypePostOnly, req.ClientOrderID)
+	case OrderTypeStopLoss:
+		return protectiveOrder(req, t.setStopLoss(req.Symbol, strings.ToUpper(req.Side), req.Quantity, req.Price))
+	case OrderTypeTakeProfit:
+		return protectiveOrder(req, t.setTakeProfit(req.Symbol, strings.ToUpper(req.Side), req.Quantity, req.Price))
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedOrderType, req.Type)
+}
+
+// ClosePosition 市价平仓
+func (t *FuturesTrader) ClosePosition(symbol, side string, quantity float64) (*Order, error) {
+	if side == SideShort {
+		return t.closeShort(symbol, quantity)
+	}
+	return t.closeLong(symbol, quantity)
+}
+
+// openLong 开多仓
+func (t *FuturesTrader) openLong(symbol string, quantity float64, leverage int, clientOrderID string) (*Order, error) {
+	// 先取消该方向的委托单（清理旧的止损止盈单，不影响另一方向持仓的保护）
+	if err := t.CancelSideOrders(symbol, "long"); err != nil {
+		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
+	}
+
+	// 设置杠杆
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	// 设置保证金模式（默认逐仓）
+	if err := t.SetMarginType(symbol, t.marginType(symbol)); err != nil {
+		return nil, err
+	}
+
+	// 格式化数量到正确精度
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	// 创建市价买入订单
+	svc := t.client.NewCreateOrderService().
+		Symbol(symbol).
+		Side(futures.SideTypeBuy).
+		PositionSide(futures.PositionSideTypeLong).
+		Type(futures.OrderTypeMarket).
+		NewOrderResponseType(futures.NewOrderRespTypeRESULT).
+		Quantity(quantityStr)
+	if clientOrderID != "" {
+		svc.NewClientOrderID(clientOrderID)
+	}
+	order, err := svc.Do(context.Background())
+
+	if err != nil {
+		return nil, fmt.Errorf("开多仓失败: %w", err)
+	}
+
+	log.Printf("✓ 开多仓成功: %s 数量: %s", symbol, quantityStr)
+	log.Printf("  订单ID: %d", order.OrderID)
+
+	return binanceOrder(order), nil
+}
+
+// openShort 开空仓
+func (t *FuturesTrader) openShort(symbol string, quantity float64, leverage int, clientOrderID string) (*Order, error) {
+	// 先取消该方向的委托单（清理旧的止损止盈单，不影响另一方向持仓的保护）
+	if err := t.CancelSideOrders(symbol, "short"); err != nil {
+		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
+	}
+
+	// 设置杠杆
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	// 设置保证金模式（默认逐仓）
+	if err := t.SetMarginType(symbol, t.marginType(symbol)); err != nil {
+		return nil, err
+	}
+
+	// 格式化数量到正确精度
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	// 创建市价卖出订单
+	svc := t.client.NewCreateOrderService().
+		Symbol(symbol).
+		Side(futures.SideTypeSell).
+		PositionSide(futures.PositionSideTypeShort).
+		Type(futures.OrderTypeMarket).
+		NewOrderResponseType(futures.NewOrderRespTypeRESULT).
+		Quantity(quantityStr)
+	if clientOrderID != "" {
+		svc.NewClientOrderID(clientOrderID)
+	}
+	order, err := svc.Do(context.Background())
+
+	if err != nil {
+		return nil, fmt.Errorf("开空仓失败: %w", err)
+	}
+
+	log.Printf("✓ 开空仓成功: %s 数量: %s", symbol, quantityStr)
+	log.Printf("  订单ID: %d", order.OrderID)
+
+	return binanceOrder(order), nil
+}
+
+// closeLong 平多仓
+func (t *FuturesTrader) closeLong(symbol string, quantity float64) (*Order, error) {
+	// 如果数量为0，获取当前持仓数量
+	if quantity == 0 {
+		positions, err := t.GetPositions()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pos := range positions {
+			if pos.Symbol == symbol && pos.Side == SideLong {
+				quantity = pos.Quantity
+				break
+			}
+		}
+
+		if quantity == 0 {
+			return nil, fmt.Errorf("没有找到 %s 的多仓", symbol)
+		}
+	}
+
+	// 格式化数量
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	// 创建市价卖出订单（平多）
+	order, err := t.client.NewCreateOrderService().
+		Symbol(symbol).
+		Side(futures.SideTypeSell).
+		PositionSide(futures.PositionSideTypeLong).
+		Type(futures.OrderTypeMarket).
+		NewOrderResponseType(futures.NewOrderRespTypeRESULT).
+		Quantity(quantityStr).
+		Do(context.Background())
+
+	if err != nil {
+		return nil, fmt.Errorf("平多仓失败: %w", err)
+	}
+
+	log.Printf("✓ 平多仓成功: %s 数量: %s", symbol, quantityStr)
+
+	// 平仓后取消该方向的挂单（止损止盈单）
+	if err := t.CancelSideOrders(symbol, "long"); err != nil {
+		log.Printf("  ⚠ 取消挂单失败: %v", err)
+	}
+
+	return binanceOrder(order), nil
+}
+
+// closeShort 平空仓
+func (t *FuturesTrader) closeShort(symbol string, quantity float64) (*Order, error) {
+	// 如果数量为0，获取当前持仓数量
+	if quantity == 0 {
+		positions, err := t.GetPositions()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pos := range positions {
+			if pos.Symbol == symbol && pos.Side == SideShort {
+				quantity = pos.Quantity
+				break
+			}
+		}
+
+		if quantity == 0 {
+			return nil, fmt.Errorf("没有找到 %s 的空仓", symbol)
+		}
+	}
+
+	// 格式化数量
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	// 创建市价买入订单（平空）
+	order, err := t.client.NewCreateOrderService().
+		Symbol(symbol).
+		Side(futures.SideTypeBuy).
+		PositionSide(futures.PositionSideTypeShort).
+		Type(futures.OrderTypeMarket).
+		NewOrderResponseType(futures.NewOrderRespTypeRESULT).
+		Quantity(quantityStr).
+		Do(context.Background())
+
+	if err != nil {
+		return nil, fmt.Errorf("平空仓失败: %w", err)
+	}
+
+	log.Printf("✓ 平空仓成功: %s 数量: %s", symbol, quantityStr)
+
+	// 平仓后取消该方向的挂单（止损止盈单）
+	if err := t.CancelSideOrders(symbol, "short"); err != nil {
+		log.Printf("  ⚠ 取消挂单失败: %v", err)
+	}
+
+	return binanceOrder(order), nil
+}
+
+// CancelAllOrders 取消该币种的所有挂单
+func (t *FuturesTrader) CancelAllOrders(symbol string) error {
+	err := t.client.NewCancelAllOpenOrdersService().
+		Symbol(symbol).
+		Do(context.Background())
+
+	if err != nil {
+		return fmt.Errorf("取消挂单失败: %w", err)
+	}
+
+	log.Printf("  ✓ 已取消 %s 的所有挂单", symbol)
+	return nil
+}
+
+// CancelSideOrders 只撤销该币种某个方向的挂单（实现SideOrderCanceler，双向持仓时不影响另一方向的止损止盈）
+func (t *FuturesTrader) CancelSideOrders(symbol, positionSide string) error {
+	orders, err := t.client.NewListOpenOrdersService().Symbol(symbol).Do(context.Background())
+	if err != nil {
+		return fmt.Errorf("获取挂单失败: %w", err)
+	}
+
+	posSide := futures.PositionSideTypeLong
+	if positionSide == "short" {
+		posSide = futures.PositionSideTypeShort
+	}
+	cancelled := 0
+	for _, o := range orders {
+		// 单向持仓模式下的订单（BOTH）无法区分方向，一并撤销
+		if o.PositionSide != posSide && o.PositionSide != futures.PositionSideTypeBoth {
+			continue
+		}
+		if _, err := t.client.NewCancelOrderService().Symbol(symbol).OrderID(o.OrderID).Do(context.Background()); err != nil {
+			return fmt.Errorf("取消挂单失败: %w", err)
+		}
+		cancelled++
+	}
+	if cancelled > 0 {
+		log.Printf("  ✓ 已取消 %s %s 方向的 %d 个挂单", symbol, positionSide, cancelled)
+	}
+	return nil
+}
+
+// GetMarketPrice 获取市场价格
+func (t *FuturesTrader) GetMarketPrice(symbol string) (float64, error) {
+	prices, err := t.client.NewListPricesService().Symbol(symbol).Do(context.Background())
+	if err != nil {
+		return 0, fmt.Errorf("获取价格失败: %w", err)
+	}
+
+	if len(prices) == 0 {
+		return 0, fmt.Errorf("未找到价格")
+	}
+
+	price, err := strconv.ParseFloat(prices[0].Price, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return price, nil
+}
+
+// binanceOrder 转换币安下单结果
+func binanceOrder(order *futures.CreateOrderResponse) *Order {
+	result := &Order{OrderID: strconv.FormatInt(order.OrderID, 10), Symbol: order.Symbol, Status: string(order.Status)}
+	// 市价单使用RESULT响应，直接返回成交均价（ACK响应和未成交的限价单为0）
+	result.AvgPrice, _ = strconv.ParseFloat(order.AvgPrice, 64)
+	return result
+}
+
+// CalculatePositionSize 计算仓位大小
+func (t *FuturesTrader) CalculatePositionSize(balance, riskPercent, price float64, leverage int) float64 {
+	riskAmount := balance * (riskPercent / 100.0)
+	positionValue := riskAmount * float64(leverage)
+	quantity := positionValue / price
+	return quantity
+}
+
+// setStopLoss 设置止损单
+func (t *FuturesTrader) setStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	var side futures.SideType
+	var posSide futures.PositionSideType
+
+	if positionSide == "LONG" {
+		side = futures.SideTypeSell
+		posSide = futures.PositionSideTypeLong
+	} else {
+		side = futures.SideTypeBuy
+		posSide = futures.PositionSideTypeShort
+	}
+
+	// 格式化数量
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.client.NewCreateOrderService().
+		Symbol(symbol).
+		Side(side).
+		PositionSide(posSide).
+		Type(futures.OrderTypeStopMarket).
+		StopPrice(fmt.Sprintf("%.8f", stopPrice)).
+		Quantity(quantityStr).
+		WorkingType(futures.WorkingTypeContractPrice).
+		ClosePosition(true).
+		Do(context.Background())
+
+	if err != nil {
+		return fmt.Errorf("设置止损失败: %w", err)
+	}
+
+	log.Printf("  止损价设置: %.4f", stopPrice)
+	return nil
+}
+
+// setTakeProfit 设置止盈单
+func (t *FuturesTrader) setTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	var side futures.SideType
+	var posSide futures.PositionSideType
+
+	if positionSide == "LONG" {
+		side = futures.SideTypeSell
+		posSide = futures.PositionSideTypeLong
+	} else {
+		side = futures.SideTypeBuy
+		posSide = futures.PositionSideTypeShort
+	}
+
+	// 格式化数量
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.client.NewCreateOrderService().
+		Symbol(symbol).
+		Side(side).
+		PositionSide(posSide).
+		Type(futures.OrderTypeTakeProfitMarket).
+		StopPrice(fmt.Sprintf("%.8f", takeProfitPrice)).
+		Quantity(quantityStr).
+		WorkingType(futures.WorkingTypeContractPrice).
+		ClosePosition(true).
+		Do(context.Background())
+
+	if err != nil {
+		return fmt.Errorf("设置止盈失败: %w", err)
+	}
+
+	log.Printf("  止盈价设置: %.4f", takeProfitPrice)
+	return nil
+}
+
+// openLimit 下开仓限价单（postOnly使用GTX，会立即成交时被交易所拒绝）
+func (t *FuturesTrader) openLimit(symbol, positionSide string, quantity, price float64, leverage int, postOnly bool, clientOrderID string) (*Order, error) {
+	// 设置杠杆
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	// 设置保证金模式（默认逐仓）
+	if err := t.SetMarginType(symbol, t.marginType(symbol)); err != nil {
+		return nil, err
+	}
+
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	priceStr, err := t.formatPrice(symbol, price)
+	if err != nil {
+		return nil, err
+	}
+
+	side, posSide := futures.SideTypeBuy, futures.PositionSideTypeLong
+	if positionSide == "short" {
+		side, posSide = futures.SideTypeSell, futures.PositionSideTypeShort
+	}
+	timeInForce := futures.TimeInForceTypeGTC
+	if postOnly {
+		timeInForce = futures.TimeInForceTypeGTX
+	}
+
+	svc := t.client.NewCreateOrderService().
+		Symbol(symbol).
+		Side(side).
+		PositionSide(posSide).
+		Type(futures.OrderTypeLimit).
+		TimeInForce(timeInForce).
+		Price(priceStr).
+		Quantity(quantityStr)
+	if clientOrderID != "" {
+		svc.NewClientOrderID(clientOrderID)
+	}
+	order, err := svc.Do(context.Background())
+
+	if err != nil {
+		return nil, fmt.Errorf("下限价单失败: %w", err)
+	}
+
+	log.Printf("✓ 限价单已提交: %s %s 数量: %s 价格: %s (%s)", symbol, positionSide, quantityStr, priceStr, timeInForce)
+
+	return binanceOrder(order), nil
+}
+
+// GetOrder 查询订单成交状态
+func (t *FuturesTrader) GetOrder(symbol, orderID string) (*OrderStatus, error) {
+	id, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+
+	order, err := t.client.NewGetOrderService().Symbol(symbol).OrderID(id).Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+
+	status := &OrderStatus{}
+	status.FilledQty, _ = strconv.ParseFloat(order.ExecutedQuantity, 64)
+	status.AvgPrice, _ = strconv.ParseFloat(order.AvgPrice, 64)
+	status.Status = binanceOrderStatus(order.Status)
+	return status, nil
+}
+
+// binanceOrderStatus 映射币安订单状态（拒绝/过期视为已撤销）
+func binanceOrderStatus(s futures.OrderStatusType) string {
+	switch s {
+	case futures.OrderStatusTypeNew:
+		return OrderStatusNew
+	case futures.OrderStatusTypePartiallyFilled:
+		return OrderStatusPartiallyFilled
+	case futures.OrderStatusTypeFilled:
+		return OrderStatusFilled
+	default:
+		return OrderStatusCanceled
+	}
+}
+
+// FindClientOrder 按客户端订单ID查询订单（Binance保留最近的订单记录，找不到时返回ErrOrderNotFound）
+func (t *FuturesTrader) FindClientOrder(symbol, clientOrderID string) (*Order, error) {
+	order, err := t.client.NewGetOrderService().Symbol(symbol).OrigClientOrderID(clientOrderID).Do(context.Background())
+	if err != nil {
+		var apiErr *common.APIError
+		if errors.As(err, &apiErr) && apiErr.Code == -2013 {
+			return nil, ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+
+	result := &Order{OrderID: strconv.FormatInt(order.OrderID, 10), Symbol: order.Symbol, Status: binanceOrderStatus(order.Status)}
+	result.AvgPrice, _ = strconv.ParseFloat(order.AvgPrice, 64)
+	return result, nil
+}
+
+// CancelOrder 撤销单个订单
+func (t *FuturesTrader) CancelOrder(symbol, orderID string) error {
+	id, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+
+	_, err = t.client.NewCancelOrderService().Symbol(symbol).OrderID(id).Do(context.Background())
+	if err != nil {
+		return fmt.Errorf("撤销订单失败: %w", err)
+	}
+	return nil
+}
+
+// GetCommission 查询订单成交的实际手续费（合约手续费以USDT计）
+func (t *FuturesTrader) GetCommission(symbol, orderID string) (float64, error) {
+	id, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+
+	trades, err := t.client.NewListAccountTradeService().Symbol(symbol).OrderID(id).Do(context.Background())
+	if err != nil {
+		return 0, fmt.Errorf("查询成交记录失败: %w", err)
+	}
+	if len(trades) == 0 {
+		return 0, fmt.Errorf("订单 %s 尚无成交记录", orderID)
+	}
+
+	total := 0.0
+	for _, trade := range trades {
+		commission, _ := strconv.ParseFloat(trade.Commission, 64)
+		total += commission
+	}
+	return total, nil
+}
+
+// GetFundingFees 查询自since以来的资金费净支出（币安资金费流水不区分多空方向，按币种合计）
+func (t *FuturesTrader) GetFundingFees(symbol, side string, since time.Time) (float64, error) {
+	incomes, err := t.client.NewGetIncomeHistoryService().Symbol(symbol).IncomeType("FUNDING_FEE").
+		StartTime(since.UnixMilli()).Limit(1000).Do(context.Background())
+	if err != nil {
+		return 0, fmt.Errorf("查询资金费流水失败: %w", err)
+	}
+
+	// 流水中负数为支出，转为支出为正
+	paid := 0.0
+	for _, income := range incomes {
+		amount, _ := strconv.ParseFloat(income.Income, 64)
+		paid -= amount
+	}
+	return paid, nil
+}
+
+// GetOpenOrders 获取所有币种的当前挂单
+func (t *FuturesTrader) GetOpenOrders() ([]OpenOrder, error) {
+	orders, err := t.client.NewListOpenOrdersService().Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取挂单失败: %w", err)
+	}
+
+	result := make([]OpenOrder, 0, len(orders))
+	for _, o := range orders {
+		side := ""
+		switch o.PositionSide {
+		case futures.PositionSideTypeLong:
+			side = "long"
+		case futures.PositionSideTypeShort:
+			side = "short"
+		}
+		protective := o.ReduceOnly || o.ClosePosition
+		switch o.Type {
+		case futures.OrderTypeStopMarket, futures.OrderTypeTakeProfitMarket, futures.OrderTypeStop, futures.OrderTypeTakeProfit, futures.OrderTypeTrailingStopMarket:
+			protective = true
+		}
+		result = append(result, OpenOrder{
+			Symbol:       o.Symbol,
+			OrderID:      strconv.FormatInt(o.OrderID, 10),
+			Type:         string(o.Type),
+			PositionSide: side,
+			Protective:   protective,
+		})
+	}
+	return result, nil
+}
+
+// formatPrice 按PRICE_FILTER的tickSize格式化价格（交易规则来自market的共享缓存）
+func (t *FuturesTrader) formatPrice(symbol string, price float64) (string, error) {
+	info, ok := market.GetSymbolInfo(symbol)
+	if !ok {
+		return strconv.FormatFloat(price, 'f', -1, 64), nil
+	}
+	return info.FormatPrice(price), nil
+}
+
+// GetSymbolFilters 获取LOT_SIZE/MIN_NOTIONAL规则和杠杆分层（杠杆分层带缓存）
+func (t *FuturesTrader) GetSymbolFilters(symbol string) (*SymbolFilters, error) {
+	t.symbolRulesMu.Lock()
+	defer t.symbolRulesMu.Unlock()
+	if rules, ok := t.symbolRules[symbol]; ok && time.Since(t.symbolRulesTime[symbol]) < symbolRulesCacheDuration {
+		return rules, nil
+	}
+
+	info, ok := market.GetSymbolInfo(symbol)
+	if !ok {
+		return nil, fmt.Errorf("未找到 %s 的交易规则", symbol)
+	}
+	rules := &SymbolFilters{StepSize: info.StepSize, MinQty: info.MinQty, MinNotional: info.MinNotional}
+
+	brackets, err := t.client.NewGetLeverageBracketService().Symbol(symbol).Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取杠杆分层失败: %w", err)
+	}
+	for _, lb := range brackets {
+		if lb.Symbol != symbol {
+			continue
+		}
+		for _, b := range lb.Brackets {
+			rules.Brackets = append(rules.Brackets, LeverageBracket{
+				NotionalCap:     b.NotionalCap,
+				MaxLeverage:     b.InitialLeverage,
+				MaintMarginRate: b.MaintMarginRatio,
+				MaintAmount:     b.Cum,
+			})
+		}
+	}
+	sort.Slice(rules.Brackets, func(i, j int) bool { return rules.Brackets[i].NotionalCap < rules.Brackets[j].NotionalCap })
+
+	t.symbolRules[symbol] = rules
+	t.symbolRulesTime[symbol] = time.Now()
+	return rules, nil
+}
+
+// GetSymbolPrecision 获取交易对的数量精度
+func (t *FuturesTrader) GetSymbolPrecision(symbol string) (int, error) {
+	info, ok := market.GetSymbolInfo(symbol)
+	if !ok {
+		log.Printf("  ⚠ %s 未找到精度信息，使用默认精度3", symbol)
+		return 3, nil // 默认精度为3
+	}
+	return info.QuantityPrecision(), nil
+}
+
+// calculatePrecision 从stepSize计算精度
+func calculatePrecision(stepSize string) int {
+	// 去除尾部的0
+	stepSize = trimTrailingZeros(stepSize)
+
+	// 查找小数点
+	dotIndex := -1
+	for i := 0; i < len(stepSize); i++ {
+		if stepSize[i] == '.' {
+			dotIndex = i
+			break
+		}
+	}
+
+	// 如果没有小数点或小数点在最后，精度为0
+	if dotIndex == -1 || dotIndex == len(stepSize)-1 {
+		return 0
+	}
+
+	// 返回小数点后的位数
+	return len(stepSize) - dotIndex - 1
+}
+
+// trimTrailingZeros 去除尾部的0
+func trimTrailingZeros(s string) string {
+	// 如果没有小数点，直接返回
+	if !stringContains(s, ".") {
+		return s
+	}
+
+	// 从后向前遍历，去除尾部的0
+	for len(s) > 0 && s[len(s)-1] == '0' {
+		s = s[:len(s)-1]
+	}
+
+	// 如果最后一位是小数点，也去掉
+	if len(s) > 0 && s[len(s)-1] == '.' {
+		s = s[:len(s)-1]
+	}
+
+	return s
+}
+
+// FormatQuantity 格式化数量到正确的精度（按LOT_SIZE步长向下取整）
+func (t *FuturesTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	info, ok := market.GetSymbolInfo(symbol)
+	if !ok {
+		// 如果获取失败，使用默认格式
+		return fmt.Sprintf("%.3f", quantity), nil
+	}
+	return info.FormatQuantity(quantity), nil
+}
+
+// 辅助函数
+func contains(s, substr string) bool {
+	return len(s) >= len(substr) && stringContains(s, substr)
+}
+
+func stringContains(s, substr string) bool {
+	for i := 0; i <= len(s)-len(substr); i++ {
+		if s[i:i+len(substr)] == substr {
+			return true
+		}
+	}
+	return false
+}
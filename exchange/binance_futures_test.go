@@ -0,0 +1,91 @@
+package exchange
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakePositionMode 模拟币安持仓模式接口：dual为当前模式，changeErr不为空时拒绝切换，stuck为true时切换成功但模式不变
+func fakePositionMode(t *testing.T, dual bool, changeErr string, stuck bool) (*FuturesTrader, *int) {
+	changes := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/fapi/v1/positionSide/dual" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == http.MethodGet {
+			if dual {
+				w.Write([]byte(`{"dualSidePosition":true}`))
+			} else {
+				w.Write([]byte(`{"dualSidePosition":false}`))
+			}
+			return
+		}
+		changes++
+		if changeErr != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(changeErr))
+			return
+		}
+		if !stuck {
+			dual = true
+		}
+		w.Write([]byte(`{"code":200,"msg":"success"}`))
+	}))
+	t.Cleanup(srv.Close)
+	ft := NewFuturesTrader("key", "secret")
+	ft.client.BaseURL = srv.URL
+	ft.client.HTTPClient = srv.Client()
+	return ft, &changes
+}
+
+func TestSetupDualSidePosition(t *testing.T) {
+	tests := []struct {
+		name        string
+		dual        bool
+		changeErr   string
+		stuck       bool
+		wantChanges int
+		wantErr     string
+	}{
+		{name: "already dual", dual: true, wantChanges: 0},
+		{name: "switched", dual: false, wantChanges: 1},
+		{name: "rejected with open positions", dual: false, changeErr: `{"code":-4068,"msg":"Position side cannot be changed if there exists position."}`, wantChanges: 1, wantErr: "切换币安双向持仓模式失败"},
+		{name: "still one-way after switch", dual: false, stuck: true, wantChanges: 1, wantErr: "仍为单向持仓"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ft, changes := fakePositionMode(t, tt.dual, tt.changeErr, tt.stuck)
+			err := ft.setupDualSidePosition(context.Background())
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+			}
+			if *changes != tt.wantChanges {
+				t.Errorf("position mode changes = %d, want %d", *changes, tt.wantChanges)
+			}
+		})
+	}
+}
+
+func TestSetupDualSidePositionTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+	ft := NewFuturesTrader("key", "secret")
+	ft.client.BaseURL = srv.URL
+	ft.client.HTTPClient = srv.Client()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := ft.setupDualSidePosition(ctx); err == nil {
+		t.Fatal("expected error when Binance does not respond")
+	}
+}
@@ -0,0 +1,443 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"nofx/market"
+	"strconv"
+	"strings"
+	"sync"
+
+	binance "github.com/adshao/go-binance/v2"
+)
+
+// spotDustValue 低于该价值（USDT）的现货余额视为零头，不算持仓
+const spotDustValue = 1.0
+
+// SpotTrader 币安现货交易器（只做多、无杠杆、无强平；USDT以外的币种余额即为持仓）
+type SpotTrader struct {
+	client *binance.Client
+
+	// 持仓成本：现货接口不提供持仓均价，按本进程内的买入成交计算（未知时按当前价，即未实现盈亏从0开始）
+	entryPrices map[string]float64
+
+	// 止损止盈：同一笔余额只能挂一个卖单，两者都设置时合并为OCO订单
+	protection map[string]*spotProtection
+	mu         sync.Mutex
+
+	// 交易规则缓存（步长、最小名义价值）
+	symbolInfos   map[string]*market.SymbolInfo
+	symbolInfosMu sync.Mutex
+}
+
+// spotProtection 现货持仓的止损止盈
+type spotProtection struct {
+	quantity   float64
+	stopLoss   float64
+	takeProfit float64
+}
+
+// spotStopLimitSlippage 止损触发后限价卖单相对触发价的让价（确保能成交）
+const spotStopLimitSlippage = 0.005
+
+// NewSpotTrader 创建币安现货交易器
+func NewSpotTrader(apiKey, secretKey string) *SpotTrader {
+	return &SpotTrader{
+		client:      binance.NewClient(apiKey, secretKey),
+		entryPrices: make(map[string]float64),
+		protection:  make(map[string]*spotProtection),
+		symbolInfos: make(map[string]*market.SymbolInfo),
+	}
+}
+
+func init() {
+	Register(Driver{
+		Name:        "binance_spot",
+		Description: "币安现货",
+		New: func(cfg Config) (Exchange, error) {
+			c := decodeConfig[binanceConfig](cfg)
+			return NewSpotTrader(c.APIKey, c.SecretKey), nil
+		},
+		Validate:   validateBinanceConfig,
+		AccountKey: binanceAccountKey,
+		Spot:       true,
+	})
+}
+
+// baseAsset 交易对的基础币种（BTCUSDT -> BTC）
+func baseAsset(symbol string) string {
+	return strings.TrimSuffix(symbol, "USDT")
+}
+
+// spotSnapshot 现货账户快照
+type spotSnapshot struct {
+	usdtFree  float64
+	usdtTotal float64
+	positions []Position
+}
+
+// snapshot 查询余额并按最新价格换算为持仓
+func (t *SpotTrader) snapshot() (*spotSnapshot, error) {
+	account, err := t.client.NewGetAccountService().Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取现货账户失败: %w", err)
+	}
+	prices, err := t.client.NewListPricesService().Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取现货价格失败: %w", err)
+	}
+	priceMap := make(map[string]float64, len(prices))
+	for _, p := range prices {
+		priceMap[p.Symbol], _ = strconv.ParseFloat(p.Price, 64)
+	}
+
+	snap := &spotSnapshot{}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, b := range account.Balances {
+		free, _ := strconv.ParseFloat(b.Free, 64)
+		locked, _ := strconv.ParseFloat(b.Locked, 64)
+		if b.Asset == "USDT" {
+			snap.usdtFree, snap.usdtTotal = free, free+locked
+			continue
+		}
+		quantity := free + locked
+		symbol := b.Asset + "USDT"
+		price := priceMap[symbol]
+		if quantity <= 0 || price <= 0 || quantity*price < spotDustValue {
+			continue
+		}
+		entry, ok := t.entryPrices[symbol]
+		if !ok {
+			entry = price
+			t.entryPrices[symbol] = entry
+		}
+		snap.positions = append(snap.positions, Position{
+			Symbol:           symbol,
+			Side:             SideLong,
+			Quantity:         quantity,
+			EntryPrice:       entry,
+			MarkPrice:        price,
+			UnrealizedProfit: (price - entry) * quantity,
+			Leverage:         1,
+		})
+	}
+	return snap, nil
+}
+
+// GetAccount 获取账户余额（钱包余额 = USDT + 持仓成本，未实现盈亏按最新价格计算）
+func (t *SpotTrader) GetAccount() (*Account, error) {
+	snap, err := t.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	wallet := snap.usdtTotal
+	unrealized := 0.0
+	for _, pos := range snap.positions {
+		wallet += pos.Quantity * pos.EntryPrice
+		unrealized += pos.UnrealizedProfit
+	}
+	return &Account{WalletBalance: wallet, AvailableBalance: snap.usdtFree, UnrealizedProfit: unrealized}, nil
+}
+
+// GetPositions 获取持仓（USDT以外价值超过零头的币种余额）
+func (t *SpotTrader) GetPositions() ([]Position, error) {
+	snap, err := t.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return snap.positions, nil
+}
+
+// PlaceOrder 下单（只支持市价买入和卖出方向的止损止盈）
+func (t *SpotTrader) PlaceOrder(req OrderRequest) (*Order, error) {
+	if req.Side == SideShort {
+		return nil, fmt.Errorf("币安现货不支持做空: %s", req.Symbol)
+	}
+	switch req.Type {
+	case OrderTypeMarket:
+		return t.buy(req.Symbol, req.Quantity)
+	case OrderTypeStopLoss:
+		return protectiveOrder(req, t.setStopLoss(req.Symbol, req.Quantity, req.Price))
+	case OrderTypeTakeProfit:
+		return protectiveOrder(req, t.setTakeProfit(req.Symbol, req.Quantity, req.Price))
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedOrderType, req.Type)
+}
+
+// ClosePosition 市价卖出（现货没有空仓）
+func (t *SpotTrader) ClosePosition(symbol, side string, quantity float64) (*Order, error) {
+	if side == SideShort {
+		return nil, fmt.Errorf("币安现货不支持做空，没有 %s 的空仓", symbol)
+	}
+	return t.sell(symbol, quantity)
+}
+
+// buy 市价买入
+func (t *SpotTrader) buy(symbol string, quantity float64) (*Order, error) {
+	// 先撤销旧的止损止盈卖单（加仓后由调用方按合并数量重新挂单）
+	if err := t.CancelAllOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
+	}
+	held := t.assetQuantity(symbol)
+
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	order, err := t.client.NewCreateOrderService().
+		Symbol(symbol).
+		Side(binance.SideTypeBuy).
+		Type(binance.OrderTypeMarket).
+		Quantity(quantityStr).
+		Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("现货买入失败: %w", err)
+	}
+
+	// 按成交均价更新持仓成本（加仓时与原持仓加权平均）
+	filled, _ := strconv.ParseFloat(order.ExecutedQuantity, 64)
+	quote, _ := strconv.ParseFloat(order.CummulativeQuoteQuantity, 64)
+	if filled > 0 {
+		avgPrice := quote / filled
+		t.mu.Lock()
+		if prev, ok := t.entryPrices[symbol]; ok && held > 0 {
+			avgPrice = (prev*held + quote) / (held + filled)
+		}
+		t.entryPrices[symbol] = avgPrice
+		t.mu.Unlock()
+	}
+
+	log.Printf("✓ 现货买入成功: %s 数量: %s", symbol, quantityStr)
+	return spotOrder(order), nil
+}
+
+// sell 市价卖出（quantity=0表示卖出全部余额）
+func (t *SpotTrader) sell(symbol string, quantity float64) (*Order, error) {
+	// 止损止盈卖单会锁定余额，卖出前先撤销
+	if err := t.CancelAllOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消挂单失败: %v", err)
+	}
+
+	held := t.assetQuantity(symbol)
+	if quantity == 0 || quantity > held {
+		quantity = held
+	}
+	if quantity <= 0 {
+		return nil, fmt.Errorf("没有找到 %s 的现货持仓", symbol)
+	}
+
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	order, err := t.client.NewCreateOrderService().
+		Symbol(symbol).
+		Side(binance.SideTypeSell).
+		Type(binance.OrderTypeMarket).
+		Quantity(quantityStr).
+		Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("现货卖出失败: %w", err)
+	}
+
+	if quantity >= held {
+		t.mu.Lock()
+		delete(t.entryPrices, symbol)
+		t.mu.Unlock()
+	}
+
+	log.Printf("✓ 现货卖出成功: %s 数量: %s", symbol, quantityStr)
+	return spotOrder(order), nil
+}
+
+// spotOrder 转换现货下单结果
+func spotOrder(order *binance.CreateOrderResponse) *Order {
+	return &Order{OrderID: strconv.FormatInt(order.OrderID, 10), Symbol: order.Symbol, Status: string(order.Status)}
+}
+
+// SetLeverage 现货没有杠杆（忽略）
+func (t *SpotTrader) SetLeverage(symbol string, leverage int) error {
+	if leverage > 1 {
+		log.Printf("  ⚠ 现货不支持杠杆，%s 按1倍买入", symbol)
+	}
+	return nil
+}
+
+// GetMarketPrice 获取现货最新价格
+func (t *SpotTrader) GetMarketPrice(symbol string) (float64, error) {
+	prices, err := t.client.NewListPricesService().Symbol(symbol).Do(context.Background())
+	if err != nil {
+		return 0, fmt.Errorf("获取价格失败: %w", err)
+	}
+	if len(prices) == 0 {
+		return 0, fmt.Errorf("未找到 %s 的价格", symbol)
+	}
+	return strconv.ParseFloat(prices[0].Price, 64)
+}
+
+// setStopLoss 设置止损（已设置止盈时与止盈合并为OCO订单）
+func (t *SpotTrader) setStopLoss(symbol string, quantity, stopPrice float64) error {
+	t.mu.Lock()
+	p := t.protectionFor(symbol, quantity)
+	p.stopLoss = stopPrice
+	t.mu.Unlock()
+	return t.placeProtection(symbol)
+}
+
+// setTakeProfit 设置止盈（已设置止损时与止损合并为OCO订单）
+func (t *SpotTrader) setTakeProfit(symbol string, quantity, takeProfitPrice float64) error {
+	t.mu.Lock()
+	p := t.protectionFor(symbol, quantity)
+	p.takeProfit = takeProfitPrice
+	t.mu.Unlock()
+	return t.placeProtection(symbol)
+}
+
+// protectionFor 获取币种的止损止盈记录（数量变化时重新开始记录，调用方持有t.mu）
+func (t *SpotTrader) protectionFor(symbol string, quantity float64) *spotProtection {
+	p, ok := t.protection[symbol]
+	if !ok || p.quantity != quantity {
+		p = &spotProtection{quantity: quantity}
+		t.protection[symbol] = p
+	}
+	return p
+}
+
+// placeProtection 撤销旧的卖单后按记录挂止损止盈：两者都有时挂OCO，否则挂单个止损限价单或限价止盈单
+func (t *SpotTrader) placeProtection(symbol string) error {
+	t.mu.Lock()
+	p := *t.protection[symbol]
+	t.mu.Unlock()
+
+	if _, err := t.client.NewCancelOpenOrdersService().Symbol(symbol).Do(context.Background()); err != nil && !strings.Contains(err.Error(), "-2011") {
+		log.Printf("  ⚠ 取消旧止损止盈失败: %v", err)
+	}
+
+	info, err := t.symbolInfo(symbol)
+	if err != nil {
+		return err
+	}
+	quantityStr := info.FormatQuantity(p.quantity)
+	stopLimit := p.stopLoss * (1 - spotStopLimitSlippage)
+
+	switch {
+	case p.stopLoss > 0 && p.takeProfit > 0:
+		_, err = t.client.NewCreateOCOService().
+			Symbol(symbol).
+			Side(binance.SideTypeSell).
+			Quantity(quantityStr).
+			Price(info.FormatPrice(p.takeProfit)).
+			StopPrice(info.FormatPrice(p.stopLoss)).
+			StopLimitPrice(info.FormatPrice(stopLimit)).
+			StopLimitTimeInForce(binance.TimeInForceTypeGTC).
+			Do(context.Background())
+		if err != nil {
+			return fmt.Errorf("设置止损止盈OCO失败: %w", err)
+		}
+		log.Printf("  止损价设置: %.4f | 止盈价设置: %.4f（OCO）", p.stopLoss, p.takeProfit)
+	case p.stopLoss > 0:
+		_, err = t.client.NewCreateOrderService().
+			Symbol(symbol).
+			Side(binance.SideTypeSell).
+			Type(binance.OrderTypeStopLossLimit).
+			TimeInForce(binance.TimeInForceTypeGTC).
+			Quantity(quantityStr).
+			StopPrice(info.FormatPrice(p.stopLoss)).
+			Price(info.FormatPrice(stopLimit)).
+			Do(context.Background())
+		if err != nil {
+			return fmt.Errorf("设置止损失败: %w", err)
+		}
+		log.Printf("  止损价设置: %.4f", p.stopLoss)
+	case p.takeProfit > 0:
+		_, err = t.client.NewCreateOrderService().
+			Symbol(symbol).
+			Side(binance.SideTypeSell).
+			Type(binance.OrderTypeLimit).
+			TimeInForce(binance.TimeInForceTypeGTC).
+			Quantity(quantityStr).
+			Price(info.FormatPrice(p.takeProfit)).
+			Do(context.Background())
+		if err != nil {
+			return fmt.Errorf("设置止盈失败: %w", err)
+		}
+		log.Printf("  止盈价设置: %.4f", p.takeProfit)
+	}
+	return nil
+}
+
+// CancelAllOrders 取消该币种的所有挂单（同时清除止损止盈记录）
+func (t *SpotTrader) CancelAllOrders(symbol string) error {
+	t.mu.Lock()
+	delete(t.protection, symbol)
+	t.mu.Unlock()
+
+	_, err := t.client.NewCancelOpenOrdersService().Symbol(symbol).Do(context.Background())
+	// -2011: 没有挂单
+	if err != nil && !strings.Contains(err.Error(), "-2011") {
+		return fmt.Errorf("取消挂单失败: %w", err)
+	}
+	log.Printf("  ✓ 已取消 %s 的所有挂单", symbol)
+	return nil
+}
+
+// FormatQuantity 按现货LOT_SIZE步长格式化数量
+func (t *SpotTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	info, err := t.symbolInfo(symbol)
+	if err != nil {
+		return "", err
+	}
+	return info.FormatQuantity(quantity), nil
+}
+
+// GetSymbolFilters 获取现货下单规则（没有杠杆分层）
+func (t *SpotTrader) GetSymbolFilters(symbol string) (*SymbolFilters, error) {
+	info, err := t.symbolInfo(symbol)
+	if err != nil {
+		return nil, err
+	}
+	return &SymbolFilters{StepSize: info.StepSize, MinQty: info.MinQty, MinNotional: info.MinNotional}, nil
+}
+
+// symbolInfo 获取现货交易规则（现货与合约的步长不同，单独查询并缓存）
+func (t *SpotTrader) symbolInfo(symbol string) (*market.SymbolInfo, error) {
+	t.symbolInfosMu.Lock()
+	defer t.symbolInfosMu.Unlock()
+	if info, ok := t.symbolInfos[symbol]; ok {
+		return info, nil
+	}
+
+	resp, err := t.client.NewExchangeInfoService().Symbol(symbol).Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取 %s 现货交易规则失败: %w", symbol, err)
+	}
+	for _, s := range resp.Symbols {
+		if s.Symbol != symbol {
+			continue
+		}
+		info := market.NewSymbolInfo(s.Symbol, s.Status, s.Filters)
+		t.symbolInfos[symbol] = info
+		return info, nil
+	}
+	return nil, fmt.Errorf("币安现货没有交易对 %s", symbol)
+}
+
+// assetQuantity 查询交易对基础币种的余额（可用 + 冻结）
+func (t *SpotTrader) assetQuantity(symbol string) float64 {
+	account, err := t.client.NewGetAccountService().Do(context.Background())
+	if err != nil {
+		log.Printf("  ⚠ 获取现货余额失败: %v", err)
+		return 0
+	}
+	asset := baseAsset(symbol)
+	for _, b := range account.Balances {
+		if b.Asset == asset {
+			free, _ := strconv.ParseFloat(b.Free, 64)
+			locked, _ := strconv.ParseFloat(b.Locked, 64)
+			return math.Max(free+locked, 0)
+		}
+	}
+	return 0
+}
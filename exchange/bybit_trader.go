@@ -0,0 +1,685 @@
+package exchange
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const bybitRecvWindow = "10000"
+
+// BybitTrader Bybit USDT永续合约交易器（v5 API，统一账户 + 双向持仓模式）
+type BybitTrader struct {
+	apiKey    string
+	secretKey string
+	baseURL   string
+	client    *http.Client
+
+	// 缓存交易对精度信息
+	symbolPrecision map[string]SymbolPrecision
+	mu              sync.RWMutex
+}
+
+// NewBybitTrader 创建Bybit交易器
+func NewBybitTrader(apiKey, secretKey string, testnet bool) (*BybitTrader, error) {
+	if apiKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("Bybit API Key和Secret不能为空")
+	}
+
+	baseURL := "https://api.bybit.com"
+	if testnet {
+		baseURL = "https://api-testnet.bybit.com"
+	}
+
+	t := &BybitTrader{
+		apiKey:          apiKey,
+		secretKey:       secretKey,
+		baseURL:         baseURL,
+		client:          &http.Client{Timeout: 30 * time.Second},
+		symbolPrecision: make(map[string]SymbolPrecision),
+	}
+
+	// 系统按多空分别管理持仓，需要双向持仓模式（mode=3）
+	if _, err := t.request("POST", "/v5/position/switch-mode", map[string]interface{}{
+		"category": "linear",
+		"coin":     "USDT",
+		"mode":     3,
+	}); err != nil {
+		log.Printf("  ⚠ 设置Bybit双向持仓模式失败（如已是双向持仓可忽略）: %v", err)
+	}
+
+	return t, nil
+}
+
+// bybitConfig Bybit配置
+type bybitConfig struct {
+	APIKey    string `json:"bybit_api_key"`
+	SecretKey string `json:"bybit_secret_key"`
+	Testnet   bool   `json:"bybit_testnet"`
+}
+
+func init() {
+	Register(Driver{
+		Name:        "bybit",
+		Description: "Bybit USDT永续合约",
+		New: func(cfg Config) (Exchange, error) {
+			c := decodeConfig[bybitConfig](cfg)
+			return NewBybitTrader(c.APIKey, c.SecretKey, c.Testnet)
+		},
+		Validate: func(cfg Config) error {
+			c := decodeConfig[bybitConfig](cfg)
+			if c.APIKey == "" || c.SecretKey == "" {
+				return fmt.Errorf("使用Bybit时必须配置bybit_api_key和bybit_secret_key")
+			}
+			return nil
+		},
+		AccountKey: func(cfg Config) string { return decodeConfig[bybitConfig](cfg).APIKey },
+		TestnetKey: "bybit_testnet",
+	})
+}
+
+// request 发送签名请求，返回result字段
+// 签名: HMAC-SHA256(timestamp + apiKey + recvWindow + queryString|jsonBody)
+func (t *BybitTrader) request(method, path string, params map[string]interface{}) (json.RawMessage, error) {
+	var payload string
+	url := t.baseURL + path
+
+	if method == "GET" {
+		var parts []string
+		for k, v := range params {
+			parts = append(parts, fmt.Sprintf("%s=%v", k, v))
+		}
+		payload = strings.Join(parts, "&")
+		if payload != "" {
+			url += "?" + payload
+		}
+	} else if params != nil {
+		bs, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		payload = string(bs)
+	}
+
+	var body io.Reader
+	if method != "GET" {
+		body = bytes.NewBufferString(payload)
+	}
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(t.secretKey))
+	mac.Write([]byte(timestamp + t.apiKey + bybitRecvWindow + payload))
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-BAPI-API-KEY", t.apiKey)
+	req.Header.Set("X-BAPI-TIMESTAMP", timestamp)
+	req.Header.Set("X-BAPI-RECV-WINDOW", bybitRecvWindow)
+	req.Header.Set("X-BAPI-SIGN", hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		RetCode int             `json:"retCode"`
+		RetMsg  string          `json:"retMsg"`
+		Result  json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("解析Bybit响应失败: %w, body: %s", err, string(respBody))
+	}
+	if result.RetCode != 0 {
+		return nil, fmt.Errorf("Bybit API错误 %d: %s", result.RetCode, result.RetMsg)
+	}
+	return result.Result, nil
+}
+
+// getPrecision 获取交易对精度信息（带缓存）
+func (t *BybitTrader) getPrecision(symbol string) (SymbolPrecision, error) {
+	t.mu.RLock()
+	if prec, ok := t.symbolPrecision[symbol]; ok {
+		t.mu.RUnlock()
+		return prec, nil
+	}
+	t.mu.RUnlock()
+
+	data, err := t.request("GET", "/v5/market/instruments-info", map[string]interface{}{
+		"category": "linear",
+		"symbol":   symbol,
+	})
+	if err != nil {
+		return SymbolPrecision{}, fmt.Errorf("获取Bybit交易对信息失败: %w", err)
+	}
+
+	var info struct {
+		List []struct {
+			PriceFilter struct {
+				TickSize string `json:"tickSize"`
+			} `json:"priceFilter"`
+			LotSizeFilter struct {
+				QtyStep string `json:"qtyStep"`
+			} `json:"lotSizeFilter"`
+		} `json:"list"`
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return SymbolPrecision{}, err
+	}
+	if len(info.List) == 0 {
+		return SymbolPrecision{}, fmt.Errorf("未找到交易对 %s 的精度信息", symbol)
+	}
+
+	prec := SymbolPrecision{}
+	prec.TickSize, _ = strconv.ParseFloat(info.List[0].PriceFilter.TickSize, 64)
+	prec.StepSize, _ = strconv.ParseFloat(info.List[0].LotSizeFilter.QtyStep, 64)
+	prec.PricePrecision = calculatePrecision(info.List[0].PriceFilter.TickSize)
+	prec.QuantityPrecision = calculatePrecision(info.List[0].LotSizeFilter.QtyStep)
+
+	t.mu.Lock()
+	t.symbolPrecision[symbol] = prec
+	t.mu.Unlock()
+	return prec, nil
+}
+
+// formatPrice 按tick size格式化价格
+func (t *BybitTrader) formatPrice(symbol string, price float64) (string, error) {
+	prec, err := t.getPrecision(symbol)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatFloat(roundToTickSize(price, prec.TickSize), 'f', prec.PricePrecision, 64), nil
+}
+
+// GetAccount 获取账户余额（统一账户）
+func (t *BybitTrader) GetAccount() (*Account, error) {
+	data, err := t.request("GET", "/v5/account/wallet-balance", map[string]interface{}{
+		"accountType": "UNIFIED",
+		"coin":        "USDT",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取账户信息失败: %w", err)
+	}
+
+	var wallet struct {
+		List []struct {
+			TotalAvailableBalance string `json:"totalAvailableBalance"`
+			Coin                  []struct {
+				Coin          string `json:"coin"`
+				WalletBalance string `json:"walletBalance"`
+				UnrealisedPnl string `json:"unrealisedPnl"`
+			} `json:"coin"`
+		} `json:"list"`
+	}
+	if err := json.Unmarshal(data, &wallet); err != nil {
+		return nil, fmt.Errorf("解析账户信息失败: %w", err)
+	}
+
+	result := &Account{}
+	if len(wallet.List) > 0 {
+		result.AvailableBalance, _ = strconv.ParseFloat(wallet.List[0].TotalAvailableBalance, 64)
+		for _, c := range wallet.List[0].Coin {
+			if c.Coin != "USDT" {
+				continue
+			}
+			result.WalletBalance, _ = strconv.ParseFloat(c.WalletBalance, 64)
+			result.UnrealizedProfit, _ = strconv.ParseFloat(c.UnrealisedPnl, 64)
+		}
+	}
+
+	log.Printf("✓ Bybit API返回: 总余额=%.2f, 可用=%.2f, 未实现盈亏=%.2f",
+		result.WalletBalance, result.AvailableBalance, result.UnrealizedProfit)
+	return result, nil
+}
+
+// GetPositions 获取所有持仓
+func (t *BybitTrader) GetPositions() ([]Position, error) {
+	data, err := t.request("GET", "/v5/position/list", map[string]interface{}{
+		"category":   "linear",
+		"settleCoin": "USDT",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	var positions struct {
+		List []struct {
+			Symbol        string `json:"symbol"`
+			Side          string `json:"side"` // Buy / Sell
+			Size          string `json:"size"`
+			AvgPrice      string `json:"avgPrice"`
+			MarkPrice     string `json:"markPrice"`
+			UnrealisedPnl string `json:"unrealisedPnl"`
+			Leverage      string `json:"leverage"`
+			LiqPrice      string `json:"liqPrice"`
+		} `json:"list"`
+	}
+	if err := json.Unmarshal(data, &positions); err != nil {
+		return nil, fmt.Errorf("解析持仓失败: %w", err)
+	}
+
+	var result []Position
+	for _, pos := range positions.List {
+		size, _ := strconv.ParseFloat(pos.Size, 64)
+		if size == 0 {
+			continue // 跳过无持仓的
+		}
+
+		p := Position{Symbol: pos.Symbol, Side: SideLong, Quantity: size}
+		if pos.Side != "Buy" {
+			p.Side = SideShort
+		}
+		p.EntryPrice, _ = strconv.ParseFloat(pos.AvgPrice, 64)
+		p.MarkPrice, _ = strconv.ParseFloat(pos.MarkPrice, 64)
+		p.UnrealizedProfit, _ = strconv.ParseFloat(pos.UnrealisedPnl, 64)
+		p.Leverage, _ = strconv.ParseFloat(pos.Leverage, 64)
+		p.LiquidationPrice, _ = strconv.ParseFloat(pos.LiqPrice, 64)
+
+		result = append(result, p)
+	}
+
+	return result, nil
+}
+
+// SetLeverage 设置杠杆（多空相同）
+func (t *BybitTrader) SetLeverage(symbol string, leverage int) error {
+	_, err := t.request("POST", "/v5/position/set-leverage", map[string]interface{}{
+		"category":     "linear",
+		"symbol":       symbol,
+		"buyLeverage":  strconv.Itoa(leverage),
+		"sellLeverage": strconv.Itoa(leverage),
+	})
+	if err != nil {
+		// 110043: 杠杆未变化
+		if strings.Contains(err.Error(), "110043") {
+			log.Printf("  ✓ %s 杠杆已是 %dx", symbol, leverage)
+			return nil
+		}
+		return fmt.Errorf("设置杠杆失败: %w", err)
+	}
+	log.Printf("  ✓ %s 杠杆已切换为 %dx", symbol, leverage)
+	return nil
+}
+
+// bybitPositionIdx 双向持仓模式下的持仓索引（1=多，2=空）
+func bybitPositionIdx(positionSide string) int {
+	if strings.EqualFold(positionSide, "short") {
+		return 2
+	}
+	return 1
+}
+
+// submitOrder 下单（orderType: Market / Limit / PostOnly，PostOnly为只做Maker的限价单），同时返回格式化后的数量
+func (t *BybitTrader) submitOrder(symbol, side, positionSide, orderType string, quantity, price float64, reduceOnly bool) (*Order, string, error) {
+	qtyStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, "", err
+	}
+	if q, _ := strconv.ParseFloat(qtyStr, 64); q <= 0 {
+		return nil, "", fmt.Errorf("下单数量过小: %s", qtyStr)
+	}
+
+	params := map[string]interface{}{
+		"category":    "linear",
+		"symbol":      symbol,
+		"side":        side,
+		"orderType":   orderType,
+		"qty":         qtyStr,
+		"positionIdx": bybitPositionIdx(positionSide),
+		"reduceOnly":  reduceOnly,
+	}
+	if orderType != "Market" {
+		priceStr, err := t.formatPrice(symbol, price)
+		if err != nil {
+			return nil, "", err
+		}
+		params["orderType"] = "Limit"
+		params["price"] = priceStr
+		params["timeInForce"] = "GTC"
+		if orderType == "PostOnly" {
+			params["timeInForce"] = "PostOnly"
+		}
+	}
+
+	data, err := t.request("POST", "/v5/order/create", params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var order struct {
+		OrderID string `json:"orderId"`
+	}
+	if err := json.Unmarshal(data, &order); err != nil {
+		return nil, "", fmt.Errorf("解析下单响应失败: %w", err)
+	}
+
+	result := &Order{OrderID: order.OrderID, Symbol: symbol, Status: OrderStatusNew} // Bybit订单ID为UUID字符串
+	if orderType == "Market" {
+		result.Status = OrderStatusFilled
+	}
+	return result, qtyStr, nil
+}
+
+// PlaceOrder 下单
+func (t *BybitTrader) PlaceOrder(req OrderRequest) (*Order, error) {
+	switch req.Type {
+	case OrderTypeMarket:
+		if req.Side == SideShort {
+			return t.openShort(req.Symbol, req.Quantity, req.Leverage)
+		}
+		return t.openLong(req.Symbol, req.Quantity, req.Leverage)
+	case OrderTypeLimit, OrderTypePostOnly:
+		return t.openLimit(req.Symbol, req.Side, req.Quantity, req.Price, req.Leverage, req.Type == OrderTypePostOnly)
+	case OrderTypeStopLoss:
+		return protectiveOrder(req, t.setStopLoss(req.Symbol, req.Side, req.Price))
+	case OrderTypeTakeProfit:
+		return protectiveOrder(req, t.setTakeProfit(req.Symbol, req.Side, req.Price))
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedOrderType, req.Type)
+}
+
+// ClosePosition 市价平仓
+func (t *BybitTrader) ClosePosition(symbol, side string, quantity float64) (*Order, error) {
+	if side == SideShort {
+		return t.closeShort(symbol, quantity)
+	}
+	return t.closeLong(symbol, quantity)
+}
+
+// openLimit 下开仓限价单（postOnly=true时会立即成交的订单被交易所拒绝）
+func (t *BybitTrader) openLimit(symbol, positionSide string, quantity, price float64, leverage int, postOnly bool) (*Order, error) {
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	side := "Buy"
+	if positionSide == "short" {
+		side = "Sell"
+	}
+	orderType := "Limit"
+	if postOnly {
+		orderType = "PostOnly"
+	}
+
+	order, qty, err := t.submitOrder(symbol, side, positionSide, orderType, quantity, price, false)
+	if err != nil {
+		return nil, fmt.Errorf("下限价单失败: %w", err)
+	}
+
+	log.Printf("✓ 限价单已提交: %s %s 数量: %s 价格: %.4f (%s)", symbol, positionSide, qty, price, orderType)
+	return order, nil
+}
+
+// GetOrder 查询订单成交状态
+func (t *BybitTrader) GetOrder(symbol, orderID string) (*OrderStatus, error) {
+	data, err := t.request("GET", "/v5/order/realtime", map[string]interface{}{
+		"category": "linear",
+		"symbol":   symbol,
+		"orderId":  orderID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+
+	var result struct {
+		List []struct {
+			OrderStatus string `json:"orderStatus"`
+			CumExecQty  string `json:"cumExecQty"`
+			AvgPrice    string `json:"avgPrice"`
+		} `json:"list"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("解析订单失败: %w", err)
+	}
+	if len(result.List) == 0 {
+		return nil, fmt.Errorf("未找到订单: %s", orderID)
+	}
+
+	order := result.List[0]
+	status := &OrderStatus{}
+	status.FilledQty, _ = strconv.ParseFloat(order.CumExecQty, 64)
+	status.AvgPrice, _ = strconv.ParseFloat(order.AvgPrice, 64)
+	switch order.OrderStatus {
+	case "New", "Untriggered":
+		status.Status = OrderStatusNew
+	case "PartiallyFilled":
+		status.Status = OrderStatusPartiallyFilled
+	case "Filled":
+		status.Status = OrderStatusFilled
+	default: // Cancelled / Rejected / PartiallyFilledCanceled / Deactivated
+		status.Status = OrderStatusCanceled
+	}
+	return status, nil
+}
+
+// CancelOrder 撤销单个订单
+func (t *BybitTrader) CancelOrder(symbol, orderID string) error {
+	_, err := t.request("POST", "/v5/order/cancel", map[string]interface{}{
+		"category": "linear",
+		"symbol":   symbol,
+		"orderId":  orderID,
+	})
+	if err != nil {
+		return fmt.Errorf("撤销订单失败: %w", err)
+	}
+	return nil
+}
+
+// openLong 开多仓
+func (t *BybitTrader) openLong(symbol string, quantity float64, leverage int) (*Order, error) {
+	// 先取消该币种的所有委托单（清理旧的止损止盈单）
+	if err := t.CancelAllOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
+	}
+
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	order, qty, err := t.submitOrder(symbol, "Buy", "long", "Market", quantity, 0, false)
+	if err != nil {
+		return nil, fmt.Errorf("开多仓失败: %w", err)
+	}
+
+	log.Printf("✓ 开多仓成功: %s 数量: %s", symbol, qty)
+	return order, nil
+}
+
+// openShort 开空仓
+func (t *BybitTrader) openShort(symbol string, quantity float64, leverage int) (*Order, error) {
+	// 先取消该币种的所有委托单（清理旧的止损止盈单）
+	if err := t.CancelAllOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
+	}
+
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	order, qty, err := t.submitOrder(symbol, "Sell", "short", "Market", quantity, 0, false)
+	if err != nil {
+		return nil, fmt.Errorf("开空仓失败: %w", err)
+	}
+
+	log.Printf("✓ 开空仓成功: %s 数量: %s", symbol, qty)
+	return order, nil
+}
+
+// positionQuantity 获取当前持仓数量（绝对值）
+func (t *BybitTrader) positionQuantity(symbol, side string) (float64, error) {
+	positions, err := t.GetPositions()
+	if err != nil {
+		return 0, err
+	}
+	for _, pos := range positions {
+		if pos.Symbol == symbol && pos.Side == side {
+			return pos.Quantity, nil
+		}
+	}
+	return 0, nil
+}
+
+// closeLong 平多仓（quantity=0表示全部平仓）
+func (t *BybitTrader) closeLong(symbol string, quantity float64) (*Order, error) {
+	if quantity == 0 {
+		var err error
+		if quantity, err = t.positionQuantity(symbol, "long"); err != nil {
+			return nil, err
+		}
+		if quantity == 0 {
+			return nil, fmt.Errorf("没有找到 %s 的多仓", symbol)
+		}
+	}
+
+	order, qty, err := t.submitOrder(symbol, "Sell", "long", "Market", quantity, 0, true)
+	if err != nil {
+		return nil, fmt.Errorf("平多仓失败: %w", err)
+	}
+	log.Printf("✓ 平多仓成功: %s 数量: %s", symbol, qty)
+
+	// 平仓后取消该币种的所有挂单（止损止盈单）
+	if err := t.CancelAllOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消挂单失败: %v", err)
+	}
+	return order, nil
+}
+
+// closeShort 平空仓（quantity=0表示全部平仓）
+func (t *BybitTrader) closeShort(symbol string, quantity float64) (*Order, error) {
+	if quantity == 0 {
+		var err error
+		if quantity, err = t.positionQuantity(symbol, "short"); err != nil {
+			return nil, err
+		}
+		if quantity == 0 {
+			return nil, fmt.Errorf("没有找到 %s 的空仓", symbol)
+		}
+	}
+
+	order, qty, err := t.submitOrder(symbol, "Buy", "short", "Market", quantity, 0, true)
+	if err != nil {
+		return nil, fmt.Errorf("平空仓失败: %w", err)
+	}
+	log.Printf("✓ 平空仓成功: %s 数量: %s", symbol, qty)
+
+	// 平仓后取消该币种的所有挂单（止损止盈单）
+	if err := t.CancelAllOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消挂单失败: %v", err)
+	}
+	return order, nil
+}
+
+// GetMarketPrice 获取最新成交价
+func (t *BybitTrader) GetMarketPrice(symbol string) (float64, error) {
+	data, err := t.request("GET", "/v5/market/tickers", map[string]interface{}{
+		"category": "linear",
+		"symbol":   symbol,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("获取价格失败: %w", err)
+	}
+
+	var tickers struct {
+		List []struct {
+			LastPrice string `json:"lastPrice"`
+		} `json:"list"`
+	}
+	if err := json.Unmarshal(data, &tickers); err != nil || len(tickers.List) == 0 {
+		return 0, fmt.Errorf("未找到价格")
+	}
+	return strconv.ParseFloat(tickers.List[0].LastPrice, 64)
+}
+
+// setTradingStop 设置整仓止损/止盈（tpslMode=Full，触发后市价平掉整个仓位）
+func (t *BybitTrader) setTradingStop(symbol, positionSide string, price float64, isStopLoss bool) error {
+	priceStr, err := t.formatPrice(symbol, price)
+	if err != nil {
+		return err
+	}
+
+	params := map[string]interface{}{
+		"category":    "linear",
+		"symbol":      symbol,
+		"tpslMode":    "Full",
+		"positionIdx": bybitPositionIdx(positionSide),
+	}
+	if isStopLoss {
+		params["stopLoss"] = priceStr
+	} else {
+		params["takeProfit"] = priceStr
+	}
+
+	_, err = t.request("POST", "/v5/position/trading-stop", params)
+	return err
+}
+
+// setStopLoss 设置止损单（整仓止损，不需要数量）
+func (t *BybitTrader) setStopLoss(symbol string, positionSide string, stopPrice float64) error {
+	if err := t.setTradingStop(symbol, positionSide, stopPrice, true); err != nil {
+		return fmt.Errorf("设置止损失败: %w", err)
+	}
+	log.Printf("  止损价设置: %.4f", stopPrice)
+	return nil
+}
+
+// setTakeProfit 设置止盈单（整仓止盈，不需要数量）
+func (t *BybitTrader) setTakeProfit(symbol string, positionSide string, takeProfitPrice float64) error {
+	if err := t.setTradingStop(symbol, positionSide, takeProfitPrice, false); err != nil {
+		return fmt.Errorf("设置止盈失败: %w", err)
+	}
+	log.Printf("  止盈价设置: %.4f", takeProfitPrice)
+	return nil
+}
+
+// CancelAllOrders 取消该币种的所有挂单
+func (t *BybitTrader) CancelAllOrders(symbol string) error {
+	_, err := t.request("POST", "/v5/order/cancel-all", map[string]interface{}{
+		"category": "linear",
+		"symbol":   symbol,
+	})
+	if err != nil {
+		return fmt.Errorf("取消挂单失败: %w", err)
+	}
+	log.Printf("  ✓ 已取消 %s 的所有挂单", symbol)
+	return nil
+}
+
+// GetSymbolFilters 获取下单规则（只提供数量步长）
+func (t *BybitTrader) GetSymbolFilters(symbol string) (*SymbolFilters, error) {
+	prec, err := t.getPrecision(symbol)
+	if err != nil {
+		return nil, err
+	}
+	return &SymbolFilters{StepSize: prec.StepSize}, nil
+}
+
+// FormatQuantity 格式化数量到正确的精度（按步长向下取整，避免超出可用保证金）
+func (t *BybitTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	prec, err := t.getPrecision(symbol)
+	if err != nil {
+		return "", err
+	}
+	if prec.StepSize > 0 {
+		quantity = math.Floor(quantity/prec.StepSize+1e-9) * prec.StepSize
+	}
+	return strconv.FormatFloat(quantity, 'f', prec.QuantityPrecision, 64), nil
+}
@@ -0,0 +1,822 @@
+package exchange
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dYdX网络参数
+const (
+	dydxMainnetIndexer = "https://indexer.dydx.trade/v4"
+	dydxMainnetNode    = "https://dydx-rest.publicnode.com"
+	dydxMainnetChainID = "dydx-mainnet-1"
+	dydxTestnetIndexer = "https://indexer.v4testnet.dydx.exchange/v4"
+	dydxTestnetNode    = "https://dydx-testnet-rest.publicnode.com"
+	dydxTestnetChainID = "dydx-testnet-4"
+
+	dydxQuoteAtomicResolution = -6                  // USDC精度
+	dydxShortTermBlocks       = 10                  // 市价单（短期IOC单）有效区块数（上限20）
+	dydxMarketSlippage        = 0.05                // 市价单限价偏离预言机价格的比例
+	dydxTriggerSlippage       = 0.1                 // 止损止盈触发后允许的最大滑点
+	dydxConditionalTTL        = 28 * 24 * time.Hour // 止损止盈单有效期（上限90天）
+	dydxFillTimeout           = 10 * time.Second    // 等待市价单成交（出块约1秒）
+)
+
+// DydxTrader dYdX v4永续合约交易器：账户/持仓/行情走Indexer，下单/撤单签名后广播到验证节点（全仓，单向持仓）
+type DydxTrader struct {
+	key        *ecdsa.PrivateKey
+	address    string
+	subaccount uint32
+	indexerURL string
+	nodeURL    string
+	chainID    string
+	client     *http.Client
+
+	markets   map[string]dydxMarket // ticker -> 市场参数
+	leverages map[string]int        // symbol -> 设置的杠杆（dYdX按账户全仓计算保证金，只用于展示）
+	mu        sync.RWMutex
+
+	// 条件单需要正确的账户序号，连续广播时在本地递增
+	accountNumber uint64
+	sequence      uint64
+	hasAccount    bool
+	txMu          sync.Mutex
+}
+
+// dydxMarket 永续合约市场参数（Indexer /perpetualMarkets）
+type dydxMarket struct {
+	ClobPairID                string `json:"clobPairId"`
+	Ticker                    string `json:"ticker"`
+	Status                    string `json:"status"`
+	OraclePrice               string `json:"oraclePrice"`
+	NextFundingRate           string `json:"nextFundingRate"`
+	InitialMarginFraction     string `json:"initialMarginFraction"`
+	MaintenanceMarginFraction string `json:"maintenanceMarginFraction"`
+	StepSize                  string `json:"stepSize"`
+	AtomicResolution          int    `json:"atomicResolution"`
+	QuantumConversionExponent int    `json:"quantumConversionExponent"`
+	StepBaseQuantums          uint64 `json:"stepBaseQuantums"`
+	SubticksPerTick           uint64 `json:"subticksPerTick"`
+}
+
+// dydxIndexerOrder Indexer返回的订单
+type dydxIndexerOrder struct {
+	ID               string `json:"id"`
+	ClientID         string `json:"clientId"`
+	ClobPairID       string `json:"clobPairId"`
+	Ticker           string `json:"ticker"`
+	Type             string `json:"type"`
+	Status           string `json:"status"`
+	ReduceOnly       bool   `json:"reduceOnly"`
+	OrderFlags       string `json:"orderFlags"`
+	GoodTilBlock     string `json:"goodTilBlock"`
+	GoodTilBlockTime string `json:"goodTilBlockTime"`
+}
+
+// NewDydxTrader 创建dYdX交易器（nodeURL为空时使用公共验证节点）
+func NewDydxTrader(mnemonic string, subaccount int, nodeURL string, testnet bool) (*DydxTrader, error) {
+	key, err := dydxKeyFromMnemonic(mnemonic)
+	if err != nil {
+		return nil, fmt.Errorf("解析dYdX助记词失败: %w", err)
+	}
+
+	t := &DydxTrader{
+		key:        key,
+		address:    cosmosAddress("dydx", &key.PublicKey),
+		subaccount: uint32(subaccount),
+		indexerURL: dydxMainnetIndexer,
+		nodeURL:    dydxMainnetNode,
+		chainID:    dydxMainnetChainID,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		markets:    make(map[string]dydxMarket),
+		leverages:  make(map[string]int),
+	}
+	if testnet {
+		t.indexerURL, t.nodeURL, t.chainID = dydxTestnetIndexer, dydxTestnetNode, dydxTestnetChainID
+	}
+	if nodeURL != "" {
+		t.nodeURL = strings.TrimSuffix(nodeURL, "/")
+	}
+
+	if err := t.loadMarkets(); err != nil {
+		return nil, err
+	}
+	log.Printf("✓ dYdX交易器初始化成功 (testnet=%v, address=%s, subaccount=%d)", testnet, t.address, subaccount)
+	return t, nil
+}
+
+// dydxConfig dYdX v4配置
+type dydxConfig struct {
+	Mnemonic   string `json:"dydx_mnemonic"`
+	Subaccount int    `json:"dydx_subaccount"`
+	NodeURL    string `json:"dydx_node_url"`
+	Testnet    bool   `json:"dydx_testnet"`
+}
+
+func init() {
+	Register(Driver{
+		Name:        "dydx",
+		Description: "dYdX v4永续合约",
+		New: func(cfg Config) (Exchange, error) {
+			c := decodeConfig[dydxConfig](cfg)
+			return NewDydxTrader(c.Mnemonic, c.Subaccount, c.NodeURL, c.Testnet)
+		},
+		Validate: func(cfg Config) error {
+			c := decodeConfig[dydxConfig](cfg)
+			if c.Mnemonic == "" {
+				return fmt.Errorf("使用dYdX时必须配置dydx_mnemonic")
+			}
+			if c.Subaccount < 0 {
+				return fmt.Errorf("dydx_subaccount不能为负数")
+			}
+			return nil
+		},
+		AccountKey: dydxAccountKey,
+		TestnetKey: "dydx_testnet",
+	})
+}
+
+// dydxAccountKey dYdX账户按助记词派生的地址和子账户区分（不使用助记词本身，账户标识会长期保存在内存中并可能出现在日志里）
+func dydxAccountKey(cfg Config) string {
+	c := decodeConfig[dydxConfig](cfg)
+	key, err := dydxKeyFromMnemonic(c.Mnemonic)
+	if err != nil {
+		// 助记词无效时创建交易器会失败，这里只需保证不包含助记词
+		return "invalid"
+	}
+	return fmt.Sprintf("%s:%d", cosmosAddress("dydx", &key.PublicKey), c.Subaccount)
+}
+
+// toDydxTicker 将币安格式的symbol转换为dYdX市场（BTCUSDT -> BTC-USD）
+func toDydxTicker(symbol string) string {
+	return strings.TrimSuffix(symbol, "USDT") + "-USD"
+}
+
+// fromDydxTicker 将dYdX市场转换为币安格式的symbol（BTC-USD -> BTCUSDT）
+func fromDydxTicker(ticker string) string {
+	return strings.TrimSuffix(ticker, "-USD") + "USDT"
+}
+
+// get 请求Indexer/节点的GET接口
+func (t *DydxTrader) get(baseURL, path string, out interface{}) error {
+	resp, err := t.client.Get(baseURL + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("dYdX API错误 %d: %s", resp.StatusCode, string(body))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("解析dYdX响应失败: %w, body: %s", err, string(body))
+	}
+	return nil
+}
+
+// loadMarkets 获取所有市场参数
+func (t *DydxTrader) loadMarkets() error {
+	var result struct {
+		Markets map[string]dydxMarket `json:"markets"`
+	}
+	if err := t.get(t.indexerURL, "/perpetualMarkets", &result); err != nil {
+		return fmt.Errorf("获取dYdX市场信息失败: %w", err)
+	}
+	t.mu.Lock()
+	t.markets = result.Markets
+	t.mu.Unlock()
+	return nil
+}
+
+// getMarket 获取市场参数（fresh=true时重新获取，用于取最新预言机价格）
+func (t *DydxTrader) getMarket(symbol string, fresh bool) (dydxMarket, error) {
+	ticker := toDydxTicker(symbol)
+	if fresh {
+		var result struct {
+			Markets map[string]dydxMarket `json:"markets"`
+		}
+		if err := t.get(t.indexerURL, "/perpetualMarkets?ticker="+url.QueryEscape(ticker), &result); err != nil {
+			return dydxMarket{}, fmt.Errorf("获取 %s 市场信息失败: %w", ticker, err)
+		}
+		if m, ok := result.Markets[ticker]; ok {
+			t.mu.Lock()
+			t.markets[ticker] = m
+			t.mu.Unlock()
+			return m, nil
+		}
+		return dydxMarket{}, fmt.Errorf("dYdX没有 %s 市场", ticker)
+	}
+
+	t.mu.RLock()
+	m, ok := t.markets[ticker]
+	t.mu.RUnlock()
+	if !ok {
+		return t.getMarket(symbol, true)
+	}
+	return m, nil
+}
+
+// quantums 币数量转换为链上数量单位（按stepBaseQuantums取整）
+func (m dydxMarket) quantums(size float64) uint64 {
+	raw := size * math.Pow10(-m.AtomicResolution)
+	steps := math.Round(raw / float64(m.StepBaseQuantums))
+	if steps < 1 {
+		steps = 1
+	}
+	return uint64(steps) * m.StepBaseQuantums
+}
+
+// subticks 价格转换为链上价格单位（按subticksPerTick取整）
+func (m dydxMarket) subticks(price float64) uint64 {
+	raw := price * math.Pow10(m.AtomicResolution-m.QuantumConversionExponent-dydxQuoteAtomicResolution)
+	ticks := math.Round(raw / float64(m.SubticksPerTick))
+	if ticks < 1 {
+		ticks = 1
+	}
+	return uint64(ticks) * m.SubticksPerTick
+}
+
+// clobPairID 订单簿ID
+func (m dydxMarket) clobPairID() uint32 {
+	id, _ := strconv.ParseUint(m.ClobPairID, 10, 32)
+	return uint32(id)
+}
+
+// dydxSubaccount Indexer返回的子账户
+type dydxSubaccount struct {
+	Equity                 string `json:"equity"`
+	FreeCollateral         string `json:"freeCollateral"`
+	OpenPerpetualPositions map[string]struct {
+		Market        string `json:"market"`
+		Side          string `json:"side"`
+		Size          string `json:"size"`
+		EntryPrice    string `json:"entryPrice"`
+		UnrealizedPnl string `json:"unrealizedPnl"`
+	} `json:"openPerpetualPositions"`
+}
+
+// getSubaccount 获取子账户的净值和持仓
+func (t *DydxTrader) getSubaccount() (*dydxSubaccount, error) {
+	var result struct {
+		Subaccount dydxSubaccount `json:"subaccount"`
+	}
+	path := fmt.Sprintf("/addresses/%s/subaccountNumber/%d", t.address, t.subaccount)
+	if err := t.get(t.indexerURL, path, &result); err != nil {
+		return nil, err
+	}
+	return &result.Subaccount, nil
+}
+
+// GetAccount 获取账户余额
+func (t *DydxTrader) GetAccount() (*Account, error) {
+	sub, err := t.getSubaccount()
+	if err != nil {
+		return nil, fmt.Errorf("获取账户信息失败: %w", err)
+	}
+
+	equity, _ := strconv.ParseFloat(sub.Equity, 64)
+	freeCollateral, _ := strconv.ParseFloat(sub.FreeCollateral, 64)
+	unrealized := 0.0
+	for _, pos := range sub.OpenPerpetualPositions {
+		pnl, _ := strconv.ParseFloat(pos.UnrealizedPnl, 64)
+		unrealized += pnl
+	}
+
+	result := &Account{
+		WalletBalance:    equity - unrealized, // 钱包余额（不含未实现盈亏）
+		AvailableBalance: freeCollateral,
+		UnrealizedProfit: unrealized,
+	}
+
+	log.Printf("✓ dYdX 账户: 净值=%.2f, 可用保证金=%.2f, 未实现盈亏=%.2f", equity, freeCollateral, unrealized)
+	return result, nil
+}
+
+// GetPositions 获取所有持仓（标记价格使用预言机价格，dYdX不提供强平价）
+func (t *DydxTrader) GetPositions() ([]Position, error) {
+	sub, err := t.getSubaccount()
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+	if len(sub.OpenPerpetualPositions) > 0 {
+		if err := t.loadMarkets(); err != nil {
+			return nil, err
+		}
+	}
+	equity, _ := strconv.ParseFloat(sub.Equity, 64)
+
+	var result []Position
+	for _, pos := range sub.OpenPerpetualPositions {
+		size, _ := strconv.ParseFloat(pos.Size, 64)
+		if size == 0 {
+			continue
+		}
+		symbol := fromDydxTicker(pos.Market)
+		m, err := t.getMarket(symbol, false)
+		if err != nil {
+			return nil, err
+		}
+		markPrice, _ := strconv.ParseFloat(m.OraclePrice, 64)
+
+		side := "long"
+		if strings.EqualFold(pos.Side, "SHORT") {
+			side = "short"
+		}
+
+		// 全仓模式没有单个持仓的杠杆，优先使用开仓时设置的杠杆，否则按名义价值/净值估算
+		t.mu.RLock()
+		leverage := float64(t.leverages[symbol])
+		t.mu.RUnlock()
+		if leverage == 0 && equity > 0 {
+			leverage = math.Max(1, math.Round(math.Abs(size)*markPrice/equity))
+		}
+
+		p := Position{
+			Symbol:     symbol,
+			Side:       side,
+			Quantity:   math.Abs(size),
+			MarkPrice:  markPrice,
+			Leverage:   leverage,
+			MarginMode: "cross",
+		}
+		p.EntryPrice, _ = strconv.ParseFloat(pos.EntryPrice, 64)
+		p.UnrealizedProfit, _ = strconv.ParseFloat(pos.UnrealizedPnl, 64)
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+// positionSize 当前持仓数量（多仓为正，空仓为负）
+func (t *DydxTrader) positionSize(symbol string) (float64, error) {
+	sub, err := t.getSubaccount()
+	if err != nil {
+		return 0, err
+	}
+	pos, ok := sub.OpenPerpetualPositions[toDydxTicker(symbol)]
+	if !ok {
+		return 0, nil
+	}
+	size, _ := strconv.ParseFloat(pos.Size, 64)
+	return size, nil
+}
+
+// SetLeverage 记录杠杆（dYdX子账户为全仓，实际杠杆由仓位大小决定，上限为1/初始保证金率）
+func (t *DydxTrader) SetLeverage(symbol string, leverage int) error {
+	m, err := t.getMarket(symbol, false)
+	if err != nil {
+		return err
+	}
+	if imf, _ := strconv.ParseFloat(m.InitialMarginFraction, 64); imf > 0 && float64(leverage) > 1/imf {
+		return fmt.Errorf("%s 在dYdX的最大杠杆为 %.0fx", symbol, 1/imf)
+	}
+	t.mu.Lock()
+	t.leverages[symbol] = leverage
+	t.mu.Unlock()
+	return nil
+}
+
+// GetMarketPrice 获取市场价格（预言机价格）
+func (t *DydxTrader) GetMarketPrice(symbol string) (float64, error) {
+	m, err := t.getMarket(symbol, true)
+	if err != nil {
+		return 0, err
+	}
+	price, err := strconv.ParseFloat(m.OraclePrice, 64)
+	if err != nil || price <= 0 {
+		return 0, fmt.Errorf("%s 价格无效: %s", symbol, m.OraclePrice)
+	}
+	return price, nil
+}
+
+// PlaceOrder 下单（不支持限价开仓）
+func (t *DydxTrader) PlaceOrder(req OrderRequest) (*Order, error) {
+	switch req.Type {
+	case OrderTypeMarket:
+		if req.Side == SideShort {
+			return t.openShort(req.Symbol, req.Quantity, req.Leverage)
+		}
+		return t.openLong(req.Symbol, req.Quantity, req.Leverage)
+	case OrderTypeStopLoss:
+		return protectiveOrder(req, t.setStopLoss(req.Symbol, req.Side, req.Quantity, req.Price))
+	case OrderTypeTakeProfit:
+		return protectiveOrder(req, t.setTakeProfit(req.Symbol, req.Side, req.Quantity, req.Price))
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedOrderType, req.Type)
+}
+
+// ClosePosition 只减仓市价单平仓（quantity=0表示全部平仓），全部平仓后撤销该币种的止损止盈单
+func (t *DydxTrader) ClosePosition(symbol, side string, quantity float64) (*Order, error) {
+	size, err := t.positionSize(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+	if (side == "long" && size <= 0) || (side == "short" && size >= 0) {
+		return nil, fmt.Errorf("没有找到 %s 的%s仓", symbol, map[string]string{"long": "多", "short": "空"}[side])
+	}
+	fullClose := quantity == 0 || quantity >= math.Abs(size)
+	if fullClose {
+		quantity = math.Abs(size)
+	}
+
+	orderSide := uint64(dydxSideSell)
+	if side == "short" {
+		orderSide = dydxSideBuy
+	}
+	order, err := t.marketOrder(symbol, orderSide, quantity, true)
+	if err != nil {
+		return nil, fmt.Errorf("平仓失败: %w", err)
+	}
+	log.Printf("✓ 平%s仓成功: %s 数量: %.4f", map[string]string{"long": "多", "short": "空"}[side], symbol, quantity)
+
+	if fullClose {
+		if err := t.CancelAllOrders(symbol); err != nil {
+			log.Printf("  ⚠ 取消挂单失败: %v", err)
+		}
+	}
+	return order, nil
+}
+
+// openLong 开多仓
+func (t *DydxTrader) openLong(symbol string, quantity float64, leverage int) (*Order, error) {
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+	order, err := t.marketOrder(symbol, dydxSideBuy, quantity, false)
+	if err != nil {
+		return nil, fmt.Errorf("开多仓失败: %w", err)
+	}
+	log.Printf("✓ 开多仓成功: %s 数量: %.4f", symbol, quantity)
+	return order, nil
+}
+
+// openShort 开空仓
+func (t *DydxTrader) openShort(symbol string, quantity float64, leverage int) (*Order, error) {
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+	order, err := t.marketOrder(symbol, dydxSideSell, quantity, false)
+	if err != nil {
+		return nil, fmt.Errorf("开空仓失败: %w", err)
+	}
+	log.Printf("✓ 开空仓成功: %s 数量: %.4f", symbol, quantity)
+	return order, nil
+}
+
+// marketOrder 以偏离预言机价格的限价下短期IOC单模拟市价单，并等待持仓变化确认成交
+func (t *DydxTrader) marketOrder(symbol string, side uint64, quantity float64, reduceOnly bool) (*Order, error) {
+	m, err := t.getMarket(symbol, true)
+	if err != nil {
+		return nil, err
+	}
+	price, _ := strconv.ParseFloat(m.OraclePrice, 64)
+	if side == dydxSideBuy {
+		price *= 1 + dydxMarketSlippage
+	} else {
+		price *= 1 - dydxMarketSlippage
+	}
+	height, err := t.blockHeight()
+	if err != nil {
+		return nil, err
+	}
+	before, err := t.positionSize(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	order := dydxOrder{
+		ID: dydxOrderID{
+			Owner:      t.address,
+			Subaccount: t.subaccount,
+			ClientID:   rand.Uint32(),
+			OrderFlags: dydxOrderFlagShortTerm,
+			ClobPairID: m.clobPairID(),
+		},
+		Side:         side,
+		Quantums:     m.quantums(quantity),
+		Subticks:     m.subticks(price),
+		GoodTilBlock: height + dydxShortTermBlocks,
+		TimeInForce:  dydxTimeInForceIOC,
+		ReduceOnly:   reduceOnly,
+	}
+	if err := t.broadcast("/dydxprotocol.clob.MsgPlaceOrder", marshalPlaceOrder(order), false); err != nil {
+		return nil, err
+	}
+
+	// 短期订单在下一个区块撮合，IOC未成交部分直接取消
+	deadline := time.Now().Add(dydxFillTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(time.Second)
+		after, err := t.positionSize(symbol)
+		if err == nil && after != before {
+			return &Order{
+				OrderID: strconv.FormatUint(uint64(order.ID.ClientID), 10),
+				Symbol:  symbol,
+				Status:  OrderStatusFilled,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("订单 %d 在 %v 内未成交", order.ID.ClientID, dydxFillTimeout)
+}
+
+// setStopLoss 设置止损单（条件单，触发后按最大滑点限价IOC成交）
+func (t *DydxTrader) setStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	if err := t.conditionalOrder(symbol, positionSide, quantity, stopPrice, dydxConditionStopLoss); err != nil {
+		return fmt.Errorf("设置止损失败: %w", err)
+	}
+	log.Printf("  止损价设置: %.4f", stopPrice)
+	return nil
+}
+
+// setTakeProfit 设置止盈单（条件单，触发后按最大滑点限价IOC成交）
+func (t *DydxTrader) setTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	if err := t.conditionalOrder(symbol, positionSide, quantity, takeProfitPrice, dydxConditionTakeProfit); err != nil {
+		return fmt.Errorf("设置止盈失败: %w", err)
+	}
+	log.Printf("  止盈价设置: %.4f", takeProfitPrice)
+	return nil
+}
+
+// conditionalOrder 下只减仓的止损/止盈条件单
+func (t *DydxTrader) conditionalOrder(symbol, positionSide string, quantity, triggerPrice float64, condition uint64) error {
+	m, err := t.getMarket(symbol, false)
+	if err != nil {
+		return err
+	}
+
+	side, limitPrice := uint64(dydxSideSell), triggerPrice*(1-dydxTriggerSlippage)
+	if positionSide == SideShort {
+		side, limitPrice = dydxSideBuy, triggerPrice*(1+dydxTriggerSlippage)
+	}
+
+	order := dydxOrder{
+		ID: dydxOrderID{
+			Owner:      t.address,
+			Subaccount: t.subaccount,
+			ClientID:   rand.Uint32(),
+			OrderFlags: dydxOrderFlagConditional,
+			ClobPairID: m.clobPairID(),
+		},
+		Side:             side,
+		Quantums:         m.quantums(quantity),
+		Subticks:         m.subticks(limitPrice),
+		GoodTilBlockTime: uint32(time.Now().Add(dydxConditionalTTL).Unix()),
+		TimeInForce:      dydxTimeInForceIOC,
+		ReduceOnly:       true,
+		ConditionType:    condition,
+		TriggerSubticks:  m.subticks(triggerPrice),
+	}
+	return t.broadcast("/dydxprotocol.clob.MsgPlaceOrder", marshalPlaceOrder(order), true)
+}
+
+// listOrders 获取未成交和未触发的订单（ticker为空表示所有市场）
+func (t *DydxTrader) listOrders(ticker string) ([]dydxIndexerOrder, error) {
+	var all []dydxIndexerOrder
+	for _, status := range []string{"OPEN", "UNTRIGGERED"} {
+		query := url.Values{}
+		query.Set("address", t.address)
+		query.Set("subaccountNumber", strconv.Itoa(int(t.subaccount)))
+		query.Set("status", status)
+		if ticker != "" {
+			query.Set("ticker", ticker)
+		}
+		var orders []dydxIndexerOrder
+		if err := t.get(t.indexerURL, "/orders?"+query.Encode(), &orders); err != nil {
+			return nil, fmt.Errorf("获取挂单失败: %w", err)
+		}
+		all = append(all, orders...)
+	}
+	return all, nil
+}
+
+// CancelAllOrders 取消该币种的所有挂单（包括未触发的止损止盈单）
+func (t *DydxTrader) CancelAllOrders(symbol string) error {
+	orders, err := t.listOrders(toDydxTicker(symbol))
+	if err != nil {
+		return err
+	}
+	if len(orders) == 0 {
+		return nil
+	}
+	height, err := t.blockHeight()
+	if err != nil {
+		return err
+	}
+
+	for _, o := range orders {
+		clientID, _ := strconv.ParseUint(o.ClientID, 10, 32)
+		flags, _ := strconv.ParseUint(o.OrderFlags, 10, 32)
+		clobPairID, _ := strconv.ParseUint(o.ClobPairID, 10, 32)
+		id := dydxOrderID{
+			Owner:      t.address,
+			Subaccount: t.subaccount,
+			ClientID:   uint32(clientID),
+			OrderFlags: uint32(flags),
+			ClobPairID: uint32(clobPairID),
+		}
+
+		var msg []byte
+		if id.OrderFlags == dydxOrderFlagShortTerm {
+			goodTilBlock, _ := strconv.ParseUint(o.GoodTilBlock, 10, 32)
+			if uint32(goodTilBlock) <= height {
+				continue // 已过期
+			}
+			msg = marshalCancelOrder(id, uint32(goodTilBlock), 0)
+		} else {
+			msg = marshalCancelOrder(id, 0, uint32(time.Now().Add(5*time.Minute).Unix()))
+		}
+		if err := t.broadcast("/dydxprotocol.clob.MsgCancelOrder", msg, id.OrderFlags != dydxOrderFlagShortTerm); err != nil {
+			log.Printf("  ⚠ 取消订单失败 (clientId=%s): %v", o.ClientID, err)
+		}
+	}
+
+	log.Printf("  ✓ 已取消 %s 的所有挂单", symbol)
+	return nil
+}
+
+// GetOpenOrders 获取所有币种的当前挂单（单向持仓，PositionSide为空）
+func (t *DydxTrader) GetOpenOrders() ([]OpenOrder, error) {
+	orders, err := t.listOrders("")
+	if err != nil {
+		return nil, err
+	}
+	result := make([]OpenOrder, 0, len(orders))
+	for _, o := range orders {
+		result = append(result, OpenOrder{
+			Symbol:     fromDydxTicker(o.Ticker),
+			OrderID:    o.ID,
+			Type:       o.Type,
+			Protective: o.ReduceOnly || strings.HasPrefix(o.Type, "STOP") || strings.HasPrefix(o.Type, "TAKE_PROFIT"),
+		})
+	}
+	return result, nil
+}
+
+// GetSymbolFilters 获取数量步长、最大杠杆和维持保证金率
+func (t *DydxTrader) GetSymbolFilters(symbol string) (*SymbolFilters, error) {
+	m, err := t.getMarket(symbol, false)
+	if err != nil {
+		return nil, err
+	}
+	if m.Status != "" && m.Status != "ACTIVE" {
+		return nil, fmt.Errorf("%s 在dYdX当前不可交易（%s）", symbol, m.Status)
+	}
+	step, _ := strconv.ParseFloat(m.StepSize, 64)
+	rules := &SymbolFilters{StepSize: step, MinQty: step}
+	if imf, _ := strconv.ParseFloat(m.InitialMarginFraction, 64); imf > 0 {
+		mmf, _ := strconv.ParseFloat(m.MaintenanceMarginFraction, 64)
+		rules.Brackets = []LeverageBracket{{NotionalCap: math.MaxFloat64, MaxLeverage: int(1 / imf), MaintMarginRate: mmf}}
+	}
+	return rules, nil
+}
+
+// GetFundingRates 获取所有币种的资金费率（实现FundingRateProvider，dYdX每小时结算，换算为8小时费率）
+func (t *DydxTrader) GetFundingRates() (map[string]float64, error) {
+	if err := t.loadMarkets(); err != nil {
+		return nil, err
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	rates := make(map[string]float64, len(t.markets))
+	for ticker, m := range t.markets {
+		if rate, err := strconv.ParseFloat(m.NextFundingRate, 64); err == nil {
+			rates[fromDydxTicker(ticker)] = rate * 8
+		}
+	}
+	return rates, nil
+}
+
+// FormatQuantity 格式化数量到正确的精度
+func (t *DydxTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	m, err := t.getMarket(symbol, false)
+	if err != nil {
+		return "", err
+	}
+	step, _ := strconv.ParseFloat(m.StepSize, 64)
+	if step <= 0 {
+		return strconv.FormatFloat(quantity, 'f', -1, 64), nil
+	}
+	decimals := 0
+	if i := strings.IndexByte(m.StepSize, '.'); i >= 0 {
+		decimals = len(strings.TrimRight(m.StepSize[i+1:], "0"))
+	}
+	return strconv.FormatFloat(math.Floor(quantity/step+1e-9)*step, 'f', decimals, 64), nil
+}
+
+// blockHeight 当前区块高度
+func (t *DydxTrader) blockHeight() (uint32, error) {
+	var result struct {
+		Height string `json:"height"`
+	}
+	if err := t.get(t.indexerURL, "/height", &result); err != nil {
+		return 0, fmt.Errorf("获取区块高度失败: %w", err)
+	}
+	height, err := strconv.ParseUint(result.Height, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("区块高度无效: %s", result.Height)
+	}
+	return uint32(height), nil
+}
+
+// loadAccount 从验证节点获取账户编号和序号
+func (t *DydxTrader) loadAccount() error {
+	var result struct {
+		Account struct {
+			AccountNumber string `json:"account_number"`
+			Sequence      string `json:"sequence"`
+		} `json:"account"`
+	}
+	if err := t.get(t.nodeURL, "/cosmos/auth/v1beta1/accounts/"+t.address, &result); err != nil {
+		return fmt.Errorf("获取链上账户失败（账户需要先入金）: %w", err)
+	}
+	t.accountNumber, _ = strconv.ParseUint(result.Account.AccountNumber, 10, 64)
+	t.sequence, _ = strconv.ParseUint(result.Account.Sequence, 10, 64)
+	t.hasAccount = true
+	return nil
+}
+
+// broadcast 签名并广播交易（stateful=true的条件单/撤单会占用账户序号，序号不匹配时重新获取后重试一次）
+func (t *DydxTrader) broadcast(typeURL string, msg []byte, stateful bool) error {
+	t.txMu.Lock()
+	defer t.txMu.Unlock()
+
+	if !t.hasAccount {
+		if err := t.loadAccount(); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		code, rawLog, err := t.sendTx(typeURL, msg)
+		if err != nil {
+			return err
+		}
+		switch {
+		case code == 0:
+			if stateful {
+				t.sequence++
+			}
+			return nil
+		case code == 32 && attempt == 0: // sdk ErrWrongSequence
+			if err := t.loadAccount(); err != nil {
+				return err
+			}
+			continue
+		default:
+			return fmt.Errorf("dYdX交易被拒绝 (code=%d): %s", code, rawLog)
+		}
+	}
+}
+
+// sendTx 广播交易（BROADCAST_MODE_SYNC，返回CheckTx结果）
+func (t *DydxTrader) sendTx(typeURL string, msg []byte) (uint32, string, error) {
+	txBytes, err := buildSignedTx(t.key, t.chainID, t.accountNumber, t.sequence, typeURL, msg)
+	if err != nil {
+		return 0, "", err
+	}
+	payload, _ := json.Marshal(map[string]string{
+		"tx_bytes": base64.StdEncoding.EncodeToString(txBytes),
+		"mode":     "BROADCAST_MODE_SYNC",
+	})
+
+	resp, err := t.client.Post(t.nodeURL+"/cosmos/tx/v1beta1/txs", "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", fmt.Errorf("广播交易失败: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, "", err
+	}
+
+	var result struct {
+		TxResponse struct {
+			Code   uint32 `json:"code"`
+			RawLog string `json:"raw_log"`
+			TxHash string `json:"txhash"`
+		} `json:"tx_response"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, "", fmt.Errorf("解析广播结果失败: %w, body: %s", err, string(body))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, "", fmt.Errorf("广播交易失败 %d: %s", resp.StatusCode, result.Message)
+	}
+	return result.TxResponse.Code, result.TxResponse.RawLog, nil
+}
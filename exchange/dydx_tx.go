@@ -0,0 +1,294 @@
+package exchange
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/ripemd160"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// dYdX v4链上订单参数（dydxprotocol.clob）
+const (
+	dydxOrderFlagShortTerm   = 0  // 短期订单（按区块高度过期，不上链存储）
+	dydxOrderFlagConditional = 32 // 条件单（止损止盈，按时间过期）
+
+	dydxSideBuy  = 1
+	dydxSideSell = 2
+
+	dydxTimeInForceIOC = 1
+
+	dydxConditionStopLoss   = 1
+	dydxConditionTakeProfit = 2
+
+	dydxGasLimit = 1_000_000 // 下单/撤单消息免手续费，只需要足够的gas上限
+)
+
+// dydxOrderID 订单标识（subaccount + clientID + flags + clobPairID唯一确定一个订单）
+type dydxOrderID struct {
+	Owner      string
+	Subaccount uint32
+	ClientID   uint32
+	OrderFlags uint32
+	ClobPairID uint32
+}
+
+// dydxOrder MsgPlaceOrder中的订单
+type dydxOrder struct {
+	ID               dydxOrderID
+	Side             uint64
+	Quantums         uint64
+	Subticks         uint64
+	GoodTilBlock     uint32 // 短期订单
+	GoodTilBlockTime uint32 // 条件单/长期订单（Unix秒）
+	TimeInForce      uint64
+	ReduceOnly       bool
+	ConditionType    uint64
+	TriggerSubticks  uint64
+}
+
+// marshal 编码OrderId
+func (id dydxOrderID) marshal() []byte {
+	subaccount := pbString(nil, 1, id.Owner)
+	subaccount = pbUint(subaccount, 2, uint64(id.Subaccount))
+
+	b := pbBytes(nil, 1, subaccount)
+	if id.ClientID != 0 {
+		b = protowire.AppendTag(b, 2, protowire.Fixed32Type)
+		b = protowire.AppendFixed32(b, id.ClientID)
+	}
+	b = pbUint(b, 3, uint64(id.OrderFlags))
+	b = pbUint(b, 4, uint64(id.ClobPairID))
+	return b
+}
+
+// marshal 编码Order（good_til_oneof按订单类型二选一，必须写入）
+func (o dydxOrder) marshal() []byte {
+	b := pbBytes(nil, 1, o.ID.marshal())
+	b = pbUint(b, 2, o.Side)
+	b = pbUint(b, 3, o.Quantums)
+	b = pbUint(b, 4, o.Subticks)
+	b = appendGoodTil(b, o.ID.OrderFlags, 5, o.GoodTilBlock, o.GoodTilBlockTime)
+	b = pbUint(b, 7, o.TimeInForce)
+	if o.ReduceOnly {
+		b = pbUint(b, 8, 1)
+	}
+	b = pbUint(b, 10, o.ConditionType)
+	b = pbUint(b, 11, o.TriggerSubticks)
+	return b
+}
+
+// appendGoodTil 写入good_til_block（短期订单，varint）或good_til_block_time（其他订单，fixed32，字段号+1）
+func appendGoodTil(b []byte, flags uint32, blockField protowire.Number, block, blockTime uint32) []byte {
+	if flags == dydxOrderFlagShortTerm {
+		b = protowire.AppendTag(b, blockField, protowire.VarintType)
+		return protowire.AppendVarint(b, uint64(block))
+	}
+	b = protowire.AppendTag(b, blockField+1, protowire.Fixed32Type)
+	return protowire.AppendFixed32(b, blockTime)
+}
+
+// marshalPlaceOrder 编码MsgPlaceOrder
+func marshalPlaceOrder(o dydxOrder) []byte {
+	return pbBytes(nil, 1, o.marshal())
+}
+
+// marshalCancelOrder 编码MsgCancelOrder
+func marshalCancelOrder(id dydxOrderID, goodTilBlock, goodTilBlockTime uint32) []byte {
+	b := pbBytes(nil, 1, id.marshal())
+	return appendGoodTil(b, id.OrderFlags, 2, goodTilBlock, goodTilBlockTime)
+}
+
+// buildSignedTx 构建并签名单消息的Cosmos交易（SIGN_MODE_DIRECT），返回TxRaw字节
+func buildSignedTx(key *ecdsa.PrivateKey, chainID string, accountNumber, sequence uint64, typeURL string, msg []byte) ([]byte, error) {
+	anyMsg := pbString(nil, 1, typeURL)
+	anyMsg = pbBytes(anyMsg, 2, msg)
+	body := pbBytes(nil, 1, anyMsg)
+
+	pubKey := pbBytes(nil, 1, crypto.CompressPubkey(&key.PublicKey))
+	pubKeyAny := pbString(nil, 1, "/cosmos.crypto.secp256k1.PubKey")
+	pubKeyAny = pbBytes(pubKeyAny, 2, pubKey)
+	modeInfo := pbBytes(nil, 1, pbUint(nil, 1, 1)) // Single{mode: SIGN_MODE_DIRECT}
+	signerInfo := pbBytes(nil, 1, pubKeyAny)
+	signerInfo = pbBytes(signerInfo, 2, modeInfo)
+	signerInfo = pbUint(signerInfo, 3, sequence)
+	fee := pbUint(nil, 2, dydxGasLimit)
+	authInfo := pbBytes(nil, 1, signerInfo)
+	authInfo = pbBytes(authInfo, 2, fee)
+
+	signDoc := pbBytes(nil, 1, body)
+	signDoc = pbBytes(signDoc, 2, authInfo)
+	signDoc = pbString(signDoc, 3, chainID)
+	signDoc = pbUint(signDoc, 4, accountNumber)
+
+	hash := sha256.Sum256(signDoc)
+	sig, err := crypto.Sign(hash[:], key)
+	if err != nil {
+		return nil, fmt.Errorf("签名交易失败: %w", err)
+	}
+
+	txRaw := pbBytes(nil, 1, body)
+	txRaw = pbBytes(txRaw, 2, authInfo)
+	txRaw = pbBytes(txRaw, 3, sig[:64]) // Cosmos签名为64字节R||S，不含恢复位
+	return txRaw, nil
+}
+
+// pbBytes 写入bytes/嵌套消息字段（空值省略）
+func pbBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+// pbString 写入string字段（空值省略）
+func pbString(b []byte, num protowire.Number, v string) []byte {
+	return pbBytes(b, num, []byte(v))
+}
+
+// pbUint 写入varint字段（零值省略）
+func pbUint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// dydxKeyFromMnemonic 按BIP39/BIP44从助记词派生私钥（Cosmos路径 m/44'/118'/0'/0/0，与dYdX网页端导出的助记词一致）
+func dydxKeyFromMnemonic(mnemonic string) (*ecdsa.PrivateKey, error) {
+	words := strings.Fields(mnemonic)
+	if len(words) != 12 && len(words) != 24 {
+		return nil, fmt.Errorf("助记词应为12或24个单词，实际 %d 个", len(words))
+	}
+	seed, err := pbkdf2.Key(sha512.New, strings.Join(words, " "), []byte("mnemonic"), 2048, 64)
+	if err != nil {
+		return nil, fmt.Errorf("生成种子失败: %w", err)
+	}
+
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	master := mac.Sum(nil)
+	key, chainCode := master[:32], master[32:]
+
+	const hardened = 0x80000000
+	for _, index := range []uint32{44 + hardened, 118 + hardened, hardened, 0, 0} {
+		key, chainCode, err = deriveChildKey(key, chainCode, index)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return crypto.ToECDSA(key)
+}
+
+// deriveChildKey BIP32子私钥派生
+func deriveChildKey(key, chainCode []byte, index uint32) ([]byte, []byte, error) {
+	var data []byte
+	if index >= 0x80000000 {
+		data = append([]byte{0}, key...)
+	} else {
+		priv, err := crypto.ToECDSA(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		data = crypto.CompressPubkey(&priv.PublicKey)
+	}
+	data = binary.BigEndian.AppendUint32(data, index)
+
+	mac := hmac.New(sha512.New, chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	n := crypto.S256().Params().N
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(n) >= 0 {
+		return nil, nil, fmt.Errorf("派生密钥无效（index=%d）", index)
+	}
+	child := il.Add(il, new(big.Int).SetBytes(key))
+	child.Mod(child, n)
+	if child.Sign() == 0 {
+		return nil, nil, fmt.Errorf("派生密钥无效（index=%d）", index)
+	}
+	return child.FillBytes(make([]byte, 32)), sum[32:], nil
+}
+
+// cosmosAddress 公钥对应的bech32地址（ripemd160(sha256(压缩公钥))）
+func cosmosAddress(hrp string, pub *ecdsa.PublicKey) string {
+	sha := sha256.Sum256(crypto.CompressPubkey(pub))
+	hasher := ripemd160.New()
+	hasher.Write(sha[:])
+	return bech32Encode(hrp, hasher.Sum(nil))
+}
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32Encode BIP173 bech32编码
+func bech32Encode(hrp string, data []byte) string {
+	values := convertBits(data, 8, 5)
+	checksumInput := make([]byte, 0, len(hrp)*2+1+len(values)+6)
+	for i := 0; i < len(hrp); i++ {
+		checksumInput = append(checksumInput, hrp[i]>>5)
+	}
+	checksumInput = append(checksumInput, 0)
+	for i := 0; i < len(hrp); i++ {
+		checksumInput = append(checksumInput, hrp[i]&31)
+	}
+	checksumInput = append(checksumInput, values...)
+	checksumInput = append(checksumInput, 0, 0, 0, 0, 0, 0)
+	polymod := bech32Polymod(checksumInput) ^ 1
+	for i := 0; i < 6; i++ {
+		values = append(values, byte(polymod>>uint(5*(5-i))&31))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, v := range values {
+		sb.WriteByte(bech32Charset[v])
+	}
+	return sb.String()
+}
+
+// bech32Polymod bech32校验和
+func bech32Polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+// convertBits 按位重新分组（8位 -> 5位，末尾补零）
+func convertBits(data []byte, fromBits, toBits uint) []byte {
+	var result []byte
+	acc, bits := uint32(0), uint(0)
+	maxValue := uint32(1)<<toBits - 1
+	for _, b := range data {
+		acc = acc<<fromBits | uint32(b)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			result = append(result, byte(acc>>bits&maxValue))
+		}
+	}
+	if bits > 0 {
+		result = append(result, byte(acc<<(toBits-bits)&maxValue))
+	}
+	return result
+}
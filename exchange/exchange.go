@@ -0,0 +1,277 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"nofx/logger"
+)
+
+var log = logger.Module("exchange")
+
+// Exchange 交易平台统一接口（AutoTrader只依赖这个接口，新增交易所只需实现它并注册）
+type Exchange interface {
+	// GetAccount 获取账户余额
+	GetAccount() (*Account, error)
+
+	// GetPositions 获取所有持仓
+	GetPositions() ([]Position, error)
+
+	// PlaceOrder 下单：市价/限价开仓，或为持仓挂止损止盈单
+	PlaceOrder(req OrderRequest) (*Order, error)
+
+	// ClosePosition 市价平仓（side: "long"/"short"，quantity=0表示全部平仓）
+	ClosePosition(symbol, side string, quantity float64) (*Order, error)
+
+	// SetLeverage 设置杠杆
+	SetLeverage(symbol string, leverage int) error
+
+	// GetSymbolFilters 获取下单规则（交易所不提供时返回nil，不做校验）
+	GetSymbolFilters(symbol string) (*SymbolFilters, error)
+
+	// GetMarketPrice 获取市场价格
+	GetMarketPrice(symbol string) (float64, error)
+
+	// CancelAllOrders 取消该币种的所有挂单
+	CancelAllOrders(symbol string) error
+
+	// FormatQuantity 格式化数量到正确的精度
+	FormatQuantity(symbol string, quantity float64) (string, error)
+}
+
+// 持仓方向
+const (
+	SideLong  = "long"
+	SideShort = "short"
+)
+
+// 订单类型
+const (
+	OrderTypeMarket     = "market"      // 市价开仓
+	OrderTypeLimit      = "limit"       // 限价开仓
+	OrderTypePostOnly   = "post_only"   // 只做Maker的限价开仓（会立即成交时被交易所拒绝）
+	OrderTypeStopLoss   = "stop_loss"   // 止损（只减仓，触发后市价成交）
+	OrderTypeTakeProfit = "take_profit" // 止盈（只减仓，触发后市价成交）
+)
+
+// ErrUnsupportedOrderType 交易所不支持该订单类型
+var ErrUnsupportedOrderType = errors.New("交易所不支持该订单类型")
+
+// ErrOrderNotFound 按客户端订单ID找不到订单（从未提交到交易所）
+var ErrOrderNotFound = errors.New("订单不存在")
+
+// transientErrorPatterns 各交易所临时性错误的特征（超时、时间戳偏差、限流、连接中断）
+var transientErrorPatterns = []string{
+	"timeout", "deadline exceeded", "connection reset", "connection refused", "eof",
+	"-1021",          // Binance: 请求时间戳超出recvWindow
+	"-1003", "-1015", // Binance: 请求权重/下单频率超限
+	"too many requests", "rate limit",
+	"bad gateway", "service unavailable",
+}
+
+// IsTransient 错误是否可能是临时性的（稍后重试可能成功）：网络超时、时间戳偏差、限流等
+// 余额不足、参数错误等业务错误返回false
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, p := range transientErrorPatterns {
+		if strings.Contains(msg, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderRequest 下单请求
+type OrderRequest struct {
+	Symbol   string
+	Side     string  // 开仓方向，止损止盈单为所保护持仓的方向（"long"/"short"）
+	Type     string  // OrderTypeMarket / OrderTypeLimit / OrderTypePostOnly / OrderTypeStopLoss / OrderTypeTakeProfit
+	Quantity float64 // 数量（币）
+	Price    float64 // 限价单的挂单价，止损止盈单的触发价
+	Leverage int     // 开仓单使用的杠杆
+
+	ClientOrderID string // 客户端订单ID（开仓单的幂等键，重试时不变；为空时由交易所生成）
+}
+
+// Order 下单结果
+type Order struct {
+	OrderID  string // 交易所订单ID（止损止盈单可能为空）
+	Symbol   string
+	Status   string  // OrderStatusNew / OrderStatusFilled 等
+	Fee      float64 // 成交手续费（USDT，只有下单结果直接返回时才填写，0表示未知）
+	AvgPrice float64 // 成交均价（只有下单结果直接返回时才填写，0表示未知）
+}
+
+// protectiveOrder 止损止盈单的下单结果（各交易所的止损止盈实现不返回订单ID）
+func protectiveOrder(req OrderRequest, err error) (*Order, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &Order{Symbol: req.Symbol, Status: OrderStatusNew}, nil
+}
+
+// Account 账户余额（USDT）
+type Account struct {
+	WalletBalance    float64 // 钱包余额（不含未实现盈亏）
+	AvailableBalance float64 // 可用余额
+	UnrealizedProfit float64 // 未实现盈亏
+}
+
+// Position 持仓
+type Position struct {
+	Symbol           string
+	Side             string  // "long" / "short"
+	Quantity         float64 // 持仓数量（正数）
+	EntryPrice       float64
+	MarkPrice        float64
+	UnrealizedProfit float64
+	Leverage         float64
+	LiquidationPrice float64
+	MarginMode       string // "isolated" / "cross"（交易所不返回时为空）
+}
+
+// 订单状态（各交易所统一映射，拒绝/过期均视为已撤销）
+const (
+	OrderStatusNew             = "NEW"
+	OrderStatusPartiallyFilled = "PARTIALLY_FILLED"
+	OrderStatusFilled          = "FILLED"
+	OrderStatusCanceled        = "CANCELED"
+)
+
+// OrderStatus 订单成交状态
+type OrderStatus struct {
+	Status    string  // NEW / PARTIALLY_FILLED / FILLED / CANCELED
+	FilledQty float64 // 已成交数量
+	AvgPrice  float64 // 成交均价
+}
+
+// SymbolFilters 交易所下单规则
+type SymbolFilters struct {
+	StepSize    float64           // 数量步长（0表示不限制）
+	MinQty      float64           // 最小下单数量
+	MinNotional float64           // 最小名义价值（USDT）
+	Brackets    []LeverageBracket // 杠杆分层（按名义价值上限从小到大排序，为空表示不限制）
+}
+
+// LeverageBracket 杠杆分层：名义价值不超过NotionalCap时允许的最大杠杆和维持保证金
+type LeverageBracket struct {
+	NotionalCap     float64
+	MaxLeverage     int
+	MaintMarginRate float64 // 维持保证金率（0表示交易所未提供）
+	MaintAmount     float64 // 维持保证金速算额（维持保证金 = 名义价值×维持保证金率 - 速算额，只有币安提供）
+}
+
+// MaxLeverage 名义价值所在分层允许的最大杠杆（0表示不限制）
+func (f *SymbolFilters) MaxLeverage(notional float64) int {
+	for _, b := range f.Brackets {
+		if notional <= b.NotionalCap {
+			return b.MaxLeverage
+		}
+	}
+	return 0
+}
+
+// Maintenance 名义价值所在分层的维持保证金率和速算额（交易所未提供时返回0）
+func (f *SymbolFilters) Maintenance(notional float64) (rate, amount float64) {
+	for _, b := range f.Brackets {
+		if notional <= b.NotionalCap {
+			return b.MaintMarginRate, b.MaintAmount
+		}
+	}
+	return 0, 0
+}
+
+// OrderTracker 支持限价开仓的交易所（可选接口，实现后PlaceOrder接受限价/只做Maker单，不支持的交易所回退为市价单）
+type OrderTracker interface {
+	// GetOrder 查询订单成交状态
+	GetOrder(symbol, orderID string) (*OrderStatus, error)
+
+	// CancelOrder 撤销单个订单
+	CancelOrder(symbol, orderID string) error
+}
+
+// ClientOrderFinder 能按客户端订单ID查询订单的交易所（可选接口，下单响应丢失或重试前确认订单是否已提交）
+// 不支持时通过对比持仓数量判断
+type ClientOrderFinder interface {
+	// FindClientOrder 按客户端订单ID查询订单，从未提交过时返回ErrOrderNotFound
+	FindClientOrder(symbol, clientOrderID string) (*Order, error)
+}
+
+// MarginModeSetter 支持按币种选择保证金模式的交易所（可选接口，不支持的交易所使用交易所默认模式）
+type MarginModeSetter interface {
+	// SetMarginMode 设置该币种后续开仓使用的保证金模式（"isolated"逐仓 / "cross"全仓）
+	SetMarginMode(symbol, mode string) error
+}
+
+// SideOrderCanceler 双向持仓模式下能只撤销一个方向挂单的交易所（可选接口，不支持时撤销该币种的所有挂单）
+type SideOrderCanceler interface {
+	// CancelSideOrders 撤销该币种某个方向（"long"/"short"）的挂单，不影响另一方向持仓的止损止盈
+	CancelSideOrders(symbol, positionSide string) error
+}
+
+// FundingRateProvider 能提供本交易所资金费率的交易所（可选接口，不支持时prompt使用币安资金费率）
+type FundingRateProvider interface {
+	// GetFundingRates 获取所有币种的当前资金费率（symbol -> 8小时费率）
+	GetFundingRates() (map[string]float64, error)
+}
+
+// OpenOrderLister 能查询当前挂单的交易所（可选接口，用于持仓对账时清理孤立的止损止盈单）
+type OpenOrderLister interface {
+	// GetOpenOrders 获取所有币种的当前挂单
+	GetOpenOrders() ([]OpenOrder, error)
+}
+
+// CommissionReporter 能查询订单实际手续费的交易所（可选接口，不支持时按taker费率估算）
+type CommissionReporter interface {
+	// GetCommission 查询订单成交的实际手续费（USDT，订单尚无成交时返回错误）
+	GetCommission(symbol, orderID string) (float64, error)
+}
+
+// FundingFeeReporter 能查询资金费支出的交易所（可选接口，不支持时资金费按0计算）
+type FundingFeeReporter interface {
+	// GetFundingFees 查询持仓自since以来的资金费净支出（USDT，负数表示收取；需在平仓前调用）
+	GetFundingFees(symbol, side string, since time.Time) (float64, error)
+}
+
+// AccountResetter 能重置账户资金的交易所（可选接口，目前只有模拟盘；赛季重置时恢复初始余额）
+type AccountResetter interface {
+	// ResetAccount 清空持仓和挂单，把钱包余额重置为balance
+	ResetAccount(balance float64) error
+}
+
+// OpenOrder 交易所当前挂单
+type OpenOrder struct {
+	Symbol       string
+	OrderID      string
+	Type         string // 交易所原始订单类型（LIMIT / STOP_MARKET / TAKE_PROFIT_MARKET 等）
+	PositionSide string // "long" / "short"（单向持仓模式下为空）
+	Protective   bool   // 止损/止盈等只减仓订单
+}
+
+// ProtectionKind 止损止盈单的种类：OrderTypeStopLoss / OrderTypeTakeProfit（其他订单返回空）
+func (o OpenOrder) ProtectionKind() string {
+	if !o.Protective {
+		return ""
+	}
+	t := strings.ToUpper(strings.ReplaceAll(o.Type, " ", "_"))
+	switch {
+	case strings.Contains(t, "TAKE_PROFIT"):
+		return OrderTypeTakeProfit
+	case strings.Contains(t, "STOP"):
+		return OrderTypeStopLoss
+	}
+	return ""
+}
@@ -1,11 +1,13 @@
-package trader
+package exchange
 
 import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"math"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/sonirico/go-hyperliquid"
@@ -16,9 +18,19 @@ type HyperliquidTrader struct {
 	exchange   *hyperliquid.Exchange
 	ctx        context.Context
 	walletAddr string
+	testnet    bool
 	meta       *hyperliquid.Meta // 缓存meta信息（包含精度等）
+
+	marginModes   map[string]string // symbol -> 开仓使用的保证金模式（"isolated"/"cross"，默认逐仓）
+	marginModesMu sync.Mutex
 }
 
+// hyperliquidMinNotional Hyperliquid单笔订单最小名义价值（USDC）
+const hyperliquidMinNotional = 10.0
+
+// hyperliquidTriggerSlippage 止损止盈触发后市价成交允许的最大滑点（作为触发单的限价）
+const hyperliquidTriggerSlippage = 0.1
+
 // NewHyperliquidTrader 创建Hyperliquid交易器
 func NewHyperliquidTrader(privateKeyHex string, walletAddr string, testnet bool) (*HyperliquidTrader, error) {
 	// 解析私钥
@@ -63,15 +75,43 @@ func NewHyperliquidTrader(privateKeyHex string, walletAddr string, testnet bool)
 	}
 
 	return &HyperliquidTrader{
-		exchange:   exchange,
-		ctx:        ctx,
-		walletAddr: walletAddr,
-		meta:       meta,
+		exchange:    exchange,
+		ctx:         ctx,
+		walletAddr:  walletAddr,
+		testnet:     testnet,
+		meta:        meta,
+		marginModes: make(map[string]string),
 	}, nil
 }
 
-// GetBalance 获取账户余额
-func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
+// hyperliquidConfig Hyperliquid配置
+type hyperliquidConfig struct {
+	PrivateKey string `json:"hyperliquid_private_key"`
+	WalletAddr string `json:"hyperliquid_wallet_addr"`
+	Testnet    bool   `json:"hyperliquid_testnet"`
+}
+
+func init() {
+	Register(Driver{
+		Name:        "hyperliquid",
+		Description: "Hyperliquid",
+		New: func(cfg Config) (Exchange, error) {
+			c := decodeConfig[hyperliquidConfig](cfg)
+			return NewHyperliquidTrader(c.PrivateKey, c.WalletAddr, c.Testnet)
+		},
+		Validate: func(cfg Config) error {
+			if decodeConfig[hyperliquidConfig](cfg).PrivateKey == "" {
+				return fmt.Errorf("使用Hyperliquid时必须配置hyperliquid_private_key")
+			}
+			return nil
+		},
+		AccountKey: func(cfg Config) string { return decodeConfig[hyperliquidConfig](cfg).WalletAddr },
+		TestnetKey: "hyperliquid_testnet",
+	})
+}
+
+// GetAccount 获取账户余额
+func (t *HyperliquidTrader) GetAccount() (*Account, error) {
 	log.Printf("🔄 正在调用Hyperliquid API获取账户余额...")
 
 	// 获取账户状态
@@ -82,8 +122,6 @@ func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
 	}
 
 	// 解析余额信息（MarginSummary字段都是string）
-	result := make(map[string]interface{})
-
 	// 🔍 调试：打印API返回的完整CrossMarginSummary结构
 	summaryJSON, _ := json.MarshalIndent(accountState.MarginSummary, "  ", "  ")
 	log.Printf("🔍 [DEBUG] Hyperliquid API CrossMarginSummary完整数据:")
@@ -107,29 +145,31 @@ func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
 	// 需要返回"不包含未实现盈亏的钱包余额"
 	walletBalanceWithoutUnrealized := accountValue - totalUnrealizedPnl
 
-	result["totalWalletBalance"] = walletBalanceWithoutUnrealized // 钱包余额（不含未实现盈亏）
-	result["availableBalance"] = accountValue - totalMarginUsed   // 可用余额（总净值 - 占用保证金）
-	result["totalUnrealizedProfit"] = totalUnrealizedPnl          // 未实现盈亏
+	result := &Account{
+		WalletBalance:    walletBalanceWithoutUnrealized, // 钱包余额（不含未实现盈亏）
+		AvailableBalance: accountValue - totalMarginUsed, // 可用余额（总净值 - 占用保证金）
+		UnrealizedProfit: totalUnrealizedPnl,             // 未实现盈亏
+	}
 
 	log.Printf("✓ Hyperliquid 账户: 总净值=%.2f (钱包%.2f+未实现%.2f), 可用=%.2f, 保证金占用=%.2f",
 		accountValue,
 		walletBalanceWithoutUnrealized,
 		totalUnrealizedPnl,
-		result["availableBalance"],
+		result.AvailableBalance,
 		totalMarginUsed)
 
 	return result, nil
 }
 
 // GetPositions 获取所有持仓
-func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
+func (t *HyperliquidTrader) GetPositions() ([]Position, error) {
 	// 获取账户状态
 	accountState, err := t.exchange.Info().UserState(t.ctx, t.walletAddr)
 	if err != nil {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
 
-	var result []map[string]interface{}
+	var result []Position
 
 	// 遍历所有持仓
 	for _, assetPos := range accountState.AssetPositions {
@@ -142,19 +182,14 @@ func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
 			continue // 跳过无持仓的
 		}
 
-		posMap := make(map[string]interface{})
-
 		// 标准化symbol格式（Hyperliquid使用如"BTC"，我们转换为"BTCUSDT"）
-		symbol := position.Coin + "USDT"
-		posMap["symbol"] = symbol
+		p := Position{Symbol: position.Coin + "USDT"}
 
 		// 持仓数量和方向
 		if posAmt > 0 {
-			posMap["side"] = "long"
-			posMap["positionAmt"] = posAmt
+			p.Side, p.Quantity = SideLong, posAmt
 		} else {
-			posMap["side"] = "short"
-			posMap["positionAmt"] = -posAmt // 转为正数
+			p.Side, p.Quantity = SideShort, -posAmt // 转为正数
 		}
 
 		// 价格信息（EntryPx和LiquidationPx是指针类型）
@@ -175,13 +210,14 @@ func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
 			markPrice = positionValue / absFloat(posAmt)
 		}
 
-		posMap["entryPrice"] = entryPrice
-		posMap["markPrice"] = markPrice
-		posMap["unRealizedProfit"] = unrealizedPnl
-		posMap["leverage"] = float64(position.Leverage.Value)
-		posMap["liquidationPrice"] = liquidationPx
+		p.EntryPrice = entryPrice
+		p.MarkPrice = markPrice
+		p.UnrealizedProfit = unrealizedPnl
+		p.Leverage = float64(position.Leverage.Value)
+		p.LiquidationPrice = liquidationPx
+		p.MarginMode = position.Leverage.Type // isolated / cross
 
-		result = append(result, posMap)
+		result = append(result, p)
 	}
 
 	return result, nil
@@ -192,18 +228,71 @@ func (t *HyperliquidTrader) SetLeverage(symbol string, leverage int) error {
 	// Hyperliquid symbol格式（去掉USDT后缀）
 	coin := convertSymbolToHyperliquid(symbol)
 
-	// 调用UpdateLeverage (leverage int, name string, isCross bool)
-	_, err := t.exchange.UpdateLeverage(t.ctx, leverage, coin, false) // false = 逐仓模式
+	// Hyperliquid的保证金模式随杠杆一起设置 (leverage int, name string, isCross bool)
+	mode := t.marginMode(symbol)
+	_, err := t.exchange.UpdateLeverage(t.ctx, leverage, coin, mode == "cross")
 	if err != nil {
 		return fmt.Errorf("设置杠杆失败: %w", err)
 	}
 
-	log.Printf("  ✓ %s 杠杆已切换为 %dx", symbol, leverage)
+	log.Printf("  ✓ %s 杠杆已切换为 %dx (%s)", symbol, leverage, mode)
 	return nil
 }
 
-// OpenLong 开多仓
-func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+// SetMarginMode 设置该币种后续开仓使用的保证金模式（实现MarginModeSetter，设置杠杆时生效）
+func (t *HyperliquidTrader) SetMarginMode(symbol, mode string) error {
+	if mode != "isolated" && mode != "cross" {
+		return fmt.Errorf("无效的保证金模式: %s", mode)
+	}
+	if mode == "cross" {
+		if asset, ok := t.assetInfo(convertSymbolToHyperliquid(symbol)); ok && asset.OnlyIsolated {
+			return fmt.Errorf("%s 在Hyperliquid只支持逐仓", symbol)
+		}
+	}
+	t.marginModesMu.Lock()
+	t.marginModes[symbol] = mode
+	t.marginModesMu.Unlock()
+	return nil
+}
+
+// marginMode 该币种开仓使用的保证金模式（默认逐仓）
+func (t *HyperliquidTrader) marginMode(symbol string) string {
+	t.marginModesMu.Lock()
+	defer t.marginModesMu.Unlock()
+	if mode, ok := t.marginModes[symbol]; ok {
+		return mode
+	}
+	return "isolated"
+}
+
+// PlaceOrder 下单
+func (t *HyperliquidTrader) PlaceOrder(req OrderRequest) (*Order, error) {
+	switch req.Type {
+	case OrderTypeMarket:
+		if req.Side == SideShort {
+			return t.openShort(req.Symbol, req.Quantity, req.Leverage)
+		}
+		return t.openLong(req.Symbol, req.Quantity, req.Leverage)
+	case OrderTypeLimit, OrderTypePostOnly:
+		return t.openLimit(req.Symbol, req.Side, req.Quantity, req.Price, req.Leverage, req.Type == OrderTypePostOnly)
+	case OrderTypeStopLoss:
+		return protectiveOrder(req, t.setStopLoss(req.Symbol, strings.ToUpper(req.Side), req.Quantity, req.Price))
+	case OrderTypeTakeProfit:
+		return protectiveOrder(req, t.setTakeProfit(req.Symbol, strings.ToUpper(req.Side), req.Quantity, req.Price))
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedOrderType, req.Type)
+}
+
+// ClosePosition 市价平仓
+func (t *HyperliquidTrader) ClosePosition(symbol, side string, quantity float64) (*Order, error) {
+	if side == SideShort {
+		return t.closeShort(symbol, quantity)
+	}
+	return t.closeLong(symbol, quantity)
+}
+
+// openLong 开多仓
+func (t *HyperliquidTrader) openLong(symbol string, quantity float64, leverage int) (*Order, error) {
 	// 先取消该币种的所有委托单
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败: %v", err)
@@ -245,23 +334,21 @@ func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage i
 		ReduceOnly: false,
 	}
 
-	_, err = t.exchange.Order(t.ctx, order, nil)
+	status, err := t.exchange.Order(t.ctx, order, nil)
+	if err == nil {
+		err = orderStatusError(status)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("开多仓失败: %w", err)
 	}
 
 	log.Printf("✓ 开多仓成功: %s 数量: %.4f", symbol, roundedQuantity)
 
-	result := make(map[string]interface{})
-	result["orderId"] = 0 // Hyperliquid没有返回order ID
-	result["symbol"] = symbol
-	result["status"] = "FILLED"
-
-	return result, nil
+	return filledResult(symbol, status), nil
 }
 
-// OpenShort 开空仓
-func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+// openShort 开空仓
+func (t *HyperliquidTrader) openShort(symbol string, quantity float64, leverage int) (*Order, error) {
 	// 先取消该币种的所有委托单
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败: %v", err)
@@ -303,23 +390,21 @@ func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage
 		ReduceOnly: false,
 	}
 
-	_, err = t.exchange.Order(t.ctx, order, nil)
+	status, err := t.exchange.Order(t.ctx, order, nil)
+	if err == nil {
+		err = orderStatusError(status)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("开空仓失败: %w", err)
 	}
 
 	log.Printf("✓ 开空仓成功: %s 数量: %.4f", symbol, roundedQuantity)
 
-	result := make(map[string]interface{})
-	result["orderId"] = 0
-	result["symbol"] = symbol
-	result["status"] = "FILLED"
-
-	return result, nil
+	return filledResult(symbol, status), nil
 }
 
-// CloseLong 平多仓
-func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+// closeLong 平多仓
+func (t *HyperliquidTrader) closeLong(symbol string, quantity float64) (*Order, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -328,8 +413,8 @@ func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[stri
 		}
 
 		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "long" {
-				quantity = pos["positionAmt"].(float64)
+			if pos.Symbol == symbol && pos.Side == SideLong {
+				quantity = pos.Quantity
 				break
 			}
 		}
@@ -370,7 +455,10 @@ func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[stri
 		ReduceOnly: true, // 只平仓，不开新仓
 	}
 
-	_, err = t.exchange.Order(t.ctx, order, nil)
+	status, err := t.exchange.Order(t.ctx, order, nil)
+	if err == nil {
+		err = orderStatusError(status)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("平多仓失败: %w", err)
 	}
@@ -382,16 +470,11 @@ func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[stri
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
-	result := make(map[string]interface{})
-	result["orderId"] = 0
-	result["symbol"] = symbol
-	result["status"] = "FILLED"
-
-	return result, nil
+	return filledResult(symbol, status), nil
 }
 
-// CloseShort 平空仓
-func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+// closeShort 平空仓
+func (t *HyperliquidTrader) closeShort(symbol string, quantity float64) (*Order, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -400,8 +483,8 @@ func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[str
 		}
 
 		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "short" {
-				quantity = pos["positionAmt"].(float64)
+			if pos.Symbol == symbol && pos.Side == SideShort {
+				quantity = pos.Quantity
 				break
 			}
 		}
@@ -442,7 +525,10 @@ func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[str
 		ReduceOnly: true,
 	}
 
-	_, err = t.exchange.Order(t.ctx, order, nil)
+	status, err := t.exchange.Order(t.ctx, order, nil)
+	if err == nil {
+		err = orderStatusError(status)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("平空仓失败: %w", err)
 	}
@@ -454,12 +540,7 @@ func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[str
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
-	result := make(map[string]interface{})
-	result["orderId"] = 0
-	result["symbol"] = symbol
-	result["status"] = "FILLED"
-
-	return result, nil
+	return filledResult(symbol, status), nil
 }
 
 // CancelAllOrders 取消该币种的所有挂单
@@ -508,8 +589,8 @@ func (t *HyperliquidTrader) GetMarketPrice(symbol string) (float64, error) {
 	return 0, fmt.Errorf("未找到 %s 的价格", symbol)
 }
 
-// SetStopLoss 设置止损单
-func (t *HyperliquidTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+// setStopLoss 设置止损单
+func (t *HyperliquidTrader) setStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
 	coin := convertSymbolToHyperliquid(symbol)
 
 	isBuy := positionSide == "SHORT" // 空仓止损=买入，多仓止损=卖出
@@ -520,12 +601,12 @@ func (t *HyperliquidTrader) SetStopLoss(symbol string, positionSide string, quan
 	// ⚠️ 关键：价格也需要处理为5位有效数字
 	roundedStopPrice := t.roundPriceToSigfigs(stopPrice)
 
-	// 创建止损单（Trigger Order）
+	// 创建止损单（Trigger Order，触发后按市价成交，限价为最差可接受价格）
 	order := hyperliquid.CreateOrderRequest{
 		Coin:  coin,
 		IsBuy: isBuy,
-		Size:  roundedQuantity,  // 使用四舍五入后的数量
-		Price: roundedStopPrice, // 使用处理后的价格
+		Size:  roundedQuantity,
+		Price: t.triggerLimitPrice(roundedStopPrice, isBuy),
 		OrderType: hyperliquid.OrderType{
 			Trigger: &hyperliquid.TriggerOrderType{
 				TriggerPx: roundedStopPrice,
@@ -536,7 +617,10 @@ func (t *HyperliquidTrader) SetStopLoss(symbol string, positionSide string, quan
 		ReduceOnly: true,
 	}
 
-	_, err := t.exchange.Order(t.ctx, order, nil)
+	status, err := t.exchange.Order(t.ctx, order, nil)
+	if err == nil {
+		err = orderStatusError(status)
+	}
 	if err != nil {
 		return fmt.Errorf("设置止损失败: %w", err)
 	}
@@ -545,8 +629,8 @@ func (t *HyperliquidTrader) SetStopLoss(symbol string, positionSide string, quan
 	return nil
 }
 
-// SetTakeProfit 设置止盈单
-func (t *HyperliquidTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+// setTakeProfit 设置止盈单
+func (t *HyperliquidTrader) setTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
 	coin := convertSymbolToHyperliquid(symbol)
 
 	isBuy := positionSide == "SHORT" // 空仓止盈=买入，多仓止盈=卖出
@@ -557,12 +641,12 @@ func (t *HyperliquidTrader) SetTakeProfit(symbol string, positionSide string, qu
 	// ⚠️ 关键：价格也需要处理为5位有效数字
 	roundedTakeProfitPrice := t.roundPriceToSigfigs(takeProfitPrice)
 
-	// 创建止盈单（Trigger Order）
+	// 创建止盈单（Trigger Order，触发后按市价成交，限价为最差可接受价格）
 	order := hyperliquid.CreateOrderRequest{
 		Coin:  coin,
 		IsBuy: isBuy,
-		Size:  roundedQuantity,        // 使用四舍五入后的数量
-		Price: roundedTakeProfitPrice, // 使用处理后的价格
+		Size:  roundedQuantity,
+		Price: t.triggerLimitPrice(roundedTakeProfitPrice, isBuy),
 		OrderType: hyperliquid.OrderType{
 			Trigger: &hyperliquid.TriggerOrderType{
 				TriggerPx: roundedTakeProfitPrice,
@@ -573,7 +657,10 @@ func (t *HyperliquidTrader) SetTakeProfit(symbol string, positionSide string, qu
 		ReduceOnly: true,
 	}
 
-	_, err := t.exchange.Order(t.ctx, order, nil)
+	status, err := t.exchange.Order(t.ctx, order, nil)
+	if err == nil {
+		err = orderStatusError(status)
+	}
 	if err != nil {
 		return fmt.Errorf("设置止盈失败: %w", err)
 	}
@@ -582,6 +669,165 @@ func (t *HyperliquidTrader) SetTakeProfit(symbol string, positionSide string, qu
 	return nil
 }
 
+// openLimit 下开仓限价单（postOnly使用ALO，会立即成交时被交易所拒绝）
+func (t *HyperliquidTrader) openLimit(symbol, positionSide string, quantity, price float64, leverage int, postOnly bool) (*Order, error) {
+	// 设置杠杆（同时设置保证金模式）
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	coin := convertSymbolToHyperliquid(symbol)
+	roundedQuantity := t.roundToSzDecimals(coin, quantity)
+	roundedPrice := t.roundPriceToSigfigs(price)
+
+	tif := hyperliquid.TifGtc
+	if postOnly {
+		tif = hyperliquid.TifAlo
+	}
+	order := hyperliquid.CreateOrderRequest{
+		Coin:  coin,
+		IsBuy: positionSide == "long",
+		Size:  roundedQuantity,
+		Price: roundedPrice,
+		OrderType: hyperliquid.OrderType{
+			Limit: &hyperliquid.LimitOrderType{Tif: tif},
+		},
+		ReduceOnly: false,
+	}
+
+	status, err := t.exchange.Order(t.ctx, order, nil)
+	if err == nil {
+		err = orderStatusError(status)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("下限价单失败: %w", err)
+	}
+
+	result := &Order{Symbol: symbol}
+	switch {
+	case status.Resting != nil:
+		result.OrderID = strconv.FormatInt(int64(status.Resting.Oid), 10)
+		result.Status = OrderStatusNew
+	case status.Filled != nil:
+		result.OrderID = strconv.FormatInt(int64(status.Filled.Oid), 10)
+		result.Status = OrderStatusFilled
+	default:
+		return nil, fmt.Errorf("下限价单失败: 未返回订单状态")
+	}
+
+	log.Printf("✓ 限价单已提交: %s %s 数量: %.4f 价格: %.4f (%s)", symbol, positionSide, roundedQuantity, roundedPrice, tif)
+	return result, nil
+}
+
+// GetOrder 查询订单成交状态
+func (t *HyperliquidTrader) GetOrder(symbol, orderID string) (*OrderStatus, error) {
+	oid, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+
+	res, err := t.exchange.Info().QueryOrderByOid(t.ctx, t.walletAddr, oid)
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+	if res.Status != hyperliquid.OrderQueryStatusSuccess {
+		return nil, fmt.Errorf("查询订单失败: 订单 %s 不存在", orderID)
+	}
+
+	// 查询结果只有剩余数量，已成交数量 = 原始数量 - 剩余数量（成交均价近似取限价）
+	order := res.Order.Order
+	origSz, _ := strconv.ParseFloat(order.OrigSz, 64)
+	sz, _ := strconv.ParseFloat(order.Sz, 64)
+	limitPx, _ := strconv.ParseFloat(order.LimitPx, 64)
+	status := &OrderStatus{FilledQty: origSz - sz, AvgPrice: limitPx}
+	switch res.Order.Status {
+	case hyperliquid.OrderStatusValueOpen:
+		status.Status = OrderStatusNew
+		if status.FilledQty > 0 {
+			status.Status = OrderStatusPartiallyFilled
+		}
+	case hyperliquid.OrderStatusValueFilled:
+		status.Status = OrderStatusFilled
+		status.FilledQty = origSz
+	default:
+		status.Status = OrderStatusCanceled
+	}
+	return status, nil
+}
+
+// CancelOrder 撤销单个订单
+func (t *HyperliquidTrader) CancelOrder(symbol, orderID string) error {
+	oid, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+
+	if _, err := t.exchange.Cancel(t.ctx, convertSymbolToHyperliquid(symbol), oid); err != nil {
+		return fmt.Errorf("撤销订单失败: %w", err)
+	}
+	return nil
+}
+
+// GetOpenOrders 获取所有币种的当前挂单（Hyperliquid为单向持仓，PositionSide为空）
+func (t *HyperliquidTrader) GetOpenOrders() ([]OpenOrder, error) {
+	orders, err := t.exchange.Info().FrontendOpenOrders(t.ctx, t.walletAddr)
+	if err != nil {
+		return nil, fmt.Errorf("获取挂单失败: %w", err)
+	}
+
+	result := make([]OpenOrder, 0, len(orders))
+	for _, o := range orders {
+		result = append(result, OpenOrder{
+			Symbol:     o.Coin + "USDT",
+			OrderID:    strconv.FormatInt(o.Oid, 10),
+			Type:       o.OrderType,
+			Protective: o.ReduceOnly || o.IsTrigger || o.IsPositionTpSl,
+		})
+	}
+	return result, nil
+}
+
+// GetSymbolFilters 获取数量精度、最小下单金额、最大杠杆和维持保证金率（来自meta信息）
+func (t *HyperliquidTrader) GetSymbolFilters(symbol string) (*SymbolFilters, error) {
+	asset, ok := t.assetInfo(convertSymbolToHyperliquid(symbol))
+	if !ok {
+		return nil, fmt.Errorf("未找到 %s 的交易规则", symbol)
+	}
+	if asset.IsDelisted {
+		return nil, fmt.Errorf("%s 已在Hyperliquid下架", symbol)
+	}
+
+	step := math.Pow10(-asset.SzDecimals)
+	rules := &SymbolFilters{StepSize: step, MinQty: step, MinNotional: hyperliquidMinNotional}
+	if asset.MaxLeverage > 0 {
+		// Hyperliquid的维持保证金率为最大杠杆下初始保证金率的一半
+		rules.Brackets = []LeverageBracket{{NotionalCap: math.MaxFloat64, MaxLeverage: asset.MaxLeverage, MaintMarginRate: 0.5 / float64(asset.MaxLeverage)}}
+	}
+	return rules, nil
+}
+
+// GetFundingRates 获取所有币种的当前资金费率（实现FundingRateProvider）
+// Hyperliquid每小时结算一次，换算为8小时费率，与币安资金费率口径一致
+func (t *HyperliquidTrader) GetFundingRates() (map[string]float64, error) {
+	data, err := t.exchange.Info().MetaAndAssetCtxs(t.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取资金费率失败: %w", err)
+	}
+
+	rates := make(map[string]float64, len(data.Ctxs))
+	for i, assetCtx := range data.Ctxs {
+		if i >= len(data.Universe) {
+			break
+		}
+		rate, err := strconv.ParseFloat(assetCtx.Funding, 64)
+		if err != nil {
+			continue
+		}
+		rates[data.Universe[i].Name+"USDT"] = rate * 8
+	}
+	return rates, nil
+}
+
 // FormatQuantity 格式化数量到正确的精度
 func (t *HyperliquidTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
 	coin := convertSymbolToHyperliquid(symbol)
@@ -599,15 +845,33 @@ func (t *HyperliquidTrader) getSzDecimals(coin string) int {
 		return 4 // 默认精度
 	}
 
-	// 在meta.Universe中查找对应的币种
+	if asset, ok := t.assetInfo(coin); ok {
+		return asset.SzDecimals
+	}
+
+	log.Printf("⚠️  未找到 %s 的精度信息，使用默认精度4", coin)
+	return 4 // 默认精度
+}
+
+// assetInfo 在meta.Universe中查找币种信息
+func (t *HyperliquidTrader) assetInfo(coin string) (hyperliquid.AssetInfo, bool) {
+	if t.meta == nil {
+		return hyperliquid.AssetInfo{}, false
+	}
 	for _, asset := range t.meta.Universe {
 		if asset.Name == coin {
-			return asset.SzDecimals
+			return asset, true
 		}
 	}
+	return hyperliquid.AssetInfo{}, false
+}
 
-	log.Printf("⚠️  未找到 %s 的精度信息，使用默认精度4", coin)
-	return 4 // 默认精度
+// triggerLimitPrice 触发单的限价：买入上浮、卖出下浮最大滑点，避免行情剧烈波动时触发后无法成交
+func (t *HyperliquidTrader) triggerLimitPrice(triggerPrice float64, isBuy bool) float64 {
+	if isBuy {
+		return t.roundPriceToSigfigs(triggerPrice * (1 + hyperliquidTriggerSlippage))
+	}
+	return t.roundPriceToSigfigs(triggerPrice * (1 - hyperliquidTriggerSlippage))
 }
 
 // roundToSzDecimals 将数量四舍五入到正确的精度
@@ -662,6 +926,23 @@ func (t *HyperliquidTrader) roundPriceToSigfigs(price float64) float64 {
 	return rounded
 }
 
+// filledResult 市价（IOC）订单的成交结果
+func filledResult(symbol string, status hyperliquid.OrderStatus) *Order {
+	result := &Order{Symbol: symbol, Status: OrderStatusFilled}
+	if status.Filled != nil {
+		result.OrderID = strconv.FormatInt(int64(status.Filled.Oid), 10)
+	}
+	return result
+}
+
+// orderStatusError 订单被交易所拒绝时返回错误（IOC未成交也会以error状态返回）
+func orderStatusError(status hyperliquid.OrderStatus) error {
+	if status.Error != nil {
+		return fmt.Errorf("%s", *status.Error)
+	}
+	return nil
+}
+
 // convertSymbolToHyperliquid 将标准symbol转换为Hyperliquid格式
 // 例如: "BTCUSDT" -> "BTC"
 func convertSymbolToHyperliquid(symbol string) string {
@@ -0,0 +1,732 @@
+package exchange
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OKXTrader OKX永续合约交易器（v5 API，逐仓 + 双向持仓模式）
+type OKXTrader struct {
+	apiKey     string
+	secretKey  string
+	passphrase string
+	testnet    bool // 模拟盘（请求头 x-simulated-trading: 1）
+	baseURL    string
+	client     *http.Client
+
+	// 缓存合约信息（面值、数量步长、价格步长）
+	instruments map[string]okxInstrument
+	mu          sync.RWMutex
+}
+
+// okxInstrument OKX合约信息
+type okxInstrument struct {
+	CtVal  float64 // 合约面值（1张 = CtVal个币）
+	LotSz  float64 // 下单数量步长（张）
+	MinSz  float64 // 最小下单数量（张）
+	TickSz float64 // 价格步长
+}
+
+// NewOKXTrader 创建OKX交易器
+func NewOKXTrader(apiKey, secretKey, passphrase string, testnet bool) (*OKXTrader, error) {
+	if apiKey == "" || secretKey == "" || passphrase == "" {
+		return nil, fmt.Errorf("OKX API Key、Secret和Passphrase不能为空")
+	}
+
+	t := &OKXTrader{
+		apiKey:      apiKey,
+		secretKey:   secretKey,
+		passphrase:  passphrase,
+		testnet:     testnet,
+		baseURL:     "https://www.okx.com",
+		client:      &http.Client{Timeout: 30 * time.Second},
+		instruments: make(map[string]okxInstrument),
+	}
+
+	// 系统按多空分别管理持仓，需要双向持仓模式（已是该模式或有持仓时会返回错误，忽略即可）
+	if _, err := t.request("POST", "/api/v5/account/set-position-mode", map[string]interface{}{
+		"posMode": "long_short_mode",
+	}); err != nil {
+		log.Printf("  ⚠ 设置OKX双向持仓模式失败（如已是双向持仓可忽略）: %v", err)
+	}
+
+	return t, nil
+}
+
+// okxConfig OKX配置
+type okxConfig struct {
+	APIKey     string `json:"okx_api_key"`
+	SecretKey  string `json:"okx_secret_key"`
+	Passphrase string `json:"okx_passphrase"`
+	Testnet    bool   `json:"okx_testnet"` // 使用OKX模拟盘
+}
+
+func init() {
+	Register(Driver{
+		Name:        "okx",
+		Description: "OKX合约",
+		New: func(cfg Config) (Exchange, error) {
+			c := decodeConfig[okxConfig](cfg)
+			return NewOKXTrader(c.APIKey, c.SecretKey, c.Passphrase, c.Testnet)
+		},
+		Validate: func(cfg Config) error {
+			c := decodeConfig[okxConfig](cfg)
+			if c.APIKey == "" || c.SecretKey == "" || c.Passphrase == "" {
+				return fmt.Errorf("使用OKX时必须配置okx_api_key, okx_secret_key和okx_passphrase")
+			}
+			return nil
+		},
+		AccountKey: func(cfg Config) string { return decodeConfig[okxConfig](cfg).APIKey },
+		TestnetKey: "okx_testnet",
+	})
+}
+
+// toInstID 将币安格式的symbol转换为OKX合约ID（BTCUSDT -> BTC-USDT-SWAP）
+func toInstID(symbol string) string {
+	base := strings.TrimSuffix(symbol, "USDT")
+	return base + "-USDT-SWAP"
+}
+
+// fromInstID 将OKX合约ID转换为币安格式的symbol（BTC-USDT-SWAP -> BTCUSDT）
+func fromInstID(instID string) string {
+	return strings.ReplaceAll(strings.TrimSuffix(instID, "-SWAP"), "-", "")
+}
+
+// sign 生成OKX签名：Base64(HMAC-SHA256(timestamp + method + requestPath + body))
+func (t *OKXTrader) sign(timestamp, method, requestPath, body string) string {
+	mac := hmac.New(sha256.New, []byte(t.secretKey))
+	mac.Write([]byte(timestamp + method + requestPath + body))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// request 发送签名请求，返回data字段
+func (t *OKXTrader) request(method, requestPath string, payload interface{}) (json.RawMessage, error) {
+	var body string
+	if payload != nil {
+		bs, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = string(bs)
+	}
+
+	req, err := http.NewRequest(method, t.baseURL+requestPath, bytes.NewBufferString(body))
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("OK-ACCESS-KEY", t.apiKey)
+	req.Header.Set("OK-ACCESS-SIGN", t.sign(timestamp, method, requestPath, body))
+	req.Header.Set("OK-ACCESS-TIMESTAMP", timestamp)
+	req.Header.Set("OK-ACCESS-PASSPHRASE", t.passphrase)
+	if t.testnet {
+		req.Header.Set("x-simulated-trading", "1")
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Code string          `json:"code"`
+		Msg  string          `json:"msg"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("解析OKX响应失败: %w, body: %s", err, string(respBody))
+	}
+	if result.Code != "0" {
+		// 批量/下单接口的具体错误在data[].sMsg中
+		var items []struct {
+			SCode string `json:"sCode"`
+			SMsg  string `json:"sMsg"`
+		}
+		if json.Unmarshal(result.Data, &items) == nil && len(items) > 0 && items[0].SMsg != "" {
+			return nil, fmt.Errorf("OKX API错误 %s: %s (%s)", result.Code, result.Msg, items[0].SMsg)
+		}
+		return nil, fmt.Errorf("OKX API错误 %s: %s", result.Code, result.Msg)
+	}
+	return result.Data, nil
+}
+
+// getInstrument 获取合约信息（带缓存）
+func (t *OKXTrader) getInstrument(symbol string) (okxInstrument, error) {
+	instID := toInstID(symbol)
+
+	t.mu.RLock()
+	if inst, ok := t.instruments[instID]; ok {
+		t.mu.RUnlock()
+		return inst, nil
+	}
+	t.mu.RUnlock()
+
+	data, err := t.request("GET", "/api/v5/public/instruments?instType=SWAP&instId="+instID, nil)
+	if err != nil {
+		return okxInstrument{}, fmt.Errorf("获取OKX合约信息失败: %w", err)
+	}
+
+	var items []struct {
+		CtVal  string `json:"ctVal"`
+		LotSz  string `json:"lotSz"`
+		MinSz  string `json:"minSz"`
+		TickSz string `json:"tickSz"`
+	}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return okxInstrument{}, err
+	}
+	if len(items) == 0 {
+		return okxInstrument{}, fmt.Errorf("OKX不支持合约 %s", instID)
+	}
+
+	inst := okxInstrument{}
+	inst.CtVal, _ = strconv.ParseFloat(items[0].CtVal, 64)
+	inst.LotSz, _ = strconv.ParseFloat(items[0].LotSz, 64)
+	inst.MinSz, _ = strconv.ParseFloat(items[0].MinSz, 64)
+	inst.TickSz, _ = strconv.ParseFloat(items[0].TickSz, 64)
+	if inst.CtVal <= 0 {
+		return okxInstrument{}, fmt.Errorf("OKX合约 %s 面值无效", instID)
+	}
+
+	t.mu.Lock()
+	t.instruments[instID] = inst
+	t.mu.Unlock()
+	return inst, nil
+}
+
+// toContracts 将币数量换算为合约张数字符串（按lotSz向下取整）
+func (t *OKXTrader) toContracts(symbol string, quantity float64) (string, error) {
+	inst, err := t.getInstrument(symbol)
+	if err != nil {
+		return "", err
+	}
+
+	contracts := quantity / inst.CtVal
+	if inst.LotSz > 0 {
+		contracts = math.Floor(contracts/inst.LotSz+1e-9) * inst.LotSz
+	}
+	if contracts <= 0 || contracts < inst.MinSz {
+		return "", fmt.Errorf("下单数量过小: %.8f（最小 %.8f 张，每张 %v）", contracts, inst.MinSz, inst.CtVal)
+	}
+	return strconv.FormatFloat(contracts, 'f', -1, 64), nil
+}
+
+// formatPrice 按价格步长格式化
+func (t *OKXTrader) formatPrice(symbol string, price float64) (string, error) {
+	inst, err := t.getInstrument(symbol)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatFloat(roundToTickSize(price, inst.TickSz), 'f', -1, 64), nil
+}
+
+// GetAccount 获取账户余额
+func (t *OKXTrader) GetAccount() (*Account, error) {
+	data, err := t.request("GET", "/api/v5/account/balance?ccy=USDT", nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取账户信息失败: %w", err)
+	}
+
+	var accounts []struct {
+		Details []struct {
+			Ccy      string `json:"ccy"`
+			CashBal  string `json:"cashBal"`
+			AvailEq  string `json:"availEq"`
+			AvailBal string `json:"availBal"`
+			Upl      string `json:"upl"`
+		} `json:"details"`
+	}
+	if err := json.Unmarshal(data, &accounts); err != nil {
+		return nil, fmt.Errorf("解析账户信息失败: %w", err)
+	}
+
+	result := &Account{}
+	if len(accounts) > 0 {
+		for _, d := range accounts[0].Details {
+			if d.Ccy != "USDT" {
+				continue
+			}
+			wallet, _ := strconv.ParseFloat(d.CashBal, 64)
+			upl, _ := strconv.ParseFloat(d.Upl, 64)
+			avail, err := strconv.ParseFloat(d.AvailEq, 64)
+			if err != nil {
+				avail, _ = strconv.ParseFloat(d.AvailBal, 64) // 简单交易模式没有availEq
+			}
+			result.WalletBalance = wallet
+			result.AvailableBalance = avail
+			result.UnrealizedProfit = upl
+		}
+	}
+
+	log.Printf("✓ OKX API返回: 总余额=%.2f, 可用=%.2f, 未实现盈亏=%.2f",
+		result.WalletBalance, result.AvailableBalance, result.UnrealizedProfit)
+	return result, nil
+}
+
+// GetPositions 获取所有持仓（数量换算为币数量）
+func (t *OKXTrader) GetPositions() ([]Position, error) {
+	data, err := t.request("GET", "/api/v5/account/positions?instType=SWAP", nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	var positions []struct {
+		InstID  string `json:"instId"`
+		PosSide string `json:"posSide"`
+		Pos     string `json:"pos"`
+		AvgPx   string `json:"avgPx"`
+		MarkPx  string `json:"markPx"`
+		Upl     string `json:"upl"`
+		Lever   string `json:"lever"`
+		LiqPx   string `json:"liqPx"`
+	}
+	if err := json.Unmarshal(data, &positions); err != nil {
+		return nil, fmt.Errorf("解析持仓失败: %w", err)
+	}
+
+	var result []Position
+	for _, pos := range positions {
+		contracts, _ := strconv.ParseFloat(pos.Pos, 64)
+		if contracts == 0 {
+			continue
+		}
+
+		symbol := fromInstID(pos.InstID)
+		inst, err := t.getInstrument(symbol)
+		if err != nil {
+			return nil, err
+		}
+
+		side := pos.PosSide
+		if side == "net" { // 单向持仓模式下按正负判断方向
+			side = "long"
+			if contracts < 0 {
+				side = "short"
+			}
+		}
+
+		p := Position{
+			Symbol:     symbol,
+			Side:       side,
+			Quantity:   math.Abs(contracts) * inst.CtVal,
+			MarginMode: "isolated",
+		}
+		p.EntryPrice, _ = strconv.ParseFloat(pos.AvgPx, 64)
+		p.MarkPrice, _ = strconv.ParseFloat(pos.MarkPx, 64)
+		p.UnrealizedProfit, _ = strconv.ParseFloat(pos.Upl, 64)
+		p.Leverage, _ = strconv.ParseFloat(pos.Lever, 64)
+		p.LiquidationPrice, _ = strconv.ParseFloat(pos.LiqPx, 64)
+
+		result = append(result, p)
+	}
+
+	return result, nil
+}
+
+// SetLeverage 设置杠杆（逐仓模式下多空分别设置）
+func (t *OKXTrader) SetLeverage(symbol string, leverage int) error {
+	for _, posSide := range []string{"long", "short"} {
+		_, err := t.request("POST", "/api/v5/account/set-leverage", map[string]interface{}{
+			"instId":  toInstID(symbol),
+			"lever":   strconv.Itoa(leverage),
+			"mgnMode": "isolated",
+			"posSide": posSide,
+		})
+		if err != nil {
+			return fmt.Errorf("设置杠杆失败: %w", err)
+		}
+	}
+	log.Printf("  ✓ %s 杠杆已切换为 %dx", symbol, leverage)
+	return nil
+}
+
+// submitOrder 下单（ordType: market / limit / post_only，reduceOnly=true表示只减仓），同时返回下单张数
+func (t *OKXTrader) submitOrder(symbol, side, posSide, ordType string, quantity, price float64, reduceOnly bool) (*Order, string, error) {
+	sz, err := t.toContracts(symbol, quantity)
+	if err != nil {
+		return nil, "", err
+	}
+
+	params := map[string]interface{}{
+		"instId":     toInstID(symbol),
+		"tdMode":     "isolated",
+		"side":       side,
+		"posSide":    posSide,
+		"ordType":    ordType,
+		"sz":         sz,
+		"reduceOnly": reduceOnly,
+	}
+	if ordType != "market" {
+		px, err := t.formatPrice(symbol, price)
+		if err != nil {
+			return nil, "", err
+		}
+		params["px"] = px
+	}
+
+	data, err := t.request("POST", "/api/v5/trade/order", params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var orders []struct {
+		OrdID string `json:"ordId"`
+	}
+	if err := json.Unmarshal(data, &orders); err != nil || len(orders) == 0 {
+		return nil, "", fmt.Errorf("解析下单响应失败: %s", string(data))
+	}
+
+	order := &Order{OrderID: orders[0].OrdID, Symbol: symbol, Status: OrderStatusFilled}
+	if ordType != "market" {
+		order.Status = OrderStatusNew
+	}
+	return order, sz, nil
+}
+
+// PlaceOrder 下单
+func (t *OKXTrader) PlaceOrder(req OrderRequest) (*Order, error) {
+	switch req.Type {
+	case OrderTypeMarket:
+		if req.Side == SideShort {
+			return t.openShort(req.Symbol, req.Quantity, req.Leverage)
+		}
+		return t.openLong(req.Symbol, req.Quantity, req.Leverage)
+	case OrderTypeLimit, OrderTypePostOnly:
+		return t.openLimit(req.Symbol, req.Side, req.Quantity, req.Price, req.Leverage, req.Type == OrderTypePostOnly)
+	case OrderTypeStopLoss:
+		return protectiveOrder(req, t.setStopLoss(req.Symbol, req.Side, req.Quantity, req.Price))
+	case OrderTypeTakeProfit:
+		return protectiveOrder(req, t.setTakeProfit(req.Symbol, req.Side, req.Quantity, req.Price))
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedOrderType, req.Type)
+}
+
+// ClosePosition 市价平仓
+func (t *OKXTrader) ClosePosition(symbol, side string, quantity float64) (*Order, error) {
+	if side == SideShort {
+		return t.closeShort(symbol, quantity)
+	}
+	return t.closeLong(symbol, quantity)
+}
+
+// openLong 开多仓
+func (t *OKXTrader) openLong(symbol string, quantity float64, leverage int) (*Order, error) {
+	// 先取消该币种的所有委托单（清理旧的止损止盈单）
+	if err := t.CancelAllOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
+	}
+
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	order, sz, err := t.submitOrder(symbol, "buy", "long", "market", quantity, 0, false)
+	if err != nil {
+		return nil, fmt.Errorf("开多仓失败: %w", err)
+	}
+
+	log.Printf("✓ 开多仓成功: %s 数量: %s张", symbol, sz)
+	return order, nil
+}
+
+// openShort 开空仓
+func (t *OKXTrader) openShort(symbol string, quantity float64, leverage int) (*Order, error) {
+	// 先取消该币种的所有委托单（清理旧的止损止盈单）
+	if err := t.CancelAllOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
+	}
+
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	order, sz, err := t.submitOrder(symbol, "sell", "short", "market", quantity, 0, false)
+	if err != nil {
+		return nil, fmt.Errorf("开空仓失败: %w", err)
+	}
+
+	log.Printf("✓ 开空仓成功: %s 数量: %s张", symbol, sz)
+	return order, nil
+}
+
+// openLimit 下开仓限价单（postOnly=true时会立即成交的订单被交易所拒绝）
+func (t *OKXTrader) openLimit(symbol, positionSide string, quantity, price float64, leverage int, postOnly bool) (*Order, error) {
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	side := "buy"
+	if positionSide == "short" {
+		side = "sell"
+	}
+	ordType := "limit"
+	if postOnly {
+		ordType = "post_only"
+	}
+
+	order, sz, err := t.submitOrder(symbol, side, positionSide, ordType, quantity, price, false)
+	if err != nil {
+		return nil, fmt.Errorf("下限价单失败: %w", err)
+	}
+
+	log.Printf("✓ 限价单已提交: %s %s 数量: %s张 价格: %.4f (%s)", symbol, positionSide, sz, price, ordType)
+	return order, nil
+}
+
+// GetOrder 查询订单成交状态
+func (t *OKXTrader) GetOrder(symbol, orderID string) (*OrderStatus, error) {
+	data, err := t.request("GET", "/api/v5/trade/order?instId="+toInstID(symbol)+"&ordId="+orderID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+
+	var orders []struct {
+		State     string `json:"state"`
+		AccFillSz string `json:"accFillSz"`
+		AvgPx     string `json:"avgPx"`
+	}
+	if err := json.Unmarshal(data, &orders); err != nil || len(orders) == 0 {
+		return nil, fmt.Errorf("未找到订单: %s", orderID)
+	}
+	inst, err := t.getInstrument(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	order := orders[0]
+	status := &OrderStatus{}
+	contracts, _ := strconv.ParseFloat(order.AccFillSz, 64)
+	status.FilledQty = contracts * inst.CtVal
+	status.AvgPrice, _ = strconv.ParseFloat(order.AvgPx, 64)
+	switch order.State {
+	case "live":
+		status.Status = OrderStatusNew
+	case "partially_filled":
+		status.Status = OrderStatusPartiallyFilled
+	case "filled":
+		status.Status = OrderStatusFilled
+	default: // canceled / mmp_canceled
+		status.Status = OrderStatusCanceled
+	}
+	return status, nil
+}
+
+// CancelOrder 撤销单个订单
+func (t *OKXTrader) CancelOrder(symbol, orderID string) error {
+	_, err := t.request("POST", "/api/v5/trade/cancel-order", map[string]interface{}{
+		"instId": toInstID(symbol),
+		"ordId":  orderID,
+	})
+	if err != nil {
+		return fmt.Errorf("撤销订单失败: %w", err)
+	}
+	return nil
+}
+
+// closeSide 平掉一个方向的持仓（quantity=0表示全部平仓）
+func (t *OKXTrader) closeSide(symbol, posSide string, quantity float64) (*Order, error) {
+	var order *Order
+
+	if quantity == 0 {
+		// 全部平仓使用市价全平接口
+		_, err := t.request("POST", "/api/v5/trade/close-position", map[string]interface{}{
+			"instId":  toInstID(symbol),
+			"mgnMode": "isolated",
+			"posSide": posSide,
+		})
+		if err != nil {
+			return nil, err
+		}
+		order = &Order{Symbol: symbol, Status: OrderStatusFilled}
+	} else {
+		side := "sell"
+		if posSide == "short" {
+			side = "buy"
+		}
+		var err error
+		order, _, err = t.submitOrder(symbol, side, posSide, "market", quantity, 0, true) // 部分平仓：只减仓
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 平仓后取消该币种的所有挂单（止损止盈单）
+	if err := t.CancelAllOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消挂单失败: %v", err)
+	}
+	return order, nil
+}
+
+// closeLong 平多仓（quantity=0表示全部平仓）
+func (t *OKXTrader) closeLong(symbol string, quantity float64) (*Order, error) {
+	order, err := t.closeSide(symbol, "long", quantity)
+	if err != nil {
+		return nil, fmt.Errorf("平多仓失败: %w", err)
+	}
+	log.Printf("✓ 平多仓成功: %s", symbol)
+	return order, nil
+}
+
+// closeShort 平空仓（quantity=0表示全部平仓）
+func (t *OKXTrader) closeShort(symbol string, quantity float64) (*Order, error) {
+	order, err := t.closeSide(symbol, "short", quantity)
+	if err != nil {
+		return nil, fmt.Errorf("平空仓失败: %w", err)
+	}
+	log.Printf("✓ 平空仓成功: %s", symbol)
+	return order, nil
+}
+
+// GetMarketPrice 获取最新成交价
+func (t *OKXTrader) GetMarketPrice(symbol string) (float64, error) {
+	data, err := t.request("GET", "/api/v5/market/ticker?instId="+toInstID(symbol), nil)
+	if err != nil {
+		return 0, fmt.Errorf("获取价格失败: %w", err)
+	}
+
+	var tickers []struct {
+		Last string `json:"last"`
+	}
+	if err := json.Unmarshal(data, &tickers); err != nil || len(tickers) == 0 {
+		return 0, fmt.Errorf("未找到价格")
+	}
+	return strconv.ParseFloat(tickers[0].Last, 64)
+}
+
+// placeAlgoOrder 下条件单（止损/止盈，触发后市价平仓）
+func (t *OKXTrader) placeAlgoOrder(symbol, positionSide string, quantity, triggerPrice float64, isStopLoss bool) error {
+	posSide := strings.ToLower(positionSide)
+	side := "sell"
+	if posSide == "short" {
+		side = "buy"
+	}
+
+	sz, err := t.toContracts(symbol, quantity)
+	if err != nil {
+		return err
+	}
+	px, err := t.formatPrice(symbol, triggerPrice)
+	if err != nil {
+		return err
+	}
+
+	params := map[string]interface{}{
+		"instId":     toInstID(symbol),
+		"tdMode":     "isolated",
+		"side":       side,
+		"posSide":    posSide,
+		"ordType":    "conditional",
+		"sz":         sz,
+		"reduceOnly": true,
+	}
+	if isStopLoss {
+		params["slTriggerPx"] = px
+		params["slOrdPx"] = "-1" // -1 表示市价
+	} else {
+		params["tpTriggerPx"] = px
+		params["tpOrdPx"] = "-1"
+	}
+
+	_, err = t.request("POST", "/api/v5/trade/order-algo", params)
+	return err
+}
+
+// setStopLoss 设置止损单
+func (t *OKXTrader) setStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	if err := t.placeAlgoOrder(symbol, positionSide, quantity, stopPrice, true); err != nil {
+		return fmt.Errorf("设置止损失败: %w", err)
+	}
+	log.Printf("  止损价设置: %.4f", stopPrice)
+	return nil
+}
+
+// setTakeProfit 设置止盈单
+func (t *OKXTrader) setTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	if err := t.placeAlgoOrder(symbol, positionSide, quantity, takeProfitPrice, false); err != nil {
+		return fmt.Errorf("设置止盈失败: %w", err)
+	}
+	log.Printf("  止盈价设置: %.4f", takeProfitPrice)
+	return nil
+}
+
+// CancelAllOrders 取消该币种的所有挂单（普通委托 + 条件单）
+func (t *OKXTrader) CancelAllOrders(symbol string) error {
+	instID := toInstID(symbol)
+
+	// 1. 普通委托
+	data, err := t.request("GET", "/api/v5/trade/orders-pending?instType=SWAP&instId="+instID, nil)
+	if err != nil {
+		return fmt.Errorf("获取挂单失败: %w", err)
+	}
+	var orders []struct {
+		OrdID string `json:"ordId"`
+	}
+	if err := json.Unmarshal(data, &orders); err == nil && len(orders) > 0 {
+		var batch []map[string]string
+		for _, o := range orders {
+			batch = append(batch, map[string]string{"instId": instID, "ordId": o.OrdID})
+		}
+		if _, err := t.request("POST", "/api/v5/trade/cancel-batch-orders", batch); err != nil {
+			return fmt.Errorf("取消挂单失败: %w", err)
+		}
+	}
+
+	// 2. 条件单（止损止盈）
+	data, err = t.request("GET", "/api/v5/trade/orders-algo-pending?ordType=conditional&instType=SWAP&instId="+instID, nil)
+	if err != nil {
+		return fmt.Errorf("获取条件单失败: %w", err)
+	}
+	var algos []struct {
+		AlgoID string `json:"algoId"`
+	}
+	if err := json.Unmarshal(data, &algos); err == nil && len(algos) > 0 {
+		var batch []map[string]string
+		for _, a := range algos {
+			batch = append(batch, map[string]string{"instId": instID, "algoId": a.AlgoID})
+		}
+		if _, err := t.request("POST", "/api/v5/trade/cancel-algos", batch); err != nil {
+			return fmt.Errorf("取消条件单失败: %w", err)
+		}
+	}
+
+	log.Printf("  ✓ 已取消 %s 的所有挂单", symbol)
+	return nil
+}
+
+// GetSymbolFilters OKX按张数下单，数量在toContracts中按面值和步长取整，不提供统一的下单规则
+func (t *OKXTrader) GetSymbolFilters(symbol string) (*SymbolFilters, error) {
+	return nil, nil
+}
+
+// FormatQuantity 格式化数量（按合约面值和步长取整后换算回币数量）
+func (t *OKXTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	sz, err := t.toContracts(symbol, quantity)
+	if err != nil {
+		return "", err
+	}
+	inst, err := t.getInstrument(symbol)
+	if err != nil {
+		return "", err
+	}
+	contracts, _ := strconv.ParseFloat(sz, 64)
+	return strconv.FormatFloat(contracts*inst.CtVal, 'f', -1, 64), nil
+}
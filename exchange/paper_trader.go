@@ -0,0 +1,747 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"nofx/ratelimit"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+const (
+	paperTakerFeeRate       = 0.0004          // 模拟手续费（币安taker 0.04%）
+	paperMakerFeeRate       = 0.0002          // 限价单成交手续费（币安maker 0.02%）
+	paperOrderRetention     = 24 * time.Hour  // 已结束的限价单保留时长（供查询成交状态）
+	paperMaintenanceMargin  = 0.005           // 维持保证金率（用于估算强平价）
+	paperFundingInterval    = 8 * time.Hour   // 资金费结算间隔
+	paperPriceCacheDuration = 5 * time.Second // 标记价格缓存有效期
+)
+
+// paperPosition 模拟持仓
+type paperPosition struct {
+	Symbol          string    `json:"symbol"`
+	Side            string    `json:"side"` // "long" or "short"
+	Quantity        float64   `json:"quantity"`
+	EntryPrice      float64   `json:"entry_price"`
+	Leverage        int       `json:"leverage"`
+	StopLoss        float64   `json:"stop_loss,omitempty"`
+	TakeProfit      float64   `json:"take_profit,omitempty"`
+	OpenTime        time.Time `json:"open_time"`
+	NextFundingTime time.Time `json:"next_funding_time"`
+	FundingPaid     float64   `json:"funding_paid"` // 累计支付的资金费（负数表示收到）
+}
+
+// paperOrder 模拟限价开仓单（标记价格触及挂单价时按挂单价成交）
+type paperOrder struct {
+	ID        int64     `json:"id"`
+	Symbol    string    `json:"symbol"`
+	Side      string    `json:"side"` // "long" or "short"
+	Quantity  float64   `json:"quantity"`
+	Price     float64   `json:"price"`
+	Leverage  int       `json:"leverage"`
+	Status    string    `json:"status"` // NEW / FILLED / CANCELED
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// paperState 模拟账户状态（持久化到磁盘，重启后继续）
+type paperState struct {
+	WalletBalance float64                   `json:"wallet_balance"`
+	RealizedPnL   float64                   `json:"realized_pnl"`
+	FeesPaid      float64                   `json:"fees_paid"`
+	Positions     map[string]*paperPosition `json:"positions"` // symbol_side -> 持仓
+	Orders        map[int64]*paperOrder     `json:"orders"`    // 订单ID -> 限价单
+	Leverage      map[string]int            `json:"leverage"`  // symbol -> 杠杆
+	NextOrderID   int64                     `json:"next_order_id"`
+}
+
+// paperPrice 标记价格缓存
+type paperPrice struct {
+	markPrice       float64
+	fundingRate     float64
+	nextFundingTime time.Time
+	fetchedAt       time.Time
+}
+
+// PaperTrader 模拟盘交易器（使用币安实时标记价格撮合，不动用真实资金）
+type PaperTrader struct {
+	client    *futures.Client
+	stateFile string
+
+	mu           sync.Mutex
+	state        paperState
+	prices       map[string]*paperPrice
+	clientOrders map[string]*Order // 客户端订单ID -> 开仓单（只保存在内存中）
+}
+
+// NewPaperTrader 创建模拟盘交易器（stateFile存在时恢复之前的模拟账户）
+func NewPaperTrader(initialBalance float64, stateFile string) (*PaperTrader, error) {
+	t := &PaperTrader{
+		client:       futures.NewClient("", ""), // 行情接口无需密钥
+		stateFile:    stateFile,
+		prices:       make(map[string]*paperPrice),
+		clientOrders: make(map[string]*Order),
+		state: paperState{
+			WalletBalance: initialBalance,
+			Positions:     make(map[string]*paperPosition),
+			Orders:        make(map[int64]*paperOrder),
+			Leverage:      make(map[string]int),
+			NextOrderID:   1,
+		},
+	}
+	t.client.HTTPClient = ratelimit.NewBinanceClient(0) // 与其他trader共享Binance权重限制
+
+	if stateFile != "" {
+		data, err := os.ReadFile(stateFile)
+		if err == nil {
+			if err := json.Unmarshal(data, &t.state); err != nil {
+				return nil, fmt.Errorf("解析模拟盘状态文件失败: %w", err)
+			}
+			if t.state.Positions == nil {
+				t.state.Positions = make(map[string]*paperPosition)
+			}
+			if t.state.Orders == nil {
+				t.state.Orders = make(map[int64]*paperOrder)
+			}
+			if t.state.Leverage == nil {
+				t.state.Leverage = make(map[string]int)
+			}
+			log.Printf("📄 已恢复模拟盘账户: 余额 %.2f USDT, 持仓 %d 个", t.state.WalletBalance, len(t.state.Positions))
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("读取模拟盘状态文件失败: %w", err)
+		}
+	}
+
+	return t, nil
+}
+
+func init() {
+	Register(Driver{
+		Name:        "paper",
+		Description: "模拟盘",
+		New: func(cfg Config) (Exchange, error) {
+			return NewPaperTrader(cfg.InitialBalance, fmt.Sprintf("paper_trading/%s.json", cfg.TraderID))
+		},
+	})
+}
+
+// fetchPrice 获取标记价格和资金费率（带短时缓存）
+func (t *PaperTrader) fetchPrice(symbol string) (*paperPrice, error) {
+	if p, ok := t.prices[symbol]; ok && time.Since(p.fetchedAt) < paperPriceCacheDuration {
+		return p, nil
+	}
+
+	res, err := t.client.NewPremiumIndexService().Symbol(symbol).Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取标记价格失败: %w", err)
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("未找到 %s 的标记价格", symbol)
+	}
+
+	markPrice, err := strconv.ParseFloat(res[0].MarkPrice, 64)
+	if err != nil || markPrice <= 0 {
+		return nil, fmt.Errorf("标记价格无效: %s", res[0].MarkPrice)
+	}
+	fundingRate, _ := strconv.ParseFloat(res[0].LastFundingRate, 64)
+
+	p := &paperPrice{
+		markPrice:       markPrice,
+		fundingRate:     fundingRate,
+		nextFundingTime: time.UnixMilli(res[0].NextFundingTime),
+		fetchedAt:       time.Now(),
+	}
+	t.prices[symbol] = p
+	return p, nil
+}
+
+// liquidationPrice 估算逐仓强平价
+func (p *paperPosition) liquidationPrice() float64 {
+	lev := float64(p.Leverage)
+	if p.Side == "long" {
+		return p.EntryPrice * (1 - 1/lev + paperMaintenanceMargin)
+	}
+	return p.EntryPrice * (1 + 1/lev - paperMaintenanceMargin)
+}
+
+// unrealizedPnL 计算未实现盈亏
+func (p *paperPosition) unrealizedPnL(markPrice float64) float64 {
+	if p.Side == "long" {
+		return (markPrice - p.EntryPrice) * p.Quantity
+	}
+	return (p.EntryPrice - markPrice) * p.Quantity
+}
+
+// margin 计算占用保证金
+func (p *paperPosition) margin() float64 {
+	return p.Quantity * p.EntryPrice / float64(p.Leverage)
+}
+
+// settle 结算持仓：限价单成交、资金费、止损止盈触发、强平（调用方需持有锁）
+func (t *PaperTrader) settle() {
+	changed := t.matchOrders()
+	for key, pos := range t.state.Positions {
+		price, err := t.fetchPrice(pos.Symbol)
+		if err != nil {
+			log.Printf("  ⚠ 模拟盘结算 %s 失败: %v", pos.Symbol, err)
+			continue
+		}
+
+		// 资金费：多头在正费率时支付，空头收取
+		for !pos.NextFundingTime.IsZero() && !time.Now().Before(pos.NextFundingTime) {
+			funding := pos.Quantity * price.markPrice * price.fundingRate
+			if pos.Side == "short" {
+				funding = -funding
+			}
+			t.state.WalletBalance -= funding
+			pos.FundingPaid += funding
+			pos.NextFundingTime = pos.NextFundingTime.Add(paperFundingInterval)
+			log.Printf("  💸 模拟盘资金费结算 %s %s: %.4f USDT (费率 %.4f%%)", pos.Symbol, pos.Side, -funding, price.fundingRate*100)
+			changed = true
+		}
+
+		// 止损止盈/强平触发（按触发价成交）
+		mark := price.markPrice
+		var exitPrice float64
+		var reason string
+		if pos.Side == "long" {
+			switch {
+			case mark <= pos.liquidationPrice():
+				exitPrice, reason = pos.liquidationPrice(), "强平"
+			case pos.StopLoss > 0 && mark <= pos.StopLoss:
+				exitPrice, reason = pos.StopLoss, "止损"
+			case pos.TakeProfit > 0 && mark >= pos.TakeProfit:
+				exitPrice, reason = pos.TakeProfit, "止盈"
+			}
+		} else {
+			switch {
+			case mark >= pos.liquidationPrice():
+				exitPrice, reason = pos.liquidationPrice(), "强平"
+			case pos.StopLoss > 0 && mark >= pos.StopLoss:
+				exitPrice, reason = pos.StopLoss, "止损"
+			case pos.TakeProfit > 0 && mark <= pos.TakeProfit:
+				exitPrice, reason = pos.TakeProfit, "止盈"
+			}
+		}
+		if reason != "" {
+			pnl, _ := t.realize(key, pos.Quantity, exitPrice)
+			log.Printf("  🎯 模拟盘触发%s: %s %s @ %.4f, 盈亏 %.2f USDT", reason, pos.Symbol, pos.Side, exitPrice, pnl)
+			changed = true
+		}
+	}
+
+	if changed {
+		t.save()
+	}
+}
+
+// realize 平掉指定数量并结算已实现盈亏（调用方需持有锁），返回扣除手续费后的盈亏和手续费
+func (t *PaperTrader) realize(key string, quantity, price float64) (float64, float64) {
+	pos := t.state.Positions[key]
+	if quantity > pos.Quantity {
+		quantity = pos.Quantity
+	}
+
+	var pnl float64
+	if pos.Side == "long" {
+		pnl = (price - pos.EntryPrice) * quantity
+	} else {
+		pnl = (pos.EntryPrice - price) * quantity
+	}
+	fee := quantity * price * paperTakerFeeRate
+
+	t.state.WalletBalance += pnl - fee
+	t.state.RealizedPnL += pnl
+	t.state.FeesPaid += fee
+
+	pos.Quantity -= quantity
+	if pos.Quantity <= 1e-12 {
+		delete(t.state.Positions, key)
+	}
+	return pnl - fee, fee
+}
+
+// save 持久化模拟账户状态（调用方需持有锁）
+func (t *PaperTrader) save() {
+	if t.stateFile == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(t.stateFile), 0755); err != nil {
+		log.Printf("  ⚠ 创建模拟盘状态目录失败: %v", err)
+		return
+	}
+	data, err := json.MarshalIndent(t.state, "", "  ")
+	if err != nil {
+		log.Printf("  ⚠ 序列化模拟盘状态失败: %v", err)
+		return
+	}
+	if err := os.WriteFile(t.stateFile, data, 0644); err != nil {
+		log.Printf("  ⚠ 保存模拟盘状态失败: %v", err)
+	}
+}
+
+// accountTotals 计算未实现盈亏和占用保证金（调用方需持有锁）
+func (t *PaperTrader) accountTotals() (unrealized, margin float64) {
+	for _, pos := range t.state.Positions {
+		margin += pos.margin()
+		if price, err := t.fetchPrice(pos.Symbol); err == nil {
+			unrealized += pos.unrealizedPnL(price.markPrice)
+		}
+	}
+	return unrealized, margin
+}
+
+// GetAccount 获取模拟账户余额
+func (t *PaperTrader) GetAccount() (*Account, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.settle()
+	unrealized, margin := t.accountTotals()
+	available := t.state.WalletBalance + math.Min(unrealized, 0) - margin
+	if available < 0 {
+		available = 0
+	}
+
+	return &Account{
+		WalletBalance:    t.state.WalletBalance,
+		AvailableBalance: available,
+		UnrealizedProfit: unrealized,
+	}, nil
+}
+
+// GetPositions 获取模拟持仓
+func (t *PaperTrader) GetPositions() ([]Position, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.settle()
+
+	var result []Position
+	for _, pos := range t.state.Positions {
+		price, err := t.fetchPrice(pos.Symbol)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, Position{
+			Symbol:           pos.Symbol,
+			Side:             pos.Side,
+			Quantity:         pos.Quantity,
+			EntryPrice:       pos.EntryPrice,
+			MarkPrice:        price.markPrice,
+			UnrealizedProfit: pos.unrealizedPnL(price.markPrice),
+			Leverage:         float64(pos.Leverage),
+			LiquidationPrice: pos.liquidationPrice(),
+			MarginMode:       "isolated",
+		})
+	}
+	return result, nil
+}
+
+// open 模拟开仓（同方向已有持仓时按均价合并）
+func (t *PaperTrader) open(symbol, side string, quantity float64, leverage int) (*Order, error) {
+	if quantity <= 0 {
+		return nil, fmt.Errorf("开仓数量必须大于0")
+	}
+	if leverage <= 0 {
+		leverage = 1
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.settle()
+
+	price, err := t.fetchPrice(symbol)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.fill(symbol, side, quantity, leverage, price.markPrice, paperTakerFeeRate, price.nextFundingTime); err != nil {
+		return nil, err
+	}
+
+	orderID := t.state.NextOrderID
+	t.state.NextOrderID++
+	t.save()
+
+	log.Printf("✓ [模拟盘] 开%s成功: %s 数量: %.6f 价格: %.4f", map[string]string{"long": "多", "short": "空"}[side], symbol, quantity, price.markPrice)
+	result := paperOrderResult(orderID, symbol, OrderStatusFilled)
+	result.Fee = quantity * price.markPrice * paperTakerFeeRate
+	result.AvgPrice = price.markPrice
+	return result, nil
+}
+
+// fill 按成交价建仓或加仓并扣除手续费（调用方需持有锁）
+func (t *PaperTrader) fill(symbol, side string, quantity float64, leverage int, fillPrice, feeRate float64, nextFundingTime time.Time) error {
+	// 检查保证金是否充足
+	unrealized, margin := t.accountTotals()
+	available := t.state.WalletBalance + math.Min(unrealized, 0) - margin
+	required := quantity*fillPrice/float64(leverage) + quantity*fillPrice*feeRate
+	if required > available {
+		return fmt.Errorf("模拟盘可用余额不足: 需要 %.2f USDT, 可用 %.2f USDT", required, available)
+	}
+
+	key := symbol + "_" + side
+	pos, exists := t.state.Positions[key]
+	if exists {
+		totalQty := pos.Quantity + quantity
+		pos.EntryPrice = (pos.EntryPrice*pos.Quantity + fillPrice*quantity) / totalQty
+		pos.Quantity = totalQty
+		pos.Leverage = leverage
+	} else {
+		t.state.Positions[key] = &paperPosition{
+			Symbol:          symbol,
+			Side:            side,
+			Quantity:        quantity,
+			EntryPrice:      fillPrice,
+			Leverage:        leverage,
+			OpenTime:        time.Now(),
+			NextFundingTime: nextFundingTime,
+		}
+	}
+	t.state.Leverage[symbol] = leverage
+
+	fee := quantity * fillPrice * feeRate
+	t.state.WalletBalance -= fee
+	t.state.FeesPaid += fee
+	return nil
+}
+
+// matchOrders 撮合限价单：标记价格触及挂单价时成交，并清理过期的已结束订单（调用方需持有锁），返回状态是否变化
+func (t *PaperTrader) matchOrders() bool {
+	changed := false
+	for id, order := range t.state.Orders {
+		if order.Status != OrderStatusNew {
+			if time.Since(order.UpdatedAt) > paperOrderRetention {
+				delete(t.state.Orders, id)
+				changed = true
+			}
+			continue
+		}
+
+		price, err := t.fetchPrice(order.Symbol)
+		if err != nil {
+			continue
+		}
+		touched := (order.Side == "long" && price.markPrice <= order.Price) ||
+			(order.Side == "short" && price.markPrice >= order.Price)
+		if !touched {
+			continue
+		}
+
+		order.UpdatedAt = time.Now()
+		if err := t.fill(order.Symbol, order.Side, order.Quantity, order.Leverage, order.Price, paperMakerFeeRate, price.nextFundingTime); err != nil {
+			order.Status = OrderStatusCanceled
+			log.Printf("  ⚠ 模拟盘限价单 #%d 成交失败，已撤销: %v", id, err)
+		} else {
+			order.Status = OrderStatusFilled
+			log.Printf("✓ [模拟盘] 限价单 #%d 成交: %s %s 数量: %.6f 价格: %.4f", id, order.Symbol, order.Side, order.Quantity, order.Price)
+		}
+		changed = true
+	}
+	return changed
+}
+
+// paperOrderResult 模拟盘下单结果
+func paperOrderResult(orderID int64, symbol, status string) *Order {
+	return &Order{OrderID: strconv.FormatInt(orderID, 10), Symbol: symbol, Status: status}
+}
+
+// openLimit 模拟限价开仓（挂单价已穿过市价时：postOnly拒绝，普通限价单按市价立即成交）
+func (t *PaperTrader) openLimit(symbol, positionSide string, quantity, price float64, leverage int, postOnly bool) (*Order, error) {
+	if quantity <= 0 || price <= 0 {
+		return nil, fmt.Errorf("挂单数量和价格必须大于0")
+	}
+	if leverage <= 0 {
+		leverage = 1
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.settle()
+
+	mark, err := t.fetchPrice(symbol)
+	if err != nil {
+		return nil, err
+	}
+	crossed := (positionSide == "long" && price >= mark.markPrice) || (positionSide == "short" && price <= mark.markPrice)
+	if crossed && postOnly {
+		return nil, fmt.Errorf("post_only订单会立即成交，已拒绝（挂单价 %.4f，标记价格 %.4f）", price, mark.markPrice)
+	}
+
+	orderID := t.state.NextOrderID
+	t.state.NextOrderID++
+	order := &paperOrder{
+		ID:        orderID,
+		Symbol:    symbol,
+		Side:      positionSide,
+		Quantity:  quantity,
+		Price:     price,
+		Leverage:  leverage,
+		Status:    OrderStatusNew,
+		UpdatedAt: time.Now(),
+	}
+	if crossed {
+		if err := t.fill(symbol, positionSide, quantity, leverage, mark.markPrice, paperTakerFeeRate, mark.nextFundingTime); err != nil {
+			return nil, err
+		}
+		order.Status = OrderStatusFilled
+		order.Price = mark.markPrice
+	}
+	t.state.Orders[orderID] = order
+	t.save()
+
+	log.Printf("✓ [模拟盘] 限价单 #%d 已提交: %s %s 数量: %.6f 价格: %.4f (%s)", orderID, symbol, positionSide, quantity, price, order.Status)
+	return paperOrderResult(orderID, symbol, order.Status), nil
+}
+
+// GetOrder 查询模拟限价单状态
+func (t *PaperTrader) GetOrder(symbol, orderID string) (*OrderStatus, error) {
+	id, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.settle()
+
+	order, exists := t.state.Orders[id]
+	if !exists || order.Symbol != symbol {
+		return nil, fmt.Errorf("未找到订单: %s", orderID)
+	}
+	status := &OrderStatus{Status: order.Status}
+	if order.Status == OrderStatusFilled {
+		status.FilledQty = order.Quantity
+		status.AvgPrice = order.Price
+	}
+	return status, nil
+}
+
+// CancelOrder 撤销模拟限价单
+func (t *PaperTrader) CancelOrder(symbol, orderID string) error {
+	id, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	order, exists := t.state.Orders[id]
+	if !exists || order.Symbol != symbol {
+		return fmt.Errorf("未找到订单: %s", orderID)
+	}
+	if order.Status == OrderStatusNew {
+		order.Status = OrderStatusCanceled
+		order.UpdatedAt = time.Now()
+		t.save()
+	}
+	return nil
+}
+
+// ClosePosition 模拟平仓（quantity=0表示全部平仓）
+func (t *PaperTrader) ClosePosition(symbol, side string, quantity float64) (*Order, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.settle()
+
+	key := symbol + "_" + side
+	pos, exists := t.state.Positions[key]
+	if !exists {
+		return nil, fmt.Errorf("没有找到 %s 的%s仓", symbol, map[string]string{"long": "多", "short": "空"}[side])
+	}
+	if quantity <= 0 {
+		quantity = pos.Quantity
+	}
+
+	price, err := t.fetchPrice(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	pnl, fee := t.realize(key, quantity, price.markPrice)
+
+	// 与真实交易所一致：平仓后取消该币种的止盈止损
+	if p, ok := t.state.Positions[key]; ok {
+		p.StopLoss = 0
+		p.TakeProfit = 0
+	}
+
+	orderID := t.state.NextOrderID
+	t.state.NextOrderID++
+	t.save()
+
+	log.Printf("✓ [模拟盘] 平%s成功: %s 数量: %.6f 价格: %.4f 盈亏: %.2f USDT", map[string]string{"long": "多", "short": "空"}[side], symbol, quantity, price.markPrice, pnl)
+	result := paperOrderResult(orderID, symbol, OrderStatusFilled)
+	result.Fee = fee
+	result.AvgPrice = price.markPrice
+	return result, nil
+}
+
+// GetFundingFees 持仓累计的资金费（模拟持仓从开仓起累计，since不影响结果）
+func (t *PaperTrader) GetFundingFees(symbol, side string, since time.Time) (float64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.settle()
+	if pos, ok := t.state.Positions[symbol+"_"+side]; ok {
+		return pos.FundingPaid, nil
+	}
+	return 0, nil
+}
+
+// PlaceOrder 模拟下单
+func (t *PaperTrader) PlaceOrder(req OrderRequest) (*Order, error) {
+	switch req.Type {
+	case OrderTypeMarket:
+		return t.rememberClientOrder(req.ClientOrderID)(t.open(req.Symbol, req.Side, req.Quantity, req.Leverage))
+	case OrderTypeLimit, OrderTypePostOnly:
+		return t.rememberClientOrder(req.ClientOrderID)(t.openLimit(req.Symbol, req.Side, req.Quantity, req.Price, req.Leverage, req.Type == OrderTypePostOnly))
+	case OrderTypeStopLoss:
+		return protectiveOrder(req, t.setStopLoss(req.Symbol, req.Side, req.Price))
+	case OrderTypeTakeProfit:
+		return protectiveOrder(req, t.setTakeProfit(req.Symbol, req.Side, req.Price))
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedOrderType, req.Type)
+}
+
+// rememberClientOrder 记录开仓单的客户端订单ID（供FindClientOrder查询）
+func (t *PaperTrader) rememberClientOrder(clientOrderID string) func(*Order, error) (*Order, error) {
+	return func(order *Order, err error) (*Order, error) {
+		if err == nil && clientOrderID != "" {
+			t.mu.Lock()
+			t.clientOrders[clientOrderID] = order
+			t.mu.Unlock()
+		}
+		return order, err
+	}
+}
+
+// FindClientOrder 按客户端订单ID查询开仓单（限价单返回当前状态）
+func (t *PaperTrader) FindClientOrder(symbol, clientOrderID string) (*Order, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	order, ok := t.clientOrders[clientOrderID]
+	if !ok || order.Symbol != symbol {
+		return nil, ErrOrderNotFound
+	}
+	result := *order
+	if id, err := strconv.ParseInt(order.OrderID, 10, 64); err == nil {
+		if po, ok := t.state.Orders[id]; ok {
+			result.Status = po.Status
+		}
+	}
+	return &result, nil
+}
+
+// SetLeverage 设置杠杆（仅记录，开仓时生效）
+func (t *PaperTrader) SetLeverage(symbol string, leverage int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state.Leverage[symbol] = leverage
+	return nil
+}
+
+// GetMarketPrice 获取标记价格
+func (t *PaperTrader) GetMarketPrice(symbol string) (float64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	price, err := t.fetchPrice(symbol)
+	if err != nil {
+		return 0, err
+	}
+	return price.markPrice, nil
+}
+
+// setTrigger 为持仓设置止损/止盈触发价
+func (t *PaperTrader) setTrigger(symbol, positionSide string, price float64, isStopLoss bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pos, exists := t.state.Positions[symbol+"_"+positionSide]
+	if !exists {
+		return fmt.Errorf("没有找到 %s 的%s持仓", symbol, positionSide)
+	}
+	if isStopLoss {
+		pos.StopLoss = price
+	} else {
+		pos.TakeProfit = price
+	}
+	t.save()
+	return nil
+}
+
+// setStopLoss 设置止损单（模拟盘按整仓止损处理）
+func (t *PaperTrader) setStopLoss(symbol string, positionSide string, stopPrice float64) error {
+	if err := t.setTrigger(symbol, positionSide, stopPrice, true); err != nil {
+		return err
+	}
+	log.Printf("  [模拟盘] 止损价设置: %.4f", stopPrice)
+	return nil
+}
+
+// setTakeProfit 设置止盈单（模拟盘按整仓止盈处理）
+func (t *PaperTrader) setTakeProfit(symbol string, positionSide string, takeProfitPrice float64) error {
+	if err := t.setTrigger(symbol, positionSide, takeProfitPrice, false); err != nil {
+		return err
+	}
+	log.Printf("  [模拟盘] 止盈价设置: %.4f", takeProfitPrice)
+	return nil
+}
+
+// CancelAllOrders 取消该币种的止盈止损和未成交的限价单
+func (t *PaperTrader) CancelAllOrders(symbol string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, pos := range t.state.Positions {
+		if pos.Symbol == symbol {
+			pos.StopLoss = 0
+			pos.TakeProfit = 0
+		}
+	}
+	for _, order := range t.state.Orders {
+		if order.Symbol == symbol && order.Status == OrderStatusNew {
+			order.Status = OrderStatusCanceled
+			order.UpdatedAt = time.Now()
+		}
+	}
+	t.save()
+	return nil
+}
+
+// ResetAccount 重置模拟账户：清空持仓、挂单和累计盈亏，钱包余额恢复为balance（杠杆设置保留）
+func (t *PaperTrader) ResetAccount(balance float64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.state.WalletBalance = balance
+	t.state.RealizedPnL = 0
+	t.state.FeesPaid = 0
+	t.state.Positions = make(map[string]*paperPosition)
+	t.state.Orders = make(map[int64]*paperOrder)
+	t.save()
+	log.Printf("📄 模拟盘账户已重置: 余额 %.2f USDT", balance)
+	return nil
+}
+
+// GetSymbolFilters 模拟盘不受交易所下单规则限制
+func (t *PaperTrader) GetSymbolFilters(symbol string) (*SymbolFilters, error) {
+	return nil, nil
+}
+
+// FormatQuantity 格式化数量（模拟盘不受交易所步长限制，保留6位小数）
+func (t *PaperTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	return strconv.FormatFloat(quantity, 'f', 6, 64), nil
+}
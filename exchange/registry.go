@@ -0,0 +1,139 @@
+package exchange
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Config 交易所连接配置：trader配置中的各项（按配置文件中的键名），各交易所用Decode读取自己的字段
+type Config struct {
+	TraderID       string                     // 模拟盘按trader保存状态文件
+	InitialBalance float64                    // 模拟盘初始资金
+	Settings       map[string]json.RawMessage // trader配置项（键名与配置文件一致）
+}
+
+// Decode 把配置项解码到交易所自己的配置结构（按json标签读取，缺少的字段保持零值）
+func (c Config) Decode(v any) error {
+	data, err := json.Marshal(c.Settings)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("交易所配置格式错误: %w", err)
+	}
+	return nil
+}
+
+// decodeConfig 解码交易所配置（配置在Validate时已校验过格式，New/AccountKey中解码失败时返回零值）
+func decodeConfig[T any](cfg Config) T {
+	var v T
+	_ = cfg.Decode(&v)
+	return v
+}
+
+// Driver 交易所注册信息（每个交易所在自己的文件里通过init注册，配置字段、校验和账户标识都由交易所自己定义）
+type Driver struct {
+	Name        string // 配置中的exchange取值
+	Description string // 日志中显示的名称
+	New         func(cfg Config) (Exchange, error)
+	Validate    func(cfg Config) error  // 校验密钥等必填配置（为nil表示不需要配置）
+	AccountKey  func(cfg Config) string // 交易所账户标识（同一账户的trader共用组合风控，不能包含私钥、助记词等密钥；为nil表示每个trader独立账户）
+	TestnetKey  string                  // 测试网开关的配置项（不支持测试网时为空）
+	Spot        bool                    // 现货（只做多，无杠杆）
+}
+
+var drivers = make(map[string]Driver)
+
+// Register 注册交易所（重复注册视为编程错误）
+func Register(d Driver) {
+	if _, exists := drivers[d.Name]; exists {
+		panic(fmt.Sprintf("交易所 %s 重复注册", d.Name))
+	}
+	drivers[d.Name] = d
+}
+
+// Lookup 查找已注册的交易所
+func Lookup(name string) (Driver, bool) {
+	d, ok := drivers[name]
+	return d, ok
+}
+
+// Names 已注册的交易所名称（排序）
+func Names() []string {
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New 创建交易所客户端
+func New(name string, cfg Config) (Exchange, error) {
+	d, ok := drivers[name]
+	if !ok {
+		return nil, fmt.Errorf("不支持的交易平台: %s", name)
+	}
+	ex, err := d.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("初始化%s交易器失败: %w", d.Description, err)
+	}
+	return ex, nil
+}
+
+// Validate 校验交易所配置
+func Validate(name string, cfg Config) error {
+	d, ok := drivers[name]
+	if !ok {
+		return fmt.Errorf("exchange必须是以下之一: %s", strings.Join(Names(), ", "))
+	}
+	if d.Validate == nil {
+		return nil
+	}
+	return d.Validate(cfg)
+}
+
+// AccountKey 交易所账户标识（交易所未定义时按trader区分，即每个trader独立账户）
+func AccountKey(name string, cfg Config) string {
+	if d, ok := drivers[name]; ok && d.AccountKey != nil {
+		return name + ":" + d.AccountKey(cfg)
+	}
+	return name + ":trader:" + cfg.TraderID
+}
+
+// SupportsTestnet 交易所是否支持切换测试网
+func SupportsTestnet(name string) bool {
+	d, ok := drivers[name]
+	return ok && d.TestnetKey != ""
+}
+
+// IsSpot 是否为现货交易所
+func IsSpot(name string) bool {
+	return drivers[name].Spot
+}
+
+// Testnet 配置是否使用测试网
+func Testnet(name string, cfg Config) bool {
+	if !SupportsTestnet(name) {
+		return false
+	}
+	var testnet bool
+	_ = json.Unmarshal(cfg.Settings[drivers[name].TestnetKey], &testnet)
+	return testnet
+}
+
+// SetTestnet 设置测试网开关（交易所不支持测试网时忽略）
+func SetTestnet(name string, cfg *Config, testnet bool) {
+	if !SupportsTestnet(name) {
+		return
+	}
+	// 复制后修改，不影响共用同一配置的其他副本
+	settings := make(map[string]json.RawMessage, len(cfg.Settings)+1)
+	for key, value := range cfg.Settings {
+		settings[key] = value
+	}
+	settings[drivers[name].TestnetKey], _ = json.Marshal(testnet)
+	cfg.Settings = settings
+}
@@ -0,0 +1,104 @@
+package exchange
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// testConfig 按配置文件键名构造交易所配置
+func testConfig(t *testing.T, traderID, settings string) Config {
+	t.Helper()
+	cfg := Config{TraderID: traderID}
+	if err := json.Unmarshal([]byte(settings), &cfg.Settings); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestDriverValidate(t *testing.T) {
+	tests := []struct {
+		exchange string
+		settings string
+		wantErr  string
+	}{
+		{"binance", `{"binance_api_key":"k","binance_secret_key":"s"}`, ""},
+		{"binance", `{"binance_api_key":"k"}`, "binance_secret_key"},
+		{"binance_spot", `{}`, "binance_api_key"},
+		{"hyperliquid", `{"hyperliquid_private_key":"p"}`, ""},
+		{"hyperliquid", `{"hyperliquid_wallet_addr":"0xabc"}`, "hyperliquid_private_key"},
+		{"aster", `{"aster_user":"u","aster_signer":"s"}`, "aster_private_key"},
+		{"okx", `{"okx_api_key":"k","okx_secret_key":"s"}`, "okx_passphrase"},
+		{"bybit", `{"bybit_api_key":"k","bybit_secret_key":"s"}`, ""},
+		{"dydx", `{}`, "dydx_mnemonic"},
+		{"dydx", `{"dydx_mnemonic":"m","dydx_subaccount":-1}`, "dydx_subaccount"},
+		{"paper", `{}`, ""},
+		{"nosuch", `{}`, "exchange必须是以下之一"},
+	}
+	for _, tt := range tests {
+		err := Validate(tt.exchange, testConfig(t, "t1", tt.settings))
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s %s: unexpected error %v", tt.exchange, tt.settings, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s %s: err = %v, want containing %q", tt.exchange, tt.settings, err, tt.wantErr)
+		}
+	}
+}
+
+func TestAccountKey(t *testing.T) {
+	futures := AccountKey("binance", testConfig(t, "a", `{"binance_api_key":"k1","binance_secret_key":"s"}`))
+	sameKey := AccountKey("binance", testConfig(t, "b", `{"binance_api_key":"k1","binance_secret_key":"s"}`))
+	spot := AccountKey("binance_spot", testConfig(t, "a", `{"binance_api_key":"k1","binance_secret_key":"s"}`))
+	otherKey := AccountKey("binance", testConfig(t, "a", `{"binance_api_key":"k2","binance_secret_key":"s"}`))
+	if futures != sameKey {
+		t.Errorf("traders sharing an API key got different accounts: %s vs %s", futures, sameKey)
+	}
+	if futures == spot || futures == otherKey {
+		t.Errorf("distinct accounts share a key: futures=%s spot=%s other=%s", futures, spot, otherKey)
+	}
+	if AccountKey("paper", testConfig(t, "a", `{}`)) == AccountKey("paper", testConfig(t, "b", `{}`)) {
+		t.Errorf("paper traders must not share an account")
+	}
+}
+
+func TestSetTestnet(t *testing.T) {
+	cfg := testConfig(t, "a", `{"okx_api_key":"k","okx_testnet":false}`)
+	original := cfg
+	SetTestnet("okx", &cfg, true)
+	if !Testnet("okx", cfg) {
+		t.Errorf("testnet not enabled")
+	}
+	if Testnet("okx", original) {
+		t.Errorf("SetTestnet modified the settings of a copy")
+	}
+	if got := decodeConfig[okxConfig](cfg); got.APIKey != "k" || !got.Testnet {
+		t.Errorf("decoded config = %+v", got)
+	}
+
+	binance := testConfig(t, "a", `{"binance_api_key":"k"}`)
+	SetTestnet("binance", &binance, true)
+	if SupportsTestnet("binance") || Testnet("binance", binance) {
+		t.Errorf("binance has no testnet switch")
+	}
+}
+
+func TestDydxAccountKeyHidesMnemonic(t *testing.T) {
+	const mnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	key := AccountKey("dydx", testConfig(t, "a", `{"dydx_mnemonic":"`+mnemonic+`","dydx_subaccount":0}`))
+	if strings.Contains(key, "abandon") {
+		t.Fatalf("account key contains the mnemonic: %s", key)
+	}
+	if !strings.HasPrefix(key, "dydx:dydx1") {
+		t.Errorf("account key should be the dYdX address, got %s", key)
+	}
+	if other := AccountKey("dydx", testConfig(t, "b", `{"dydx_mnemonic":"`+mnemonic+`","dydx_subaccount":0}`)); other != key {
+		t.Errorf("same wallet got different accounts: %s vs %s", key, other)
+	}
+	if sub := AccountKey("dydx", testConfig(t, "a", `{"dydx_mnemonic":"`+mnemonic+`","dydx_subaccount":1}`)); sub == key {
+		t.Errorf("subaccounts must be separate accounts")
+	}
+	if invalid := AccountKey("dydx", testConfig(t, "a", `{"dydx_mnemonic":"not a valid phrase"}`)); strings.Contains(invalid, "valid phrase") {
+		t.Errorf("account key contains the mnemonic: %s", invalid)
+	}
+}
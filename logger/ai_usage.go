@@ -0,0 +1,77 @@
+package logger
+
+import "fmt"
+
+// AIUsage 某天某个模型的AI调用用量
+type AIUsage struct {
+	Day              string  `json:"day"` // 本地日期 YYYY-MM-DD
+	Provider         string  `json:"provider"`
+	Model            string  `json:"model"`
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"` // 按价格表估算的成本
+}
+
+// RecordAIUsage 累加一次AI调用的用量（按trader、日期、提供商、模型汇总）
+func RecordAIUsage(traderID string, usage AIUsage) error {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(`INSERT INTO ai_usage (trader_id, day, provider, model, calls, prompt_tokens, completion_tokens, cost_usd)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(trader_id, day, provider, model) DO UPDATE SET
+			calls = calls + excluded.calls,
+			prompt_tokens = prompt_tokens + excluded.prompt_tokens,
+			completion_tokens = completion_tokens + excluded.completion_tokens,
+			cost_usd = cost_usd + excluded.cost_usd`,
+		traderID, usage.Day, usage.Provider, usage.Model, usage.Calls,
+		usage.PromptTokens, usage.CompletionTokens, usage.CostUSD); err != nil {
+		return fmt.Errorf("写入AI用量失败: %w", err)
+	}
+	return nil
+}
+
+// GetAIUsage 获取trader自sinceDay（含）以来的AI用量（按日期、提供商、模型排序；空字符串表示不限制）
+func GetAIUsage(traderID, sinceDay string) ([]AIUsage, error) {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT day, provider, model, calls, prompt_tokens, completion_tokens, cost_usd
+		FROM ai_usage WHERE trader_id = ? AND day >= ? ORDER BY day, provider, model`, traderID, sinceDay)
+	if err != nil {
+		return nil, fmt.Errorf("查询AI用量失败: %w", err)
+	}
+	defer rows.Close()
+
+	var usages []AIUsage
+	for rows.Next() {
+		var u AIUsage
+		if err := rows.Scan(&u.Day, &u.Provider, &u.Model, &u.Calls, &u.PromptTokens, &u.CompletionTokens, &u.CostUSD); err != nil {
+			return nil, fmt.Errorf("读取AI用量失败: %w", err)
+		}
+		usages = append(usages, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取AI用量失败: %w", err)
+	}
+	return usages, nil
+}
+
+// GetAIDailyCost 获取trader某天的AI估算总成本（美元）
+func GetAIDailyCost(traderID, day string) (float64, error) {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return 0, err
+	}
+
+	var cost float64
+	if err := db.QueryRow(`SELECT COALESCE(SUM(cost_usd), 0) FROM ai_usage WHERE trader_id = ? AND day = ?`, traderID, day).Scan(&cost); err != nil {
+		return 0, fmt.Errorf("查询AI成本失败: %w", err)
+	}
+	return cost, nil
+}
@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AuditEntry 一次控制面操作（启停trader、修改配置、管理用户等）的审计记录
+type AuditEntry struct {
+	ID       int64           `json:"id"`
+	Time     time.Time       `json:"time"`
+	UserID   string          `json:"user_id"`
+	Username string          `json:"username"`
+	APIKeyID string          `json:"api_key_id,omitempty"` // 通过API Key操作时的Key ID
+	IP       string          `json:"ip"`
+	Action   string          `json:"action"`  // 方法和路由，如 PUT /api/traders/:id/risk
+	Target   string          `json:"target"`  // 操作对象（trader、用户、API Key的ID或模板名称），没有时为空
+	Changes  json.RawMessage `json:"changes"` // 提交的内容（已隐藏密钥类字段），没有请求体时为null
+	Status   int             `json:"status"`  // HTTP状态码
+	Error    string          `json:"error,omitempty"`
+}
+
+// AuditFilter 审计记录查询条件（零值表示不限制）
+type AuditFilter struct {
+	RecordFilter
+	Username string
+	Target   string
+	Action   string // 按包含匹配，如 "risk"
+}
+
+// RecordAudit 写入一条审计记录
+func RecordAudit(e AuditEntry) error {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return err
+	}
+
+	changes := "null"
+	if len(e.Changes) > 0 {
+		changes = string(e.Changes)
+	}
+	if _, err := db.Exec(`INSERT INTO audit_log (timestamp, user_id, username, api_key_id, ip, action, target, changes, status, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.Time.UnixMilli(), e.UserID, e.Username, e.APIKeyID, e.IP, e.Action, e.Target, changes, e.Status, e.Error); err != nil {
+		return fmt.Errorf("写入审计记录失败: %w", err)
+	}
+	return nil
+}
+
+// QueryAudit 分页查询审计记录（按时间倒序，最新的在前），同时返回满足条件的总条数
+func QueryAudit(f AuditFilter) ([]AuditEntry, int, error) {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	where, args := "1 = 1", []interface{}{}
+	if !f.From.IsZero() {
+		where += " AND timestamp >= ?"
+		args = append(args, f.From.UnixMilli())
+	}
+	if !f.To.IsZero() {
+		where += " AND timestamp <= ?"
+		args = append(args, f.To.UnixMilli())
+	}
+	if f.Username != "" {
+		where += " AND username = ?"
+		args = append(args, f.Username)
+	}
+	if f.Target != "" {
+		where += " AND target = ?"
+		args = append(args, f.Target)
+	}
+	if f.Action != "" {
+		where += " AND action LIKE ?"
+		args = append(args, "%"+f.Action+"%")
+	}
+
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM audit_log WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("查询审计记录失败: %w", err)
+	}
+
+	page, pageArgs := f.page()
+	rows, err := db.Query(`SELECT id, timestamp, user_id, username, api_key_id, ip, action, target, changes, status, error
+		FROM audit_log WHERE `+where+` ORDER BY timestamp DESC, id DESC`+page, append(args, pageArgs...)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询审计记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		var ts int64
+		var changes string
+		if err := rows.Scan(&e.ID, &ts, &e.UserID, &e.Username, &e.APIKeyID, &e.IP, &e.Action, &e.Target, &changes, &e.Status, &e.Error); err != nil {
+			return nil, 0, fmt.Errorf("读取审计记录失败: %w", err)
+		}
+		e.Time = time.UnixMilli(ts)
+		e.Changes = json.RawMessage(changes)
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("读取审计记录失败: %w", err)
+	}
+	return entries, total, nil
+}
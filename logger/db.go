@@ -0,0 +1,313 @@
+package logger
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// defaultDBPath 决策日志数据库默认路径（位于decision_logs目录下，沿用原有的数据卷挂载）
+var defaultDBPath = filepath.Join("decision_logs", "nofx.db")
+
+var (
+	dbs   = make(map[string]*sql.DB) // 路径 -> 连接（同一个文件只打开一次，多个trader共享）
+	dbsMu sync.Mutex
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS decisions (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	trader_id    TEXT    NOT NULL,
+	timestamp    INTEGER NOT NULL, -- Unix毫秒
+	cycle_number INTEGER NOT NULL,
+	success      INTEGER NOT NULL,
+	record       TEXT    NOT NULL  -- 完整的DecisionRecord JSON
+);
+CREATE INDEX IF NOT EXISTS idx_decisions_trader_time ON decisions(trader_id, timestamp);
+
+CREATE TABLE IF NOT EXISTS fills (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	decision_id INTEGER NOT NULL REFERENCES decisions(id) ON DELETE CASCADE,
+	trader_id   TEXT    NOT NULL,
+	timestamp   INTEGER NOT NULL,
+	symbol      TEXT    NOT NULL,
+	action      TEXT    NOT NULL,
+	quantity    REAL    NOT NULL,
+	price       REAL    NOT NULL,
+	leverage    INTEGER NOT NULL,
+	order_id    INTEGER NOT NULL,
+	success     INTEGER NOT NULL,
+	error       TEXT    NOT NULL,
+	fee         REAL    NOT NULL DEFAULT 0,
+	funding     REAL    NOT NULL DEFAULT 0,
+	decision_price REAL NOT NULL DEFAULT 0,
+	slippage_bps   REAL NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_fills_trader_time ON fills(trader_id, timestamp);
+
+CREATE TABLE IF NOT EXISTS equity_snapshots (
+	decision_id       INTEGER PRIMARY KEY REFERENCES decisions(id) ON DELETE CASCADE,
+	trader_id         TEXT    NOT NULL,
+	timestamp         INTEGER NOT NULL,
+	cycle_number      INTEGER NOT NULL,
+	total_equity      REAL    NOT NULL,
+	available_balance REAL    NOT NULL,
+	total_pnl         REAL    NOT NULL,
+	position_count    INTEGER NOT NULL,
+	margin_used_pct   REAL    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_equity_trader_time ON equity_snapshots(trader_id, timestamp);
+
+CREATE TABLE IF NOT EXISTS decision_contexts (
+	decision_id  INTEGER PRIMARY KEY REFERENCES decisions(id) ON DELETE CASCADE,
+	trader_id    TEXT    NOT NULL,
+	cycle_number INTEGER NOT NULL,
+	context      TEXT    NOT NULL  -- 决策时完整交易上下文的JSON（decision.ContextSnapshot）
+);
+CREATE INDEX IF NOT EXISTS idx_decision_contexts_cycle ON decision_contexts(trader_id, cycle_number);
+
+CREATE TABLE IF NOT EXISTS leaderboard_snapshots (
+	id               INTEGER PRIMARY KEY AUTOINCREMENT,
+	timestamp        INTEGER NOT NULL,
+	trader_id        TEXT    NOT NULL,
+	trader_name      TEXT    NOT NULL,
+	ai_model         TEXT    NOT NULL,
+	total_equity     REAL    NOT NULL,
+	total_pnl_pct    REAL    NOT NULL,
+	sharpe_ratio     REAL    NOT NULL,
+	max_drawdown_pct REAL    NOT NULL,
+	win_rate         REAL    NOT NULL,
+	total_trades     INTEGER NOT NULL,
+	benchmark_pnl_pct REAL   NOT NULL DEFAULT 0,
+	alpha_pct        REAL    NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_leaderboard_time ON leaderboard_snapshots(timestamp);
+
+CREATE TABLE IF NOT EXISTS trader_settings (
+	trader_id  TEXT    NOT NULL,
+	key        TEXT    NOT NULL,
+	value      TEXT    NOT NULL, -- JSON
+	updated_at INTEGER NOT NULL,
+	PRIMARY KEY (trader_id, key)
+);
+
+CREATE TABLE IF NOT EXISTS ai_usage (
+	trader_id         TEXT    NOT NULL,
+	day               TEXT    NOT NULL, -- 本地日期 YYYY-MM-DD
+	provider          TEXT    NOT NULL,
+	model             TEXT    NOT NULL,
+	calls             INTEGER NOT NULL,
+	prompt_tokens     INTEGER NOT NULL,
+	completion_tokens INTEGER NOT NULL,
+	cost_usd          REAL    NOT NULL,
+	PRIMARY KEY (trader_id, day, provider, model)
+);
+
+CREATE TABLE IF NOT EXISTS trades (
+	id              INTEGER PRIMARY KEY AUTOINCREMENT,
+	trader_id       TEXT    NOT NULL,
+	symbol          TEXT    NOT NULL,
+	side            TEXT    NOT NULL,
+	status          TEXT    NOT NULL, -- open / closed
+	timestamp       INTEGER NOT NULL, -- 开仓时间（Unix毫秒）
+	open_price      REAL    NOT NULL,
+	quantity        REAL    NOT NULL, -- 累计开仓数量（含加仓）
+	open_quantity   REAL    NOT NULL, -- 尚未平仓的数量
+	leverage        INTEGER NOT NULL,
+	ai_model        TEXT    NOT NULL DEFAULT '',
+	regime          TEXT    NOT NULL DEFAULT '', -- 开仓时的市场状态
+	confidence      INTEGER NOT NULL,
+	reasoning       TEXT    NOT NULL,
+	stop_loss       REAL    NOT NULL,
+	take_profit     REAL    NOT NULL,
+	close_time      INTEGER NOT NULL DEFAULT 0,
+	close_price     REAL    NOT NULL DEFAULT 0, -- 平仓均价（部分平仓按数量加权）
+	close_reason    TEXT    NOT NULL DEFAULT '',
+	close_reasoning TEXT    NOT NULL DEFAULT '',
+	gross_pnl       REAL    NOT NULL DEFAULT 0,
+	fees            REAL    NOT NULL DEFAULT 0,
+	funding         REAL    NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_trades_trader_time ON trades(trader_id, timestamp);
+
+CREATE TABLE IF NOT EXISTS lessons (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	trader_id    TEXT    NOT NULL,
+	trade_id     INTEGER NOT NULL UNIQUE,
+	timestamp    INTEGER NOT NULL, -- 平仓时间（Unix毫秒）
+	symbol       TEXT    NOT NULL,
+	side         TEXT    NOT NULL,
+	regime       TEXT    NOT NULL, -- 开仓时的市场状态
+	ai_model     TEXT    NOT NULL,
+	confidence   INTEGER NOT NULL,
+	outcome      TEXT    NOT NULL, -- win / loss
+	pnl_pct      REAL    NOT NULL,
+	close_reason TEXT    NOT NULL,
+	summary      TEXT    NOT NULL, -- 自动生成的交易复盘
+	lesson       TEXT    NOT NULL  -- 从结果中得出的教训
+);
+CREATE INDEX IF NOT EXISTS idx_lessons_trader_symbol ON lessons(trader_id, symbol);
+
+CREATE TABLE IF NOT EXISTS setups (
+	trade_id     INTEGER PRIMARY KEY,
+	trader_id    TEXT    NOT NULL,
+	timestamp    INTEGER NOT NULL, -- 开仓时间（Unix毫秒）
+	symbol       TEXT    NOT NULL,
+	side         TEXT    NOT NULL,
+	vector       TEXT    NOT NULL, -- 开仓时的形态向量（JSON数组）
+	closed       INTEGER NOT NULL DEFAULT 0,
+	pnl_pct      REAL    NOT NULL DEFAULT 0, -- 全部平仓后的净盈亏（相对保证金）
+	close_reason TEXT    NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_setups_trader ON setups(trader_id, closed);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	timestamp  INTEGER NOT NULL,
+	user_id    TEXT    NOT NULL,
+	username   TEXT    NOT NULL,
+	api_key_id TEXT    NOT NULL,
+	ip         TEXT    NOT NULL,
+	action     TEXT    NOT NULL, -- 方法和路由，如 PUT /api/traders/:id/risk
+	target     TEXT    NOT NULL,
+	changes    TEXT    NOT NULL, -- 提交的JSON（已隐藏密钥）
+	status     INTEGER NOT NULL,
+	error      TEXT    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_audit_time ON audit_log(timestamp);
+
+CREATE TABLE IF NOT EXISTS saved_traders (
+	trader_id  TEXT    PRIMARY KEY,
+	config     TEXT    NOT NULL, -- TraderConfig JSON
+	created_at INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS trader_templates (
+	name       TEXT    PRIMARY KEY,
+	value      TEXT    NOT NULL, -- 模板JSON
+	updated_at INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS seasons (
+	number      INTEGER PRIMARY KEY,
+	name        TEXT    NOT NULL,
+	start_time  INTEGER NOT NULL,
+	end_time    INTEGER NOT NULL, -- 计划结束时间
+	ended_at    INTEGER NOT NULL, -- 实际结算时间
+	leaderboard TEXT    NOT NULL, -- 最终排行榜JSON
+	archive     TEXT    NOT NULL, -- 归档的数据库文件
+	errors      TEXT    NOT NULL  -- 结算失败明细JSON
+);
+
+CREATE TABLE IF NOT EXISTS model_ratings (
+	model      TEXT    PRIMARY KEY,
+	rating     REAL    NOT NULL,
+	periods    INTEGER NOT NULL,
+	wins       INTEGER NOT NULL,
+	losses     INTEGER NOT NULL,
+	draws      INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS rating_history (
+	id            INTEGER PRIMARY KEY AUTOINCREMENT,
+	timestamp     INTEGER NOT NULL,
+	period        TEXT    NOT NULL, -- 赛季名称或周
+	model         TEXT    NOT NULL,
+	traders       INTEGER NOT NULL,
+	score         REAL    NOT NULL, -- 风险调整后收益
+	rating_before REAL    NOT NULL,
+	rating_after  REAL    NOT NULL,
+	wins          INTEGER NOT NULL,
+	losses        INTEGER NOT NULL,
+	draws         INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_rating_history_time ON rating_history(timestamp);
+`
+
+// migrations 已有数据库的表结构升级（列已存在时跳过）
+var migrations = []string{
+	`ALTER TABLE leaderboard_snapshots ADD COLUMN benchmark_pnl_pct REAL NOT NULL DEFAULT 0`,
+	`ALTER TABLE leaderboard_snapshots ADD COLUMN alpha_pct REAL NOT NULL DEFAULT 0`,
+	`ALTER TABLE fills ADD COLUMN fee REAL NOT NULL DEFAULT 0`,
+	`ALTER TABLE fills ADD COLUMN funding REAL NOT NULL DEFAULT 0`,
+	`ALTER TABLE fills ADD COLUMN decision_price REAL NOT NULL DEFAULT 0`,
+	`ALTER TABLE fills ADD COLUMN slippage_bps REAL NOT NULL DEFAULT 0`,
+	`ALTER TABLE trades ADD COLUMN ai_model TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE trades ADD COLUMN regime TEXT NOT NULL DEFAULT ''`,
+}
+
+// SetDatabasePath 设置决策日志数据库路径（需在创建DecisionLogger之前调用）
+func SetDatabasePath(path string) {
+	if path != "" {
+		defaultDBPath = path
+	}
+}
+
+// openDB 打开（或复用）SQLite数据库并初始化表结构
+func openDB(path string) (*sql.DB, error) {
+	dbsMu.Lock()
+	defer dbsMu.Unlock()
+
+	if db, exists := dbs[path]; exists {
+		return db, nil
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("创建数据库目录失败: %w", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=on")
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败: %w", err)
+	}
+	// SQLite只允许单写者，串行化连接避免 database is locked
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化数据库表失败: %w", err)
+	}
+	for _, m := range migrations {
+		if _, err := db.Exec(m); err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+			db.Close()
+			return nil, fmt.Errorf("升级数据库表失败: %w", err)
+		}
+	}
+
+	dbs[path] = db
+	log.Printf("🗄️  决策日志数据库: %s", path)
+	return db, nil
+}
+
+// PingDatabase 检查决策日志数据库是否可用（就绪探针）
+func PingDatabase() error {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return err
+	}
+	var one int
+	if err := db.QueryRow(`SELECT 1`).Scan(&one); err != nil {
+		return fmt.Errorf("数据库不可用: %w", err)
+	}
+	return nil
+}
+
+// CloseDatabases 关闭所有已打开的数据库（退出前调用，确保WAL日志写回数据库文件）
+func CloseDatabases() {
+	dbsMu.Lock()
+	defer dbsMu.Unlock()
+	for path, db := range dbs {
+		if err := db.Close(); err != nil {
+			log.Printf("⚠️  关闭数据库 %s 失败: %v", path, err)
+		}
+		delete(dbs, path)
+	}
+}
@@ -1,29 +1,37 @@
 package logger
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 )
 
+var log = Module("logger")
+
 // DecisionRecord 决策记录
 type DecisionRecord struct {
-	Timestamp      time.Time          `json:"timestamp"`       // 决策时间
-	CycleNumber    int                `json:"cycle_number"`    // 周期编号
-	InputPrompt    string             `json:"input_prompt"`    // 发送给AI的输入prompt
-	CoTTrace       string             `json:"cot_trace"`       // AI思维链（输出）
-	DecisionJSON   string             `json:"decision_json"`   // 决策JSON
-	AccountState   AccountSnapshot    `json:"account_state"`   // 账户状态快照
-	Positions      []PositionSnapshot `json:"positions"`       // 持仓快照
-	CandidateCoins []string           `json:"candidate_coins"` // 候选币种列表
-	Decisions      []DecisionAction   `json:"decisions"`       // 执行的决策
-	ExecutionLog   []string           `json:"execution_log"`   // 执行日志
-	Success        bool               `json:"success"`         // 是否成功
-	ErrorMessage   string             `json:"error_message"`   // 错误信息（如果有）
+	Timestamp      time.Time          `json:"timestamp"`            // 决策时间
+	CycleNumber    int                `json:"cycle_number"`         // 周期编号
+	InputPrompt    string             `json:"input_prompt"`         // 发送给AI的输入prompt
+	CoTTrace       string             `json:"cot_trace"`            // AI思维链（输出）
+	DecisionJSON   string             `json:"decision_json"`        // 决策JSON
+	AccountState   AccountSnapshot    `json:"account_state"`        // 账户状态快照
+	Positions      []PositionSnapshot `json:"positions"`            // 持仓快照
+	CandidateCoins []string           `json:"candidate_coins"`      // 候选币种列表
+	Decisions      []DecisionAction   `json:"decisions"`            // 执行的决策
+	ExecutionLog   []string           `json:"execution_log"`        // 执行日志
+	Success        bool               `json:"success"`              // 是否成功
+	ErrorMessage   string             `json:"error_message"`        // 错误信息（如果有）
+	Regime         string             `json:"regime,omitempty"`     // 市场状态: trending / ranging / high_volatility
+	FearGreed      int                `json:"fear_greed,omitempty"` // 恐惧贪婪指数（0表示未获取到）
+	DryRun         bool               `json:"dry_run,omitempty"`    // 观察模式周期（决策只模拟成交，未下单）
+	Source         string             `json:"source,omitempty"`     // 决策来源: 空表示AI决策周期, manual为人工下单, approval为人工审批通过的开仓, retry为失败后重试
+	Context        json.RawMessage    `json:"-"`                    // 决策时的完整交易上下文（单独保存在decision_contexts表，通过DecisionContext查询）
 }
 
 // AccountSnapshot 账户状态快照
@@ -53,93 +61,268 @@ type DecisionAction struct {
 	Symbol    string    `json:"symbol"`    // 币种
 	Quantity  float64   `json:"quantity"`  // 数量
 	Leverage  int       `json:"leverage"`  // 杠杆（开仓时）
-	Price     float64   `json:"price"`     // 执行价格
+	Price     float64   `json:"price"`     // 执行价格（交易所返回成交均价时为实际成交价）
 	OrderID   int64     `json:"order_id"`  // 订单ID
 	Timestamp time.Time `json:"timestamp"` // 执行时间
 	Success   bool      `json:"success"`   // 是否成功
 	Error     string    `json:"error"`     // 错误信息
+	Fee       float64   `json:"fee"`       // 手续费（USDT，交易所不提供时为估算值）
+	Funding   float64   `json:"funding"`   // 持仓期间的资金费净支出（USDT，全部平仓时记录，负数表示收取）
+
+	DecisionPrice float64 `json:"decision_price"`            // AI决策时看到的价格（0表示没有记录）
+	SlippageBps   float64 `json:"slippage_bps"`              // 执行价相对决策价格的滑点（基点，正数表示成交价更差）
+	DryRun        bool    `json:"dry_run,omitempty"`         // 观察模式下的模拟成交（未下单，不计入成交和业绩统计）
+	Manual        bool    `json:"manual,omitempty"`          // 操作员通过API手动下单
+	ClientOrderID string  `json:"client_order_id,omitempty"` // 开仓单的客户端订单ID（由决策幂等键生成，重试时不变）
 }
 
-// DecisionLogger 决策日志记录器
+// DecisionLogger 决策日志记录器（SQLite存储，按trader_id隔离）
 type DecisionLogger struct {
-	logDir      string
+	db          *sql.DB
+	traderID    string
 	cycleNumber int
 }
 
-// NewDecisionLogger 创建决策日志记录器
-func NewDecisionLogger(logDir string) *DecisionLogger {
-	if logDir == "" {
-		logDir = "decision_logs"
+// EquitySnapshot 每个周期的账户净值快照（用于收益率曲线）
+type EquitySnapshot struct {
+	Timestamp        time.Time `json:"timestamp"`
+	CycleNumber      int       `json:"cycle_number"`
+	TotalEquity      float64   `json:"total_equity"`
+	AvailableBalance float64   `json:"available_balance"`
+	TotalPnL         float64   `json:"total_pnl"`
+	PositionCount    int       `json:"position_count"`
+	MarginUsedPct    float64   `json:"margin_used_pct"`
+}
+
+// RecordFilter 记录查询条件（零值表示不限制；Offset从最新的记录往前数）
+type RecordFilter struct {
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
+
+// where 构建查询条件
+func (f RecordFilter) where(traderID string) (string, []interface{}) {
+	clause := "trader_id = ?"
+	args := []interface{}{traderID}
+	if !f.From.IsZero() {
+		clause += " AND timestamp >= ?"
+		args = append(args, f.From.UnixMilli())
+	}
+	if !f.To.IsZero() {
+		clause += " AND timestamp <= ?"
+		args = append(args, f.To.UnixMilli())
+	}
+	return clause, args
+}
+
+// page 构建分页子句（LIMIT -1 表示不限制条数）
+func (f RecordFilter) page() (string, []interface{}) {
+	limit := f.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	offset := f.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	return " LIMIT ? OFFSET ?", []interface{}{limit, offset}
+}
+
+// NewDecisionLogger 创建决策日志记录器（使用全局决策日志数据库）
+func NewDecisionLogger(traderID string) (*DecisionLogger, error) {
+	return NewDecisionLoggerWithDB(defaultDBPath, traderID)
+}
+
+// NewDecisionLoggerWithDB 使用指定数据库文件创建决策日志记录器（回测等独立场景）
+func NewDecisionLoggerWithDB(dbPath, traderID string) (*DecisionLogger, error) {
+	db, err := openDB(dbPath)
+	if err != nil {
+		return nil, err
 	}
 
-	// 确保日志目录存在
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		fmt.Printf("⚠ 创建日志目录失败: %v\n", err)
+	// 周期编号接着上次运行继续
+	var lastCycle sql.NullInt64
+	if err := db.QueryRow(`SELECT MAX(cycle_number) FROM decisions WHERE trader_id = ?`, traderID).Scan(&lastCycle); err != nil {
+		return nil, fmt.Errorf("读取周期编号失败: %w", err)
 	}
 
 	return &DecisionLogger{
-		logDir:      logDir,
-		cycleNumber: 0,
+		db:          db,
+		traderID:    traderID,
+		cycleNumber: int(lastCycle.Int64),
+	}, nil
+}
+
+// ImportJSONDir 导入旧版本保存在目录中的JSON决策记录（仅当该trader在数据库中还没有记录时执行）
+func (l *DecisionLogger) ImportJSONDir(dir string) (int, error) {
+	var count int
+	if err := l.db.QueryRow(`SELECT COUNT(*) FROM decisions WHERE trader_id = ?`, l.traderID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("查询决策记录失败: %w", err)
 	}
+	if count > 0 {
+		return 0, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "decision_*.json"))
+	if err != nil || len(files) == 0 {
+		return 0, nil
+	}
+
+	var records []*DecisionRecord
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var record DecisionRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			continue
+		}
+		records = append(records, &record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Timestamp.Before(records[j].Timestamp)
+	})
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("开启事务失败: %w", err)
+	}
+	for i, record := range records {
+		record.CycleNumber = i + 1 // 旧版本每次重启都从1开始编号，导入时重新编号
+		if err := insertRecord(tx, l.traderID, record); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("提交事务失败: %w", err)
+	}
+
+	l.cycleNumber = len(records)
+	log.Printf("📥 已从 %s 导入 %d 条历史决策记录", dir, len(records))
+	return len(records), nil
 }
 
 // LogDecision 记录决策
 func (l *DecisionLogger) LogDecision(record *DecisionRecord) error {
 	l.cycleNumber++
 	record.CycleNumber = l.cycleNumber
-	record.Timestamp = time.Now()
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now() // 回测等场景可预先指定时间
+	}
 
-	// 生成文件名：decision_YYYYMMDD_HHMMSS_cycleN.json
-	filename := fmt.Sprintf("decision_%s_cycle%d.json",
-		record.Timestamp.Format("20060102_150405"),
-		record.CycleNumber)
+	tx, err := l.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	if err := insertRecord(tx, l.traderID, record); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("写入决策记录失败: %w", err)
+	}
 
-	filepath := filepath.Join(l.logDir, filename)
+	log.Printf("📝 决策记录已保存: 周期 #%d", record.CycleNumber)
+	return nil
+}
 
-	// 序列化为JSON（带缩进，方便阅读）
-	data, err := json.MarshalIndent(record, "", "  ")
+// insertRecord 写入一条决策记录及其成交和净值快照
+func insertRecord(tx *sql.Tx, traderID string, record *DecisionRecord) error {
+	data, err := json.Marshal(record)
 	if err != nil {
 		return fmt.Errorf("序列化决策记录失败: %w", err)
 	}
 
-	// 写入文件
-	if err := ioutil.WriteFile(filepath, data, 0644); err != nil {
+	ts := record.Timestamp.UnixMilli()
+	res, err := tx.Exec(`INSERT INTO decisions (trader_id, timestamp, cycle_number, success, record) VALUES (?, ?, ?, ?, ?)`,
+		traderID, ts, record.CycleNumber, record.Success, string(data))
+	if err != nil {
+		return fmt.Errorf("写入决策记录失败: %w", err)
+	}
+	decisionID, err := res.LastInsertId()
+	if err != nil {
 		return fmt.Errorf("写入决策记录失败: %w", err)
 	}
 
-	fmt.Printf("📝 决策记录已保存: %s\n", filename)
+	for _, action := range record.Decisions {
+		if action.DryRun {
+			continue
+		}
+		if _, err := tx.Exec(`INSERT INTO fills (decision_id, trader_id, timestamp, symbol, action, quantity, price, leverage, order_id, success, error, fee, funding, decision_price, slippage_bps)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			decisionID, traderID, action.Timestamp.UnixMilli(), action.Symbol, action.Action,
+			action.Quantity, action.Price, action.Leverage, action.OrderID, action.Success, action.Error,
+			action.Fee, action.Funding, action.DecisionPrice, action.SlippageBps); err != nil {
+			return fmt.Errorf("写入成交记录失败: %w", err)
+		}
+	}
+
+	if len(record.Context) > 0 {
+		if _, err := tx.Exec(`INSERT INTO decision_contexts (decision_id, trader_id, cycle_number, context) VALUES (?, ?, ?, ?)`,
+			decisionID, traderID, record.CycleNumber, string(record.Context)); err != nil {
+			return fmt.Errorf("写入上下文快照失败: %w", err)
+		}
+	}
+
+	// 没有账户快照的记录（如构建上下文失败）不写入净值曲线
+	if record.AccountState.TotalBalance > 0 {
+		if _, err := tx.Exec(`INSERT INTO equity_snapshots (decision_id, trader_id, timestamp, cycle_number, total_equity, available_balance, total_pnl, position_count, margin_used_pct)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			decisionID, traderID, ts, record.CycleNumber, record.AccountState.TotalBalance, record.AccountState.AvailableBalance,
+			record.AccountState.TotalUnrealizedProfit, record.AccountState.PositionCount, record.AccountState.MarginUsedPct); err != nil {
+			return fmt.Errorf("写入净值快照失败: %w", err)
+		}
+	}
 	return nil
 }
 
-// GetLatestRecords 获取最近N条记录（按时间正序：从旧到新）
-func (l *DecisionLogger) GetLatestRecords(n int) ([]*DecisionRecord, error) {
-	files, err := ioutil.ReadDir(l.logDir)
+// queryRecords 查询并反序列化决策记录
+func (l *DecisionLogger) queryRecords(query string, args ...interface{}) ([]*DecisionRecord, error) {
+	rows, err := l.db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("读取日志目录失败: %w", err)
+		return nil, fmt.Errorf("查询决策记录失败: %w", err)
 	}
+	defer rows.Close()
 
-	// 先按修改时间倒序收集（最新的在前）
 	var records []*DecisionRecord
-	count := 0
-	for i := len(files) - 1; i >= 0 && count < n; i-- {
-		file := files[i]
-		if file.IsDir() {
-			continue
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("读取决策记录失败: %w", err)
 		}
-
-		filepath := filepath.Join(l.logDir, file.Name())
-		data, err := ioutil.ReadFile(filepath)
-		if err != nil {
-			continue
-		}
-
 		var record DecisionRecord
-		if err := json.Unmarshal(data, &record); err != nil {
+		if err := json.Unmarshal([]byte(data), &record); err != nil {
 			continue
 		}
-
 		records = append(records, &record)
-		count++
+	}
+	return records, rows.Err()
+}
+
+// GetLatestRecords 获取最近N条记录（按时间正序：从旧到新）
+func (l *DecisionLogger) GetLatestRecords(n int) ([]*DecisionRecord, error) {
+	records, _, err := l.QueryRecords(RecordFilter{Limit: n})
+	return records, err
+}
+
+// QueryRecords 按时间范围分页查询决策记录（返回按时间正序排列的当前页，以及满足条件的总条数）
+func (l *DecisionLogger) QueryRecords(f RecordFilter) ([]*DecisionRecord, int, error) {
+	where, args := f.where(l.traderID)
+
+	var total int
+	if err := l.db.QueryRow(`SELECT COUNT(*) FROM decisions WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("查询决策记录失败: %w", err)
+	}
+
+	page, pageArgs := f.page()
+	records, err := l.queryRecords(`SELECT record FROM decisions WHERE `+where+` ORDER BY timestamp DESC, id DESC`+page,
+		append(args, pageArgs...)...)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	// 反转数组，让时间从旧到新排列（用于图表显示）
@@ -147,64 +330,151 @@ func (l *DecisionLogger) GetLatestRecords(n int) ([]*DecisionRecord, error) {
 		records[i], records[j] = records[j], records[i]
 	}
 
-	return records, nil
+	return records, total, nil
+}
+
+// DecisionContext 查询指定周期的决策记录及其上下文快照（周期不存在时返回nil，记录没有快照时Context为空）
+func (l *DecisionLogger) DecisionContext(cycle int) (*DecisionRecord, error) {
+	var data string
+	var context sql.NullString
+	err := l.db.QueryRow(`SELECT d.record, c.context FROM decisions d LEFT JOIN decision_contexts c ON c.decision_id = d.id
+		WHERE d.trader_id = ? AND d.cycle_number = ? ORDER BY d.id DESC LIMIT 1`, l.traderID, cycle).Scan(&data, &context)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询决策记录失败: %w", err)
+	}
+	var record DecisionRecord
+	if err := json.Unmarshal([]byte(data), &record); err != nil {
+		return nil, fmt.Errorf("读取决策记录失败: %w", err)
+	}
+	if context.Valid {
+		record.Context = json.RawMessage(context.String)
+	}
+	return &record, nil
 }
 
 // GetRecordByDate 获取指定日期的所有记录
 func (l *DecisionLogger) GetRecordByDate(date time.Time) ([]*DecisionRecord, error) {
-	dateStr := date.Format("20060102")
-	pattern := filepath.Join(l.logDir, fmt.Sprintf("decision_%s_*.json", dateStr))
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	end := start.AddDate(0, 0, 1)
+	return l.queryRecords(`SELECT record FROM decisions WHERE trader_id = ? AND timestamp >= ? AND timestamp < ? ORDER BY timestamp, id`,
+		l.traderID, start.UnixMilli(), end.UnixMilli())
+}
 
-	files, err := filepath.Glob(pattern)
-	if err != nil {
-		return nil, fmt.Errorf("查找日志文件失败: %w", err)
-	}
+// QueryEquityHistory 按时间范围分页查询净值快照（返回按时间正序排列的当前页，以及满足条件的总条数）
+func (l *DecisionLogger) QueryEquityHistory(f RecordFilter) ([]EquitySnapshot, int, error) {
+	where, args := f.where(l.traderID)
 
-	var records []*DecisionRecord
-	for _, filepath := range files {
-		data, err := ioutil.ReadFile(filepath)
-		if err != nil {
-			continue
-		}
+	var total int
+	if err := l.db.QueryRow(`SELECT COUNT(*) FROM equity_snapshots WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("查询净值快照失败: %w", err)
+	}
 
-		var record DecisionRecord
-		if err := json.Unmarshal(data, &record); err != nil {
-			continue
+	page, pageArgs := f.page()
+	rows, err := l.db.Query(`SELECT timestamp, cycle_number, total_equity, available_balance, total_pnl, position_count, margin_used_pct
+		FROM equity_snapshots WHERE `+where+` ORDER BY timestamp DESC`+page, append(args, pageArgs...)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询净值快照失败: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []EquitySnapshot
+	for rows.Next() {
+		var ts int64
+		var snap EquitySnapshot
+		if err := rows.Scan(&ts, &snap.CycleNumber, &snap.TotalEquity, &snap.AvailableBalance,
+			&snap.TotalPnL, &snap.PositionCount, &snap.MarginUsedPct); err != nil {
+			return nil, 0, fmt.Errorf("读取净值快照失败: %w", err)
 		}
+		snap.Timestamp = time.UnixMilli(ts)
+		snapshots = append(snapshots, snap)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("读取净值快照失败: %w", err)
+	}
 
-		records = append(records, &record)
+	for i, j := 0, len(snapshots)-1; i < j; i, j = i+1, j-1 {
+		snapshots[i], snapshots[j] = snapshots[j], snapshots[i]
 	}
+	return snapshots, total, nil
+}
 
-	return records, nil
+// EquityCandle 一个时间桶内的净值OHLC（其余字段取桶内最后一个快照）
+type EquityCandle struct {
+	Timestamp        time.Time `json:"timestamp"` // 桶起始时间（按UTC对齐）
+	Open             float64   `json:"open"`
+	High             float64   `json:"high"`
+	Low              float64   `json:"low"`
+	Close            float64   `json:"close"`
+	AvailableBalance float64   `json:"available_balance"`
+	TotalPnL         float64   `json:"total_pnl"`
+	PositionCount    int       `json:"position_count"`
+	MarginUsedPct    float64   `json:"margin_used_pct"`
+	CycleNumber      int       `json:"cycle_number"`
+	Samples          int       `json:"samples"` // 桶内快照数
 }
 
-// CleanOldRecords 清理N天前的旧记录
-func (l *DecisionLogger) CleanOldRecords(days int) error {
-	cutoffTime := time.Now().AddDate(0, 0, -days)
+// QueryEquityCandles 按时间桶聚合净值快照（Limit/Offset按桶计数，返回按时间正序排列的当前页，以及满足条件的总桶数）
+func (l *DecisionLogger) QueryEquityCandles(f RecordFilter, resolution time.Duration) ([]EquityCandle, int, error) {
+	bucketMs := resolution.Milliseconds()
+	if bucketMs <= 0 {
+		return nil, 0, fmt.Errorf("无效的聚合周期: %s", resolution)
+	}
+	where, args := f.where(l.traderID)
 
-	files, err := ioutil.ReadDir(l.logDir)
-	if err != nil {
-		return fmt.Errorf("读取日志目录失败: %w", err)
+	var total int
+	if err := l.db.QueryRow(`SELECT COUNT(DISTINCT timestamp / ?) FROM equity_snapshots WHERE `+where,
+		append([]interface{}{bucketMs}, args...)...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("查询净值快照失败: %w", err)
 	}
 
-	removedCount := 0
-	for _, file := range files {
-		if file.IsDir() {
-			continue
+	// 开盘/收盘取桶内第一个/最后一个快照（decision_id随时间递增）
+	page, pageArgs := f.page()
+	queryArgs := append([]interface{}{bucketMs}, args...)
+	rows, err := l.db.Query(`SELECT b.bucket, b.high, b.low, b.samples, o.total_equity,
+			c.total_equity, c.available_balance, c.total_pnl, c.position_count, c.margin_used_pct, c.cycle_number
+		FROM (SELECT timestamp / ? AS bucket, MIN(decision_id) AS first_id, MAX(decision_id) AS last_id,
+				MAX(total_equity) AS high, MIN(total_equity) AS low, COUNT(*) AS samples
+			FROM equity_snapshots WHERE `+where+` GROUP BY bucket ORDER BY bucket DESC`+page+`) b
+		JOIN equity_snapshots o ON o.decision_id = b.first_id
+		JOIN equity_snapshots c ON c.decision_id = b.last_id
+		ORDER BY b.bucket`, append(queryArgs, pageArgs...)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询净值快照失败: %w", err)
+	}
+	defer rows.Close()
+
+	var candles []EquityCandle
+	for rows.Next() {
+		var bucket int64
+		var candle EquityCandle
+		if err := rows.Scan(&bucket, &candle.High, &candle.Low, &candle.Samples, &candle.Open,
+			&candle.Close, &candle.AvailableBalance, &candle.TotalPnL, &candle.PositionCount,
+			&candle.MarginUsedPct, &candle.CycleNumber); err != nil {
+			return nil, 0, fmt.Errorf("读取净值快照失败: %w", err)
 		}
+		candle.Timestamp = time.UnixMilli(bucket * bucketMs)
+		candles = append(candles, candle)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("读取净值快照失败: %w", err)
+	}
+	return candles, total, nil
+}
 
-		if file.ModTime().Before(cutoffTime) {
-			filepath := filepath.Join(l.logDir, file.Name())
-			if err := os.Remove(filepath); err != nil {
-				fmt.Printf("⚠ 删除旧记录失败 %s: %v\n", file.Name(), err)
-				continue
-			}
-			removedCount++
-		}
+// CleanOldRecords 清理N天前的旧记录（成交和净值快照级联删除）
+func (l *DecisionLogger) CleanOldRecords(days int) error {
+	cutoff := time.Now().AddDate(0, 0, -days).UnixMilli()
+
+	res, err := l.db.Exec(`DELETE FROM decisions WHERE trader_id = ? AND timestamp < ?`, l.traderID, cutoff)
+	if err != nil {
+		return fmt.Errorf("清理旧记录失败: %w", err)
 	}
 
-	if removedCount > 0 {
-		fmt.Printf("🗑️ 已清理 %d 条旧记录（%d天前）\n", removedCount, days)
+	if removedCount, _ := res.RowsAffected(); removedCount > 0 {
+		log.Printf("🗑️ 已清理 %d 条旧记录（%d天前）", removedCount, days)
 	}
 
 	return nil
@@ -212,48 +482,30 @@ func (l *DecisionLogger) CleanOldRecords(days int) error {
 
 // GetStatistics 获取统计信息
 func (l *DecisionLogger) GetStatistics() (*Statistics, error) {
-	files, err := ioutil.ReadDir(l.logDir)
-	if err != nil {
-		return nil, fmt.Errorf("读取日志目录失败: %w", err)
-	}
-
 	stats := &Statistics{}
 
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-
-		filepath := filepath.Join(l.logDir, file.Name())
-		data, err := ioutil.ReadFile(filepath)
-		if err != nil {
-			continue
-		}
-
-		var record DecisionRecord
-		if err := json.Unmarshal(data, &record); err != nil {
-			continue
-		}
-
-		stats.TotalCycles++
-
-		for _, action := range record.Decisions {
-			if action.Success {
-				switch action.Action {
-				case "open_long", "open_short":
-					stats.TotalOpenPositions++
-				case "close_long", "close_short":
-					stats.TotalClosePositions++
-				}
-			}
-		}
+	var successful sql.NullInt64
+	if err := l.db.QueryRow(`SELECT COUNT(*), SUM(success) FROM decisions WHERE trader_id = ?`, l.traderID).
+		Scan(&stats.TotalCycles, &successful); err != nil {
+		return nil, fmt.Errorf("查询统计信息失败: %w", err)
+	}
+	stats.SuccessfulCycles = int(successful.Int64)
+	stats.FailedCycles = stats.TotalCycles - stats.SuccessfulCycles
+
+	if err := l.db.QueryRow(`SELECT
+			COUNT(CASE WHEN action IN ('open_long', 'open_short') THEN 1 END),
+			COUNT(CASE WHEN action IN ('close_long', 'close_short') THEN 1 END),
+			COALESCE(SUM(fee), 0), COALESCE(SUM(funding), 0)
+		FROM fills WHERE trader_id = ? AND success = 1`, l.traderID).
+		Scan(&stats.TotalOpenPositions, &stats.TotalClosePositions, &stats.TotalFees, &stats.TotalFunding); err != nil {
+		return nil, fmt.Errorf("查询统计信息失败: %w", err)
+	}
 
-		if record.Success {
-			stats.SuccessfulCycles++
-		} else {
-			stats.FailedCycles++
-		}
+	slippage, err := l.SlippageBySymbol(time.Time{})
+	if err != nil {
+		return nil, err
 	}
+	stats.Slippage = slippage
 
 	return stats, nil
 }
@@ -265,6 +517,11 @@ type Statistics struct {
 	FailedCycles        int `json:"failed_cycles"`
 	TotalOpenPositions  int `json:"total_open_positions"`
 	TotalClosePositions int `json:"total_close_positions"`
+
+	TotalFees    float64 `json:"total_fees"`    // 累计手续费（USDT）
+	TotalFunding float64 `json:"total_funding"` // 累计资金费净支出（USDT）
+
+	Slippage []SymbolSlippage `json:"slippage"` // 各币种的平均滑点
 }
 
 // TradeOutcome 单笔交易结果
@@ -277,28 +534,38 @@ type TradeOutcome struct {
 	ClosePrice    float64   `json:"close_price"`    // 平仓价
 	PositionValue float64   `json:"position_value"` // 仓位价值（quantity × openPrice）
 	MarginUsed    float64   `json:"margin_used"`    // 保证金使用（positionValue / leverage）
-	PnL           float64   `json:"pn_l"`           // 盈亏（USDT）
+	PnL           float64   `json:"pn_l"`           // 净盈亏（USDT，已扣除手续费和资金费）
 	PnLPct        float64   `json:"pn_l_pct"`       // 盈亏百分比（相对保证金）
 	Duration      string    `json:"duration"`       // 持仓时长
 	OpenTime      time.Time `json:"open_time"`      // 开仓时间
 	CloseTime     time.Time `json:"close_time"`     // 平仓时间
 	WasStopLoss   bool      `json:"was_stop_loss"`  // 是否止损
+	GrossPnL      float64   `json:"gross_pnl"`      // 毛盈亏（不含手续费和资金费）
+	Fees          float64   `json:"fees"`           // 开平仓手续费
+	Funding       float64   `json:"funding"`        // 资金费净支出
 }
 
 // PerformanceAnalysis 交易表现分析
 type PerformanceAnalysis struct {
-	TotalTrades   int                           `json:"total_trades"`   // 总交易数
-	WinningTrades int                           `json:"winning_trades"` // 盈利交易数
-	LosingTrades  int                           `json:"losing_trades"`  // 亏损交易数
-	WinRate       float64                       `json:"win_rate"`       // 胜率
-	AvgWin        float64                       `json:"avg_win"`        // 平均盈利
-	AvgLoss       float64                       `json:"avg_loss"`       // 平均亏损
-	ProfitFactor  float64                       `json:"profit_factor"`  // 盈亏比
-	SharpeRatio   float64                       `json:"sharpe_ratio"`   // 夏普比率（风险调整后收益）
-	RecentTrades  []TradeOutcome                `json:"recent_trades"`  // 最近N笔交易
-	SymbolStats   map[string]*SymbolPerformance `json:"symbol_stats"`   // 各币种表现
-	BestSymbol    string                        `json:"best_symbol"`    // 表现最好的币种
-	WorstSymbol   string                        `json:"worst_symbol"`   // 表现最差的币种
+	TotalTrades       int                           `json:"total_trades"`        // 总交易数
+	WinningTrades     int                           `json:"winning_trades"`      // 盈利交易数
+	LosingTrades      int                           `json:"losing_trades"`       // 亏损交易数
+	WinRate           float64                       `json:"win_rate"`            // 胜率
+	AvgWin            float64                       `json:"avg_win"`             // 平均盈利
+	AvgLoss           float64                       `json:"avg_loss"`            // 平均亏损
+	ProfitFactor      float64                       `json:"profit_factor"`       // 盈亏比
+	SharpeRatio       float64                       `json:"sharpe_ratio"`        // 夏普比率（风险调整后收益）
+	SortinoRatio      float64                       `json:"sortino_ratio"`       // 索提诺比率（只把下跌波动当作风险）
+	CalmarRatio       float64                       `json:"calmar_ratio"`        // 卡玛比率（窗口收益率 / 最大回撤）
+	MaxDrawdownPct    float64                       `json:"max_drawdown_pct"`    // 窗口内净值从峰值到谷底的最大回撤（%）
+	AvgHoldingMinutes float64                       `json:"avg_holding_minutes"` // 已平仓交易的平均持仓时长（分钟）
+	ExposurePct       float64                       `json:"exposure_pct"`        // 有持仓的周期占比（%）
+	TotalFees         float64                       `json:"total_fees"`          // 窗口内已平仓交易的手续费合计
+	TotalFunding      float64                       `json:"total_funding"`       // 窗口内已平仓交易的资金费合计
+	RecentTrades      []TradeOutcome                `json:"recent_trades"`       // 最近N笔交易
+	SymbolStats       map[string]*SymbolPerformance `json:"symbol_stats"`        // 各币种表现
+	BestSymbol        string                        `json:"best_symbol"`         // 表现最好的币种
+	WorstSymbol       string                        `json:"worst_symbol"`        // 表现最差的币种
 }
 
 // SymbolPerformance 币种表现统计
@@ -334,6 +601,8 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 	// 追踪持仓状态：symbol_side -> {side, openPrice, openTime, quantity, leverage}
 	openPositions := make(map[string]map[string]interface{})
 
+	var totalHolding time.Duration // 已平仓交易的持仓时长合计
+
 	// 为了避免开仓记录在窗口外导致匹配失败，需要先从所有历史记录中找出未平仓的持仓
 	// 获取更多历史记录来构建完整的持仓状态（使用更大的窗口）
 	allRecords, err := l.GetLatestRecords(lookbackCycles * 3) // 扩大3倍窗口
@@ -341,7 +610,7 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 		// 先从扩大的窗口中收集所有开仓记录
 		for _, record := range allRecords {
 			for _, action := range record.Decisions {
-				if !action.Success {
+				if !action.Success || action.DryRun {
 					continue
 				}
 
@@ -363,6 +632,7 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 						"openTime":  action.Timestamp,
 						"quantity":  action.Quantity,
 						"leverage":  action.Leverage,
+						"fee":       action.Fee,
 					}
 				case "close_long", "close_short":
 					// 移除已平仓记录
@@ -375,7 +645,7 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 	// 遍历分析窗口内的记录，生成交易结果
 	for _, record := range records {
 		for _, action := range record.Decisions {
-			if !action.Success {
+			if !action.Success || action.DryRun {
 				continue
 			}
 
@@ -397,6 +667,7 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 					"openTime":  action.Timestamp,
 					"quantity":  action.Quantity,
 					"leverage":  action.Leverage,
+					"fee":       action.Fee,
 				}
 
 			case "close_long", "close_short":
@@ -411,13 +682,19 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 					// 计算实际盈亏（USDT）
 					// 合约交易 PnL 计算：quantity × 价格差
 					// 注意：杠杆不影响绝对盈亏，只影响保证金需求
-					var pnl float64
+					var grossPnL float64
 					if side == "long" {
-						pnl = quantity * (action.Price - openPrice)
+						grossPnL = quantity * (action.Price - openPrice)
 					} else {
-						pnl = quantity * (openPrice - action.Price)
+						grossPnL = quantity * (openPrice - action.Price)
 					}
 
+					// 扣除开平仓手续费和持仓期间的资金费
+					fees := openPos["fee"].(float64) + action.Fee
+					pnl := grossPnL - fees - action.Funding
+					analysis.TotalFees += fees
+					analysis.TotalFunding += action.Funding
+
 					// 计算盈亏百分比（相对保证金）
 					positionValue := quantity * openPrice
 					marginUsed := positionValue / float64(leverage)
@@ -438,6 +715,9 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 						MarginUsed:    marginUsed,
 						PnL:           pnl,
 						PnLPct:        pnlPct,
+						GrossPnL:      grossPnL,
+						Fees:          fees,
+						Funding:       action.Funding,
 						Duration:      action.Timestamp.Sub(openTime).String(),
 						OpenTime:      openTime,
 						CloseTime:     action.Timestamp,
@@ -445,6 +725,7 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 
 					analysis.RecentTrades = append(analysis.RecentTrades, outcome)
 					analysis.TotalTrades++
+					totalHolding += action.Timestamp.Sub(openTime)
 
 					// 分类交易：盈利、亏损、持平（避免将pnl=0算入亏损）
 					if pnl > 0 {
@@ -481,6 +762,7 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 	// 计算统计指标
 	if analysis.TotalTrades > 0 {
 		analysis.WinRate = (float64(analysis.WinningTrades) / float64(analysis.TotalTrades)) * 100
+		analysis.AvgHoldingMinutes = totalHolding.Minutes() / float64(analysis.TotalTrades)
 
 		// 计算总盈利和总亏损
 		totalWinAmount := analysis.AvgWin   // 当前是累加的总和
@@ -538,10 +820,76 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 
 	// 计算夏普比率（需要至少2个数据点）
 	analysis.SharpeRatio = l.calculateSharpeRatio(records)
+	calculateRiskMetrics(records, analysis)
 
 	return analysis, nil
 }
 
+// calculateRiskMetrics 计算最大回撤、索提诺比率、卡玛比率和持仓暴露度（与夏普比率一样基于窗口内的周期净值，不做年化）
+func calculateRiskMetrics(records []*DecisionRecord, analysis *PerformanceAnalysis) {
+	exposed := 0
+	for _, record := range records {
+		if record.AccountState.PositionCount > 0 {
+			exposed++
+		}
+	}
+	if len(records) > 0 {
+		analysis.ExposurePct = float64(exposed) / float64(len(records)) * 100
+	}
+
+	equities := equityCurve(records)
+	if len(equities) < 2 {
+		return
+	}
+
+	// 最大回撤：净值从历史峰值到之后谷底的最大跌幅
+	peak := equities[0]
+	for _, equity := range equities {
+		if equity > peak {
+			peak = equity
+		}
+		if drawdown := (peak - equity) / peak * 100; drawdown > analysis.MaxDrawdownPct {
+			analysis.MaxDrawdownPct = drawdown
+		}
+	}
+
+	// 索提诺比率：平均周期收益 / 下行偏差（只统计负收益的波动）
+	sumReturns, sumDownside := 0.0, 0.0
+	for i := 1; i < len(equities); i++ {
+		r := (equities[i] - equities[i-1]) / equities[i-1]
+		sumReturns += r
+		if r < 0 {
+			sumDownside += r * r
+		}
+	}
+	n := float64(len(equities) - 1)
+	meanReturn := sumReturns / n
+	if downsideDev := math.Sqrt(sumDownside / n); downsideDev > 0 {
+		analysis.SortinoRatio = meanReturn / downsideDev
+	} else if meanReturn > 0 {
+		analysis.SortinoRatio = 999.0 // 没有下跌的正收益
+	}
+
+	// 卡玛比率：窗口收益率 / 最大回撤
+	totalReturnPct := (equities[len(equities)-1] - equities[0]) / equities[0] * 100
+	if analysis.MaxDrawdownPct > 0 {
+		analysis.CalmarRatio = totalReturnPct / analysis.MaxDrawdownPct
+	} else if totalReturnPct > 0 {
+		analysis.CalmarRatio = 999.0 // 没有回撤的正收益
+	}
+}
+
+// equityCurve 提取每个周期的账户净值（TotalBalance字段实际存储的是账户总净值，跳过无效的0值）
+func equityCurve(records []*DecisionRecord) []float64 {
+	var equities []float64
+	for _, record := range records {
+		if equity := record.AccountState.TotalBalance; equity > 0 {
+			equities = append(equities, equity)
+		}
+	}
+	return equities
+}
+
 // calculateSharpeRatio 计算夏普比率
 // 基于账户净值的变化计算风险调整后收益
 func (l *DecisionLogger) calculateSharpeRatio(records []*DecisionRecord) float64 {
@@ -550,16 +898,7 @@ func (l *DecisionLogger) calculateSharpeRatio(records []*DecisionRecord) float64
 	}
 
 	// 提取每个周期的账户净值
-	// 注意：TotalBalance字段实际存储的是TotalEquity（账户总净值）
-	// TotalUnrealizedProfit字段实际存储的是TotalPnL（相对初始余额的盈亏）
-	var equities []float64
-	for _, record := range records {
-		// 直接使用TotalBalance，因为它已经是完整的账户净值
-		equity := record.AccountState.TotalBalance
-		if equity > 0 {
-			equities = append(equities, equity)
-		}
-	}
+	equities := equityCurve(records)
 
 	if len(equities) < 2 {
 		return 0.0
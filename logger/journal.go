@@ -0,0 +1,223 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// JournalFeeRate 没有记录手续费的旧成交按此费率估算（taker 0.04%）
+const JournalFeeRate = 0.0004
+
+// FundingRate 一次资金费结算
+type FundingRate struct {
+	Time time.Time
+	Rate float64
+}
+
+// FundingHistoryFunc 获取币种在 [from, to] 内的历史资金费率
+type FundingHistoryFunc func(symbol string, from, to time.Time) ([]FundingRate, error)
+
+// JournalEntry 交易日志中的一笔交易（一次开仓到平仓，部分平仓拆成多笔）
+type JournalEntry struct {
+	Symbol         string    `json:"symbol"`
+	Side           string    `json:"side"`   // long / short
+	Status         string    `json:"status"` // closed / open（尚未平仓或被止损止盈平掉而没有平仓记录）
+	Quantity       float64   `json:"quantity"`
+	Leverage       int       `json:"leverage"`
+	OpenTime       time.Time `json:"open_time"`
+	OpenPrice      float64   `json:"open_price"` // 加仓后为加权均价
+	CloseTime      time.Time `json:"close_time"`
+	ClosePrice     float64   `json:"close_price"`
+	EntryFee       float64   `json:"entry_fee"`    // 开仓手续费（没有记录时估算）
+	ExitFee        float64   `json:"exit_fee"`     // 平仓手续费（没有记录时估算）
+	FundingPaid    float64   `json:"funding_paid"` // 持仓期间的资金费净支出（负数表示收取，没有记录时估算）
+	GrossPnL       float64   `json:"gross_pnl"`
+	NetPnL         float64   `json:"net_pnl"` // 扣除手续费和资金费
+	Confidence     int       `json:"confidence"`
+	Reasoning      string    `json:"reasoning"`       // 开仓理由
+	CloseReasoning string    `json:"close_reasoning"` // 平仓理由
+
+	fundingRecorded bool // 平仓时已记录实际资金费，不再估算
+}
+
+// decisionMeta AI决策中交易日志需要的字段
+type decisionMeta struct {
+	Symbol     string `json:"symbol"`
+	Action     string `json:"action"`
+	Confidence int    `json:"confidence"`
+	Reasoning  string `json:"reasoning"`
+}
+
+// TradeJournal 生成完整交易日志（按开仓时间排序），funding为nil时不计算资金费（现货）
+func (l *DecisionLogger) TradeJournal(f RecordFilter, funding FundingHistoryFunc) ([]JournalEntry, error) {
+	f.Limit, f.Offset = 0, 0
+	records, _, err := l.QueryRecords(f)
+	if err != nil {
+		return nil, fmt.Errorf("读取历史记录失败: %w", err)
+	}
+
+	var entries []JournalEntry
+	open := make(map[string]*JournalEntry) // symbol_side -> 未平仓部分
+
+	for _, record := range records {
+		meta := make(map[string]decisionMeta)
+		var decisions []decisionMeta
+		if err := json.Unmarshal([]byte(record.DecisionJSON), &decisions); err == nil {
+			for _, d := range decisions {
+				meta[d.Symbol+"_"+d.Action] = d
+			}
+		}
+
+		for _, action := range record.Decisions {
+			if !action.Success || action.DryRun {
+				continue
+			}
+			var side string
+			switch action.Action {
+			case "open_long", "close_long":
+				side = "long"
+			case "open_short", "close_short":
+				side = "short"
+			default:
+				continue
+			}
+			posKey := action.Symbol + "_" + side
+			d := meta[action.Symbol+"_"+action.Action]
+
+			switch action.Action {
+			case "open_long", "open_short":
+				fee := actionFee(action, action.Quantity)
+				if pos, exists := open[posKey]; exists {
+					// 加仓：合并为加权均价
+					total := pos.Quantity + action.Quantity
+					if total > 0 {
+						pos.OpenPrice = (pos.Quantity*pos.OpenPrice + action.Quantity*action.Price) / total
+					}
+					pos.Quantity = total
+					pos.EntryFee += fee
+					continue
+				}
+				open[posKey] = &JournalEntry{
+					Symbol:     action.Symbol,
+					Side:       side,
+					Status:     "open",
+					Quantity:   action.Quantity,
+					Leverage:   action.Leverage,
+					OpenTime:   action.Timestamp,
+					OpenPrice:  action.Price,
+					EntryFee:   fee,
+					Confidence: d.Confidence,
+					Reasoning:  d.Reasoning,
+				}
+
+			case "close_long", "close_short":
+				pos, exists := open[posKey]
+				if !exists {
+					continue
+				}
+				// 全部平仓时记录的数量为0
+				closed := *pos
+				if action.Quantity > 0 && action.Quantity < pos.Quantity {
+					closed.Quantity = action.Quantity
+					closed.EntryFee = pos.EntryFee * action.Quantity / pos.Quantity
+					pos.Quantity -= action.Quantity
+					pos.EntryFee -= closed.EntryFee
+				} else {
+					delete(open, posKey)
+				}
+
+				closed.Status = "closed"
+				closed.CloseTime = action.Timestamp
+				closed.ClosePrice = action.Price
+				closed.ExitFee = actionFee(action, closed.Quantity)
+				if action.Funding != 0 {
+					closed.FundingPaid = action.Funding
+					closed.fundingRecorded = true
+				}
+				closed.CloseReasoning = d.Reasoning
+				if side == "long" {
+					closed.GrossPnL = closed.Quantity * (action.Price - closed.OpenPrice)
+				} else {
+					closed.GrossPnL = closed.Quantity * (closed.OpenPrice - action.Price)
+				}
+				entries = append(entries, closed)
+			}
+		}
+	}
+
+	for _, pos := range open {
+		entries = append(entries, *pos)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].OpenTime.Before(entries[j].OpenTime)
+	})
+
+	if funding != nil {
+		applyFunding(entries, funding)
+	}
+	for i := range entries {
+		e := &entries[i]
+		e.NetPnL = e.GrossPnL - e.EntryFee - e.ExitFee - e.FundingPaid
+	}
+
+	return entries, nil
+}
+
+// actionFee 成交记录的手续费（没有记录时按数量和成交价估算）
+func actionFee(action DecisionAction, quantity float64) float64 {
+	if action.Fee > 0 {
+		return action.Fee
+	}
+	return quantity * action.Price * JournalFeeRate
+}
+
+// applyFunding 按持仓期间的资金费率估算资金费（每个币种只请求一次历史费率，名义价值按开仓价计算）
+func applyFunding(entries []JournalEntry, funding FundingHistoryFunc) {
+	now := time.Now()
+	bySymbol := make(map[string][]int)
+	for i, e := range entries {
+		if !e.fundingRecorded {
+			bySymbol[e.Symbol] = append(bySymbol[e.Symbol], i)
+		}
+	}
+
+	for symbol, idx := range bySymbol {
+		from, to := entries[idx[0]].OpenTime, time.Time{}
+		for _, i := range idx {
+			end := entries[i].CloseTime
+			if end.IsZero() {
+				end = now
+			}
+			if end.After(to) {
+				to = end
+			}
+		}
+
+		rates, err := funding(symbol, from, to)
+		if err != nil {
+			log.Printf("⚠️  获取%s历史资金费率失败，资金费按0计算: %v", symbol, err)
+			continue
+		}
+
+		for _, i := range idx {
+			e := &entries[i]
+			end := e.CloseTime
+			if end.IsZero() {
+				end = now
+			}
+			notional := e.Quantity * e.OpenPrice
+			for _, r := range rates {
+				if r.Time.After(e.OpenTime) && !r.Time.After(end) {
+					// 正费率多头支付、空头收取
+					if e.Side == "long" {
+						e.FundingPaid += notional * r.Rate
+					} else {
+						e.FundingPaid -= notional * r.Rate
+					}
+				}
+			}
+		}
+	}
+}
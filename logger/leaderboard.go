@@ -0,0 +1,139 @@
+package logger
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// LeaderboardEntry 排行榜条目
+type LeaderboardEntry struct {
+	Rank           int     `json:"rank"`
+	TraderID       string  `json:"trader_id"`
+	TraderName     string  `json:"trader_name"`
+	AIModel        string  `json:"ai_model"`
+	TotalEquity    float64 `json:"total_equity"`
+	TotalPnLPct    float64 `json:"total_pnl_pct"`
+	SharpeRatio    float64 `json:"sharpe_ratio"`
+	MaxDrawdownPct float64 `json:"max_drawdown_pct"`
+	WinRate        float64 `json:"win_rate"`
+	TotalTrades    int     `json:"total_trades"`
+
+	BenchmarkPnLPct float64 `json:"benchmark_pnl_pct"` // 同期BTC买入持有的收益率
+	AlphaPct        float64 `json:"alpha_pct"`         // 相对BTC买入持有的超额收益
+}
+
+// LeaderboardSnapshot 某一时刻的排行榜快照
+type LeaderboardSnapshot struct {
+	Timestamp time.Time          `json:"timestamp"`
+	Entries   []LeaderboardEntry `json:"entries"`
+}
+
+// SaveLeaderboardSnapshot 保存排行榜快照（只保存指标，名次在查询时按指定指标重新计算）
+func SaveLeaderboardSnapshot(ts time.Time, entries []LeaderboardEntry) error {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	for _, e := range entries {
+		if _, err := tx.Exec(`INSERT INTO leaderboard_snapshots (timestamp, trader_id, trader_name, ai_model, total_equity, total_pnl_pct, sharpe_ratio, max_drawdown_pct, win_rate, total_trades, benchmark_pnl_pct, alpha_pct)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			ts.UnixMilli(), e.TraderID, e.TraderName, e.AIModel, e.TotalEquity, e.TotalPnLPct,
+			e.SharpeRatio, e.MaxDrawdownPct, e.WinRate, e.TotalTrades, e.BenchmarkPnLPct, e.AlphaPct); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("写入排行榜快照失败: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("写入排行榜快照失败: %w", err)
+	}
+	return nil
+}
+
+// GetLeaderboardHistory 获取时间范围内的排行榜快照（按时间正序；零值表示不限制）
+func GetLeaderboardHistory(from, to time.Time) ([]LeaderboardSnapshot, error) {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return nil, err
+	}
+
+	clause, args := "1 = 1", []interface{}{}
+	if !from.IsZero() {
+		clause += " AND timestamp >= ?"
+		args = append(args, from.UnixMilli())
+	}
+	if !to.IsZero() {
+		clause += " AND timestamp <= ?"
+		args = append(args, to.UnixMilli())
+	}
+
+	rows, err := db.Query(`SELECT timestamp, trader_id, trader_name, ai_model, total_equity, total_pnl_pct, sharpe_ratio, max_drawdown_pct, win_rate, total_trades, benchmark_pnl_pct, alpha_pct
+		FROM leaderboard_snapshots WHERE `+clause+` ORDER BY timestamp, id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询排行榜快照失败: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []LeaderboardSnapshot
+	for rows.Next() {
+		var ts int64
+		var e LeaderboardEntry
+		if err := rows.Scan(&ts, &e.TraderID, &e.TraderName, &e.AIModel, &e.TotalEquity, &e.TotalPnLPct,
+			&e.SharpeRatio, &e.MaxDrawdownPct, &e.WinRate, &e.TotalTrades, &e.BenchmarkPnLPct, &e.AlphaPct); err != nil {
+			return nil, fmt.Errorf("读取排行榜快照失败: %w", err)
+		}
+		// 同一时间戳的行属于同一次快照
+		if n := len(snapshots); n == 0 || snapshots[n-1].Timestamp.UnixMilli() != ts {
+			snapshots = append(snapshots, LeaderboardSnapshot{Timestamp: time.UnixMilli(ts)})
+		}
+		last := &snapshots[len(snapshots)-1]
+		last.Entries = append(last.Entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取排行榜快照失败: %w", err)
+	}
+	return snapshots, nil
+}
+
+// FirstSnapshotTime 第一个净值快照的时间（trader开始运行的时间，没有快照时返回false）
+func (l *DecisionLogger) FirstSnapshotTime() (time.Time, bool, error) {
+	var ts sql.NullInt64
+	if err := l.db.QueryRow(`SELECT MIN(timestamp) FROM equity_snapshots WHERE trader_id = ?`, l.traderID).Scan(&ts); err != nil {
+		return time.Time{}, false, fmt.Errorf("查询净值快照失败: %w", err)
+	}
+	if !ts.Valid {
+		return time.Time{}, false, nil
+	}
+	return time.UnixMilli(ts.Int64), true, nil
+}
+
+// MaxDrawdownPct 根据净值快照计算历史最大回撤（百分比）
+func (l *DecisionLogger) MaxDrawdownPct() (float64, error) {
+	rows, err := l.db.Query(`SELECT total_equity FROM equity_snapshots WHERE trader_id = ? ORDER BY timestamp`, l.traderID)
+	if err != nil {
+		return 0, fmt.Errorf("查询净值快照失败: %w", err)
+	}
+	defer rows.Close()
+
+	peak, maxDD := 0.0, 0.0
+	for rows.Next() {
+		var equity float64
+		if err := rows.Scan(&equity); err != nil {
+			return 0, fmt.Errorf("读取净值快照失败: %w", err)
+		}
+		if equity > peak {
+			peak = equity
+		}
+		if peak > 0 {
+			if dd := (peak - equity) / peak * 100; dd > maxDD {
+				maxDD = dd
+			}
+		}
+	}
+	return maxDD, rows.Err()
+}
@@ -0,0 +1,231 @@
+package logger
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 复盘不记录的平仓原因（与开仓形态无关）
+var lessonSkippedReasons = map[string]bool{CloseReasonPanic: true, CloseReasonSeasonEnd: true}
+
+const (
+	lessonQuickStop      = 30 * time.Minute // 开仓后这么快被止损视为入场过早/止损过近
+	lessonHighConfidence = 80               // 高信心度开仓
+	lessonReasoningRunes = 120              // 复盘中开平仓理由的最大长度（字符）
+)
+
+// Lesson 一笔已平仓交易的复盘（跨赛季保留，作为AI的长期记忆）
+type Lesson struct {
+	ID          int64     `json:"id"`
+	TradeID     int64     `json:"trade_id"`
+	Time        time.Time `json:"time"` // 平仓时间
+	Symbol      string    `json:"symbol"`
+	Side        string    `json:"side"`
+	Regime      string    `json:"regime"` // 开仓时的市场状态
+	AIModel     string    `json:"ai_model"`
+	Confidence  int       `json:"confidence"`
+	Outcome     string    `json:"outcome"` // win / loss
+	PnLPct      float64   `json:"pnl_pct"`
+	CloseReason string    `json:"close_reason"`
+	Summary     string    `json:"summary"` // 开平仓经过
+	Lesson      string    `json:"lesson"`  // 从结果中得出的教训
+}
+
+const lessonColumns = `id, trade_id, timestamp, symbol, side, regime, ai_model, confidence, outcome, pnl_pct, close_reason, summary, lesson`
+
+// scanLesson 读取一行复盘记录
+func scanLesson(row interface{ Scan(...interface{}) error }) (*Lesson, error) {
+	var l Lesson
+	var ms int64
+	if err := row.Scan(&l.ID, &l.TradeID, &ms, &l.Symbol, &l.Side, &l.Regime, &l.AIModel, &l.Confidence,
+		&l.Outcome, &l.PnLPct, &l.CloseReason, &l.Summary, &l.Lesson); err != nil {
+		return nil, err
+	}
+	l.Time = time.UnixMilli(ms)
+	return &l, nil
+}
+
+// newLesson 由已平仓交易自动生成复盘（紧急平仓、赛季结算等非策略原因的平仓返回nil）
+func newLesson(t *Trade) *Lesson {
+	if t.Status != "closed" || lessonSkippedReasons[t.CloseReason] {
+		return nil
+	}
+	outcome := "win"
+	if t.PnL <= 0 {
+		outcome = "loss"
+	}
+	sideName := map[string]string{"long": "做多", "short": "做空"}[t.Side]
+	held := t.CloseTime.Sub(t.OpenTime)
+
+	summary := fmt.Sprintf("%s%s，持仓%s，%s，净盈亏%+.2f%%", t.Symbol, sideName, formatHeld(held), closeReasonText(t.CloseReason), t.PnLPct)
+	if t.Regime != "" {
+		summary += "，开仓时市场状态: " + t.Regime
+	}
+	if t.Confidence > 0 {
+		summary += fmt.Sprintf("，信心度%d", t.Confidence)
+	}
+	if r := shorten(t.Reasoning, lessonReasoningRunes); r != "" {
+		summary += "。开仓理由: " + r
+	}
+	if r := shorten(t.CloseReasoning, lessonReasoningRunes); r != "" {
+		summary += "。平仓理由: " + r
+	}
+
+	return &Lesson{
+		TradeID:     t.ID,
+		Time:        t.CloseTime,
+		Symbol:      t.Symbol,
+		Side:        t.Side,
+		Regime:      t.Regime,
+		AIModel:     t.AIModel,
+		Confidence:  t.Confidence,
+		Outcome:     outcome,
+		PnLPct:      t.PnLPct,
+		CloseReason: t.CloseReason,
+		Summary:     summary,
+		Lesson:      lessonText(t, outcome == "win", held),
+	}
+}
+
+// lessonText 按盈亏、平仓方式、信心度和持仓时长总结教训
+func lessonText(t *Trade, win bool, held time.Duration) string {
+	switch {
+	case !win && t.CloseReason == CloseReasonStopLoss && held < lessonQuickStop:
+		return "开仓后很快被止损：入场过早或止损过近，同类形态应等待确认或给止损留出波动空间"
+	case !win && t.CloseReason == CloseReasonStopLoss && t.Confidence >= lessonHighConfidence:
+		return "高信心度开仓仍被止损：该形态的成功率被高估，下次需要更多证据或降低信心度"
+	case !win && t.CloseReason == CloseReasonStopLoss:
+		return "按计划止损：开仓逻辑未兑现，同类形态需要更严格的筛选"
+	case !win:
+		return "开仓逻辑未兑现、亏损离场：留意开仓时忽略的反向信号"
+	case t.CloseReason == CloseReasonTakeProfit:
+		return "按计划止盈：该形态在此市场状态下有效，可以继续采用"
+	case t.CloseReason == CloseReasonStopLoss:
+		return "移动止损锁定了部分利润：方向判断正确，但行情没有延续到止盈目标"
+	case t.TakeProfit > 0 && t.ClosePrice > 0 && !reachedTarget(t):
+		return "盈利但在止盈目标前离场：止盈目标可能过远，或平仓偏早"
+	default:
+		return "盈利离场：该形态有效"
+	}
+}
+
+// reachedTarget 平仓价是否达到止盈目标
+func reachedTarget(t *Trade) bool {
+	if t.Side == "short" {
+		return t.ClosePrice <= t.TakeProfit
+	}
+	return t.ClosePrice >= t.TakeProfit
+}
+
+// closeReasonText 平仓方式描述
+func closeReasonText(reason string) string {
+	switch reason {
+	case CloseReasonStopLoss:
+		return "止损触发"
+	case CloseReasonTakeProfit:
+		return "止盈触发"
+	case CloseReasonManual:
+		return "人工平仓"
+	default:
+		return "AI平仓"
+	}
+}
+
+// formatHeld 持仓时长描述
+func formatHeld(d time.Duration) string {
+	if d < time.Hour {
+		return fmt.Sprintf("%d分钟", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%d小时%d分钟", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// shorten 合并空白并截断到n个字符
+func shorten(s string, n int) string {
+	r := []rune(strings.Join(strings.Fields(s), " "))
+	if len(r) > n {
+		return string(r[:n]) + "…"
+	}
+	return string(r)
+}
+
+// recordLesson 交易全部平仓后写入复盘（在平仓事务中调用）
+func (l *DecisionLogger) recordLesson(tx *sql.Tx, t *Trade) error {
+	lesson := newLesson(t)
+	if lesson == nil {
+		return nil
+	}
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO lessons (trader_id, trade_id, timestamp, symbol, side, regime, ai_model, confidence,
+		outcome, pnl_pct, close_reason, summary, lesson) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		l.traderID, lesson.TradeID, lesson.Time.UnixMilli(), lesson.Symbol, lesson.Side, lesson.Regime, lesson.AIModel,
+		lesson.Confidence, lesson.Outcome, lesson.PnLPct, lesson.CloseReason, lesson.Summary, lesson.Lesson); err != nil {
+		return fmt.Errorf("写入交易复盘失败: %w", err)
+	}
+	return nil
+}
+
+// QueryLessons 分页查询复盘（symbol为空时不过滤，按平仓时间从新到旧，同时返回满足条件的总条数）
+func (l *DecisionLogger) QueryLessons(f RecordFilter, symbol string) ([]Lesson, int, error) {
+	where, args := f.where(l.traderID)
+	if symbol != "" {
+		where += " AND symbol = ?"
+		args = append(args, symbol)
+	}
+
+	var total int
+	if err := l.db.QueryRow(`SELECT COUNT(*) FROM lessons WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("查询交易复盘失败: %w", err)
+	}
+	page, pageArgs := f.page()
+	rows, err := l.db.Query(`SELECT `+lessonColumns+` FROM lessons WHERE `+where+` ORDER BY timestamp DESC, id DESC`+page,
+		append(args, pageArgs...)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询交易复盘失败: %w", err)
+	}
+	lessons, err := scanLessons(rows)
+	return lessons, total, err
+}
+
+// RelevantLessons 与当前行情最相关的复盘：symbols中的币种优先，其次是相同的市场状态，同等相关时较新的优先
+func (l *DecisionLogger) RelevantLessons(symbols []string, regime string, limit int) ([]Lesson, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	symbolMatch := "0"
+	args := []interface{}{l.traderID}
+	if len(symbols) > 0 {
+		symbolMatch = "symbol IN (?" + strings.Repeat(", ?", len(symbols)-1) + ")"
+		for _, s := range symbols {
+			args = append(args, s)
+		}
+	}
+	args = append(args, regime, regime, limit)
+
+	rows, err := l.db.Query(`SELECT `+lessonColumns+` FROM lessons
+		WHERE trader_id = ?
+		ORDER BY (CASE WHEN `+symbolMatch+` THEN 2 ELSE 0 END) + (CASE WHEN ? != '' AND regime = ? THEN 1 ELSE 0 END) DESC,
+			timestamp DESC, id DESC
+		LIMIT ?`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询交易复盘失败: %w", err)
+	}
+	return scanLessons(rows)
+}
+
+// scanLessons 读取复盘查询结果
+func scanLessons(rows *sql.Rows) ([]Lesson, error) {
+	defer rows.Close()
+	var lessons []Lesson
+	for rows.Next() {
+		lesson, err := scanLesson(rows)
+		if err != nil {
+			return nil, fmt.Errorf("读取交易复盘失败: %w", err)
+		}
+		lessons = append(lessons, *lesson)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取交易复盘失败: %w", err)
+	}
+	return lessons, nil
+}
@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Setup 初始化全局结构化日志（format: "text" 或 "json"；level: "debug", "info", "warn", "error"）
+// 标准库log包的输出也会被转发到同一个handler
+func Setup(format, level string) error {
+	var lvl slog.Level
+	switch strings.ToLower(level) {
+	case "", "info":
+		lvl = slog.LevelInfo
+	case "debug":
+		lvl = slog.LevelDebug
+	case "warn":
+		lvl = slog.LevelWarn
+	case "error":
+		lvl = slog.LevelError
+	default:
+		return fmt.Errorf("不支持的日志级别: %s", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "", "text":
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	default:
+		return fmt.Errorf("不支持的日志格式: %s", format)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// Logger 带固定字段（module、trader_id、cycle等）的结构化日志
+// 保留Printf/Println风格的调用方式，方便替换原有的log.Printf
+type Logger struct {
+	attrs []any
+}
+
+// Module 创建带module字段的日志（在Setup之前创建也可以，每次写日志时才取全局handler）
+func Module(name string) *Logger {
+	return &Logger{attrs: []any{"module", name}}
+}
+
+// With 返回附加了字段的新Logger
+func (l *Logger) With(args ...any) *Logger {
+	attrs := make([]any, 0, len(l.attrs)+len(args))
+	attrs = append(attrs, l.attrs...)
+	attrs = append(attrs, args...)
+	return &Logger{attrs: attrs}
+}
+
+func (l *Logger) log(level slog.Level, msg string) {
+	logger := slog.Default()
+	if !logger.Enabled(context.Background(), level) {
+		return
+	}
+	logger.Log(context.Background(), level, msg, l.attrs...)
+}
+
+// levelOf 兼容旧日志约定：以❌开头的记为ERROR，以⚠开头的记为WARN，其余为INFO
+func levelOf(msg string) slog.Level {
+	trimmed := strings.TrimSpace(msg)
+	switch {
+	case strings.HasPrefix(trimmed, "❌"):
+		return slog.LevelError
+	case strings.HasPrefix(trimmed, "⚠"):
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// Printf 按格式输出日志（级别由消息前缀推断）
+func (l *Logger) Printf(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	l.log(levelOf(msg), msg)
+}
+
+// Println 输出日志（级别由消息前缀推断）
+func (l *Logger) Println(args ...any) {
+	msg := strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+	l.log(levelOf(msg), msg)
+}
+
+// Print 输出日志（级别由消息前缀推断）
+func (l *Logger) Print(args ...any) {
+	msg := fmt.Sprint(args...)
+	l.log(levelOf(msg), msg)
+}
+
+// Debugf 输出DEBUG级别日志
+func (l *Logger) Debugf(format string, args ...any) {
+	l.log(slog.LevelDebug, fmt.Sprintf(format, args...))
+}
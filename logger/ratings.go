@@ -0,0 +1,163 @@
+package logger
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ModelRating AI模型的ELO评分（跨赛季保留，不随trader重置）
+type ModelRating struct {
+	Model     string    `json:"model"`
+	Rating    float64   `json:"rating"`
+	Periods   int       `json:"periods"` // 参与评分的赛季/周数
+	Wins      int       `json:"wins"`    // 与其他模型两两比较的胜场
+	Losses    int       `json:"losses"`
+	Draws     int       `json:"draws"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RatingChange 一个评分周期内某个模型的评分变化
+type RatingChange struct {
+	Time    time.Time `json:"time"`
+	Period  string    `json:"period"` // 赛季名称或周（如 2025-W03）
+	Model   string    `json:"model"`
+	Traders int       `json:"traders"` // 参与计分的trader数
+	Score   float64   `json:"score"`   // 风险调整后收益（收益率 / max(最大回撤, 1%)，多个trader取平均）
+	Before  float64   `json:"before"`
+	After   float64   `json:"after"`
+	Wins    int       `json:"wins"`
+	Losses  int       `json:"losses"`
+	Draws   int       `json:"draws"`
+}
+
+// RatingFilter 评分历史查询条件
+type RatingFilter struct {
+	RecordFilter
+	Model string
+}
+
+// LoadModelRatings 读取所有模型的当前评分（按评分从高到低）
+func LoadModelRatings() ([]ModelRating, error) {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT model, rating, periods, wins, losses, draws, updated_at FROM model_ratings ORDER BY rating DESC, model`)
+	if err != nil {
+		return nil, fmt.Errorf("读取模型评分失败: %w", err)
+	}
+	defer rows.Close()
+
+	ratings := []ModelRating{}
+	for rows.Next() {
+		var r ModelRating
+		var updatedAt int64
+		if err := rows.Scan(&r.Model, &r.Rating, &r.Periods, &r.Wins, &r.Losses, &r.Draws, &updatedAt); err != nil {
+			return nil, fmt.Errorf("读取模型评分失败: %w", err)
+		}
+		r.UpdatedAt = time.UnixMilli(updatedAt)
+		ratings = append(ratings, r)
+	}
+	return ratings, rows.Err()
+}
+
+// SaveRatingChanges 在一个事务中写入一个评分周期的评分变化并更新各模型的当前评分
+func SaveRatingChanges(changes []RatingChange) error {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	for _, c := range changes {
+		if _, err := tx.Exec(`INSERT INTO rating_history (timestamp, period, model, traders, score, rating_before, rating_after, wins, losses, draws)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			c.Time.UnixMilli(), c.Period, c.Model, c.Traders, c.Score, c.Before, c.After, c.Wins, c.Losses, c.Draws); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("写入评分历史失败: %w", err)
+		}
+		if _, err := tx.Exec(`INSERT INTO model_ratings (model, rating, periods, wins, losses, draws, updated_at) VALUES (?, ?, 1, ?, ?, ?, ?)
+			ON CONFLICT(model) DO UPDATE SET rating = excluded.rating, periods = periods + 1, wins = wins + excluded.wins,
+			losses = losses + excluded.losses, draws = draws + excluded.draws, updated_at = excluded.updated_at`,
+			c.Model, c.After, c.Wins, c.Losses, c.Draws, c.Time.UnixMilli()); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("更新模型评分失败: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("保存模型评分失败: %w", err)
+	}
+	return nil
+}
+
+// LastRatingTime 最近一次更新评分的时间（还没有评分时返回false）
+func LastRatingTime() (time.Time, bool, error) {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	var ts sql.NullInt64
+	if err := db.QueryRow(`SELECT MAX(timestamp) FROM rating_history`).Scan(&ts); err != nil {
+		return time.Time{}, false, fmt.Errorf("读取评分历史失败: %w", err)
+	}
+	if !ts.Valid {
+		return time.Time{}, false, nil
+	}
+	return time.UnixMilli(ts.Int64), true, nil
+}
+
+// QueryRatingHistory 分页查询评分变化（按时间倒序，最新的在前），同时返回满足条件的总条数
+func QueryRatingHistory(f RatingFilter) ([]RatingChange, int, error) {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	where, args := "1 = 1", []interface{}{}
+	if !f.From.IsZero() {
+		where += " AND timestamp >= ?"
+		args = append(args, f.From.UnixMilli())
+	}
+	if !f.To.IsZero() {
+		where += " AND timestamp <= ?"
+		args = append(args, f.To.UnixMilli())
+	}
+	if f.Model != "" {
+		where += " AND model = ?"
+		args = append(args, f.Model)
+	}
+
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM rating_history WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("查询评分历史失败: %w", err)
+	}
+
+	page, pageArgs := f.page()
+	rows, err := db.Query(`SELECT timestamp, period, model, traders, score, rating_before, rating_after, wins, losses, draws
+		FROM rating_history WHERE `+where+` ORDER BY timestamp DESC, id DESC`+page, append(args, pageArgs...)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询评分历史失败: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []RatingChange{}
+	for rows.Next() {
+		var c RatingChange
+		var ts int64
+		if err := rows.Scan(&ts, &c.Period, &c.Model, &c.Traders, &c.Score, &c.Before, &c.After, &c.Wins, &c.Losses, &c.Draws); err != nil {
+			return nil, 0, fmt.Errorf("读取评分历史失败: %w", err)
+		}
+		c.Time = time.UnixMilli(ts)
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("读取评分历史失败: %w", err)
+	}
+	return changes, total, nil
+}
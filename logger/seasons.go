@@ -0,0 +1,140 @@
+package logger
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Season 已结束的竞赛赛季
+type Season struct {
+	Number      int                `json:"number"`
+	Name        string             `json:"name"`
+	StartTime   time.Time          `json:"start_time"`
+	EndTime     time.Time          `json:"end_time"` // 计划结束时间
+	EndedAt     time.Time          `json:"ended_at"` // 实际结算时间
+	Leaderboard []LeaderboardEntry `json:"leaderboard"`
+	Archive     string             `json:"archive"`          // 归档的决策日志数据库文件
+	Errors      []string           `json:"errors,omitempty"` // 结算失败的明细（平仓失败的trader不会重置）
+}
+
+// seasonArchivedTables 赛季结算时归档后清空的表（trader设置、AI用量、审计记录等跨赛季保留）
+var seasonArchivedTables = []string{"decisions", "fills", "equity_snapshots", "leaderboard_snapshots"}
+
+// SaveSeason 保存已结束赛季的结算结果
+func SaveSeason(s Season) error {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return err
+	}
+
+	leaderboard, err := json.Marshal(s.Leaderboard)
+	if err != nil {
+		return fmt.Errorf("序列化排行榜失败: %w", err)
+	}
+	errs, err := json.Marshal(s.Errors)
+	if err != nil {
+		return fmt.Errorf("序列化结算错误失败: %w", err)
+	}
+	if _, err := db.Exec(`INSERT INTO seasons (number, name, start_time, end_time, ended_at, leaderboard, archive, errors) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.Number, s.Name, s.StartTime.UnixMilli(), s.EndTime.UnixMilli(), s.EndedAt.UnixMilli(),
+		string(leaderboard), s.Archive, string(errs)); err != nil {
+		return fmt.Errorf("保存赛季结果失败: %w", err)
+	}
+	return nil
+}
+
+// LoadSeasons 读取所有已结束的赛季（最新的在前）
+func LoadSeasons() ([]Season, error) {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT number, name, start_time, end_time, ended_at, leaderboard, archive, errors FROM seasons ORDER BY number DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("读取赛季结果失败: %w", err)
+	}
+	defer rows.Close()
+
+	seasons := []Season{}
+	for rows.Next() {
+		var s Season
+		var start, end, endedAt int64
+		var leaderboard, errs string
+		if err := rows.Scan(&s.Number, &s.Name, &start, &end, &endedAt, &leaderboard, &s.Archive, &errs); err != nil {
+			return nil, fmt.Errorf("读取赛季结果失败: %w", err)
+		}
+		s.StartTime, s.EndTime, s.EndedAt = time.UnixMilli(start), time.UnixMilli(end), time.UnixMilli(endedAt)
+		if err := json.Unmarshal([]byte(leaderboard), &s.Leaderboard); err != nil {
+			return nil, fmt.Errorf("解析赛季 %d 的排行榜失败: %w", s.Number, err)
+		}
+		_ = json.Unmarshal([]byte(errs), &s.Errors)
+		seasons = append(seasons, s)
+	}
+	return seasons, rows.Err()
+}
+
+// LastSeason 最近结束的赛季（还没有赛季结束时返回false）
+func LastSeason() (*Season, bool, error) {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var s Season
+	var start, end, endedAt int64
+	err = db.QueryRow(`SELECT number, name, start_time, end_time, ended_at, archive FROM seasons ORDER BY number DESC LIMIT 1`).
+		Scan(&s.Number, &s.Name, &start, &end, &endedAt, &s.Archive)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("读取赛季结果失败: %w", err)
+	}
+	s.StartTime, s.EndTime, s.EndedAt = time.UnixMilli(start), time.UnixMilli(end), time.UnixMilli(endedAt)
+	return &s, true, nil
+}
+
+// ArchiveSeason 把整个数据库复制到 archive/season-N.db（与数据库同目录），然后清空该赛季的决策、成交、净值和排行榜快照
+// 已平仓的交易记录一并清空，仍未平仓的交易（结算时平仓失败）保留到下个赛季
+func ArchiveSeason(number int) (string, error) {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(filepath.Dir(defaultDBPath), "archive")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("创建归档目录失败: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("season-%d.db", number))
+	if _, err := os.Stat(path); err == nil {
+		path = filepath.Join(dir, fmt.Sprintf("season-%d-%d.db", number, time.Now().Unix()))
+	}
+	if _, err := db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return "", fmt.Errorf("归档数据库失败: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return path, fmt.Errorf("开启事务失败: %w", err)
+	}
+	for _, table := range seasonArchivedTables {
+		if _, err := tx.Exec(`DELETE FROM ` + table); err != nil {
+			tx.Rollback()
+			return path, fmt.Errorf("清空 %s 失败: %w", table, err)
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM trades WHERE status != 'open'`); err != nil {
+		tx.Rollback()
+		return path, fmt.Errorf("清空 trades 失败: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return path, fmt.Errorf("清空赛季数据失败: %w", err)
+	}
+	return path, nil
+}
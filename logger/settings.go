@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SaveTraderSetting 保存trader的运行时设置（通过API修改、需要在重启后保留的配置，值以JSON保存）
+func SaveTraderSetting(traderID, key string, value interface{}) error {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("序列化设置失败: %w", err)
+	}
+	if _, err := db.Exec(`INSERT INTO trader_settings (trader_id, key, value, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(trader_id, key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
+		traderID, key, string(data), time.Now().UnixMilli()); err != nil {
+		return fmt.Errorf("保存设置失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteTraderSetting 删除trader的运行时设置（不存在时不做任何事）
+func DeleteTraderSetting(traderID, key string) error {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(`DELETE FROM trader_settings WHERE trader_id = ? AND key = ?`, traderID, key); err != nil {
+		return fmt.Errorf("删除设置失败: %w", err)
+	}
+	return nil
+}
+
+// LoadTraderSetting 读取trader的运行时设置到value中（不存在时返回false）
+func LoadTraderSetting(traderID, key string, value interface{}) (bool, error) {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return false, err
+	}
+
+	var data string
+	err = db.QueryRow(`SELECT value FROM trader_settings WHERE trader_id = ? AND key = ?`, traderID, key).Scan(&data)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("读取设置失败: %w", err)
+	}
+	if err := json.Unmarshal([]byte(data), value); err != nil {
+		return false, fmt.Errorf("解析设置失败: %w", err)
+	}
+	return true, nil
+}
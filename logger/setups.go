@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// HistoricalSetup 一笔已平仓交易开仓时的形态向量和最终结果（跨赛季保留，用于检索历史上相似的行情）
+type HistoricalSetup struct {
+	TradeID     int64     `json:"trade_id"`
+	Time        time.Time `json:"time"` // 开仓时间
+	Symbol      string    `json:"symbol"`
+	Side        string    `json:"side"`
+	Vector      []float64 `json:"vector"`
+	PnLPct      float64   `json:"pnl_pct"` // 净盈亏（相对保证金）
+	CloseReason string    `json:"close_reason"`
+}
+
+// recordSetup 保存新开仓交易的形态向量（在开仓事务中调用）
+func (l *DecisionLogger) recordSetup(tx *sql.Tx, res sql.Result, e TradeEntry) error {
+	tradeID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	vector, err := json.Marshal(e.Setup)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT OR REPLACE INTO setups (trade_id, trader_id, timestamp, symbol, side, vector) VALUES (?, ?, ?, ?, ?, ?)`,
+		tradeID, l.traderID, e.Time.UnixMilli(), e.Symbol, e.Side, string(vector))
+	return err
+}
+
+// closeSetup 交易全部平仓后记录形态的结果（在平仓事务中调用，没有形态向量的交易不受影响）
+func closeSetup(tx *sql.Tx, t *Trade) error {
+	if _, err := tx.Exec(`UPDATE setups SET closed = 1, pnl_pct = ?, close_reason = ? WHERE trade_id = ?`,
+		t.PnLPct, t.CloseReason, t.ID); err != nil {
+		return fmt.Errorf("写入形态结果失败: %w", err)
+	}
+	return nil
+}
+
+// ClosedSetups 该trader所有已有结果的形态（紧急平仓和赛季结算的交易除外）
+func (l *DecisionLogger) ClosedSetups() ([]HistoricalSetup, error) {
+	rows, err := l.db.Query(`SELECT trade_id, timestamp, symbol, side, vector, pnl_pct, close_reason FROM setups
+		WHERE trader_id = ? AND closed = 1 AND close_reason NOT IN (?, ?)`, l.traderID, CloseReasonPanic, CloseReasonSeasonEnd)
+	if err != nil {
+		return nil, fmt.Errorf("查询历史形态失败: %w", err)
+	}
+	defer rows.Close()
+
+	var setups []HistoricalSetup
+	for rows.Next() {
+		var s HistoricalSetup
+		var ms int64
+		var vector string
+		if err := rows.Scan(&s.TradeID, &ms, &s.Symbol, &s.Side, &vector, &s.PnLPct, &s.CloseReason); err != nil {
+			return nil, fmt.Errorf("读取历史形态失败: %w", err)
+		}
+		if err := json.Unmarshal([]byte(vector), &s.Vector); err != nil {
+			continue
+		}
+		s.Time = time.UnixMilli(ms)
+		setups = append(setups, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取历史形态失败: %w", err)
+	}
+	return setups, nil
+}
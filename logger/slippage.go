@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"fmt"
+	"time"
+)
+
+// SymbolSlippage 单个币种的成交滑点统计
+type SymbolSlippage struct {
+	Symbol   string  `json:"symbol"`
+	Fills    int     `json:"fills"`   // 有决策价格的成交笔数
+	AvgBps   float64 `json:"avg_bps"` // 平均滑点（基点，正数表示成交价比决策价格差）
+	WorstBps float64 `json:"worst_bps"`
+}
+
+// SlippageBySymbol 按币种统计since之后成功成交的平均滑点（since为零值时统计全部，按平均滑点从大到小排序）
+func (l *DecisionLogger) SlippageBySymbol(since time.Time) ([]SymbolSlippage, error) {
+	var sinceMs int64
+	if !since.IsZero() {
+		sinceMs = since.UnixMilli()
+	}
+	rows, err := l.db.Query(`SELECT symbol, COUNT(*), AVG(slippage_bps), MAX(slippage_bps)
+		FROM fills
+		WHERE trader_id = ? AND success = 1 AND decision_price > 0 AND timestamp >= ?
+		GROUP BY symbol
+		ORDER BY AVG(slippage_bps) DESC`, l.traderID, sinceMs)
+	if err != nil {
+		return nil, fmt.Errorf("查询滑点统计失败: %w", err)
+	}
+	defer rows.Close()
+
+	var result []SymbolSlippage
+	for rows.Next() {
+		var s SymbolSlippage
+		if err := rows.Scan(&s.Symbol, &s.Fills, &s.AvgBps, &s.WorstBps); err != nil {
+			return nil, fmt.Errorf("查询滑点统计失败: %w", err)
+		}
+		result = append(result, s)
+	}
+	return result, rows.Err()
+}
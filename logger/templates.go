@@ -0,0 +1,156 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SavedTrader 通过API创建（克隆或从模板创建）的trader，重启后从数据库恢复
+type SavedTrader struct {
+	TraderID  string
+	Config    json.RawMessage // TraderConfig JSON（含密钥）
+	CreatedAt time.Time
+}
+
+// TraderTemplate 命名的trader模板
+type TraderTemplate struct {
+	Name      string
+	Value     json.RawMessage // 模板内容JSON（含密钥）
+	UpdatedAt time.Time
+}
+
+// SaveTrader 保存通过API创建的trader配置
+func SaveTrader(traderID string, cfg interface{}) error {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("序列化trader配置失败: %w", err)
+	}
+	if _, err := db.Exec(`INSERT INTO saved_traders (trader_id, config, created_at) VALUES (?, ?, ?)`,
+		traderID, string(data), time.Now().UnixMilli()); err != nil {
+		return fmt.Errorf("保存trader配置失败: %w", err)
+	}
+	return nil
+}
+
+// LoadSavedTraders 读取所有通过API创建的trader（按创建时间排序）
+func LoadSavedTraders() ([]SavedTrader, error) {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT trader_id, config, created_at FROM saved_traders ORDER BY created_at, trader_id`)
+	if err != nil {
+		return nil, fmt.Errorf("读取trader配置失败: %w", err)
+	}
+	defer rows.Close()
+
+	var traders []SavedTrader
+	for rows.Next() {
+		var t SavedTrader
+		var cfg string
+		var createdAt int64
+		if err := rows.Scan(&t.TraderID, &cfg, &createdAt); err != nil {
+			return nil, fmt.Errorf("读取trader配置失败: %w", err)
+		}
+		t.Config = json.RawMessage(cfg)
+		t.CreatedAt = time.UnixMilli(createdAt)
+		traders = append(traders, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取trader配置失败: %w", err)
+	}
+	return traders, nil
+}
+
+// DeleteTrader 删除通过API创建的trader配置及其运行时设置（历史决策和交易记录保留）
+func DeleteTrader(traderID string) error {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("删除trader配置失败: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM saved_traders WHERE trader_id = ?`, traderID); err != nil {
+		return fmt.Errorf("删除trader配置失败: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM trader_settings WHERE trader_id = ?`, traderID); err != nil {
+		return fmt.Errorf("删除trader设置失败: %w", err)
+	}
+	return tx.Commit()
+}
+
+// SaveTraderTemplate 保存（或覆盖）命名的trader模板
+func SaveTraderTemplate(name string, value interface{}) error {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("序列化模板失败: %w", err)
+	}
+	if _, err := db.Exec(`INSERT INTO trader_templates (name, value, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
+		name, string(data), time.Now().UnixMilli()); err != nil {
+		return fmt.Errorf("保存模板失败: %w", err)
+	}
+	return nil
+}
+
+// LoadTraderTemplates 读取所有trader模板（按名称排序）
+func LoadTraderTemplates() ([]TraderTemplate, error) {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT name, value, updated_at FROM trader_templates ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("读取模板失败: %w", err)
+	}
+	defer rows.Close()
+
+	var templates []TraderTemplate
+	for rows.Next() {
+		var t TraderTemplate
+		var value string
+		var updatedAt int64
+		if err := rows.Scan(&t.Name, &value, &updatedAt); err != nil {
+			return nil, fmt.Errorf("读取模板失败: %w", err)
+		}
+		t.Value = json.RawMessage(value)
+		t.UpdatedAt = time.UnixMilli(updatedAt)
+		templates = append(templates, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取模板失败: %w", err)
+	}
+	return templates, nil
+}
+
+// DeleteTraderTemplate 删除trader模板（不存在时返回false）
+func DeleteTraderTemplate(name string) (bool, error) {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := db.Exec(`DELETE FROM trader_templates WHERE name = ?`, name)
+	if err != nil {
+		return false, fmt.Errorf("删除模板失败: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
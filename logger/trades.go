@@ -0,0 +1,393 @@
+package logger
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 平仓原因
+const (
+	CloseReasonAI         = "ai"          // AI决策平仓
+	CloseReasonStopLoss   = "stop_loss"   // 交易所止损单触发
+	CloseReasonTakeProfit = "take_profit" // 交易所止盈单触发
+	CloseReasonPanic      = "panic"       // 紧急平仓
+	CloseReasonSeasonEnd  = "season_end"  // 赛季结束统一平仓
+	CloseReasonManual     = "manual"      // 操作员手动平仓
+)
+
+// Trade 一笔交易从开仓决策到最终平仓的完整生命周期（加仓合并到同一笔，部分平仓累计到同一笔）
+type Trade struct {
+	ID             int64     `json:"id"`
+	Symbol         string    `json:"symbol"`
+	Side           string    `json:"side"`   // long / short
+	Status         string    `json:"status"` // open / closed
+	OpenTime       time.Time `json:"open_time"`
+	OpenPrice      float64   `json:"open_price"`    // 加仓后为加权均价
+	Quantity       float64   `json:"quantity"`      // 累计开仓数量
+	OpenQuantity   float64   `json:"open_quantity"` // 尚未平仓的数量
+	Leverage       int       `json:"leverage"`
+	AIModel        string    `json:"ai_model"`   // 给出开仓决策的AI模型
+	Regime         string    `json:"regime"`     // 开仓时的市场状态（功能上线前的交易为空）
+	Confidence     int       `json:"confidence"` // 开仓决策的信心度
+	Reasoning      string    `json:"reasoning"`  // 开仓理由
+	StopLoss       float64   `json:"stop_loss"`
+	TakeProfit     float64   `json:"take_profit"`
+	CloseTime      time.Time `json:"close_time"`
+	ClosePrice     float64   `json:"close_price"`     // 平仓均价
+	CloseReason    string    `json:"close_reason"`    // ai / stop_loss / take_profit / panic / season_end / manual
+	CloseReasoning string    `json:"close_reasoning"` // AI平仓理由（止损止盈触发时为空）
+	GrossPnL       float64   `json:"gross_pnl"`       // 已平仓部分的毛盈亏
+	Fees           float64   `json:"fees"`
+	Funding        float64   `json:"funding"`
+	PnL            float64   `json:"pnl"`     // 已实现净盈亏（扣除手续费和资金费）
+	PnLPct         float64   `json:"pnl_pct"` // 净盈亏相对保证金的百分比
+}
+
+// TradeEntry 开仓或加仓成交
+type TradeEntry struct {
+	Symbol     string
+	Side       string
+	Time       time.Time
+	Price      float64
+	Quantity   float64
+	Leverage   int
+	AIModel    string
+	Regime     string
+	Confidence int
+	Reasoning  string
+	StopLoss   float64
+	TakeProfit float64
+	Fee        float64
+	Setup      []float64 // 开仓时的形态向量（为空时不保存，加仓时忽略）
+}
+
+// TradeExit 平仓成交
+type TradeExit struct {
+	Symbol    string
+	Side      string
+	Time      time.Time
+	Price     float64
+	Quantity  float64 // 平仓数量（0或不小于剩余数量表示全部平仓）
+	Fee       float64
+	Funding   float64
+	Reason    string // CloseReasonAI / CloseReasonStopLoss / CloseReasonTakeProfit / CloseReasonPanic
+	Reasoning string
+}
+
+// CalibrationBucket 某个AI模型在某个信心度区间内已平仓交易的结果（用于信心度-胜率校准图）
+type CalibrationBucket struct {
+	AIModel       string  `json:"ai_model"`
+	MinConfidence int     `json:"min_confidence"`
+	MaxConfidence int     `json:"max_confidence"`
+	Trades        int     `json:"trades"`
+	Wins          int     `json:"wins"`
+	WinRate       float64 `json:"win_rate"`       // %
+	AvgConfidence float64 `json:"avg_confidence"` // 组内平均信心度，与胜率对比即可看出是否过度自信
+	StopLosses    int     `json:"stop_losses"`
+	AvgPnL        float64 `json:"avg_pnl"`
+	AvgPnLPct     float64 `json:"avg_pnl_pct"`
+	TotalPnL      float64 `json:"total_pnl"`
+}
+
+const tradeColumns = `id, symbol, side, status, timestamp, open_price, quantity, open_quantity, leverage, ai_model, regime, confidence, reasoning,
+	stop_loss, take_profit, close_time, close_price, close_reason, close_reasoning, gross_pnl, fees, funding`
+
+// scanTrade 读取一行交易记录并计算净盈亏
+func scanTrade(row interface{ Scan(...interface{}) error }) (*Trade, error) {
+	var t Trade
+	var openMs, closeMs int64
+	if err := row.Scan(&t.ID, &t.Symbol, &t.Side, &t.Status, &openMs, &t.OpenPrice, &t.Quantity, &t.OpenQuantity,
+		&t.Leverage, &t.AIModel, &t.Regime, &t.Confidence, &t.Reasoning, &t.StopLoss, &t.TakeProfit, &closeMs, &t.ClosePrice,
+		&t.CloseReason, &t.CloseReasoning, &t.GrossPnL, &t.Fees, &t.Funding); err != nil {
+		return nil, err
+	}
+	t.OpenTime = time.UnixMilli(openMs)
+	if closeMs > 0 {
+		t.CloseTime = time.UnixMilli(closeMs)
+	}
+	t.PnL = t.GrossPnL - t.Fees - t.Funding
+	if margin := t.Quantity * t.OpenPrice / float64(max(t.Leverage, 1)); margin > 0 {
+		t.PnLPct = t.PnL / margin * 100
+	}
+	return &t, nil
+}
+
+// openTrade 查询币种方向上未平仓的交易（没有时返回nil）
+func (l *DecisionLogger) openTrade(q interface {
+	QueryRow(string, ...interface{}) *sql.Row
+}, symbol, side string) (*Trade, error) {
+	t, err := scanTrade(q.QueryRow(`SELECT `+tradeColumns+` FROM trades
+		WHERE trader_id = ? AND symbol = ? AND side = ? AND status = 'open' ORDER BY id DESC LIMIT 1`,
+		l.traderID, symbol, side))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询未平仓交易失败: %w", err)
+	}
+	return t, nil
+}
+
+// RecordTradeEntry 记录开仓成交（已有同方向未平仓交易时视为加仓，合并为加权均价）
+func (l *DecisionLogger) RecordTradeEntry(e TradeEntry) error {
+	tx, err := l.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	existing, err := l.openTrade(tx, e.Symbol, e.Side)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		var res sql.Result
+		res, err = tx.Exec(`INSERT INTO trades (trader_id, symbol, side, status, timestamp, open_price, quantity, open_quantity,
+			leverage, ai_model, regime, confidence, reasoning, stop_loss, take_profit, fees)
+			VALUES (?, ?, ?, 'open', ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			l.traderID, e.Symbol, e.Side, e.Time.UnixMilli(), e.Price, e.Quantity, e.Quantity,
+			e.Leverage, e.AIModel, e.Regime, e.Confidence, e.Reasoning, e.StopLoss, e.TakeProfit, e.Fee)
+		if err == nil && len(e.Setup) > 0 {
+			err = l.recordSetup(tx, res, e)
+		}
+	} else {
+		openPrice := existing.OpenPrice
+		if total := existing.OpenQuantity + e.Quantity; total > 0 {
+			openPrice = (existing.OpenQuantity*existing.OpenPrice + e.Quantity*e.Price) / total
+		}
+		// 加仓的止损止盈覆盖原值（没有给出时保留）
+		_, err = tx.Exec(`UPDATE trades SET open_price = ?, quantity = quantity + ?, open_quantity = open_quantity + ?, fees = fees + ?,
+			stop_loss = CASE WHEN ? > 0 THEN ? ELSE stop_loss END, take_profit = CASE WHEN ? > 0 THEN ? ELSE take_profit END
+			WHERE id = ?`,
+			openPrice, e.Quantity, e.Quantity, e.Fee, e.StopLoss, e.StopLoss, e.TakeProfit, e.TakeProfit, existing.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("写入交易记录失败: %w", err)
+	}
+	return tx.Commit()
+}
+
+// RecordTradeExit 记录平仓成交，全部平仓时交易结束（没有对应的未平仓交易时忽略，如功能上线前开的仓）
+func (l *DecisionLogger) RecordTradeExit(x TradeExit) error {
+	tx, err := l.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	t, err := l.openTrade(tx, x.Symbol, x.Side)
+	if err != nil || t == nil {
+		return err
+	}
+
+	quantity := x.Quantity
+	if quantity <= 0 || quantity >= t.OpenQuantity {
+		quantity = t.OpenQuantity
+	}
+	gross := quantity * (x.Price - t.OpenPrice)
+	if t.Side == "short" {
+		gross = -gross
+	}
+	closed := t.Quantity - t.OpenQuantity
+	closePrice := x.Price
+	if closed+quantity > 0 {
+		closePrice = (closed*t.ClosePrice + quantity*x.Price) / (closed + quantity)
+	}
+	status := "open"
+	if quantity >= t.OpenQuantity {
+		status = "closed"
+	}
+
+	if _, err := tx.Exec(`UPDATE trades SET status = ?, open_quantity = open_quantity - ?, close_time = ?, close_price = ?,
+		close_reason = ?, close_reasoning = ?, gross_pnl = gross_pnl + ?, fees = fees + ?, funding = funding + ?
+		WHERE id = ?`,
+		status, quantity, x.Time.UnixMilli(), closePrice, x.Reason, x.Reasoning, gross, x.Fee, x.Funding, t.ID); err != nil {
+		return fmt.Errorf("写入交易记录失败: %w", err)
+	}
+	if status == "closed" {
+		closed, err := scanTrade(tx.QueryRow(`SELECT `+tradeColumns+` FROM trades WHERE id = ?`, t.ID))
+		if err != nil {
+			return fmt.Errorf("读取交易记录失败: %w", err)
+		}
+		if err := l.recordLesson(tx, closed); err != nil {
+			return err
+		}
+		if err := closeSetup(tx, closed); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// QueryTrades 按开仓时间范围分页查询交易（status为空时不过滤，返回按开仓时间正序排列的当前页，以及满足条件的总条数）
+func (l *DecisionLogger) QueryTrades(f RecordFilter, status string) ([]Trade, int, error) {
+	where, args := f.where(l.traderID)
+	if status != "" {
+		where += " AND status = ?"
+		args = append(args, status)
+	}
+
+	var total int
+	if err := l.db.QueryRow(`SELECT COUNT(*) FROM trades WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("查询交易记录失败: %w", err)
+	}
+
+	page, pageArgs := f.page()
+	rows, err := l.db.Query(`SELECT `+tradeColumns+` FROM trades WHERE `+where+` ORDER BY timestamp DESC, id DESC`+page,
+		append(args, pageArgs...)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询交易记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	var trades []Trade
+	for rows.Next() {
+		t, err := scanTrade(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("读取交易记录失败: %w", err)
+		}
+		trades = append(trades, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("读取交易记录失败: %w", err)
+	}
+
+	for i, j := 0, len(trades)-1; i < j; i, j = i+1, j-1 {
+		trades[i], trades[j] = trades[j], trades[i]
+	}
+	return trades, total, nil
+}
+
+// CalibrationBounds 默认的信心度分组下限（<70、70-79、80-89、90+）
+var CalibrationBounds = []int{0, 70, 80, 90}
+
+// ConfidenceCalibration 按AI模型和开仓信心度分组统计该trader已平仓交易的胜率和盈亏（bounds为各组信心度下限，升序）
+func (l *DecisionLogger) ConfidenceCalibration(bounds []int) ([]CalibrationBucket, error) {
+	return queryCalibration(l.db, []string{l.traderID}, bounds)
+}
+
+// ModelCalibration 按AI模型和开仓信心度分组统计traderIDs（nil表示所有trader）已平仓交易的胜率和盈亏
+func ModelCalibration(bounds []int, traderIDs []string) ([]CalibrationBucket, error) {
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return nil, err
+	}
+	return queryCalibration(db, traderIDs, bounds)
+}
+
+// queryCalibration 信心度校准统计（traderIDs为nil时统计所有trader）
+func queryCalibration(db *sql.DB, traderIDs []string, bounds []int) ([]CalibrationBucket, error) {
+	if traderIDs != nil && len(traderIDs) == 0 {
+		return nil, nil
+	}
+	if len(bounds) == 0 {
+		bounds = CalibrationBounds
+	}
+	// 信心度落在哪个分组（从高到低匹配下限）
+	var bucketExpr strings.Builder
+	bucketExpr.WriteString("CASE")
+	for i := len(bounds) - 1; i >= 0; i-- {
+		fmt.Fprintf(&bucketExpr, " WHEN confidence >= %d THEN %d", bounds[i], i)
+	}
+	bucketExpr.WriteString(" ELSE -1 END")
+
+	where, args := "status = 'closed' AND quantity > 0 AND open_price > 0", []interface{}{CloseReasonStopLoss}
+	if traderIDs != nil {
+		where += " AND trader_id IN (?" + strings.Repeat(", ?", len(traderIDs)-1) + ")"
+		for _, id := range traderIDs {
+			args = append(args, id)
+		}
+	}
+	rows, err := db.Query(`SELECT ai_model, `+bucketExpr.String()+` AS bucket, COUNT(*),
+			SUM(CASE WHEN gross_pnl - fees - funding > 0 THEN 1 ELSE 0 END),
+			SUM(CASE WHEN close_reason = ? THEN 1 ELSE 0 END),
+			AVG(confidence),
+			AVG(gross_pnl - fees - funding),
+			AVG((gross_pnl - fees - funding) / (quantity * open_price / MAX(leverage, 1)) * 100),
+			SUM(gross_pnl - fees - funding)
+		FROM trades
+		WHERE `+where+`
+		GROUP BY ai_model, bucket HAVING bucket >= 0 ORDER BY ai_model, bucket`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询信心度校准失败: %w", err)
+	}
+	defer rows.Close()
+
+	var buckets []CalibrationBucket
+	for rows.Next() {
+		var b CalibrationBucket
+		var bucket int
+		if err := rows.Scan(&b.AIModel, &bucket, &b.Trades, &b.Wins, &b.StopLosses, &b.AvgConfidence,
+			&b.AvgPnL, &b.AvgPnLPct, &b.TotalPnL); err != nil {
+			return nil, fmt.Errorf("查询信心度校准失败: %w", err)
+		}
+		b.MinConfidence = bounds[bucket]
+		b.MaxConfidence = 100
+		if bucket+1 < len(bounds) {
+			b.MaxConfidence = bounds[bucket+1] - 1
+		}
+		if b.Trades > 0 {
+			b.WinRate = float64(b.Wins) / float64(b.Trades) * 100
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+// RegimeStats 某个AI模型在某种市场状态下开仓的已平仓交易结果
+type RegimeStats struct {
+	AIModel       string  `json:"ai_model"`
+	Regime        string  `json:"regime"` // 开仓时的市场状态（功能上线前的交易为 "unknown"）
+	Trades        int     `json:"trades"`
+	Wins          int     `json:"wins"`
+	WinRate       float64 `json:"win_rate"` // %
+	AvgConfidence float64 `json:"avg_confidence"`
+	AvgPnLPct     float64 `json:"avg_pnl_pct"`
+	TotalPnL      float64 `json:"total_pnl"`
+}
+
+// ModelRegimeStats 按AI模型和开仓时的市场状态汇总已平仓交易（traderIDs为nil时统计所有trader）
+func ModelRegimeStats(traderIDs []string) ([]RegimeStats, error) {
+	if traderIDs != nil && len(traderIDs) == 0 {
+		return nil, nil
+	}
+	db, err := openDB(defaultDBPath)
+	if err != nil {
+		return nil, err
+	}
+
+	where, args := "status = 'closed' AND quantity > 0 AND open_price > 0", []interface{}{}
+	if traderIDs != nil {
+		where += " AND trader_id IN (?" + strings.Repeat(", ?", len(traderIDs)-1) + ")"
+		for _, id := range traderIDs {
+			args = append(args, id)
+		}
+	}
+	rows, err := db.Query(`SELECT ai_model, CASE WHEN regime = '' THEN 'unknown' ELSE regime END AS r, COUNT(*),
+			SUM(CASE WHEN gross_pnl - fees - funding > 0 THEN 1 ELSE 0 END),
+			AVG(confidence),
+			AVG((gross_pnl - fees - funding) / (quantity * open_price / MAX(leverage, 1)) * 100),
+			SUM(gross_pnl - fees - funding)
+		FROM trades
+		WHERE `+where+`
+		GROUP BY ai_model, r ORDER BY ai_model, r`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询市场状态统计失败: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []RegimeStats
+	for rows.Next() {
+		var s RegimeStats
+		if err := rows.Scan(&s.AIModel, &s.Regime, &s.Trades, &s.Wins, &s.AvgConfidence, &s.AvgPnLPct, &s.TotalPnL); err != nil {
+			return nil, fmt.Errorf("查询市场状态统计失败: %w", err)
+		}
+		if s.Trades > 0 {
+			s.WinRate = float64(s.Wins) / float64(s.Trades) * 100
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
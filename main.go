@@ -8,8 +8,13 @@ import (
     "net"
     "net/http"
     "nofx/api"
+    "nofx/auth"
     "nofx/config"
+    "nofx/logger"
     "nofx/manager"
+    "nofx/market"
+    "nofx/mcp"
+    "nofx/news"
     "nofx/pool"
     "os"
     "os/signal"
@@ -20,6 +25,13 @@ import (
 )
 
 func main() {
+	// 回测模式：nofx backtest --from ... --to ... --symbols ...
+	if len(os.Args) > 1 && os.Args[1] == "backtest" {
+		log.SetOutput(os.Stdout)
+		runBacktest(os.Args[2:])
+		return
+	}
+
 	fmt.Println("╔════════════════════════════════════════════════════════════╗")
 	fmt.Println("║    🏆 AI模型交易竞赛系统 - Qwen vs DeepSeek               ║")
 	fmt.Println("╚════════════════════════════════════════════════════════════╝")
@@ -40,6 +52,12 @@ func main() {
         log.Fatalf("❌ 加载配置失败: %v", err)
     }
 
+    if err := logger.Setup(cfg.LogFormat, cfg.LogLevel); err != nil {
+        log.Fatalf("❌ 初始化日志失败: %v", err)
+    }
+    logger.SetDatabasePath(cfg.DatabasePath)
+    loadedCfg := *cfg // 配置热加载的比较基准（不含下面的环境变量覆盖）
+
     log.Printf("✓ 配置加载成功，共%d个trader参赛", len(cfg.Traders))
     fmt.Println()
 
@@ -81,8 +99,42 @@ func main() {
 		log.Printf("✓ 已配置OI Top API")
 	}
 
+	// AI提供商熔断参数（所有trader共用）
+	mcp.SetBreakerConfig(cfg.AIBreaker.FailureThreshold, cfg.AIBreaker.Cooldown())
+
+	// 加载合约交易规则（价格/数量步长、最小名义价值），之后每小时刷新
+	if err := market.LoadExchangeInfo(); err != nil {
+		log.Printf("⚠️  加载交易规则失败，将在首次使用时重试: %v", err)
+	} else {
+		log.Printf("✓ 已加载合约交易规则")
+	}
+	stopExchangeInfo := make(chan struct{})
+	go market.RunExchangeInfoRefresh(market.ExchangeInfoRefreshInterval, stopExchangeInfo)
+
+	// 启动WebSocket行情（断线时自动回退到REST）
+	if cfg.MarketDataSource == "websocket" {
+		market.StartFeed()
+		log.Printf("✓ 已启用WebSocket实时行情")
+	}
+
+	// 启动新闻源（未配置新闻源时仍可通过API推送新闻和宏观事件）
+	news.Start(cfg.News)
+	if cfg.News.CryptoPanicToken != "" || len(cfg.News.RSSFeeds) > 0 {
+		log.Printf("📰 已启用新闻源: CryptoPanic %v, RSS %d 个（每%d分钟抓取）",
+			cfg.News.CryptoPanicToken != "", len(cfg.News.RSSFeeds), cfg.News.PollMinutes)
+	}
+	if cfg.News.BlackoutBeforeMinutes > 0 || cfg.News.BlackoutAfterMinutes > 0 {
+		log.Printf("📅 重大事件前%d分钟至后%d分钟暂停开仓（已配置 %d 个事件）",
+			cfg.News.BlackoutBeforeMinutes, cfg.News.BlackoutAfterMinutes, len(cfg.News.Events))
+	}
+
 	// 创建TraderManager
 	traderManager := manager.NewTraderManager()
+	traderManager.SetPortfolioLimits(cfg.PortfolioLimits)
+	traderManager.SetTraderDefaults(cfg.CoinPoolAPIURL, cfg.MaxDailyLoss, cfg.MaxDrawdown, cfg.StopTradingMinutes, cfg.Leverage)
+	if err := traderManager.SetCompetition(cfg.Competition); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
 
 	// 添加所有启用的trader
 	enabledCount := 0
@@ -110,6 +162,12 @@ func main() {
 		}
 	}
 
+	// 恢复通过API克隆或从模板创建的trader
+	if n := traderManager.LoadSavedTraders(); n > 0 {
+		log.Printf("📦 已恢复 %d 个通过API创建的trader", n)
+		enabledCount += n
+	}
+
 	// 检查是否至少有一个启用的trader
 	if enabledCount == 0 {
 		log.Fatalf("❌ 没有启用的trader，请在config.json中设置至少一个trader的enabled=true")
@@ -141,7 +199,19 @@ func main() {
 	fmt.Println()
 
 	// 创建并启动API服务器
-	apiServer := api.NewServer(traderManager, cfg.APIServerPort)
+	var authCfg *api.AuthConfig
+	if !cfg.AdminMode {
+		users, err := auth.NewUserStore(cfg.UsersFile)
+		if err != nil {
+			log.Fatalf("❌ 加载用户文件失败: %v", err)
+		}
+		authCfg = &api.AuthConfig{
+			Users:         users,
+			JWTSecret:     []byte(cfg.JWTSecret),
+			AllowRegister: cfg.AllowRegister,
+		}
+	}
+	apiServer := api.NewServer(traderManager, cfg.APIServerPort, authCfg, cfg.APILimits)
 	go func() {
 		if err := apiServer.Start(); err != nil {
 			log.Printf("❌ API服务器错误: %v", err)
@@ -155,17 +225,87 @@ func main() {
 	// 启动所有trader
 	traderManager.StartAll()
 
+	// 定期保存排行榜快照（用于绘制名次变化）
+	if err := traderManager.SetLeaderboardMetric(cfg.LeaderboardMetric); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	stopSnapshots := make(chan struct{})
+	go traderManager.RunLeaderboardSnapshots(time.Duration(cfg.LeaderboardSnapshotMinutes)*time.Minute, stopSnapshots)
+
+	// 竞赛赛季：到期后自动平仓、保存最终排行榜、归档决策日志并以新的初始余额重新开始
+	stopCompetition := make(chan struct{})
+	if cfg.Competition.Enabled() {
+		log.Printf("🏁 已启用竞赛赛季: %s ~ %s（重复: %v）", cfg.Competition.Start, cfg.Competition.End, cfg.Competition.Repeat)
+		go traderManager.RunCompetition(stopCompetition)
+	} else {
+		// 未启用赛季时AI模型的ELO评分每周更新一次（启用赛季时在每个赛季结算时更新）
+		go traderManager.RunWeeklyRatings(stopCompetition)
+	}
+
+	// 配置热加载：修改config.json或发送SIGHUP后，币种池、全局风控参数和日志设置无需重启即可生效
+	stopWatcher := make(chan struct{})
+	watcher := config.NewWatcher(configFile, &loadedCfg, time.Duration(cfg.ConfigReloadSeconds)*time.Second,
+		func(newCfg *config.Config, changes []config.Change) {
+			applyConfigChanges(traderManager, newCfg, changes)
+		})
+	go watcher.Run(stopWatcher)
+
 	// 等待退出信号
 	<-sigChan
 	fmt.Println()
 	fmt.Println()
+	apiServer.SetDraining(true)
+	if cfg.DrainSeconds > 0 {
+		log.Printf("📛 收到退出信号，等待%d秒摘除流量...", cfg.DrainSeconds)
+		time.Sleep(time.Duration(cfg.DrainSeconds) * time.Second)
+	}
 	log.Println("📛 收到退出信号，正在停止所有trader...")
-	traderManager.StopAll()
+	close(stopSnapshots)
+	close(stopCompetition)
+	close(stopExchangeInfo)
+	close(stopWatcher)
+	traderManager.Shutdown(time.Duration(cfg.ShutdownTimeoutSeconds)*time.Second, cfg.CancelOrdersOnShutdown)
+	market.StopFeed()
+	news.Stop()
+	logger.CloseDatabases()
 
 	fmt.Println()
 	fmt.Println("👋 感谢使用AI交易竞赛系统！")
 }
 
+// applyConfigChanges 应用热加载的配置变化（同一类配置只应用一次）
+func applyConfigChanges(tm *manager.TraderManager, cfg *config.Config, changes []config.Change) {
+	changed := make(map[string]bool, len(changes))
+	for _, c := range changes {
+		changed[c.Key] = true
+	}
+
+	if changed["default_coins"] {
+		pool.SetDefaultCoins(cfg.DefaultCoins)
+	}
+	if changed["use_default_coins"] {
+		pool.SetUseDefaultCoins(cfg.UseDefaultCoins)
+	}
+	if changed["coin_pool_api_url"] {
+		pool.SetCoinPoolAPI(cfg.CoinPoolAPIURL)
+	}
+	if changed["oi_top_api_url"] {
+		pool.SetOITopAPI(cfg.OITopAPIURL)
+	}
+	if changed["max_daily_loss"] || changed["max_drawdown"] || changed["stop_trading_minutes"] {
+		tm.ApplyGlobalRiskLimits(cfg.MaxDailyLoss, cfg.MaxDrawdown, cfg.StopTradingMinutes)
+	}
+	if changed["log_format"] || changed["log_level"] {
+		if err := logger.Setup(cfg.LogFormat, cfg.LogLevel); err != nil {
+			log.Printf("⚠️  应用日志设置失败: %v", err)
+		}
+	}
+	if changed["ai_breaker"] {
+		mcp.SetBreakerConfig(cfg.AIBreaker.FailureThreshold, cfg.AIBreaker.Cooldown())
+	}
+	log.Printf("✓ 已应用%d项配置变化", len(changes))
+}
+
 // detectPublicIP 尝试通过多个公共服务获取当前主机的出口 IP。
 // 返回空字符串表示未获取到。
 func detectPublicIP() string {
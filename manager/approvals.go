@@ -0,0 +1,31 @@
+package manager
+
+import (
+	"fmt"
+	"nofx/trader"
+	"sort"
+)
+
+// ListApprovals 汇总指定trader的开仓审批（status为空表示全部，按创建时间从新到旧）
+func (tm *TraderManager) ListApprovals(ids []string, status string) []trader.Approval {
+	result := make([]trader.Approval, 0)
+	for _, id := range ids {
+		t, err := tm.GetTrader(id)
+		if err != nil {
+			continue
+		}
+		result = append(result, t.GetApprovals(status)...)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.After(result[j].CreatedAt) })
+	return result
+}
+
+// ApprovalTrader 查找审批所属的trader
+func (tm *TraderManager) ApprovalTrader(approvalID string) (*trader.AutoTrader, error) {
+	for _, t := range tm.GetAllTraders() {
+		if t.HasApproval(approvalID) {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("审批 '%s' 不存在", approvalID)
+}
@@ -0,0 +1,212 @@
+package manager
+
+import (
+	"fmt"
+	"nofx/config"
+	"nofx/logger"
+	"sort"
+	"time"
+)
+
+// seasonStopTimeout 赛季结算时等待trader当前周期结束的最长时间
+const seasonStopTimeout = 2 * time.Minute
+
+// seasonCheckInterval 检查赛季是否到期的间隔
+const seasonCheckInterval = time.Minute
+
+// SeasonInfo 进行中的赛季
+type SeasonInfo struct {
+	Number    int       `json:"number"`
+	Name      string    `json:"name"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+// seasonSchedule 赛季安排：第一个赛季的起止时间，repeat时之后的赛季按相同时长顺延
+type seasonSchedule struct {
+	name   string
+	start  time.Time
+	end    time.Time
+	repeat bool
+}
+
+// bounds 第n个赛季（从1开始）的起止时间
+func (s *seasonSchedule) bounds(n int) (time.Time, time.Time) {
+	offset := time.Duration(n-1) * s.end.Sub(s.start)
+	return s.start.Add(offset), s.end.Add(offset)
+}
+
+// SetCompetition 设置赛季安排（未启用时不做任何事）
+func (tm *TraderManager) SetCompetition(c config.Competition) error {
+	if !c.Enabled() {
+		return nil
+	}
+	start, end, err := c.Bounds()
+	if err != nil {
+		return err
+	}
+
+	tm.seasonMu.Lock()
+	tm.seasons = &seasonSchedule{name: c.Name, start: start, end: end, repeat: c.Repeat}
+	tm.seasonMu.Unlock()
+	return nil
+}
+
+// CurrentSeason 进行中的赛季（未启用赛季或不重复的赛季已结束时返回false）
+func (tm *TraderManager) CurrentSeason() (*SeasonInfo, bool, error) {
+	tm.seasonMu.Lock()
+	defer tm.seasonMu.Unlock()
+	return tm.currentSeason()
+}
+
+// currentSeason 进行中的赛季（调用方持有seasonMu）
+func (tm *TraderManager) currentSeason() (*SeasonInfo, bool, error) {
+	s := tm.seasons
+	if s == nil {
+		return nil, false, nil
+	}
+	last, found, err := logger.LastSeason()
+	if err != nil {
+		return nil, false, err
+	}
+
+	n := 1
+	if found {
+		// 停机期间错过的赛季合并到一次结算：从上次结算之后才结束的赛季继续
+		n = last.Number + 1
+		for {
+			if _, end := s.bounds(n); end.After(last.EndedAt) {
+				break
+			}
+			n++
+		}
+	}
+	if n > 1 && !s.repeat {
+		return nil, false, nil
+	}
+
+	start, end := s.bounds(n)
+	return &SeasonInfo{Number: n, Name: fmt.Sprintf("%s %d", s.name, n), StartTime: start, EndTime: end}, true, nil
+}
+
+// RunCompetition 定期检查赛季是否到期并自动结算，直到stop关闭或不重复的赛季已结束
+func (tm *TraderManager) RunCompetition(stop <-chan struct{}) {
+	ticker := time.NewTicker(seasonCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		season, ok, err := tm.CurrentSeason()
+		switch {
+		case err != nil:
+			log.Printf("⚠️  读取赛季状态失败: %v", err)
+		case !ok:
+			log.Printf("🏁 赛季已全部结束，不再自动结算")
+			return
+		case !time.Now().Before(season.EndTime):
+			if _, err := tm.EndSeason(); err != nil {
+				log.Printf("❌ 赛季结算失败: %v", err)
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// EndSeason 结算进行中的赛季（到期时自动调用，也可以提前手动结算）：
+// 停止所有trader并平仓，保存最终排行榜，归档并清空决策日志，以新的初始余额重置后重新启动原先运行中的trader
+// 平仓失败的trader不会重置，失败明细记录在赛季结果中
+func (tm *TraderManager) EndSeason() (*logger.Season, error) {
+	tm.seasonMu.Lock()
+	defer tm.seasonMu.Unlock()
+
+	current, ok, err := tm.currentSeason()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("没有进行中的赛季")
+	}
+	log.Printf("🏁 %s 结算开始", current.Name)
+
+	traders := tm.GetAllTraders()
+	ids := make([]string, 0, len(traders))
+	running := make(map[string]bool)
+	for id, t := range traders {
+		ids = append(ids, id)
+		running[id] = t.IsRunning()
+	}
+	sort.Strings(ids)
+	tm.Shutdown(seasonStopTimeout, false)
+
+	season := logger.Season{
+		Number:    current.Number,
+		Name:      current.Name,
+		StartTime: current.StartTime,
+		EndTime:   current.EndTime,
+		EndedAt:   time.Now(),
+	}
+
+	var flattened []string
+	for _, id := range ids {
+		if _, err := traders[id].FlattenForSeasonEnd(); err != nil {
+			season.Errors = append(season.Errors, fmt.Sprintf("%s: %v", id, err))
+			continue
+		}
+		flattened = append(flattened, id)
+	}
+
+	// 最终排行榜在平仓后计算（已实现盈亏包含平仓手续费），同时写入排行榜快照便于在归档中查看
+	leaderboard, err := tm.GetLeaderboard("")
+	if err != nil {
+		season.Errors = append(season.Errors, fmt.Sprintf("计算排行榜失败: %v", err))
+	}
+	season.Leaderboard = leaderboard
+	if _, err := tm.UpdateRatings(current.Name, current.StartTime, season.EndedAt); err != nil {
+		season.Errors = append(season.Errors, fmt.Sprintf("更新ELO评分失败: %v", err))
+	}
+	if len(leaderboard) > 0 {
+		if err := logger.SaveLeaderboardSnapshot(season.EndedAt, leaderboard); err != nil {
+			log.Printf("⚠️  保存排行榜快照失败: %v", err)
+		}
+	}
+
+	// 归档失败时保留数据库中的记录，trader也不重置（新赛季的收益率会接着上个赛季计算）
+	season.Archive, err = logger.ArchiveSeason(current.Number)
+	if err != nil {
+		season.Errors = append(season.Errors, err.Error())
+		flattened = nil
+	}
+	reset := 0
+	for _, id := range flattened {
+		t := traders[id]
+		if _, err := t.ResetSeason(); err != nil {
+			season.Errors = append(season.Errors, fmt.Sprintf("%s: %v", id, err))
+			continue
+		}
+		if err := logger.SaveTraderSetting(id, profileSetting, t.GetProfile()); err != nil {
+			season.Errors = append(season.Errors, fmt.Sprintf("%s: %v", id, err))
+			continue
+		}
+		reset++
+	}
+
+	if err := logger.SaveSeason(season); err != nil {
+		log.Printf("❌ %v", err)
+	}
+
+	for _, id := range ids {
+		if running[id] {
+			if err := tm.StartTrader(id); err != nil {
+				log.Printf("⚠️  重新启动 %s 失败: %v", id, err)
+			}
+		}
+	}
+
+	log.Printf("🏁 %s 结算完成: %d 个trader已重置，失败 %d 项，决策日志已归档到 %s",
+		current.Name, reset, len(season.Errors), season.Archive)
+	return &season, nil
+}
@@ -0,0 +1,130 @@
+package manager
+
+import (
+	"fmt"
+	"math"
+	"nofx/config"
+	"nofx/exchange"
+	"nofx/trader"
+	"sync"
+)
+
+// exposureGuard 一个交易所账户的组合风控：汇总共用该账户的所有trader的持仓和未成交限价单，
+// 开仓后单币种名义价值或合计保证金超过上限时拒绝（同一账户的开仓串行执行，避免并发开仓同时通过校验）
+type exposureGuard struct {
+	tm      *TraderManager
+	account string
+	limits  config.PortfolioLimits
+	mu      sync.Mutex // 从校验到下单完成期间持有
+}
+
+// SetPortfolioLimits 设置组合风控上限（在添加trader之前调用）
+func (tm *TraderManager) SetPortfolioLimits(limits config.PortfolioLimits) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.portfolioLimits = limits
+}
+
+// exposureGuardFor 获取trader所在账户的组合风控（调用方持有tm.mu写锁，未设置组合限制时返回nil）
+func (tm *TraderManager) exposureGuardFor(cfg config.TraderConfig) *exposureGuard {
+	if tm.portfolioLimits.IsEmpty() {
+		return nil
+	}
+	// 同一交易所、同一账户（由交易所定义，如API密钥或钱包地址）的trader共用组合风控，模拟盘每个trader独立
+	account := exchange.AccountKey(cfg.Exchange, cfg.ExchangeConfig())
+	tm.traderAccounts[cfg.ID] = account
+	if g, ok := tm.exposureGuards[account]; ok {
+		return g
+	}
+	g := &exposureGuard{tm: tm, account: account, limits: tm.portfolioLimits}
+	tm.exposureGuards[account] = g
+	return g
+}
+
+// tradersOnAccount 共用该账户的trader
+func (g *exposureGuard) tradersOnAccount() []*trader.AutoTrader {
+	g.tm.mu.RLock()
+	defer g.tm.mu.RUnlock()
+
+	var traders []*trader.AutoTrader
+	for id, account := range g.tm.traderAccounts {
+		if account != g.account {
+			continue
+		}
+		if t, ok := g.tm.traders[id]; ok {
+			traders = append(traders, t)
+		}
+	}
+	return traders
+}
+
+// Reserve 实现trader.ExposureGuard
+func (g *exposureGuard) Reserve(req trader.ExposureRequest) (func(), error) {
+	g.mu.Lock()
+	if err := g.check(req); err != nil {
+		g.mu.Unlock()
+		return nil, err
+	}
+	return g.mu.Unlock, nil
+}
+
+// check 按账户当前持仓 + 所有trader的未成交限价单 + 本次开仓校验组合上限
+func (g *exposureGuard) check(req trader.ExposureRequest) error {
+	traders := g.tradersOnAccount()
+	var requester *trader.AutoTrader
+	for _, t := range traders {
+		if t.GetID() == req.TraderID {
+			requester = t
+		}
+	}
+	if requester == nil {
+		return nil
+	}
+
+	// 共用账户时任一trader查询到的都是整个账户的持仓
+	account, err := requester.GetAccountInfo()
+	if err != nil {
+		return fmt.Errorf("组合风控: %w", err)
+	}
+	positions, err := requester.GetPositions()
+	if err != nil {
+		return fmt.Errorf("组合风控: %w", err)
+	}
+
+	symbolNotional := 0.0
+	for _, pos := range positions {
+		if pos["symbol"] != req.Symbol {
+			continue
+		}
+		quantity, _ := pos["quantity"].(float64)
+		markPrice, _ := pos["mark_price"].(float64)
+		symbolNotional += math.Abs(quantity) * markPrice
+	}
+	totalMargin, _ := account["margin_used"].(float64)
+	equity, _ := account["total_equity"].(float64)
+
+	for _, t := range traders {
+		for _, po := range t.GetPendingOrders() {
+			notional := po.Quantity * po.LimitPrice
+			if po.Symbol == req.Symbol {
+				symbolNotional += notional
+			}
+			if po.Leverage > 0 {
+				totalMargin += notional / float64(po.Leverage)
+			}
+		}
+	}
+
+	if limit := g.limits.SymbolLimit(req.Symbol); limit > 0 && symbolNotional+req.Notional > limit {
+		return fmt.Errorf("❌ 组合风控: %s 所有trader合计名义价值 %.2f + %.2f USDT 将超过上限 %.2f USDT",
+			req.Symbol, symbolNotional, req.Notional, limit)
+	}
+	if g.limits.MaxTotalMarginPct > 0 && equity > 0 {
+		pct := (totalMargin + req.Margin) / equity * 100
+		if pct > g.limits.MaxTotalMarginPct {
+			return fmt.Errorf("❌ 组合风控: 开仓后账户合计保证金 %.2f USDT 占净值 %.1f%%，超过上限 %.1f%%",
+				totalMargin+req.Margin, pct, g.limits.MaxTotalMarginPct)
+		}
+	}
+	return nil
+}
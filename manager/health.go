@@ -0,0 +1,63 @@
+package manager
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	exchangeCheckTTL     = 30 * time.Second // 交易所连通性检查结果的缓存时间（就绪探针调用频繁，避免触发交易所限频）
+	exchangeCheckTimeout = 5 * time.Second  // 单个交易所的检查超时
+)
+
+// ExchangeStatus 一个trader的交易所连通性
+type ExchangeStatus struct {
+	TraderID  string    `json:"trader_id"`
+	Exchange  string    `json:"exchange"`
+	Reachable bool      `json:"reachable"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// exchangeHealth 交易所连通性检查的缓存
+type exchangeHealth struct {
+	mu        sync.Mutex
+	statuses  []ExchangeStatus
+	checkedAt time.Time
+}
+
+// CheckExchanges 检查各trader的交易所是否可访问（并发检查，结果缓存exchangeCheckTTL）
+func (tm *TraderManager) CheckExchanges() []ExchangeStatus {
+	tm.health.mu.Lock()
+	defer tm.health.mu.Unlock()
+	if time.Since(tm.health.checkedAt) < exchangeCheckTTL {
+		return tm.health.statuses
+	}
+
+	traders := tm.GetAllTraders()
+	statuses := make([]ExchangeStatus, 0, len(traders))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for id, t := range traders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status := ExchangeStatus{TraderID: id, Exchange: t.GetExchange(), Reachable: true, CheckedAt: time.Now()}
+			if err := t.CheckExchange(exchangeCheckTimeout); err != nil {
+				status.Reachable = false
+				status.Error = err.Error()
+				log.Printf("⚠️  trader '%s' 的交易所 %s 不可访问: %v", id, status.Exchange, err)
+			}
+			mu.Lock()
+			statuses = append(statuses, status)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].TraderID < statuses[j].TraderID })
+
+	tm.health.statuses = statuses
+	tm.health.checkedAt = time.Now()
+	return statuses
+}
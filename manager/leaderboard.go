@@ -0,0 +1,165 @@
+package manager
+
+import (
+	"fmt"
+	"nofx/logger"
+	"sort"
+	"time"
+)
+
+// 排行榜指标
+const (
+	MetricPnLPct      = "pnl_pct"      // 总收益率（越高越好）
+	MetricSharpe      = "sharpe"       // 夏普比率（越高越好）
+	MetricMaxDrawdown = "max_drawdown" // 最大回撤（越低越好）
+	MetricWinRate     = "win_rate"     // 胜率（越高越好）
+	MetricAlpha       = "alpha"        // 相对BTC买入持有的超额收益（越高越好）
+)
+
+// leaderboardLookback 计算夏普比率和胜率时回看的周期数（与AI表现分析一致）
+const leaderboardLookback = 100
+
+// ValidLeaderboardMetric 是否为支持的排行指标
+func ValidLeaderboardMetric(metric string) bool {
+	switch metric {
+	case MetricPnLPct, MetricSharpe, MetricMaxDrawdown, MetricWinRate, MetricAlpha:
+		return true
+	}
+	return false
+}
+
+// RankLeaderboard 按指标排序并填充名次（entries会被原地排序）
+func RankLeaderboard(entries []logger.LeaderboardEntry, metric string) {
+	value := func(e logger.LeaderboardEntry) float64 {
+		switch metric {
+		case MetricSharpe:
+			return e.SharpeRatio
+		case MetricMaxDrawdown:
+			return -e.MaxDrawdownPct // 回撤越小排名越高
+		case MetricWinRate:
+			return e.WinRate
+		case MetricAlpha:
+			return e.AlphaPct
+		default:
+			return e.TotalPnLPct
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		vi, vj := value(entries[i]), value(entries[j])
+		if vi != vj {
+			return vi > vj
+		}
+		return entries[i].TraderID < entries[j].TraderID
+	})
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+}
+
+// metricError 不支持的排行指标
+func metricError(metric string) error {
+	return fmt.Errorf("不支持的排行指标: %s（可选 '%s', '%s', '%s', '%s', '%s'）",
+		metric, MetricPnLPct, MetricSharpe, MetricMaxDrawdown, MetricWinRate, MetricAlpha)
+}
+
+// SetLeaderboardMetric 设置排行榜默认排序指标
+func (tm *TraderManager) SetLeaderboardMetric(metric string) error {
+	if !ValidLeaderboardMetric(metric) {
+		return metricError(metric)
+	}
+	tm.mu.Lock()
+	tm.leaderboardMetric = metric
+	tm.mu.Unlock()
+	return nil
+}
+
+// GetLeaderboard 获取当前排行榜（metric为空时使用默认指标）
+func (tm *TraderManager) GetLeaderboard(metric string) ([]logger.LeaderboardEntry, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	if metric == "" {
+		metric = tm.leaderboardMetric
+	}
+	if !ValidLeaderboardMetric(metric) {
+		return nil, metricError(metric)
+	}
+
+	entries := make([]logger.LeaderboardEntry, 0, len(tm.traders))
+	for _, t := range tm.traders {
+		account, err := t.GetAccountInfo()
+		if err != nil {
+			continue
+		}
+
+		entry := logger.LeaderboardEntry{
+			TraderID:   t.GetID(),
+			TraderName: t.GetName(),
+			AIModel:    t.GetAIModel(),
+		}
+		entry.TotalEquity, _ = account["total_equity"].(float64)
+		entry.TotalPnLPct, _ = account["total_pnl_pct"].(float64)
+
+		if performance, err := t.GetDecisionLogger().AnalyzePerformance(leaderboardLookback); err == nil {
+			entry.SharpeRatio = performance.SharpeRatio
+			entry.WinRate = performance.WinRate
+			entry.TotalTrades = performance.TotalTrades
+		}
+		if maxDD, err := t.GetDecisionLogger().MaxDrawdownPct(); err == nil {
+			entry.MaxDrawdownPct = maxDD
+		}
+		if benchmarks, err := t.GetBenchmarks(entry.TotalPnLPct); err != nil {
+			log.Printf("⚠️  [%s] 计算基准收益失败: %v", t.GetName(), err)
+		} else if len(benchmarks) > 0 {
+			entry.BenchmarkPnLPct = benchmarks[0].ReturnPct
+			entry.AlphaPct = benchmarks[0].AlphaPct
+		}
+
+		entries = append(entries, entry)
+	}
+
+	RankLeaderboard(entries, metric)
+	return entries, nil
+}
+
+// GetLeaderboardHistory 获取排行榜历史（每个快照按指定指标重新计算名次）
+func (tm *TraderManager) GetLeaderboardHistory(metric string, from, to time.Time) ([]logger.LeaderboardSnapshot, error) {
+	if metric == "" {
+		tm.mu.RLock()
+		metric = tm.leaderboardMetric
+		tm.mu.RUnlock()
+	}
+	if !ValidLeaderboardMetric(metric) {
+		return nil, metricError(metric)
+	}
+
+	snapshots, err := logger.GetLeaderboardHistory(from, to)
+	if err != nil {
+		return nil, err
+	}
+	for i := range snapshots {
+		RankLeaderboard(snapshots[i].Entries, metric)
+	}
+	return snapshots, nil
+}
+
+// RunLeaderboardSnapshots 定期保存排行榜快照，用于绘制名次变化曲线（阻塞运行，直到stop关闭）
+func (tm *TraderManager) RunLeaderboardSnapshots(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			entries, err := tm.GetLeaderboard("")
+			if err != nil || len(entries) == 0 {
+				continue
+			}
+			if err := logger.SaveLeaderboardSnapshot(time.Now(), entries); err != nil {
+				log.Printf("⚠️  保存排行榜快照失败: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package manager
+
+import (
+	"nofx/logger"
+	"nofx/trader"
+)
+
+// loadOwner trader的所属用户（通过API指定过的优先于配置文件）
+func loadOwner(traderID, configOwner string) string {
+	var owner string
+	found, err := logger.LoadTraderSetting(traderID, ownerSetting, &owner)
+	if err != nil {
+		log.Printf("⚠️  读取trader '%s' 的所属用户失败: %v", traderID, err)
+	}
+	if !found {
+		return configOwner
+	}
+	return owner
+}
+
+// GetOwner trader的所属用户名（为空表示共享）
+func (tm *TraderManager) GetOwner(id string) string {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.owners[id]
+}
+
+// SetOwner 修改trader的所属用户并持久化（owner为空表示所有用户共享，立即生效，重启后保留）
+func (tm *TraderManager) SetOwner(id, owner string) error {
+	if _, err := tm.GetTrader(id); err != nil {
+		return err
+	}
+	if err := logger.SaveTraderSetting(id, ownerSetting, owner); err != nil {
+		return err
+	}
+
+	tm.mu.Lock()
+	tm.owners[id] = owner
+	tm.mu.Unlock()
+	return nil
+}
+
+// CanAccess 用户能否查看和操作该trader（管理员可以访问所有trader，其他用户只能访问自己的和共享的trader）
+func (tm *TraderManager) CanAccess(id, username string, admin bool) bool {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	if _, exists := tm.traders[id]; !exists {
+		return false
+	}
+	owner := tm.owners[id]
+	return admin || owner == "" || owner == username
+}
+
+// AccessibleTraderIDs 用户能访问的trader ID列表
+func (tm *TraderManager) AccessibleTraderIDs(username string, admin bool) []string {
+	ids := make([]string, 0)
+	for _, id := range tm.GetTraderIDs() {
+		if tm.CanAccess(id, username, admin) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// PanicTraders 紧急平仓并锁定指定的trader
+func (tm *TraderManager) PanicTraders(ids []string) []*trader.PanicResult {
+	results := make([]*trader.PanicResult, 0, len(ids))
+	for _, id := range ids {
+		result, err := tm.PanicTrader(id)
+		if err != nil {
+			if result == nil {
+				result = &trader.PanicResult{TraderID: id}
+			}
+			result.Errors = append(result.Errors, err.Error())
+		}
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,168 @@
+package manager
+
+import (
+	"fmt"
+	"math"
+	"nofx/logger"
+	"sort"
+	"time"
+)
+
+// ELO评分参数
+const (
+	eloInitialRating = 1500.0 // 新模型的初始评分
+	eloK             = 32.0   // 每个周期评分变化的上限（与其余模型两两比较的平均值乘以K）
+	ratingWeek       = 7 * 24 * time.Hour
+	ratingDrawMargin = 0.01 // 风险调整后收益相差不超过该值视为平局
+)
+
+// ratingCheckInterval 未启用赛季时检查是否满一周的间隔
+const ratingCheckInterval = time.Hour
+
+// modelScore 一个评分周期内某个模型的风险调整后收益
+type modelScore struct {
+	score   float64
+	traders int
+}
+
+// periodScores 统计[from, to]内各AI模型的风险调整后收益：收益率 / max(最大回撤, 1%)，同一模型的多个trader取平均
+// 期间净值快照不足两个的trader不参与
+func (tm *TraderManager) periodScores(from, to time.Time) map[string]*modelScore {
+	scores := make(map[string]*modelScore)
+	for _, t := range tm.GetAllTraders() {
+		snapshots, _, err := t.GetDecisionLogger().QueryEquityHistory(logger.RecordFilter{From: from, To: to})
+		if err != nil || len(snapshots) < 2 || snapshots[0].TotalEquity <= 0 {
+			continue
+		}
+
+		first := snapshots[0].TotalEquity
+		returnPct := (snapshots[len(snapshots)-1].TotalEquity - first) / first * 100
+		peak, maxDD := 0.0, 0.0
+		for _, s := range snapshots {
+			peak = math.Max(peak, s.TotalEquity)
+			if peak > 0 {
+				maxDD = math.Max(maxDD, (peak-s.TotalEquity)/peak*100)
+			}
+		}
+
+		model := t.GetAIModel()
+		ms, ok := scores[model]
+		if !ok {
+			ms = &modelScore{}
+			scores[model] = ms
+		}
+		ms.score += returnPct / math.Max(maxDD, 1)
+		ms.traders++
+	}
+	for _, ms := range scores {
+		ms.score /= float64(ms.traders)
+	}
+	return scores
+}
+
+// UpdateRatings 按[from, to]内的风险调整后收益更新各AI模型的ELO评分（少于两个模型参与时不更新）
+// 每个模型与其余模型两两比较：收益高者胜，评分变化为 K × 平均(实际结果 − 预期胜率)
+func (tm *TraderManager) UpdateRatings(period string, from, to time.Time) ([]logger.RatingChange, error) {
+	scores := tm.periodScores(from, to)
+	if len(scores) < 2 {
+		log.Printf("ℹ️  %s 参与评分的模型不足两个，跳过ELO评分", period)
+		return nil, nil
+	}
+
+	ratings, err := logger.LoadModelRatings()
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]float64, len(scores))
+	for model := range scores {
+		current[model] = eloInitialRating
+	}
+	for _, r := range ratings {
+		if _, ok := current[r.Model]; ok {
+			current[r.Model] = r.Rating
+		}
+	}
+
+	models := make([]string, 0, len(scores))
+	for model := range scores {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+
+	now := time.Now()
+	changes := make([]logger.RatingChange, 0, len(models))
+	for _, model := range models {
+		c := logger.RatingChange{
+			Time:    now,
+			Period:  period,
+			Model:   model,
+			Traders: scores[model].traders,
+			Score:   scores[model].score,
+			Before:  current[model],
+		}
+		var delta float64
+		for _, other := range models {
+			if other == model {
+				continue
+			}
+			expected := 1 / (1 + math.Pow(10, (current[other]-current[model])/400))
+			actual := 0.5
+			switch diff := scores[model].score - scores[other].score; {
+			case diff > ratingDrawMargin:
+				actual = 1
+				c.Wins++
+			case diff < -ratingDrawMargin:
+				actual = 0
+				c.Losses++
+			default:
+				c.Draws++
+			}
+			delta += actual - expected
+		}
+		c.After = current[model] + eloK*delta/float64(len(models)-1)
+		changes = append(changes, c)
+	}
+
+	if err := logger.SaveRatingChanges(changes); err != nil {
+		return nil, err
+	}
+	for _, c := range changes {
+		log.Printf("📈 %s ELO评分: %s %.0f → %.0f（风险调整后收益 %.2f）", period, c.Model, c.Before, c.After, c.Score)
+	}
+	return changes, nil
+}
+
+// RunWeeklyRatings 未启用赛季时每周更新一次AI模型的ELO评分（直到stop关闭）
+func (tm *TraderManager) RunWeeklyRatings(stop <-chan struct{}) {
+	ticker := time.NewTicker(ratingCheckInterval)
+	defer ticker.Stop()
+
+	var skippedAt time.Time // 上次没有写入评分（参与的模型不足或出错）的时间，一周内不再重试
+	for {
+		now := time.Now()
+		last, found, err := logger.LastRatingTime()
+		switch {
+		case err != nil:
+			log.Printf("⚠️  %v", err)
+		case (!found || now.Sub(last) >= ratingWeek) && now.Sub(skippedAt) >= ratingWeek:
+			from := now.Add(-ratingWeek)
+			if found {
+				from = last
+			}
+			year, week := now.ISOWeek()
+			changes, err := tm.UpdateRatings(fmt.Sprintf("%d-W%02d", year, week), from, now)
+			if err != nil {
+				log.Printf("⚠️  更新ELO评分失败: %v", err)
+			}
+			if len(changes) == 0 {
+				skippedAt = now
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,426 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"nofx/config"
+	"nofx/logger"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// copiedSettings 克隆trader和保存模板时复制的运行时设置（名称、初始余额和扫描间隔并入配置，锁定状态和所属用户不复制）
+var copiedSettings = []string{riskLimitsSetting, strategySetting, promptSetting, symbolFilterSetting, scheduleSetting, exchangeSetting, dryRunSetting}
+
+// secretFieldHints 字段名包含这些词的配置项是密钥，不能通过overrides修改
+var secretFieldHints = []string{"key", "secret", "private", "passphrase", "mnemonic", "token", "password"}
+
+// destinationFieldHints 字段名包含这些词的配置项决定密钥或通知发往哪里（AI接口、节点、Webhook、Telegram会话），
+// 复制的trader沿用源trader的密钥，修改这些配置项会把密钥发到新地址，不能通过overrides修改
+var destinationFieldHints = []string{"_url", "chat_id"}
+
+// traderDefaults 通过API创建trader时使用的全局配置（与启动时添加trader的参数一致）
+type traderDefaults struct {
+	coinPoolURL        string
+	maxDailyLoss       float64
+	maxDrawdown        float64
+	stopTradingMinutes int
+	leverage           config.LeverageConfig
+}
+
+// TraderPreset trader的完整预设：配置文件中的配置（AI模型、交易所及密钥等）加上通过API修改过的运行时设置（prompt、风控等）
+type TraderPreset struct {
+	Config   config.TraderConfig        `json:"config"`
+	Settings map[string]json.RawMessage `json:"settings"` // trader_settings中的键 -> JSON值
+}
+
+// NewTraderRequest 克隆或从模板创建trader的参数
+type NewTraderRequest struct {
+	ID        string                     // 新trader的ID
+	Name      string                     // 为空时使用 "<原名称> (<ID>)"
+	Overrides map[string]json.RawMessage // 覆盖的配置项（如 ai_model、exchange、initial_balance），不能修改密钥；复制的运行时设置仍然优先
+	Owner     string                     // 所属用户名（为空表示共享）
+}
+
+// Template 命名的trader模板（对外展示的信息，不含密钥）
+type Template struct {
+	Name         string    `json:"name"`
+	SourceTrader string    `json:"source_trader"` // 保存模板时的trader
+	Owner        string    `json:"owner"`         // 为空表示所有用户共享
+	AIModel      string    `json:"ai_model"`
+	Exchange     string    `json:"exchange"`
+	Strategy     string    `json:"strategy"`
+	Settings     []string  `json:"settings"` // 模板包含的运行时设置
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// storedTemplate 数据库中保存的模板内容
+type storedTemplate struct {
+	SourceTrader string       `json:"source_trader"`
+	Owner        string       `json:"owner"`
+	Preset       TraderPreset `json:"preset"`
+}
+
+// SetTraderDefaults 设置通过API创建trader时使用的全局配置（与传给AddTrader的参数相同）
+func (tm *TraderManager) SetTraderDefaults(coinPoolURL string, maxDailyLoss, maxDrawdown float64, stopTradingMinutes int, leverage config.LeverageConfig) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.defaults = traderDefaults{
+		coinPoolURL:        coinPoolURL,
+		maxDailyLoss:       maxDailyLoss,
+		maxDrawdown:        maxDrawdown,
+		stopTradingMinutes: stopTradingMinutes,
+		leverage:           leverage,
+	}
+}
+
+// LoadSavedTraders 恢复通过API创建的trader（在添加配置文件中的trader之后调用，ID冲突时跳过），返回恢复的数量
+func (tm *TraderManager) LoadSavedTraders() int {
+	saved, err := logger.LoadSavedTraders()
+	if err != nil {
+		log.Printf("⚠️  读取通过API创建的trader失败: %v", err)
+		return 0
+	}
+
+	count := 0
+	for _, s := range saved {
+		var cfg config.TraderConfig
+		if err := json.Unmarshal(s.Config, &cfg); err != nil {
+			log.Printf("⚠️  trader '%s' 保存的配置无效，跳过: %v", s.TraderID, err)
+			continue
+		}
+		if _, err := tm.GetTrader(cfg.ID); err == nil {
+			log.Printf("⚠️  通过API创建的trader '%s' 与配置文件中的trader ID相同，跳过", cfg.ID)
+			continue
+		}
+		if err := tm.addDefaultTrader(cfg); err != nil {
+			log.Printf("⚠️  恢复trader '%s' 失败: %v", cfg.ID, err)
+			continue
+		}
+		tm.mu.Lock()
+		tm.savedTraders[cfg.ID] = true
+		tm.mu.Unlock()
+		count++
+	}
+	return count
+}
+
+// addDefaultTrader 使用全局配置添加trader
+func (tm *TraderManager) addDefaultTrader(cfg config.TraderConfig) error {
+	tm.mu.RLock()
+	d := tm.defaults
+	tm.mu.RUnlock()
+	return tm.AddTrader(cfg, d.coinPoolURL, d.maxDailyLoss, d.maxDrawdown, d.stopTradingMinutes, d.leverage)
+}
+
+// Preset 获取trader当前的完整预设（通过API修改过的名称、初始余额和扫描间隔并入配置）
+func (tm *TraderManager) Preset(id string) (TraderPreset, error) {
+	t, err := tm.GetTrader(id)
+	if err != nil {
+		return TraderPreset{}, err
+	}
+
+	tm.mu.RLock()
+	cfg := tm.traderConfigs[id]
+	tm.mu.RUnlock()
+
+	profile := t.GetProfile()
+	cfg.Name = profile.Name
+	cfg.InitialBalance = profile.InitialBalance
+	cfg.ScanIntervalMinutes = profile.ScanIntervalMinutes
+
+	settings := make(map[string]json.RawMessage)
+	for _, key := range copiedSettings {
+		var value json.RawMessage
+		found, err := logger.LoadTraderSetting(id, key, &value)
+		if err != nil {
+			return TraderPreset{}, err
+		}
+		if found {
+			settings[key] = value
+		}
+	}
+	return TraderPreset{Config: cfg, Settings: settings}, nil
+}
+
+// CloneTrader 以现有trader的配置和运行时设置创建新trader（不自动启动，重启后保留）
+func (tm *TraderManager) CloneTrader(sourceID string, req NewTraderRequest) (config.TraderConfig, error) {
+	preset, err := tm.Preset(sourceID)
+	if err != nil {
+		return config.TraderConfig{}, err
+	}
+	return tm.createTrader(preset, req)
+}
+
+// CreateFromTemplate 从命名模板创建新trader（不自动启动，重启后保留）
+func (tm *TraderManager) CreateFromTemplate(name string, req NewTraderRequest) (config.TraderConfig, error) {
+	stored, err := loadTemplate(name)
+	if err != nil {
+		return config.TraderConfig{}, err
+	}
+	return tm.createTrader(stored.Preset, req)
+}
+
+// createTrader 按预设创建trader：写入运行时设置和配置后添加（失败时清理已写入的数据）
+func (tm *TraderManager) createTrader(preset TraderPreset, req NewTraderRequest) (config.TraderConfig, error) {
+	if err := validateTraderID(req.ID); err != nil {
+		return config.TraderConfig{}, err
+	}
+	if _, err := tm.GetTrader(req.ID); err == nil {
+		return config.TraderConfig{}, fmt.Errorf("trader ID '%s' 已存在", req.ID)
+	}
+
+	cfg, err := applyOverrides(preset.Config, req.Overrides)
+	if err != nil {
+		return config.TraderConfig{}, err
+	}
+	if req.Name == "" {
+		req.Name = fmt.Sprintf("%s (%s)", preset.Config.Name, req.ID)
+	}
+	cfg.ID = req.ID
+	cfg.Name = req.Name
+	cfg.Owner = req.Owner
+	cfg.Enabled = true
+	if err := cfg.Validate(); err != nil {
+		return config.TraderConfig{}, err
+	}
+
+	// 复制的运行时设置与启动时一样优先于配置（新trader可以再通过对应的PUT接口修改）
+	for key, value := range preset.Settings {
+		if err := logger.SaveTraderSetting(cfg.ID, key, value); err != nil {
+			logger.DeleteTrader(cfg.ID)
+			return config.TraderConfig{}, err
+		}
+	}
+	if err := logger.SaveTrader(cfg.ID, cfg); err != nil {
+		logger.DeleteTrader(cfg.ID)
+		return config.TraderConfig{}, err
+	}
+	if err := tm.addDefaultTrader(cfg); err != nil {
+		logger.DeleteTrader(cfg.ID)
+		return config.TraderConfig{}, err
+	}
+
+	tm.mu.Lock()
+	tm.savedTraders[cfg.ID] = true
+	tm.mu.Unlock()
+	log.Printf("🧬 已创建trader '%s'（%s, %s）", cfg.ID, cfg.AIModel, cfg.Exchange)
+	return cfg, nil
+}
+
+// RemoveTrader 删除通过API创建的trader（须已停止；配置文件中的trader只能在配置文件中删除，历史记录保留）
+func (tm *TraderManager) RemoveTrader(id string) error {
+	t, err := tm.GetTrader(id)
+	if err != nil {
+		return err
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if !tm.savedTraders[id] {
+		return fmt.Errorf("trader '%s' 来自配置文件，只能在配置文件中删除", id)
+	}
+	if t.IsRunning() {
+		return fmt.Errorf("trader '%s' 正在运行，请先停止", id)
+	}
+	if err := logger.DeleteTrader(id); err != nil {
+		return err
+	}
+
+	delete(tm.traders, id)
+	delete(tm.traderConfigs, id)
+	delete(tm.traderAccounts, id)
+	delete(tm.owners, id)
+	delete(tm.savedTraders, id)
+	log.Printf("🗑️  已删除trader '%s'", id)
+	return nil
+}
+
+// IsSavedTrader trader是否通过API创建（可以通过API删除）
+func (tm *TraderManager) IsSavedTrader(id string) bool {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.savedTraders[id]
+}
+
+// SaveTemplate 把trader当前的配置和运行时设置保存为命名模板（同名模板被覆盖）
+func (tm *TraderManager) SaveTemplate(name, sourceID, owner string) (Template, error) {
+	if err := validateTraderID(name); err != nil {
+		return Template{}, fmt.Errorf("模板名称无效: %w", err)
+	}
+	preset, err := tm.Preset(sourceID)
+	if err != nil {
+		return Template{}, err
+	}
+	stored := storedTemplate{SourceTrader: sourceID, Owner: owner, Preset: preset}
+	if err := logger.SaveTraderTemplate(name, stored); err != nil {
+		return Template{}, err
+	}
+	return stored.summary(name, time.Now()), nil
+}
+
+// Templates 所有trader模板
+func (tm *TraderManager) Templates() ([]Template, error) {
+	rows, err := logger.LoadTraderTemplates()
+	if err != nil {
+		return nil, err
+	}
+	templates := make([]Template, 0, len(rows))
+	for _, row := range rows {
+		var stored storedTemplate
+		if err := json.Unmarshal(row.Value, &stored); err != nil {
+			log.Printf("⚠️  模板 '%s' 无效，跳过: %v", row.Name, err)
+			continue
+		}
+		templates = append(templates, stored.summary(row.Name, row.UpdatedAt))
+	}
+	return templates, nil
+}
+
+// GetTemplate 获取指定模板
+func (tm *TraderManager) GetTemplate(name string) (Template, error) {
+	templates, err := tm.Templates()
+	if err != nil {
+		return Template{}, err
+	}
+	for _, t := range templates {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	return Template{}, fmt.Errorf("模板 '%s' 不存在", name)
+}
+
+// DeleteTemplate 删除指定模板
+func (tm *TraderManager) DeleteTemplate(name string) error {
+	found, err := logger.DeleteTraderTemplate(name)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("模板 '%s' 不存在", name)
+	}
+	return nil
+}
+
+// loadTemplate 读取模板的完整内容
+func loadTemplate(name string) (storedTemplate, error) {
+	rows, err := logger.LoadTraderTemplates()
+	if err != nil {
+		return storedTemplate{}, err
+	}
+	for _, row := range rows {
+		if row.Name != name {
+			continue
+		}
+		var stored storedTemplate
+		if err := json.Unmarshal(row.Value, &stored); err != nil {
+			return storedTemplate{}, fmt.Errorf("模板 '%s' 无效: %w", name, err)
+		}
+		return stored, nil
+	}
+	return storedTemplate{}, fmt.Errorf("模板 '%s' 不存在", name)
+}
+
+// summary 模板对外展示的信息
+func (s storedTemplate) summary(name string, updatedAt time.Time) Template {
+	strategy := s.Preset.Config.Strategy
+	if raw, ok := s.Preset.Settings[strategySetting]; ok {
+		_ = json.Unmarshal(raw, &strategy)
+	}
+	settings := make([]string, 0, len(s.Preset.Settings))
+	for _, key := range copiedSettings {
+		if _, ok := s.Preset.Settings[key]; ok {
+			settings = append(settings, key)
+		}
+	}
+	return Template{
+		Name:         name,
+		SourceTrader: s.SourceTrader,
+		Owner:        s.Owner,
+		AIModel:      s.Preset.Config.AIModel,
+		Exchange:     s.Preset.Config.Exchange,
+		Strategy:     strategy,
+		Settings:     settings,
+		UpdatedAt:    updatedAt,
+	}
+}
+
+// applyOverrides 用overrides覆盖配置项（不能修改ID、所属用户、启用状态、密钥和接口地址，未知配置项报错）
+func applyOverrides(cfg config.TraderConfig, overrides map[string]json.RawMessage) (config.TraderConfig, error) {
+	if len(overrides) == 0 {
+		return cfg, nil
+	}
+
+	known := make(map[string]bool)
+	t := reflect.TypeOf(cfg)
+	for i := 0; i < t.NumField(); i++ {
+		known[strings.Split(t.Field(i).Tag.Get("json"), ",")[0]] = true
+	}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return cfg, fmt.Errorf("序列化trader配置失败: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return cfg, fmt.Errorf("序列化trader配置失败: %w", err)
+	}
+	for key, value := range overrides {
+		switch {
+		case !known[key]:
+			return cfg, fmt.Errorf("未知的配置项: %s", key)
+		case key == "id" || key == "owner" || key == "enabled" || key == "name":
+			return cfg, fmt.Errorf("配置项 %s 不能通过overrides修改", key)
+		case isSecretField(key):
+			return cfg, fmt.Errorf("密钥 %s 只能在配置文件中设置", key)
+		case isDestinationField(key):
+			return cfg, fmt.Errorf("配置项 %s 只能在配置文件中设置（新trader沿用源trader的密钥）", key)
+		}
+		fields[key] = value
+	}
+
+	data, _ = json.Marshal(fields)
+	var merged config.TraderConfig
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return cfg, fmt.Errorf("overrides格式错误: %w", err)
+	}
+	return merged, nil
+}
+
+// isSecretField 配置项是否为密钥
+func isSecretField(key string) bool {
+	for _, hint := range secretFieldHints {
+		if strings.Contains(key, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDestinationField 配置项是否为密钥或通知的发送地址
+func isDestinationField(key string) bool {
+	for _, hint := range destinationFieldHints {
+		if strings.Contains(key, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateTraderID 通过API创建的trader ID和模板名称只能包含字母、数字、-和_（用于环境变量名和文件名）
+func validateTraderID(id string) error {
+	if id == "" {
+		return fmt.Errorf("ID不能为空")
+	}
+	if len(id) > 64 {
+		return fmt.Errorf("ID不能超过64个字符")
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return fmt.Errorf("ID只能包含字母、数字、-和_")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package manager
+
+import (
+	"encoding/json"
+	"nofx/config"
+	"strings"
+	"testing"
+)
+
+func TestApplyOverrides(t *testing.T) {
+	source := config.TraderConfig{
+		ID:            "src",
+		Name:          "Source",
+		AIModel:       "openai",
+		Exchange:      "binance",
+		OpenAIKey:     "sk-source",
+		OpenAIBaseURL: "https://api.openai.com/v1",
+		BinanceAPIKey: "binance-key",
+		WebhookURL:    "https://hooks.example.com/nofx",
+	}
+
+	tests := []struct {
+		name      string
+		overrides string
+		wantErr   string
+		check     func(t *testing.T, cfg config.TraderConfig)
+	}{
+		{
+			name:      "no overrides keeps config",
+			overrides: `{}`,
+			check: func(t *testing.T, cfg config.TraderConfig) {
+				if cfg.OpenAIKey != "sk-source" || cfg.OpenAIBaseURL != "https://api.openai.com/v1" {
+					t.Errorf("config changed: %+v", cfg)
+				}
+			},
+		},
+		{
+			name:      "plain settings are applied",
+			overrides: `{"exchange":"paper","initial_balance":500,"max_net_exposure":2}`,
+			check: func(t *testing.T, cfg config.TraderConfig) {
+				if cfg.Exchange != "paper" || cfg.InitialBalance != 500 || cfg.MaxNetExposure != 2 {
+					t.Errorf("overrides not applied: exchange=%s initial_balance=%v max_net_exposure=%v", cfg.Exchange, cfg.InitialBalance, cfg.MaxNetExposure)
+				}
+				if cfg.OpenAIKey != "sk-source" {
+					t.Errorf("inherited key lost")
+				}
+			},
+		},
+		{name: "unknown key", overrides: `{"no_such_field":1}`, wantErr: "未知的配置项"},
+		{name: "id", overrides: `{"id":"other"}`, wantErr: "不能通过overrides修改"},
+		{name: "owner", overrides: `{"owner":"mallory"}`, wantErr: "不能通过overrides修改"},
+		{name: "enabled", overrides: `{"enabled":false}`, wantErr: "不能通过overrides修改"},
+		{name: "api key", overrides: `{"openai_key":"sk-other"}`, wantErr: "密钥"},
+		{name: "exchange secret", overrides: `{"binance_secret_key":"x"}`, wantErr: "密钥"},
+		{name: "mnemonic", overrides: `{"dydx_mnemonic":"x"}`, wantErr: "密钥"},
+		{name: "openai base url", overrides: `{"openai_base_url":"https://attacker.example/v1"}`, wantErr: "openai_base_url"},
+		{name: "custom api url", overrides: `{"custom_api_url":"https://attacker.example/v1"}`, wantErr: "custom_api_url"},
+		{name: "dydx node url", overrides: `{"dydx_node_url":"https://attacker.example"}`, wantErr: "dydx_node_url"},
+		{name: "webhook url", overrides: `{"webhook_url":"https://attacker.example"}`, wantErr: "webhook_url"},
+		{name: "telegram chat", overrides: `{"telegram_chat_id":"12345"}`, wantErr: "telegram_chat_id"},
+		{name: "endpoint with other fields", overrides: `{"ai_model":"openai","openai_base_url":"https://attacker.example/v1"}`, wantErr: "openai_base_url"},
+		{name: "bad value type", overrides: `{"initial_balance":"lots"}`, wantErr: "overrides格式错误"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var overrides map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(tt.overrides), &overrides); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := applyOverrides(source, overrides)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				if cfg.OpenAIBaseURL != source.OpenAIBaseURL || cfg.WebhookURL != source.WebhookURL {
+					t.Errorf("rejected overrides still changed the config: %+v", cfg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
@@ -2,23 +2,46 @@ package manager
 
 import (
 	"fmt"
-	"log"
 	"nofx/config"
+	"nofx/decision"
+	"nofx/exchange"
+	"nofx/logger"
+	"nofx/pool"
+	"nofx/schedule"
 	"nofx/trader"
 	"sync"
 	"time"
 )
 
+var log = logger.Module("manager")
+
 // TraderManager 管理多个trader实例
 type TraderManager struct {
-	traders map[string]*trader.AutoTrader // key: trader ID
-	mu      sync.RWMutex
+	traders           map[string]*trader.AutoTrader  // key: trader ID
+	leaderboardMetric string                         // 排行榜默认排序指标
+	portfolioLimits   config.PortfolioLimits         // 组合风控上限
+	exposureGuards    map[string]*exposureGuard      // 账户标识 -> 组合风控
+	traderAccounts    map[string]string              // trader ID -> 账户标识
+	traderConfigs     map[string]config.TraderConfig // trader ID -> 配置文件中的配置
+	owners            map[string]string              // trader ID -> 所属用户名（为空表示共享）
+	savedTraders      map[string]bool                // 通过API创建的trader ID
+	defaults          traderDefaults                 // 通过API创建trader时使用的全局配置
+	health            exchangeHealth                 // 交易所连通性检查（就绪探针）
+	seasons           *seasonSchedule                // 赛季安排（未启用时为nil）
+	seasonMu          sync.Mutex                     // 保护seasons，同时保证同一时间只有一次赛季结算
+	mu                sync.RWMutex
 }
 
 // NewTraderManager 创建trader管理器
 func NewTraderManager() *TraderManager {
 	return &TraderManager{
-		traders: make(map[string]*trader.AutoTrader),
+		traders:           make(map[string]*trader.AutoTrader),
+		leaderboardMetric: MetricPnLPct,
+		exposureGuards:    make(map[string]*exposureGuard),
+		traderAccounts:    make(map[string]string),
+		traderConfigs:     make(map[string]config.TraderConfig),
+		owners:            make(map[string]string),
+		savedTraders:      make(map[string]bool),
 	}
 }
 
@@ -31,34 +54,107 @@ func (tm *TraderManager) AddTrader(cfg config.TraderConfig, coinPoolURL string,
 		return fmt.Errorf("trader ID '%s' 已存在", cfg.ID)
 	}
 
+	// 通过API切换过的测试网设置优先于配置文件（在创建交易所客户端之前应用）
+	exchangeConfig := cfg.ExchangeConfig()
+	var exchangeSettings trader.ExchangeSettings
+	if found, err := logger.LoadTraderSetting(cfg.ID, exchangeSetting, &exchangeSettings); err != nil {
+		log.Printf("⚠️  读取trader '%s' 的交易所设置失败: %v", cfg.ID, err)
+	} else if found {
+		exchange.SetTestnet(cfg.Exchange, &exchangeConfig, exchangeSettings.Testnet)
+	}
+
+	// 现货没有杠杆
+	if exchange.IsSpot(cfg.Exchange) {
+		leverage = config.LeverageConfig{BTCETHLeverage: 1, AltcoinLeverage: 1}
+	}
+
 	// 构建AutoTraderConfig
 	traderConfig := trader.AutoTraderConfig{
-		ID:                    cfg.ID,
-		Name:                  cfg.Name,
-		AIModel:               cfg.AIModel,
-		Exchange:              cfg.Exchange,
-		BinanceAPIKey:         cfg.BinanceAPIKey,
-		BinanceSecretKey:      cfg.BinanceSecretKey,
-		HyperliquidPrivateKey: cfg.HyperliquidPrivateKey,
-		HyperliquidWalletAddr: cfg.HyperliquidWalletAddr,
-		HyperliquidTestnet:    cfg.HyperliquidTestnet,
-		AsterUser:             cfg.AsterUser,
-		AsterSigner:           cfg.AsterSigner,
-		AsterPrivateKey:       cfg.AsterPrivateKey,
-		CoinPoolAPIURL:        coinPoolURL,
-		UseQwen:               cfg.AIModel == "qwen",
-		DeepSeekKey:           cfg.DeepSeekKey,
-		QwenKey:               cfg.QwenKey,
-		CustomAPIURL:          cfg.CustomAPIURL,
-		CustomAPIKey:          cfg.CustomAPIKey,
-		CustomModelName:       cfg.CustomModelName,
-		ScanInterval:          cfg.GetScanInterval(),
-		InitialBalance:        cfg.InitialBalance,
-		BTCETHLeverage:        leverage.BTCETHLeverage,  // 使用配置的杠杆倍数
-		AltcoinLeverage:       leverage.AltcoinLeverage, // 使用配置的杠杆倍数
-		MaxDailyLoss:          maxDailyLoss,
-		MaxDrawdown:           maxDrawdown,
-		StopTradingTime:       time.Duration(stopTradingMinutes) * time.Minute,
+		ID:                   cfg.ID,
+		Name:                 cfg.Name,
+		AIModel:              cfg.AIModel,
+		Exchange:             cfg.Exchange,
+		ExchangeConfig:       exchangeConfig,
+		CoinPoolAPIURL:       coinPoolURL,
+		CoinSources:          cfg.CoinSources,
+		CoinPoolSize:         cfg.CoinPoolSize,
+		UseQwen:              cfg.AIModel == "qwen",
+		DeepSeekKey:          cfg.DeepSeekKey,
+		QwenKey:              cfg.QwenKey,
+		OpenAIKey:            cfg.OpenAIKey,
+		OpenAIBaseURL:        cfg.OpenAIBaseURL,
+		OpenAIModel:          cfg.OpenAIModel,
+		ClaudeKey:            cfg.ClaudeKey,
+		ClaudeModel:          cfg.ClaudeModel,
+		CustomAPIURL:         cfg.CustomAPIURL,
+		CustomAPIKey:         cfg.CustomAPIKey,
+		CustomModelName:      cfg.CustomModelName,
+		AIStream:             cfg.AIStream,
+		StructuredOutput:     cfg.StructuredOutput,
+		FallbackModels:       cfg.GetFallbackModels(),
+		AICacheTTL:           cfg.GetAICacheTTL(),
+		MaxDailyAICost:       cfg.MaxDailyAICost,
+		AIInputPrice:         cfg.AIInputPrice,
+		AIOutputPrice:        cfg.AIOutputPrice,
+		ScanInterval:         cfg.GetScanInterval(),
+		Timeframes:           cfg.GetTimeframes(),
+		SymbolFilter:         cfg.GetSymbolFilter(),
+		Schedule:             cfg.Schedule,
+		AdaptiveInterval:     cfg.AdaptiveInterval,
+		MaxScanInterval:      cfg.GetMaxScanInterval(),
+		QuietVolatilityPct:   cfg.QuietVolatilityPct,
+		StopOutCooldown:      cfg.GetStopOutCooldown(),
+		LimitOrderTTL:        cfg.GetLimitOrderTTL(),
+		Strategy:             cfg.Strategy,
+		EnsembleModels:       cfg.EnsembleModels,
+		EnsembleQuorum:       cfg.EnsembleQuorum,
+		TelegramBotToken:     cfg.TelegramBotToken,
+		TelegramChatID:       cfg.TelegramChatID,
+		WebhookURL:           cfg.WebhookURL,
+		DryRun:               cfg.DryRun,
+		ApprovalThreshold:    cfg.ApprovalThresholdUSD,
+		OrderRetryAttempts:   cfg.GetOrderRetryAttempts(),
+		PromptLessons:        cfg.GetPromptLessons(),
+		SimilarSetups:        cfg.GetSimilarSetups(),
+		ApprovalTimeout:      cfg.GetApprovalTimeout(),
+		WebhookSecret:        cfg.WebhookSecret,
+		InitialBalance:       cfg.InitialBalance,
+		BTCETHLeverage:       leverage.BTCETHLeverage,  // 使用配置的杠杆倍数
+		AltcoinLeverage:      leverage.AltcoinLeverage, // 使用配置的杠杆倍数
+		MaxDailyLoss:         maxDailyLoss,
+		MaxDrawdown:          maxDrawdown,
+		StopTradingTime:      time.Duration(stopTradingMinutes) * time.Minute,
+		MaxConsecutiveLosses: cfg.MaxConsecutiveLosses,
+		LossWindowPct:        cfg.LossWindowPct,
+		LossWindow:           time.Duration(cfg.LossWindowMinutes) * time.Minute,
+		LossCooldown:         time.Duration(cfg.LossCooldownMinutes) * time.Minute,
+
+		MaxCorrelatedPositions: cfg.MaxCorrelatedPositions,
+		CorrelationThreshold:   cfg.CorrelationThreshold,
+		MaxSymbolExposure:      cfg.MaxSymbolExposure,
+		MaxNetExposure:         cfg.MaxNetExposure,
+		MinLiquidationBuffer:   cfg.MinLiquidationBuffer,
+	}
+
+	// trader单独配置的风控参数优先于全局配置
+	if cfg.MaxDailyLoss > 0 {
+		traderConfig.MaxDailyLoss = cfg.MaxDailyLoss
+	}
+	if cfg.MaxDrawdown > 0 {
+		traderConfig.MaxDrawdown = cfg.MaxDrawdown
+	}
+	if cfg.StopTradingMinutes > 0 {
+		traderConfig.StopTradingTime = time.Duration(cfg.StopTradingMinutes) * time.Minute
+	}
+
+	// 影子trader（模拟盘执行，沿用主trader的密钥和风控配置）
+	if s := cfg.Shadow; s != nil {
+		traderConfig.Shadow = &trader.ShadowConfig{
+			AIModel:  s.AIModel,
+			Model:    s.Model,
+			Strategy: s.Strategy,
+			Prompts:  decision.PromptTemplates{System: s.SystemPrompt, User: s.UserPrompt},
+		}
 	}
 
 	// 创建trader实例
@@ -67,11 +163,294 @@ func (tm *TraderManager) AddTrader(cfg config.TraderConfig, coinPoolURL string,
 		return fmt.Errorf("创建trader失败: %w", err)
 	}
 
+	// 恢复上次运行的周期计数、风控暂停、止损冷却和未成交的限价单
+	if err := at.RestoreRuntimeState(); err != nil {
+		log.Printf("⚠️  恢复trader '%s' 的运行时状态失败: %v", cfg.ID, err)
+	}
+
+	// 恢复紧急锁定状态（panic后重启仍保持锁定，直到手动解锁）
+	var locked bool
+	if found, err := logger.LoadTraderSetting(cfg.ID, panicLockSetting, &locked); err != nil {
+		log.Printf("⚠️  读取trader '%s' 的锁定状态失败: %v", cfg.ID, err)
+	} else if found && locked {
+		at.SetLocked(true)
+	}
+
+	// 通过API修改过的名称、初始余额和扫描间隔优先于配置文件
+	var profile trader.Profile
+	if found, err := logger.LoadTraderSetting(cfg.ID, profileSetting, &profile); err != nil {
+		log.Printf("⚠️  读取trader '%s' 的基本设置失败: %v", cfg.ID, err)
+	} else if found {
+		if err := at.SetProfile(profile); err != nil {
+			log.Printf("⚠️  trader '%s' 保存的基本设置无效，使用配置文件: %v", cfg.ID, err)
+		}
+	}
+
+	// 通过API切换过的观察模式优先于配置文件
+	var dryRun bool
+	if found, err := logger.LoadTraderSetting(cfg.ID, dryRunSetting, &dryRun); err != nil {
+		log.Printf("⚠️  读取trader '%s' 的观察模式设置失败: %v", cfg.ID, err)
+	} else if found {
+		at.SetDryRun(dryRun)
+	}
+
+	// 通过API修改过的风控参数优先于配置文件
+	var limits trader.RiskLimits
+	if found, err := logger.LoadTraderSetting(cfg.ID, riskLimitsSetting, &limits); err != nil {
+		log.Printf("⚠️  读取trader '%s' 的风控参数失败: %v", cfg.ID, err)
+	} else if found {
+		if err := at.SetRiskLimits(limits); err != nil {
+			log.Printf("⚠️  trader '%s' 保存的风控参数无效，使用配置文件: %v", cfg.ID, err)
+		}
+	}
+
+	// 通过API修改过的币种黑白名单优先于配置文件
+	var filter pool.SymbolFilter
+	if found, err := logger.LoadTraderSetting(cfg.ID, symbolFilterSetting, &filter); err != nil {
+		log.Printf("⚠️  读取trader '%s' 的币种黑白名单失败: %v", cfg.ID, err)
+	} else if found {
+		if err := at.SetSymbolFilter(filter); err != nil {
+			log.Printf("⚠️  trader '%s' 保存的币种黑白名单无效，使用配置文件: %v", cfg.ID, err)
+		}
+	}
+
+	// 通过API修改过的交易时段优先于配置文件
+	var sched schedule.Schedule
+	if found, err := logger.LoadTraderSetting(cfg.ID, scheduleSetting, &sched); err != nil {
+		log.Printf("⚠️  读取trader '%s' 的交易时段失败: %v", cfg.ID, err)
+	} else if found {
+		if err := at.SetSchedule(sched); err != nil {
+			log.Printf("⚠️  trader '%s' 保存的交易时段无效，使用配置文件: %v", cfg.ID, err)
+		}
+	}
+
+	// 通过API切换过的决策策略优先于配置文件
+	var strategy string
+	if found, err := logger.LoadTraderSetting(cfg.ID, strategySetting, &strategy); err != nil {
+		log.Printf("⚠️  读取trader '%s' 的决策策略失败: %v", cfg.ID, err)
+	} else if found {
+		if err := at.SetStrategy(strategy); err != nil {
+			log.Printf("⚠️  trader '%s' 保存的决策策略无效，使用配置文件: %v", cfg.ID, err)
+		}
+	}
+
+	// 通过API保存的自定义prompt模板
+	var prompts decision.PromptTemplates
+	if found, err := logger.LoadTraderSetting(cfg.ID, promptSetting, &prompts); err != nil {
+		log.Printf("⚠️  读取trader '%s' 的prompt模板失败: %v", cfg.ID, err)
+	} else if found {
+		if err := at.SetPromptTemplates(prompts); err != nil {
+			log.Printf("⚠️  trader '%s' 保存的prompt模板无效，使用默认模板: %v", cfg.ID, err)
+		}
+	}
+
+	// 共用交易所账户的trader受同一个组合风控约束
+	if guard := tm.exposureGuardFor(cfg); guard != nil {
+		at.SetExposureGuard(guard)
+	}
+
 	tm.traders[cfg.ID] = at
+	tm.traderConfigs[cfg.ID] = cfg
+	tm.owners[cfg.ID] = loadOwner(cfg.ID, cfg.Owner)
 	log.Printf("✓ Trader '%s' (%s) 已添加", cfg.Name, cfg.AIModel)
 	return nil
 }
 
+// trader_settings中的键名
+const (
+	riskLimitsSetting   = "risk_limits"   // 风控参数
+	panicLockSetting    = "panic_lock"    // 紧急锁定状态
+	strategySetting     = "strategy"      // 决策策略
+	promptSetting       = "prompt"        // 自定义prompt模板
+	symbolFilterSetting = "symbol_filter" // 币种黑白名单
+	scheduleSetting     = "schedule"      // 交易时段
+	exchangeSetting     = "exchange"      // 交易所连接设置（测试网）
+	dryRunSetting       = "dry_run"       // 观察模式
+	profileSetting      = "profile"       // 名称、初始余额和扫描间隔
+	ownerSetting        = "owner"         // 所属用户
+)
+
+// PanicTrader 紧急平仓并锁定指定trader（锁定状态持久化，重启后保持）
+func (tm *TraderManager) PanicTrader(id string) (*trader.PanicResult, error) {
+	t, err := tm.GetTrader(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := logger.SaveTraderSetting(id, panicLockSetting, true); err != nil {
+		log.Printf("⚠️  保存trader '%s' 的锁定状态失败: %v", id, err)
+	}
+	return t.Panic()
+}
+
+// PanicAll 紧急平仓并锁定所有trader
+func (tm *TraderManager) PanicAll() []*trader.PanicResult {
+	return tm.PanicTraders(tm.GetTraderIDs())
+}
+
+// UnlockTrader 解除紧急锁定
+func (tm *TraderManager) UnlockTrader(id string) error {
+	t, err := tm.GetTrader(id)
+	if err != nil {
+		return err
+	}
+	if err := logger.SaveTraderSetting(id, panicLockSetting, false); err != nil {
+		return err
+	}
+	t.SetLocked(false)
+	return nil
+}
+
+// UpdateRiskLimits 修改trader的风控参数（立即生效并持久化，重启后保留）
+func (tm *TraderManager) UpdateRiskLimits(id string, limits trader.RiskLimits) error {
+	t, err := tm.GetTrader(id)
+	if err != nil {
+		return err
+	}
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+	if err := logger.SaveTraderSetting(id, riskLimitsSetting, limits); err != nil {
+		return err
+	}
+	return t.SetRiskLimits(limits)
+}
+
+// ApplyGlobalRiskLimits 配置热加载后应用新的全局风控参数
+// trader单独配置的参数和通过API修改过风控参数的trader不受影响（与启动时的优先级一致）
+func (tm *TraderManager) ApplyGlobalRiskLimits(maxDailyLoss, maxDrawdown float64, stopTradingMinutes int) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	tm.defaults.maxDailyLoss = maxDailyLoss
+	tm.defaults.maxDrawdown = maxDrawdown
+	tm.defaults.stopTradingMinutes = stopTradingMinutes
+
+	for id, t := range tm.traders {
+		var saved trader.RiskLimits
+		if found, err := logger.LoadTraderSetting(id, riskLimitsSetting, &saved); err != nil {
+			log.Printf("⚠️  读取trader '%s' 的风控参数失败，跳过: %v", id, err)
+			continue
+		} else if found {
+			log.Printf("⏭️  trader '%s' 的风控参数已通过API修改，不使用全局配置", id)
+			continue
+		}
+
+		cfg := tm.traderConfigs[id]
+		limits := t.GetRiskLimits()
+		if cfg.MaxDailyLoss == 0 {
+			limits.MaxDailyLoss = maxDailyLoss
+		}
+		if cfg.MaxDrawdown == 0 {
+			limits.MaxDrawdown = maxDrawdown
+		}
+		if cfg.StopTradingMinutes == 0 {
+			limits.StopTradingMinutes = stopTradingMinutes
+		}
+		if err := t.SetRiskLimits(limits); err != nil {
+			log.Printf("⚠️  更新trader '%s' 的风控参数失败: %v", id, err)
+		}
+	}
+}
+
+// UpdateStrategy 切换trader的决策策略并持久化（重启后保留）
+func (tm *TraderManager) UpdateStrategy(id, strategy string) error {
+	t, err := tm.GetTrader(id)
+	if err != nil {
+		return err
+	}
+	if !decision.ValidStrategy(strategy) {
+		return fmt.Errorf("不支持的策略: %s", strategy)
+	}
+	// 先切换再持久化，避免保存无法创建的策略（如未配置委员会模型的ensemble）
+	if err := t.SetStrategy(strategy); err != nil {
+		return err
+	}
+	return logger.SaveTraderSetting(id, strategySetting, strategy)
+}
+
+// UpdatePromptTemplates 修改trader的prompt模板并持久化（字段为空表示恢复默认模板）
+func (tm *TraderManager) UpdatePromptTemplates(id string, prompts decision.PromptTemplates) error {
+	t, err := tm.GetTrader(id)
+	if err != nil {
+		return err
+	}
+	if err := t.SetPromptTemplates(prompts); err != nil {
+		return err
+	}
+	return logger.SaveTraderSetting(id, promptSetting, prompts)
+}
+
+// UpdateSymbolFilter 修改trader的币种黑白名单（下个周期生效并持久化，重启后保留）
+func (tm *TraderManager) UpdateSymbolFilter(id string, filter pool.SymbolFilter) error {
+	t, err := tm.GetTrader(id)
+	if err != nil {
+		return err
+	}
+	if err := filter.Validate(); err != nil {
+		return err
+	}
+	filter = filter.Normalize()
+	if err := logger.SaveTraderSetting(id, symbolFilterSetting, filter); err != nil {
+		return err
+	}
+	return t.SetSymbolFilter(filter)
+}
+
+// UpdateSchedule 修改trader的交易时段（下个周期生效并持久化，重启后保留）
+func (tm *TraderManager) UpdateSchedule(id string, s schedule.Schedule) error {
+	t, err := tm.GetTrader(id)
+	if err != nil {
+		return err
+	}
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	if err := logger.SaveTraderSetting(id, scheduleSetting, s); err != nil {
+		return err
+	}
+	return t.SetSchedule(s)
+}
+
+// UpdateProfile 修改trader的名称、初始余额和扫描间隔并持久化（立即生效，重启后保留）
+func (tm *TraderManager) UpdateProfile(id string, p trader.Profile) error {
+	t, err := tm.GetTrader(id)
+	if err != nil {
+		return err
+	}
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if err := logger.SaveTraderSetting(id, profileSetting, p); err != nil {
+		return err
+	}
+	return t.SetProfile(p)
+}
+
+// UpdateExchangeSettings 切换trader的测试网/主网（trader须已停止，持久化，重启后保留）
+func (tm *TraderManager) UpdateExchangeSettings(id string, s trader.ExchangeSettings) error {
+	t, err := tm.GetTrader(id)
+	if err != nil {
+		return err
+	}
+	if err := t.SetExchangeSettings(s); err != nil {
+		return err
+	}
+	return logger.SaveTraderSetting(id, exchangeSetting, s)
+}
+
+// UpdateDryRun 开启/关闭trader的观察模式（下个周期生效并持久化，重启后保留）
+func (tm *TraderManager) UpdateDryRun(id string, dryRun bool) error {
+	t, err := tm.GetTrader(id)
+	if err != nil {
+		return err
+	}
+	if err := logger.SaveTraderSetting(id, dryRunSetting, dryRun); err != nil {
+		return err
+	}
+	t.SetDryRun(dryRun)
+	return nil
+}
+
 // GetTrader 获取指定ID的trader
 func (tm *TraderManager) GetTrader(id string) (*trader.AutoTrader, error) {
 	tm.mu.RLock()
@@ -124,6 +503,39 @@ func (tm *TraderManager) StartAll() {
 	}
 }
 
+// StartTrader 启动指定trader（已在运行时返回错误）
+func (tm *TraderManager) StartTrader(id string) error {
+	at, err := tm.GetTrader(id)
+	if err != nil {
+		return err
+	}
+	if at.IsRunning() {
+		return fmt.Errorf("trader '%s' 已在运行", id)
+	}
+
+	go func() {
+		log.Printf("▶️  启动 %s...", at.GetName())
+		if err := at.Run(); err != nil {
+			log.Printf("❌ %s 运行错误: %v", at.GetName(), err)
+		}
+	}()
+	return nil
+}
+
+// StopTrader 停止指定trader
+func (tm *TraderManager) StopTrader(id string) error {
+	at, err := tm.GetTrader(id)
+	if err != nil {
+		return err
+	}
+	if !at.IsRunning() {
+		return fmt.Errorf("trader '%s' 未在运行", id)
+	}
+
+	at.Stop()
+	return nil
+}
+
 // StopAll 停止所有trader
 func (tm *TraderManager) StopAll() {
 	tm.mu.RLock()
@@ -135,6 +547,32 @@ func (tm *TraderManager) StopAll() {
 	}
 }
 
+// Shutdown 停止所有trader并等待各自当前的决策周期和下单完成（每个trader最多等待timeout），
+// cancelOrders为true时同时撤销交易所上的止损止盈单和限价开仓单
+func (tm *TraderManager) Shutdown(timeout time.Duration, cancelOrders bool) {
+	tm.mu.RLock()
+	traders := make([]*trader.AutoTrader, 0, len(tm.traders))
+	for _, t := range tm.traders {
+		traders = append(traders, t)
+	}
+	tm.mu.RUnlock()
+
+	log.Printf("⏹  停止所有Trader（最多等待 %v）...", timeout)
+	var wg sync.WaitGroup
+	for _, t := range traders {
+		wg.Add(1)
+		go func(at *trader.AutoTrader) {
+			defer wg.Done()
+			if err := at.Shutdown(timeout, cancelOrders); err != nil {
+				log.Printf("⚠️  %s 未能正常退出: %v", at.GetName(), err)
+				return
+			}
+			log.Printf("✓ %s 已停止", at.GetName())
+		}(t)
+	}
+	wg.Wait()
+}
+
 // GetComparisonData 获取对比数据
 func (tm *TraderManager) GetComparisonData() (map[string]interface{}, error) {
 	tm.mu.RLock()
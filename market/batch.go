@@ -0,0 +1,126 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// 批量获取参数
+const (
+	batchWorkers          = 8  // 并发请求的币种数量
+	batchPremiumThreshold = 10 // 币种数量超过该值时一次性获取全市场资金费率（权重10）
+)
+
+// GetBatch 并发获取多个币种的市场数据（获取失败的币种不在结果中，ctx取消时中止进行中的请求并不再发起新的请求）
+func GetBatch(ctx context.Context, symbols []string, tf Timeframes) map[string]*Data {
+	tf = tf.WithDefaults()
+
+	// REST模式下币种较多时一次性获取资金费率，避免逐个请求
+	var fundingRates map[string]float64
+	if activeFeed() == nil && len(symbols) > batchPremiumThreshold {
+		rates, err := getAllFundingRates(ctx)
+		if err != nil {
+			log.Printf("⚠️  批量获取资金费率失败，逐个获取: %v", err)
+		} else {
+			fundingRates = rates
+		}
+	}
+
+	var mu sync.Mutex
+	results := make(map[string]*Data, len(symbols))
+	runBatch(ctx, symbols, func(symbol string) {
+		data, err := getWithFunding(ctx, Normalize(symbol), tf, fundingRates)
+		if err != nil {
+			log.Debugf("%s 获取市场数据失败: %v", symbol, err)
+			return
+		}
+		mu.Lock()
+		results[symbol] = data
+		mu.Unlock()
+	})
+	return results
+}
+
+// GetOrderBookBatch 并发获取多个币种的订单簿（获取失败的币种不在结果中，ctx同GetBatch）
+func GetOrderBookBatch(ctx context.Context, symbols []string) map[string]*OrderBook {
+	var mu sync.Mutex
+	results := make(map[string]*OrderBook, len(symbols))
+	runBatch(ctx, symbols, func(symbol string) {
+		ob, err := getOrderBook(ctx, symbol)
+		if err != nil {
+			log.Debugf("%s 获取订单簿失败: %v", symbol, err)
+			return
+		}
+		mu.Lock()
+		results[symbol] = ob
+		mu.Unlock()
+	})
+	return results
+}
+
+// runBatch 用固定数量的worker并发处理币种（请求权重由共享限流器控制，ctx取消后不再分发剩余的币种）
+func runBatch(ctx context.Context, symbols []string, fn func(symbol string)) {
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	workers := batchWorkers
+	if len(symbols) < workers {
+		workers = len(symbols)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for symbol := range jobs {
+				fn(symbol)
+			}
+		}()
+	}
+dispatch:
+	for _, symbol := range symbols {
+		select {
+		case jobs <- symbol:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// getAllFundingRates 一次性获取全市场资金费率
+func getAllFundingRates(ctx context.Context) (map[string]float64, error) {
+	resp, err := get(ctx, "https://fapi.binance.com/fapi/v1/premiumIndex")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取资金费率失败 (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result []struct {
+		Symbol          string `json:"symbol"`
+		LastFundingRate string `json:"lastFundingRate"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	rates := make(map[string]float64, len(result))
+	for _, item := range result {
+		if rate, err := strconv.ParseFloat(item.LastFundingRate, 64); err == nil {
+			rates[item.Symbol] = rate
+		}
+	}
+	return rates, nil
+}
@@ -0,0 +1,142 @@
+package market
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// 相关性参数
+const (
+	correlationInterval = "1h"             // 计算收益率相关性的K线周期
+	correlationBars     = 73               // 72个小时收益率（3天）
+	correlationMinBars  = 24               // 重叠的收益率少于该数量时不计算相关性
+	correlationCacheTTL = 15 * time.Minute // 每个币种K线的缓存时间
+)
+
+// CorrelationMatrix 币种两两之间的收益率相关系数（-1到1，对称，不包含自身）
+type CorrelationMatrix map[string]map[string]float64
+
+// Get 两个币种的相关系数（没有数据时返回false）
+func (m CorrelationMatrix) Get(a, b string) (float64, bool) {
+	row, ok := m[a]
+	if !ok {
+		return 0, false
+	}
+	c, ok := row[b]
+	return c, ok
+}
+
+// set 写入一对币种的相关系数（两个方向）
+func (m CorrelationMatrix) set(a, b string, c float64) {
+	if m[a] == nil {
+		m[a] = make(map[string]float64)
+	}
+	if m[b] == nil {
+		m[b] = make(map[string]float64)
+	}
+	m[a][b], m[b][a] = c, c
+}
+
+// cachedReturns 某个币种的小时收益率（按K线开盘时间索引）
+type cachedReturns struct {
+	returns   map[int64]float64
+	fetchedAt time.Time
+}
+
+var (
+	returnsMu    sync.Mutex
+	returnsCache = make(map[string]*cachedReturns)
+)
+
+// hourlyReturns 最近72小时的对数收益率（缓存15分钟）
+func hourlyReturns(ctx context.Context, symbol string) (map[int64]float64, error) {
+	returnsMu.Lock()
+	cached, ok := returnsCache[symbol]
+	returnsMu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < correlationCacheTTL {
+		return cached.returns, nil
+	}
+
+	klines, err := getKlines(ctx, symbol, correlationInterval, correlationBars)
+	if err != nil {
+		return nil, err
+	}
+	returns := make(map[int64]float64, len(klines))
+	for i := 1; i < len(klines); i++ {
+		if klines[i-1].Close > 0 && klines[i].Close > 0 {
+			returns[klines[i].OpenTime] = math.Log(klines[i].Close / klines[i-1].Close)
+		}
+	}
+
+	returnsMu.Lock()
+	returnsCache[symbol] = &cachedReturns{returns: returns, fetchedAt: time.Now()}
+	returnsMu.Unlock()
+	return returns, nil
+}
+
+// GetCorrelations 并发获取币种的小时K线，计算两两之间最近72小时收益率的皮尔逊相关系数
+// 获取失败或数据不足的币种不在结果中（ctx取消时不再发起新的请求）
+func GetCorrelations(ctx context.Context, symbols []string) CorrelationMatrix {
+	var mu sync.Mutex
+	returns := make(map[string]map[int64]float64, len(symbols))
+	runBatch(ctx, symbols, func(symbol string) {
+		r, err := hourlyReturns(ctx, Normalize(symbol))
+		if err != nil {
+			log.Debugf("%s 获取相关性K线失败: %v", symbol, err)
+			return
+		}
+		mu.Lock()
+		returns[symbol] = r
+		mu.Unlock()
+	})
+
+	matrix := make(CorrelationMatrix)
+	for i, a := range symbols {
+		for _, b := range symbols[i+1:] {
+			ra, okA := returns[a]
+			rb, okB := returns[b]
+			if !okA || !okB || a == b {
+				continue
+			}
+			if c, ok := pearson(ra, rb); ok {
+				matrix.set(a, b, c)
+			}
+		}
+	}
+	return matrix
+}
+
+// pearson 按K线时间对齐后计算皮尔逊相关系数（重叠数据不足或方差为0时返回false）
+func pearson(a, b map[int64]float64) (float64, bool) {
+	var xs, ys []float64
+	for t, x := range a {
+		if y, ok := b[t]; ok {
+			xs = append(xs, x)
+			ys = append(ys, y)
+		}
+	}
+	n := float64(len(xs))
+	if len(xs) < correlationMinBars {
+		return 0, false
+	}
+
+	var sumX, sumY float64
+	for i := range xs {
+		sumX += xs[i]
+		sumY += ys[i]
+	}
+	meanX, meanY := sumX/n, sumY/n
+	var cov, varX, varY float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0, false
+	}
+	return cov / math.Sqrt(varX*varY), true
+}
@@ -1,28 +1,40 @@
 package market
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math"
 	"net/http"
+	"nofx/ratelimit"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// httpClient Binance REST请求共用的客户端（经过进程内所有trader共享的权重限流）
+var httpClient = ratelimit.NewBinanceClient(30 * time.Second)
+
 // Data 市场数据结构
 type Data struct {
 	Symbol            string
+	Timeframes        Timeframes // 日内和长期数据使用的K线周期
 	CurrentPrice      float64
 	PriceChange1h     float64 // 1小时价格变化百分比
 	PriceChange4h     float64 // 4小时价格变化百分比
 	CurrentEMA20      float64
 	CurrentMACD       float64
 	CurrentRSI7       float64
+	Bollinger         *BollingerData  // 日内周期布林带(20, 2)
+	Stochastic        *StochasticData // 日内周期随机指标(14, 3, 3)
+	SessionVWAP       float64         // 当日(UTC)会话VWAP（日内K线窗口内，0表示无数据）
 	OpenInterest      *OIData
 	FundingRate       float64
+	LongShort         *LongShortRatio // 全市场账户多空比（获取失败时为nil）
 	IntradaySeries    *IntradayData
 	LongerTermContext *LongerTermData
+	Liquidations      *LiquidationData // 最近1小时强平统计（仅WebSocket行情提供）
 }
 
 // OIData Open Interest数据
@@ -31,7 +43,7 @@ type OIData struct {
 	Average float64
 }
 
-// IntradayData 日内数据(3分钟间隔)
+// IntradayData 日内数据(日内周期，默认3分钟)
 type IntradayData struct {
 	MidPrices   []float64
 	EMA20Values []float64
@@ -40,10 +52,12 @@ type IntradayData struct {
 	RSI14Values []float64
 }
 
-// LongerTermData 长期数据(4小时时间框架)
+// LongerTermData 长期数据(长期周期，默认4小时)
 type LongerTermData struct {
 	EMA20         float64
+	EMA20Slope    float64 // EMA20相对emaSlopeBars根K线前的变化百分比
 	EMA50         float64
+	ADX           *ADXData // ADX(14)趋势强度
 	ATR3          float64
 	ATR14         float64
 	CurrentVolume float64
@@ -63,85 +77,151 @@ type Kline struct {
 	CloseTime int64
 }
 
-// Get 获取指定代币的市场数据
+// Get 获取指定代币的市场数据（默认3分钟 + 4小时周期）
 func Get(symbol string) (*Data, error) {
+	return GetWithTimeframes(symbol, DefaultTimeframes)
+}
+
+// GetWithTimeframes 按指定K线周期获取市场数据（已启动WebSocket行情时从内存读取，否则通过REST获取）
+func GetWithTimeframes(symbol string, tf Timeframes) (*Data, error) {
 	// 标准化symbol
-	symbol = Normalize(symbol)
+	return getWithFunding(context.Background(), Normalize(symbol), tf.WithDefaults(), nil)
+}
 
-	// 获取3分钟K线数据 (最近10个)
-	klines3m, err := getKlines(symbol, "3m", 40) // 多获取一些用于计算
-	if err != nil {
-		return nil, fmt.Errorf("获取3分钟K线失败: %v", err)
+// getWithFunding 获取市场数据（REST模式下fundingRates中已有的资金费率不再单独请求，ctx取消时中止REST请求）
+func getWithFunding(ctx context.Context, symbol string, tf Timeframes, fundingRates map[string]float64) (*Data, error) {
+	if feed := activeFeed(); feed != nil {
+		data, err := feed.Get(symbol, tf)
+		if err == nil {
+			return data, nil
+		}
+		log.Debugf("%s WebSocket行情不可用，使用REST: %v", symbol, err)
 	}
+	return getREST(ctx, symbol, tf, fundingRates)
+}
 
-	// 获取4小时K线数据 (最近10个)
-	klines4h, err := getKlines(symbol, "4h", 60) // 多获取用于计算指标
+// getREST 通过REST接口获取市场数据
+func getREST(ctx context.Context, symbol string, tf Timeframes, fundingRates map[string]float64) (*Data, error) {
+	// 获取日内K线数据
+	intraday, err := getKlines(ctx, symbol, tf.Intraday, intradayWindow)
 	if err != nil {
-		return nil, fmt.Errorf("获取4小时K线失败: %v", err)
-	}
-
-	// 计算当前指标 (基于3分钟最新数据)
-	currentPrice := klines3m[len(klines3m)-1].Close
-	currentEMA20 := calculateEMA(klines3m, 20)
-	currentMACD := calculateMACD(klines3m)
-	currentRSI7 := calculateRSI(klines3m, 7)
-
-	// 计算价格变化百分比
-	// 1小时价格变化 = 20个3分钟K线前的价格
-	priceChange1h := 0.0
-	if len(klines3m) >= 21 { // 至少需要21根K线 (当前 + 20根前)
-		price1hAgo := klines3m[len(klines3m)-21].Close
-		if price1hAgo > 0 {
-			priceChange1h = ((currentPrice - price1hAgo) / price1hAgo) * 100
-		}
+		return nil, fmt.Errorf("获取%sK线失败: %v", IntervalLabelCN(tf.Intraday), err)
 	}
 
-	// 4小时价格变化 = 1个4小时K线前的价格
-	priceChange4h := 0.0
-	if len(klines4h) >= 2 {
-		price4hAgo := klines4h[len(klines4h)-2].Close
-		if price4hAgo > 0 {
-			priceChange4h = ((currentPrice - price4hAgo) / price4hAgo) * 100
-		}
+	// 获取长期K线数据
+	longer, err := getKlines(ctx, symbol, tf.LongerTerm, longerTermWindow)
+	if err != nil {
+		return nil, fmt.Errorf("获取%sK线失败: %v", IntervalLabelCN(tf.LongerTerm), err)
 	}
 
 	// 获取OI数据
-	oiData, err := getOpenInterestData(symbol)
+	oiData, err := getOpenInterestData(ctx, symbol)
 	if err != nil {
 		// OI失败不影响整体,使用默认值
 		oiData = &OIData{Latest: 0, Average: 0}
 	}
 
-	// 获取Funding Rate
-	fundingRate, _ := getFundingRate(symbol)
+	// 获取Funding Rate（批量获取时已有）
+	fundingRate, ok := fundingRates[symbol]
+	if !ok {
+		fundingRate, _ = getFundingRate(ctx, symbol)
+	}
+
+	data := BuildDataWithTimeframes(symbol, tf, intraday, longer, oiData, fundingRate)
+
+	// 获取多空比（失败不影响整体）
+	data.LongShort, _ = getLongShortRatio(ctx, symbol)
+	return data, nil
+}
+
+// BuildData 根据3分钟和4小时K线、OI和资金费率计算市场数据（回测使用）
+func BuildData(symbol string, klines3m, klines4h []Kline, oiData *OIData, fundingRate float64) *Data {
+	return BuildDataWithTimeframes(symbol, DefaultTimeframes, klines3m, klines4h, oiData, fundingRate)
+}
+
+// BuildDataWithTimeframes 根据日内/长期K线、OI和资金费率计算市场数据（实时行情和回测共用）
+func BuildDataWithTimeframes(symbol string, tf Timeframes, intraday, longer []Kline, oiData *OIData, fundingRate float64) *Data {
+	// 计算当前指标 (基于日内周期最新数据)
+	currentPrice := intraday[len(intraday)-1].Close
+	currentEMA20 := calculateEMA(intraday, 20)
+	currentMACD := calculateMACD(intraday)
+	currentRSI7 := calculateRSI(intraday, 7)
+
+	// 计算价格变化百分比：优先用日内K线（如20根3分钟K线前），周期不匹配或数据不足时用长期K线
+	priceChange1h, ok := priceChangePct(intraday, tf.Intraday, time.Hour, currentPrice)
+	if !ok {
+		priceChange1h, _ = priceChangePct(longer, tf.LongerTerm, time.Hour, currentPrice)
+	}
+	priceChange4h, ok := priceChangePct(intraday, tf.Intraday, 4*time.Hour, currentPrice)
+	if !ok {
+		priceChange4h, _ = priceChangePct(longer, tf.LongerTerm, 4*time.Hour, currentPrice)
+	}
 
 	// 计算日内系列数据
-	intradayData := calculateIntradaySeries(klines3m)
+	intradayData := calculateIntradaySeries(intraday)
 
 	// 计算长期数据
-	longerTermData := calculateLongerTermData(klines4h)
+	longerTermData := calculateLongerTermData(longer)
 
 	return &Data{
 		Symbol:            symbol,
+		Timeframes:        tf,
 		CurrentPrice:      currentPrice,
 		PriceChange1h:     priceChange1h,
 		PriceChange4h:     priceChange4h,
 		CurrentEMA20:      currentEMA20,
 		CurrentMACD:       currentMACD,
 		CurrentRSI7:       currentRSI7,
+		Bollinger:         calculateBollinger(intraday, 20, 2),
+		Stochastic:        calculateStochastic(intraday, 14, 3, 3),
+		SessionVWAP:       calculateSessionVWAP(intraday),
 		OpenInterest:      oiData,
 		FundingRate:       fundingRate,
 		IntradaySeries:    intradayData,
 		LongerTermContext: longerTermData,
-	}, nil
+	}
 }
 
 // getKlines 从Binance获取K线数据
-func getKlines(symbol, interval string, limit int) ([]Kline, error) {
+func getKlines(ctx context.Context, symbol, interval string, limit int) ([]Kline, error) {
 	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/klines?symbol=%s&interval=%s&limit=%d",
 		symbol, interval, limit)
+	return fetchKlines(ctx, url)
+}
+
+// GetKlinesBetween 获取指定时间范围内的历史K线（自动分页，用于回测）
+func GetKlinesBetween(symbol, interval string, start, end time.Time) ([]Kline, error) {
+	var all []Kline
+	startMs := start.UnixMilli()
+	endMs := end.UnixMilli()
+	for startMs < endMs {
+		url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/klines?symbol=%s&interval=%s&startTime=%d&endTime=%d&limit=1500",
+			symbol, interval, startMs, endMs)
+		klines, err := fetchKlines(context.Background(), url)
+		if err != nil {
+			return nil, err
+		}
+		if len(klines) == 0 {
+			break
+		}
+		all = append(all, klines...)
+		startMs = klines[len(klines)-1].OpenTime + 1
+	}
+	return all, nil
+}
 
-	resp, err := http.Get(url)
+// get 发送GET请求（ctx取消时中止请求，包括等待限流的过程）
+func get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return httpClient.Do(req)
+}
+
+// fetchKlines 请求K线接口并解析
+func fetchKlines(ctx context.Context, url string) ([]Kline, error) {
+	resp, err := get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -151,6 +231,9 @@ func getKlines(symbol, interval string, limit int) ([]Kline, error) {
 	if err != nil {
 		return nil, err
 	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取K线失败 (status %d): %s", resp.StatusCode, string(body))
+	}
 
 	var rawData [][]interface{}
 	if err := json.Unmarshal(body, &rawData); err != nil {
@@ -350,6 +433,14 @@ func calculateLongerTermData(klines []Kline) *LongerTermData {
 	// 计算EMA
 	data.EMA20 = calculateEMA(klines, 20)
 	data.EMA50 = calculateEMA(klines, 50)
+	if len(klines) > emaSlopeBars {
+		if prev := calculateEMA(klines[:len(klines)-emaSlopeBars], 20); prev > 0 {
+			data.EMA20Slope = (data.EMA20 - prev) / prev * 100
+		}
+	}
+
+	// 计算ADX
+	data.ADX = calculateADX(klines, 14)
 
 	// 计算ATR
 	data.ATR3 = calculateATR(klines, 3)
@@ -387,10 +478,10 @@ func calculateLongerTermData(klines []Kline) *LongerTermData {
 }
 
 // getOpenInterestData 获取OI数据
-func getOpenInterestData(symbol string) (*OIData, error) {
+func getOpenInterestData(ctx context.Context, symbol string) (*OIData, error) {
 	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/openInterest?symbol=%s", symbol)
 
-	resp, err := http.Get(url)
+	resp, err := get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -420,10 +511,10 @@ func getOpenInterestData(symbol string) (*OIData, error) {
 }
 
 // getFundingRate 获取资金费率
-func getFundingRate(symbol string) (float64, error) {
+func getFundingRate(ctx context.Context, symbol string) (float64, error) {
 	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/premiumIndex?symbol=%s", symbol)
 
-	resp, err := http.Get(url)
+	resp, err := get(ctx, url)
 	if err != nil {
 		return 0, err
 	}
@@ -459,6 +550,21 @@ func Format(data *Data) string {
 	sb.WriteString(fmt.Sprintf("current_price = %.2f, current_ema20 = %.3f, current_macd = %.3f, current_rsi (7 period) = %.3f\n\n",
 		data.CurrentPrice, data.CurrentEMA20, data.CurrentMACD, data.CurrentRSI7))
 
+	if data.Bollinger != nil {
+		sb.WriteString(fmt.Sprintf("Bollinger Bands (20, 2): upper = %.3f, middle = %.3f, lower = %.3f, width = %.2f%%, %%B = %.2f (%s)\n\n",
+			data.Bollinger.Upper, data.Bollinger.Middle, data.Bollinger.Lower, data.Bollinger.WidthPct, data.Bollinger.PercentB, bollingerState(data.Bollinger)))
+	}
+
+	if data.Stochastic != nil {
+		sb.WriteString(fmt.Sprintf("Stochastic (14, 3, 3): %%K = %.2f, %%D = %.2f (%s)\n\n",
+			data.Stochastic.K, data.Stochastic.D, stochasticState(data.Stochastic)))
+	}
+
+	if data.SessionVWAP > 0 {
+		sb.WriteString(fmt.Sprintf("Session VWAP (UTC day) = %.3f, price vs VWAP = %+.2f%%\n\n",
+			data.SessionVWAP, (data.CurrentPrice-data.SessionVWAP)/data.SessionVWAP*100))
+	}
+
 	sb.WriteString(fmt.Sprintf("In addition, here is the latest %s open interest and funding rate for perps:\n\n",
 		data.Symbol))
 
@@ -469,8 +575,22 @@ func Format(data *Data) string {
 
 	sb.WriteString(fmt.Sprintf("Funding Rate: %.2e\n\n", data.FundingRate))
 
+	if ls := data.LongShort; ls != nil {
+		sb.WriteString(fmt.Sprintf("Long/Short Account Ratio: %.2f (longs %.1f%%, shorts %.1f%%, %s)",
+			ls.Ratio, ls.LongPct, ls.ShortPct, longShortState(ls)))
+		if ls.HasChange {
+			sb.WriteString(fmt.Sprintf(", 1h change: %+.2f", ls.Change1h))
+		}
+		sb.WriteString("\n\n")
+	}
+
+	if data.Liquidations != nil && data.Liquidations.Count > 0 {
+		sb.WriteString(fmt.Sprintf("Liquidations (last 1h): Longs: %.0f USD Shorts: %.0f USD (%d orders)\n\n",
+			data.Liquidations.LongUSD, data.Liquidations.ShortUSD, data.Liquidations.Count))
+	}
+
 	if data.IntradaySeries != nil {
-		sb.WriteString("Intraday series (3‑minute intervals, oldest → latest):\n\n")
+		sb.WriteString(fmt.Sprintf("Intraday series (%s intervals, oldest → latest):\n\n", IntervalLabel(data.Timeframes.WithDefaults().Intraday)))
 
 		if len(data.IntradaySeries.MidPrices) > 0 {
 			sb.WriteString(fmt.Sprintf("Mid prices: %s\n\n", formatFloatSlice(data.IntradaySeries.MidPrices)))
@@ -494,11 +614,16 @@ func Format(data *Data) string {
 	}
 
 	if data.LongerTermContext != nil {
-		sb.WriteString("Longer‑term context (4‑hour timeframe):\n\n")
+		sb.WriteString(fmt.Sprintf("Longer‑term context (%s timeframe):\n\n", IntervalLabel(data.Timeframes.WithDefaults().LongerTerm)))
 
 		sb.WriteString(fmt.Sprintf("20‑Period EMA: %.3f vs. 50‑Period EMA: %.3f\n\n",
 			data.LongerTermContext.EMA20, data.LongerTermContext.EMA50))
 
+		if adx := data.LongerTermContext.ADX; adx != nil {
+			sb.WriteString(fmt.Sprintf("ADX (14): %.2f, +DI: %.2f, -DI: %.2f (%s)\n\n",
+				adx.ADX, adx.PlusDI, adx.MinusDI, adxTrendState(adx)))
+		}
+
 		sb.WriteString(fmt.Sprintf("3‑Period ATR: %.3f vs. 14‑Period ATR: %.3f\n\n",
 			data.LongerTermContext.ATR3, data.LongerTermContext.ATR14))
 
@@ -0,0 +1,208 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ExchangeInfoRefreshInterval = time.Hour   // 交易规则默认刷新间隔
+	exchangeInfoRetryInterval   = time.Minute // 未加载成功时按需重试的最小间隔
+)
+
+// SymbolInfo 合约交易规则（来自Binance exchangeInfo）
+type SymbolInfo struct {
+	Symbol       string
+	Status       string  // TRADING 表示正常交易
+	TickSize     float64 // 价格步长
+	StepSize     float64 // 数量步长
+	MinQty       float64 // 最小下单数量
+	MinNotional  float64 // 最小名义价值（USDT）
+	tickDecimals int
+	stepDecimals int
+}
+
+var (
+	symbolInfos     map[string]*SymbolInfo
+	symbolInfosTime time.Time
+	symbolInfosTry  time.Time // 最近一次按需加载的时间
+	symbolInfosMu   sync.RWMutex
+)
+
+// LoadExchangeInfo 加载所有合约的交易规则（启动时调用，之后由RunExchangeInfoRefresh定期刷新）
+func LoadExchangeInfo() error {
+	resp, err := httpClient.Get("https://fapi.binance.com/fapi/v1/exchangeInfo")
+	if err != nil {
+		return fmt.Errorf("获取交易规则失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取交易规则失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("获取交易规则失败 (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Symbols []struct {
+			Symbol  string                   `json:"symbol"`
+			Status  string                   `json:"status"`
+			Filters []map[string]interface{} `json:"filters"`
+		} `json:"symbols"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("解析交易规则失败: %w", err)
+	}
+
+	infos := make(map[string]*SymbolInfo, len(result.Symbols))
+	for _, s := range result.Symbols {
+		infos[s.Symbol] = NewSymbolInfo(s.Symbol, s.Status, s.Filters)
+	}
+
+	symbolInfosMu.Lock()
+	symbolInfos, symbolInfosTime = infos, time.Now()
+	symbolInfosMu.Unlock()
+	return nil
+}
+
+// RunExchangeInfoRefresh 定期刷新交易规则（直到stopCh关闭）
+func RunExchangeInfoRefresh(interval time.Duration, stopCh <-chan struct{}) {
+	if interval <= 0 {
+		interval = ExchangeInfoRefreshInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			if err := LoadExchangeInfo(); err != nil {
+				log.Printf("⚠️  刷新交易规则失败（继续使用 %s 加载的规则）: %v", ExchangeInfoUpdatedAt().Format("15:04:05"), err)
+			}
+		}
+	}
+}
+
+// ExchangeInfoUpdatedAt 交易规则最近一次加载成功的时间（未加载时为零值）
+func ExchangeInfoUpdatedAt() time.Time {
+	symbolInfosMu.RLock()
+	defer symbolInfosMu.RUnlock()
+	return symbolInfosTime
+}
+
+// GetSymbolInfo 获取币种的交易规则（尚未加载时先加载一次；加载失败或币种不存在时返回false）
+func GetSymbolInfo(symbol string) (*SymbolInfo, bool) {
+	symbol = Normalize(symbol)
+	symbolInfosMu.Lock()
+	loaded := symbolInfos != nil
+	info, ok := symbolInfos[symbol]
+	retry := !loaded && time.Since(symbolInfosTry) >= exchangeInfoRetryInterval
+	if retry {
+		symbolInfosTry = time.Now()
+	}
+	symbolInfosMu.Unlock()
+	if loaded || !retry {
+		return info, ok
+	}
+
+	if err := LoadExchangeInfo(); err != nil {
+		log.Printf("⚠️  %v", err)
+		return nil, false
+	}
+	symbolInfosMu.RLock()
+	defer symbolInfosMu.RUnlock()
+	info, ok = symbolInfos[symbol]
+	return info, ok
+}
+
+// NewSymbolInfo 从交易所exchangeInfo的filters解析交易规则（合约和现货的格式相同，只是最小名义价值的过滤器不同）
+func NewSymbolInfo(symbol, status string, filters []map[string]interface{}) *SymbolInfo {
+	info := &SymbolInfo{Symbol: symbol, Status: status}
+	for _, filter := range filters {
+		switch filter["filterType"] {
+		case "PRICE_FILTER":
+			info.TickSize, info.tickDecimals = parseStep(filter["tickSize"])
+		case "LOT_SIZE":
+			info.StepSize, info.stepDecimals = parseStep(filter["stepSize"])
+			info.MinQty, _ = parseStep(filter["minQty"])
+		case "MIN_NOTIONAL": // 合约
+			info.MinNotional, _ = parseStep(filter["notional"])
+		case "NOTIONAL": // 现货
+			info.MinNotional, _ = parseStep(filter["minNotional"])
+		}
+	}
+	return info
+}
+
+// IsTrading 是否正常交易中
+func (s *SymbolInfo) IsTrading() bool {
+	return s.Status == "TRADING"
+}
+
+// RoundPrice 价格四舍五入到价格步长
+func (s *SymbolInfo) RoundPrice(price float64) float64 {
+	if s.TickSize <= 0 {
+		return price
+	}
+	return roundTo(math.Round(price/s.TickSize)*s.TickSize, s.tickDecimals)
+}
+
+// FloorQuantity 数量向下取整到数量步长（避免超出可用保证金）
+func (s *SymbolInfo) FloorQuantity(quantity float64) float64 {
+	if s.StepSize <= 0 {
+		return quantity
+	}
+	return roundTo(math.Floor(quantity/s.StepSize+1e-9)*s.StepSize, s.stepDecimals)
+}
+
+// FormatPrice 按价格步长格式化价格
+func (s *SymbolInfo) FormatPrice(price float64) string {
+	if s.TickSize <= 0 {
+		return strconv.FormatFloat(price, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(s.RoundPrice(price), 'f', s.tickDecimals, 64)
+}
+
+// FormatQuantity 按数量步长格式化数量（向下取整）
+func (s *SymbolInfo) FormatQuantity(quantity float64) string {
+	if s.StepSize <= 0 {
+		return strconv.FormatFloat(quantity, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(s.FloorQuantity(quantity), 'f', s.stepDecimals, 64)
+}
+
+// QuantityPrecision 数量小数位数
+func (s *SymbolInfo) QuantityPrecision() int {
+	return s.stepDecimals
+}
+
+// parseStep 解析步长字符串，返回数值和小数位数（"0.00100" -> 0.001, 3）
+func parseStep(v interface{}) (float64, int) {
+	str, _ := v.(string)
+	value, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, 0
+	}
+	decimals := 0
+	if dot := strings.IndexByte(str, '.'); dot >= 0 {
+		decimals = len(strings.TrimRight(str[dot+1:], "0"))
+	}
+	return value, decimals
+}
+
+// roundTo 保留n位小数（消除浮点误差）
+func roundTo(value float64, n int) float64 {
+	p := math.Pow(10, float64(n))
+	return math.Round(value*p) / p
+}
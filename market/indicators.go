@@ -0,0 +1,236 @@
+package market
+
+import (
+	"math"
+	"time"
+)
+
+// BollingerData 布林带(20, 2)
+type BollingerData struct {
+	Upper    float64
+	Middle   float64
+	Lower    float64
+	WidthPct float64 // 带宽 = (上轨-下轨)/中轨 × 100
+	PercentB float64 // 价格在带内的位置（0=下轨，1=上轨，超出带外时<0或>1）
+}
+
+// ADXData 平均趋向指数(14)
+type ADXData struct {
+	ADX     float64 // 趋势强度（不区分方向）
+	PlusDI  float64 // +DI
+	MinusDI float64 // -DI
+}
+
+// StochasticData 随机指标(14, 3, 3)
+type StochasticData struct {
+	K float64 // 平滑后的%K
+	D float64 // %K的3周期均值
+}
+
+// calculateBollinger 计算布林带（数据不足时返回nil）
+func calculateBollinger(klines []Kline, period int, mult float64) *BollingerData {
+	if len(klines) < period {
+		return nil
+	}
+	window := klines[len(klines)-period:]
+	mean := 0.0
+	for _, k := range window {
+		mean += k.Close
+	}
+	mean /= float64(period)
+
+	variance := 0.0
+	for _, k := range window {
+		variance += (k.Close - mean) * (k.Close - mean)
+	}
+	std := math.Sqrt(variance / float64(period))
+
+	data := &BollingerData{
+		Upper:  mean + mult*std,
+		Middle: mean,
+		Lower:  mean - mult*std,
+	}
+	if mean > 0 {
+		data.WidthPct = (data.Upper - data.Lower) / mean * 100
+	}
+	if data.Upper > data.Lower {
+		data.PercentB = (klines[len(klines)-1].Close - data.Lower) / (data.Upper - data.Lower)
+	} else {
+		data.PercentB = 0.5
+	}
+	return data
+}
+
+// calculateADX 计算ADX和±DI（Wilder平滑，至少需要2×period+1根K线，不足时返回nil）
+func calculateADX(klines []Kline, period int) *ADXData {
+	if len(klines) < 2*period+1 {
+		return nil
+	}
+
+	n := len(klines) - 1
+	trs := make([]float64, n)
+	plusDMs := make([]float64, n)
+	minusDMs := make([]float64, n)
+	for i := 1; i < len(klines); i++ {
+		cur, prev := klines[i], klines[i-1]
+		trs[i-1] = math.Max(cur.High-cur.Low, math.Max(math.Abs(cur.High-prev.Close), math.Abs(cur.Low-prev.Close)))
+		up, down := cur.High-prev.High, prev.Low-cur.Low
+		if up > down && up > 0 {
+			plusDMs[i-1] = up
+		}
+		if down > up && down > 0 {
+			minusDMs[i-1] = down
+		}
+	}
+
+	// 初始值为前period个的和，之后Wilder平滑
+	var tr, plusDM, minusDM float64
+	for i := 0; i < period; i++ {
+		tr += trs[i]
+		plusDM += plusDMs[i]
+		minusDM += minusDMs[i]
+	}
+
+	di := func() (float64, float64, float64) {
+		if tr == 0 {
+			return 0, 0, 0
+		}
+		plus, minus := plusDM/tr*100, minusDM/tr*100
+		if plus+minus == 0 {
+			return plus, minus, 0
+		}
+		return plus, minus, math.Abs(plus-minus) / (plus + minus) * 100
+	}
+
+	plusDI, minusDI, dx := di()
+	dxs := []float64{dx}
+	for i := period; i < n; i++ {
+		tr = tr - tr/float64(period) + trs[i]
+		plusDM = plusDM - plusDM/float64(period) + plusDMs[i]
+		minusDM = minusDM - minusDM/float64(period) + minusDMs[i]
+		plusDI, minusDI, dx = di()
+		dxs = append(dxs, dx)
+	}
+
+	// ADX = DX的Wilder平滑
+	adx := 0.0
+	for _, v := range dxs[:period] {
+		adx += v
+	}
+	adx /= float64(period)
+	for _, v := range dxs[period:] {
+		adx = (adx*float64(period-1) + v) / float64(period)
+	}
+
+	return &ADXData{ADX: adx, PlusDI: plusDI, MinusDI: minusDI}
+}
+
+// calculateSessionVWAP 计算当日（UTC）会话的VWAP（只使用窗口内属于当日的K线，没有成交量时返回0）
+func calculateSessionVWAP(klines []Kline) float64 {
+	if len(klines) == 0 {
+		return 0
+	}
+	last := time.UnixMilli(klines[len(klines)-1].OpenTime).UTC()
+	sessionStart := time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, time.UTC).UnixMilli()
+
+	var pv, volume float64
+	for _, k := range klines {
+		if k.OpenTime < sessionStart {
+			continue
+		}
+		typical := (k.High + k.Low + k.Close) / 3
+		pv += typical * k.Volume
+		volume += k.Volume
+	}
+	if volume == 0 {
+		return 0
+	}
+	return pv / volume
+}
+
+// calculateStochastic 计算随机指标（数据不足时返回nil）
+func calculateStochastic(klines []Kline, period, smoothK, smoothD int) *StochasticData {
+	if len(klines) < period+smoothK+smoothD-2 {
+		return nil
+	}
+
+	// 原始%K序列（只需要最后smoothK+smoothD-1个）
+	count := smoothK + smoothD - 1
+	raw := make([]float64, 0, count)
+	for end := len(klines) - count; end < len(klines); end++ {
+		window := klines[end-period+1 : end+1]
+		high, low := window[0].High, window[0].Low
+		for _, k := range window[1:] {
+			high = math.Max(high, k.High)
+			low = math.Min(low, k.Low)
+		}
+		if high > low {
+			raw = append(raw, (klines[end].Close-low)/(high-low)*100)
+		} else {
+			raw = append(raw, 50)
+		}
+	}
+
+	// 平滑%K，再取均值得到%D
+	ks := make([]float64, 0, smoothD)
+	for i := smoothK - 1; i < len(raw); i++ {
+		ks = append(ks, average(raw[i-smoothK+1:i+1]))
+	}
+	return &StochasticData{K: ks[len(ks)-1], D: average(ks)}
+}
+
+// average 算术平均
+func average(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// bollingerState 布林带位置解读
+func bollingerState(b *BollingerData) string {
+	switch {
+	case b.PercentB > 1:
+		return "above upper band"
+	case b.PercentB >= 0.8:
+		return "near upper band"
+	case b.PercentB < 0:
+		return "below lower band"
+	case b.PercentB <= 0.2:
+		return "near lower band"
+	}
+	return "inside bands"
+}
+
+// adxTrendState ADX趋势强度和方向解读
+func adxTrendState(a *ADXData) string {
+	direction := "bullish"
+	if a.MinusDI > a.PlusDI {
+		direction = "bearish"
+	}
+	switch {
+	case a.ADX < 20:
+		return "weak or no trend"
+	case a.ADX < 25:
+		return "emerging " + direction + " trend"
+	case a.ADX < 40:
+		return "strong " + direction + " trend"
+	}
+	return "very strong " + direction + " trend"
+}
+
+// stochasticState 随机指标解读
+func stochasticState(s *StochasticData) string {
+	zone := "neutral"
+	switch {
+	case s.K >= 80:
+		zone = "overbought"
+	case s.K <= 20:
+		zone = "oversold"
+	}
+	if s.K > s.D {
+		return zone + ", %K above %D"
+	}
+	return zone + ", %K below %D"
+}
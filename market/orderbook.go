@@ -0,0 +1,152 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// 订单簿参数
+const (
+	orderBookLimit     = 20    // 获取的档位数量（权重2）
+	orderBookImpactUSD = 10000 // 计算深度加权价差使用的成交金额
+)
+
+// OrderBook 订单簿流动性指标
+type OrderBook struct {
+	BestBid        float64
+	BestAsk        float64
+	SpreadBps      float64 // 买一卖一价差（基点）
+	DepthSpreadBps float64 // 按orderBookImpactUSD双向吃单的成交均价价差（基点），深度不足时按全部档位计算
+	BidDepthUSD    float64 // 买盘前orderBookLimit档的名义价值
+	AskDepthUSD    float64 // 卖盘前orderBookLimit档的名义价值
+	Imbalance      float64 // 买卖盘不平衡 = (买盘-卖盘)/(买盘+卖盘)，范围-1到1，正数表示买盘更厚
+}
+
+// GetOrderBook 获取订单簿并计算流动性指标
+func GetOrderBook(symbol string) (*OrderBook, error) {
+	return getOrderBook(context.Background(), symbol)
+}
+
+// getOrderBook 获取订单簿并计算流动性指标（ctx取消时中止请求）
+func getOrderBook(ctx context.Context, symbol string) (*OrderBook, error) {
+	symbol = Normalize(symbol)
+	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/depth?symbol=%s&limit=%d", symbol, orderBookLimit)
+
+	resp, err := get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取订单簿失败 (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Bids [][]string `json:"bids"`
+		Asks [][]string `json:"asks"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	bids, err := parseLevels(result.Bids)
+	if err != nil {
+		return nil, err
+	}
+	asks, err := parseLevels(result.Asks)
+	if err != nil {
+		return nil, err
+	}
+	return buildOrderBook(bids, asks)
+}
+
+// bookLevel 订单簿档位
+type bookLevel struct {
+	price, qty float64
+}
+
+// parseLevels 解析 [["价格","数量"], ...] 格式的档位
+func parseLevels(raw [][]string) ([]bookLevel, error) {
+	levels := make([]bookLevel, 0, len(raw))
+	for _, item := range raw {
+		if len(item) < 2 {
+			continue
+		}
+		price, err := parseFloat(item[0])
+		if err != nil {
+			return nil, fmt.Errorf("解析订单簿价格失败: %w", err)
+		}
+		qty, err := parseFloat(item[1])
+		if err != nil {
+			return nil, fmt.Errorf("解析订单簿数量失败: %w", err)
+		}
+		levels = append(levels, bookLevel{price: price, qty: qty})
+	}
+	return levels, nil
+}
+
+// buildOrderBook 根据买卖盘档位计算流动性指标（档位按最优价在前排序）
+func buildOrderBook(bids, asks []bookLevel) (*OrderBook, error) {
+	if len(bids) == 0 || len(asks) == 0 {
+		return nil, fmt.Errorf("订单簿为空")
+	}
+
+	ob := &OrderBook{BestBid: bids[0].price, BestAsk: asks[0].price}
+	mid := (ob.BestBid + ob.BestAsk) / 2
+	if mid <= 0 {
+		return nil, fmt.Errorf("订单簿价格无效")
+	}
+	ob.SpreadBps = (ob.BestAsk - ob.BestBid) / mid * 10000
+
+	for _, l := range bids {
+		ob.BidDepthUSD += l.price * l.qty
+	}
+	for _, l := range asks {
+		ob.AskDepthUSD += l.price * l.qty
+	}
+	if total := ob.BidDepthUSD + ob.AskDepthUSD; total > 0 {
+		ob.Imbalance = (ob.BidDepthUSD - ob.AskDepthUSD) / total
+	}
+
+	ob.DepthSpreadBps = (impactPrice(asks, orderBookImpactUSD) - impactPrice(bids, orderBookImpactUSD)) / mid * 10000
+	return ob, nil
+}
+
+// impactPrice 按名义金额逐档吃单的成交均价（深度不足时返回全部档位的均价）
+func impactPrice(levels []bookLevel, notional float64) float64 {
+	var filledUSD, filledQty float64
+	for _, l := range levels {
+		remaining := notional - filledUSD
+		levelUSD := l.price * l.qty
+		if levelUSD >= remaining {
+			filledQty += remaining / l.price
+			filledUSD = notional
+			break
+		}
+		filledUSD += levelUSD
+		filledQty += l.qty
+	}
+	if filledQty == 0 {
+		return levels[0].price
+	}
+	return filledUSD / filledQty
+}
+
+// FormatOrderBook 格式化输出订单簿流动性指标
+func FormatOrderBook(ob *OrderBook) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Order book: best bid = %.4f, best ask = %.4f, spread = %.2f bps, depth‑weighted spread ($%d per side) = %.2f bps\n\n",
+		ob.BestBid, ob.BestAsk, ob.SpreadBps, orderBookImpactUSD, ob.DepthSpreadBps))
+	sb.WriteString(fmt.Sprintf("Top %d levels depth: bids = %.0f USD, asks = %.0f USD, imbalance = %+.2f\n\n",
+		orderBookLimit, ob.BidDepthUSD, ob.AskDepthUSD, ob.Imbalance))
+	return sb.String()
+}
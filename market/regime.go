@@ -0,0 +1,187 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 市场状态
+const (
+	RegimeTrending = "trending"        // 趋势（EMA20明显倾斜且与EMA50方向一致）
+	RegimeRanging  = "ranging"         // 震荡
+	RegimeHighVol  = "high_volatility" // 高波动（短期ATR明显放大或ATR占价格比例过高）
+)
+
+// 市场状态分类参数（基于BTC长期周期K线，默认4小时）
+const (
+	emaSlopeBars       = 5   // EMA20斜率的计算跨度（K线根数）
+	trendSlopePct      = 1.0 // EMA20在emaSlopeBars根K线内变化超过该百分比视为趋势
+	highVolATRRatio    = 1.5 // ATR3 / ATR14 超过该值视为波动放大
+	highVolATRPct      = 4.0 // ATR14占价格的百分比超过该值视为高波动
+	regimeCacheTTL     = 5 * time.Minute
+	fearGreedCacheTTL  = time.Hour // 恐惧贪婪指数每天更新一次
+	fearGreedURL       = "https://api.alternative.me/fng/?limit=1"
+	regimeSymbol       = "BTCUSDT"
+	externalAPITimeout = 10 * time.Second
+)
+
+// Regime 市场状态（由BTC的ATR和EMA斜率判断）
+type Regime struct {
+	Label       string  `json:"label"`     // trending / ranging / high_volatility
+	Direction   string  `json:"direction"` // 趋势方向: up / down（非趋势时为空）
+	ATRPct      float64 `json:"atr_pct"`   // ATR14占价格的百分比
+	ATRRatio    float64 `json:"atr_ratio"` // ATR3 / ATR14
+	EMASlopePct float64 `json:"ema_slope_pct"`
+}
+
+// Description 中文描述（写入prompt）
+func (r *Regime) Description() string {
+	switch r.Label {
+	case RegimeTrending:
+		if r.Direction == "down" {
+			return "下跌趋势"
+		}
+		return "上涨趋势"
+	case RegimeHighVol:
+		return "高波动"
+	default:
+		return "震荡"
+	}
+}
+
+// ClassifyRegime 根据BTC的长期周期指标判断市场状态（数据不足时返回nil）：
+// 短期ATR明显放大或ATR占价格比例过高为高波动；否则EMA20明显倾斜且与EMA50方向一致为趋势；其余为震荡
+func ClassifyRegime(data *Data) *Regime {
+	if data == nil || data.LongerTermContext == nil || data.CurrentPrice <= 0 {
+		return nil
+	}
+	lt := data.LongerTermContext
+	if lt.ATR14 <= 0 || lt.EMA50 <= 0 {
+		return nil
+	}
+
+	r := &Regime{
+		Label:       RegimeRanging,
+		ATRPct:      lt.ATR14 / data.CurrentPrice * 100,
+		ATRRatio:    lt.ATR3 / lt.ATR14,
+		EMASlopePct: lt.EMA20Slope,
+	}
+	switch {
+	case r.ATRRatio >= highVolATRRatio || r.ATRPct >= highVolATRPct:
+		r.Label = RegimeHighVol
+	case math.Abs(r.EMASlopePct) >= trendSlopePct && (r.EMASlopePct > 0) == (lt.EMA20 > lt.EMA50):
+		r.Label = RegimeTrending
+		r.Direction = "up"
+		if r.EMASlopePct < 0 {
+			r.Direction = "down"
+		}
+	}
+	return r
+}
+
+// FearGreed 加密货币恐惧贪婪指数（alternative.me，0-100，越低越恐惧）
+type FearGreed struct {
+	Value          int       `json:"value"`
+	Classification string    `json:"classification"` // Extreme Fear / Fear / Neutral / Greed / Extreme Greed
+	Time           time.Time `json:"time"`
+}
+
+// externalClient 访问非币安接口的HTTP客户端（不占用币安的请求权重）
+var externalClient = &http.Client{Timeout: externalAPITimeout}
+
+var (
+	regimeMu        sync.Mutex
+	cachedRegime    *Regime
+	regimeFetchedAt time.Time
+
+	fearGreedMu        sync.Mutex
+	cachedFearGreed    *FearGreed
+	fearGreedFetchedAt time.Time
+)
+
+// GetRegime 当前市场状态（按BTC默认周期数据计算，缓存5分钟）
+func GetRegime() (*Regime, error) {
+	regimeMu.Lock()
+	defer regimeMu.Unlock()
+	if cachedRegime != nil && time.Since(regimeFetchedAt) < regimeCacheTTL {
+		return cachedRegime, nil
+	}
+
+	data, err := Get(regimeSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("获取BTC行情失败: %w", err)
+	}
+	r := ClassifyRegime(data)
+	if r == nil {
+		return nil, fmt.Errorf("BTC长期K线数据不足，无法判断市场状态")
+	}
+	cachedRegime, regimeFetchedAt = r, time.Now()
+	return r, nil
+}
+
+// GetFearGreed 最新的恐惧贪婪指数（缓存1小时，获取失败时返回上次的结果）
+func GetFearGreed() (*FearGreed, error) {
+	fearGreedMu.Lock()
+	defer fearGreedMu.Unlock()
+	if cachedFearGreed != nil && time.Since(fearGreedFetchedAt) < fearGreedCacheTTL {
+		return cachedFearGreed, nil
+	}
+
+	fg, err := fetchFearGreed()
+	if err != nil {
+		if cachedFearGreed != nil {
+			return cachedFearGreed, nil
+		}
+		return nil, err
+	}
+	cachedFearGreed, fearGreedFetchedAt = fg, time.Now()
+	return fg, nil
+}
+
+// fetchFearGreed 从alternative.me获取恐惧贪婪指数
+func fetchFearGreed() (*FearGreed, error) {
+	resp, err := externalClient.Get(fearGreedURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取恐惧贪婪指数失败 (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Data []struct {
+			Value               string `json:"value"`
+			ValueClassification string `json:"value_classification"`
+			Timestamp           string `json:"timestamp"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析恐惧贪婪指数失败: %w", err)
+	}
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("没有恐惧贪婪指数数据")
+	}
+
+	latest := result.Data[0]
+	value, err := strconv.Atoi(latest.Value)
+	if err != nil {
+		return nil, fmt.Errorf("解析恐惧贪婪指数失败: %w", err)
+	}
+	fg := &FearGreed{Value: value, Classification: latest.ValueClassification}
+	if ts, err := strconv.ParseInt(latest.Timestamp, 10, 64); err == nil {
+		fg.Time = time.Unix(ts, 0)
+	}
+	return fg, nil
+}
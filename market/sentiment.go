@@ -0,0 +1,93 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// 多空比参数
+const (
+	longShortPeriod = "5m" // 统计周期
+	longShortLimit  = 13   // 获取的数据点数量（最新一个 + 1小时前的一个）
+)
+
+// LongShortRatio 全市场账户多空比（持有多仓账户数 / 持有空仓账户数）
+type LongShortRatio struct {
+	Ratio     float64 // 最新多空比
+	LongPct   float64 // 多仓账户占比（%）
+	ShortPct  float64 // 空仓账户占比（%）
+	Change1h  float64 // 相对1小时前的多空比变化
+	HasChange bool    // 数据点不足1小时时为false
+}
+
+// getLongShortRatio 获取全市场账户多空比（Binance /futures/data/globalLongShortAccountRatio）
+func getLongShortRatio(ctx context.Context, symbol string) (*LongShortRatio, error) {
+	url := fmt.Sprintf("https://fapi.binance.com/futures/data/globalLongShortAccountRatio?symbol=%s&period=%s&limit=%d",
+		symbol, longShortPeriod, longShortLimit)
+
+	resp, err := get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取多空比失败 (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	// 按时间升序返回
+	var result []struct {
+		LongShortRatio string `json:"longShortRatio"`
+		LongAccount    string `json:"longAccount"`
+		ShortAccount   string `json:"shortAccount"`
+		Timestamp      int64  `json:"timestamp"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%s 没有多空比数据", symbol)
+	}
+
+	latest := result[len(result)-1]
+	ratio, err := parseFloat(latest.LongShortRatio)
+	if err != nil {
+		return nil, fmt.Errorf("解析多空比失败: %w", err)
+	}
+	longAccount, _ := parseFloat(latest.LongAccount)
+	shortAccount, _ := parseFloat(latest.ShortAccount)
+
+	data := &LongShortRatio{
+		Ratio:    ratio,
+		LongPct:  longAccount * 100,
+		ShortPct: shortAccount * 100,
+	}
+	if len(result) == longShortLimit {
+		if past, err := parseFloat(result[0].LongShortRatio); err == nil {
+			data.Change1h, data.HasChange = ratio-past, true
+		}
+	}
+	return data, nil
+}
+
+// longShortState 多空比解读（账户比偏离1越多，散户仓位越拥挤）
+func longShortState(r *LongShortRatio) string {
+	switch {
+	case r.Ratio >= 2:
+		return "longs heavily crowded"
+	case r.Ratio >= 1.3:
+		return "long-leaning"
+	case r.Ratio <= 0.5:
+		return "shorts heavily crowded"
+	case r.Ratio <= 0.77:
+		return "short-leaning"
+	}
+	return "balanced"
+}
@@ -0,0 +1,107 @@
+package market
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// setupFeature 形态向量的一维：从行情数据中提取的无量纲指标，scale为该指标的典型波动幅度（用于归一化）
+type setupFeature struct {
+	name    string
+	scale   float64
+	extract func(d *Data) (float64, bool)
+}
+
+// setupFeatures 形态向量的各维（顺序固定，已保存的向量按此顺序解释；新增维度只能追加在末尾）
+var setupFeatures = []setupFeature{
+	{"RSI7", 15, func(d *Data) (float64, bool) { return d.CurrentRSI7, d.CurrentRSI7 > 0 }},
+	{"MACD%", 0.2, func(d *Data) (float64, bool) { return pctOf(d.CurrentMACD, d.CurrentPrice) }},
+	{"偏离EMA20%", 1, func(d *Data) (float64, bool) { return pctOf(d.CurrentPrice-d.CurrentEMA20, d.CurrentEMA20) }},
+	{"1h涨跌%", 1, func(d *Data) (float64, bool) { return d.PriceChange1h, true }},
+	{"4h涨跌%", 2.5, func(d *Data) (float64, bool) { return d.PriceChange4h, true }},
+	{"OI偏离%", 3, func(d *Data) (float64, bool) {
+		if d.OpenInterest == nil {
+			return 0, false
+		}
+		return pctOf(d.OpenInterest.Latest-d.OpenInterest.Average, d.OpenInterest.Average)
+	}},
+	{"资金费率bp", 1, func(d *Data) (float64, bool) { return d.FundingRate * 10000, true }},
+	{"ATR%", 1, func(d *Data) (float64, bool) {
+		if d.LongerTermContext == nil {
+			return 0, false
+		}
+		return pctOf(d.LongerTermContext.ATR14, d.CurrentPrice)
+	}},
+	{"EMA20斜率%", 2, func(d *Data) (float64, bool) {
+		if d.LongerTermContext == nil {
+			return 0, false
+		}
+		return d.LongerTermContext.EMA20Slope, true
+	}},
+	{"量比", 0.5, func(d *Data) (float64, bool) {
+		if d.LongerTermContext == nil || d.LongerTermContext.AverageVolume <= 0 {
+			return 0, false
+		}
+		return d.LongerTermContext.CurrentVolume / d.LongerTermContext.AverageVolume, true
+	}},
+}
+
+// pctOf a占b的百分比（b无效时返回false）
+func pctOf(a, b float64) (float64, bool) {
+	if b == 0 || math.IsNaN(a) || math.IsNaN(b) {
+		return 0, false
+	}
+	return a / b * 100, true
+}
+
+// SetupVector 把行情数据编码为形态向量（用于检索历史上相似的行情），缺少关键数据时返回nil
+func SetupVector(d *Data) []float64 {
+	if d == nil || d.CurrentPrice <= 0 {
+		return nil
+	}
+	vec := make([]float64, len(setupFeatures))
+	missing := 0
+	for i, f := range setupFeatures {
+		v, ok := f.extract(d)
+		if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+			missing++
+			continue
+		}
+		vec[i] = v
+	}
+	if missing > len(setupFeatures)/2 {
+		return nil
+	}
+	return vec
+}
+
+// SetupDistance 两个形态向量按各指标典型波动幅度归一化后的欧氏距离（维度不一致时只比较共同的维度）
+func SetupDistance(a, b []float64) float64 {
+	n := min(len(a), len(b), len(setupFeatures))
+	if n == 0 {
+		return math.Inf(1)
+	}
+	sum := 0.0
+	for i := 0; i < n; i++ {
+		diff := (a[i] - b[i]) / setupFeatures[i].scale
+		sum += diff * diff
+	}
+	return math.Sqrt(sum / float64(n))
+}
+
+// DescribeSetup 形态向量的简短描述（只列出RSI、偏离EMA20、1h/4h涨跌和OI偏离）
+func DescribeSetup(vec []float64) string {
+	var parts []string
+	for _, i := range []int{0, 2, 3, 4, 5} {
+		if i >= len(vec) {
+			break
+		}
+		format := "%s %+.1f"
+		if i == 0 {
+			format = "%s %.0f"
+		}
+		parts = append(parts, fmt.Sprintf(format, setupFeatures[i].name, vec[i]))
+	}
+	return strings.Join(parts, " | ")
+}
@@ -0,0 +1,533 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"nofx/logger"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var log = logger.Module("market")
+
+// 行情推送参数
+const (
+	streamURL           = "wss://fstream.binance.com/stream?streams=!markPrice@arr@1s/!forceOrder@arr"
+	streamMaxStreams    = 1000                   // 单连接最多订阅的K线流数量（Binance单连接上限1024个流）
+	streamBatchSize     = 100                    // 每条SUBSCRIBE消息包含的流数量
+	streamMsgInterval   = 200 * time.Millisecond // 订阅消息间隔（Binance限制每秒10条）
+	streamReadTimeout   = 30 * time.Second       // 全市场标记价格每秒推送，超过该时间没有消息视为断线
+	streamMaxBackoff    = time.Minute            // 断线重连最大等待
+	streamIdleSymbolTTL = 30 * time.Minute       // 超过该时间未被查询的币种取消订阅
+	oiRefreshInterval   = 10 * time.Minute       // 持仓量和多空比没有推送，按该间隔通过REST刷新
+	liquidationWindow   = time.Hour              // 强平统计窗口
+)
+
+// LiquidationData 最近一段时间的强平统计（仅WebSocket行情提供）
+type LiquidationData struct {
+	LongUSD  float64 // 多头被强平的名义价值
+	ShortUSD float64 // 空头被强平的名义价值
+	Count    int     // 强平单数量
+}
+
+// liquidation 单笔强平
+type liquidation struct {
+	time     time.Time
+	long     bool
+	notional float64
+}
+
+// symbolState 单个币种在内存中维护的行情
+type symbolState struct {
+	initMu sync.Mutex // 串行化REST初始化（同一币种并发查询时只初始化一次）
+
+	// 以下字段由Feed.mu保护
+	intervals    map[string]bool    // 已订阅的K线周期
+	klines       map[string][]Kline // 周期 -> 已通过REST初始化的K线窗口（断线后清空，重新初始化以补齐缺口）
+	fundingRate  float64
+	hasFunding   bool
+	oi           *OIData
+	longShort    *LongShortRatio
+	oiTime       time.Time
+	liquidations []liquidation
+	data         map[Timeframes]*Data // 缓存的计算结果（K线或资金费率变化后清空重新计算）
+	lastAccess   time.Time
+}
+
+// Feed 通过Binance组合流（K线、标记价格、强平）在内存中维护行情，查询时无需REST请求
+type Feed struct {
+	mu          sync.Mutex
+	symbols     map[string]*symbolState
+	streamCount int // 已订阅的K线流数量
+	connected   bool
+
+	conn    *websocket.Conn
+	writeMu sync.Mutex // gorilla/websocket不允许并发写
+	nextID  int
+
+	stopCh chan struct{}
+}
+
+var (
+	defaultFeed   *Feed
+	defaultFeedMu sync.RWMutex
+)
+
+// StartFeed 启动WebSocket行情（之后Get优先从内存读取，断线或未就绪时回退到REST）
+func StartFeed() *Feed {
+	defaultFeedMu.Lock()
+	defer defaultFeedMu.Unlock()
+	if defaultFeed == nil {
+		defaultFeed = &Feed{symbols: make(map[string]*symbolState), stopCh: make(chan struct{})}
+		go defaultFeed.run()
+		go defaultFeed.sweepIdle()
+	}
+	return defaultFeed
+}
+
+// StopFeed 停止WebSocket行情
+func StopFeed() {
+	defaultFeedMu.Lock()
+	feed := defaultFeed
+	defaultFeed = nil
+	defaultFeedMu.Unlock()
+	if feed != nil {
+		feed.stop()
+	}
+}
+
+// activeFeed 当前运行的行情（未启动时返回nil）
+func activeFeed() *Feed {
+	defaultFeedMu.RLock()
+	defer defaultFeedMu.RUnlock()
+	return defaultFeed
+}
+
+// Get 从内存读取币种行情（首次查询时订阅对应周期的K线流并用REST初始化窗口）
+func (f *Feed) Get(symbol string, tf Timeframes) (*Data, error) {
+	f.mu.Lock()
+	if !f.connected {
+		f.mu.Unlock()
+		return nil, fmt.Errorf("行情WebSocket未连接")
+	}
+	st := f.symbols[symbol]
+	if st == nil {
+		st = &symbolState{intervals: make(map[string]bool), klines: make(map[string][]Kline)}
+	}
+	var newIntervals []string
+	for _, interval := range []string{tf.Intraday, tf.LongerTerm} {
+		if !st.intervals[interval] {
+			newIntervals = append(newIntervals, interval)
+		}
+	}
+	if f.streamCount+len(newIntervals) > streamMaxStreams {
+		f.mu.Unlock()
+		return nil, fmt.Errorf("订阅K线流数量已达上限(%d)", streamMaxStreams)
+	}
+	f.symbols[symbol] = st
+	for _, interval := range newIntervals {
+		st.intervals[interval] = true
+	}
+	f.streamCount += len(newIntervals)
+	st.lastAccess = time.Now()
+	f.mu.Unlock()
+
+	if len(newIntervals) > 0 {
+		if err := f.send("SUBSCRIBE", klineStreams(symbol, newIntervals...)); err != nil {
+			log.Printf("⚠️  订阅 %s 行情失败: %v", symbol, err)
+		}
+	}
+
+	if err := f.ensureReady(symbol, st, tf); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if data := st.data[tf]; data != nil {
+		return data, nil
+	}
+	intraday, longer := st.klines[tf.Intraday], st.klines[tf.LongerTerm]
+	if len(intraday) == 0 || len(longer) == 0 {
+		return nil, fmt.Errorf("%s K线窗口尚未就绪", symbol) // 初始化后恰好断线
+	}
+	if len(intraday) > intradayWindow {
+		intraday = intraday[len(intraday)-intradayWindow:]
+	}
+	data := BuildDataWithTimeframes(symbol, tf, intraday, longer, st.oi, st.fundingRate)
+	data.LongShort = st.longShort
+	data.Liquidations = st.liquidationStats(time.Now())
+	if st.data == nil {
+		st.data = make(map[Timeframes]*Data)
+	}
+	st.data[tf] = data
+	return data, nil
+}
+
+// ensureReady 初始化K线窗口、资金费率，并按间隔刷新持仓量和多空比（这些REST请求不持有Feed.mu）
+func (f *Feed) ensureReady(symbol string, st *symbolState, tf Timeframes) error {
+	st.initMu.Lock()
+	defer st.initMu.Unlock()
+
+	f.mu.Lock()
+	var missing []string
+	for _, interval := range []string{tf.Intraday, tf.LongerTerm} {
+		if _, ready := st.klines[interval]; !ready {
+			missing = append(missing, interval)
+		}
+	}
+	hasFunding := st.hasFunding
+	refreshOI := time.Since(st.oiTime) >= oiRefreshInterval
+	f.mu.Unlock()
+
+	// 每个周期都保存长期窗口长度的K线，日内数据计算时截取末尾，与REST模式结果一致
+	for _, interval := range missing {
+		klines, err := getKlines(context.Background(), symbol, interval, longerTermWindow)
+		if err != nil {
+			return fmt.Errorf("获取%sK线失败: %v", IntervalLabelCN(interval), err)
+		}
+		if len(klines) == 0 {
+			return fmt.Errorf("%s 没有%sK线数据", symbol, IntervalLabelCN(interval))
+		}
+		f.mu.Lock()
+		st.klines[interval] = klines
+		st.data = nil
+		f.mu.Unlock()
+	}
+
+	if !hasFunding {
+		if rate, err := getFundingRate(context.Background(), symbol); err == nil {
+			f.mu.Lock()
+			if !st.hasFunding {
+				st.fundingRate, st.hasFunding = rate, true
+				st.data = nil
+			}
+			f.mu.Unlock()
+		}
+	}
+
+	if refreshOI {
+		oi, err := getOpenInterestData(context.Background(), symbol)
+		if err != nil {
+			oi = &OIData{Latest: 0, Average: 0}
+		}
+		longShort, _ := getLongShortRatio(context.Background(), symbol)
+		f.mu.Lock()
+		st.oi, st.longShort, st.oiTime = oi, longShort, time.Now()
+		st.data = nil
+		f.mu.Unlock()
+	}
+	return nil
+}
+
+// run 连接并读取行情，断线后指数退避重连
+func (f *Feed) run() {
+	backoff := time.Second
+	for {
+		start := time.Now()
+		err := f.connectAndRead()
+
+		f.mu.Lock()
+		f.connected = false
+		// 断线期间的K线缺失，重连后重新初始化
+		for _, st := range f.symbols {
+			st.klines = make(map[string][]Kline)
+			st.data = nil
+		}
+		f.mu.Unlock()
+
+		select {
+		case <-f.stopCh:
+			return
+		default:
+		}
+
+		if time.Since(start) > streamMaxBackoff {
+			backoff = time.Second
+		}
+		log.Printf("⚠️  行情WebSocket断开: %v，%v后重连（期间使用REST）", err, backoff)
+		select {
+		case <-f.stopCh:
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > streamMaxBackoff {
+			backoff = streamMaxBackoff
+		}
+	}
+}
+
+// connectAndRead 建立连接、重新订阅已跟踪的币种并处理推送（返回时连接已关闭）
+func (f *Feed) connectAndRead() error {
+	conn, _, err := websocket.DefaultDialer.Dial(streamURL, nil)
+	if err != nil {
+		return fmt.Errorf("连接失败: %w", err)
+	}
+	defer conn.Close()
+
+	f.writeMu.Lock()
+	f.conn = conn
+	f.writeMu.Unlock()
+	defer func() {
+		f.writeMu.Lock()
+		f.conn = nil
+		f.writeMu.Unlock()
+	}()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-f.stopCh:
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	f.mu.Lock()
+	var streams []string
+	for symbol, st := range f.symbols {
+		for interval := range st.intervals {
+			streams = append(streams, klineStreams(symbol, interval)...)
+		}
+	}
+	f.connected = true
+	f.mu.Unlock()
+	log.Printf("📡 行情WebSocket已连接（跟踪 %d 个K线流）", len(streams))
+
+	if len(streams) > 0 {
+		go func() {
+			if err := f.send("SUBSCRIBE", streams); err != nil {
+				log.Printf("⚠️  重新订阅行情失败: %v", err)
+			}
+		}()
+	}
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(streamReadTimeout))
+		var msg struct {
+			Stream string          `json:"stream"`
+			Data   json.RawMessage `json:"data"`
+		}
+		if err := conn.ReadJSON(&msg); err != nil {
+			return err
+		}
+		if msg.Stream == "" {
+			continue // SUBSCRIBE/UNSUBSCRIBE的响应
+		}
+		if err := f.handle(msg.Stream, msg.Data); err != nil {
+			log.Printf("⚠️  解析行情推送失败 (%s): %v", msg.Stream, err)
+		}
+	}
+}
+
+// handle 按流类型更新内存行情
+func (f *Feed) handle(stream string, data json.RawMessage) error {
+	switch {
+	case strings.Contains(stream, "@kline_"):
+		var event struct {
+			Symbol string `json:"s"`
+			K      struct {
+				OpenTime  int64  `json:"t"`
+				CloseTime int64  `json:"T"`
+				Interval  string `json:"i"`
+				Open      string `json:"o"`
+				High      string `json:"h"`
+				Low       string `json:"l"`
+				Close     string `json:"c"`
+				Volume    string `json:"v"`
+			} `json:"k"`
+		}
+		if err := json.Unmarshal(data, &event); err != nil {
+			return err
+		}
+		k := Kline{OpenTime: event.K.OpenTime, CloseTime: event.K.CloseTime}
+		k.Open, _ = parseFloat(event.K.Open)
+		k.High, _ = parseFloat(event.K.High)
+		k.Low, _ = parseFloat(event.K.Low)
+		k.Close, _ = parseFloat(event.K.Close)
+		k.Volume, _ = parseFloat(event.K.Volume)
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		st := f.symbols[event.Symbol]
+		if st == nil {
+			return nil
+		}
+		klines, ready := st.klines[event.K.Interval]
+		if !ready {
+			return nil
+		}
+		st.klines[event.K.Interval] = mergeKline(klines, k, longerTermWindow)
+		st.data = nil
+
+	case strings.HasPrefix(stream, "!markPrice@arr"):
+		var events []struct {
+			Symbol      string `json:"s"`
+			FundingRate string `json:"r"`
+		}
+		if err := json.Unmarshal(data, &events); err != nil {
+			return err
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for _, e := range events {
+			st := f.symbols[e.Symbol]
+			if st == nil {
+				continue
+			}
+			rate, _ := parseFloat(e.FundingRate)
+			if !st.hasFunding || rate != st.fundingRate {
+				st.fundingRate, st.hasFunding = rate, true
+				st.data = nil
+			}
+		}
+
+	case strings.HasPrefix(stream, "!forceOrder@arr"):
+		var event struct {
+			Order struct {
+				Symbol    string `json:"s"`
+				Side      string `json:"S"`
+				AvgPrice  string `json:"ap"`
+				FilledQty string `json:"z"`
+				Time      int64  `json:"T"`
+			} `json:"o"`
+		}
+		if err := json.Unmarshal(data, &event); err != nil {
+			return err
+		}
+		price, _ := parseFloat(event.Order.AvgPrice)
+		qty, _ := parseFloat(event.Order.FilledQty)
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		st := f.symbols[event.Order.Symbol]
+		if st == nil {
+			return nil
+		}
+		st.liquidations = append(st.liquidations, liquidation{
+			time:     time.UnixMilli(event.Order.Time),
+			long:     event.Order.Side == "SELL", // 卖出强平单对应多头仓位
+			notional: price * qty,
+		})
+		st.data = nil
+	}
+	return nil
+}
+
+// mergeKline 用推送的K线更新窗口：同一根K线覆盖，新K线追加并保持窗口长度
+func mergeKline(klines []Kline, k Kline, window int) []Kline {
+	if n := len(klines); n > 0 {
+		last := klines[n-1]
+		switch {
+		case k.OpenTime == last.OpenTime:
+			klines[n-1] = k
+			return klines
+		case k.OpenTime < last.OpenTime:
+			return klines
+		}
+	}
+	klines = append(klines, k)
+	if len(klines) > window {
+		klines = append(klines[:0:0], klines[len(klines)-window:]...)
+	}
+	return klines
+}
+
+// liquidationStats 统计窗口内的强平并清理过期记录（调用方持有Feed.mu）
+func (st *symbolState) liquidationStats(now time.Time) *LiquidationData {
+	cutoff := now.Add(-liquidationWindow)
+	kept := st.liquidations[:0]
+	stats := &LiquidationData{}
+	for _, l := range st.liquidations {
+		if l.time.Before(cutoff) {
+			continue
+		}
+		kept = append(kept, l)
+		stats.Count++
+		if l.long {
+			stats.LongUSD += l.notional
+		} else {
+			stats.ShortUSD += l.notional
+		}
+	}
+	st.liquidations = kept
+	return stats
+}
+
+// sweepIdle 定期取消长时间未被查询的币种订阅
+func (f *Feed) sweepIdle() {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		f.mu.Lock()
+		var streams []string
+		idle := 0
+		for symbol, st := range f.symbols {
+			if time.Since(st.lastAccess) > streamIdleSymbolTTL {
+				delete(f.symbols, symbol)
+				idle++
+				for interval := range st.intervals {
+					streams = append(streams, klineStreams(symbol, interval)...)
+				}
+			}
+		}
+		f.streamCount -= len(streams)
+		f.mu.Unlock()
+
+		if len(streams) > 0 {
+			log.Printf("📡 取消订阅 %d 个长时间未使用的币种", idle)
+			if err := f.send("UNSUBSCRIBE", streams); err != nil {
+				log.Printf("⚠️  取消订阅行情失败: %v", err)
+			}
+		}
+	}
+}
+
+// send 发送订阅/取消订阅请求（按批发送，遵守消息频率限制；未连接时忽略，重连后会重新订阅）
+func (f *Feed) send(method string, streams []string) error {
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+	if f.conn == nil {
+		return nil
+	}
+	for i := 0; i < len(streams); i += streamBatchSize {
+		end := i + streamBatchSize
+		if end > len(streams) {
+			end = len(streams)
+		}
+		f.nextID++
+		req := map[string]interface{}{"method": method, "params": streams[i:end], "id": f.nextID}
+		if err := f.conn.WriteJSON(req); err != nil {
+			return err
+		}
+		time.Sleep(streamMsgInterval)
+	}
+	return nil
+}
+
+// stop 关闭连接并停止重连
+func (f *Feed) stop() {
+	close(f.stopCh)
+}
+
+// klineStreams 币种各周期对应的K线流名称
+func klineStreams(symbol string, intervals ...string) []string {
+	s := strings.ToLower(symbol)
+	streams := make([]string, len(intervals))
+	for i, interval := range intervals {
+		streams[i] = s + "@kline_" + interval
+	}
+	return streams
+}
@@ -0,0 +1,130 @@
+package market
+
+import (
+	"fmt"
+	"time"
+)
+
+// K线窗口长度（REST和WebSocket行情一致，保证指标计算结果相同）
+const (
+	intradayWindow   = 40 // 日内周期K线数量（多获取一些用于计算）
+	longerTermWindow = 60 // 长期周期K线数量（多获取用于计算指标）
+)
+
+// Timeframes 分析使用的K线周期
+type Timeframes struct {
+	Intraday   string `json:"intraday"`    // 日内周期（默认3m）
+	LongerTerm string `json:"longer_term"` // 长期周期（默认4h）
+}
+
+// DefaultTimeframes 默认周期：3分钟 + 4小时
+var DefaultTimeframes = Timeframes{Intraday: "3m", LongerTerm: "4h"}
+
+// intervalDurations 支持的Binance K线周期
+var intervalDurations = map[string]time.Duration{
+	"1m":  time.Minute,
+	"3m":  3 * time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"30m": 30 * time.Minute,
+	"1h":  time.Hour,
+	"2h":  2 * time.Hour,
+	"4h":  4 * time.Hour,
+	"6h":  6 * time.Hour,
+	"8h":  8 * time.Hour,
+	"12h": 12 * time.Hour,
+	"1d":  24 * time.Hour,
+	"3d":  3 * 24 * time.Hour,
+	"1w":  7 * 24 * time.Hour,
+}
+
+// IntervalDuration K线周期对应的时长（不支持的周期返回false）
+func IntervalDuration(interval string) (time.Duration, bool) {
+	d, ok := intervalDurations[interval]
+	return d, ok
+}
+
+// WithDefaults 为空的周期填充默认值
+func (tf Timeframes) WithDefaults() Timeframes {
+	if tf.Intraday == "" {
+		tf.Intraday = DefaultTimeframes.Intraday
+	}
+	if tf.LongerTerm == "" {
+		tf.LongerTerm = DefaultTimeframes.LongerTerm
+	}
+	return tf
+}
+
+// Validate 校验周期是否受支持，且长期周期大于日内周期
+func (tf Timeframes) Validate() error {
+	tf = tf.WithDefaults()
+	intraday, ok := IntervalDuration(tf.Intraday)
+	if !ok {
+		return fmt.Errorf("不支持的日内K线周期: %s", tf.Intraday)
+	}
+	longer, ok := IntervalDuration(tf.LongerTerm)
+	if !ok {
+		return fmt.Errorf("不支持的长期K线周期: %s", tf.LongerTerm)
+	}
+	if longer <= intraday {
+		return fmt.Errorf("长期K线周期(%s)必须大于日内K线周期(%s)", tf.LongerTerm, tf.Intraday)
+	}
+	return nil
+}
+
+// IntervalLabel K线周期的英文描述（如 3‑minute、4‑hour，用于市场数据）
+func IntervalLabel(interval string) string {
+	n, unit := splitInterval(interval)
+	switch unit {
+	case 'm':
+		return n + "‑minute"
+	case 'h':
+		return n + "‑hour"
+	case 'd':
+		return n + "‑day"
+	case 'w':
+		return n + "‑week"
+	}
+	return interval
+}
+
+// IntervalLabelCN K线周期的中文描述（如 3分钟、4小时，用于System Prompt）
+func IntervalLabelCN(interval string) string {
+	n, unit := splitInterval(interval)
+	switch unit {
+	case 'm':
+		return n + "分钟"
+	case 'h':
+		return n + "小时"
+	case 'd':
+		return n + "天"
+	case 'w':
+		return n + "周"
+	}
+	return interval
+}
+
+// splitInterval 拆分周期的数字和单位（如 "15m" -> "15", 'm'）
+func splitInterval(interval string) (string, byte) {
+	if len(interval) < 2 {
+		return interval, 0
+	}
+	return interval[:len(interval)-1], interval[len(interval)-1]
+}
+
+// priceChangePct 计算相对于d时长之前的价格变化百分比（K线周期不能整除d或数据不足时返回false）
+func priceChangePct(klines []Kline, interval string, d time.Duration, currentPrice float64) (float64, bool) {
+	step, ok := IntervalDuration(interval)
+	if !ok || d%step != 0 {
+		return 0, false
+	}
+	bars := int(d / step)
+	if len(klines) < bars+1 {
+		return 0, false
+	}
+	past := klines[len(klines)-1-bars].Close
+	if past <= 0 {
+		return 0, true
+	}
+	return (currentPrice - past) / past * 100, true
+}
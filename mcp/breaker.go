@@ -0,0 +1,154 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen AI提供商已熔断（连续失败次数达到阈值，冷却期内不再请求）
+var ErrCircuitOpen = errors.New("AI提供商已熔断")
+
+const (
+	defaultBreakerThreshold = 3
+	defaultBreakerCooldown  = 60 * time.Second
+)
+
+// breakerSettings 熔断参数（所有提供商共用，可热加载）
+var breakerSettings = struct {
+	sync.RWMutex
+	threshold int
+	cooldown  time.Duration
+}{threshold: defaultBreakerThreshold, cooldown: defaultBreakerCooldown}
+
+// SetBreakerConfig 设置熔断参数：连续失败threshold次后熔断，cooldown后放行一次试探请求（threshold<=0表示不熔断）
+func SetBreakerConfig(threshold int, cooldown time.Duration) {
+	breakerSettings.Lock()
+	defer breakerSettings.Unlock()
+	breakerSettings.threshold = threshold
+	breakerSettings.cooldown = cooldown
+}
+
+// breakerState 熔断器状态
+type breakerState int
+
+const (
+	breakerClosed   breakerState = iota // 正常
+	breakerOpen                         // 熔断中，直接拒绝请求
+	breakerHalfOpen                     // 冷却结束，放行一次试探请求
+)
+
+// String 状态名称（用于健康状态接口）
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// breaker 单个AI提供商的熔断器和健康统计（同一提供商的所有trader共用，一个trader发现故障后其他trader不再等待重试）
+type breaker struct {
+	provider Provider
+	baseURL  string
+	mu       sync.Mutex
+	state    breakerState
+	failures int       // 连续失败次数
+	openedAt time.Time // 最近一次熔断或放行试探请求的时间
+	health   healthWindow
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = make(map[string]*breaker) // 提供商 + 地址 -> 熔断器
+)
+
+// breaker 当前客户端所属提供商的熔断器（按提供商和API地址区分，自定义API各自独立）
+func (cfg *Client) breaker() *breaker {
+	key := string(cfg.Provider) + " " + cfg.BaseURL
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	b, ok := breakers[key]
+	if !ok {
+		b = &breaker{provider: cfg.Provider, baseURL: cfg.BaseURL}
+		breakers[key] = b
+	}
+	return b
+}
+
+// allow 是否可以发送请求（熔断冷却结束后只放行一次试探请求，其余请求在试探结果出来前仍被拒绝；
+// 试探请求被取消、没有结果时，再过一个冷却期重新试探）
+func (b *breaker) allow() bool {
+	breakerSettings.RLock()
+	threshold, cooldown := breakerSettings.threshold, breakerSettings.cooldown
+	breakerSettings.RUnlock()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case threshold <= 0 || b.state == breakerClosed:
+		return true
+	case time.Since(b.openedAt) >= cooldown:
+		b.state = breakerHalfOpen
+		b.openedAt = time.Now()
+		log.Printf("🔌 %s 熔断冷却结束，发送试探请求", b.provider)
+		return true
+	default:
+		return false
+	}
+}
+
+// record 记录一次请求结果和耗时（只有服务故障类错误计为失败，提供商返回的业务错误说明服务可用）
+func (b *breaker) record(err error, latency time.Duration) {
+	breakerSettings.RLock()
+	threshold := breakerSettings.threshold
+	breakerSettings.RUnlock()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.health.add(err, latency)
+	if err == nil || !isOutageError(err) {
+		if b.state != breakerClosed {
+			log.Printf("✅ %s 已恢复，解除熔断", b.provider)
+		}
+		b.state = breakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	switch {
+	case b.state == breakerHalfOpen:
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+		log.Printf("🔌 %s 试探请求失败，继续熔断: %v", b.provider, err)
+	case threshold > 0 && b.state == breakerClosed && b.failures >= threshold:
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+		log.Printf("🔌 %s 连续失败%d次，熔断: %v", b.provider, b.failures, err)
+	}
+}
+
+// statusError AI接口返回的非200状态码
+type statusError struct {
+	status int
+	body   string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("API返回错误 (status %d): %s", e.status, e.body)
+}
+
+// isOutageError 是否为提供商服务故障（网络错误、超时、5xx和限流），请求参数等错误不算
+func isOutageError(err error) bool {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.status >= 500 || se.status == http.StatusTooManyRequests
+	}
+	return isRetryableError(err)
+}
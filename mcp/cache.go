@@ -0,0 +1,105 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// cacheKeyType context中响应缓存键的类型
+type cacheKeyType struct{}
+
+// WithCacheKey 为本次调用指定响应缓存键（通常是去掉时间等易变内容后的prompt），
+// 客户端开启CacheTTL时，同一提供商和模型下缓存键相同的调用复用第一次调用的响应
+func WithCacheKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, cacheKeyType{}, key)
+}
+
+// cacheEntry 一条缓存的响应（请求进行中时其他调用等待done，不重复请求）
+type cacheEntry struct {
+	done    chan struct{} // 请求结束时关闭
+	content string
+	err     error
+	at      time.Time // 响应时间
+	expires time.Time // 按写入方的CacheTTL计算的过期时间（用于清理）
+}
+
+// responseCache 所有客户端共用的响应缓存（相同配置的trader之间复用）
+var responseCache = struct {
+	sync.Mutex
+	entries map[string]*cacheEntry
+}{entries: make(map[string]*cacheEntry)}
+
+// withCache 开启缓存且ctx带有缓存键时，优先返回未过期的缓存响应，否则调用call并缓存成功的结果
+// 命中缓存时不产生token用量；onDelta不为nil时把缓存的响应一次性输出
+func (cfg *Client) withCache(ctx context.Context, kind string, onDelta func(string), call func() (string, error)) (string, error) {
+	material, _ := ctx.Value(cacheKeyType{}).(string)
+	if cfg.CacheTTL <= 0 || material == "" {
+		return call()
+	}
+	sum := sha256.Sum256([]byte(kind + "\x00" + string(cfg.Provider) + "\x00" + cfg.BaseURL + "\x00" + cfg.Model + "\x00" + material))
+	key := hex.EncodeToString(sum[:])
+
+	for {
+		responseCache.Lock()
+		pruneResponseCache()
+		entry, ok := responseCache.entries[key]
+		if !ok {
+			entry = &cacheEntry{done: make(chan struct{})}
+			responseCache.entries[key] = entry
+			responseCache.Unlock()
+			return cfg.fillCache(key, entry, call)
+		}
+		responseCache.Unlock()
+
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		if entry.err == nil && time.Since(entry.at) <= cfg.CacheTTL {
+			log.Printf("♻️  复用%v前相同prompt的AI响应（%s %s）", time.Since(entry.at).Round(time.Second), cfg.Provider, cfg.Model)
+			if onDelta != nil {
+				onDelta(entry.content)
+			}
+			return entry.content, nil
+		}
+		// 失败或超过本客户端的缓存时间：移除后重新请求
+		responseCache.Lock()
+		if responseCache.entries[key] == entry {
+			delete(responseCache.entries, key)
+		}
+		responseCache.Unlock()
+	}
+}
+
+// fillCache 发送请求并写入缓存项（失败时移除，等待中的调用各自重新请求）
+func (cfg *Client) fillCache(key string, entry *cacheEntry, call func() (string, error)) (string, error) {
+	content, err := call()
+	entry.content, entry.err = content, err
+	entry.at = time.Now()
+	entry.expires = entry.at.Add(cfg.CacheTTL)
+	if err != nil {
+		responseCache.Lock()
+		delete(responseCache.entries, key)
+		responseCache.Unlock()
+	}
+	close(entry.done)
+	return content, err
+}
+
+// pruneResponseCache 清理已过期的缓存项（调用方持有锁）
+func pruneResponseCache() {
+	now := time.Now()
+	for key, entry := range responseCache.entries {
+		select {
+		case <-entry.done:
+			if now.After(entry.expires) {
+				delete(responseCache.entries, key)
+			}
+		default:
+		}
+	}
+}
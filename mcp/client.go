@@ -2,14 +2,19 @@ package mcp
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"nofx/logger"
 	"strings"
 	"time"
 )
 
+var log = logger.Module("mcp")
+
 // Provider AI提供商类型
 type Provider string
 
@@ -17,6 +22,13 @@ const (
 	ProviderDeepSeek Provider = "deepseek"
 	ProviderQwen     Provider = "qwen"
 	ProviderCustom   Provider = "custom"
+	ProviderOpenAI   Provider = "openai"
+	ProviderClaude   Provider = "claude"
+)
+
+const (
+	claudeAPIVersion = "2023-06-01" // Anthropic API版本头
+	claudeMaxTokens  = 4096         // Claude要求显式指定max_tokens，思维链+JSON决策需要比2000更大的额度
 )
 
 // Client AI API配置
@@ -28,6 +40,33 @@ type Client struct {
 	Model      string
 	Timeout    time.Duration
 	UseFullURL bool // 是否使用完整URL（不添加/chat/completions）
+	Stream     bool // 是否使用流式输出（Timeout变为两段输出之间的空闲超时）
+
+	StructuredOutput bool // 是否通过函数调用获取结构化输出（见CallWithTool）
+
+	OnUsage func(Usage) // 每次调用成功后回调token用量（可为nil）
+
+	Fallbacks []*Client // 备用AI模型链（主AI熔断或服务故障时按顺序切换，可为空）
+
+	CacheTTL time.Duration // 响应缓存时间（0表示不缓存，只对带缓存键的调用生效，见WithCacheKey）
+}
+
+// Usage 单次调用的token用量
+type Usage struct {
+	Provider         Provider `json:"provider"`
+	Model            string   `json:"model"`
+	PromptTokens     int      `json:"prompt_tokens"`
+	CompletionTokens int      `json:"completion_tokens"`
+}
+
+// reportUsage 回调token用量
+func (cfg *Client) reportUsage(usage Usage) {
+	if cfg.OnUsage == nil {
+		return
+	}
+	usage.Provider = cfg.Provider
+	usage.Model = cfg.Model
+	cfg.OnUsage(usage)
 }
 
 func New() *Client {
@@ -58,6 +97,40 @@ func (cfg *Client) SetQwenAPIKey(apiKey, secretKey string) {
 	cfg.Model = "qwen-plus" // 可选: qwen-turbo, qwen-plus, qwen-max
 }
 
+// SetOpenAIAPIKey 设置OpenAI API密钥（baseURL/model为空时使用官方地址和gpt-4o）
+func (cfg *Client) SetOpenAIAPIKey(apiKey, baseURL, model string) {
+	cfg.Provider = ProviderOpenAI
+	cfg.APIKey = apiKey
+	cfg.BaseURL = "https://api.openai.com/v1"
+	if baseURL != "" {
+		cfg.BaseURL = strings.TrimSuffix(baseURL, "/")
+	}
+	cfg.Model = "gpt-4o"
+	if model != "" {
+		cfg.Model = model
+	}
+}
+
+// SetClaudeAPIKey 设置Anthropic Claude API密钥（使用原生messages接口，model为空时使用claude-sonnet-4-5）
+func (cfg *Client) SetClaudeAPIKey(apiKey, model string) {
+	cfg.Provider = ProviderClaude
+	cfg.APIKey = apiKey
+	cfg.BaseURL = "https://api.anthropic.com/v1"
+	cfg.Model = "claude-sonnet-4-5"
+	if model != "" {
+		cfg.Model = model
+	}
+}
+
+// isReasoningModel 判断是否为OpenAI推理模型（o1/o3/o4系列不支持temperature和max_tokens参数）
+func (cfg *Client) isReasoningModel() bool {
+	if cfg.Provider != ProviderOpenAI {
+		return false
+	}
+	model := strings.ToLower(cfg.Model)
+	return strings.HasPrefix(model, "o1") || strings.HasPrefix(model, "o3") || strings.HasPrefix(model, "o4")
+}
+
 // SetCustomAPI 设置自定义OpenAI兼容API
 func (cfg *Client) SetCustomAPI(apiURL, apiKey, modelName string) {
 	cfg.Provider = ProviderCustom
@@ -84,55 +157,118 @@ func (cfg *Client) SetClient(Client Client) {
 	cfg = &Client
 }
 
-// CallWithMessages 使用 system + user prompt 调用AI API（推荐）
-func (cfg *Client) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
+// CallWithMessages 使用 system + user prompt 调用AI API（推荐；ctx取消时中止请求和重试等待）
+func (cfg *Client) CallWithMessages(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	return cfg.CallWithMessagesStream(ctx, systemPrompt, userPrompt, nil)
+}
+
+// CallWithMessagesStream 同CallWithMessages，Stream开启时每收到一段输出调用一次onDelta（可为nil；重试时会从头再次输出）
+func (cfg *Client) CallWithMessagesStream(ctx context.Context, systemPrompt, userPrompt string, onDelta func(string)) (string, error) {
 	if cfg.APIKey == "" {
 		return "", fmt.Errorf("AI API密钥未设置，请先调用 SetDeepSeekAPIKey() 或 SetQwenAPIKey()")
 	}
+	return cfg.withCache(ctx, "chat", onDelta, func() (string, error) {
+		return cfg.withFailover(ctx, func(c *Client) (string, error) {
+			return c.withRetry(ctx, func() (string, error) {
+				return c.callOnce(ctx, systemPrompt, userPrompt, onDelta)
+			})
+		})
+	})
+}
+
+// withFailover 按模型链依次调用：当前模型熔断、因服务故障失败或不支持本次调用时改用下一个备用AI
+// （成功、请求本身有误或已取消时立即返回，未配置备用AI时直接返回主AI的结果）
+func (cfg *Client) withFailover(ctx context.Context, call func(c *Client) (string, error)) (string, error) {
+	result, err := call(cfg)
+	current := cfg
+	var failed []string
+	for _, next := range cfg.Fallbacks {
+		if err == nil || ctx.Err() != nil || !shouldFailover(err) {
+			break
+		}
+		if next.APIKey == "" {
+			continue
+		}
+		failed = append(failed, fmt.Sprintf("%s: %v", current.Provider, err))
+		log.Printf("🔀 %s (%s) 不可用，切换到备用AI %s (%s): %v", current.Provider, current.Model, next.Provider, next.Model, err)
+		current = next
+		result, err = call(next)
+	}
+	if err != nil && len(failed) > 0 {
+		return "", fmt.Errorf("模型链全部失败（%s；%s: %w）", strings.Join(failed, "；"), current.Provider, err)
+	}
+	return result, err
+}
+
+// shouldFailover 失败后是否改用下一个备用AI
+func shouldFailover(err error) bool {
+	return errors.Is(err, ErrCircuitOpen) || errors.Is(err, errToolsUnsupported) || isOutageError(err)
+}
 
+// withRetry 网络类错误自动重试（最多3次，ctx取消或提供商熔断后不再重试）
+func (cfg *Client) withRetry(ctx context.Context, call func() (string, error)) (string, error) {
 	// 重试配置
 	maxRetries := 3
 	var lastErr error
+	b := cfg.breaker()
 
 	for attempt := 1; attempt <= maxRetries; attempt++ {
+		if !b.allow() {
+			if lastErr != nil {
+				return "", fmt.Errorf("%w: %v", ErrCircuitOpen, lastErr)
+			}
+			return "", fmt.Errorf("%w: %s", ErrCircuitOpen, cfg.Provider)
+		}
 		if attempt > 1 {
-			fmt.Printf("⚠️  AI API调用失败，正在重试 (%d/%d)...\n", attempt, maxRetries)
+			log.Printf("⚠️  AI API调用失败，正在重试 (%d/%d)...", attempt, maxRetries)
 		}
 
-		result, err := cfg.callOnce(systemPrompt, userPrompt)
+		start := time.Now()
+		result, err := call()
+		if ctx.Err() == nil {
+			b.record(err, time.Since(start)) // 主动取消不计入熔断和健康统计
+		}
 		if err == nil {
 			if attempt > 1 {
-				fmt.Printf("✓ AI API重试成功\n")
+				log.Printf("✓ AI API重试成功")
 			}
 			return result, nil
 		}
 
 		lastErr = err
-		// 如果不是网络错误，不重试
-		if !isRetryableError(err) {
+		// 已取消或不是网络错误，不重试
+		if ctx.Err() != nil || !isRetryableError(err) {
 			return "", err
 		}
 
 		// 重试前等待
 		if attempt < maxRetries {
 			waitTime := time.Duration(attempt) * 2 * time.Second
-			fmt.Printf("⏳ 等待%v后重试...\n", waitTime)
-			time.Sleep(waitTime)
+			log.Printf("⏳ 等待%v后重试...", waitTime)
+			select {
+			case <-time.After(waitTime):
+			case <-ctx.Done():
+				return "", fmt.Errorf("AI调用已取消: %w", ctx.Err())
+			}
 		}
 	}
 
 	return "", fmt.Errorf("重试%d次后仍然失败: %w", maxRetries, lastErr)
 }
 
-// callOnce 单次调用AI API（内部使用）
-func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
+// chatRequestBody 构建OpenAI兼容接口的请求体
+func (cfg *Client) chatRequestBody(systemPrompt, userPrompt string) map[string]interface{} {
 	// 构建 messages 数组
 	messages := []map[string]string{}
 
-	// 如果有 system prompt，添加 system message
+	// 如果有 system prompt，添加 system message（OpenAI推理模型使用developer角色）
 	if systemPrompt != "" {
+		role := "system"
+		if cfg.isReasoningModel() {
+			role = "developer"
+		}
 		messages = append(messages, map[string]string{
-			"role":    "system",
+			"role":    role,
 			"content": systemPrompt,
 		})
 	}
@@ -150,13 +286,20 @@ func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
 		"temperature": 0.5, // 降低temperature以提高JSON格式稳定性
 		"max_tokens":  2000,
 	}
+	if cfg.isReasoningModel() {
+		// 推理模型的思考过程也计入输出token，需要更大的额度
+		delete(requestBody, "temperature")
+		delete(requestBody, "max_tokens")
+		requestBody["max_completion_tokens"] = 16000
+	}
+	return requestBody
+}
 
-	// 注意：response_format 参数仅 OpenAI 支持，DeepSeek/Qwen 不支持
-	// 我们通过强化 prompt 和后处理来确保 JSON 格式正确
-
+// newChatRequest 创建OpenAI兼容接口的HTTP请求
+func (cfg *Client) newChatRequest(ctx context.Context, requestBody map[string]interface{}) (*http.Request, error) {
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("序列化请求失败: %w", err)
+		return nil, fmt.Errorf("序列化请求失败: %w", err)
 	}
 
 	// 创建HTTP请求
@@ -168,9 +311,9 @@ func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
 		// 默认行为：添加/chat/completions
 		url = fmt.Sprintf("%s/chat/completions", cfg.BaseURL)
 	}
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("创建请求失败: %w", err)
+		return nil, fmt.Errorf("创建请求失败: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -186,23 +329,60 @@ func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
 	default:
 		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", cfg.APIKey))
 	}
+	return req, nil
+}
 
+// do 发送非流式请求并读取响应体（非200状态码返回错误）
+func (cfg *Client) do(req *http.Request) ([]byte, error) {
 	// 发送请求
 	client := &http.Client{Timeout: cfg.Timeout}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("发送请求失败: %w", err)
+		return nil, fmt.Errorf("发送请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// 读取响应
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("读取响应失败: %w", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
+		return nil, &statusError{status: resp.StatusCode, body: string(body)}
+	}
+	return body, nil
+}
+
+// callOnce 单次调用AI API（内部使用）
+func (cfg *Client) callOnce(ctx context.Context, systemPrompt, userPrompt string, onDelta func(string)) (string, error) {
+	if cfg.Provider == ProviderClaude {
+		return cfg.callClaudeOnce(ctx, systemPrompt, userPrompt, onDelta)
+	}
+
+	requestBody := cfg.chatRequestBody(systemPrompt, userPrompt)
+	if cfg.Stream {
+		requestBody["stream"] = true
+		if cfg.Provider != ProviderCustom {
+			// 最后一个chunk返回token用量（自定义接口不一定支持该参数）
+			requestBody["stream_options"] = map[string]bool{"include_usage": true}
+		}
+	}
+
+	// 注意：response_format 参数仅 OpenAI 支持，DeepSeek/Qwen 不支持
+	// 结构化输出请使用 CallWithTool（函数调用）
+
+	req, err := cfg.newChatRequest(ctx, requestBody)
+	if err != nil {
+		return "", err
+	}
+	if cfg.Stream {
+		return cfg.callStream(req, readChatStream, onDelta)
+	}
+
+	body, err := cfg.do(req)
+	if err != nil {
+		return "", err
 	}
 
 	// 解析响应
@@ -212,6 +392,7 @@ func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
 				Content string `json:"content"`
 			} `json:"message"`
 		} `json:"choices"`
+		Usage chatUsage `json:"usage"`
 	}
 
 	if err := json.Unmarshal(body, &result); err != nil {
@@ -222,9 +403,112 @@ func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
 		return "", fmt.Errorf("API返回空响应")
 	}
 
+	cfg.reportUsage(result.Usage.toUsage())
 	return result.Choices[0].Message.Content, nil
 }
 
+// claudeRequestBody 构建Anthropic messages接口的请求体（system prompt为顶层字段，而非messages中的一条）
+func (cfg *Client) claudeRequestBody(systemPrompt, userPrompt string) map[string]interface{} {
+	requestBody := map[string]interface{}{
+		"model":       cfg.Model,
+		"max_tokens":  claudeMaxTokens,
+		"temperature": 0.5,
+		"messages": []map[string]string{
+			{"role": "user", "content": userPrompt},
+		},
+	}
+	if systemPrompt != "" {
+		requestBody["system"] = systemPrompt
+	}
+	return requestBody
+}
+
+// newClaudeRequest 创建Anthropic messages接口的HTTP请求
+func (cfg *Client) newClaudeRequest(ctx context.Context, requestBody map[string]interface{}) (*http.Request, error) {
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", cfg.BaseURL+"/messages", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", cfg.APIKey)
+	req.Header.Set("anthropic-version", claudeAPIVersion)
+	return req, nil
+}
+
+// callClaudeOnce 单次调用Anthropic messages接口
+func (cfg *Client) callClaudeOnce(ctx context.Context, systemPrompt, userPrompt string, onDelta func(string)) (string, error) {
+	requestBody := cfg.claudeRequestBody(systemPrompt, userPrompt)
+	if cfg.Stream {
+		requestBody["stream"] = true
+	}
+
+	req, err := cfg.newClaudeRequest(ctx, requestBody)
+	if err != nil {
+		return "", err
+	}
+	if cfg.Stream {
+		return cfg.callStream(req, readClaudeStream, onDelta)
+	}
+
+	body, err := cfg.do(req)
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		StopReason string      `json:"stop_reason"`
+		Usage      claudeUsage `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	var sb strings.Builder
+	for _, block := range result.Content {
+		if block.Type == "text" {
+			sb.WriteString(block.Text)
+		}
+	}
+	if sb.Len() == 0 {
+		return "", fmt.Errorf("API返回空响应")
+	}
+	if result.StopReason == "max_tokens" {
+		log.Printf("⚠️  Claude响应达到max_tokens(%d)上限被截断，决策JSON可能不完整", claudeMaxTokens)
+	}
+
+	cfg.reportUsage(result.Usage.toUsage())
+	return sb.String(), nil
+}
+
+// chatUsage OpenAI兼容接口返回的token用量
+type chatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+func (u chatUsage) toUsage() Usage {
+	return Usage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens}
+}
+
+// claudeUsage Anthropic接口返回的token用量
+type claudeUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+func (u claudeUsage) toUsage() Usage {
+	return Usage{PromptTokens: u.InputTokens, CompletionTokens: u.OutputTokens}
+}
+
 // isRetryableError 判断错误是否可重试
 func isRetryableError(err error) bool {
 	errStr := err.Error()
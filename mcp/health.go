@@ -0,0 +1,141 @@
+package mcp
+
+import (
+	"sort"
+	"time"
+)
+
+// healthWindowSize 健康统计使用的最近请求数
+const healthWindowSize = 50
+
+// healthSample 一次请求的结果
+type healthSample struct {
+	failed  bool          // 服务故障（网络错误、超时、5xx、限流）
+	latency time.Duration // 请求耗时（服务故障时不计入平均延迟）
+}
+
+// healthWindow 最近healthWindowSize次请求的结果（环形缓冲，由breaker.mu保护）
+type healthWindow struct {
+	samples       [healthWindowSize]healthSample
+	next, count   int
+	lastError     string
+	lastErrorAt   time.Time
+	lastSuccessAt time.Time
+}
+
+// add 记录一次请求结果
+func (w *healthWindow) add(err error, latency time.Duration) {
+	failed := err != nil && isOutageError(err)
+	w.samples[w.next] = healthSample{failed: failed, latency: latency}
+	w.next = (w.next + 1) % healthWindowSize
+	w.count = min(w.count+1, healthWindowSize)
+	if failed {
+		w.lastError = err.Error()
+		w.lastErrorAt = time.Now()
+	} else {
+		w.lastSuccessAt = time.Now()
+	}
+}
+
+// ProviderHealth AI提供商的健康状态（错误率和延迟按最近50次请求统计）
+type ProviderHealth struct {
+	Provider            Provider   `json:"provider"`
+	BaseURL             string     `json:"base_url"`
+	State               string     `json:"state"`                // 熔断状态: closed / open / half_open
+	ConsecutiveFailures int        `json:"consecutive_failures"` // 连续失败次数
+	Requests            int        `json:"requests"`             // 统计窗口内的请求数
+	ErrorRate           float64    `json:"error_rate"`           // 统计窗口内服务故障的比例（%）
+	AvgLatencyMs        int64      `json:"avg_latency_ms"`       // 统计窗口内正常响应的平均耗时
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"` // 熔断时间（熔断中时有效）
+}
+
+// snapshot 当前健康状态
+func (b *breaker) snapshot() ProviderHealth {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h := ProviderHealth{
+		Provider:            b.provider,
+		BaseURL:             b.baseURL,
+		State:               b.state.String(),
+		ConsecutiveFailures: b.failures,
+		Requests:            b.health.count,
+		LastError:           b.health.lastError,
+	}
+	failed, ok := 0, 0
+	var latency time.Duration
+	for _, s := range b.health.samples[:b.health.count] {
+		if s.failed {
+			failed++
+			continue
+		}
+		ok++
+		latency += s.latency
+	}
+	if h.Requests > 0 {
+		h.ErrorRate = float64(failed) / float64(h.Requests) * 100
+	}
+	if ok > 0 {
+		h.AvgLatencyMs = (latency / time.Duration(ok)).Milliseconds()
+	}
+	h.LastErrorAt = timePtr(b.health.lastErrorAt)
+	h.LastSuccessAt = timePtr(b.health.lastSuccessAt)
+	if b.state != breakerClosed {
+		h.OpenedAt = timePtr(b.openedAt)
+	}
+	return h
+}
+
+// timePtr 零值时间返回nil
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// Health 所有已调用过或已查询过模型链的AI提供商的健康状态（按提供商和地址排序）
+func Health() []ProviderHealth {
+	breakersMu.Lock()
+	list := make([]*breaker, 0, len(breakers))
+	for _, b := range breakers {
+		list = append(list, b)
+	}
+	breakersMu.Unlock()
+
+	result := make([]ProviderHealth, 0, len(list))
+	for _, b := range list {
+		result = append(result, b.snapshot())
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Provider != result[j].Provider {
+			return result[i].Provider < result[j].Provider
+		}
+		return result[i].BaseURL < result[j].BaseURL
+	})
+	return result
+}
+
+// RouteEntry 模型链中的一个模型
+type RouteEntry struct {
+	Provider Provider `json:"provider"`
+	BaseURL  string   `json:"base_url"`
+	Model    string   `json:"model"`
+	State    string   `json:"state"` // 所属提供商的熔断状态
+}
+
+// Route 客户端的模型链（主AI在前，之后按切换顺序排列备用AI）
+func (cfg *Client) Route() []RouteEntry {
+	chain := append([]*Client{cfg}, cfg.Fallbacks...)
+	route := make([]RouteEntry, 0, len(chain))
+	for _, c := range chain {
+		b := c.breaker()
+		b.mu.Lock()
+		state := b.state
+		b.mu.Unlock()
+		route = append(route, RouteEntry{Provider: c.Provider, BaseURL: c.BaseURL, Model: c.Model, State: state.String()})
+	}
+	return route
+}
@@ -0,0 +1,54 @@
+package mcp
+
+import "strings"
+
+// Price 模型价格（美元/百万token）
+type Price struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// modelPrices 常见模型的公开价格（按模型名前缀匹配，更具体的前缀放在前面）
+// 仅用于估算成本，实际费用以提供商账单为准
+var modelPrices = []struct {
+	prefix string
+	price  Price
+}{
+	{"deepseek-reasoner", Price{0.55, 2.19}},
+	{"deepseek-chat", Price{0.27, 1.10}},
+	{"qwen-turbo", Price{0.05, 0.20}},
+	{"qwen-plus", Price{0.40, 1.20}},
+	{"qwen-max", Price{1.60, 6.40}},
+	{"gpt-4o-mini", Price{0.15, 0.60}},
+	{"gpt-4o", Price{2.50, 10.00}},
+	{"gpt-4.1-mini", Price{0.40, 1.60}},
+	{"gpt-4.1", Price{2.00, 8.00}},
+	{"o1-mini", Price{1.10, 4.40}},
+	{"o1", Price{15.00, 60.00}},
+	{"o3-mini", Price{1.10, 4.40}},
+	{"o3", Price{2.00, 8.00}},
+	{"o4-mini", Price{1.10, 4.40}},
+	{"claude-opus", Price{15.00, 75.00}},
+	{"claude-3-opus", Price{15.00, 75.00}},
+	{"claude-sonnet", Price{3.00, 15.00}},
+	{"claude-3-5-sonnet", Price{3.00, 15.00}},
+	{"claude-3-7-sonnet", Price{3.00, 15.00}},
+	{"claude-haiku", Price{0.80, 4.00}},
+	{"claude-3-5-haiku", Price{0.80, 4.00}},
+}
+
+// LookupPrice 查找模型价格，未知模型返回false
+func LookupPrice(model string) (Price, bool) {
+	model = strings.ToLower(model)
+	for _, p := range modelPrices {
+		if strings.HasPrefix(model, p.prefix) {
+			return p.price, true
+		}
+	}
+	return Price{}, false
+}
+
+// Cost 按价格估算一次调用的成本（美元）
+func (p Price) Cost(usage Usage) float64 {
+	return (float64(usage.PromptTokens)*p.Input + float64(usage.CompletionTokens)*p.Output) / 1e6
+}
@@ -0,0 +1,206 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// streamMaxLineSize SSE单行最大长度
+const streamMaxLineSize = 1024 * 1024
+
+// idleTimeoutBody 流式响应体：每次读到数据时重置空闲计时器，超过Timeout没有新输出才取消请求
+type idleTimeoutBody struct {
+	io.ReadCloser
+	timer   *time.Timer
+	timeout time.Duration
+	cancel  context.CancelFunc
+	idle    atomic.Bool
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.timer.Reset(b.timeout)
+	}
+	if err != nil && b.idle.Load() {
+		err = b.idleError()
+	}
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	b.timer.Stop()
+	b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// idleError 空闲超时错误（包含"timeout"，会被重试）
+func (b *idleTimeoutBody) idleError() error {
+	return fmt.Errorf("流式响应%v内没有新输出: %w", b.timeout, os.ErrDeadlineExceeded)
+}
+
+// callStream 发送流式请求并解析SSE输出（Timeout作为空闲超时，长回复不会被总超时截断）
+func (cfg *Client) callStream(req *http.Request, parse func(io.Reader, func(string)) (string, Usage, error), onDelta func(string)) (string, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	body := &idleTimeoutBody{timeout: cfg.Timeout, cancel: cancel}
+	body.timer = time.AfterFunc(cfg.Timeout, func() {
+		body.idle.Store(true)
+		cancel()
+	})
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		body.timer.Stop()
+		cancel()
+		if body.idle.Load() {
+			return "", fmt.Errorf("发送请求失败: %w", body.idleError())
+		}
+		return "", fmt.Errorf("发送请求失败: %w", err)
+	}
+	body.ReadCloser = resp.Body
+	defer body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(body)
+		return "", &statusError{status: resp.StatusCode, body: string(data)}
+	}
+	content, usage, err := parse(body, onDelta)
+	if err != nil {
+		return "", err
+	}
+	cfg.reportUsage(usage)
+	return content, nil
+}
+
+// sseData 逐行读取SSE，对每个data字段调用handle（返回false时停止读取）
+func sseData(r io.Reader, handle func(payload string) (bool, error)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), streamMaxLineSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		more, err := handle(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("读取流式响应失败: %w", err)
+	}
+	return nil
+}
+
+// readChatStream 解析OpenAI兼容接口的流式输出（choices[].delta.content，以[DONE]结束）
+func readChatStream(r io.Reader, onDelta func(string)) (string, Usage, error) {
+	var sb strings.Builder
+	var usage Usage
+	err := sseData(r, func(payload string) (bool, error) {
+		if payload == "[DONE]" {
+			return false, nil
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+			Usage *chatUsage `json:"usage"`
+		}
+		if err := json.Unmarshal([]byte(payload), &chunk); err != nil {
+			return false, fmt.Errorf("解析流式响应失败: %w", err)
+		}
+		if chunk.Error != nil {
+			return false, fmt.Errorf("API返回错误: %s", chunk.Error.Message)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage.toUsage()
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content == "" {
+				continue
+			}
+			sb.WriteString(choice.Delta.Content)
+			if onDelta != nil {
+				onDelta(choice.Delta.Content)
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return "", usage, err
+	}
+	if sb.Len() == 0 {
+		return "", usage, fmt.Errorf("API返回空响应")
+	}
+	return sb.String(), usage, nil
+}
+
+// readClaudeStream 解析Anthropic messages接口的流式输出（content_block_delta中的text_delta）
+func readClaudeStream(r io.Reader, onDelta func(string)) (string, Usage, error) {
+	var sb strings.Builder
+	var usage Usage
+	err := sseData(r, func(payload string) (bool, error) {
+		var event struct {
+			Type  string `json:"type"`
+			Delta struct {
+				Type       string `json:"type"`
+				Text       string `json:"text"`
+				StopReason string `json:"stop_reason"`
+			} `json:"delta"`
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+			Message struct {
+				Usage claudeUsage `json:"usage"`
+			} `json:"message"`
+			Usage claudeUsage `json:"usage"`
+		}
+		if err := json.Unmarshal([]byte(payload), &event); err != nil {
+			return false, fmt.Errorf("解析流式响应失败: %w", err)
+		}
+		switch event.Type {
+		case "message_start":
+			usage.PromptTokens = event.Message.Usage.InputTokens
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+				sb.WriteString(event.Delta.Text)
+				if onDelta != nil {
+					onDelta(event.Delta.Text)
+				}
+			}
+		case "message_delta":
+			usage.CompletionTokens = event.Usage.OutputTokens
+			if event.Delta.StopReason == "max_tokens" {
+				log.Printf("⚠️  Claude响应达到max_tokens(%d)上限被截断，决策JSON可能不完整", claudeMaxTokens)
+			}
+		case "message_stop":
+			return false, nil
+		case "error":
+			return false, fmt.Errorf("API返回错误: %s", event.Error.Message)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return "", usage, err
+	}
+	if sb.Len() == 0 {
+		return "", usage, fmt.Errorf("API返回空响应")
+	}
+	return sb.String(), usage, nil
+}
@@ -0,0 +1,158 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Tool 结构化输出使用的函数定义（模型被强制调用该函数，参数即结构化结果）
+type Tool struct {
+	Name        string                 // 函数名
+	Description string                 // 函数说明
+	Parameters  map[string]interface{} // 参数的JSON Schema
+}
+
+// errToolsUnsupported 备用AI不支持函数调用（跳过该模型）
+var errToolsUnsupported = errors.New("模型不支持函数调用")
+
+// SupportsTools 当前模型是否支持强制函数调用（不支持时调用方应回退到文本解析）
+func (cfg *Client) SupportsTools() bool {
+	model := strings.ToLower(cfg.Model)
+	switch {
+	case cfg.Provider == ProviderDeepSeek && strings.Contains(model, "reasoner"):
+		// deepseek-reasoner 不支持function calling
+		return false
+	case cfg.Provider == ProviderOpenAI && (strings.HasPrefix(model, "o1-mini") || strings.HasPrefix(model, "o1-preview")):
+		// 早期推理模型不支持tools
+		return false
+	}
+	return true
+}
+
+// CallWithTool 强制模型调用指定函数，返回函数参数（JSON字符串）
+// OpenAI兼容接口使用tools/tool_choice，Claude使用tool_use；不支持流式输出
+func (cfg *Client) CallWithTool(ctx context.Context, systemPrompt, userPrompt string, tool Tool) (string, error) {
+	if cfg.APIKey == "" {
+		return "", fmt.Errorf("AI API密钥未设置，请先调用 SetDeepSeekAPIKey() 或 SetQwenAPIKey()")
+	}
+	return cfg.withCache(ctx, "tool:"+tool.Name, nil, func() (string, error) {
+		return cfg.withFailover(ctx, func(c *Client) (string, error) {
+			if c != cfg && !c.SupportsTools() {
+				return "", fmt.Errorf("%w: %s", errToolsUnsupported, c.Model)
+			}
+			return c.withRetry(ctx, func() (string, error) {
+				if c.Provider == ProviderClaude {
+					return c.callClaudeTool(ctx, systemPrompt, userPrompt, tool)
+				}
+				return c.callChatTool(ctx, systemPrompt, userPrompt, tool)
+			})
+		})
+	})
+}
+
+// callChatTool OpenAI兼容接口的函数调用
+func (cfg *Client) callChatTool(ctx context.Context, systemPrompt, userPrompt string, tool Tool) (string, error) {
+	requestBody := cfg.chatRequestBody(systemPrompt, userPrompt)
+	requestBody["tools"] = []map[string]interface{}{{
+		"type": "function",
+		"function": map[string]interface{}{
+			"name":        tool.Name,
+			"description": tool.Description,
+			"parameters":  tool.Parameters,
+		},
+	}}
+	requestBody["tool_choice"] = map[string]interface{}{
+		"type":     "function",
+		"function": map[string]string{"name": tool.Name},
+	}
+	if _, ok := requestBody["max_tokens"]; ok {
+		// 思维链写在参数中，需要比纯文本更大的额度
+		requestBody["max_tokens"] = 4096
+	}
+
+	req, err := cfg.newChatRequest(ctx, requestBody)
+	if err != nil {
+		return "", err
+	}
+	body, err := cfg.do(req)
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		Choices []struct {
+			Message struct {
+				ToolCalls []struct {
+					Function struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage chatUsage `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("解析响应失败: %w", err)
+	}
+	if len(result.Choices) == 0 {
+		return "", fmt.Errorf("API返回空响应")
+	}
+	cfg.reportUsage(result.Usage.toUsage())
+	for _, call := range result.Choices[0].Message.ToolCalls {
+		if call.Function.Name == tool.Name {
+			if result.Choices[0].FinishReason == "length" {
+				log.Printf("⚠️  函数调用输出达到max_tokens上限被截断，参数JSON可能不完整")
+			}
+			return call.Function.Arguments, nil
+		}
+	}
+	return "", fmt.Errorf("模型没有调用函数 %s", tool.Name)
+}
+
+// callClaudeTool Anthropic messages接口的tool_use
+func (cfg *Client) callClaudeTool(ctx context.Context, systemPrompt, userPrompt string, tool Tool) (string, error) {
+	requestBody := cfg.claudeRequestBody(systemPrompt, userPrompt)
+	requestBody["tools"] = []map[string]interface{}{{
+		"name":         tool.Name,
+		"description":  tool.Description,
+		"input_schema": tool.Parameters,
+	}}
+	requestBody["tool_choice"] = map[string]string{"type": "tool", "name": tool.Name}
+
+	req, err := cfg.newClaudeRequest(ctx, requestBody)
+	if err != nil {
+		return "", err
+	}
+	body, err := cfg.do(req)
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		Content []struct {
+			Type  string          `json:"type"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
+		} `json:"content"`
+		StopReason string      `json:"stop_reason"`
+		Usage      claudeUsage `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("解析响应失败: %w", err)
+	}
+	if result.StopReason == "max_tokens" {
+		log.Printf("⚠️  Claude响应达到max_tokens(%d)上限被截断，参数JSON可能不完整", claudeMaxTokens)
+	}
+	cfg.reportUsage(result.Usage.toUsage())
+	for _, block := range result.Content {
+		if block.Type == "tool_use" && block.Name == tool.Name {
+			return string(block.Input), nil
+		}
+	}
+	return "", fmt.Errorf("模型没有调用函数 %s", tool.Name)
+}
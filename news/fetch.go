@@ -0,0 +1,171 @@
+package news
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// httpClient 抓取新闻用的HTTP客户端
+var httpClient = &http.Client{Timeout: 20 * time.Second}
+
+// cryptoPanicURL CryptoPanic公开新闻接口
+const cryptoPanicURL = "https://cryptopanic.com/api/v1/posts/"
+
+// get 请求url并返回响应内容
+func get(rawURL string) ([]byte, error) {
+	resp, err := httpClient.Get(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, truncate(string(body), 200))
+	}
+	return body, nil
+}
+
+// fetchCryptoPanic 抓取CryptoPanic最新新闻
+func fetchCryptoPanic(token string) ([]Item, error) {
+	body, err := get(cryptoPanicURL + "?public=true&auth_token=" + url.QueryEscape(token))
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Results []struct {
+			Title       string    `json:"title"`
+			PublishedAt time.Time `json:"published_at"`
+			URL         string    `json:"url"`
+			Source      struct {
+				Title string `json:"title"`
+			} `json:"source"`
+			Currencies []struct {
+				Code string `json:"code"`
+			} `json:"currencies"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析CryptoPanic响应失败: %w", err)
+	}
+
+	items := make([]Item, 0, len(result.Results))
+	for _, r := range result.Results {
+		it := Item{Time: r.PublishedAt, Source: "CryptoPanic", Title: r.Title, URL: r.URL}
+		if r.Source.Title != "" {
+			it.Source = r.Source.Title
+		}
+		for _, c := range r.Currencies {
+			it.Symbols = append(it.Symbols, c.Code)
+		}
+		items = append(items, it)
+	}
+	return items, nil
+}
+
+// rssFeed RSS 2.0和Atom共用的解析结构
+type rssFeed struct {
+	Title   string     `xml:"channel>title"`
+	Items   []rssEntry `xml:"channel>item"`
+	Feed    string     `xml:"title"` // Atom
+	Entries []rssEntry `xml:"entry"` // Atom
+}
+
+// rssEntry RSS item或Atom entry
+type rssEntry struct {
+	Title     string     `xml:"title"`
+	Links     []feedLink `xml:"link"`
+	PubDate   string     `xml:"pubDate"`
+	Published string     `xml:"published"` // Atom
+	Updated   string     `xml:"updated"`   // Atom
+}
+
+// feedLink RSS的<link>文本或Atom的<link href rel>
+type feedLink struct {
+	Text string `xml:",chardata"`
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+// link 条目链接（Atom优先使用rel为alternate或未设置的链接）
+func (e rssEntry) link() string {
+	for _, l := range e.Links {
+		if text := strings.TrimSpace(l.Text); text != "" {
+			return text
+		}
+		if l.Href != "" && (l.Rel == "" || l.Rel == "alternate") {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+// rssTimeLayouts RSS和Atom常见的时间格式
+var rssTimeLayouts = []string{time.RFC1123Z, time.RFC1123, time.RFC3339, "Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST"}
+
+// parseFeedTime 解析RSS/Atom时间（无法解析时返回零值）
+func parseFeedTime(values ...string) time.Time {
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		for _, layout := range rssTimeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// fetchRSS 抓取RSS 2.0或Atom订阅（无法解析时间的条目跳过）
+func fetchRSS(feedURL string) ([]Item, error) {
+	body, err := get(feedURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var feed rssFeed
+	if err := xml.Unmarshal(body, &feed); err != nil {
+		return nil, fmt.Errorf("解析RSS失败: %w", err)
+	}
+	source := feed.Title
+	entries := feed.Items
+	if len(entries) == 0 {
+		source, entries = feed.Feed, feed.Entries
+	}
+	if source == "" {
+		if u, err := url.Parse(feedURL); err == nil {
+			source = u.Host
+		}
+	}
+
+	items := make([]Item, 0, len(entries))
+	for _, e := range entries {
+		t := parseFeedTime(e.PubDate, e.Published, e.Updated)
+		if t.IsZero() {
+			continue
+		}
+		items = append(items, Item{Time: t, Source: strings.TrimSpace(source), Title: strings.TrimSpace(e.Title), URL: e.link()})
+	}
+	return items, nil
+}
+
+// truncate 截断过长的错误信息
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,344 @@
+// Package news 新闻和宏观事件：定期抓取CryptoPanic和RSS，也可以通过API推送，每个周期汇总写入prompt；
+// 重大事件（FOMC、CPI等）前后暂停开仓
+package news
+
+import (
+	"fmt"
+	"nofx/logger"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var log = logger.Module("news")
+
+// 新闻参数
+const (
+	maxStoredItems  = 200            // 内存中最多保留的新闻条数
+	eventLookahead  = 24 * time.Hour // prompt中列出未来多久内的宏观事件
+	maxTitleRunes   = 160            // 写入prompt的标题最大长度
+	pastEventWindow = 24 * time.Hour // 已发生超过该时间的事件从日历中移除
+)
+
+// Item 一条新闻
+type Item struct {
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source"`
+	Title   string    `json:"title"`
+	URL     string    `json:"url,omitempty"`
+	Symbols []string  `json:"symbols,omitempty"` // 相关币种（如 BTC），可为空
+}
+
+// key 去重键（优先使用链接）
+func (it Item) key() string {
+	if it.URL != "" {
+		return it.URL
+	}
+	return it.Source + "|" + it.Title
+}
+
+// Event 宏观事件（如 FOMC 利率决议、CPI 发布）
+type Event struct {
+	Name string    `json:"name"`
+	Time time.Time `json:"time"` // RFC3339格式
+}
+
+// Config 新闻源和事件暂停设置（零值表示不抓取新闻、不暂停开仓，仍可通过API推送新闻和事件）
+type Config struct {
+	CryptoPanicToken      string   `json:"cryptopanic_token"`       // CryptoPanic API token（为空不抓取）
+	RSSFeeds              []string `json:"rss_feeds"`               // RSS/Atom订阅地址
+	PollMinutes           int      `json:"poll_minutes"`            // 抓取间隔（默认10分钟）
+	MaxItems              int      `json:"max_items"`               // 每个周期写入prompt的最多新闻条数（默认8）
+	MaxAgeHours           int      `json:"max_age_hours"`           // 只使用最近N小时的新闻（默认6）
+	Events                []Event  `json:"events"`                  // 宏观事件日历
+	BlackoutBeforeMinutes int      `json:"blackout_before_minutes"` // 事件前暂停开仓的分钟数（0表示事件前不暂停）
+	BlackoutAfterMinutes  int      `json:"blackout_after_minutes"`  // 事件后暂停开仓的分钟数（0表示事件后不暂停）
+}
+
+// Validate 校验配置并填充默认值
+func (c *Config) Validate() error {
+	if c.PollMinutes <= 0 {
+		c.PollMinutes = 10
+	}
+	if c.MaxItems <= 0 {
+		c.MaxItems = 8
+	}
+	if c.MaxAgeHours <= 0 {
+		c.MaxAgeHours = 6
+	}
+	if c.BlackoutBeforeMinutes < 0 || c.BlackoutAfterMinutes < 0 {
+		return fmt.Errorf("news.blackout_before_minutes和blackout_after_minutes不能为负数")
+	}
+	for i, e := range c.Events {
+		if err := e.Validate(); err != nil {
+			return fmt.Errorf("news.events[%d]: %w", i, err)
+		}
+	}
+	for i, u := range c.RSSFeeds {
+		if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+			return fmt.Errorf("news.rss_feeds[%d]: 必须是http(s)地址", i)
+		}
+	}
+	return nil
+}
+
+// Validate 校验事件名称和时间
+func (e Event) Validate() error {
+	if strings.TrimSpace(e.Name) == "" {
+		return fmt.Errorf("事件名称不能为空")
+	}
+	if e.Time.IsZero() {
+		return fmt.Errorf("事件 %s 缺少时间", e.Name)
+	}
+	return nil
+}
+
+// Validate 校验新闻标题和时间（时间为空时使用当前时间）
+func (it *Item) Validate() error {
+	it.Title = strings.TrimSpace(it.Title)
+	if it.Title == "" {
+		return fmt.Errorf("新闻标题不能为空")
+	}
+	if it.Source == "" {
+		it.Source = "webhook"
+	}
+	if it.Time.IsZero() {
+		it.Time = time.Now()
+	}
+	return nil
+}
+
+// Feed 新闻和宏观事件（内存中保存，重启后重新抓取；配置文件中的事件重启后仍然生效）
+type Feed struct {
+	cfg    Config
+	mu     sync.RWMutex
+	items  []Item          // 按时间倒序
+	seen   map[string]bool // 已收录的新闻
+	events []Event         // 按时间正序
+	stopCh chan struct{}
+}
+
+var (
+	defaultFeed   *Feed
+	defaultFeedMu sync.RWMutex
+)
+
+// Start 启动新闻源（配置了CryptoPanic或RSS时定期抓取）
+func Start(cfg Config) *Feed {
+	defaultFeedMu.Lock()
+	defer defaultFeedMu.Unlock()
+	if defaultFeed == nil {
+		defaultFeed = &Feed{cfg: cfg, seen: make(map[string]bool), stopCh: make(chan struct{})}
+		defaultFeed.AddEvents(cfg.Events...)
+		if cfg.CryptoPanicToken != "" || len(cfg.RSSFeeds) > 0 {
+			go defaultFeed.run()
+		}
+	}
+	return defaultFeed
+}
+
+// Stop 停止新闻源
+func Stop() {
+	defaultFeedMu.Lock()
+	feed := defaultFeed
+	defaultFeed = nil
+	defaultFeedMu.Unlock()
+	if feed != nil {
+		close(feed.stopCh)
+	}
+}
+
+// Active 当前运行的新闻源（未启动时返回nil）
+func Active() *Feed {
+	defaultFeedMu.RLock()
+	defer defaultFeedMu.RUnlock()
+	return defaultFeed
+}
+
+// run 定期抓取新闻，直到Stop
+func (f *Feed) run() {
+	ticker := time.NewTicker(time.Duration(f.cfg.PollMinutes) * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		f.poll()
+		select {
+		case <-f.stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll 抓取所有新闻源（单个源失败只记录日志）
+func (f *Feed) poll() {
+	var items []Item
+	if f.cfg.CryptoPanicToken != "" {
+		fetched, err := fetchCryptoPanic(f.cfg.CryptoPanicToken)
+		if err != nil {
+			log.Printf("⚠️  抓取CryptoPanic新闻失败: %v", err)
+		}
+		items = append(items, fetched...)
+	}
+	for _, url := range f.cfg.RSSFeeds {
+		fetched, err := fetchRSS(url)
+		if err != nil {
+			log.Printf("⚠️  抓取RSS %s 失败: %v", url, err)
+		}
+		items = append(items, fetched...)
+	}
+	if n := f.Ingest(items...); n > 0 {
+		log.Printf("📰 收录 %d 条新闻", n)
+	}
+}
+
+// Ingest 收录新闻（按链接或来源+标题去重），返回新增条数
+func (f *Feed) Ingest(items ...Item) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	added := 0
+	for _, it := range items {
+		if it.Validate() != nil || f.seen[it.key()] {
+			continue
+		}
+		f.seen[it.key()] = true
+		f.items = append(f.items, it)
+		added++
+	}
+	if added == 0 {
+		return 0
+	}
+
+	sort.SliceStable(f.items, func(i, j int) bool { return f.items[i].Time.After(f.items[j].Time) })
+	if len(f.items) > maxStoredItems {
+		for _, it := range f.items[maxStoredItems:] {
+			delete(f.seen, it.key())
+		}
+		f.items = f.items[:maxStoredItems]
+	}
+	return added
+}
+
+// AddEvents 添加宏观事件（同名同时间的事件只保留一个），已过去超过一天的事件会被移除
+func (f *Feed) AddEvents(events ...Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, e := range events {
+		duplicate := false
+		for _, existing := range f.events {
+			if existing.Name == e.Name && existing.Time.Equal(e.Time) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			f.events = append(f.events, e)
+		}
+	}
+	f.pruneEvents(time.Now())
+}
+
+// pruneEvents 按时间排序并移除已过去超过一天的事件（调用方持有写锁）
+func (f *Feed) pruneEvents(now time.Time) {
+	sort.SliceStable(f.events, func(i, j int) bool { return f.events[i].Time.Before(f.events[j].Time) })
+	kept := f.events[:0]
+	for _, e := range f.events {
+		if now.Sub(e.Time) <= pastEventWindow {
+			kept = append(kept, e)
+		}
+	}
+	f.events = kept
+}
+
+// Recent 最近maxAge内的新闻（最新的在前，最多limit条，limit<=0表示不限制）
+func (f *Feed) Recent(maxAge time.Duration, limit int) []Item {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	cutoff := time.Now().Add(-maxAge)
+	items := []Item{}
+	for _, it := range f.items {
+		if it.Time.Before(cutoff) || (limit > 0 && len(items) >= limit) {
+			break
+		}
+		items = append(items, it)
+	}
+	return items
+}
+
+// Events 日历中的宏观事件（按时间正序，包含最近一天内已发生的事件）
+func (f *Feed) Events() []Event {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return append([]Event{}, f.events...)
+}
+
+// Blackout 当前是否处于事件前后的暂停开仓时段（返回原因）
+func (f *Feed) Blackout(now time.Time) (string, bool) {
+	before := time.Duration(f.cfg.BlackoutBeforeMinutes) * time.Minute
+	after := time.Duration(f.cfg.BlackoutAfterMinutes) * time.Minute
+	if before == 0 && after == 0 {
+		return "", false
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, e := range f.events {
+		if !now.Before(e.Time.Add(-before)) && !now.After(e.Time.Add(after)) {
+			return fmt.Sprintf("%s（%s）前%d分钟至后%d分钟", e.Name, e.Time.UTC().Format("2006-01-02 15:04 UTC"),
+				f.cfg.BlackoutBeforeMinutes, f.cfg.BlackoutAfterMinutes), true
+		}
+	}
+	return "", false
+}
+
+// PromptLines 写入prompt的新闻和宏观事件摘要：未来24小时内的事件和最近的新闻标题
+func (f *Feed) PromptLines(now time.Time) []string {
+	var lines []string
+	for _, e := range f.Events() {
+		if e.Time.Before(now) || e.Time.Sub(now) > eventLookahead {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("📅 %s %s（%s后）", e.Time.UTC().Format("01-02 15:04 UTC"), e.Name, formatUntil(e.Time.Sub(now))))
+	}
+	for _, it := range f.Recent(time.Duration(f.cfg.MaxAgeHours)*time.Hour, f.cfg.MaxItems) {
+		title := []rune(it.Title)
+		if len(title) > maxTitleRunes {
+			title = append(title[:maxTitleRunes], '…')
+		}
+		line := fmt.Sprintf("%s [%s] %s", it.Time.UTC().Format("15:04"), it.Source, string(title))
+		if len(it.Symbols) > 0 {
+			line += " (" + strings.Join(it.Symbols, ", ") + ")"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// formatUntil 距离事件的时间（如 "2小时15分钟"）
+func formatUntil(d time.Duration) string {
+	hours, minutes := int(d.Hours()), int(d.Minutes())%60
+	if hours == 0 {
+		return fmt.Sprintf("%d分钟", minutes)
+	}
+	return fmt.Sprintf("%d小时%d分钟", hours, minutes)
+}
+
+// PromptLines 当前新闻源的prompt摘要（未启动时返回nil）
+func PromptLines(now time.Time) []string {
+	if f := Active(); f != nil {
+		return f.PromptLines(now)
+	}
+	return nil
+}
+
+// Blackout 当前是否处于事件暂停开仓时段（新闻源未启动时返回false）
+func Blackout(now time.Time) (string, bool) {
+	if f := Active(); f != nil {
+		return f.Blackout(now)
+	}
+	return "", false
+}
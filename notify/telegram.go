@@ -0,0 +1,122 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"nofx/logger"
+	"time"
+)
+
+var log = logger.Module("notify")
+
+// telegramAPI Telegram Bot API地址
+var telegramAPI = "https://api.telegram.org"
+
+// TelegramNotifier Telegram Bot推送（通过sendMessage接口发送文本消息）
+type TelegramNotifier struct {
+	botToken string
+	chatID   string
+	client   *http.Client
+}
+
+// NewTelegramNotifier 创建Telegram推送器（botToken或chatID为空时返回nil，调用方可直接忽略）
+func NewTelegramNotifier(botToken, chatID string) *TelegramNotifier {
+	if botToken == "" || chatID == "" {
+		return nil
+	}
+	return &TelegramNotifier{
+		botToken: botToken,
+		chatID:   chatID,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Button inline键盘按钮（点击后Telegram回调Data）
+type Button struct {
+	Text string `json:"text"`
+	Data string `json:"callback_data"` // 最长64字节
+}
+
+// Send 同步发送一条消息
+func (t *TelegramNotifier) Send(text string) error {
+	return t.SendButtons(text, nil)
+}
+
+// SendButtons 同步发送一条带inline按钮的消息（按钮排成一行，为空时发送普通消息）
+func (t *TelegramNotifier) SendButtons(text string, buttons []Button) error {
+	payload := map[string]interface{}{
+		"chat_id":                  t.chatID,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	}
+	if len(buttons) > 0 {
+		payload["reply_markup"] = map[string]interface{}{"inline_keyboard": [][]Button{buttons}}
+	}
+	if err := t.call(context.Background(), t.client, "sendMessage", payload, nil); err != nil {
+		return fmt.Errorf("发送Telegram消息失败: %w", err)
+	}
+	return nil
+}
+
+// call 调用Telegram Bot API（ctx取消时中断请求），result不为nil时解析返回的result字段
+func (t *TelegramNotifier) call(ctx context.Context, client *http.Client, method string, payload, result interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("序列化Telegram请求失败: %w", err)
+	}
+	url := fmt.Sprintf("%s/bot%s/%s", telegramAPI, t.botToken, method)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &telegramError{status: resp.StatusCode, body: string(body)}
+	}
+	if result == nil {
+		return nil
+	}
+	var envelope struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("解析Telegram响应失败: %w", err)
+	}
+	return json.Unmarshal(envelope.Result, result)
+}
+
+// telegramError Telegram返回的HTTP错误
+type telegramError struct {
+	status int
+	body   string
+}
+
+func (e *telegramError) Error() string {
+	return fmt.Sprintf("Telegram返回错误 (status %d): %s", e.status, e.body)
+}
+
+// SendAsync 异步发送消息（不阻塞交易流程，失败只记录日志）
+func (t *TelegramNotifier) SendAsync(text string) {
+	if t == nil {
+		return
+	}
+	go func() {
+		if err := t.Send(text); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+	}()
+}
@@ -0,0 +1,173 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	callbackPollTimeout  = 30               // getUpdates长轮询时长（秒）
+	callbackRetryDelay   = 5 * time.Second  // 轮询失败后的重试间隔
+	callbackConflictWait = 60 * time.Second // Bot已设置Webhook或其他进程在轮询时（409）的重试间隔
+)
+
+// CallbackHandler 处理inline按钮回调：data为按钮数据，user为点击者；handled为false表示不是该处理器的按钮
+// reply为显示给点击者的提示，同时追加到原消息末尾
+type CallbackHandler func(data, user string) (reply string, handled bool)
+
+// callbackRoute 某个聊天的回调处理器（owner为注册它的推送器，Stop时按owner移除）
+type callbackRoute struct {
+	owner   *TelegramNotifier
+	chatID  string
+	handler CallbackHandler
+}
+
+var (
+	callbackMu     sync.Mutex
+	callbackRoutes = make(map[string][]callbackRoute)    // botToken -> 处理器
+	callbackPolls  = make(map[string]context.CancelFunc) // botToken -> 停止长轮询
+)
+
+// telegramUpdate getUpdates返回的更新（只订阅callback_query）
+type telegramUpdate struct {
+	UpdateID      int64 `json:"update_id"`
+	CallbackQuery *struct {
+		ID   string `json:"id"`
+		Data string `json:"data"`
+		From struct {
+			ID       int64  `json:"id"`
+			Username string `json:"username"`
+		} `json:"from"`
+		Message *struct {
+			MessageID int64  `json:"message_id"`
+			Text      string `json:"text"`
+			Chat      struct {
+				ID int64 `json:"id"`
+			} `json:"chat"`
+		} `json:"message"`
+	} `json:"callback_query"`
+}
+
+// HandleCallbacks 注册本聊天的inline按钮回调处理器（同一个Bot只启动一个getUpdates长轮询，多个trader共用时按处理器依次尝试）
+// 只处理来自配置的chat_id的按钮；Bot设置了Webhook时getUpdates不可用。不再需要时调用Stop
+func (t *TelegramNotifier) HandleCallbacks(handler CallbackHandler) {
+	if t == nil {
+		return
+	}
+	callbackMu.Lock()
+	defer callbackMu.Unlock()
+	callbackRoutes[t.botToken] = append(callbackRoutes[t.botToken], callbackRoute{owner: t, chatID: t.chatID, handler: handler})
+	if callbackPolls[t.botToken] == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		callbackPolls[t.botToken] = cancel
+		go t.pollCallbacks(ctx)
+	}
+}
+
+// Stop 移除该推送器注册的回调处理器；同一个Bot没有其他处理器时停止getUpdates长轮询
+func (t *TelegramNotifier) Stop() {
+	if t == nil {
+		return
+	}
+	callbackMu.Lock()
+	defer callbackMu.Unlock()
+	routes := callbackRoutes[t.botToken][:0]
+	for _, r := range callbackRoutes[t.botToken] {
+		if r.owner != t {
+			routes = append(routes, r)
+		}
+	}
+	if len(routes) > 0 {
+		callbackRoutes[t.botToken] = routes
+		return
+	}
+	delete(callbackRoutes, t.botToken)
+	if cancel := callbackPolls[t.botToken]; cancel != nil {
+		cancel()
+		delete(callbackPolls, t.botToken)
+	}
+}
+
+// pollCallbacks 长轮询getUpdates并分发按钮回调（直到ctx取消）
+func (t *TelegramNotifier) pollCallbacks(ctx context.Context) {
+	client := &http.Client{Timeout: (callbackPollTimeout + 10) * time.Second}
+	var offset int64
+	for {
+		var updates []telegramUpdate
+		err := t.call(ctx, client, "getUpdates", map[string]interface{}{
+			"offset":          offset,
+			"timeout":         callbackPollTimeout,
+			"allowed_updates": []string{"callback_query"},
+		}, &updates)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			wait := callbackRetryDelay
+			var te *telegramError
+			if errors.As(err, &te) && te.status == http.StatusConflict {
+				wait = callbackConflictWait
+			}
+			log.Printf("⚠️  获取Telegram按钮回调失败（%v后重试）: %v", wait, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+			continue
+		}
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			if u.CallbackQuery != nil {
+				t.dispatchCallback(u)
+			}
+		}
+	}
+}
+
+// dispatchCallback 把按钮回调交给对应聊天的处理器，回复点击者并把结果追加到原消息
+func (t *TelegramNotifier) dispatchCallback(u telegramUpdate) {
+	q := u.CallbackQuery
+	if q.Message == nil {
+		return
+	}
+	chatID := strconv.FormatInt(q.Message.Chat.ID, 10)
+	user := q.From.Username
+	if user == "" {
+		user = strconv.FormatInt(q.From.ID, 10)
+	}
+
+	callbackMu.Lock()
+	routes := append([]callbackRoute(nil), callbackRoutes[t.botToken]...)
+	callbackMu.Unlock()
+
+	reply := "该按钮已失效"
+	for _, r := range routes {
+		if r.chatID != chatID {
+			continue
+		}
+		if text, handled := r.handler(q.Data, "telegram:"+user); handled {
+			reply = text
+			break
+		}
+	}
+
+	if err := t.call(context.Background(), t.client, "answerCallbackQuery", map[string]interface{}{
+		"callback_query_id": q.ID,
+		"text":              reply,
+	}, nil); err != nil {
+		log.Printf("⚠️  回复Telegram按钮回调失败: %v", err)
+	}
+	// 追加处理结果并移除按钮，防止重复点击
+	if err := t.call(context.Background(), t.client, "editMessageText", map[string]interface{}{
+		"chat_id":    q.Message.Chat.ID,
+		"message_id": q.Message.MessageID,
+		"text":       q.Message.Text + "\n\n" + reply,
+	}, nil); err != nil {
+		log.Printf("⚠️  更新Telegram消息失败: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package notify
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blockingTelegram 模拟Telegram：getUpdates一直挂起直到客户端取消请求
+func blockingTelegram(t *testing.T) (polls, cancelled chan struct{}) {
+	polls, cancelled = make(chan struct{}, 10), make(chan struct{}, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/getUpdates") {
+			w.Write([]byte(`{"ok":true,"result":true}`))
+			return
+		}
+		// 读完请求体后服务端才能感知客户端断开
+		io.Copy(io.Discard, r.Body)
+		polls <- struct{}{}
+		<-r.Context().Done()
+		cancelled <- struct{}{}
+	}))
+	old := telegramAPI
+	telegramAPI = srv.URL
+	t.Cleanup(func() {
+		telegramAPI = old
+		srv.Close()
+	})
+	return polls, cancelled
+}
+
+func waitFor(t *testing.T, ch chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
+
+func TestStopCancelsCallbackPoll(t *testing.T) {
+	polls, cancelled := blockingTelegram(t)
+	handler := func(data, user string) (string, bool) { return "", false }
+
+	a := NewTelegramNotifier("token-stop", "1")
+	b := NewTelegramNotifier("token-stop", "2")
+	a.HandleCallbacks(handler)
+	b.HandleCallbacks(handler)
+	waitFor(t, polls, "first poll")
+
+	// 同一个Bot还有其他处理器时继续轮询
+	a.Stop()
+	select {
+	case <-cancelled:
+		t.Fatal("poll cancelled while another notifier still uses the bot")
+	case <-time.After(100 * time.Millisecond):
+	}
+	callbackMu.Lock()
+	if n := len(callbackRoutes["token-stop"]); n != 1 {
+		t.Errorf("routes after first Stop = %d, want 1", n)
+	}
+	callbackMu.Unlock()
+
+	b.Stop()
+	waitFor(t, cancelled, "poll cancellation")
+	callbackMu.Lock()
+	_, polling := callbackPolls["token-stop"]
+	_, routed := callbackRoutes["token-stop"]
+	callbackMu.Unlock()
+	if polling || routed {
+		t.Errorf("bot still registered after last Stop: polling=%v routes=%v", polling, routed)
+	}
+
+	// 重新注册（如trader重新启动）时启动新的轮询
+	a.HandleCallbacks(handler)
+	waitFor(t, polls, "poll after restart")
+	a.Stop()
+	waitFor(t, cancelled, "second cancellation")
+}
+
+func TestStopNilNotifier(t *testing.T) {
+	var n *TelegramNotifier
+	n.HandleCallbacks(nil)
+	n.Stop()
+}
@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// WebhookNotifier 通用出站Webhook（POST JSON，配置secret时附带HMAC-SHA256签名）
+//
+// 请求头:
+//
+//	X-Nofx-Event:     事件类型
+//	X-Nofx-Timestamp: Unix秒级时间戳
+//	X-Nofx-Signature: sha256=hex(HMAC_SHA256(secret, timestamp + "." + body))
+type WebhookNotifier struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// NewWebhookNotifier 创建Webhook推送器（url为空时返回nil，调用方可直接忽略）
+func NewWebhookNotifier(url, secret string) *WebhookNotifier {
+	if url == "" {
+		return nil
+	}
+	return &WebhookNotifier{
+		url:    url,
+		secret: secret,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Sign 计算签名（接收方可用同样方式校验）
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Send 同步发送一条事件
+func (w *WebhookNotifier) Send(event string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("序列化Webhook负载失败: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建Webhook请求失败: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Nofx-Event", event)
+	req.Header.Set("X-Nofx-Timestamp", timestamp)
+	if w.secret != "" {
+		req.Header.Set("X-Nofx-Signature", Sign(w.secret, timestamp, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送Webhook失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Webhook返回错误 (status %d): %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// SendAsync 异步发送事件（不阻塞交易流程，失败只记录日志）
+func (w *WebhookNotifier) SendAsync(event string, payload interface{}) {
+	if w == nil {
+		return
+	}
+	go func() {
+		if err := w.Send(event, payload); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+	}()
+}
@@ -0,0 +1,169 @@
+package pool
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"nofx/ratelimit"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Binance行情缓存时间（多个trader共用，避免每个周期重复请求高权重接口）
+const (
+	tickerCacheDuration       = time.Minute // 24小时行情（权重40）
+	exchangeInfoCacheDuration = time.Hour   // 合约列表（上线时间）
+)
+
+// binanceClient Binance请求共用的客户端（与交易共享权重限制）
+var binanceClient = ratelimit.NewBinanceClient(30 * time.Second)
+
+// ticker24h 24小时行情
+type ticker24h struct {
+	Symbol             string
+	QuoteVolume        float64
+	PriceChangePercent float64
+}
+
+var (
+	tickerCache     []ticker24h
+	tickerCacheTime time.Time
+	tickerCacheMu   sync.Mutex
+
+	listingCache     map[string]int64 // 交易中的USDT永续合约 -> 上线时间（毫秒）
+	listingCacheTime time.Time
+	listingCacheMu   sync.Mutex
+)
+
+// topTickers 按score从大到小返回前limit个USDT永续合约
+func topTickers(limit int, score func(t ticker24h) float64) ([]string, error) {
+	tickers, err := getTickers()
+	if err != nil {
+		return nil, err
+	}
+	listings, err := getListings()
+	if err != nil {
+		return nil, err
+	}
+
+	// 只保留仍在交易的永续合约（排除交割合约和已下架的币种）
+	candidates := make([]ticker24h, 0, len(tickers))
+	for _, t := range tickers {
+		if _, ok := listings[t.Symbol]; ok {
+			candidates = append(candidates, t)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return score(candidates[i]) > score(candidates[j]) })
+
+	symbols := make([]string, 0, limit)
+	for _, t := range truncateTickers(candidates, limit) {
+		symbols = append(symbols, t.Symbol)
+	}
+	return symbols, nil
+}
+
+// newListings 最近days天上线的USDT永续合约（新的在前）
+func newListings(days, limit int) ([]string, error) {
+	listings, err := getListings()
+	if err != nil {
+		return nil, err
+	}
+	since := time.Now().AddDate(0, 0, -days).UnixMilli()
+
+	var symbols []string
+	for symbol, onboard := range listings {
+		if onboard >= since {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Slice(symbols, func(i, j int) bool { return listings[symbols[i]] > listings[symbols[j]] })
+	return truncate(symbols, limit), nil
+}
+
+// getTickers 获取全部合约的24小时行情（带缓存）
+func getTickers() ([]ticker24h, error) {
+	tickerCacheMu.Lock()
+	defer tickerCacheMu.Unlock()
+	if tickerCache != nil && time.Since(tickerCacheTime) < tickerCacheDuration {
+		return tickerCache, nil
+	}
+
+	var raw []struct {
+		Symbol             string `json:"symbol"`
+		QuoteVolume        string `json:"quoteVolume"`
+		PriceChangePercent string `json:"priceChangePercent"`
+	}
+	if err := getBinanceJSON("https://fapi.binance.com/fapi/v1/ticker/24hr", &raw); err != nil {
+		return nil, fmt.Errorf("获取24小时行情失败: %w", err)
+	}
+
+	tickers := make([]ticker24h, 0, len(raw))
+	for _, item := range raw {
+		volume, _ := strconv.ParseFloat(item.QuoteVolume, 64)
+		change, _ := strconv.ParseFloat(item.PriceChangePercent, 64)
+		tickers = append(tickers, ticker24h{Symbol: item.Symbol, QuoteVolume: volume, PriceChangePercent: change})
+	}
+	tickerCache, tickerCacheTime = tickers, time.Now()
+	return tickers, nil
+}
+
+// getListings 获取交易中的USDT永续合约及上线时间（带缓存）
+func getListings() (map[string]int64, error) {
+	listingCacheMu.Lock()
+	defer listingCacheMu.Unlock()
+	if listingCache != nil && time.Since(listingCacheTime) < exchangeInfoCacheDuration {
+		return listingCache, nil
+	}
+
+	var info struct {
+		Symbols []struct {
+			Symbol       string `json:"symbol"`
+			ContractType string `json:"contractType"`
+			Status       string `json:"status"`
+			QuoteAsset   string `json:"quoteAsset"`
+			OnboardDate  int64  `json:"onboardDate"`
+		} `json:"symbols"`
+	}
+	if err := getBinanceJSON("https://fapi.binance.com/fapi/v1/exchangeInfo", &info); err != nil {
+		return nil, fmt.Errorf("获取合约列表失败: %w", err)
+	}
+
+	listings := make(map[string]int64, len(info.Symbols))
+	for _, s := range info.Symbols {
+		if s.ContractType == "PERPETUAL" && s.Status == "TRADING" && s.QuoteAsset == "USDT" {
+			listings[s.Symbol] = s.OnboardDate
+		}
+	}
+	listingCache, listingCacheTime = listings, time.Now()
+	return listings, nil
+}
+
+// getBinanceJSON GET请求Binance接口并解析JSON
+func getBinanceJSON(url string, v interface{}) error {
+	resp, err := binanceClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, v)
+}
+
+// truncateTickers 截取前limit个
+func truncateTickers(tickers []ticker24h, limit int) []ticker24h {
+	if len(tickers) > limit {
+		return tickers[:limit]
+	}
+	return tickers
+}
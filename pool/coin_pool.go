@@ -4,14 +4,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
+	"nofx/logger"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
+var log = logger.Module("pool")
+
 // defaultMainstreamCoins 默认主流币种池（从配置文件读取）
 var defaultMainstreamCoins = []string{
 	"BTCUSDT",
@@ -39,6 +42,9 @@ var coinPoolConfig = CoinPoolConfig{
 	UseDefaultCoins: false, // 默认不使用
 }
 
+// settingsMu 保护API URL和默认币种设置（配置热加载时会在运行中修改）
+var settingsMu sync.RWMutex
+
 // CoinPoolCache 币种池缓存
 type CoinPoolCache struct {
 	Coins      []CoinInfo `json:"coins"`
@@ -70,39 +76,56 @@ type CoinPoolAPIResponse struct {
 
 // SetCoinPoolAPI 设置币种池API
 func SetCoinPoolAPI(apiURL string) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
 	coinPoolConfig.APIURL = apiURL
 }
 
 // SetOITopAPI 设置OI Top API
 func SetOITopAPI(apiURL string) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
 	oiTopConfig.APIURL = apiURL
 }
 
 // SetUseDefaultCoins 设置是否使用默认主流币种
 func SetUseDefaultCoins(useDefault bool) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
 	coinPoolConfig.UseDefaultCoins = useDefault
 }
 
 // SetDefaultCoins 设置默认主流币种列表
 func SetDefaultCoins(coins []string) {
 	if len(coins) > 0 {
+		settingsMu.Lock()
 		defaultMainstreamCoins = coins
+		settingsMu.Unlock()
 		log.Printf("✓ 已设置默认币种池（共%d个币种）: %v", len(coins), coins)
 	}
 }
 
+// poolSettings 当前的币种池设置（API URL、是否使用默认币种、默认币种列表）
+func poolSettings() (apiURL string, useDefault bool, defaults []string) {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return coinPoolConfig.APIURL, coinPoolConfig.UseDefaultCoins, defaultMainstreamCoins
+}
+
 // GetCoinPool 获取币种池列表（带重试和缓存机制）
 func GetCoinPool() ([]CoinInfo, error) {
+	apiURL, useDefault, defaultCoins := poolSettings()
+
 	// 优先检查是否启用默认币种列表
-	if coinPoolConfig.UseDefaultCoins {
+	if useDefault {
 		log.Printf("✓ 已启用默认主流币种列表")
-		return convertSymbolsToCoins(defaultMainstreamCoins), nil
+		return convertSymbolsToCoins(defaultCoins), nil
 	}
 
 	// 检查API URL是否配置
-	if strings.TrimSpace(coinPoolConfig.APIURL) == "" {
+	if strings.TrimSpace(apiURL) == "" {
 		log.Printf("⚠️  未配置币种池API URL，使用默认主流币种列表")
-		return convertSymbolsToCoins(defaultMainstreamCoins), nil
+		return convertSymbolsToCoins(defaultCoins), nil
 	}
 
 	maxRetries := 3
@@ -115,7 +138,7 @@ func GetCoinPool() ([]CoinInfo, error) {
 			time.Sleep(2 * time.Second) // 重试前等待2秒
 		}
 
-		coins, err := fetchCoinPool()
+		coins, err := fetchCoinPool(apiURL)
 		if err == nil {
 			if attempt > 1 {
 				log.Printf("✓ 第%d次重试成功", attempt)
@@ -141,18 +164,18 @@ func GetCoinPool() ([]CoinInfo, error) {
 
 	// 缓存也失败，使用默认主流币种
 	log.Printf("⚠️  无法加载缓存数据（最后错误: %v），使用默认主流币种列表", lastErr)
-	return convertSymbolsToCoins(defaultMainstreamCoins), nil
+	return convertSymbolsToCoins(defaultCoins), nil
 }
 
 // fetchCoinPool 实际执行币种池请求
-func fetchCoinPool() ([]CoinInfo, error) {
+func fetchCoinPool(apiURL string) ([]CoinInfo, error) {
 	log.Printf("🔄 正在请求AI500币种池...")
 
 	client := &http.Client{
 		Timeout: coinPoolConfig.Timeout,
 	}
 
-	resp, err := client.Get(coinPoolConfig.APIURL)
+	resp, err := client.Get(apiURL)
 	if err != nil {
 		return nil, fmt.Errorf("请求币种池API失败: %w", err)
 	}
@@ -420,8 +443,12 @@ var oiTopConfig = struct {
 
 // GetOITopPositions 获取持仓量增长Top20数据（带重试和缓存）
 func GetOITopPositions() ([]OIPosition, error) {
+	settingsMu.RLock()
+	apiURL := oiTopConfig.APIURL
+	settingsMu.RUnlock()
+
 	// 检查API URL是否配置
-	if strings.TrimSpace(oiTopConfig.APIURL) == "" {
+	if strings.TrimSpace(apiURL) == "" {
 		log.Printf("⚠️  未配置OI Top API URL，跳过OI Top数据获取")
 		return []OIPosition{}, nil // 返回空列表，不是错误
 	}
@@ -436,7 +463,7 @@ func GetOITopPositions() ([]OIPosition, error) {
 			time.Sleep(2 * time.Second)
 		}
 
-		positions, err := fetchOITop()
+		positions, err := fetchOITop(apiURL)
 		if err == nil {
 			if attempt > 1 {
 				log.Printf("✓ 第%d次重试成功", attempt)
@@ -466,14 +493,14 @@ func GetOITopPositions() ([]OIPosition, error) {
 }
 
 // fetchOITop 实际执行OI Top请求
-func fetchOITop() ([]OIPosition, error) {
+func fetchOITop(apiURL string) ([]OIPosition, error) {
 	log.Printf("🔄 正在请求OI Top数据...")
 
 	client := &http.Client{
 		Timeout: oiTopConfig.Timeout,
 	}
 
-	resp, err := client.Get(oiTopConfig.APIURL)
+	resp, err := client.Get(apiURL)
 	if err != nil {
 		return nil, fmt.Errorf("请求OI Top API失败: %w", err)
 	}
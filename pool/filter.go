@@ -0,0 +1,98 @@
+package pool
+
+import "fmt"
+
+// SymbolFilter 币种黑白名单（白名单非空时只交易白名单中的币种，黑名单始终排除）
+type SymbolFilter struct {
+	Whitelist []string `json:"whitelist"` // 只交易这些币种（为空表示不限制）
+	Blacklist []string `json:"blacklist"` // 禁止开仓的币种
+}
+
+// Normalize 标准化币种符号（大写、补全USDT后缀）并去重
+func (f SymbolFilter) Normalize() SymbolFilter {
+	return SymbolFilter{
+		Whitelist: normalizeSymbols(f.Whitelist),
+		Blacklist: normalizeSymbols(f.Blacklist),
+	}
+}
+
+// Validate 校验黑白名单（不能有空符号，同一币种不能同时出现在两个名单中）
+func (f SymbolFilter) Validate() error {
+	for _, list := range [][]string{f.Whitelist, f.Blacklist} {
+		for _, symbol := range list {
+			if trimSpaces(symbol) == "" {
+				return fmt.Errorf("币种符号不能为空")
+			}
+		}
+	}
+	f = f.Normalize()
+	for _, symbol := range f.Whitelist {
+		if contains(f.Blacklist, symbol) {
+			return fmt.Errorf("%s 不能同时在白名单和黑名单中", symbol)
+		}
+	}
+	return nil
+}
+
+// IsEmpty 是否未设置任何限制
+func (f SymbolFilter) IsEmpty() bool {
+	return len(f.Whitelist) == 0 && len(f.Blacklist) == 0
+}
+
+// Allows 是否允许交易该币种
+func (f SymbolFilter) Allows(symbol string) bool {
+	symbol = normalizeSymbol(symbol)
+	if contains(normalizeSymbols(f.Blacklist), symbol) {
+		return false
+	}
+	return len(f.Whitelist) == 0 || contains(normalizeSymbols(f.Whitelist), symbol)
+}
+
+// Filter 按黑白名单筛选币种池（白名单中不在币种池里的币种也加入候选，来源标记为"whitelist"）
+func (m *MergedCoinPool) Filter(f SymbolFilter) *MergedCoinPool {
+	if f.IsEmpty() {
+		return m
+	}
+	f = f.Normalize()
+
+	filtered := &MergedCoinPool{
+		AI500Coins:    m.AI500Coins,
+		OITopCoins:    m.OITopCoins,
+		SymbolSources: make(map[string][]string),
+	}
+	for _, symbol := range m.AllSymbols {
+		if f.Allows(symbol) {
+			filtered.AllSymbols = append(filtered.AllSymbols, symbol)
+			filtered.SymbolSources[symbol] = m.SymbolSources[symbol]
+		}
+	}
+	for _, symbol := range f.Whitelist {
+		if _, exists := filtered.SymbolSources[symbol]; !exists && f.Allows(symbol) {
+			filtered.AllSymbols = append(filtered.AllSymbols, symbol)
+			filtered.SymbolSources[symbol] = []string{"whitelist"}
+		}
+	}
+	return filtered
+}
+
+// normalizeSymbols 标准化并去重币种列表
+func normalizeSymbols(symbols []string) []string {
+	result := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		symbol = normalizeSymbol(symbol)
+		if !contains(result, symbol) {
+			result = append(result, symbol)
+		}
+	}
+	return result
+}
+
+// contains 列表中是否包含该币种
+func contains(symbols []string, symbol string) bool {
+	for _, s := range symbols {
+		if s == symbol {
+			return true
+		}
+	}
+	return false
+}
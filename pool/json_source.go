@@ -0,0 +1,101 @@
+package pool
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// jsonURLSource 从任意返回JSON的URL提取币种列表（按响应中的顺序作为优先级）
+type jsonURLSource struct {
+	name        string
+	url         string
+	listPath    []string
+	symbolField string
+	client      *http.Client
+}
+
+// newJSONURLSource 创建json_url来源
+func newJSONURLSource(cfg SourceConfig) (CoinSource, error) {
+	if !strings.HasPrefix(cfg.URL, "http://") && !strings.HasPrefix(cfg.URL, "https://") {
+		return nil, fmt.Errorf("币种来源 %s 的url必须以http://或https://开头", cfg.displayName())
+	}
+	var path []string
+	if cfg.ListPath != "" {
+		path = strings.Split(cfg.ListPath, ".")
+	}
+	return &jsonURLSource{
+		name:        cfg.displayName(),
+		url:         cfg.URL,
+		listPath:    path,
+		symbolField: cfg.SymbolField,
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Name 来源名称
+func (s *jsonURLSource) Name() string { return s.name }
+
+// Fetch 请求URL并按list_path/symbol_field提取币种
+func (s *jsonURLSource) Fetch(limit int) ([]string, error) {
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("返回错误 (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("JSON解析失败: %w", err)
+	}
+	return extractSymbols(data, s.listPath, s.symbolField, limit)
+}
+
+// extractSymbols 按路径找到列表，再从每一项中取出币种
+func extractSymbols(data interface{}, path []string, symbolField string, limit int) ([]string, error) {
+	for _, key := range path {
+		obj, ok := data.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("路径 %s 不是对象", key)
+		}
+		if data, ok = obj[key]; !ok {
+			return nil, fmt.Errorf("响应中没有字段 %s", key)
+		}
+	}
+	items, ok := data.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("list_path指向的不是列表")
+	}
+
+	var symbols []string
+	for _, item := range items {
+		if len(symbols) >= limit {
+			break
+		}
+		if symbolField != "" {
+			obj, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			item = obj[symbolField]
+		}
+		if symbol, ok := item.(string); ok && strings.TrimSpace(symbol) != "" {
+			symbols = append(symbols, normalizeSymbol(symbol))
+		}
+	}
+	if len(symbols) == 0 {
+		return nil, fmt.Errorf("没有提取到币种")
+	}
+	return symbols, nil
+}
@@ -0,0 +1,222 @@
+package pool
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 内置币种来源类型
+const (
+	SourceAI500       = "ai500"        // AI500评分最高的币种
+	SourceOITop       = "oi_top"       // 持仓量增长Top
+	SourceTopVolume   = "top_volume"   // Binance 24小时成交额最高的USDT永续合约
+	SourceTopGainers  = "top_gainers"  // Binance 24小时涨幅最高的USDT永续合约
+	SourceNewListings = "new_listings" // 最近上线的USDT永续合约（新的在前）
+	SourceJSONURL     = "json_url"     // 任意返回JSON的URL（按路径提取币种列表）
+)
+
+const (
+	defaultPoolSize    = 20 // 未配置coin_pool_size时的候选币种数量
+	defaultListingDays = 30 // new_listings默认只取最近30天上线的合约
+)
+
+// CoinSource 候选币种来源（返回按优先级排序的币种列表）
+type CoinSource interface {
+	Name() string                      // 来源名称（写入候选币种的来源标记）
+	Fetch(limit int) ([]string, error) // 获取最多limit个币种（标准化为USDT交易对）
+}
+
+// SourceConfig 单个币种来源的配置（trader的coin_sources中的一项）
+type SourceConfig struct {
+	Type   string  `json:"type"`             // 来源类型（见Source*常量或RegisterSource注册的类型）
+	Name   string  `json:"name,omitempty"`   // 来源名称（默认为类型，同一类型配置多次时用于区分）
+	Weight float64 `json:"weight,omitempty"` // 权重（按权重比例分配候选名额，默认1）
+
+	// new_listings
+	Days int `json:"days,omitempty"` // 只取最近N天上线的合约（默认30）
+
+	// json_url
+	URL         string `json:"url,omitempty"`          // 请求地址（GET）
+	ListPath    string `json:"list_path,omitempty"`    // 列表在响应中的路径（如 "data.coins"，为空表示响应本身就是列表）
+	SymbolField string `json:"symbol_field,omitempty"` // 列表项中的币种字段（为空表示列表项本身就是币种字符串）
+}
+
+// SourceFactory 根据配置创建币种来源
+type SourceFactory func(cfg SourceConfig) (CoinSource, error)
+
+var (
+	sourceFactories   = make(map[string]SourceFactory)
+	sourceFactoriesMu sync.RWMutex
+)
+
+// RegisterSource 注册自定义币种来源类型（同名覆盖）
+func RegisterSource(sourceType string, factory SourceFactory) {
+	sourceFactoriesMu.Lock()
+	defer sourceFactoriesMu.Unlock()
+	sourceFactories[sourceType] = factory
+}
+
+func init() {
+	RegisterSource(SourceAI500, func(cfg SourceConfig) (CoinSource, error) {
+		return &funcSource{name: cfg.displayName(), fetch: GetTopRatedCoins}, nil
+	})
+	RegisterSource(SourceOITop, func(cfg SourceConfig) (CoinSource, error) {
+		return &funcSource{name: cfg.displayName(), fetch: func(limit int) ([]string, error) {
+			symbols, err := GetOITopSymbols()
+			return truncate(symbols, limit), err
+		}}, nil
+	})
+	RegisterSource(SourceTopVolume, func(cfg SourceConfig) (CoinSource, error) {
+		return &funcSource{name: cfg.displayName(), fetch: func(limit int) ([]string, error) {
+			return topTickers(limit, func(t ticker24h) float64 { return t.QuoteVolume })
+		}}, nil
+	})
+	RegisterSource(SourceTopGainers, func(cfg SourceConfig) (CoinSource, error) {
+		return &funcSource{name: cfg.displayName(), fetch: func(limit int) ([]string, error) {
+			return topTickers(limit, func(t ticker24h) float64 { return t.PriceChangePercent })
+		}}, nil
+	})
+	RegisterSource(SourceNewListings, func(cfg SourceConfig) (CoinSource, error) {
+		days := cfg.Days
+		if days <= 0 {
+			days = defaultListingDays
+		}
+		return &funcSource{name: cfg.displayName(), fetch: func(limit int) ([]string, error) {
+			return newListings(days, limit)
+		}}, nil
+	})
+	RegisterSource(SourceJSONURL, newJSONURLSource)
+}
+
+// displayName 来源名称（未配置时使用类型）
+func (cfg SourceConfig) displayName() string {
+	if cfg.Name != "" {
+		return cfg.Name
+	}
+	return cfg.Type
+}
+
+// Validate 校验来源配置
+func (cfg SourceConfig) Validate() error {
+	if cfg.Weight < 0 {
+		return fmt.Errorf("币种来源 %s 的weight不能为负数", cfg.displayName())
+	}
+	_, err := NewSource(cfg)
+	return err
+}
+
+// NewSource 根据配置创建币种来源
+func NewSource(cfg SourceConfig) (CoinSource, error) {
+	sourceFactoriesMu.RLock()
+	factory, ok := sourceFactories[cfg.Type]
+	sourceFactoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("不支持的币种来源类型: %s", cfg.Type)
+	}
+	return factory(cfg)
+}
+
+// ValidateSources 校验trader的币种来源列表（来源名称不能重复）
+func ValidateSources(configs []SourceConfig) error {
+	seen := make(map[string]bool)
+	for _, cfg := range configs {
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+		if seen[cfg.displayName()] {
+			return fmt.Errorf("币种来源名称 '%s' 重复（同一类型配置多次时请设置name）", cfg.displayName())
+		}
+		seen[cfg.displayName()] = true
+	}
+	return nil
+}
+
+// GetWeightedCoinPool 按权重组合多个币种来源（每个来源按权重比例分配size个候选名额中的一部分，
+// 已被其他来源选中的币种只追加来源标记；单个来源失败时跳过，全部失败时返回错误）
+func GetWeightedCoinPool(configs []SourceConfig, size int) (*MergedCoinPool, error) {
+	if size <= 0 {
+		size = defaultPoolSize
+	}
+
+	// 权重高的来源优先挑选
+	configs = append([]SourceConfig(nil), configs...)
+	for i := range configs {
+		if configs[i].Weight == 0 {
+			configs[i].Weight = 1
+		}
+	}
+	sort.SliceStable(configs, func(i, j int) bool { return configs[i].Weight > configs[j].Weight })
+	totalWeight := 0.0
+	for _, cfg := range configs {
+		totalWeight += cfg.Weight
+	}
+
+	merged := &MergedCoinPool{SymbolSources: make(map[string][]string)}
+	var errs []string
+	for _, cfg := range configs {
+		source, err := NewSource(cfg)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		symbols, err := source.Fetch(size)
+		if err != nil {
+			log.Printf("⚠️  币种来源 %s 获取失败: %v", source.Name(), err)
+			errs = append(errs, fmt.Sprintf("%s: %v", source.Name(), err))
+			continue
+		}
+
+		quota := int(math.Max(1, math.Round(float64(size)*cfg.Weight/totalWeight)))
+		added := 0
+		for _, symbol := range symbols {
+			symbol = normalizeSymbol(symbol)
+			if sources, exists := merged.SymbolSources[symbol]; exists {
+				if !contains(sources, source.Name()) {
+					merged.SymbolSources[symbol] = append(sources, source.Name())
+				}
+				continue
+			}
+			if added >= quota {
+				continue
+			}
+			merged.AllSymbols = append(merged.AllSymbols, symbol)
+			merged.SymbolSources[symbol] = []string{source.Name()}
+			added++
+		}
+		log.Printf("📊 币种来源 %s: 获取%d个，新增%d个（名额%d）", source.Name(), len(symbols), added, quota)
+	}
+
+	if len(merged.AllSymbols) == 0 && len(errs) > 0 {
+		return nil, fmt.Errorf("所有币种来源均获取失败: %s", strings.Join(errs, "; "))
+	}
+	return merged, nil
+}
+
+// funcSource 由函数实现的币种来源
+type funcSource struct {
+	name  string
+	fetch func(limit int) ([]string, error)
+}
+
+// Name 来源名称
+func (s *funcSource) Name() string { return s.name }
+
+// Fetch 获取币种列表
+func (s *funcSource) Fetch(limit int) ([]string, error) {
+	symbols, err := s.fetch(limit)
+	if err != nil {
+		return nil, err
+	}
+	return truncate(symbols, limit), nil
+}
+
+// truncate 截取前limit个
+func truncate(symbols []string, limit int) []string {
+	if len(symbols) > limit {
+		return symbols[:limit]
+	}
+	return symbols
+}
@@ -0,0 +1,226 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"nofx/logger"
+)
+
+var log = logger.Module("ratelimit")
+
+// Binance U本位合约REST限制参数
+const (
+	binanceWeightLimit = 2400             // Binance每分钟权重上限（按IP计算）
+	binanceWeightSafe  = 2000             // 本进程使用的权重预算（留出余量给手动操作和误差）
+	rateLimitBackoff   = time.Minute      // 429且没有Retry-After时的等待时间
+	ipBanBackoff       = 2 * time.Minute  // 418且没有Retry-After时的等待时间
+	maxBackoff         = 30 * time.Minute // 单次等待上限
+)
+
+// Limiter 按分钟窗口计算的请求权重限流器（进程内所有trader共享）
+type Limiter struct {
+	mu          sync.Mutex
+	budget      int       // 每分钟可用权重
+	used        int       // 当前分钟已用权重（本地预估，响应头更大时以响应头为准）
+	window      int64     // 当前分钟（Unix分钟数）
+	pausedUntil time.Time // 收到429/418后暂停所有请求直到该时间
+}
+
+// BinanceFutures Binance U本位合约REST接口共享的限流器
+var BinanceFutures = NewLimiter(binanceWeightSafe)
+
+// NewLimiter 创建每分钟权重预算为budget的限流器
+func NewLimiter(budget int) *Limiter {
+	return &Limiter{budget: budget}
+}
+
+// Wait 阻塞直到有足够的权重发送请求，并预先扣除权重（ctx取消时放弃等待并返回ctx的错误）
+func (l *Limiter) Wait(ctx context.Context, weight int) error {
+	if weight <= 0 {
+		return nil
+	}
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		if now.Before(l.pausedUntil) {
+			wait := l.pausedUntil.Sub(now)
+			l.mu.Unlock()
+			if err := sleep(ctx, wait); err != nil {
+				return err
+			}
+			continue
+		}
+		l.resetWindow(now)
+		if l.used+weight <= l.budget || l.used == 0 {
+			l.used += weight
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Unix((l.window+1)*60, 0).Sub(now)
+		used := l.used
+		l.mu.Unlock()
+		log.Printf("⏳ Binance权重已用 %d/%d，等待 %.0f 秒", used, l.budget, wait.Seconds())
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// sleep 等待d或直到ctx取消
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Observe 根据响应同步已用权重，收到429/418时暂停所有请求
+func (l *Limiter) Observe(resp *http.Response) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.resetWindow(now)
+
+	if weight, err := strconv.Atoi(resp.Header.Get("X-MBX-USED-WEIGHT-1M")); err == nil && weight > l.used {
+		l.used = weight
+	}
+
+	var backoff time.Duration
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		backoff = retryAfter(resp, rateLimitBackoff)
+		log.Printf("⚠️  Binance返回429（权重超限），暂停所有请求 %v", backoff)
+	case http.StatusTeapot:
+		backoff = retryAfter(resp, ipBanBackoff)
+		log.Printf("🚫 Binance返回418（IP已被封禁），暂停所有请求 %v", backoff)
+	default:
+		return
+	}
+	if until := now.Add(backoff); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// Used 当前分钟已用权重和预算
+func (l *Limiter) Used() (used, budget int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.resetWindow(time.Now())
+	return l.used, l.budget
+}
+
+// resetWindow 进入新的一分钟时清零已用权重（调用方持有mu）
+func (l *Limiter) resetWindow(now time.Time) {
+	if minute := now.Unix() / 60; minute != l.window {
+		l.window = minute
+		l.used = 0
+	}
+}
+
+// retryAfter 解析Retry-After响应头（秒），没有时使用默认值
+func retryAfter(resp *http.Response, fallback time.Duration) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return fallback
+	}
+	if d := time.Duration(seconds) * time.Second; d < maxBackoff {
+		return d
+	}
+	return maxBackoff
+}
+
+// Transport 在请求前按接口权重限流、在响应后同步权重的http.RoundTripper
+type Transport struct {
+	Base    http.RoundTripper // 为nil时使用http.DefaultTransport
+	Limiter *Limiter
+}
+
+// RoundTrip 实现http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.Limiter.Wait(req.Context(), binanceWeight(req.URL)); err != nil {
+		return nil, err
+	}
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	t.Limiter.Observe(resp)
+	return resp, nil
+}
+
+// NewBinanceClient 创建经过共享限流器的HTTP客户端（timeout为0表示不超时）
+func NewBinanceClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &Transport{Limiter: BinanceFutures},
+	}
+}
+
+// binanceWeight 估算Binance U本位合约接口的请求权重
+func binanceWeight(u *url.URL) int {
+	query := u.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	hasSymbol := query.Get("symbol") != ""
+
+	switch {
+	case strings.HasPrefix(u.Path, "/futures/data/"):
+		return 0 // 数据接口单独限流，不计入IP权重
+	case u.Path == "/fapi/v1/klines" || u.Path == "/fapi/v1/markPriceKlines" || u.Path == "/fapi/v1/indexPriceKlines":
+		switch {
+		case limit > 0 && limit < 100:
+			return 1
+		case limit > 0 && limit < 500:
+			return 2
+		case limit == 0 || limit <= 1000:
+			return 5
+		}
+		return 10
+	case u.Path == "/fapi/v1/depth":
+		switch {
+		case limit == 0 || limit <= 50:
+			return 2
+		case limit <= 100:
+			return 5
+		case limit <= 500:
+			return 10
+		}
+		return 20
+	case u.Path == "/fapi/v1/premiumIndex" || u.Path == "/fapi/v1/ticker/price" || u.Path == "/fapi/v1/ticker/bookTicker":
+		if hasSymbol {
+			return 1
+		}
+		return 10
+	case u.Path == "/fapi/v1/ticker/24hr":
+		if hasSymbol {
+			return 1
+		}
+		return 40
+	case u.Path == "/fapi/v1/openOrders":
+		if hasSymbol {
+			return 1
+		}
+		return 40
+	case u.Path == "/fapi/v1/exchangeInfo":
+		return 1
+	case u.Path == "/fapi/v2/account" || u.Path == "/fapi/v2/balance" || u.Path == "/fapi/v2/positionRisk" ||
+		u.Path == "/fapi/v3/account" || u.Path == "/fapi/v3/balance" || u.Path == "/fapi/v3/positionRisk":
+		return 5
+	case u.Path == "/fapi/v1/allOrders" || u.Path == "/fapi/v1/userTrades" || u.Path == "/fapi/v1/income":
+		return 5
+	}
+	return 1
+}
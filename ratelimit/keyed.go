@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// idleBucketTTL 令牌桶闲置超过该时间后清理（此时桶已补满，重新创建等价）
+const idleBucketTTL = 10 * time.Minute
+
+// KeyedLimiter 按键（IP、用户等）分别计算的令牌桶限流器：每分钟补充perMinute个令牌，最多累积burst个
+type KeyedLimiter struct {
+	mu        sync.Mutex
+	rate      float64 // 每秒补充的令牌数
+	burst     float64
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// bucket 单个键的令牌桶
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewKeyedLimiter 创建每个键每分钟perMinute次、突发burst次的限流器
+func NewKeyedLimiter(perMinute, burst int) *KeyedLimiter {
+	return &KeyedLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(max(burst, 1)),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow 尝试消耗key的一个令牌，被限流时返回需要等待的时间
+func (l *KeyedLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	if l.rate <= 0 {
+		return false, time.Minute
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// sweep 定期清理闲置的令牌桶，避免大量不同IP占用内存（调用方持有mu）
+func (l *KeyedLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleBucketTTL {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > idleBucketTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
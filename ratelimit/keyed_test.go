@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiterAllow(t *testing.T) {
+	tests := []struct {
+		name      string
+		perMinute int
+		burst     int
+		calls     int
+		allowed   int
+		wantWait  time.Duration // 第一次被限流时的等待时间
+	}{
+		{name: "within burst", perMinute: 60, burst: 5, calls: 5, allowed: 5},
+		{name: "burst exhausted", perMinute: 60, burst: 3, calls: 5, allowed: 3, wantWait: time.Second},
+		{name: "slow refill", perMinute: 6, burst: 2, calls: 3, allowed: 2, wantWait: 10 * time.Second},
+		{name: "zero burst allows one", perMinute: 60, burst: 0, calls: 2, allowed: 1, wantWait: time.Second},
+		{name: "zero rate waits a minute", perMinute: 0, burst: 1, calls: 2, allowed: 1, wantWait: time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewKeyedLimiter(tt.perMinute, tt.burst)
+			allowed := 0
+			var wait time.Duration
+			for i := 0; i < tt.calls; i++ {
+				ok, retry := l.Allow("1.2.3.4")
+				if ok {
+					allowed++
+				} else if wait == 0 {
+					wait = retry
+				}
+			}
+			if allowed != tt.allowed {
+				t.Errorf("allowed = %d, want %d", allowed, tt.allowed)
+			}
+			// 两次调用之间有极少的补充，等待时间允许略短
+			if wait > tt.wantWait || wait < tt.wantWait-50*time.Millisecond {
+				t.Errorf("retry after = %v, want %v", wait, tt.wantWait)
+			}
+		})
+	}
+}
+
+func TestKeyedLimiterKeysIndependent(t *testing.T) {
+	l := NewKeyedLimiter(60, 1)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Fatal("first call for a rejected")
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Fatal("second call for a allowed")
+	}
+	if ok, _ := l.Allow("b"); !ok {
+		t.Fatal("key b limited by key a")
+	}
+}
+
+func TestKeyedLimiterRefill(t *testing.T) {
+	l := NewKeyedLimiter(60, 5)
+	for i := 0; i < 5; i++ {
+		l.Allow("a")
+	}
+	// 模拟经过2秒：补充2个令牌
+	l.buckets["a"].last = l.buckets["a"].last.Add(-2 * time.Second)
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("call %d after refill rejected", i+1)
+		}
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Fatal("refill exceeded elapsed time")
+	}
+
+	// 闲置很久也不超过burst
+	l.buckets["a"].last = time.Now().Add(-time.Hour)
+	l.lastSweep = time.Now()
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if ok, _ := l.Allow("a"); ok {
+			allowed++
+		}
+	}
+	if allowed != 5 {
+		t.Errorf("allowed after long idle = %d, want burst 5", allowed)
+	}
+}
+
+func TestKeyedLimiterSweep(t *testing.T) {
+	l := NewKeyedLimiter(60, 1)
+	l.Allow("idle")
+	l.Allow("active")
+	l.buckets["idle"].last = time.Now().Add(-idleBucketTTL - time.Second)
+	l.lastSweep = time.Now().Add(-idleBucketTTL - time.Second)
+
+	l.Allow("active")
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("idle bucket not swept")
+	}
+	if _, ok := l.buckets["active"]; !ok {
+		t.Error("active bucket swept")
+	}
+}
+
+func TestKeyedLimiterConcurrent(t *testing.T) {
+	l := NewKeyedLimiter(1, 20)
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := l.Allow("shared"); ok {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := allowed.Load(); n != 20 {
+		t.Errorf("allowed = %d, want 20", n)
+	}
+}
@@ -0,0 +1,149 @@
+// Package schedule 交易时段：每周允许交易的时间窗口和禁止交易的时间段（如重大数据发布）
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schedule trader的交易时段（零值表示全天候交易）
+type Schedule struct {
+	Timezone  string     `json:"timezone,omitempty"` // 时区（IANA名称，如 "Asia/Shanghai"，默认UTC）
+	Windows   []Window   `json:"windows"`            // 允许开仓的时间窗口（为空表示不限制）
+	Blackouts []Blackout `json:"blackouts"`          // 禁止开仓的时间段（优先于windows）
+}
+
+// Window 每周重复的交易窗口
+type Window struct {
+	Days  []string `json:"days,omitempty"` // 星期（mon/tue/wed/thu/fri/sat/sun，为空表示每天）
+	Start string   `json:"start"`          // 开始时间 "HH:MM"
+	End   string   `json:"end"`            // 结束时间 "HH:MM"（早于开始时间表示跨越午夜，与开始时间相同表示全天）
+}
+
+// Blackout 一次性的禁止交易时间段
+type Blackout struct {
+	Name  string    `json:"name,omitempty"` // 说明（如 "FOMC"）
+	Start time.Time `json:"start"`          // RFC3339格式
+	End   time.Time `json:"end"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// IsEmpty 是否未设置任何限制
+func (s Schedule) IsEmpty() bool {
+	return len(s.Windows) == 0 && len(s.Blackouts) == 0
+}
+
+// Validate 校验时区、星期和时间格式
+func (s Schedule) Validate() error {
+	if _, err := s.location(); err != nil {
+		return err
+	}
+	for i, w := range s.Windows {
+		for _, d := range w.Days {
+			if _, ok := weekdays[strings.ToLower(d)]; !ok {
+				return fmt.Errorf("windows[%d]: 无效的星期 '%s'（可选 mon, tue, wed, thu, fri, sat, sun）", i, d)
+			}
+		}
+		if _, err := parseClock(w.Start); err != nil {
+			return fmt.Errorf("windows[%d].start: %w", i, err)
+		}
+		if _, err := parseClock(w.End); err != nil {
+			return fmt.Errorf("windows[%d].end: %w", i, err)
+		}
+	}
+	for i, b := range s.Blackouts {
+		if b.Start.IsZero() || b.End.IsZero() || !b.End.After(b.Start) {
+			return fmt.Errorf("blackouts[%d]: end必须晚于start", i)
+		}
+	}
+	return nil
+}
+
+// Closed 判断now是否处于非交易时段，返回原因（交易时段内返回false）
+func (s Schedule) Closed(now time.Time) (string, bool) {
+	for _, b := range s.Blackouts {
+		if !now.Before(b.Start) && now.Before(b.End) {
+			name := b.Name
+			if name == "" {
+				name = "禁止交易时段"
+			}
+			return fmt.Sprintf("%s（至 %s）", name, b.End.Format("2006-01-02 15:04 MST")), true
+		}
+	}
+	if len(s.Windows) == 0 {
+		return "", false
+	}
+
+	loc, err := s.location()
+	if err != nil {
+		return "", false
+	}
+	local := now.In(loc)
+	for _, w := range s.Windows {
+		if w.contains(local) {
+			return "", false
+		}
+	}
+	return fmt.Sprintf("不在交易时段内（%s %s）", local.Format("Mon 15:04"), loc), true
+}
+
+// contains 本地时间是否在窗口内（跨午夜的窗口按开始时间所在的星期判断）
+func (w Window) contains(t time.Time) bool {
+	start, _ := parseClock(w.Start)
+	end, _ := parseClock(w.End)
+	minute := t.Hour()*60 + t.Minute()
+
+	if start == end {
+		return w.onDay(t.Weekday())
+	}
+	if start < end {
+		return minute >= start && minute < end && w.onDay(t.Weekday())
+	}
+	// 跨午夜：开始时间之后算当天，结束时间之前算前一天的窗口
+	if minute >= start {
+		return w.onDay(t.Weekday())
+	}
+	if minute < end {
+		return w.onDay((t.Weekday() + 6) % 7)
+	}
+	return false
+}
+
+// onDay 窗口是否适用于该星期
+func (w Window) onDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if weekdays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// location 解析时区（默认UTC）
+func (s Schedule) location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("无效的时区 '%s': %w", s.Timezone, err)
+	}
+	return loc, nil
+}
+
+// parseClock 解析 "HH:MM" 为当天的分钟数
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("时间格式必须为HH:MM: '%s'", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,134 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/mcp"
+	"time"
+)
+
+// AIUsageSummary AI调用用量汇总（GET /api/traders/:id/ai-usage）
+type AIUsageSummary struct {
+	Days             int              `json:"days"`              // 统计天数（含今天）
+	Calls            int              `json:"calls"`             // 统计期间的调用次数
+	PromptTokens     int              `json:"prompt_tokens"`     // 统计期间的输入token
+	CompletionTokens int              `json:"completion_tokens"` // 统计期间的输出token
+	CostUSD          float64          `json:"cost_usd"`          // 统计期间的估算成本
+	TodayCostUSD     float64          `json:"today_cost_usd"`    // 今日估算成本
+	MaxDailyCostUSD  float64          `json:"max_daily_ai_cost"` // 每日成本上限（0表示不限制）
+	BudgetExceeded   bool             `json:"budget_exceeded"`   // 今日是否已暂停决策
+	Usage            []logger.AIUsage `json:"usage"`             // 按日期、提供商、模型的明细
+}
+
+// trackAIUsage 为trader使用的所有AI客户端（包括备用AI和委员会成员）注册用量回调
+func (at *AutoTrader) trackAIUsage() {
+	at.mcpClient.OnUsage = at.recordAIUsage
+	for _, c := range at.mcpClient.Fallbacks {
+		c.OnUsage = at.recordAIUsage
+	}
+	for _, m := range at.strategyOpts.EnsembleModels {
+		m.Client.OnUsage = at.recordAIUsage
+	}
+}
+
+// ModelRoute 决策使用的AI模型链（主模型在前，之后是按顺序切换的备用模型）
+func (at *AutoTrader) ModelRoute() []mcp.RouteEntry {
+	return at.mcpClient.Route()
+}
+
+// recordAIUsage 估算一次AI调用的成本并累加到当日用量（在AI客户端的调用协程中执行）
+func (at *AutoTrader) recordAIUsage(usage mcp.Usage) {
+	price, ok := mcp.LookupPrice(usage.Model)
+	if !ok {
+		price = mcp.Price{Input: at.config.AIInputPrice, Output: at.config.AIOutputPrice}
+	}
+	cost := price.Cost(usage)
+	day := time.Now().Format("2006-01-02")
+
+	// 持锁写库，避免与aiCostTodayUSD从数据库加载时重复累加
+	at.aiUsageMu.Lock()
+	defer at.aiUsageMu.Unlock()
+	if at.aiCostDay == day {
+		at.aiCostToday += cost
+	}
+	if err := logger.RecordAIUsage(at.id, logger.AIUsage{
+		Day:              day,
+		Provider:         string(usage.Provider),
+		Model:            usage.Model,
+		Calls:            1,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CostUSD:          cost,
+	}); err != nil {
+		at.baseLog.Printf("⚠️  记录AI用量失败: %v", err)
+	}
+	at.baseLog.Printf("🧾 AI用量 %s: 输入 %d / 输出 %d tokens，约 $%.4f",
+		usage.Model, usage.PromptTokens, usage.CompletionTokens, cost)
+}
+
+// aiCostTodayUSD 今日AI估算成本（跨天或重启后从数据库重新加载）
+func (at *AutoTrader) aiCostTodayUSD() float64 {
+	day := time.Now().Format("2006-01-02")
+
+	at.aiUsageMu.Lock()
+	defer at.aiUsageMu.Unlock()
+	if at.aiCostDay != day {
+		cost, err := logger.GetAIDailyCost(at.id, day)
+		if err != nil {
+			at.baseLog.Printf("⚠️  读取今日AI成本失败: %v", err)
+			return at.aiCostToday
+		}
+		at.aiCostDay = day
+		at.aiCostToday = cost
+	}
+	return at.aiCostToday
+}
+
+// aiBudgetExceeded 今日AI成本是否已达上限（规则策略不调用AI，不受限制）
+func (at *AutoTrader) aiBudgetExceeded() (float64, bool) {
+	if at.config.MaxDailyAICost <= 0 || at.GetStrategy().Name() == decision.StrategyRule {
+		return 0, false
+	}
+	cost := at.aiCostTodayUSD()
+	return cost, cost >= at.config.MaxDailyAICost
+}
+
+// notifyAIBudgetExceeded 推送预算耗尽通知（每天只推送一次）
+func (at *AutoTrader) notifyAIBudgetExceeded(cost float64) {
+	day := time.Now().Format("2006-01-02")
+	at.aiUsageMu.Lock()
+	notified := at.aiBudgetNotified == day
+	at.aiBudgetNotified = day
+	at.aiUsageMu.Unlock()
+	if !notified {
+		at.notify("💸 今日AI成本 $%.2f 已达上限 $%.2f，暂停决策至次日", cost, at.config.MaxDailyAICost)
+	}
+}
+
+// GetAIUsage 获取最近days天（含今天）的AI用量汇总
+func (at *AutoTrader) GetAIUsage(days int) (*AIUsageSummary, error) {
+	since := time.Now().AddDate(0, 0, -(days - 1)).Format("2006-01-02")
+	usage, err := logger.GetAIUsage(at.id, since)
+	if err != nil {
+		return nil, fmt.Errorf("获取AI用量失败: %w", err)
+	}
+
+	summary := &AIUsageSummary{
+		Days:            days,
+		TodayCostUSD:    at.aiCostTodayUSD(),
+		MaxDailyCostUSD: at.config.MaxDailyAICost,
+		Usage:           usage,
+	}
+	if summary.Usage == nil {
+		summary.Usage = []logger.AIUsage{}
+	}
+	for _, u := range usage {
+		summary.Calls += u.Calls
+		summary.PromptTokens += u.PromptTokens
+		summary.CompletionTokens += u.CompletionTokens
+		summary.CostUSD += u.CostUSD
+	}
+	_, summary.BudgetExceeded = at.aiBudgetExceeded()
+	return summary, nil
+}
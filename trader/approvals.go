@@ -0,0 +1,195 @@
+package trader
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"nofx/decision"
+	"nofx/notify"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 审批状态
+const (
+	ApprovalPending  = "pending"  // 等待人工确认
+	ApprovalApproved = "approved" // 已批准并执行成功
+	ApprovalFailed   = "failed"   // 已批准但执行失败
+	ApprovalRejected = "rejected" // 已拒绝
+	ApprovalExpired  = "expired"  // 超时未处理
+)
+
+const (
+	defaultApprovalTimeout = 15 * time.Minute // 未设置审批有效期时的默认值
+	approvalRetention      = 24 * time.Hour   // 已处理的审批保留时长（供API查询）
+	approvalCallbackPrefix = "approval:"      // Telegram按钮数据前缀（approval:<approve|reject>:<id>）
+)
+
+// Approval 等待人工确认的开仓决策
+type Approval struct {
+	ID        string            `json:"id"`
+	TraderID  string            `json:"trader_id"`
+	Decision  decision.Decision `json:"decision"`
+	Price     float64           `json:"price"` // 决策时的价格
+	Status    string            `json:"status"`
+	CreatedAt time.Time         `json:"created_at"`
+	ExpiresAt time.Time         `json:"expires_at"`
+	DecidedAt time.Time         `json:"decided_at,omitempty"`
+	DecidedBy string            `json:"decided_by,omitempty"`
+	Error     string            `json:"error,omitempty"` // 批准后执行失败的原因
+}
+
+// newApprovalID 生成审批ID（16位十六进制）
+func newApprovalID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// needsApproval 开仓金额达到审批阈值时需要人工确认
+func (at *AutoTrader) needsApproval(d *decision.Decision) bool {
+	threshold := at.config.ApprovalThreshold
+	return threshold > 0 && (d.Action == "open_long" || d.Action == "open_short") && d.PositionSizeUSD >= threshold
+}
+
+// queueApproval 把开仓决策加入待审批队列，并推送带批准/拒绝按钮的Telegram消息
+func (at *AutoTrader) queueApproval(d decision.Decision, price float64) *Approval {
+	timeout := at.config.ApprovalTimeout
+	if timeout <= 0 {
+		timeout = defaultApprovalTimeout
+	}
+	now := time.Now()
+	a := &Approval{
+		ID:        newApprovalID(),
+		TraderID:  at.id,
+		Decision:  d,
+		Price:     price,
+		Status:    ApprovalPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(timeout),
+	}
+
+	at.approvalMu.Lock()
+	at.pruneApprovals(now)
+	at.approvals[a.ID] = a
+	at.approvalMu.Unlock()
+
+	at.log.Printf("  ⏳ %s %s %.2f USDT 超过审批阈值，等待人工确认（审批ID: %s，%s前有效）",
+		d.Symbol, d.Action, d.PositionSizeUSD, a.ID, a.ExpiresAt.Format("15:04"))
+	if at.telegram != nil {
+		text := fmt.Sprintf("[%s] ⏳ 开仓待审批 #%s\n%s %s | 仓位 %.2f USDT | %dx | 止损 %.4f | 止盈 %.4f\n理由: %s\n%s前有效",
+			at.GetName(), a.ID, d.Symbol, d.Action, d.PositionSizeUSD, d.Leverage, d.StopLoss, d.TakeProfit, d.Reasoning,
+			a.ExpiresAt.Format("2006-01-02 15:04"))
+		buttons := []notify.Button{
+			{Text: "✅ 批准", Data: approvalCallbackPrefix + "approve:" + a.ID},
+			{Text: "❌ 拒绝", Data: approvalCallbackPrefix + "reject:" + a.ID},
+		}
+		go func() {
+			if err := at.telegram.SendButtons(text, buttons); err != nil {
+				at.baseLog.Printf("⚠️  推送审批消息失败: %v", err)
+			}
+		}()
+	}
+	return a
+}
+
+// pruneApprovals 标记过期的审批，删除超过保留时长的已处理审批（需持有approvalMu）
+func (at *AutoTrader) pruneApprovals(now time.Time) {
+	for id, a := range at.approvals {
+		if a.Status == ApprovalPending && now.After(a.ExpiresAt) {
+			a.Status = ApprovalExpired
+			a.DecidedAt = a.ExpiresAt
+		}
+		if a.Status != ApprovalPending && now.Sub(a.DecidedAt) > approvalRetention {
+			delete(at.approvals, id)
+		}
+	}
+}
+
+// GetApprovals 获取审批列表（status为空表示全部，按创建时间从新到旧）
+func (at *AutoTrader) GetApprovals(status string) []Approval {
+	at.approvalMu.Lock()
+	defer at.approvalMu.Unlock()
+	at.pruneApprovals(time.Now())
+
+	result := make([]Approval, 0, len(at.approvals))
+	for _, a := range at.approvals {
+		if status == "" || a.Status == status {
+			result = append(result, *a)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.After(result[j].CreatedAt) })
+	return result
+}
+
+// HasApproval 审批是否属于该trader
+func (at *AutoTrader) HasApproval(id string) bool {
+	at.approvalMu.Lock()
+	defer at.approvalMu.Unlock()
+	_, ok := at.approvals[id]
+	return ok
+}
+
+// ResolveApproval 批准或拒绝待审批的开仓：批准后立即按当前行情执行（仍需通过所有开仓检查），by为操作人
+func (at *AutoTrader) ResolveApproval(id string, approve bool, by string) (*Approval, error) {
+	at.approvalMu.Lock()
+	at.pruneApprovals(time.Now())
+	a, ok := at.approvals[id]
+	if !ok {
+		at.approvalMu.Unlock()
+		return nil, fmt.Errorf("审批 '%s' 不存在", id)
+	}
+	if a.Status != ApprovalPending {
+		result := *a
+		at.approvalMu.Unlock()
+		return &result, fmt.Errorf("审批 '%s' 已处理（%s）", id, a.Status)
+	}
+	a.DecidedAt = time.Now()
+	a.DecidedBy = by
+	if !approve {
+		a.Status = ApprovalRejected
+		result := *a
+		at.approvalMu.Unlock()
+		at.baseLog.Printf("❌ %s 拒绝了开仓审批 #%s（%s %s）", by, id, a.Decision.Symbol, a.Decision.Action)
+		return &result, nil
+	}
+	// 先标记为已批准，防止执行期间被重复批准
+	a.Status = ApprovalApproved
+	d := a.Decision
+	at.approvalMu.Unlock()
+
+	at.baseLog.Printf("✅ %s 批准了开仓审批 #%s（%s %s），开始执行", by, id, d.Symbol, d.Action)
+	_, err := at.executeOutOfCycle(d, DecisionSourceApproval, fmt.Sprintf("人工审批 #%s（%s）", id, by), "")
+
+	at.approvalMu.Lock()
+	if err != nil {
+		a.Status = ApprovalFailed
+		a.Error = err.Error()
+	}
+	result := *a
+	at.approvalMu.Unlock()
+	return &result, nil
+}
+
+// handleApprovalCallback 处理Telegram审批按钮
+func (at *AutoTrader) handleApprovalCallback(data, user string) (string, bool) {
+	if !strings.HasPrefix(data, approvalCallbackPrefix) {
+		return "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(data, approvalCallbackPrefix), ":", 2)
+	if len(parts) != 2 || !at.HasApproval(parts[1]) {
+		return "", false
+	}
+	a, err := at.ResolveApproval(parts[1], parts[0] == "approve", user)
+	switch {
+	case err != nil:
+		return "⚠️ " + err.Error(), true
+	case a.Status == ApprovalRejected:
+		return fmt.Sprintf("❌ 已由 %s 拒绝", user), true
+	case a.Status == ApprovalFailed:
+		return fmt.Sprintf("⚠️ 已由 %s 批准，但执行失败: %s", user, a.Error), true
+	default:
+		return fmt.Sprintf("✅ 已由 %s 批准并执行", user), true
+	}
+}
@@ -1,18 +1,27 @@
 package trader
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
 	"nofx/decision"
+	"nofx/exchange"
 	"nofx/logger"
 	"nofx/market"
 	"nofx/mcp"
+	"nofx/news"
+	"nofx/notify"
 	"nofx/pool"
-	"strings"
+	"nofx/schedule"
+	"strconv"
+	"sync"
 	"time"
 )
 
+var log = logger.Module("trader")
+
 // AutoTraderConfig 自动交易配置（简化版 - AI全权决策）
 type AutoTraderConfig struct {
 	// Trader标识
@@ -21,37 +30,71 @@ type AutoTraderConfig struct {
 	AIModel string // AI模型: "qwen" 或 "deepseek"
 
 	// 交易平台选择
-	Exchange string // "binance", "hyperliquid" 或 "aster"
-
-	// 币安API配置
-	BinanceAPIKey    string
-	BinanceSecretKey string
-
-	// Hyperliquid配置
-	HyperliquidPrivateKey string
-	HyperliquidWalletAddr string
-	HyperliquidTestnet    bool
+	Exchange string // 已注册的交易所名称（见 exchange.Names）
 
-	// Aster配置
-	AsterUser       string // Aster主钱包地址
-	AsterSigner     string // Aster API钱包地址
-	AsterPrivateKey string // Aster API钱包私钥
+	// 交易所连接配置（各交易所只读取自己的字段）
+	ExchangeConfig exchange.Config
 
 	CoinPoolAPIURL string
 
+	// 候选币种来源（为空时使用AI500 + OI Top）和候选数量
+	CoinSources  []pool.SourceConfig
+	CoinPoolSize int
+
 	// AI配置
 	UseQwen     bool
 	DeepSeekKey string
 	QwenKey     string
 
+	// OpenAI配置
+	OpenAIKey     string
+	OpenAIBaseURL string
+	OpenAIModel   string
+
+	// Claude配置
+	ClaudeKey   string
+	ClaudeModel string
+
 	// 自定义AI API配置
 	CustomAPIURL    string
 	CustomAPIKey    string
 	CustomModelName string
 
+	// 流式输出AI响应（实时推送思维链）
+	AIStream bool
+
+	// 通过函数调用获取结构化决策（不支持时回退到文本解析）
+	StructuredOutput bool
+
+	// 备用AI模型链（主AI熔断或服务故障时按顺序切换，为空时不切换）
+	FallbackModels []string
+
+	// AI响应缓存时间（观察模式和模拟盘复用相同prompt的响应，0表示不缓存）
+	AICacheTTL time.Duration
+
+	// AI调用预算：当日估算成本（美元）达到上限后暂停决策周期（0表示不限制）
+	MaxDailyAICost float64
+	// 价格表中没有的模型按此价格估算成本（美元/百万token）
+	AIInputPrice  float64
+	AIOutputPrice float64
+
 	// 扫描配置
 	ScanInterval time.Duration // 扫描间隔（建议3分钟）
 
+	// 分析使用的K线周期（零值使用默认的3m + 4h）
+	Timeframes market.Timeframes
+
+	// 币种黑白名单（可通过SetSymbolFilter在运行时修改）
+	SymbolFilter pool.SymbolFilter
+
+	// 交易时段（时段外禁止开仓，可通过SetSchedule在运行时修改）
+	Schedule schedule.Schedule
+
+	// 自适应扫描间隔（空仓且市场平静时延长间隔）
+	AdaptiveInterval   bool          // 是否启用自适应扫描间隔
+	MaxScanInterval    time.Duration // 最大扫描间隔
+	QuietVolatilityPct float64       // 判定市场平静的1小时涨跌幅阈值（%）
+
 	// 账户配置
 	InitialBalance float64 // 初始金额（用于计算盈亏，需手动设置）
 
@@ -59,30 +102,228 @@ type AutoTraderConfig struct {
 	BTCETHLeverage  int // BTC和ETH的杠杆倍数
 	AltcoinLeverage int // 山寨币的杠杆倍数
 
-	// 风险控制（仅作为提示，AI可自主决定）
-	MaxDailyLoss    float64       // 最大日亏损百分比（提示）
-	MaxDrawdown     float64       // 最大回撤百分比（提示）
+	// 风险控制（日亏损或回撤超限时暂停交易，可通过SetRiskLimits在运行时修改）
+	MaxDailyLoss    float64       // 最大日亏损百分比（0表示不限制）
+	MaxDrawdown     float64       // 最大回撤百分比（0表示不限制）
 	StopTradingTime time.Duration // 触发风控后暂停时长
+
+	// 连续亏损冷却：连续亏损N笔或时间窗口内亏损达到净值X%后暂停开仓（可通过SetRiskLimits在运行时修改）
+	MaxConsecutiveLosses int           // 最大连续亏损笔数（0表示不限制）
+	LossWindowPct        float64       // 时间窗口内亏损百分比上限（0表示不限制）
+	LossWindow           time.Duration // 亏损统计的时间窗口
+	LossCooldown         time.Duration // 暂停开仓时长
+
+	// 相关性敞口限制（可通过SetRiskLimits在运行时修改）
+	MaxCorrelatedPositions int     // 同方向最多持有的高度相关仓位数（0表示不限制）
+	CorrelationThreshold   float64 // 高度相关的相关系数阈值（0表示默认0.8）
+
+	// 集中度限制，按账户净值的倍数（可通过SetRiskLimits在运行时修改）
+	MaxSymbolExposure float64 // 单币种名义价值上限（0表示不限制）
+	MaxNetExposure    float64 // 净方向名义价值上限（0表示不限制）
+
+	// 止损价与估算强平价之间的最小距离，入场价的%（可通过SetRiskLimits在运行时修改）
+	MinLiquidationBuffer float64
+
+	// 止损后同向重新开仓冷却（0表示不限制）
+	StopOutCooldown time.Duration
+
+	// 限价开仓单超时未成交自动撤单（0表示使用扫描间隔）
+	LimitOrderTTL time.Duration
+
+	// 决策策略（ai/rule/hybrid/ensemble，为空时使用ai）
+	Strategy string
+
+	// 委员会模式的投票模型和法定票数（0表示过半数）
+	EnsembleModels []string
+	EnsembleQuorum int
+
+	// 观察模式：完整运行决策周期并记录模拟成交，不向交易所下单（可通过SetDryRun在运行时切换）
+	DryRun bool
+
+	// 临时性错误（超时、时间戳偏差、限流）导致开平仓失败时的最大重试次数（0表示不重试）
+	OrderRetryAttempts int
+
+	// 每个周期写入prompt的历史交易复盘条数（0表示不写入）
+	PromptLessons int
+
+	// 每个候选币种写入prompt的历史相似形态条数（0表示不检索）
+	SimilarSetups int
+
+	// 人工审批：仓位金额不低于ApprovalThreshold（USDT）的开仓需人工确认后执行（0表示不启用）
+	ApprovalThreshold float64
+	ApprovalTimeout   time.Duration // 审批有效期（0表示默认15分钟）
+
+	// 影子trader：每个周期使用相同的候选币种，换模型/策略/prompt在模拟盘上决策（为nil表示不启用）
+	Shadow *ShadowConfig
+
+	// Telegram通知（为空则不推送）
+	TelegramBotToken string
+	TelegramChatID   string
+
+	// 出站Webhook（为空则不推送）
+	WebhookURL    string
+	WebhookSecret string
 }
 
+// aiFailureAlertThreshold AI决策连续失败多少次后推送告警
+const aiFailureAlertThreshold = 3
+
 // AutoTrader 自动交易器
 type AutoTrader struct {
 	id                    string // Trader唯一标识
 	name                  string // Trader显示名称
 	aiModel               string // AI模型名称
-	exchange              string // 交易平台名称
+	exchangeName          string // 交易平台名称
 	config                AutoTraderConfig
-	trader                Trader // 使用Trader接口（支持多平台）
+	exchange              exchange.Exchange // 交易所客户端（统一接口，支持多平台）
 	mcpClient             *mcp.Client
-	decisionLogger        *logger.DecisionLogger // 决策日志记录器
+	strategy              decision.Strategy        // 决策来源（可通过API在运行时切换）
+	strategyMu            sync.RWMutex             // 保护strategy
+	strategyOpts          decision.StrategyOptions // 创建策略所需的AI客户端
+	prompts               decision.PromptTemplates // 自定义prompt模板（可通过API在运行时修改）
+	promptMu              sync.RWMutex             // 保护prompts
+	symbolFilterMu        sync.RWMutex             // 保护config.SymbolFilter
+	scheduleMu            sync.RWMutex             // 保护config.Schedule
+	scheduleNotified      bool                     // 已推送过进入非交易时段的通知
+	exposureGuard         ExposureGuard            // 跨trader组合风控（未设置时不限制）
+	decisionLogger        *logger.DecisionLogger   // 决策日志记录器
 	initialBalance        float64
+	profileMu             sync.RWMutex  // 保护name、initialBalance和config中的扫描间隔（可通过API修改）
+	intervalCh            chan struct{} // 扫描间隔被修改时通知运行循环重置计时器
+	triggerCh             chan string   // 手动触发决策周期（值为操作人）
+	triggerMu             sync.Mutex    // 保护lastTrigger
+	lastTrigger           time.Time     // 上次手动触发的时间（限频）
 	dailyPnL              float64
+	dayStartEquity        float64       // 当日起始净值（用于计算日亏损）
+	peakEquity            float64       // 净值高点（用于计算回撤）
+	riskMu                sync.RWMutex  // 保护config中的风控参数和紧急锁定状态（可通过API修改）
+	locked                bool          // 紧急锁定（panic后禁止开仓，需手动解锁）
+	lossStreak            int           // 当前连续亏损笔数（riskMu保护）
+	recentTrades          []tradeResult // 最近已平仓交易的盈亏（riskMu保护，用于时间窗口内亏损统计）
+	entryCooldownUntil    time.Time     // 连续亏损冷却截止时间（riskMu保护）
+	entryCooldownReason   string        // 连续亏损冷却原因
 	lastResetTime         time.Time
 	stopUntil             time.Time
 	isRunning             bool
-	startTime             time.Time        // 系统启动时间
-	callCount             int              // AI调用次数
-	positionFirstSeenTime map[string]int64 // 持仓首次出现时间 (symbol_side -> timestamp毫秒)
+	cancel                context.CancelFunc // Stop时调用，通知主循环退出并中止正在进行的行情和AI请求
+	doneCh                chan struct{}      // Run完全退出（当前周期和监控协程都已结束）时关闭
+	runMu                 sync.Mutex         // 保护isRunning和cancel（支持通过API启停）
+	startTime             time.Time          // 系统启动时间
+	cycleMu               sync.RWMutex       // 保护callCount和currentInterval（决策周期中修改，状态接口和其他goroutine读取）
+	callCount             int                // AI调用次数
+	positionFirstSeenTime map[string]int64   // 持仓首次出现时间 (symbol_side -> timestamp毫秒)
+	currentInterval       time.Duration      // 当前扫描间隔（自适应模式下会动态调整）
+	lastCycleQuiet        bool               // 上个周期是否为空仓且市场平静
+	cycleRegime           string             // 本周期的市场状态（记录到开仓交易）
+
+	shadow *AutoTrader // 影子trader（只在模拟盘执行，未配置时为nil）
+
+	execMu           sync.Mutex               // 串行执行决策（AI决策周期与人工审批通过的开仓）
+	approvals        map[string]*Approval     // 审批ID -> 待审批/已处理的开仓
+	approvalMu       sync.Mutex               // 保护approvals
+	sharedCandidates []decision.CandidateCoin // 作为影子trader时，本周期沿用主trader的候选币种
+
+	// 止损检测：上个周期的持仓快照，持仓在未经AI平仓的情况下消失且处于亏损，视为被止损
+	lastPositions map[string]decision.PositionInfo // symbol_side -> 上次看到的持仓
+	stopOutTimes  map[string]time.Time             // symbol_side -> 最近一次被止损的时间
+
+	trailingStops map[string]*TrailingStopState // symbol_side -> 移动止损状态
+	protection    map[string]protectiveOrders   // symbol_side -> 当前止损止盈价（部分平仓后按剩余数量重新挂单）
+	trailingMu    sync.Mutex                    // 保护trailingStops和protection（监控循环与决策周期并发访问）
+	protectMu     sync.Mutex                    // 串行化交易所上止损止盈单的撤销重挂（避免保护检查在撤销后、重挂前误判为缺失）
+
+	pendingOrders map[string]*PendingOrder // 订单ID -> 未成交的限价开仓单
+	pendingMu     sync.Mutex               // 保护pendingOrders
+
+	orderRetries map[string]*OrderRetry // 幂等键 -> 因临时性错误等待重试的决策
+	retryMu      sync.Mutex             // 保护orderRetries
+
+	telegram            *notify.TelegramNotifier // Telegram推送（未配置时为nil）
+	webhook             *notify.WebhookNotifier  // 出站Webhook（未配置时为nil）
+	aiFailureCount      int                      // AI决策连续失败次数
+	riskPauseNotifiedAt time.Time                // 已推送过的风控暂停截止时间（避免每个周期重复推送）
+
+	aiUsageMu        sync.Mutex // 保护以下AI成本字段（委员会模式下多个客户端并发回调）
+	aiCostDay        string     // aiCostToday对应的日期（YYYY-MM-DD，为空表示尚未从数据库加载）
+	aiCostToday      float64    // 当日AI估算成本（美元）
+	aiBudgetNotified string     // 已推送过预算耗尽通知的日期
+
+	benchmarkMu sync.Mutex      // 保护benchmark
+	benchmark   *benchmarkStart // 买入持有基准的起始价格（首次查询时加载）
+
+	baseLog *logger.Logger // 带trader_id字段的日志
+	log     *logger.Logger // 带trader_id和cycle字段的日志（cycle在写日志时取当前周期编号，创建后不再替换）
+}
+
+// NewAIClient 根据配置创建AI客户端（自动交易和回测共用）
+func NewAIClient(config AutoTraderConfig) *mcp.Client {
+	tlog := log.With("trader_id", config.ID)
+	mcpClient := mcp.New()
+
+	if config.AIModel == "custom" {
+		// 使用自定义API
+		mcpClient.SetCustomAPI(config.CustomAPIURL, config.CustomAPIKey, config.CustomModelName)
+		tlog.Printf("🤖 [%s] 使用自定义AI API: %s (模型: %s)", config.Name, config.CustomAPIURL, config.CustomModelName)
+	} else if config.AIModel == "openai" {
+		// 使用OpenAI
+		mcpClient.SetOpenAIAPIKey(config.OpenAIKey, config.OpenAIBaseURL, config.OpenAIModel)
+		tlog.Printf("🤖 [%s] 使用OpenAI: %s (模型: %s)", config.Name, mcpClient.BaseURL, mcpClient.Model)
+	} else if config.AIModel == "claude" {
+		// 使用Anthropic Claude（原生messages接口）
+		mcpClient.SetClaudeAPIKey(config.ClaudeKey, config.ClaudeModel)
+		tlog.Printf("🤖 [%s] 使用Anthropic Claude (模型: %s)", config.Name, mcpClient.Model)
+	} else if config.UseQwen || config.AIModel == "qwen" {
+		// 使用Qwen
+		mcpClient.SetQwenAPIKey(config.QwenKey, "")
+		tlog.Printf("🤖 [%s] 使用阿里云Qwen AI", config.Name)
+	} else {
+		// 默认使用DeepSeek
+		mcpClient.SetDeepSeekAPIKey(config.DeepSeekKey)
+		tlog.Printf("🤖 [%s] 使用DeepSeek AI", config.Name)
+	}
+	mcpClient.Stream = config.AIStream
+	mcpClient.StructuredOutput = config.StructuredOutput
+	mcpClient.CacheTTL = config.AICacheTTL
+
+	for _, model := range config.FallbackModels {
+		if model == config.AIModel {
+			continue // 影子trader或重放替换了主模型时跳过与之相同的备用模型
+		}
+		fallbackConfig := config
+		fallbackConfig.AIModel = model
+		fallbackConfig.UseQwen = model == "qwen"
+		fallbackConfig.FallbackModels = nil
+		mcpClient.Fallbacks = append(mcpClient.Fallbacks, NewAIClient(fallbackConfig))
+	}
+
+	return mcpClient
+}
+
+// newEnsembleMembers 为委员会模式的每个模型创建AI客户端（密钥沿用trader配置）
+func newEnsembleMembers(config AutoTraderConfig) []decision.EnsembleMember {
+	members := make([]decision.EnsembleMember, 0, len(config.EnsembleModels))
+	for _, model := range config.EnsembleModels {
+		memberConfig := config
+		memberConfig.AIModel = model
+		memberConfig.UseQwen = model == "qwen"
+		memberConfig.FallbackModels = nil // 委员会成员不切换模型，避免同一模型重复投票
+		members = append(members, decision.EnsembleMember{Name: model, Client: NewAIClient(memberConfig)})
+	}
+	return members
+}
+
+// newExchange 根据配置创建对应交易平台的客户端
+func newExchange(config AutoTraderConfig) (exchange.Exchange, error) {
+	driver, ok := exchange.Lookup(config.Exchange)
+	if !ok {
+		return nil, fmt.Errorf("不支持的交易平台: %s", config.Exchange)
+	}
+	log.With("trader_id", config.ID).Printf("🏦 [%s] 使用%s交易", config.Name, driver.Description)
+
+	cfg := config.ExchangeConfig
+	cfg.TraderID = config.ID
+	cfg.InitialBalance = config.InitialBalance
+	return exchange.New(config.Exchange, cfg)
 }
 
 // NewAutoTrader 创建自动交易器
@@ -102,21 +343,16 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 		}
 	}
 
-	mcpClient := mcp.New()
-
 	// 初始化AI
-	if config.AIModel == "custom" {
-		// 使用自定义API
-		mcpClient.SetCustomAPI(config.CustomAPIURL, config.CustomAPIKey, config.CustomModelName)
-		log.Printf("🤖 [%s] 使用自定义AI API: %s (模型: %s)", config.Name, config.CustomAPIURL, config.CustomModelName)
-	} else if config.UseQwen || config.AIModel == "qwen" {
-		// 使用Qwen
-		mcpClient.SetQwenAPIKey(config.QwenKey, "")
-		log.Printf("🤖 [%s] 使用阿里云Qwen AI", config.Name)
-	} else {
-		// 默认使用DeepSeek
-		mcpClient.SetDeepSeekAPIKey(config.DeepSeekKey)
-		log.Printf("🤖 [%s] 使用DeepSeek AI", config.Name)
+	mcpClient := NewAIClient(config)
+	strategyOpts := decision.StrategyOptions{
+		MCPClient:      mcpClient,
+		EnsembleModels: newEnsembleMembers(config),
+		EnsembleQuorum: config.EnsembleQuorum,
+	}
+	strategy, err := decision.NewStrategy(config.Strategy, strategyOpts)
+	if err != nil {
+		return nil, err
 	}
 
 	// 初始化币种池API
@@ -130,27 +366,9 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 	}
 
 	// 根据配置创建对应的交易器
-	var trader Trader
-	var err error
-
-	switch config.Exchange {
-	case "binance":
-		log.Printf("🏦 [%s] 使用币安合约交易", config.Name)
-		trader = NewFuturesTrader(config.BinanceAPIKey, config.BinanceSecretKey)
-	case "hyperliquid":
-		log.Printf("🏦 [%s] 使用Hyperliquid交易", config.Name)
-		trader, err = NewHyperliquidTrader(config.HyperliquidPrivateKey, config.HyperliquidWalletAddr, config.HyperliquidTestnet)
-		if err != nil {
-			return nil, fmt.Errorf("初始化Hyperliquid交易器失败: %w", err)
-		}
-	case "aster":
-		log.Printf("🏦 [%s] 使用Aster交易", config.Name)
-		trader, err = NewAsterTrader(config.AsterUser, config.AsterSigner, config.AsterPrivateKey)
-		if err != nil {
-			return nil, fmt.Errorf("初始化Aster交易器失败: %w", err)
-		}
-	default:
-		return nil, fmt.Errorf("不支持的交易平台: %s", config.Exchange)
+	client, err := newExchange(config)
+	if err != nil {
+		return nil, err
 	}
 
 	// 验证初始金额配置
@@ -158,91 +376,309 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 		return nil, fmt.Errorf("初始金额必须大于0，请在配置中设置InitialBalance")
 	}
 
-	// 初始化决策日志记录器（使用trader ID创建独立目录）
-	logDir := fmt.Sprintf("decision_logs/%s", config.ID)
-	decisionLogger := logger.NewDecisionLogger(logDir)
+	// 初始化决策日志记录器（按trader ID存储到数据库）
+	decisionLogger, err := logger.NewDecisionLogger(config.ID)
+	if err != nil {
+		return nil, fmt.Errorf("初始化决策日志失败: %w", err)
+	}
+	// 兼容旧版本：导入 decision_logs/<trader_id>/ 下的JSON记录
+	if _, err := decisionLogger.ImportJSONDir(fmt.Sprintf("decision_logs/%s", config.ID)); err != nil {
+		log.Printf("⚠️  导入历史决策记录失败: %v", err)
+	}
 
-	return &AutoTrader{
+	baseLog := log.With("trader_id", config.ID)
+
+	at := &AutoTrader{
 		id:                    config.ID,
 		name:                  config.Name,
 		aiModel:               config.AIModel,
-		exchange:              config.Exchange,
+		exchangeName:          config.Exchange,
 		config:                config,
-		trader:                trader,
+		exchange:              client,
 		mcpClient:             mcpClient,
+		strategy:              strategy,
+		strategyOpts:          strategyOpts,
 		decisionLogger:        decisionLogger,
 		initialBalance:        config.InitialBalance,
+		peakEquity:            config.InitialBalance,
 		lastResetTime:         time.Now(),
 		startTime:             time.Now(),
 		callCount:             0,
 		isRunning:             false,
 		positionFirstSeenTime: make(map[string]int64),
-	}, nil
+		currentInterval:       config.ScanInterval,
+		intervalCh:            make(chan struct{}, 1),
+		triggerCh:             make(chan string, 1),
+		lastPositions:         make(map[string]decision.PositionInfo),
+		trailingStops:         make(map[string]*TrailingStopState),
+		protection:            make(map[string]protectiveOrders),
+		pendingOrders:         make(map[string]*PendingOrder),
+		stopOutTimes:          make(map[string]time.Time),
+		approvals:             make(map[string]*Approval),
+		orderRetries:          make(map[string]*OrderRetry),
+		telegram:              notify.NewTelegramNotifier(config.TelegramBotToken, config.TelegramChatID),
+		webhook:               notify.NewWebhookNotifier(config.WebhookURL, config.WebhookSecret),
+		baseLog:               baseLog,
+	}
+	at.log = baseLog.With("cycle", cycleLogValue{at})
+	at.trackAIUsage()
+
+	if config.Shadow != nil {
+		if at.shadow, err = newShadow(config); err != nil {
+			return nil, err
+		}
+	}
+	return at, nil
 }
 
-// Run 运行自动交易主循环
+// Run 运行自动交易主循环（阻塞直到Stop）
 func (at *AutoTrader) Run() error {
+	at.runMu.Lock()
+	if at.isRunning {
+		at.runMu.Unlock()
+		return fmt.Errorf("trader '%s' 已在运行", at.GetName())
+	}
 	at.isRunning = true
-	log.Println("🚀 AI驱动自动交易系统启动")
-	log.Printf("💰 初始余额: %.2f USDT", at.initialBalance)
-	log.Printf("⚙️  扫描间隔: %v", at.config.ScanInterval)
-	log.Println("🤖 AI将全权决定杠杆、仓位大小、止损止盈等参数")
-
-	ticker := time.NewTicker(at.config.ScanInterval)
+	runCtx, cancel := context.WithCancel(context.Background())
+	at.cancel = cancel
+	at.doneCh = make(chan struct{})
+	stopCh, doneCh := runCtx.Done(), at.doneCh
+	at.runMu.Unlock()
+	var monitors sync.WaitGroup
+	defer func() {
+		monitors.Wait()
+		close(doneCh)
+	}()
+	// 审批按钮只在运行期间处理，停止或删除trader后释放Telegram长轮询
+	if at.config.ApprovalThreshold > 0 {
+		at.telegram.HandleCallbacks(at.handleApprovalCallback)
+		defer at.telegram.Stop()
+	}
+
+	at.log.Println("🚀 AI驱动自动交易系统启动")
+	scanInterval, maxScanInterval := at.scanIntervals()
+	at.log.Printf("💰 初始余额: %.2f USDT", at.getInitialBalance())
+	at.log.Printf("⚙️  扫描间隔: %v", scanInterval)
+	if at.config.AdaptiveInterval {
+		at.log.Printf("⚙️  自适应扫描间隔已启用: 空仓且市场平静时最长延长至 %v", maxScanInterval)
+	}
+	at.log.Println("🤖 AI将全权决定杠杆、仓位大小、止损止盈等参数")
+
+	at.setCurrentInterval(scanInterval)
+	ticker := time.NewTicker(scanInterval)
 	defer ticker.Stop()
 
+	// 移动止损独立于AI决策周期运行，更及时地跟随价格
+	monitors.Add(3)
+	go func() {
+		defer monitors.Done()
+		at.runTrailingStops(stopCh)
+	}()
+	go func() {
+		defer monitors.Done()
+		at.runPendingOrders(stopCh)
+	}()
+	go func() {
+		defer monitors.Done()
+		at.runOrderRetries(stopCh)
+	}()
+	if shadow := at.shadow; shadow != nil {
+		monitors.Add(2)
+		go func() {
+			defer monitors.Done()
+			shadow.runTrailingStops(stopCh)
+		}()
+		go func() {
+			defer monitors.Done()
+			shadow.runPendingOrders(stopCh)
+		}()
+		shadow.reconcile("启动")
+	}
+
+	// 以交易所实际持仓和挂单为准同步内部状态（清理重启前遗留的孤立挂单）
+	at.reconcile("启动")
+
 	// 首次立即执行
-	if err := at.runCycle(); err != nil {
-		log.Printf("❌ 执行失败: %v", err)
+	if err := at.runCycle(runCtx); err != nil {
+		at.log.Printf("❌ 执行失败: %v", err)
 	}
+	at.adjustScanInterval(ticker)
 
-	for at.isRunning {
+	for {
 		select {
+		case <-stopCh:
+			return nil
 		case <-ticker.C:
-			if err := at.runCycle(); err != nil {
-				log.Printf("❌ 执行失败: %v", err)
+			if err := at.runCycle(runCtx); err != nil {
+				at.log.Printf("❌ 执行失败: %v", err)
+			}
+			at.adjustScanInterval(ticker)
+		case <-at.intervalCh:
+			// 通过API修改了扫描间隔，从现在开始按新间隔计时
+			next, _ := at.scanIntervals()
+			at.log.Printf("⏱️  [%s] 扫描间隔修改: %v → %v", at.GetName(), at.currentInterval, next)
+			at.setCurrentInterval(next)
+			ticker.Reset(next)
+		case by := <-at.triggerCh:
+			at.log.Printf("⚡ %s 手动触发决策周期", by)
+			if err := at.runCycle(runCtx); err != nil {
+				at.log.Printf("❌ 执行失败: %v", err)
 			}
+			ticker.Reset(at.currentInterval)
+			at.adjustScanInterval(ticker)
 		}
 	}
-
-	return nil
 }
 
-// Stop 停止自动交易
+// Stop 停止自动交易：立即中止正在进行的行情和AI请求，已开始提交的订单会先执行完（保证交易所状态已知）
 func (at *AutoTrader) Stop() {
+	at.runMu.Lock()
+	defer at.runMu.Unlock()
+	if !at.isRunning {
+		return
+	}
 	at.isRunning = false
-	log.Println("⏹ 自动交易系统停止")
+	at.cancel()
+	at.log.Println("⏹ 自动交易系统停止")
+}
+
+// IsRunning 是否正在运行
+func (at *AutoTrader) IsRunning() bool {
+	at.runMu.Lock()
+	defer at.runMu.Unlock()
+	return at.isRunning
+}
+
+// adjustScanInterval 根据上个周期的持仓和市场波动调整扫描间隔（仅自适应模式）
+// 空仓且市场平静时间隔翻倍（不超过上限），有持仓或波动放大时立即恢复基础间隔
+func (at *AutoTrader) adjustScanInterval(ticker *time.Ticker) {
+	if !at.config.AdaptiveInterval {
+		return
+	}
+
+	next, maxInterval := at.scanIntervals()
+	reason := "有持仓或市场波动放大，恢复基础间隔"
+	if at.lastCycleQuiet {
+		next = at.currentInterval * 2
+		if next > maxInterval {
+			next = maxInterval
+		}
+		reason = "空仓且市场平静，延长扫描间隔"
+	}
+
+	if next == at.currentInterval {
+		return
+	}
+
+	at.log.Printf("⏱️  [%s] 扫描间隔调整: %v → %v（%s）", at.GetName(), at.currentInterval, next, reason)
+	at.setCurrentInterval(next)
+	ticker.Reset(next)
+}
+
+// setCurrentInterval 修改当前扫描间隔（只在Run所在goroutine中调用，该goroutine读取时不需要加锁）
+func (at *AutoTrader) setCurrentInterval(d time.Duration) {
+	at.cycleMu.Lock()
+	at.currentInterval = d
+	at.cycleMu.Unlock()
+}
+
+// getCurrentInterval 当前扫描间隔（可在任意goroutine中调用）
+func (at *AutoTrader) getCurrentInterval() time.Duration {
+	at.cycleMu.RLock()
+	defer at.cycleMu.RUnlock()
+	return at.currentInterval
+}
+
+// cycleNumber 当前周期编号（可在任意goroutine中调用）
+func (at *AutoTrader) cycleNumber() int {
+	at.cycleMu.RLock()
+	defer at.cycleMu.RUnlock()
+	return at.callCount
+}
+
+// cycleLogValue 日志中的cycle字段：写日志时才读取周期编号，at.log不需要在每个周期替换
+type cycleLogValue struct{ at *AutoTrader }
+
+// LogValue 实现slog.LogValuer
+func (v cycleLogValue) LogValue() slog.Value {
+	return slog.IntValue(v.at.cycleNumber())
 }
 
-// runCycle 运行一个交易周期（使用AI全权决策）
-func (at *AutoTrader) runCycle() error {
+// isMarketQuiet 判断市场是否平静（所有已获取数据币种的1小时涨跌幅都低于阈值）
+func isMarketQuiet(dataMap map[string]*market.Data, thresholdPct float64) bool {
+	if len(dataMap) == 0 {
+		return false
+	}
+	for _, data := range dataMap {
+		if math.Abs(data.PriceChange1h) >= thresholdPct {
+			return false
+		}
+	}
+	return true
+}
+
+// runCycle 运行一个交易周期（使用AI全权决策，runCtx取消时中止行情和AI请求并跳过剩余决策）
+func (at *AutoTrader) runCycle(runCtx context.Context) error {
+	at.cycleMu.Lock()
 	at.callCount++
+	cycle := at.callCount
+	at.cycleMu.Unlock()
+	at.lastCycleQuiet = false
 
-	log.Printf("\n" + strings.Repeat("=", 70))
-	log.Printf("⏰ %s - AI决策周期 #%d", time.Now().Format("2006-01-02 15:04:05"), at.callCount)
-	log.Printf(strings.Repeat("=", 70))
+	at.log.Printf("⏰ %s - AI决策周期 #%d", time.Now().Format("2006-01-02 15:04:05"), cycle)
+	defer at.saveRuntimeState()
 
 	// 创建决策记录
 	record := &logger.DecisionRecord{
 		ExecutionLog: []string{},
 		Success:      true,
+		DryRun:       at.IsDryRun(),
 	}
 
 	// 1. 检查是否需要停止交易
+	if at.IsLocked() {
+		at.log.Println("🔒 Trader已紧急锁定，跳过本周期（需手动解锁）")
+		// 紧急平仓的持仓不算作止损
+		at.lastPositions = make(map[string]decision.PositionInfo)
+		record.Success = false
+		record.ErrorMessage = "Trader已紧急锁定"
+		at.decisionLogger.LogDecision(record)
+		return nil
+	}
 	if time.Now().Before(at.stopUntil) {
 		remaining := at.stopUntil.Sub(time.Now())
-		log.Printf("⏸ 风险控制：暂停交易中，剩余 %.0f 分钟", remaining.Minutes())
+		at.log.Printf("⏸ 风险控制：暂停交易中，剩余 %.0f 分钟", remaining.Minutes())
+		if !at.riskPauseNotifiedAt.Equal(at.stopUntil) {
+			at.riskPauseNotifiedAt = at.stopUntil
+			at.notify("⏸ 风控触发，暂停交易至 %s", at.stopUntil.Format("2006-01-02 15:04"))
+		}
 		record.Success = false
 		record.ErrorMessage = fmt.Sprintf("风险控制暂停中，剩余 %.0f 分钟", remaining.Minutes())
 		at.decisionLogger.LogDecision(record)
 		return nil
 	}
+	if cost, exceeded := at.aiBudgetExceeded(); exceeded {
+		at.log.Printf("💸 今日AI成本 $%.2f 已达上限 $%.2f，跳过本周期（次日恢复）", cost, at.config.MaxDailyAICost)
+		at.notifyAIBudgetExceeded(cost)
+		record.Success = false
+		record.ErrorMessage = fmt.Sprintf("今日AI成本 $%.2f 已达上限 $%.2f", cost, at.config.MaxDailyAICost)
+		at.decisionLogger.LogDecision(record)
+		return nil
+	}
+	if err := at.checkSchedule(); err != nil {
+		at.log.Printf("🕒 %v，空仓跳过本周期", err)
+		record.Success = false
+		record.ErrorMessage = err.Error()
+		at.decisionLogger.LogDecision(record)
+		return nil
+	}
 
 	// 2. 重置日盈亏（每天重置）
 	if time.Since(at.lastResetTime) > 24*time.Hour {
 		at.dailyPnL = 0
+		at.dayStartEquity = 0
 		at.lastResetTime = time.Now()
-		log.Println("📅 日盈亏已重置")
+		at.log.Println("📅 日盈亏已重置")
 	}
 
 	// 3. 收集交易上下文
@@ -253,6 +689,15 @@ func (at *AutoTrader) runCycle() error {
 		at.decisionLogger.LogDecision(record)
 		return fmt.Errorf("构建交易上下文失败: %w", err)
 	}
+	ctx.Ctx = runCtx
+	ctx.ShareResponses = record.DryRun || at.config.Exchange == "paper" // 不向真实交易所下单时才复用其他trader的AI响应
+
+	// 核对交易所上的止损止盈单：补挂缺失的，撤销已没有持仓的（观察模式不操作交易所）
+	if !record.DryRun {
+		for _, fix := range at.checkProtection() {
+			record.ExecutionLog = append(record.ExecutionLog, "🛡 "+fix)
+		}
+	}
 
 	// 保存账户状态快照
 	record.AccountState = logger.AccountSnapshot{
@@ -282,12 +727,54 @@ func (at *AutoTrader) runCycle() error {
 		record.CandidateCoins = append(record.CandidateCoins, coin.Symbol)
 	}
 
-	log.Printf("📊 账户净值: %.2f USDT | 可用: %.2f USDT | 持仓: %d",
+	// 记录本周期的市场状态（开仓交易也会记录，用于按市场状态分析各模型的表现）
+	at.cycleRegime = ""
+	if ctx.Regime != nil {
+		at.cycleRegime = ctx.Regime.Label
+	}
+	record.Regime = at.cycleRegime
+	if ctx.FearGreed != nil {
+		record.FearGreed = ctx.FearGreed.Value
+	}
+
+	at.log.Printf("📊 账户净值: %.2f USDT | 可用: %.2f USDT | 持仓: %d",
 		ctx.Account.TotalEquity, ctx.Account.AvailableBalance, ctx.Account.PositionCount)
 
-	// 4. 调用AI获取完整决策
-	log.Println("🤖 正在请求AI分析并决策...")
-	decision, err := decision.GetFullDecision(ctx, at.mcpClient)
+	// 推送账户和持仓快照
+	at.publishEvent(EventAccount, ctx.Account)
+	at.publishEvent(EventPositions, ctx.Positions)
+
+	// 影子trader使用本周期相同的候选币种在模拟盘上并行决策（本周期结束前等待其完成）
+	waitShadow := at.startShadowCycle(ctx)
+	defer waitShadow()
+
+	// 检查日亏损和回撤是否超限
+	if reason := at.checkRiskLimits(ctx.Account.TotalEquity); reason != "" {
+		at.log.Printf("⚠️ 风险控制触发: %s，暂停交易至 %s", reason, at.stopUntil.Format("15:04"))
+		at.riskPauseNotifiedAt = at.stopUntil
+		at.notify("⏸ 风控触发（%s），暂停交易至 %s", reason, at.stopUntil.Format("2006-01-02 15:04"))
+		record.Success = false
+		record.ErrorMessage = fmt.Sprintf("风险控制触发: %s", reason)
+		at.decisionLogger.LogDecision(record)
+		return nil
+	}
+
+	// 4. 调用策略获取完整决策
+	strategy := at.GetStrategy()
+	if record.DryRun {
+		at.log.Println("👁 观察模式：本周期的决策只模拟成交，不下单")
+	}
+	at.log.Printf("🤖 正在请求决策（策略: %s）...", strategy.Name())
+	var stream *cotStream
+	if at.mcpClient.Stream {
+		stream = at.newCoTStream()
+		ctx.OnStream = stream.write
+	}
+	decision, err := strategy.Decide(ctx)
+	if stream != nil {
+		stream.close()
+	}
+	record.Context = at.snapshotContext(ctx) // 策略已填充行情数据，保存本周期决策的完整依据
 
 	// 即使有错误，也保存思维链、决策和输入prompt（用于debug）
 	if decision != nil {
@@ -299,52 +786,66 @@ func (at *AutoTrader) runCycle() error {
 		}
 	}
 
+	if err != nil && runCtx.Err() != nil {
+		// trader已停止，请求被主动中止，不算作AI失败
+		at.log.Println("⏹ Trader已停止，本周期的决策请求已取消")
+		record.Success = false
+		record.ErrorMessage = "Trader已停止，决策请求已取消"
+		at.decisionLogger.LogDecision(record)
+		return nil
+	}
 	if err != nil {
 		record.Success = false
 		record.ErrorMessage = fmt.Sprintf("获取AI决策失败: %v", err)
 
+		at.aiFailureCount++
+		if at.aiFailureCount == aiFailureAlertThreshold {
+			at.notify("🤖❌ AI决策已连续失败 %d 次，最近错误: %v", at.aiFailureCount, err)
+		}
+
 		// 打印AI思维链（即使有错误）
 		if decision != nil && decision.CoTTrace != "" {
-			log.Printf("\n" + strings.Repeat("-", 70))
-			log.Println("💭 AI思维链分析（错误情况）:")
-			log.Println(strings.Repeat("-", 70))
-			log.Println(decision.CoTTrace)
-			log.Printf(strings.Repeat("-", 70) + "\n")
+			at.log.With("cot", decision.CoTTrace).Println("💭 AI思维链分析（错误情况）")
 		}
 
 		at.decisionLogger.LogDecision(record)
 		return fmt.Errorf("获取AI决策失败: %w", err)
 	}
 
+	at.aiFailureCount = 0
+
 	// 5. 打印AI思维链
-	log.Printf("\n" + strings.Repeat("-", 70))
-	log.Println("💭 AI思维链分析:")
-	log.Println(strings.Repeat("-", 70))
-	log.Println(decision.CoTTrace)
-	log.Printf(strings.Repeat("-", 70) + "\n")
+	at.log.With("cot", decision.CoTTrace).Println("💭 AI思维链分析")
 
 	// 6. 打印AI决策
-	log.Printf("📋 AI决策列表 (%d 个):\n", len(decision.Decisions))
+	at.log.Printf("📋 AI决策列表 (%d 个):", len(decision.Decisions))
 	for i, d := range decision.Decisions {
-		log.Printf("  [%d] %s: %s - %s", i+1, d.Symbol, d.Action, d.Reasoning)
+		at.log.Printf("  [%d] %s: %s - %s", i+1, d.Symbol, d.Action, d.Reasoning)
 		if d.Action == "open_long" || d.Action == "open_short" {
-			log.Printf("      杠杆: %dx | 仓位: %.2f USDT | 止损: %.4f | 止盈: %.4f",
+			at.log.Printf("      杠杆: %dx | 仓位: %.2f USDT | 止损: %.4f | 止盈: %.4f",
 				d.Leverage, d.PositionSizeUSD, d.StopLoss, d.TakeProfit)
 		}
 	}
-	log.Println()
 
 	// 7. 对决策排序：确保先平仓后开仓（防止仓位叠加超限）
 	sortedDecisions := sortDecisionsByPriority(decision.Decisions)
 
-	log.Println("🔄 执行顺序（已优化）: 先平仓→后开仓")
+	at.log.Println("🔄 执行顺序（已优化）: 先平仓→后开仓")
 	for i, d := range sortedDecisions {
-		log.Printf("  [%d] %s %s", i+1, d.Symbol, d.Action)
+		at.log.Printf("  [%d] %s %s", i+1, d.Symbol, d.Action)
 	}
-	log.Println()
 
 	// 执行决策并记录结果
+	openedPosition := false
+	orderFailed := false
+	at.execMu.Lock()
 	for _, d := range sortedDecisions {
+		// trader已停止：不再提交新的订单（已提交的订单不中止，避免不确定是否成交）
+		if runCtx.Err() != nil {
+			at.log.Println("⏹ Trader已停止，跳过剩余决策")
+			record.ExecutionLog = append(record.ExecutionLog, "⏹ Trader已停止，跳过剩余决策")
+			break
+		}
 		actionRecord := logger.DecisionAction{
 			Action:    d.Action,
 			Symbol:    d.Symbol,
@@ -353,87 +854,207 @@ func (at *AutoTrader) runCycle() error {
 			Price:     0,
 			Timestamp: time.Now(),
 			Success:   false,
+			DryRun:    record.DryRun,
+		}
+		// 决策时AI看到的价格，用于计算成交滑点
+		if data, ok := ctx.MarketDataMap[d.Symbol]; ok {
+			actionRecord.DecisionPrice = data.CurrentPrice
+		}
+
+		// 超过审批阈值的开仓交给人工确认（观察模式下照常模拟成交）
+		if !record.DryRun && at.needsApproval(&d) {
+			a := at.queueApproval(d, actionRecord.DecisionPrice)
+			actionRecord.Error = fmt.Sprintf("等待人工审批 #%s", a.ID)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⏳ %s %s 等待人工审批 #%s", d.Symbol, d.Action, a.ID))
+			record.Decisions = append(record.Decisions, actionRecord)
+			continue
 		}
 
+		if d.Action != "hold" && d.Action != "wait" {
+			at.dropOrderRetries(d.Symbol)
+		}
+		key := at.decisionKey(&d, actionRecord.Timestamp)
+		setClientOrderID(&actionRecord, key)
 		if err := at.executeDecisionWithRecord(&d, &actionRecord); err != nil {
-			log.Printf("❌ 执行决策失败 (%s %s): %v", d.Symbol, d.Action, err)
+			at.log.Printf("❌ 执行决策失败 (%s %s): %v", d.Symbol, d.Action, err)
+			if at.isInStopOutCooldown(d.Symbol, d.Action) {
+				at.notify("🧊 %s %s 被止损冷却拦截: %v", d.Symbol, d.Action, err)
+			}
 			actionRecord.Error = err.Error()
 			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", d.Symbol, d.Action, err))
+			if !record.DryRun && at.queueOrderRetry(d, key, err, ctx.Positions) {
+				record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🔁 %s %s 临时性错误，已加入重试队列", d.Symbol, d.Action))
+			}
+			if d.Action != "hold" && d.Action != "wait" && !record.DryRun {
+				orderFailed = true
+			}
+		} else if record.DryRun {
+			actionRecord.Success = true
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("👁 %s %s 模拟成交（观察模式，未下单）", d.Symbol, d.Action))
 		} else {
 			actionRecord.Success = true
+			if d.Action == "open_long" || d.Action == "open_short" {
+				openedPosition = true
+			}
 			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功", d.Symbol, d.Action))
 			// 成功执行后短暂延迟
 			time.Sleep(1 * time.Second)
 		}
 
 		record.Decisions = append(record.Decisions, actionRecord)
+		at.sendDecisionWebhook(d, actionRecord)
+	}
+	at.execMu.Unlock()
+
+	// 下单失败后订单可能已部分生效（如超时但已成交），重新对账
+	if orderFailed {
+		at.reconcile("下单失败")
+	}
+
+	// 记录本周期是否空仓且市场平静（用于自适应扫描间隔）
+	if at.config.AdaptiveInterval {
+		at.lastCycleQuiet = len(ctx.Positions) == 0 && !openedPosition &&
+			isMarketQuiet(ctx.MarketDataMap, at.config.QuietVolatilityPct)
 	}
 
 	// 8. 保存决策记录
 	if err := at.decisionLogger.LogDecision(record); err != nil {
-		log.Printf("⚠ 保存决策记录失败: %v", err)
+		at.log.Printf("⚠ 保存决策记录失败: %v", err)
 	}
+	at.publishEvent(EventDecision, record)
 
 	return nil
 }
 
+// notify 推送Telegram通知（未配置时忽略，异步发送不阻塞交易）
+func (at *AutoTrader) notify(format string, args ...interface{}) {
+	if at.telegram == nil {
+		return
+	}
+	at.telegram.SendAsync(fmt.Sprintf("[%s] ", at.GetName()) + fmt.Sprintf(format, args...))
+}
+
+// DecisionWebhookPayload 出站Webhook负载（每个执行的决策一条）
+type DecisionWebhookPayload struct {
+	TraderID   string                `json:"trader_id"`
+	TraderName string                `json:"trader_name"`
+	Exchange   string                `json:"exchange"`
+	AIModel    string                `json:"ai_model"`
+	Cycle      int                   `json:"cycle"`
+	Decision   decision.Decision     `json:"decision"`  // AI原始决策（含理由、止损止盈）
+	Execution  logger.DecisionAction `json:"execution"` // 执行结果（数量、价格、订单ID、错误）
+}
+
+// sendDecisionWebhook 推送已执行决策到出站Webhook（hold/wait不涉及执行，不推送）
+func (at *AutoTrader) sendDecisionWebhook(d decision.Decision, actionRecord logger.DecisionAction) {
+	if at.webhook == nil || d.Action == "hold" || d.Action == "wait" {
+		return
+	}
+	at.webhook.SendAsync("decision.executed", DecisionWebhookPayload{
+		TraderID:   at.id,
+		TraderName: at.GetName(),
+		Exchange:   at.exchangeName,
+		AIModel:    at.aiModel,
+		Cycle:      at.cycleNumber(),
+		Decision:   d,
+		Execution:  actionRecord,
+	})
+}
+
+// publishEvent 向事件中心推送本trader的实时事件
+func (at *AutoTrader) publishEvent(eventType string, data interface{}) {
+	Events().Publish(Event{
+		Type:     eventType,
+		TraderID: at.id,
+		Data:     data,
+	})
+}
+
+// candidateCoins 获取合并的候选币种池（AI500 + OI Top，去重）
+func (at *AutoTrader) candidateCoins() ([]decision.CandidateCoin, error) {
+	// 无论有没有持仓，都分析相同数量的币种（让AI看到所有好机会）
+	// AI会根据保证金使用率和现有持仓情况，自己决定是否要换仓
+	const ai500Limit = 20 // AI500取前20个评分最高的币种
+
+	// 获取合并后的币种池（配置了coin_sources时按权重组合，否则为AI500 + OI Top），按黑白名单筛选
+	var mergedPool *pool.MergedCoinPool
+	var err error
+	if len(at.config.CoinSources) > 0 {
+		mergedPool, err = pool.GetWeightedCoinPool(at.config.CoinSources, at.config.CoinPoolSize)
+	} else {
+		mergedPool, err = pool.GetMergedCoinPool(ai500Limit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取合并币种池失败: %w", err)
+	}
+	mergedPool = mergedPool.Filter(at.GetSymbolFilter())
+
+	// 构建候选币种列表（包含来源信息）
+	var candidateCoins []decision.CandidateCoin
+	for _, symbol := range mergedPool.AllSymbols {
+		sources := mergedPool.SymbolSources[symbol]
+		candidateCoins = append(candidateCoins, decision.CandidateCoin{
+			Symbol:  symbol,
+			Sources: sources, // "ai500" 和/或 "oi_top"（或coin_sources中的来源名称）
+		})
+	}
+
+	if len(at.config.CoinSources) > 0 {
+		at.log.Printf("📋 合并币种池: %d个来源 = 总计%d个候选币种", len(at.config.CoinSources), len(candidateCoins))
+	} else {
+		at.log.Printf("📋 合并币种池: AI500前%d + OI_Top20 = 总计%d个候选币种",
+			ai500Limit, len(candidateCoins))
+	}
+	return candidateCoins, nil
+}
+
 // buildTradingContext 构建交易上下文
 func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 	// 1. 获取账户信息
-	balance, err := at.trader.GetBalance()
+	account, err := at.exchange.GetAccount()
 	if err != nil {
 		return nil, fmt.Errorf("获取账户余额失败: %w", err)
 	}
 
 	// 获取账户字段
-	totalWalletBalance := 0.0
-	totalUnrealizedProfit := 0.0
-	availableBalance := 0.0
-
-	if wallet, ok := balance["totalWalletBalance"].(float64); ok {
-		totalWalletBalance = wallet
-	}
-	if unrealized, ok := balance["totalUnrealizedProfit"].(float64); ok {
-		totalUnrealizedProfit = unrealized
-	}
-	if avail, ok := balance["availableBalance"].(float64); ok {
-		availableBalance = avail
-	}
+	totalWalletBalance := account.WalletBalance
+	totalUnrealizedProfit := account.UnrealizedProfit
+	availableBalance := account.AvailableBalance
 
 	// Total Equity = 钱包余额 + 未实现盈亏
 	totalEquity := totalWalletBalance + totalUnrealizedProfit
 
 	// 2. 获取持仓信息
-	positions, err := at.trader.GetPositions()
+	positions, err := at.exchange.GetPositions()
 	if err != nil {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
 
 	var positionInfos []decision.PositionInfo
 	totalMarginUsed := 0.0
+	theses := at.positionTheses()
 
 	// 当前持仓的key集合（用于清理已平仓的记录）
 	currentPositionKeys := make(map[string]bool)
+	currentPositions := make(map[string]decision.PositionInfo)
 
 	for _, pos := range positions {
-		symbol := pos["symbol"].(string)
-		side := pos["side"].(string)
-		entryPrice := pos["entryPrice"].(float64)
-		markPrice := pos["markPrice"].(float64)
-		quantity := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity // 空仓数量为负，转为正数
-		}
-		unrealizedPnl := pos["unRealizedProfit"].(float64)
-		liquidationPrice := pos["liquidationPrice"].(float64)
+		symbol := pos.Symbol
+		side := pos.Side
+		entryPrice := pos.EntryPrice
+		markPrice := pos.MarkPrice
+		quantity := pos.Quantity
+		unrealizedPnl := pos.UnrealizedProfit
+		liquidationPrice := pos.LiquidationPrice
 
 		// 计算占用保证金（估算）
-		leverage := 10 // 默认值，实际应该从持仓信息获取
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
+		leverage := 10 // 默认值，交易所未返回杠杆时使用
+		if pos.Leverage > 0 {
+			leverage = int(pos.Leverage)
 		}
 		marginUsed := (quantity * markPrice) / float64(leverage)
 		totalMarginUsed += marginUsed
+		marginMode := pos.MarginMode
 
 		// 计算盈亏百分比
 		pnlPct := 0.0
@@ -452,7 +1073,7 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		}
 		updateTime := at.positionFirstSeenTime[posKey]
 
-		positionInfos = append(positionInfos, decision.PositionInfo{
+		posInfo := decision.PositionInfo{
 			Symbol:           symbol,
 			Side:             side,
 			EntryPrice:       entryPrice,
@@ -463,8 +1084,12 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 			UnrealizedPnLPct: pnlPct,
 			LiquidationPrice: liquidationPrice,
 			MarginUsed:       marginUsed,
+			MarginMode:       marginMode,
 			UpdateTime:       updateTime,
-		})
+		}
+		posInfo.Thesis = theses[posKey]
+		positionInfos = append(positionInfos, posInfo)
+		currentPositions[posKey] = posInfo
 	}
 
 	// 清理已平仓的持仓记录
@@ -474,35 +1099,23 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		}
 	}
 
-	// 3. 获取合并的候选币种池（AI500 + OI Top，去重）
-	// 无论有没有持仓，都分析相同数量的币种（让AI看到所有好机会）
-	// AI会根据保证金使用率和现有持仓情况，自己决定是否要换仓
-	const ai500Limit = 20 // AI500取前20个评分最高的币种
-
-	// 获取合并后的币种池（AI500 + OI Top）
-	mergedPool, err := pool.GetMergedCoinPool(ai500Limit)
-	if err != nil {
-		return nil, fmt.Errorf("获取合并币种池失败: %w", err)
-	}
+	// 检测被交易所止损的持仓（AI主动平仓时会提前从lastPositions中移除）
+	at.detectStopOuts(currentPositions)
 
-	// 构建候选币种列表（包含来源信息）
-	var candidateCoins []decision.CandidateCoin
-	for _, symbol := range mergedPool.AllSymbols {
-		sources := mergedPool.SymbolSources[symbol]
-		candidateCoins = append(candidateCoins, decision.CandidateCoin{
-			Symbol:  symbol,
-			Sources: sources, // "ai500" 和/或 "oi_top"
-		})
+	// 3. 获取候选币种（影子trader沿用主trader本周期的候选币种）
+	candidateCoins := at.sharedCandidates
+	if candidateCoins == nil {
+		if candidateCoins, err = at.candidateCoins(); err != nil {
+			return nil, err
+		}
 	}
 
-	log.Printf("📋 合并币种池: AI500前%d + OI_Top20 = 总计%d个候选币种",
-		ai500Limit, len(candidateCoins))
-
 	// 4. 计算总盈亏
-	totalPnL := totalEquity - at.initialBalance
+	initialBalance := at.getInitialBalance()
+	totalPnL := totalEquity - initialBalance
 	totalPnLPct := 0.0
-	if at.initialBalance > 0 {
-		totalPnLPct = (totalPnL / at.initialBalance) * 100
+	if initialBalance > 0 {
+		totalPnLPct = (totalPnL / initialBalance) * 100
 	}
 
 	marginUsedPct := 0.0
@@ -514,18 +1127,40 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 	// 假设每3分钟一个周期，100个周期 = 5小时，足够覆盖大部分交易
 	performance, err := at.decisionLogger.AnalyzePerformance(100)
 	if err != nil {
-		log.Printf("⚠️  分析历史表现失败: %v", err)
+		at.log.Printf("⚠️  分析历史表现失败: %v", err)
 		// 不影响主流程，继续执行（但设置performance为nil以避免传递错误数据）
 		performance = nil
 	}
 
+	// 市场状态和恐惧贪婪指数（获取失败不影响主流程）
+	regime, err := market.GetRegime()
+	if err != nil {
+		at.log.Printf("⚠️  判断市场状态失败: %v", err)
+	}
+	fearGreed, err := market.GetFearGreed()
+	if err != nil {
+		at.log.Printf("⚠️  获取恐惧贪婪指数失败: %v", err)
+	}
+
 	// 6. 构建上下文
 	ctx := &decision.Context{
-		CurrentTime:     time.Now().Format("2006-01-02 15:04:05"),
-		RuntimeMinutes:  int(time.Since(at.startTime).Minutes()),
-		CallCount:       at.callCount,
-		BTCETHLeverage:  at.config.BTCETHLeverage,  // 使用配置的杠杆倍数
-		AltcoinLeverage: at.config.AltcoinLeverage, // 使用配置的杠杆倍数
+		CurrentTime:      time.Now().Format("2006-01-02 15:04:05"),
+		RuntimeMinutes:   int(time.Since(at.startTime).Minutes()),
+		CallCount:        at.cycleNumber(),
+		BTCETHLeverage:   at.config.BTCETHLeverage,  // 使用配置的杠杆倍数
+		AltcoinLeverage:  at.config.AltcoinLeverage, // 使用配置的杠杆倍数
+		Timeframes:       at.config.Timeframes,
+		SymbolFilter:     at.GetSymbolFilter(),
+		RiskNotices:      at.riskNotices(),
+		Slippage:         at.slippageWarnings(),
+		Calibration:      at.calibrationSummary(),
+		Lessons:          at.lessonSummary(positionInfos, candidateCoins, regime),
+		SetupMemory:      at.setupMemory(),
+		CorrelationLimit: at.correlationLimit(),
+		News:             news.PromptLines(time.Now()),
+		Regime:           regime,
+		FearGreed:        fearGreed,
+		Spot:             at.IsSpot(),
 		Account: decision.AccountInfo{
 			TotalEquity:      totalEquity,
 			AvailableBalance: availableBalance,
@@ -539,12 +1174,46 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		CandidateCoins: candidateCoins,
 		Performance:    performance, // 添加历史表现分析
 	}
+	if prompts := at.GetPromptTemplates(); prompts.System != "" || prompts.User != "" {
+		ctx.Prompts = &prompts
+	}
+	if ft, ok := at.exchange.(exchange.FundingRateProvider); ok {
+		rates, err := ft.GetFundingRates()
+		if err != nil {
+			at.log.Printf("⚠️  获取交易所资金费率失败，使用币安资金费率: %v", err)
+		} else {
+			ctx.FundingRates = rates
+		}
+	}
 
 	return ctx, nil
 }
 
 // executeDecisionWithRecord 执行AI决策并记录详细信息
 func (at *AutoTrader) executeDecisionWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
+	// 紧急锁定期间禁止开仓（防止panic时正在运行的周期继续开仓）
+	if (decision.Action == "open_long" || decision.Action == "open_short") && at.IsLocked() {
+		return fmt.Errorf("trader已紧急锁定，禁止开仓")
+	}
+	if decision.Action == "open_long" || decision.Action == "open_short" {
+		if reason, closed := at.scheduleClosed(); closed {
+			return fmt.Errorf("非交易时段，禁止开仓: %s", reason)
+		}
+		if reason, active := at.entryCooldown(); active {
+			return fmt.Errorf("亏损冷却中，禁止开仓: %s", reason)
+		}
+		if reason, active := news.Blackout(time.Now()); active {
+			return fmt.Errorf("重大事件前后禁止开仓: %s", reason)
+		}
+	}
+	if decision.Action == "open_short" && at.IsSpot() {
+		return fmt.Errorf("❌ 现货交易不支持做空: %s", decision.Symbol)
+	}
+	// 观察模式：只模拟成交，不下单
+	if actionRecord.DryRun {
+		return at.simulateDecision(decision, actionRecord)
+	}
+
 	switch decision.Action {
 	case "open_long":
 		return at.executeOpenLongWithRecord(decision, actionRecord)
@@ -562,161 +1231,464 @@ func (at *AutoTrader) executeDecisionWithRecord(decision *decision.Decision, act
 	}
 }
 
+// detectStopOuts 对比上个周期的持仓快照，找出未经AI平仓就消失且处于亏损的持仓（视为止损）
+func (at *AutoTrader) detectStopOuts(currentPositions map[string]decision.PositionInfo) {
+	for key, lastPos := range at.lastPositions {
+		if _, stillOpen := currentPositions[key]; stillOpen {
+			continue
+		}
+		at.recordTradeResult(lastPos.Symbol, lastPos.Side, lastPos.UnrealizedPnL)
+		at.recordTriggeredExit(lastPos)
+		if lastPos.UnrealizedPnL < 0 {
+			at.stopOutTimes[key] = time.Now()
+			at.log.Printf("🛑 检测到 %s %s 已被止损（最后浮亏 %.2f USDT）", lastPos.Symbol, lastPos.Side, lastPos.UnrealizedPnL)
+			at.notify("🛑 %s %s 触发止损（最后浮亏 %.2f USDT）", lastPos.Symbol, lastPos.Side, lastPos.UnrealizedPnL)
+		} else {
+			at.log.Printf("🎯 检测到 %s %s 已止盈（最后浮盈 %.2f USDT）", lastPos.Symbol, lastPos.Side, lastPos.UnrealizedPnL)
+			at.notify("🎯 %s %s 触发止盈（最后浮盈 %.2f USDT）", lastPos.Symbol, lastPos.Side, lastPos.UnrealizedPnL)
+		}
+	}
+	at.lastPositions = currentPositions
+
+	// 清理已过冷却期的止损记录
+	for key, t := range at.stopOutTimes {
+		if time.Since(t) > at.config.StopOutCooldown {
+			delete(at.stopOutTimes, key)
+		}
+	}
+}
+
+// checkStopOutCooldown 检查币种同方向是否处于止损后的冷却期
+func (at *AutoTrader) checkStopOutCooldown(symbol, side string) error {
+	if at.config.StopOutCooldown <= 0 {
+		return nil
+	}
+	stoppedAt, exists := at.stopOutTimes[symbol+"_"+side]
+	if !exists {
+		return nil
+	}
+	elapsed := time.Since(stoppedAt)
+	if elapsed >= at.config.StopOutCooldown {
+		return nil
+	}
+	return fmt.Errorf("❌ %s %s 在 %.0f 分钟前刚被止损，原交易逻辑已失效，冷却期内禁止同向重新开仓（剩余 %.0f 分钟）",
+		symbol, side, elapsed.Minutes(), (at.config.StopOutCooldown - elapsed).Minutes())
+}
+
+// checkPriceLevels 开仓前用最新价格和买卖价差校验止损止盈，止损超出强平价或离强平价过近时自动降低杠杆
+func (at *AutoTrader) checkPriceLevels(d *decision.Decision) error {
+	marketData, err := market.GetWithTimeframes(d.Symbol, at.config.Timeframes)
+	if err != nil {
+		return err
+	}
+	spread := 0.0
+	if orderBook, err := market.GetOrderBook(d.Symbol); err == nil {
+		spread = orderBook.BestAsk - orderBook.BestBid
+	}
+	adjustment, err := decision.CheckPriceLevels(d, marketData.CurrentPrice, spread, at.liquidationRules(d))
+	if err != nil {
+		return fmt.Errorf("❌ %s 止损止盈价位无效: %w", d.Symbol, err)
+	}
+	if adjustment != "" {
+		at.log.Printf("  🔧 %s %s", d.Symbol, adjustment)
+	}
+	return nil
+}
+
+// liquidationRules 估算强平价的参数：维持保证金按仓位名义价值所在的交易所杠杆分层（交易所未提供时使用默认值），
+// 止损与强平价的最小距离来自风控参数
+func (at *AutoTrader) liquidationRules(d *decision.Decision) decision.LiquidationRules {
+	rules := decision.LiquidationRules{MinBufferPct: at.GetRiskLimits().MinLiquidationBuffer}
+	if filters, err := at.exchange.GetSymbolFilters(d.Symbol); err == nil && filters != nil {
+		rules.MaintMarginRate, rules.MaintAmount = filters.Maintenance(d.PositionSizeUSD)
+	}
+	return rules
+}
+
+// isInStopOutCooldown 判断开仓决策是否因止损冷却被拦截（用于风控通知）
+func (at *AutoTrader) isInStopOutCooldown(symbol, action string) bool {
+	side := ""
+	switch action {
+	case "open_long":
+		side = "long"
+	case "open_short":
+		side = "short"
+	default:
+		return false
+	}
+	return at.checkStopOutCooldown(symbol, side) != nil
+}
+
+// placeStopLoss 为持仓挂止损单
+func (at *AutoTrader) placeStopLoss(symbol, side string, quantity, price float64) error {
+	_, err := at.exchange.PlaceOrder(exchange.OrderRequest{
+		Symbol:   symbol,
+		Side:     side,
+		Type:     exchange.OrderTypeStopLoss,
+		Quantity: quantity,
+		Price:    price,
+	})
+	return err
+}
+
+// placeTakeProfit 为持仓挂止盈单
+func (at *AutoTrader) placeTakeProfit(symbol, side string, quantity, price float64) error {
+	_, err := at.exchange.PlaceOrder(exchange.OrderRequest{
+		Symbol:   symbol,
+		Side:     side,
+		Type:     exchange.OrderTypeTakeProfit,
+		Quantity: quantity,
+		Price:    price,
+	})
+	return err
+}
+
+// recordOrderID 记录交易所订单ID（非数字ID的交易所不记录）
+func recordOrderID(actionRecord *logger.DecisionAction, order *exchange.Order) {
+	if id, err := strconv.ParseInt(order.OrderID, 10, 64); err == nil {
+		actionRecord.OrderID = id
+	}
+}
+
 // executeOpenLongWithRecord 执行开多仓并记录详细信息
 func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
-	log.Printf("  📈 开多仓: %s", decision.Symbol)
+	at.log.Printf("  📈 开多仓: %s", decision.Symbol)
 
-	// ⚠️ 关键：检查是否已有同币种同方向持仓，如果有则拒绝开仓（防止仓位叠加超限）
-	positions, err := at.trader.GetPositions()
+	// 止损后同向冷却：刚被止损的方向不允许立即重新开仓（防止报复性交易）
+	if err := at.checkStopOutCooldown(decision.Symbol, "long"); err != nil {
+		return err
+	}
+	if at.hasPendingOrder(decision.Symbol, "long") {
+		return fmt.Errorf("❌ %s 已有未成交的开多限价单，拒绝重复开仓", decision.Symbol)
+	}
+
+	// ⚠️ 关键：检查是否已有同币种同方向持仓，如果有则拒绝开仓（防止仓位叠加超限），明确加仓时除外
+	existingQty := 0.0
+	positions, err := at.exchange.GetPositions()
 	if err == nil {
 		for _, pos := range positions {
-			if pos["symbol"] == decision.Symbol && pos["side"] == "long" {
-				return fmt.Errorf("❌ %s 已有多仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_long 决策", decision.Symbol)
+			if pos.Symbol == decision.Symbol && pos.Side == "long" {
+				if !decision.AddToPosition {
+					return fmt.Errorf("❌ %s 已有多仓，拒绝开仓以防止仓位叠加超限。如需加仓请设置 add_to_position，如需换仓请先给出 close_long 决策", decision.Symbol)
+				}
+				existingQty = pos.Quantity
 			}
 		}
 	}
+	if decision.AddToPosition && existingQty == 0 {
+		return fmt.Errorf("❌ %s 没有多仓，无法加仓", decision.Symbol)
+	}
+
+	// 用最新价格校验止损止盈价位（方向、距离、强平价）
+	if err := at.checkPriceLevels(decision); err != nil {
+		return err
+	}
+
+	// 保证金模式（逐仓/全仓）
+	if err := at.applyMarginMode(decision); err != nil {
+		return err
+	}
+
+	// 限价/post_only开仓：挂单后由限价单监控在成交时设置止损止盈
+	if decision.OrderType == "limit" || decision.OrderType == "post_only" {
+		if _, ok := at.exchange.(exchange.OrderTracker); ok {
+			return at.placeLimitOpen(decision, actionRecord, "long")
+		}
+		at.log.Printf("  ⚠ %s 不支持限价单，改用市价单开仓", at.exchangeName)
+	}
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := market.GetWithTimeframes(decision.Symbol, at.config.Timeframes)
 	if err != nil {
 		return err
 	}
 
-	// 计算数量
-	quantity := decision.PositionSizeUSD / marketData.CurrentPrice
+	// 计算数量（按交易所步长取整，校验最小名义价值、杠杆分层和可用保证金）
+	quantity, err := at.checkFeasibility(decision, marketData.CurrentPrice)
+	if err != nil {
+		return err
+	}
+	if err := at.checkConcentration(decision, "long", quantity, marketData.CurrentPrice); err != nil {
+		return err
+	}
+	release, err := at.reserveExposure(decision, quantity, marketData.CurrentPrice)
+	if err != nil {
+		return err
+	}
+	defer release()
 	actionRecord.Quantity = quantity
 	actionRecord.Price = marketData.CurrentPrice
+	actionRecord.Leverage = decision.Leverage // 强平距离保护和杠杆分层可能已降低杠杆
 
 	// 开仓
-	order, err := at.trader.OpenLong(decision.Symbol, quantity, decision.Leverage)
+	order, err := at.placeOpenOrder(exchange.OrderRequest{
+		Symbol:        decision.Symbol,
+		Side:          exchange.SideLong,
+		Type:          exchange.OrderTypeMarket,
+		Quantity:      quantity,
+		Leverage:      decision.Leverage,
+		ClientOrderID: actionRecord.ClientOrderID,
+	})
 	if err != nil {
 		return err
 	}
 
-	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
+	// 记录订单ID、成交价和手续费
+	recordOrderID(actionRecord, order)
+	at.recordFill(actionRecord, order)
+	at.recordFee(actionRecord, order, quantity, actionRecord.Price)
 
-	log.Printf("  ✓ 开仓成功，订单ID: %v, 数量: %.4f", order["orderId"], quantity)
+	at.log.Printf("  ✓ 开仓成功，订单ID: %s, 数量: %.4f", order.OrderID, quantity)
+	at.recordTradeEntry(decision, "long", quantity, actionRecord.Price, actionRecord.Fee, marketData)
+
+	// 加仓：保留原开仓时间，止损止盈按合并后的数量重新挂单
+	if existingQty > 0 {
+		at.notify("📈 %s 加多 %dx | 加仓数量: %.4f @ %.4f | 合计: %.4f | 止损: %.4f | 止盈: %.4f\n理由: %s",
+			decision.Symbol, decision.Leverage, quantity, marketData.CurrentPrice, existingQty+quantity, decision.StopLoss, decision.TakeProfit, decision.Reasoning)
+		at.replaceProtection(decision.Symbol, "long", existingQty+quantity, decision)
+		at.registerTrailingStop(decision, "long", marketData.CurrentPrice, marketData)
+		return nil
+	}
 
 	// 记录开仓时间
 	posKey := decision.Symbol + "_long"
 	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
 
+	at.notify("📈 %s 开多 %dx | 数量: %.4f @ %.4f | 止损: %.4f | 止盈: %.4f\n理由: %s",
+		decision.Symbol, decision.Leverage, quantity, marketData.CurrentPrice, decision.StopLoss, decision.TakeProfit, decision.Reasoning)
+
 	// 设置止损止盈
-	if err := at.trader.SetStopLoss(decision.Symbol, "LONG", quantity, decision.StopLoss); err != nil {
-		log.Printf("  ⚠ 设置止损失败: %v", err)
+	if err := at.placeStopLoss(decision.Symbol, "long", quantity, decision.StopLoss); err != nil {
+		at.log.Printf("  ⚠ 设置止损失败: %v", err)
 	}
-	if err := at.trader.SetTakeProfit(decision.Symbol, "LONG", quantity, decision.TakeProfit); err != nil {
-		log.Printf("  ⚠ 设置止盈失败: %v", err)
+	if err := at.placeTakeProfit(decision.Symbol, "long", quantity, decision.TakeProfit); err != nil {
+		at.log.Printf("  ⚠ 设置止盈失败: %v", err)
 	}
+	at.setProtection(decision.Symbol, "long", decision.StopLoss, decision.TakeProfit)
+	at.registerTrailingStop(decision, "long", marketData.CurrentPrice, marketData)
 
 	return nil
 }
 
 // executeOpenShortWithRecord 执行开空仓并记录详细信息
 func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
-	log.Printf("  📉 开空仓: %s", decision.Symbol)
+	at.log.Printf("  📉 开空仓: %s", decision.Symbol)
+
+	// 止损后同向冷却：刚被止损的方向不允许立即重新开仓（防止报复性交易）
+	if err := at.checkStopOutCooldown(decision.Symbol, "short"); err != nil {
+		return err
+	}
+	if at.hasPendingOrder(decision.Symbol, "short") {
+		return fmt.Errorf("❌ %s 已有未成交的开空限价单，拒绝重复开仓", decision.Symbol)
+	}
 
-	// ⚠️ 关键：检查是否已有同币种同方向持仓，如果有则拒绝开仓（防止仓位叠加超限）
-	positions, err := at.trader.GetPositions()
+	// ⚠️ 关键：检查是否已有同币种同方向持仓，如果有则拒绝开仓（防止仓位叠加超限），明确加仓时除外
+	existingQty := 0.0
+	positions, err := at.exchange.GetPositions()
 	if err == nil {
 		for _, pos := range positions {
-			if pos["symbol"] == decision.Symbol && pos["side"] == "short" {
-				return fmt.Errorf("❌ %s 已有空仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_short 决策", decision.Symbol)
+			if pos.Symbol == decision.Symbol && pos.Side == "short" {
+				if !decision.AddToPosition {
+					return fmt.Errorf("❌ %s 已有空仓，拒绝开仓以防止仓位叠加超限。如需加仓请设置 add_to_position，如需换仓请先给出 close_short 决策", decision.Symbol)
+				}
+				existingQty = pos.Quantity
 			}
 		}
 	}
+	if decision.AddToPosition && existingQty == 0 {
+		return fmt.Errorf("❌ %s 没有空仓，无法加仓", decision.Symbol)
+	}
+
+	// 用最新价格校验止损止盈价位（方向、距离、强平价）
+	if err := at.checkPriceLevels(decision); err != nil {
+		return err
+	}
+
+	// 保证金模式（逐仓/全仓）
+	if err := at.applyMarginMode(decision); err != nil {
+		return err
+	}
+
+	// 限价/post_only开仓：挂单后由限价单监控在成交时设置止损止盈
+	if decision.OrderType == "limit" || decision.OrderType == "post_only" {
+		if _, ok := at.exchange.(exchange.OrderTracker); ok {
+			return at.placeLimitOpen(decision, actionRecord, "short")
+		}
+		at.log.Printf("  ⚠ %s 不支持限价单，改用市价单开仓", at.exchangeName)
+	}
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := market.GetWithTimeframes(decision.Symbol, at.config.Timeframes)
 	if err != nil {
 		return err
 	}
 
-	// 计算数量
-	quantity := decision.PositionSizeUSD / marketData.CurrentPrice
+	// 计算数量（按交易所步长取整，校验最小名义价值、杠杆分层和可用保证金）
+	quantity, err := at.checkFeasibility(decision, marketData.CurrentPrice)
+	if err != nil {
+		return err
+	}
+	if err := at.checkConcentration(decision, "short", quantity, marketData.CurrentPrice); err != nil {
+		return err
+	}
+	release, err := at.reserveExposure(decision, quantity, marketData.CurrentPrice)
+	if err != nil {
+		return err
+	}
+	defer release()
 	actionRecord.Quantity = quantity
 	actionRecord.Price = marketData.CurrentPrice
+	actionRecord.Leverage = decision.Leverage // 强平距离保护和杠杆分层可能已降低杠杆
 
 	// 开仓
-	order, err := at.trader.OpenShort(decision.Symbol, quantity, decision.Leverage)
+	order, err := at.placeOpenOrder(exchange.OrderRequest{
+		Symbol:        decision.Symbol,
+		Side:          exchange.SideShort,
+		Type:          exchange.OrderTypeMarket,
+		Quantity:      quantity,
+		Leverage:      decision.Leverage,
+		ClientOrderID: actionRecord.ClientOrderID,
+	})
 	if err != nil {
 		return err
 	}
 
-	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
+	// 记录订单ID、成交价和手续费
+	recordOrderID(actionRecord, order)
+	at.recordFill(actionRecord, order)
+	at.recordFee(actionRecord, order, quantity, actionRecord.Price)
+
+	at.log.Printf("  ✓ 开仓成功，订单ID: %s, 数量: %.4f", order.OrderID, quantity)
+	at.recordTradeEntry(decision, "short", quantity, actionRecord.Price, actionRecord.Fee, marketData)
 
-	log.Printf("  ✓ 开仓成功，订单ID: %v, 数量: %.4f", order["orderId"], quantity)
+	// 加仓：保留原开仓时间，止损止盈按合并后的数量重新挂单
+	if existingQty > 0 {
+		at.notify("📉 %s 加空 %dx | 加仓数量: %.4f @ %.4f | 合计: %.4f | 止损: %.4f | 止盈: %.4f\n理由: %s",
+			decision.Symbol, decision.Leverage, quantity, marketData.CurrentPrice, existingQty+quantity, decision.StopLoss, decision.TakeProfit, decision.Reasoning)
+		at.replaceProtection(decision.Symbol, "short", existingQty+quantity, decision)
+		at.registerTrailingStop(decision, "short", marketData.CurrentPrice, marketData)
+		return nil
+	}
 
 	// 记录开仓时间
 	posKey := decision.Symbol + "_short"
 	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
 
+	at.notify("📉 %s 开空 %dx | 数量: %.4f @ %.4f | 止损: %.4f | 止盈: %.4f\n理由: %s",
+		decision.Symbol, decision.Leverage, quantity, marketData.CurrentPrice, decision.StopLoss, decision.TakeProfit, decision.Reasoning)
+
 	// 设置止损止盈
-	if err := at.trader.SetStopLoss(decision.Symbol, "SHORT", quantity, decision.StopLoss); err != nil {
-		log.Printf("  ⚠ 设置止损失败: %v", err)
+	if err := at.placeStopLoss(decision.Symbol, "short", quantity, decision.StopLoss); err != nil {
+		at.log.Printf("  ⚠ 设置止损失败: %v", err)
 	}
-	if err := at.trader.SetTakeProfit(decision.Symbol, "SHORT", quantity, decision.TakeProfit); err != nil {
-		log.Printf("  ⚠ 设置止盈失败: %v", err)
+	if err := at.placeTakeProfit(decision.Symbol, "short", quantity, decision.TakeProfit); err != nil {
+		at.log.Printf("  ⚠ 设置止盈失败: %v", err)
 	}
+	at.setProtection(decision.Symbol, "short", decision.StopLoss, decision.TakeProfit)
+	at.registerTrailingStop(decision, "short", marketData.CurrentPrice, marketData)
 
 	return nil
 }
 
 // executeCloseLongWithRecord 执行平多仓并记录详细信息
 func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
-	log.Printf("  🔄 平多仓: %s", decision.Symbol)
+	at.log.Printf("  🔄 平多仓: %s", decision.Symbol)
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := market.GetWithTimeframes(decision.Symbol, at.config.Timeframes)
 	if err != nil {
 		return err
 	}
 	actionRecord.Price = marketData.CurrentPrice
 
-	// 平仓
-	order, err := at.trader.CloseLong(decision.Symbol, 0) // 0 = 全部平仓
+	// 部分平仓：按比例计算平仓数量
+	if decision.CloseFraction > 0 && decision.CloseFraction < 1 {
+		return at.executePartialClose(decision, actionRecord, "long", marketData.CurrentPrice)
+	}
+
+	// 平仓（资金费需在平仓前查询）
+	at.recordFunding(actionRecord, decision.Symbol, "long")
+	order, err := at.exchange.ClosePosition(decision.Symbol, exchange.SideLong, 0) // 0 = 全部平仓
 	if err != nil {
 		return err
 	}
 
-	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
+	// AI主动平仓，不计入止损检测
+	lastPos, hadPos := at.lastPositions[decision.Symbol+"_long"]
+	delete(at.lastPositions, decision.Symbol+"_long")
+	at.removeTrailingStop(decision.Symbol, "long")
+	at.removeProtection(decision.Symbol, "long")
+	at.cancelProtectiveOrders(decision.Symbol, "long")
+
+	if hadPos {
+		at.recordTradeResult(decision.Symbol, "long", lastPos.UnrealizedPnL)
+		at.notify("🔄 %s 平多 @ %.4f | 平仓前浮盈: %+.2f USDT\n理由: %s", decision.Symbol, marketData.CurrentPrice, lastPos.UnrealizedPnL, decision.Reasoning)
+	} else {
+		at.notify("🔄 %s 平多 @ %.4f\n理由: %s", decision.Symbol, marketData.CurrentPrice, decision.Reasoning)
 	}
 
-	log.Printf("  ✓ 平仓成功")
+	// 记录订单ID、成交价和手续费
+	recordOrderID(actionRecord, order)
+	at.recordFill(actionRecord, order)
+	closedQty := 0.0
+	if hadPos {
+		closedQty = lastPos.Quantity
+	}
+	at.recordFee(actionRecord, order, closedQty, actionRecord.Price)
+	at.recordTradeExit(decision, "long", 0, actionRecord)
+
+	at.log.Printf("  ✓ 平仓成功")
 	return nil
 }
 
 // executeCloseShortWithRecord 执行平空仓并记录详细信息
 func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
-	log.Printf("  🔄 平空仓: %s", decision.Symbol)
+	at.log.Printf("  🔄 平空仓: %s", decision.Symbol)
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := market.GetWithTimeframes(decision.Symbol, at.config.Timeframes)
 	if err != nil {
 		return err
 	}
 	actionRecord.Price = marketData.CurrentPrice
 
-	// 平仓
-	order, err := at.trader.CloseShort(decision.Symbol, 0) // 0 = 全部平仓
+	// 部分平仓：按比例计算平仓数量
+	if decision.CloseFraction > 0 && decision.CloseFraction < 1 {
+		return at.executePartialClose(decision, actionRecord, "short", marketData.CurrentPrice)
+	}
+
+	// 平仓（资金费需在平仓前查询）
+	at.recordFunding(actionRecord, decision.Symbol, "short")
+	order, err := at.exchange.ClosePosition(decision.Symbol, exchange.SideShort, 0) // 0 = 全部平仓
 	if err != nil {
 		return err
 	}
 
-	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
+	// AI主动平仓，不计入止损检测
+	lastPos, hadPos := at.lastPositions[decision.Symbol+"_short"]
+	delete(at.lastPositions, decision.Symbol+"_short")
+	at.removeTrailingStop(decision.Symbol, "short")
+	at.removeProtection(decision.Symbol, "short")
+	at.cancelProtectiveOrders(decision.Symbol, "short")
+
+	if hadPos {
+		at.recordTradeResult(decision.Symbol, "short", lastPos.UnrealizedPnL)
+		at.notify("🔄 %s 平空 @ %.4f | 平仓前浮盈: %+.2f USDT\n理由: %s", decision.Symbol, marketData.CurrentPrice, lastPos.UnrealizedPnL, decision.Reasoning)
+	} else {
+		at.notify("🔄 %s 平空 @ %.4f\n理由: %s", decision.Symbol, marketData.CurrentPrice, decision.Reasoning)
+	}
+
+	// 记录订单ID、成交价和手续费
+	recordOrderID(actionRecord, order)
+	at.recordFill(actionRecord, order)
+	closedQty := 0.0
+	if hadPos {
+		closedQty = lastPos.Quantity
 	}
+	at.recordFee(actionRecord, order, closedQty, actionRecord.Price)
+	at.recordTradeExit(decision, "short", 0, actionRecord)
 
-	log.Printf("  ✓ 平仓成功")
+	at.log.Printf("  ✓ 平仓成功")
 	return nil
 }
 
@@ -727,9 +1699,16 @@ func (at *AutoTrader) GetID() string {
 
 // GetName 获取trader名称
 func (at *AutoTrader) GetName() string {
+	at.profileMu.RLock()
+	defer at.profileMu.RUnlock()
 	return at.name
 }
 
+// IsSpot 是否为现货trader（只做多、无杠杆）
+func (at *AutoTrader) IsSpot() bool {
+	return exchange.IsSpot(at.exchangeName)
+}
+
 // GetAIModel 获取AI模型
 func (at *AutoTrader) GetAIModel() string {
 	return at.aiModel
@@ -746,51 +1725,56 @@ func (at *AutoTrader) GetStatus() map[string]interface{} {
 	if at.config.UseQwen {
 		aiProvider = "Qwen"
 	}
+	profile := at.GetProfile()
+	scanInterval, _ := at.scanIntervals()
 
 	return map[string]interface{}{
-		"trader_id":       at.id,
-		"trader_name":     at.name,
-		"ai_model":        at.aiModel,
-		"exchange":        at.exchange,
-		"is_running":      at.isRunning,
-		"start_time":      at.startTime.Format(time.RFC3339),
-		"runtime_minutes": int(time.Since(at.startTime).Minutes()),
-		"call_count":      at.callCount,
-		"initial_balance": at.initialBalance,
-		"scan_interval":   at.config.ScanInterval.String(),
-		"stop_until":      at.stopUntil.Format(time.RFC3339),
-		"last_reset_time": at.lastResetTime.Format(time.RFC3339),
-		"ai_provider":     aiProvider,
+		"trader_id":         at.id,
+		"trader_name":       profile.Name,
+		"ai_model":          at.aiModel,
+		"exchange":          at.exchangeName,
+		"is_running":        at.IsRunning(),
+		"start_time":        at.startTime.Format(time.RFC3339),
+		"runtime_minutes":   int(time.Since(at.startTime).Minutes()),
+		"call_count":        at.cycleNumber(),
+		"initial_balance":   profile.InitialBalance,
+		"scan_interval":     scanInterval.String(),
+		"current_interval":  at.getCurrentInterval().String(),
+		"adaptive_interval": at.config.AdaptiveInterval,
+		"stop_until":        at.stopUntil.Format(time.RFC3339),
+		"last_reset_time":   at.lastResetTime.Format(time.RFC3339),
+		"risk_limits":       at.GetRiskLimits(),
+		"locked":            at.IsLocked(),
+		"dry_run":           at.IsDryRun(),
+		"has_shadow":        at.HasShadow(),
+		"pending_approvals": len(at.GetApprovals(ApprovalPending)),
+		"order_retries":     at.GetOrderRetries(),
+		"trailing_stops":    at.GetTrailingStops(),
+		"pending_orders":    at.GetPendingOrders(),
+		"strategy":          at.GetStrategy().Name(),
+		"ai_provider":       aiProvider,
+		"ai_cost_today_usd": at.aiCostTodayUSD(),
+		"max_daily_ai_cost": at.config.MaxDailyAICost,
 	}
 }
 
 // GetAccountInfo 获取账户信息（用于API）
 func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
-	balance, err := at.trader.GetBalance()
+	account, err := at.exchange.GetAccount()
 	if err != nil {
 		return nil, fmt.Errorf("获取余额失败: %w", err)
 	}
 
 	// 获取账户字段
-	totalWalletBalance := 0.0
-	totalUnrealizedProfit := 0.0
-	availableBalance := 0.0
-
-	if wallet, ok := balance["totalWalletBalance"].(float64); ok {
-		totalWalletBalance = wallet
-	}
-	if unrealized, ok := balance["totalUnrealizedProfit"].(float64); ok {
-		totalUnrealizedProfit = unrealized
-	}
-	if avail, ok := balance["availableBalance"].(float64); ok {
-		availableBalance = avail
-	}
+	totalWalletBalance := account.WalletBalance
+	totalUnrealizedProfit := account.UnrealizedProfit
+	availableBalance := account.AvailableBalance
 
 	// Total Equity = 钱包余额 + 未实现盈亏
 	totalEquity := totalWalletBalance + totalUnrealizedProfit
 
 	// 获取持仓计算总保证金
-	positions, err := at.trader.GetPositions()
+	positions, err := at.exchange.GetPositions()
 	if err != nil {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
@@ -798,26 +1782,21 @@ func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
 	totalMarginUsed := 0.0
 	totalUnrealizedPnL := 0.0
 	for _, pos := range positions {
-		markPrice := pos["markPrice"].(float64)
-		quantity := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity
-		}
-		unrealizedPnl := pos["unRealizedProfit"].(float64)
-		totalUnrealizedPnL += unrealizedPnl
+		totalUnrealizedPnL += pos.UnrealizedProfit
 
 		leverage := 10
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
+		if pos.Leverage > 0 {
+			leverage = int(pos.Leverage)
 		}
-		marginUsed := (quantity * markPrice) / float64(leverage)
+		marginUsed := (pos.Quantity * pos.MarkPrice) / float64(leverage)
 		totalMarginUsed += marginUsed
 	}
 
-	totalPnL := totalEquity - at.initialBalance
+	initialBalance := at.getInitialBalance()
+	totalPnL := totalEquity - initialBalance
 	totalPnLPct := 0.0
-	if at.initialBalance > 0 {
-		totalPnLPct = (totalPnL / at.initialBalance) * 100
+	if initialBalance > 0 {
+		totalPnLPct = (totalPnL / initialBalance) * 100
 	}
 
 	marginUsedPct := 0.0
@@ -836,7 +1815,7 @@ func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
 		"total_pnl":            totalPnL,           // 总盈亏 = equity - initial
 		"total_pnl_pct":        totalPnLPct,        // 总盈亏百分比
 		"total_unrealized_pnl": totalUnrealizedPnL, // 未实现盈亏（从持仓计算）
-		"initial_balance":      at.initialBalance,  // 初始余额
+		"initial_balance":      initialBalance,     // 初始余额
 		"daily_pnl":            at.dailyPnL,        // 日盈亏
 
 		// 持仓信息
@@ -848,27 +1827,24 @@ func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
 
 // GetPositions 获取持仓列表（用于API）
 func (at *AutoTrader) GetPositions() ([]map[string]interface{}, error) {
-	positions, err := at.trader.GetPositions()
+	positions, err := at.exchange.GetPositions()
 	if err != nil {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
 
 	var result []map[string]interface{}
 	for _, pos := range positions {
-		symbol := pos["symbol"].(string)
-		side := pos["side"].(string)
-		entryPrice := pos["entryPrice"].(float64)
-		markPrice := pos["markPrice"].(float64)
-		quantity := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity
-		}
-		unrealizedPnl := pos["unRealizedProfit"].(float64)
-		liquidationPrice := pos["liquidationPrice"].(float64)
+		symbol := pos.Symbol
+		side := pos.Side
+		entryPrice := pos.EntryPrice
+		markPrice := pos.MarkPrice
+		quantity := pos.Quantity
+		unrealizedPnl := pos.UnrealizedProfit
+		liquidationPrice := pos.LiquidationPrice
 
 		leverage := 10
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
+		if pos.Leverage > 0 {
+			leverage = int(pos.Leverage)
 		}
 
 		pnlPct := 0.0
@@ -0,0 +1,132 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/logger"
+	"nofx/market"
+	"sync"
+	"time"
+)
+
+// benchmarkSetting trader_settings中保存基准起始价格的键名
+const benchmarkSetting = "benchmark"
+
+// benchmarkSymbols 买入持有基准（第一个作为排行榜的默认基准）
+var benchmarkSymbols = []string{"BTCUSDT", "ETHUSDT"}
+
+// benchmarkPriceTTL 基准最新价格的缓存时间（排行榜每次刷新都会查询所有trader）
+const benchmarkPriceTTL = 30 * time.Second
+
+// benchmarkStart trader开始运行时的基准价格
+type benchmarkStart struct {
+	StartTime time.Time          `json:"start_time"`
+	Prices    map[string]float64 `json:"prices"`
+}
+
+// BenchmarkReturn 与买入持有基准的收益对比
+type BenchmarkReturn struct {
+	Symbol       string    `json:"symbol"`
+	StartTime    time.Time `json:"start_time"` // trader第一个净值快照的时间
+	StartPrice   float64   `json:"start_price"`
+	CurrentPrice float64   `json:"current_price"`
+	ReturnPct    float64   `json:"return_pct"` // 同期买入持有的收益率
+	AlphaPct     float64   `json:"alpha_pct"`  // trader收益率 - 基准收益率
+}
+
+// GetBenchmarks 计算trader收益率（totalPnLPct）相对BTC/ETH买入持有的超额收益（trader尚未运行过时返回nil）
+func (at *AutoTrader) GetBenchmarks(totalPnLPct float64) ([]BenchmarkReturn, error) {
+	start, err := at.loadBenchmarkStart()
+	if err != nil || start == nil {
+		return nil, err
+	}
+
+	var results []BenchmarkReturn
+	for _, symbol := range benchmarkSymbols {
+		startPrice := start.Prices[symbol]
+		if startPrice <= 0 {
+			continue
+		}
+		price, err := latestBenchmarkPrice(symbol)
+		if err != nil {
+			return nil, err
+		}
+		returnPct := (price/startPrice - 1) * 100
+		results = append(results, BenchmarkReturn{
+			Symbol:       symbol,
+			StartTime:    start.StartTime,
+			StartPrice:   startPrice,
+			CurrentPrice: price,
+			ReturnPct:    returnPct,
+			AlphaPct:     totalPnLPct - returnPct,
+		})
+	}
+	return results, nil
+}
+
+// loadBenchmarkStart 加载基准起始价格：优先读取已保存的，否则按第一个净值快照时间取历史价格并保存
+func (at *AutoTrader) loadBenchmarkStart() (*benchmarkStart, error) {
+	at.benchmarkMu.Lock()
+	defer at.benchmarkMu.Unlock()
+	if at.benchmark != nil {
+		return at.benchmark, nil
+	}
+
+	var start benchmarkStart
+	if found, err := logger.LoadTraderSetting(at.id, benchmarkSetting, &start); err != nil {
+		return nil, err
+	} else if found {
+		at.benchmark = &start
+		return at.benchmark, nil
+	}
+
+	startTime, ok, err := at.decisionLogger.FirstSnapshotTime()
+	if err != nil || !ok {
+		return nil, err
+	}
+	start = benchmarkStart{StartTime: startTime, Prices: make(map[string]float64)}
+	for _, symbol := range benchmarkSymbols {
+		klines, err := market.GetKlinesBetween(symbol, "1m", startTime, startTime.Add(time.Minute))
+		if err != nil {
+			return nil, fmt.Errorf("获取%s起始价格失败: %w", symbol, err)
+		}
+		if len(klines) == 0 {
+			return nil, fmt.Errorf("获取%s起始价格失败: 没有K线数据", symbol)
+		}
+		start.Prices[symbol] = klines[0].Open
+	}
+
+	if err := logger.SaveTraderSetting(at.id, benchmarkSetting, start); err != nil {
+		at.baseLog.Printf("⚠️  保存基准起始价格失败: %v", err)
+	}
+	at.benchmark = &start
+	return at.benchmark, nil
+}
+
+// benchmarkPrices 基准最新价格缓存（所有trader共享）
+var benchmarkPrices = struct {
+	sync.Mutex
+	prices map[string]float64
+	at     map[string]time.Time
+}{prices: make(map[string]float64), at: make(map[string]time.Time)}
+
+// latestBenchmarkPrice 基准币种的最新价格（最近一根1分钟K线的收盘价）
+func latestBenchmarkPrice(symbol string) (float64, error) {
+	benchmarkPrices.Lock()
+	defer benchmarkPrices.Unlock()
+	if time.Since(benchmarkPrices.at[symbol]) < benchmarkPriceTTL {
+		return benchmarkPrices.prices[symbol], nil
+	}
+
+	now := time.Now()
+	klines, err := market.GetKlinesBetween(symbol, "1m", now.Add(-2*time.Minute), now)
+	if err != nil {
+		return 0, fmt.Errorf("获取%s最新价格失败: %w", symbol, err)
+	}
+	if len(klines) == 0 {
+		return 0, fmt.Errorf("获取%s最新价格失败: 没有K线数据", symbol)
+	}
+	price := klines[len(klines)-1].Close
+	benchmarkPrices.prices[symbol] = price
+	benchmarkPrices.at[symbol] = now
+	return price, nil
+}
@@ -0,0 +1,39 @@
+package trader
+
+import "fmt"
+
+// calibrationMinTrades 信心度区间至少有这么多笔已平仓交易才写入prompt（样本太少的胜率没有参考意义）
+const calibrationMinTrades = 5
+
+// calibrationMinConfidence 只反馈高信心度区间（低于开仓门槛的信心度很少真正开仓）
+const calibrationMinConfidence = 70
+
+// overconfidenceGap 实际胜率比平均信心度低超过该值（百分点）时视为过度自信
+const overconfidenceGap = 20.0
+
+// calibrationSummary 当前AI模型各信心度区间的实际胜率（写入prompt，让过度自信的模型得到纠正）
+func (at *AutoTrader) calibrationSummary() []string {
+	buckets, err := at.decisionLogger.ConfidenceCalibration(nil)
+	if err != nil {
+		at.log.Printf("⚠️  查询信心度校准失败: %v", err)
+		return nil
+	}
+
+	var lines []string
+	for _, b := range buckets {
+		if b.AIModel != at.aiModel || b.MinConfidence < calibrationMinConfidence || b.Trades < calibrationMinTrades {
+			continue
+		}
+		label := fmt.Sprintf("%d-%d", b.MinConfidence, b.MaxConfidence)
+		if b.MaxConfidence >= 100 {
+			label = fmt.Sprintf("%d+", b.MinConfidence)
+		}
+		line := fmt.Sprintf("信心度%s: %d笔，实际胜率%.0f%%（平均信心度%.0f），平均净盈亏%+.2f USDT",
+			label, b.Trades, b.WinRate, b.AvgConfidence, b.AvgPnL)
+		if b.AvgConfidence-b.WinRate > overconfidenceGap {
+			line += " ⚠️ 过度自信：实际胜率远低于给出的信心度，请如实下调信心度或提高开仓标准"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
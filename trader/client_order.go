@@ -0,0 +1,72 @@
+package trader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"nofx/exchange"
+	"nofx/logger"
+	"strings"
+)
+
+// clientOrderIDPrefix 客户端订单ID前缀（便于在交易所订单列表中识别本系统的订单）
+const clientOrderIDPrefix = "nofx-"
+
+// clientOrderID 由决策幂等键生成确定性的客户端订单ID（同一决策的首次下单和重试使用相同ID，交易所会拒绝重复的ID）
+// 长度29，只含字母数字和"-"，满足各交易所的格式限制
+func clientOrderID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return clientOrderIDPrefix + hex.EncodeToString(sum[:])[:24]
+}
+
+// setClientOrderID 为开仓决策设置客户端订单ID（平仓单为只减仓，重复提交不会扩大持仓，不设置）
+func setClientOrderID(actionRecord *logger.DecisionAction, key string) {
+	if strings.HasPrefix(actionRecord.Action, "open_") {
+		actionRecord.ClientOrderID = clientOrderID(key)
+	}
+}
+
+// placeOpenOrder 提交开仓单：临时性错误（超时、连接断开）可能只是响应丢失，按客户端订单ID查询确认订单是否已提交
+func (at *AutoTrader) placeOpenOrder(req exchange.OrderRequest) (*exchange.Order, error) {
+	order, err := at.exchange.PlaceOrder(req)
+	if err == nil || req.ClientOrderID == "" || !exchange.IsTransient(err) {
+		return order, err
+	}
+	finder, ok := at.exchange.(exchange.ClientOrderFinder)
+	if !ok {
+		return nil, err
+	}
+	found, findErr := finder.FindClientOrder(req.Symbol, req.ClientOrderID)
+	if findErr != nil {
+		if !errors.Is(findErr, exchange.ErrOrderNotFound) {
+			at.log.Printf("  ⚠ 按客户端订单ID %s 查询订单失败: %v", req.ClientOrderID, findErr)
+		}
+		return nil, err
+	}
+	if found.Status == exchange.OrderStatusCanceled {
+		return nil, err
+	}
+	at.log.Printf("  ✓ 下单响应丢失（%v），按客户端订单ID %s 确认订单已提交（订单ID: %s）", err, req.ClientOrderID, found.OrderID)
+	return found, nil
+}
+
+// findClientOrder 按客户端订单ID查询订单是否已提交到交易所（已撤销/过期的订单视为未提交），ok为false表示无法确认（未设置ID、交易所不支持查询或查询失败）
+func (at *AutoTrader) findClientOrder(symbol, clientOrderID string) (found, ok bool) {
+	if clientOrderID == "" {
+		return false, false
+	}
+	finder, supported := at.exchange.(exchange.ClientOrderFinder)
+	if !supported {
+		return false, false
+	}
+	order, err := finder.FindClientOrder(symbol, clientOrderID)
+	switch {
+	case err == nil:
+		return order.Status != exchange.OrderStatusCanceled, true
+	case errors.Is(err, exchange.ErrOrderNotFound):
+		return false, true
+	default:
+		at.log.Printf("  ⚠ 按客户端订单ID %s 查询订单失败: %v", clientOrderID, err)
+		return false, false
+	}
+}
@@ -0,0 +1,70 @@
+package trader
+
+import (
+	"fmt"
+	"math"
+	"nofx/decision"
+)
+
+// checkConcentration 开仓前检查单币种敞口和净方向敞口（按净值倍数，未设置时不限制）
+// 持仓按标记价格计算名义价值，未成交的限价开仓单按挂单价格计入；与AI给出的仓位大小无关，始终在下单前执行
+func (at *AutoTrader) checkConcentration(d *decision.Decision, side string, quantity, price float64) error {
+	limits := at.GetRiskLimits()
+	if limits.MaxSymbolExposure <= 0 && limits.MaxNetExposure <= 0 {
+		return nil
+	}
+
+	account, err := at.exchange.GetAccount()
+	if err != nil {
+		return fmt.Errorf("获取账户余额失败: %w", err)
+	}
+	equity := account.WalletBalance + account.UnrealizedProfit
+	if equity <= 0 {
+		return fmt.Errorf("❌ 账户净值 %.2f USDT，无法计算敞口限制", equity)
+	}
+	positions, err := at.exchange.GetPositions()
+	if err != nil {
+		return fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	notional := quantity * price
+	symbolNotional := notional // 该币种的总名义价值（多空相加）
+	netNotional := notional    // 多头减空头的净名义价值
+	if side == "short" {
+		netNotional = -notional
+	}
+	add := func(symbol, posSide string, value float64) {
+		if symbol == d.Symbol {
+			symbolNotional += value
+		}
+		if posSide == "short" {
+			value = -value
+		}
+		netNotional += value
+	}
+	for _, pos := range positions {
+		add(pos.Symbol, pos.Side, pos.Quantity*pos.MarkPrice)
+	}
+	for _, po := range at.GetPendingOrders() {
+		add(po.Symbol, po.Side, po.Quantity*po.LimitPrice)
+	}
+
+	if limits.MaxSymbolExposure > 0 && symbolNotional > limits.MaxSymbolExposure*equity {
+		return fmt.Errorf("❌ %s 开仓后名义价值 %.2f USDT 超过单币种上限 %.1f倍净值（%.2f USDT）",
+			d.Symbol, symbolNotional, limits.MaxSymbolExposure, limits.MaxSymbolExposure*equity)
+	}
+	// 只拒绝使净敞口超限且进一步扩大的开仓，反向开仓（对冲）始终允许
+	before := netNotional + notional
+	if side == "long" {
+		before = netNotional - notional
+	}
+	if limits.MaxNetExposure > 0 && math.Abs(netNotional) > limits.MaxNetExposure*equity && math.Abs(netNotional) > math.Abs(before) {
+		direction := "多"
+		if netNotional < 0 {
+			direction = "空"
+		}
+		return fmt.Errorf("❌ %s 开仓后净%s头敞口 %.2f USDT 超过上限 %.1f倍净值（%.2f USDT）",
+			d.Symbol, direction, math.Abs(netNotional), limits.MaxNetExposure, limits.MaxNetExposure*equity)
+	}
+	return nil
+}
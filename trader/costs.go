@@ -0,0 +1,45 @@
+package trader
+
+import (
+	"nofx/exchange"
+	"nofx/logger"
+	"time"
+)
+
+// estimatedFeeRate 交易所不提供实际手续费时的估算费率（taker 0.04%）
+const estimatedFeeRate = 0.0004
+
+// recordFee 记录订单手续费：优先使用下单结果或交易所查询到的实际手续费，否则按taker费率估算
+func (at *AutoTrader) recordFee(actionRecord *logger.DecisionAction, order *exchange.Order, quantity, price float64) {
+	if order.Fee > 0 {
+		actionRecord.Fee = order.Fee
+		return
+	}
+	if cr, ok := at.exchange.(exchange.CommissionReporter); ok && order.OrderID != "" {
+		fee, err := cr.GetCommission(actionRecord.Symbol, order.OrderID)
+		if err == nil {
+			actionRecord.Fee = fee
+			return
+		}
+		at.log.Printf("  ⚠ 查询订单 %s 手续费失败，按估算费率记录: %v", order.OrderID, err)
+	}
+	actionRecord.Fee = quantity * price * estimatedFeeRate
+}
+
+// recordFunding 记录持仓期间的资金费（全部平仓前调用；部分平仓不记录，剩余资金费在最终平仓时一并记录）
+func (at *AutoTrader) recordFunding(actionRecord *logger.DecisionAction, symbol, side string) {
+	fr, ok := at.exchange.(exchange.FundingFeeReporter)
+	if !ok {
+		return
+	}
+	openedAt, ok := at.positionFirstSeenTime[symbol+"_"+side]
+	if !ok {
+		return
+	}
+	funding, err := fr.GetFundingFees(symbol, side, time.UnixMilli(openedAt))
+	if err != nil {
+		at.log.Printf("  ⚠ 查询 %s 资金费失败: %v", symbol, err)
+		return
+	}
+	actionRecord.Funding = funding
+}
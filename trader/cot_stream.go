@@ -0,0 +1,61 @@
+package trader
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// 流式输出合并推送阈值（避免每个token推送一条事件，订阅者缓冲区满时会丢弃事件）
+const (
+	cotStreamFlushSize     = 256
+	cotStreamFlushInterval = 300 * time.Millisecond
+)
+
+// CoTDelta AI流式输出的增量（EventCoT事件数据）
+type CoTDelta struct {
+	Cycle int    `json:"cycle"`          // 当前周期（与决策记录的周期编号一致）
+	Delta string `json:"delta"`          // 新增的输出片段
+	Done  bool   `json:"done,omitempty"` // 本周期输出结束
+}
+
+// cotStream 合并AI流式输出的片段后推送到事件中心
+type cotStream struct {
+	at       *AutoTrader
+	cycle    int
+	mu       sync.Mutex
+	buf      strings.Builder
+	lastSent time.Time
+}
+
+// newCoTStream 为当前周期创建流式输出推送
+func (at *AutoTrader) newCoTStream() *cotStream {
+	return &cotStream{at: at, cycle: at.cycleNumber(), lastSent: time.Now()}
+}
+
+// write 收到新的输出片段（由AI客户端在读取响应的协程中调用）
+func (s *cotStream) write(delta string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buf.WriteString(delta)
+	if s.buf.Len() >= cotStreamFlushSize || time.Since(s.lastSent) >= cotStreamFlushInterval {
+		s.flush(false)
+	}
+}
+
+// close 推送剩余内容并标记本周期输出结束
+func (s *cotStream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flush(true)
+}
+
+// flush 推送缓冲区内容（调用方持有锁）
+func (s *cotStream) flush(done bool) {
+	if s.buf.Len() == 0 && !done {
+		return
+	}
+	s.at.publishEvent(EventCoT, CoTDelta{Cycle: s.cycle, Delta: s.buf.String(), Done: done})
+	s.buf.Reset()
+	s.lastSent = time.Now()
+}
@@ -0,0 +1,87 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+)
+
+// IsDryRun 是否处于观察模式（完整运行决策周期，但不向交易所发送订单）
+func (at *AutoTrader) IsDryRun() bool {
+	at.riskMu.RLock()
+	defer at.riskMu.RUnlock()
+	return at.config.DryRun
+}
+
+// SetDryRun 开启/关闭观察模式（下个周期生效）
+func (at *AutoTrader) SetDryRun(dryRun bool) {
+	at.riskMu.Lock()
+	at.config.DryRun = dryRun
+	at.riskMu.Unlock()
+
+	if dryRun {
+		at.baseLog.Printf("👁 已开启观察模式：决策只模拟成交，不下单")
+	} else {
+		at.baseLog.Printf("▶️ 已关闭观察模式，恢复实盘下单")
+	}
+}
+
+// simulateDecision 观察模式下执行决策：与实盘相同的开平仓检查，按当前价格（限价单按挂单价）模拟成交并记录，不下单
+// 模拟成交不会产生持仓，下个周期AI看到的仍是交易所上的真实账户
+func (at *AutoTrader) simulateDecision(d *decision.Decision, actionRecord *logger.DecisionAction) error {
+	var side string
+	switch d.Action {
+	case "hold", "wait":
+		return nil
+	case "open_long", "close_long":
+		side = "long"
+	case "open_short", "close_short":
+		side = "short"
+	default:
+		return fmt.Errorf("未知的action: %s", d.Action)
+	}
+
+	marketData, err := market.GetWithTimeframes(d.Symbol, at.config.Timeframes)
+	if err != nil {
+		return err
+	}
+	price := marketData.CurrentPrice
+
+	var quantity float64
+	if d.Action == "open_long" || d.Action == "open_short" {
+		if err := at.checkStopOutCooldown(d.Symbol, side); err != nil {
+			return err
+		}
+		if err := at.checkPriceLevels(d); err != nil {
+			return err
+		}
+		if (d.OrderType == "limit" || d.OrderType == "post_only") && d.LimitPrice > 0 {
+			price = d.LimitPrice
+		}
+		if quantity, err = at.checkFeasibility(d, price); err != nil {
+			return err
+		}
+		if err := at.checkConcentration(d, side, quantity, price); err != nil {
+			return err
+		}
+	} else {
+		positionQty, err := at.positionQuantity(d.Symbol, side)
+		if err != nil {
+			return err
+		}
+		if positionQty == 0 {
+			return fmt.Errorf("没有找到 %s 的%s仓", d.Symbol, map[string]string{"long": "多", "short": "空"}[side])
+		}
+		quantity = positionQty
+		if d.CloseFraction > 0 && d.CloseFraction < 1 {
+			quantity = positionQty * d.CloseFraction
+		}
+	}
+
+	actionRecord.Quantity = quantity
+	actionRecord.Price = price
+	actionRecord.Fee = quantity * price * estimatedFeeRate
+	at.log.Printf("  👁 [观察模式] %s %s 模拟成交: %.4f @ %.4f（未下单）", d.Symbol, d.Action, quantity, price)
+	return nil
+}
@@ -0,0 +1,92 @@
+package trader
+
+import (
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	EventAccount   = "account"   // 账户净值更新
+	EventPositions = "positions" // 持仓快照更新
+	EventDecision  = "decision"  // 新的AI决策记录
+	EventCoT       = "cot"       // AI流式输出的增量（思维链）
+)
+
+// Event 实时推送事件
+type Event struct {
+	Type      string      `json:"type"`
+	TraderID  string      `json:"trader_id"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// subscriber 事件订阅者
+type subscriber struct {
+	traderID string // 为空表示订阅所有trader
+	ch       chan Event
+}
+
+// EventBus 事件发布/订阅中心（进程内）
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers map[*subscriber]struct{}
+}
+
+// 默认事件中心（所有AutoTrader共用）
+var defaultEventBus = NewEventBus()
+
+// NewEventBus 创建事件中心
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// Events 获取默认事件中心
+func Events() *EventBus {
+	return defaultEventBus
+}
+
+// Subscribe 订阅事件（traderID为空表示订阅全部），返回事件通道和取消订阅函数
+func (b *EventBus) Subscribe(traderID string) (<-chan Event, func()) {
+	sub := &subscriber{
+		traderID: traderID,
+		ch:       make(chan Event, 64),
+	}
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, sub)
+			b.mu.Unlock()
+			close(sub.ch)
+		})
+	}
+	return sub.ch, cancel
+}
+
+// Publish 发布事件（非阻塞，订阅者缓冲区满时丢弃该事件）
+func (b *EventBus) Publish(event Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subscribers {
+		if sub.traderID != "" && sub.traderID != event.TraderID {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			// 慢消费者，丢弃事件，避免阻塞交易主循环
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/exchange"
+)
+
+// ExchangeSettings 可通过API修改的交易所连接设置
+type ExchangeSettings struct {
+	Testnet bool `json:"testnet"` // 使用测试网/模拟盘（hyperliquid、okx、bybit、dydx支持）
+}
+
+// GetExchange 获取交易平台名称
+func (at *AutoTrader) GetExchange() string {
+	return at.exchangeName
+}
+
+// GetExchangeSettings 获取当前的交易所连接设置
+func (at *AutoTrader) GetExchangeSettings() ExchangeSettings {
+	at.runMu.Lock()
+	defer at.runMu.Unlock()
+	return ExchangeSettings{Testnet: exchange.Testnet(at.exchangeName, at.config.ExchangeConfig)}
+}
+
+// SetExchangeSettings 切换测试网/主网：重新创建交易所客户端（trader必须已停止，未成交的限价单属于原网络，一并丢弃）
+func (at *AutoTrader) SetExchangeSettings(s ExchangeSettings) error {
+	at.runMu.Lock()
+	defer at.runMu.Unlock()
+	if at.isRunning {
+		return fmt.Errorf("trader运行中，请先停止再切换交易所设置")
+	}
+	if !exchange.SupportsTestnet(at.exchangeName) {
+		if s.Testnet {
+			return fmt.Errorf("%s 不支持测试网", at.exchangeName)
+		}
+		return nil
+	}
+
+	config := at.config
+	exchange.SetTestnet(at.exchangeName, &config.ExchangeConfig, s.Testnet)
+
+	client, err := newExchange(config)
+	if err != nil {
+		return fmt.Errorf("重新连接交易所失败: %w", err)
+	}
+	at.exchange = client
+	at.config = config
+
+	at.pendingMu.Lock()
+	dropped := len(at.pendingOrders)
+	at.pendingOrders = make(map[string]*PendingOrder)
+	at.pendingMu.Unlock()
+
+	at.baseLog.Printf("🔀 已切换到%s（丢弃 %d 个原网络的限价单，下次启动时按新网络的持仓对账）", networkName(s.Testnet), dropped)
+	return nil
+}
+
+// networkName 网络名称
+func networkName(testnet bool) string {
+	if testnet {
+		return "测试网"
+	}
+	return "主网"
+}
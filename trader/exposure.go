@@ -0,0 +1,40 @@
+package trader
+
+import "nofx/decision"
+
+// ExposureRequest 开仓前提交给组合风控的新增敞口
+type ExposureRequest struct {
+	TraderID string
+	Symbol   string
+	Notional float64 // 新增名义价值（USDT）
+	Margin   float64 // 新增保证金（USDT）
+}
+
+// ExposureGuard 跨trader的组合风控（多个trader共用一个交易所账户时由manager设置）
+type ExposureGuard interface {
+	// Reserve 校验开仓后是否超过组合限制，通过时返回释放函数（下单完成后调用，期间同账户的其他开仓需等待）
+	Reserve(req ExposureRequest) (release func(), err error)
+}
+
+// SetExposureGuard 设置组合风控（启动前调用）
+func (at *AutoTrader) SetExposureGuard(guard ExposureGuard) {
+	at.exposureGuard = guard
+}
+
+// reserveExposure 向组合风控申请本次开仓的敞口（未设置组合风控时直接通过）
+func (at *AutoTrader) reserveExposure(d *decision.Decision, quantity, price float64) (func(), error) {
+	if at.exposureGuard == nil {
+		return func() {}, nil
+	}
+	notional := quantity * price
+	margin := notional
+	if d.Leverage > 0 {
+		margin = notional / float64(d.Leverage)
+	}
+	return at.exposureGuard.Reserve(ExposureRequest{
+		TraderID: at.id,
+		Symbol:   d.Symbol,
+		Notional: notional,
+		Margin:   margin,
+	})
+}
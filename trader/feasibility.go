@@ -0,0 +1,50 @@
+package trader
+
+import (
+	"fmt"
+	"math"
+	"nofx/decision"
+)
+
+// checkFeasibility 开仓前按交易所规则和可用余额校验决策，返回按步长取整后的下单数量：
+// 数量低于最小下单量/最小名义价值时拒绝，杠杆超过该名义价值所在分层的上限时自动降级，
+// 可用余额不足以支付保证金时拒绝（price为预计成交价，限价单传挂单价）
+func (at *AutoTrader) checkFeasibility(d *decision.Decision, price float64) (float64, error) {
+	if price <= 0 {
+		return 0, fmt.Errorf("❌ %s 价格无效，无法计算下单数量", d.Symbol)
+	}
+	quantity := d.PositionSizeUSD / price
+
+	// 交易所不提供下单规则时（rules为nil）跳过步长和杠杆分层校验
+	if rules, err := at.exchange.GetSymbolFilters(d.Symbol); err != nil {
+		at.log.Printf("  ⚠ %s 获取下单规则失败，跳过步长和杠杆分层校验: %v", d.Symbol, err)
+	} else if rules != nil {
+		if rules.StepSize > 0 {
+			quantity = math.Floor(quantity/rules.StepSize+1e-9) * rules.StepSize
+		}
+		if quantity <= 0 || quantity < rules.MinQty {
+			return 0, fmt.Errorf("❌ %s 仓位 %.2f USDT 按步长取整后数量 %.6f 低于最小下单量 %.6f", d.Symbol, d.PositionSizeUSD, quantity, rules.MinQty)
+		}
+		notional := quantity * price
+		if notional < rules.MinNotional {
+			return 0, fmt.Errorf("❌ %s 名义价值 %.2f USDT 低于交易所最小名义价值 %.2f USDT", d.Symbol, notional, rules.MinNotional)
+		}
+		if maxLeverage := rules.MaxLeverage(notional); maxLeverage > 0 && d.Leverage > maxLeverage {
+			at.log.Printf("  🔧 %s 名义价值 %.2f USDT 所在杠杆分层最高 %dx，杠杆 %dx 降级为 %dx", d.Symbol, notional, maxLeverage, d.Leverage, maxLeverage)
+			d.Leverage = maxLeverage
+		}
+	}
+
+	// 保证金：可用余额必须覆盖 名义价值/杠杆
+	if d.Leverage > 0 {
+		account, err := at.exchange.GetAccount()
+		if err != nil {
+			return 0, fmt.Errorf("获取账户余额失败: %w", err)
+		}
+		margin := quantity * price / float64(d.Leverage)
+		if margin > account.AvailableBalance {
+			return 0, fmt.Errorf("❌ %s 所需保证金 %.2f USDT 超过可用余额 %.2f USDT", d.Symbol, margin, account.AvailableBalance)
+		}
+	}
+	return quantity, nil
+}
@@ -0,0 +1,21 @@
+package trader
+
+import (
+	"fmt"
+	"time"
+)
+
+// CheckExchange 检查交易所是否可访问（查询一次账户，超时视为不可访问）
+func (at *AutoTrader) CheckExchange(timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := at.exchange.GetAccount()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("查询账户超时（%v）", timeout)
+	}
+}
@@ -0,0 +1,57 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/market"
+)
+
+// lessonSummary 与本周期持仓、候选币种和市场状态最相关的历史交易复盘（写入prompt，让AI从过去的交易中总结经验）
+func (at *AutoTrader) lessonSummary(positions []decision.PositionInfo, candidates []decision.CandidateCoin, regime *market.Regime) []string {
+	limit := at.config.PromptLessons
+	if limit <= 0 {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var symbols []string
+	for _, pos := range positions {
+		if !seen[pos.Symbol] {
+			seen[pos.Symbol] = true
+			symbols = append(symbols, pos.Symbol)
+		}
+	}
+	for _, coin := range candidates {
+		if !seen[coin.Symbol] {
+			seen[coin.Symbol] = true
+			symbols = append(symbols, coin.Symbol)
+		}
+	}
+	label := ""
+	if regime != nil {
+		label = regime.Label
+	}
+
+	lessons, err := at.decisionLogger.RelevantLessons(symbols, label, limit)
+	if err != nil {
+		at.log.Printf("⚠️  查询交易复盘失败: %v", err)
+		return nil
+	}
+	lines := make([]string, 0, len(lessons))
+	for _, l := range lessons {
+		lines = append(lines, l.Time.Format("01-02")+" "+l.Summary+" → 教训: "+l.Lesson)
+	}
+	return lines
+}
+
+// setupMemory 本周期用于检索相似形态的历史交易（未启用或查询失败时为空）
+func (at *AutoTrader) setupMemory() decision.SetupMemory {
+	k := at.config.SimilarSetups
+	if k <= 0 {
+		return decision.SetupMemory{}
+	}
+	history, err := at.decisionLogger.ClosedSetups()
+	if err != nil {
+		at.log.Printf("⚠️  查询历史形态失败: %v", err)
+		return decision.SetupMemory{}
+	}
+	return decision.SetupMemory{K: k, History: history}
+}
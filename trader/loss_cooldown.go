@@ -0,0 +1,74 @@
+package trader
+
+import (
+	"fmt"
+	"time"
+)
+
+// tradeResult 一笔已平仓交易的盈亏
+type tradeResult struct {
+	At  time.Time `json:"at"`
+	PnL float64   `json:"pnl"`
+}
+
+// recordTradeResult 记录已平仓交易（AI平仓、止损止盈触发），连续亏损笔数或时间窗口内亏损超限时暂停开仓
+func (at *AutoTrader) recordTradeResult(symbol, side string, pnl float64) {
+	limits := at.GetRiskLimits()
+	now := time.Now()
+
+	at.riskMu.Lock()
+	if pnl < 0 {
+		at.lossStreak++
+	} else {
+		at.lossStreak = 0
+	}
+	at.recentTrades = append(at.recentTrades, tradeResult{At: now, PnL: pnl})
+	window := time.Duration(limits.LossWindowMinutes) * time.Minute
+	kept := at.recentTrades[:0]
+	windowPnL := 0.0
+	for _, t := range at.recentTrades {
+		if now.Sub(t.At) <= window {
+			kept = append(kept, t)
+			windowPnL += t.PnL
+		}
+	}
+	at.recentTrades = kept
+	streak := at.lossStreak
+	at.riskMu.Unlock()
+
+	reason := ""
+	equity := at.dayStartEquity
+	if equity <= 0 {
+		equity = at.getInitialBalance()
+	}
+	if limits.MaxConsecutiveLosses > 0 && streak >= limits.MaxConsecutiveLosses {
+		reason = fmt.Sprintf("连续亏损 %d 笔（上限 %d 笔，最近一笔 %s %s %+.2f USDT）", streak, limits.MaxConsecutiveLosses, symbol, side, pnl)
+	} else if limits.LossWindowPct > 0 && equity > 0 && -windowPnL/equity*100 >= limits.LossWindowPct {
+		reason = fmt.Sprintf("%d 分钟内已平仓亏损 %.2f USDT（%.2f%%，上限 %.1f%%）", limits.LossWindowMinutes, -windowPnL, -windowPnL/equity*100, limits.LossWindowPct)
+	}
+	if reason == "" {
+		return
+	}
+
+	until := now.Add(time.Duration(limits.LossCooldownMinutes) * time.Minute)
+	at.riskMu.Lock()
+	at.entryCooldownUntil = until
+	at.entryCooldownReason = reason
+	// 冷却后重新计数，避免冷却结束后下一笔亏损立即再次触发
+	at.lossStreak = 0
+	at.recentTrades = nil
+	at.riskMu.Unlock()
+
+	at.log.Printf("🧊 亏损冷却触发: %s，暂停开仓至 %s", reason, until.Format("15:04"))
+	at.notify("🧊 亏损冷却触发（%s），暂停开仓至 %s", reason, until.Format("2006-01-02 15:04"))
+}
+
+// entryCooldown 亏损冷却是否生效（返回提示原因）
+func (at *AutoTrader) entryCooldown() (string, bool) {
+	at.riskMu.RLock()
+	defer at.riskMu.RUnlock()
+	if !time.Now().Before(at.entryCooldownUntil) {
+		return "", false
+	}
+	return fmt.Sprintf("%s，暂停开仓至 %s（只允许持有或平仓）", at.entryCooldownReason, at.entryCooldownUntil.Format("15:04")), true
+}
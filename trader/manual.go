@@ -0,0 +1,163 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"time"
+)
+
+// 决策记录来源（AI决策周期的记录不设置来源）
+const (
+	DecisionSourceManual   = "manual"   // 操作员通过API手动开平仓
+	DecisionSourceApproval = "approval" // 人工审批通过后执行的开仓
+	DecisionSourceRetry    = "retry"    // 临时性错误后重试的AI决策
+)
+
+// ManualOrder 操作员手动开平仓的参数
+type ManualOrder struct {
+	Symbol string `json:"symbol"`
+	Side   string `json:"side"` // long | short
+
+	// 开仓参数
+	PositionSizeUSD float64 `json:"position_size_usd,omitempty"`
+	Leverage        int     `json:"leverage,omitempty"`
+	StopLoss        float64 `json:"stop_loss,omitempty"`
+	TakeProfit      float64 `json:"take_profit,omitempty"`
+	MarginMode      string  `json:"margin_mode,omitempty"` // isolated（默认）| cross
+
+	// 平仓参数
+	CloseFraction float64 `json:"close_fraction,omitempty"` // 平仓比例（0-1，不填或1表示全部平仓）
+
+	Reason string `json:"reason,omitempty"` // 操作原因（写入决策记录）
+}
+
+// decision 把手动下单转换为决策（open为true时开仓）
+func (o ManualOrder) decision(open bool, by string) (decision.Decision, error) {
+	if o.Symbol == "" {
+		return decision.Decision{}, fmt.Errorf("symbol不能为空")
+	}
+	if o.Side != "long" && o.Side != "short" {
+		return decision.Decision{}, fmt.Errorf("side必须是long或short")
+	}
+	action := "close_" + o.Side
+	if open {
+		action = "open_" + o.Side
+	}
+	reason := o.Reason
+	if reason == "" {
+		reason = "人工操作"
+	}
+	return decision.Decision{
+		Symbol:          market.Normalize(o.Symbol),
+		Action:          action,
+		Leverage:        o.Leverage,
+		PositionSizeUSD: o.PositionSizeUSD,
+		StopLoss:        o.StopLoss,
+		TakeProfit:      o.TakeProfit,
+		MarginMode:      o.MarginMode,
+		CloseFraction:   o.CloseFraction,
+		Reasoning:       fmt.Sprintf("[%s] %s", by, reason),
+	}, nil
+}
+
+// ManualOpen 操作员手动开仓：按当前价格市价开仓并设置止损止盈（与AI开仓经过相同的风控检查，不需要审批），by为操作人
+// 观察模式下同样真实下单
+func (at *AutoTrader) ManualOpen(o ManualOrder, by string) (*logger.DecisionAction, error) {
+	d, err := o.decision(true, by)
+	if err != nil {
+		return nil, err
+	}
+	maxLeverage := at.config.AltcoinLeverage
+	if d.Symbol == "BTCUSDT" || d.Symbol == "ETHUSDT" {
+		maxLeverage = at.config.BTCETHLeverage
+	}
+	if d.Leverage <= 0 || d.Leverage > maxLeverage {
+		return nil, fmt.Errorf("杠杆必须在1-%d之间（%s）", maxLeverage, d.Symbol)
+	}
+	if d.PositionSizeUSD <= 0 {
+		return nil, fmt.Errorf("position_size_usd必须大于0")
+	}
+	if d.StopLoss <= 0 || d.TakeProfit <= 0 {
+		return nil, fmt.Errorf("止损和止盈必须大于0")
+	}
+	if (o.Side == "long" && d.StopLoss >= d.TakeProfit) || (o.Side == "short" && d.StopLoss <= d.TakeProfit) {
+		return nil, fmt.Errorf("止损价必须在止盈价的亏损一侧")
+	}
+	switch d.MarginMode {
+	case "", "isolated", "cross":
+	default:
+		return nil, fmt.Errorf("无效的margin_mode: %s（可选 isolated, cross）", d.MarginMode)
+	}
+	if d.Action == "open_short" && at.IsSpot() {
+		return nil, fmt.Errorf("现货交易不支持做空")
+	}
+
+	at.baseLog.Printf("🖐 %s 手动开仓: %s %s %.2f USDT %dx", by, d.Symbol, d.Action, d.PositionSizeUSD, d.Leverage)
+	return at.executeOutOfCycle(d, DecisionSourceManual, fmt.Sprintf("人工开仓（%s）", by), "")
+}
+
+// ManualClose 操作员手动平仓（全部或按close_fraction部分平仓），by为操作人
+func (at *AutoTrader) ManualClose(o ManualOrder, by string) (*logger.DecisionAction, error) {
+	d, err := o.decision(false, by)
+	if err != nil {
+		return nil, err
+	}
+	if d.CloseFraction < 0 || d.CloseFraction > 1 {
+		return nil, fmt.Errorf("close_fraction必须在0-1之间")
+	}
+
+	at.baseLog.Printf("🖐 %s 手动平仓: %s %s", by, d.Symbol, d.Action)
+	return at.executeOutOfCycle(d, DecisionSourceManual, fmt.Sprintf("人工平仓（%s）", by), "")
+}
+
+// executeOutOfCycle 在决策周期之外执行一条决策（人工下单、审批通过的开仓、失败重试），单独写入一条决策记录
+// 与决策周期串行执行，始终真实下单（不受观察模式影响）；key为决策的幂等键（为空时按本次执行生成）
+func (at *AutoTrader) executeOutOfCycle(d decision.Decision, source, note, key string) (*logger.DecisionAction, error) {
+	at.execMu.Lock()
+	defer at.execMu.Unlock()
+
+	decisionJSON, _ := json.MarshalIndent([]decision.Decision{d}, "", "  ")
+	record := &logger.DecisionRecord{
+		DecisionJSON: string(decisionJSON),
+		ExecutionLog: []string{},
+		Success:      true,
+		Source:       source,
+	}
+	actionRecord := logger.DecisionAction{
+		Action:    d.Action,
+		Symbol:    d.Symbol,
+		Leverage:  d.Leverage,
+		Timestamp: time.Now(),
+		Manual:    source == DecisionSourceManual,
+	}
+	if marketData, err := market.GetWithTimeframes(d.Symbol, at.config.Timeframes); err == nil {
+		actionRecord.DecisionPrice = marketData.CurrentPrice
+	}
+	if key == "" {
+		key = at.decisionKey(&d, actionRecord.Timestamp)
+	}
+	setClientOrderID(&actionRecord, key)
+	err := at.executeDecisionWithRecord(&d, &actionRecord)
+	if err != nil {
+		at.baseLog.Printf("❌ %s失败 (%s %s): %v", note, d.Symbol, d.Action, err)
+		actionRecord.Error = err.Error()
+		record.Success = false
+		record.ErrorMessage = err.Error()
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s: %s %s 失败: %v", note, d.Symbol, d.Action, err))
+		at.reconcile("下单失败")
+	} else {
+		actionRecord.Success = true
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s: %s %s 成功", note, d.Symbol, d.Action))
+	}
+	record.Decisions = append(record.Decisions, actionRecord)
+
+	if logErr := at.decisionLogger.LogDecision(record); logErr != nil {
+		at.baseLog.Printf("⚠ 保存决策记录失败: %v", logErr)
+	}
+	at.publishEvent(EventDecision, record)
+	at.sendDecisionWebhook(d, actionRecord)
+	return &actionRecord, err
+}
@@ -0,0 +1,30 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/exchange"
+)
+
+// applyMarginMode 按决策设置开仓的保证金模式（未指定时为逐仓）
+func (at *AutoTrader) applyMarginMode(d *decision.Decision) error {
+	mt, ok := at.exchange.(exchange.MarginModeSetter)
+	if !ok {
+		if d.MarginMode != "" {
+			at.log.Printf("  ⚠ %s 不支持选择保证金模式，忽略 margin_mode=%s", at.exchangeName, d.MarginMode)
+		}
+		return nil
+	}
+	mode := d.MarginMode
+	if mode == "" {
+		mode = "isolated"
+	}
+	return mt.SetMarginMode(d.Symbol, mode)
+}
+
+// cancelSideOrders 撤销一个方向的挂单（双向持仓时不影响另一方向的止损止盈，交易所不支持时撤销该币种的所有挂单）
+func (at *AutoTrader) cancelSideOrders(symbol, side string) error {
+	if st, ok := at.exchange.(exchange.SideOrderCanceler); ok {
+		return st.CancelSideOrders(symbol, side)
+	}
+	return at.exchange.CancelAllOrders(symbol)
+}
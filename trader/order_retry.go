@@ -0,0 +1,201 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/decision"
+	"nofx/exchange"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	orderRetryBaseDelay     = 10 * time.Second // 第n次重试前等待 base×2^(n-1)
+	orderRetryCheckInterval = 5 * time.Second  // 重试队列检查间隔
+)
+
+// OrderRetry 因临时性错误（超时、时间戳偏差、限流）失败、等待重试的决策
+type OrderRetry struct {
+	Key           string            `json:"key"` // 幂等键（同一决策的首次执行和所有重试共用）
+	Decision      decision.Decision `json:"decision"`
+	ClientOrderID string            `json:"client_order_id,omitempty"` // 开仓单的客户端订单ID（所有重试共用）
+	Attempts      int               `json:"attempts"`                  // 已重试次数
+	NextAt        time.Time         `json:"next_at"`
+	LastError     string            `json:"last_error"`
+	CreatedAt     time.Time         `json:"created_at"`
+
+	baseQty float64 // 决策时该方向的持仓数量（用于判断失败的订单是否实际已生效）
+}
+
+// decisionKey 决策的幂等键：trader、币种、操作和首次执行时间（毫秒）
+func (at *AutoTrader) decisionKey(d *decision.Decision, ts time.Time) string {
+	return fmt.Sprintf("%s-%s-%s-%d", at.id, d.Symbol, d.Action, ts.UnixMilli())
+}
+
+// decisionSide 开平仓决策对应的持仓方向
+func decisionSide(action string) string {
+	if strings.HasSuffix(action, "_short") {
+		return "short"
+	}
+	return "long"
+}
+
+// queueOrderRetry 临时性错误导致开平仓失败时加入重试队列，返回是否已加入
+// 限价开仓单超时后可能已挂在交易所，不重试（由对账处理）
+func (at *AutoTrader) queueOrderRetry(d decision.Decision, key string, err error, positions []decision.PositionInfo) bool {
+	if at.config.OrderRetryAttempts <= 0 || !exchange.IsTransient(err) {
+		return false
+	}
+	switch d.Action {
+	case "open_long", "open_short":
+		if d.OrderType == "limit" || d.OrderType == "post_only" {
+			return false
+		}
+	case "close_long", "close_short":
+	default:
+		return false
+	}
+
+	now := time.Now()
+	r := &OrderRetry{
+		Key:       key,
+		Decision:  d,
+		NextAt:    now.Add(orderRetryBaseDelay),
+		LastError: err.Error(),
+		CreatedAt: now,
+	}
+	if strings.HasPrefix(d.Action, "open_") {
+		r.ClientOrderID = clientOrderID(key)
+	}
+	side := decisionSide(d.Action)
+	for _, pos := range positions {
+		if pos.Symbol == d.Symbol && pos.Side == side {
+			r.baseQty = pos.Quantity
+		}
+	}
+
+	at.retryMu.Lock()
+	at.orderRetries[key] = r
+	at.retryMu.Unlock()
+	at.log.Printf("  🔁 %s %s 临时性错误，%v后重试（#%s）", d.Symbol, d.Action, orderRetryBaseDelay, key)
+	return true
+}
+
+// dropOrderRetries 放弃该币种等待中的重试（新周期的AI已重新对该币种做出决策）
+func (at *AutoTrader) dropOrderRetries(symbol string) {
+	at.retryMu.Lock()
+	defer at.retryMu.Unlock()
+	for key, r := range at.orderRetries {
+		if r.Decision.Symbol == symbol {
+			delete(at.orderRetries, key)
+			at.log.Printf("  🔁 %s 已有新决策，放弃重试 #%s（%s）", symbol, key, r.Decision.Action)
+		}
+	}
+}
+
+// GetOrderRetries 等待重试的决策（按下次重试时间排序）
+func (at *AutoTrader) GetOrderRetries() []OrderRetry {
+	at.retryMu.Lock()
+	defer at.retryMu.Unlock()
+	result := make([]OrderRetry, 0, len(at.orderRetries))
+	for _, r := range at.orderRetries {
+		result = append(result, *r)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].NextAt.Before(result[j].NextAt) })
+	return result
+}
+
+// runOrderRetries 重试队列监控循环（直到stopCh关闭）
+func (at *AutoTrader) runOrderRetries(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(orderRetryCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			for _, r := range at.dueOrderRetries() {
+				at.retryOrder(r)
+			}
+		}
+	}
+}
+
+// dueOrderRetries 到达重试时间的决策
+func (at *AutoTrader) dueOrderRetries() []*OrderRetry {
+	at.retryMu.Lock()
+	defer at.retryMu.Unlock()
+	now := time.Now()
+	var due []*OrderRetry
+	for _, r := range at.orderRetries {
+		if !now.Before(r.NextAt) {
+			due = append(due, r)
+		}
+	}
+	return due
+}
+
+// retryOrder 重试一条决策：先确认之前失败的订单没有实际生效（如超时但已成交），再重新执行
+func (at *AutoTrader) retryOrder(r *OrderRetry) {
+	d := r.Decision
+	if applied, qty, err := at.retryApplied(r); err == nil && applied {
+		at.removeOrderRetry(r.Key)
+		at.baseLog.Printf("✓ %s %s 之前失败的订单实际已生效，取消重试 #%s", d.Symbol, d.Action, r.Key)
+		// 下单返回错误时没有设置止损止盈，按实际持仓补上
+		if strings.HasPrefix(d.Action, "open_") {
+			at.replaceProtection(d.Symbol, decisionSide(d.Action), qty, &d)
+		}
+		return
+	}
+
+	at.retryMu.Lock()
+	if _, ok := at.orderRetries[r.Key]; !ok {
+		at.retryMu.Unlock()
+		return // 已被新决策取代
+	}
+	r.Attempts++
+	attempt := r.Attempts
+	at.retryMu.Unlock()
+
+	_, err := at.executeOutOfCycle(d, DecisionSourceRetry, fmt.Sprintf("重试 #%s（第%d次）", r.Key, attempt), r.Key)
+	if err == nil {
+		at.removeOrderRetry(r.Key)
+		at.baseLog.Printf("✓ %s %s 第%d次重试成功", d.Symbol, d.Action, attempt)
+		return
+	}
+	if exchange.IsTransient(err) && attempt < at.config.OrderRetryAttempts {
+		at.retryMu.Lock()
+		r.LastError = err.Error()
+		r.NextAt = time.Now().Add(orderRetryBaseDelay << attempt)
+		at.retryMu.Unlock()
+		return
+	}
+	at.removeOrderRetry(r.Key)
+	at.baseLog.Printf("❌ %s %s 重试%d次后仍失败，放弃: %v", d.Symbol, d.Action, attempt, err)
+	at.notify("❌ %s %s 下单重试%d次后仍失败，已放弃: %v", d.Symbol, d.Action, attempt, err)
+}
+
+// retryApplied 判断之前失败的订单是否实际已生效，同时返回当前持仓数量
+// 开仓单优先按客户端订单ID向交易所查询；不支持查询时对比决策时的持仓数量（开仓后持仓增加，平仓后持仓减少）
+func (at *AutoTrader) retryApplied(r *OrderRetry) (bool, float64, error) {
+	qty, err := at.positionQuantity(r.Decision.Symbol, decisionSide(r.Decision.Action))
+	if err != nil {
+		return false, 0, err
+	}
+	if found, ok := at.findClientOrder(r.Decision.Symbol, r.ClientOrderID); ok {
+		return found, qty, nil
+	}
+	const tolerance = 1e-9
+	if strings.HasPrefix(r.Decision.Action, "open_") {
+		return qty > r.baseQty+tolerance, qty, nil
+	}
+	return qty < r.baseQty-tolerance, qty, nil
+}
+
+// removeOrderRetry 从重试队列移除
+func (at *AutoTrader) removeOrderRetry(key string) {
+	at.retryMu.Lock()
+	delete(at.orderRetries, key)
+	at.retryMu.Unlock()
+}
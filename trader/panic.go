@@ -0,0 +1,90 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/logger"
+	"strings"
+)
+
+// PanicResult 紧急平仓结果
+type PanicResult struct {
+	TraderID string   `json:"trader_id"`
+	Closed   []string `json:"closed"`           // 已平仓的持仓（symbol_side）
+	Errors   []string `json:"errors,omitempty"` // 撤单/平仓失败的明细
+}
+
+// IsLocked 是否处于紧急锁定状态（锁定期间不运行AI决策，也不允许开仓）
+func (at *AutoTrader) IsLocked() bool {
+	at.riskMu.RLock()
+	defer at.riskMu.RUnlock()
+	return at.locked
+}
+
+// SetLocked 设置紧急锁定状态（解锁需人工调用）
+func (at *AutoTrader) SetLocked(locked bool) {
+	at.riskMu.Lock()
+	at.locked = locked
+	at.riskMu.Unlock()
+
+	if locked {
+		at.baseLog.Printf("🔒 Trader已紧急锁定，禁止开仓")
+	} else {
+		at.baseLog.Printf("🔓 Trader已解除锁定")
+		at.notify("🔓 已解除紧急锁定，恢复AI交易")
+	}
+}
+
+// Panic 紧急平仓：锁定trader，撤销所有挂单并市价平掉交易所上的全部持仓
+func (at *AutoTrader) Panic() (*PanicResult, error) {
+	// 先锁定，防止正在运行的周期继续开仓
+	at.SetLocked(true)
+
+	result, err := at.closeAllPositions(logger.CloseReasonPanic)
+	if err != nil {
+		at.notify("🚨 紧急平仓失败: %v", err)
+		return result, err
+	}
+
+	at.baseLog.Printf("🚨 紧急平仓完成: 平仓 %d 个持仓，失败 %d 项", len(result.Closed), len(result.Errors))
+	msg := fmt.Sprintf("🚨 紧急平仓: 已平仓 %d 个持仓，trader已锁定（需手动解锁）", len(result.Closed))
+	if len(result.Errors) > 0 {
+		msg += "\n失败: " + strings.Join(result.Errors, "; ")
+	}
+	at.notify("%s", msg)
+	return result, nil
+}
+
+// closeAllPositions 撤销所有挂单并市价平掉交易所上的全部持仓，reason记为交易的平仓原因（不锁定trader，也不推送通知）
+func (at *AutoTrader) closeAllPositions(reason string) (*PanicResult, error) {
+	result := &PanicResult{TraderID: at.id, Closed: []string{}}
+	positions, err := at.exchange.GetPositions()
+	if err != nil {
+		return result, fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	// 撤销未成交的限价开仓单，防止锁定后成交
+	result.Errors = append(result.Errors, at.cancelPendingOrders()...)
+
+	cancelled := make(map[string]bool)
+	for _, pos := range positions {
+		symbol, side := pos.Symbol, pos.Side
+
+		// 撤销该币种的止损止盈等挂单（每个币种只撤一次）
+		if !cancelled[symbol] {
+			cancelled[symbol] = true
+			if err := at.exchange.CancelAllOrders(symbol); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s 撤单失败: %v", symbol, err))
+			}
+		}
+
+		// quantity=0 表示全部平仓
+		order, closeErr := at.exchange.ClosePosition(symbol, side, 0)
+		if closeErr != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s %s 平仓失败: %v", symbol, side, closeErr))
+			continue
+		}
+		at.recordForcedExit(pos, order, reason)
+		result.Closed = append(result.Closed, symbol+"_"+side)
+	}
+	return result, nil
+}
@@ -0,0 +1,250 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/decision"
+	"nofx/exchange"
+	"nofx/logger"
+	"nofx/market"
+	"time"
+)
+
+// pendingOrderCheckInterval 限价单成交检查间隔
+const pendingOrderCheckInterval = 15 * time.Second
+
+// PendingOrder 未成交的限价开仓单（成交后才设置止损止盈）
+type PendingOrder struct {
+	OrderID    string    `json:"order_id"`
+	Symbol     string    `json:"symbol"`
+	Side       string    `json:"side"`       // long/short
+	OrderType  string    `json:"order_type"` // limit/post_only
+	Quantity   float64   `json:"quantity"`
+	LimitPrice float64   `json:"limit_price"`
+	Leverage   int       `json:"leverage"`
+	StopLoss   float64   `json:"stop_loss"`
+	TakeProfit float64   `json:"take_profit"`
+	PlacedAt   time.Time `json:"placed_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+
+	decision decision.Decision // 原始决策（成交后设置止损止盈、移动止损）
+}
+
+// hasPendingOrder 币种同方向是否已有未成交的限价开仓单
+func (at *AutoTrader) hasPendingOrder(symbol, side string) bool {
+	at.pendingMu.Lock()
+	defer at.pendingMu.Unlock()
+	for _, po := range at.pendingOrders {
+		if po.Symbol == symbol && po.Side == side {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPendingOrders 获取未成交的限价开仓单（用于API）
+func (at *AutoTrader) GetPendingOrders() []PendingOrder {
+	at.pendingMu.Lock()
+	defer at.pendingMu.Unlock()
+
+	orders := make([]PendingOrder, 0, len(at.pendingOrders))
+	for _, po := range at.pendingOrders {
+		orders = append(orders, *po)
+	}
+	return orders
+}
+
+// limitOrderTTL 限价单挂单有效期（未配置时为一个扫描间隔）
+func (at *AutoTrader) limitOrderTTL() time.Duration {
+	if at.config.LimitOrderTTL > 0 {
+		return at.config.LimitOrderTTL
+	}
+	interval, _ := at.scanIntervals()
+	return interval
+}
+
+// placeLimitOpen 下限价/post_only开仓单并登记到待成交列表
+func (at *AutoTrader) placeLimitOpen(d *decision.Decision, actionRecord *logger.DecisionAction, side string) error {
+	quantity, err := at.checkFeasibility(d, d.LimitPrice)
+	if err != nil {
+		return err
+	}
+	if err := at.checkConcentration(d, side, quantity, d.LimitPrice); err != nil {
+		return err
+	}
+	release, err := at.reserveExposure(d, quantity, d.LimitPrice)
+	if err != nil {
+		return err
+	}
+	defer release()
+	actionRecord.Quantity = quantity
+	actionRecord.Price = d.LimitPrice
+
+	order, err := at.placeOpenOrder(exchange.OrderRequest{
+		Symbol:        d.Symbol,
+		Side:          side,
+		Type:          d.OrderType, // "limit" / "post_only"
+		Quantity:      quantity,
+		Price:         d.LimitPrice,
+		Leverage:      d.Leverage,
+		ClientOrderID: actionRecord.ClientOrderID,
+	})
+	if err != nil {
+		return err
+	}
+	recordOrderID(actionRecord, order)
+	at.recordFee(actionRecord, order, quantity, d.LimitPrice)
+
+	ttl := at.limitOrderTTL()
+	po := &PendingOrder{
+		OrderID:    order.OrderID,
+		Symbol:     d.Symbol,
+		Side:       side,
+		OrderType:  d.OrderType,
+		Quantity:   quantity,
+		LimitPrice: d.LimitPrice,
+		Leverage:   d.Leverage,
+		StopLoss:   d.StopLoss,
+		TakeProfit: d.TakeProfit,
+		PlacedAt:   time.Now(),
+		ExpiresAt:  time.Now().Add(ttl),
+		decision:   *d,
+	}
+	at.pendingMu.Lock()
+	at.pendingOrders[po.OrderID] = po
+	at.pendingMu.Unlock()
+
+	at.log.Printf("  ✓ 限价单已挂出，订单ID: %s, 数量: %.4f @ %.4f（%.0f分钟未成交自动撤单）", po.OrderID, quantity, d.LimitPrice, ttl.Minutes())
+	at.notify("⏳ %s %s 限价挂单 %dx | 数量: %.4f @ %.4f | 止损: %.4f | 止盈: %.4f\n理由: %s",
+		d.Symbol, side, d.Leverage, quantity, d.LimitPrice, d.StopLoss, d.TakeProfit, d.Reasoning)
+	return nil
+}
+
+// runPendingOrders 限价单监控循环：成交后设置止损止盈，超时未成交则撤单（直到stopCh关闭）
+func (at *AutoTrader) runPendingOrders(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(pendingOrderCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			at.checkPendingOrders()
+		}
+	}
+}
+
+// checkPendingOrders 检查所有未成交限价单的状态
+func (at *AutoTrader) checkPendingOrders() {
+	lt, ok := at.exchange.(exchange.OrderTracker)
+	if !ok {
+		return
+	}
+
+	at.pendingMu.Lock()
+	orders := make([]*PendingOrder, 0, len(at.pendingOrders))
+	for _, po := range at.pendingOrders {
+		orders = append(orders, po)
+	}
+	at.pendingMu.Unlock()
+
+	for _, po := range orders {
+		status, err := lt.GetOrder(po.Symbol, po.OrderID)
+		if err != nil {
+			at.baseLog.Printf("⚠️ 限价单: 查询 %s 订单 %s 失败: %v", po.Symbol, po.OrderID, err)
+			continue
+		}
+
+		switch status.Status {
+		case exchange.OrderStatusFilled:
+			at.removePendingOrder(po.OrderID)
+			at.onLimitFilled(po, status)
+		case exchange.OrderStatusCanceled:
+			at.removePendingOrder(po.OrderID)
+			at.baseLog.Printf("🚫 %s %s 限价单 %s 已被撤销（已成交 %.4f）", po.Symbol, po.Side, po.OrderID, status.FilledQty)
+			if status.FilledQty > 0 {
+				at.onLimitFilled(po, status)
+			}
+		default:
+			if time.Now().Before(po.ExpiresAt) {
+				continue
+			}
+			if err := lt.CancelOrder(po.Symbol, po.OrderID); err != nil {
+				at.baseLog.Printf("⚠️ 限价单: 撤销 %s 订单 %s 失败: %v", po.Symbol, po.OrderID, err)
+				continue
+			}
+			// 撤单前后可能有新的成交，以撤单后的状态为准
+			if final, err := lt.GetOrder(po.Symbol, po.OrderID); err == nil {
+				status = final
+			}
+			at.removePendingOrder(po.OrderID)
+			at.baseLog.Printf("⌛ %s %s 限价单 %s 超时未成交，已撤单（已成交 %.4f）", po.Symbol, po.Side, po.OrderID, status.FilledQty)
+			if status.FilledQty > 0 {
+				at.onLimitFilled(po, status)
+			} else {
+				at.notify("⌛ %s %s 限价单 @ %.4f 超时未成交，已撤单", po.Symbol, po.Side, po.LimitPrice)
+			}
+		}
+	}
+}
+
+// removePendingOrder 从待成交列表移除
+func (at *AutoTrader) removePendingOrder(orderID string) {
+	at.pendingMu.Lock()
+	delete(at.pendingOrders, orderID)
+	at.pendingMu.Unlock()
+}
+
+// onLimitFilled 限价单（部分）成交后设置止损止盈和移动止损
+func (at *AutoTrader) onLimitFilled(po *PendingOrder, status *exchange.OrderStatus) {
+	d := po.decision
+	fillPrice := status.AvgPrice
+	if fillPrice <= 0 {
+		fillPrice = po.LimitPrice
+	}
+	at.recordTradeEntry(&d, po.Side, status.FilledQty, fillPrice, status.FilledQty*fillPrice*estimatedFeeRate, nil)
+
+	if d.AddToPosition {
+		// 加仓单成交：按合并后的持仓数量重新挂止损止盈
+		quantity, err := at.positionQuantity(po.Symbol, po.Side)
+		if err != nil || quantity == 0 {
+			quantity = status.FilledQty
+		}
+		at.replaceProtection(po.Symbol, po.Side, quantity, &d)
+	} else {
+		at.protectMu.Lock()
+		if err := at.placeStopLoss(po.Symbol, po.Side, status.FilledQty, d.StopLoss); err != nil {
+			at.baseLog.Printf("  ⚠ 设置止损失败: %v", err)
+		}
+		if err := at.placeTakeProfit(po.Symbol, po.Side, status.FilledQty, d.TakeProfit); err != nil {
+			at.baseLog.Printf("  ⚠ 设置止盈失败: %v", err)
+		}
+		at.setProtection(po.Symbol, po.Side, d.StopLoss, d.TakeProfit)
+		at.protectMu.Unlock()
+	}
+	if marketData, err := market.GetWithTimeframes(po.Symbol, at.config.Timeframes); err == nil {
+		at.registerTrailingStop(&d, po.Side, fillPrice, marketData)
+	}
+
+	at.baseLog.Printf("✅ %s %s 限价单 %s 成交: 数量 %.4f @ %.4f", po.Symbol, po.Side, po.OrderID, status.FilledQty, fillPrice)
+	at.notify("✅ %s %s 限价单成交 | 数量: %.4f @ %.4f | 止损: %.4f | 止盈: %.4f",
+		po.Symbol, po.Side, status.FilledQty, fillPrice, d.StopLoss, d.TakeProfit)
+}
+
+// cancelPendingOrders 撤销所有未成交的限价开仓单（紧急平仓时调用），返回失败明细
+func (at *AutoTrader) cancelPendingOrders() []string {
+	lt, ok := at.exchange.(exchange.OrderTracker)
+	if !ok {
+		return nil
+	}
+
+	var errs []string
+	for _, po := range at.GetPendingOrders() {
+		if err := lt.CancelOrder(po.Symbol, po.OrderID); err != nil {
+			errs = append(errs, fmt.Sprintf("%s 限价单 %s 撤单失败: %v", po.Symbol, po.OrderID, err))
+			continue
+		}
+		at.removePendingOrder(po.OrderID)
+	}
+	return errs
+}
@@ -0,0 +1,130 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/decision"
+	"nofx/logger"
+)
+
+// protectiveOrders 持仓当前挂出的止损止盈价
+type protectiveOrders struct {
+	StopLoss   float64
+	TakeProfit float64
+}
+
+// setProtection 记录持仓的止损止盈价（开仓/加仓/调整后调用）
+func (at *AutoTrader) setProtection(symbol, side string, stopLoss, takeProfit float64) {
+	at.trailingMu.Lock()
+	at.protection[symbol+"_"+side] = protectiveOrders{StopLoss: stopLoss, TakeProfit: takeProfit}
+	at.trailingMu.Unlock()
+}
+
+// removeProtection 全部平仓后移除止损止盈记录
+func (at *AutoTrader) removeProtection(symbol, side string) {
+	at.trailingMu.Lock()
+	delete(at.protection, symbol+"_"+side)
+	at.trailingMu.Unlock()
+}
+
+// positionQuantity 获取交易所上指定持仓的数量（不存在时返回0）
+func (at *AutoTrader) positionQuantity(symbol, side string) (float64, error) {
+	positions, err := at.exchange.GetPositions()
+	if err != nil {
+		return 0, fmt.Errorf("获取持仓失败: %w", err)
+	}
+	for _, pos := range positions {
+		if pos.Symbol == symbol && pos.Side == side {
+			return pos.Quantity, nil
+		}
+	}
+	return 0, nil
+}
+
+// replaceProtection 按新的持仓数量重新挂止损止盈（部分平仓、加仓、限价加仓单成交后调用，可能在监控协程中运行）
+// 决策中给出的止损止盈优先，否则沿用之前记录的价格（移动止损生效时使用其当前止损价）
+func (at *AutoTrader) replaceProtection(symbol, side string, quantity float64, d *decision.Decision) {
+	key := symbol + "_" + side
+
+	at.trailingMu.Lock()
+	current := at.protection[key]
+	ts := at.trailingStops[key]
+	if ts != nil {
+		current.StopLoss = ts.StopPrice
+		if ts.TakeProfit > 0 {
+			current.TakeProfit = ts.TakeProfit
+		}
+	}
+	if d.StopLoss > 0 {
+		current.StopLoss = d.StopLoss
+	}
+	if d.TakeProfit > 0 {
+		current.TakeProfit = d.TakeProfit
+	}
+	at.protection[key] = current
+	if ts != nil {
+		ts.StopPrice = current.StopLoss
+		ts.TakeProfit = current.TakeProfit
+	}
+	at.trailingMu.Unlock()
+
+	at.protectMu.Lock()
+	defer at.protectMu.Unlock()
+
+	// 交易所平仓/开仓后会撤销该方向的挂单，这里统一撤销后按新数量重挂
+	if err := at.cancelSideOrders(symbol, side); err != nil {
+		at.baseLog.Printf("  ⚠ 撤销旧止损止盈失败: %v", err)
+	}
+	if current.StopLoss <= 0 && current.TakeProfit <= 0 {
+		at.baseLog.Printf("  ⚠ %s %s 没有记录的止损止盈价，剩余仓位未设置保护", symbol, side)
+		return
+	}
+
+	if current.StopLoss > 0 {
+		if err := at.placeStopLoss(symbol, side, quantity, current.StopLoss); err != nil {
+			at.baseLog.Printf("  ⚠ 设置止损失败: %v", err)
+		}
+	}
+	if current.TakeProfit > 0 {
+		if err := at.placeTakeProfit(symbol, side, quantity, current.TakeProfit); err != nil {
+			at.baseLog.Printf("  ⚠ 设置止盈失败: %v", err)
+		}
+	}
+	at.baseLog.Printf("  🛡 %s %s 已按数量 %.4f 重新设置止损 %.4f / 止盈 %.4f", symbol, side, quantity, current.StopLoss, current.TakeProfit)
+}
+
+// executePartialClose 按close_fraction部分平仓（只减仓），剩余仓位保留移动止损并重新挂止损止盈
+func (at *AutoTrader) executePartialClose(d *decision.Decision, actionRecord *logger.DecisionAction, side string, price float64) error {
+	positionQty, err := at.positionQuantity(d.Symbol, side)
+	if err != nil {
+		return err
+	}
+	sideName := map[string]string{"long": "多", "short": "空"}[side]
+	if positionQty == 0 {
+		return fmt.Errorf("没有找到 %s 的%s仓", d.Symbol, sideName)
+	}
+
+	quantity := positionQty * d.CloseFraction
+	actionRecord.Quantity = quantity
+
+	order, err := at.exchange.ClosePosition(d.Symbol, side, quantity)
+	if err != nil {
+		return err
+	}
+	recordOrderID(actionRecord, order)
+	at.recordFill(actionRecord, order)
+	at.recordFee(actionRecord, order, quantity, actionRecord.Price)
+	at.recordTradeExit(d, side, quantity, actionRecord)
+
+	remaining, err := at.positionQuantity(d.Symbol, side)
+	if err != nil {
+		remaining = positionQty - quantity
+	}
+	at.log.Printf("  ✓ 部分平仓成功: %.0f%%，平仓数量 %.4f，剩余 %.4f", d.CloseFraction*100, quantity, remaining)
+	at.notify("✂️ %s 平%s %.0f%% @ %.4f | 数量: %.4f | 剩余: %.4f\n理由: %s",
+		d.Symbol, sideName, d.CloseFraction*100, price, quantity, remaining, d.Reasoning)
+
+	if remaining > 0 {
+		at.replaceProtection(d.Symbol, side, remaining, d)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package trader
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxScanIntervalMinutes 通过API可设置的最大扫描间隔（1天）
+const maxScanIntervalMinutes = 24 * 60
+
+// Profile trader的基本设置（可通过API在运行时修改，无需重新创建trader）
+type Profile struct {
+	Name                string  `json:"name"`
+	InitialBalance      float64 `json:"initial_balance"`       // 计算收益率的基准
+	ScanIntervalMinutes int     `json:"scan_interval_minutes"` // 决策周期间隔
+}
+
+// Validate 校验基本设置
+func (p Profile) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("name不能为空")
+	}
+	if p.InitialBalance <= 0 {
+		return fmt.Errorf("initial_balance必须大于0")
+	}
+	if p.ScanIntervalMinutes <= 0 || p.ScanIntervalMinutes > maxScanIntervalMinutes {
+		return fmt.Errorf("scan_interval_minutes必须在1-%d之间", maxScanIntervalMinutes)
+	}
+	return nil
+}
+
+// GetProfile 获取当前的基本设置
+func (at *AutoTrader) GetProfile() Profile {
+	at.profileMu.RLock()
+	defer at.profileMu.RUnlock()
+	return Profile{
+		Name:                at.name,
+		InitialBalance:      at.initialBalance,
+		ScanIntervalMinutes: int(at.config.ScanInterval / time.Minute),
+	}
+}
+
+// SetProfile 修改基本设置（扫描间隔立即生效，运行中的trader从现在开始按新间隔计时）
+func (at *AutoTrader) SetProfile(p Profile) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	interval := time.Duration(p.ScanIntervalMinutes) * time.Minute
+
+	at.profileMu.Lock()
+	intervalChanged := interval != at.config.ScanInterval
+	at.name = p.Name
+	at.initialBalance = p.InitialBalance
+	at.config.ScanInterval = interval
+	// 自适应模式的上限不能小于基础间隔（与配置文件默认值一致：基础间隔的4倍）
+	if at.config.MaxScanInterval < interval {
+		at.config.MaxScanInterval = interval * 4
+	}
+	at.profileMu.Unlock()
+
+	if intervalChanged {
+		select {
+		case at.intervalCh <- struct{}{}:
+		default:
+		}
+	}
+	at.baseLog.Printf("📝 基本设置已更新: 名称 %s | 初始余额 %.2f USDT | 扫描间隔 %v", p.Name, p.InitialBalance, interval)
+	return nil
+}
+
+// getInitialBalance 初始余额（计算收益率的基准）
+func (at *AutoTrader) getInitialBalance() float64 {
+	at.profileMu.RLock()
+	defer at.profileMu.RUnlock()
+	return at.initialBalance
+}
+
+// scanIntervals 基础扫描间隔和自适应模式的最大间隔
+func (at *AutoTrader) scanIntervals() (time.Duration, time.Duration) {
+	at.profileMu.RLock()
+	defer at.profileMu.RUnlock()
+	return at.config.ScanInterval, at.config.MaxScanInterval
+}
@@ -0,0 +1,31 @@
+package trader
+
+import "nofx/decision"
+
+// GetPromptTemplates 获取自定义的prompt模板（字段为空表示使用默认模板）
+func (at *AutoTrader) GetPromptTemplates() decision.PromptTemplates {
+	at.promptMu.RLock()
+	defer at.promptMu.RUnlock()
+	return at.prompts
+}
+
+// SetPromptTemplates 设置自定义的prompt模板（校验通过后下个周期生效，字段为空恢复默认模板）
+func (at *AutoTrader) SetPromptTemplates(prompts decision.PromptTemplates) error {
+	if err := prompts.Validate(); err != nil {
+		return err
+	}
+	at.promptMu.Lock()
+	at.prompts = prompts
+	at.promptMu.Unlock()
+
+	at.baseLog.Printf("📝 prompt模板已更新（system: %s, user: %s）", promptSource(prompts.System), promptSource(prompts.User))
+	return nil
+}
+
+// promptSource 模板来源描述
+func promptSource(tmpl string) string {
+	if tmpl == "" {
+		return "默认"
+	}
+	return "自定义"
+}
@@ -0,0 +1,128 @@
+package trader
+
+import (
+	"nofx/exchange"
+)
+
+// checkProtection 核对交易所上的止损止盈单（每个周期调用）：持仓缺少已记录的止损或止盈单时按当前数量补挂，
+// 撤销已没有对应持仓的止损止盈单，返回所做的修正（交易所不支持查询挂单时跳过）
+func (at *AutoTrader) checkProtection() []string {
+	lister, ok := at.exchange.(exchange.OpenOrderLister)
+	if !ok {
+		return nil
+	}
+	// 与决策执行、止损止盈撤销重挂串行，避免把正在重挂的保护单当成缺失
+	at.execMu.Lock()
+	defer at.execMu.Unlock()
+	at.protectMu.Lock()
+	defer at.protectMu.Unlock()
+
+	positions, err := at.exchange.GetPositions()
+	if err != nil {
+		at.log.Printf("⚠️  止损止盈检查: 获取持仓失败: %v", err)
+		return nil
+	}
+	orders, err := lister.GetOpenOrders()
+	if err != nil {
+		at.log.Printf("⚠️  止损止盈检查: %v", err)
+		return nil
+	}
+
+	open := make(map[string]exchange.Position) // symbol_side -> 持仓
+	current := make(map[string]bool)
+	hasPosition := make(map[string]bool)
+	for _, pos := range positions {
+		if pos.Quantity == 0 {
+			continue
+		}
+		key := pos.Symbol + "_" + pos.Side
+		open[key] = pos
+		current[key] = true
+		hasPosition[pos.Symbol] = true
+	}
+
+	// 交易所上已挂的保护单（单向持仓模式下的订单不区分方向）
+	placed := make(map[string]bool) // symbol_side_kind -> 已挂出
+	for _, o := range orders {
+		kind := o.ProtectionKind()
+		if kind == "" {
+			continue
+		}
+		if o.PositionSide != "" {
+			placed[o.Symbol+"_"+o.PositionSide+"_"+kind] = true
+		} else {
+			placed[o.Symbol+"_long_"+kind] = true
+			placed[o.Symbol+"_short_"+kind] = true
+		}
+	}
+
+	// 记录的止损止盈价（移动止损生效时以其当前止损价为准）
+	at.trailingMu.Lock()
+	expected := make(map[string]protectiveOrders)
+	for key, p := range at.protection {
+		if !current[key] {
+			continue
+		}
+		if ts := at.trailingStops[key]; ts != nil {
+			p.StopLoss = ts.StopPrice
+		}
+		expected[key] = p
+	}
+	at.trailingMu.Unlock()
+
+	fixes := at.cancelOrphanOrders(orders, current, hasPosition, true)
+	for key, p := range expected {
+		pos := open[key]
+		if p.StopLoss > 0 && !placed[key+"_"+exchange.OrderTypeStopLoss] {
+			if err := at.placeStopLoss(pos.Symbol, pos.Side, pos.Quantity, p.StopLoss); err != nil {
+				at.log.Printf("  ⚠ %s 止损单缺失，补挂失败: %v", key, err)
+			} else {
+				fixes = append(fixes, "补挂止损 "+key)
+			}
+		}
+		if p.TakeProfit > 0 && !placed[key+"_"+exchange.OrderTypeTakeProfit] {
+			if err := at.placeTakeProfit(pos.Symbol, pos.Side, pos.Quantity, p.TakeProfit); err != nil {
+				at.log.Printf("  ⚠ %s 止盈单缺失，补挂失败: %v", key, err)
+			} else {
+				fixes = append(fixes, "补挂止盈 "+key)
+			}
+		}
+	}
+
+	if len(fixes) > 0 {
+		at.log.Printf("🛡 止损止盈检查修正 %d 项: %v", len(fixes), fixes)
+		at.notify("🛡 止损止盈检查修正 %d 项: %v", len(fixes), fixes)
+	}
+	return fixes
+}
+
+// cancelProtectiveOrders 全部平仓后撤销该方向残留的止损止盈单（交易所平仓时不一定会撤销条件单）
+// 交易所不支持查询挂单或撤销单个订单时，由下个周期的止损止盈检查清理
+func (at *AutoTrader) cancelProtectiveOrders(symbol, side string) {
+	lister, ok := at.exchange.(exchange.OpenOrderLister)
+	if !ok {
+		return
+	}
+	tracker, ok := at.exchange.(exchange.OrderTracker)
+	if !ok {
+		return
+	}
+	at.protectMu.Lock()
+	defer at.protectMu.Unlock()
+
+	orders, err := lister.GetOpenOrders()
+	if err != nil {
+		at.log.Printf("  ⚠ 清理残留止损止盈单失败: %v", err)
+		return
+	}
+	for _, o := range orders {
+		if o.Symbol != symbol || !o.Protective || (o.PositionSide != "" && o.PositionSide != side) {
+			continue
+		}
+		if err := tracker.CancelOrder(o.Symbol, o.OrderID); err != nil {
+			at.log.Printf("  ⚠ 撤销残留的 %s %s 失败: %v", o.Symbol, o.Type, err)
+			continue
+		}
+		at.log.Printf("  ✓ 已撤销残留的 %s %s", o.Symbol, o.Type)
+	}
+}
@@ -0,0 +1,171 @@
+package trader
+
+import (
+	"nofx/exchange"
+	"time"
+)
+
+// reconcile 以交易所的实际持仓和挂单为准同步内部状态（启动时和下单失败后调用）：
+// 清理已不存在持仓的移动止损/止损止盈记录，撤销没有对应持仓的孤立止损止盈单和未被跟踪的开仓挂单，
+// 并提示没有止损保护的持仓（例如下单超时但实际已成交）
+func (at *AutoTrader) reconcile(reason string) {
+	positions, err := at.exchange.GetPositions()
+	if err != nil {
+		at.baseLog.Printf("⚠️  持仓对账失败（%s）: 获取持仓失败: %v", reason, err)
+		return
+	}
+
+	current := make(map[string]bool)     // symbol_side -> 存在持仓
+	hasPosition := make(map[string]bool) // symbol -> 任一方向存在持仓
+	for _, pos := range positions {
+		if pos.Quantity == 0 {
+			continue
+		}
+		current[pos.Symbol+"_"+pos.Side] = true
+		hasPosition[pos.Symbol] = true
+	}
+
+	var fixes []string
+
+	// 内部记录：以交易所持仓为准
+	at.trailingMu.Lock()
+	for key := range at.trailingStops {
+		if !current[key] {
+			delete(at.trailingStops, key)
+			fixes = append(fixes, "移除移动止损 "+key)
+		}
+	}
+	for key := range at.protection {
+		if !current[key] {
+			delete(at.protection, key)
+			fixes = append(fixes, "移除止损止盈记录 "+key)
+		}
+	}
+	var unprotected []string
+	for key := range current {
+		if _, ok := at.protection[key]; !ok {
+			unprotected = append(unprotected, key)
+		}
+	}
+	at.trailingMu.Unlock()
+
+	for key := range at.positionFirstSeenTime {
+		if !current[key] {
+			delete(at.positionFirstSeenTime, key)
+		}
+	}
+	for key := range current {
+		if _, ok := at.positionFirstSeenTime[key]; !ok {
+			at.positionFirstSeenTime[key] = time.Now().UnixMilli()
+		}
+	}
+
+	// 交易所挂单：撤销孤立的止损止盈单和未被跟踪的开仓挂单（无法查询挂单的交易所不检查持仓保护）
+	ot, ok := at.exchange.(exchange.OpenOrderLister)
+	if !ok {
+		unprotected = nil
+	} else {
+		orders, err := ot.GetOpenOrders()
+		if err != nil {
+			at.baseLog.Printf("⚠️  持仓对账（%s）: %v", reason, err)
+			unprotected = nil
+		} else {
+			fixes = append(fixes, at.cancelOrphanOrders(orders, current, hasPosition, false)...)
+			unprotected = withoutExchangeProtection(unprotected, orders)
+		}
+	}
+
+	// 交易所上有持仓但没有止损止盈（内部没有记录，交易所上也查不到保护单）
+	if len(unprotected) > 0 {
+		at.baseLog.Printf("⚠️  持仓对账（%s）: 以下持仓没有止损止盈保护，请人工确认: %v", reason, unprotected)
+		at.notify("⚠️ 以下持仓没有止损止盈保护，请人工确认: %v", unprotected)
+	}
+
+	if len(fixes) == 0 {
+		at.baseLog.Printf("✓ 持仓对账完成（%s）: %d 个持仓，内部状态一致", reason, len(current))
+		return
+	}
+	at.baseLog.Printf("🔄 持仓对账完成（%s）: %d 个持仓，修正 %d 项: %v", reason, len(current), len(fixes), fixes)
+	at.notify("🔄 持仓对账（%s）修正 %d 项: %v", reason, len(fixes), fixes)
+}
+
+// withoutExchangeProtection 过滤掉交易所上仍挂有止损止盈单的持仓
+func withoutExchangeProtection(keys []string, orders []exchange.OpenOrder) []string {
+	protected := make(map[string]bool)
+	for _, o := range orders {
+		if !o.Protective {
+			continue
+		}
+		if o.PositionSide != "" {
+			protected[o.Symbol+"_"+o.PositionSide] = true
+		} else {
+			protected[o.Symbol+"_long"] = true
+			protected[o.Symbol+"_short"] = true
+		}
+	}
+	var result []string
+	for _, key := range keys {
+		if !protected[key] {
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+// cancelOrphanOrders 撤销没有对应持仓的止损止盈单，以及不在待成交列表中的开仓挂单（成交后不会设置止损止盈）
+// protectiveOnly为true时只撤销止损止盈单，其他挂单一律保留
+func (at *AutoTrader) cancelOrphanOrders(orders []exchange.OpenOrder, current, hasPosition map[string]bool, protectiveOnly bool) []string {
+	at.pendingMu.Lock()
+	tracked := make(map[string]bool, len(at.pendingOrders))
+	for id := range at.pendingOrders {
+		tracked[id] = true
+	}
+	at.pendingMu.Unlock()
+
+	var orphans []exchange.OpenOrder
+	keep := make(map[string]bool) // symbol -> 有需要保留的挂单
+	for _, o := range orders {
+		var orphan bool
+		if o.Protective {
+			if o.PositionSide != "" {
+				orphan = !current[o.Symbol+"_"+o.PositionSide]
+			} else {
+				orphan = !hasPosition[o.Symbol]
+			}
+		} else {
+			orphan = !protectiveOnly && !tracked[o.OrderID]
+		}
+		if orphan {
+			orphans = append(orphans, o)
+		} else {
+			keep[o.Symbol] = true
+		}
+	}
+
+	var fixes []string
+	lt, canCancelOne := at.exchange.(exchange.OrderTracker)
+	cancelledAll := make(map[string]bool)
+	for _, o := range orphans {
+		var err error
+		switch {
+		case canCancelOne:
+			err = lt.CancelOrder(o.Symbol, o.OrderID)
+		case !keep[o.Symbol]:
+			// 不支持撤销单个订单时，只在该币种没有需要保留的挂单时整体撤销
+			if cancelledAll[o.Symbol] {
+				continue
+			}
+			cancelledAll[o.Symbol] = true
+			err = at.exchange.CancelAllOrders(o.Symbol)
+		default:
+			at.baseLog.Printf("  ⚠ %s 孤立挂单 %s(%s) 无法单独撤销", o.Symbol, o.OrderID, o.Type)
+			continue
+		}
+		if err != nil {
+			at.baseLog.Printf("  ⚠ 撤销孤立挂单 %s %s 失败: %v", o.Symbol, o.OrderID, err)
+			continue
+		}
+		fixes = append(fixes, "撤销孤立挂单 "+o.Symbol+" "+o.Type)
+	}
+	return fixes
+}
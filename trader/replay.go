@@ -0,0 +1,193 @@
+package trader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"nofx/decision"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrReplayUnavailable 周期不存在或没有保存上下文快照
+	ErrReplayUnavailable = errors.New("无法重放")
+	// ErrInvalidReplay 重放选项无效（模型、策略或prompt）
+	ErrInvalidReplay = errors.New("重放选项无效")
+)
+
+// ReplayOptions 重放历史周期时替换的模型、策略和prompt（为空的项沿用trader当前的设置）
+type ReplayOptions struct {
+	Cycle        int    `json:"cycle"`                   // 要重放的周期编号
+	AIModel      string `json:"ai_model,omitempty"`      // deepseek / qwen / openai / claude / custom
+	Model        string `json:"model,omitempty"`         // 模型名称（覆盖openai_model、claude_model或custom_model_name）
+	Strategy     string `json:"strategy,omitempty"`      // ai / rule / hybrid / ensemble
+	SystemPrompt string `json:"system_prompt,omitempty"` // 自定义System Prompt模板
+	UserPrompt   string `json:"user_prompt,omitempty"`   // 自定义User Prompt模板
+}
+
+// ReplaySide 对比中一方的决策
+type ReplaySide struct {
+	AIModel   string              `json:"ai_model,omitempty"` // 原始决策不记录模型，rule策略不使用模型，均为空
+	Strategy  string              `json:"strategy,omitempty"`
+	CoTTrace  string              `json:"cot_trace"`
+	Decisions []decision.Decision `json:"decisions"`
+}
+
+// ReplayDiff 同一币种双方的动作（多个决策时以逗号分隔）
+type ReplayDiff struct {
+	Symbol   string `json:"symbol"`
+	Original string `json:"original"` // 原始决策的动作（没有决策时为空）
+	Replay   string `json:"replay"`
+	Same     bool   `json:"same"`
+}
+
+// ReplayResult 用新的模型/策略/prompt重新运行历史周期的结果（假设性决策，不会执行）
+type ReplayResult struct {
+	Cycle      int          `json:"cycle"`
+	Timestamp  time.Time    `json:"timestamp"` // 原周期的决策时间
+	Original   ReplaySide   `json:"original"`
+	Replay     ReplaySide   `json:"replay"`
+	UserPrompt string       `json:"user_prompt"` // 重放时发送给AI的User Prompt
+	Diff       []ReplayDiff `json:"diff"`        // 按币种对比双方的动作
+}
+
+// Replay 用保存的上下文快照重新运行历史周期：行情、账户和持仓与当时完全一致，只替换模型、策略或prompt，
+// 返回假设性决策与原始决策的对比（不下单，也不写入决策记录，AI用量照常计入）
+func (at *AutoTrader) Replay(reqCtx context.Context, opts ReplayOptions) (*ReplayResult, error) {
+	record, err := at.decisionLogger.DecisionContext(opts.Cycle)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, fmt.Errorf("%w: 周期 #%d 不存在", ErrReplayUnavailable, opts.Cycle)
+	}
+	if len(record.Context) == 0 {
+		return nil, fmt.Errorf("%w: 周期 #%d 没有保存上下文快照", ErrReplayUnavailable, opts.Cycle)
+	}
+	var snapshot decision.ContextSnapshot
+	if err := json.Unmarshal(record.Context, &snapshot); err != nil {
+		return nil, fmt.Errorf("读取上下文快照失败: %w", err)
+	}
+	ctx := snapshot.Restore()
+	ctx.Ctx = reqCtx // 客户端断开时中止AI请求
+
+	prompts := decision.PromptTemplates{System: opts.SystemPrompt, User: opts.UserPrompt}
+	if prompts.System == "" && prompts.User == "" {
+		prompts = at.GetPromptTemplates()
+	} else if err := prompts.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReplay, err)
+	}
+	if prompts.System != "" || prompts.User != "" {
+		ctx.Prompts = &prompts
+	}
+
+	strategy, aiModel, err := at.replayStrategy(opts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReplay, err)
+	}
+	at.baseLog.Printf("🔁 重放周期 #%d（模型: %s，策略: %s）", opts.Cycle, aiModel, strategy.Name())
+	replayed, err := strategy.Decide(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("重放周期 #%d 失败: %w", opts.Cycle, err)
+	}
+
+	result := &ReplayResult{
+		Cycle:     record.CycleNumber,
+		Timestamp: record.Timestamp,
+		Original: ReplaySide{
+			CoTTrace:  record.CoTTrace,
+			Decisions: []decision.Decision{},
+		},
+		Replay: ReplaySide{
+			AIModel:   aiModel,
+			Strategy:  strategy.Name(),
+			CoTTrace:  replayed.CoTTrace,
+			Decisions: replayed.Decisions,
+		},
+		UserPrompt: replayed.UserPrompt,
+	}
+	if record.DecisionJSON != "" {
+		if err := json.Unmarshal([]byte(record.DecisionJSON), &result.Original.Decisions); err != nil {
+			return nil, fmt.Errorf("读取原始决策失败: %w", err)
+		}
+	}
+	if result.Replay.Decisions == nil {
+		result.Replay.Decisions = []decision.Decision{}
+	}
+	result.Diff = diffDecisions(result.Original.Decisions, result.Replay.Decisions)
+	return result, nil
+}
+
+// replayStrategy 按重放选项创建策略和AI客户端（AI用量计入当前trader），返回策略和使用的模型
+func (at *AutoTrader) replayStrategy(opts ReplayOptions) (decision.Strategy, string, error) {
+	config := at.config
+	if err := overrideModel(&config, opts.AIModel, opts.Model); err != nil {
+		return nil, "", err
+	}
+	name := opts.Strategy
+	if name == "" {
+		name = at.GetStrategy().Name()
+	}
+
+	client := NewAIClient(config)
+	if name != decision.StrategyRule && client.APIKey == "" {
+		return nil, "", fmt.Errorf("模型 '%s' 未配置API密钥", config.AIModel)
+	}
+	client.OnUsage = at.recordAIUsage
+	for _, c := range client.Fallbacks {
+		c.OnUsage = at.recordAIUsage
+	}
+	members := newEnsembleMembers(config)
+	for _, m := range members {
+		m.Client.OnUsage = at.recordAIUsage
+	}
+	strategy, err := decision.NewStrategy(name, decision.StrategyOptions{
+		MCPClient:      client,
+		EnsembleModels: members,
+		EnsembleQuorum: config.EnsembleQuorum,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if name == decision.StrategyRule {
+		return strategy, "", nil
+	}
+	aiModel := config.AIModel
+	if client.Model != "" {
+		aiModel += "/" + client.Model
+	}
+	return strategy, aiModel, nil
+}
+
+// diffDecisions 按币种对比两组决策的动作（按币种排序）
+func diffDecisions(original, replay []decision.Decision) []ReplayDiff {
+	actions := func(decisions []decision.Decision) map[string]string {
+		m := make(map[string]string)
+		for _, d := range decisions {
+			if m[d.Symbol] != "" {
+				m[d.Symbol] += ","
+			}
+			m[d.Symbol] += d.Action
+		}
+		return m
+	}
+	a, b := actions(original), actions(replay)
+	symbols := make([]string, 0, len(a)+len(b))
+	for s := range a {
+		symbols = append(symbols, s)
+	}
+	for s := range b {
+		if _, ok := a[s]; !ok {
+			symbols = append(symbols, s)
+		}
+	}
+	sort.Strings(symbols)
+
+	diff := make([]ReplayDiff, 0, len(symbols))
+	for _, s := range symbols {
+		diff = append(diff, ReplayDiff{Symbol: s, Original: a[s], Replay: b[s], Same: a[s] == b[s]})
+	}
+	return diff
+}
@@ -0,0 +1,192 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/decision"
+	"nofx/news"
+	"strings"
+	"time"
+)
+
+// defaultCorrelationThreshold 未设置correlation_threshold时视为高度相关的相关系数
+const defaultCorrelationThreshold = 0.8
+
+// RiskLimits 风控参数（可通过API在运行时修改）
+type RiskLimits struct {
+	MaxDailyLoss       float64 `json:"max_daily_loss"`       // 最大日亏损百分比（0表示不限制）
+	MaxDrawdown        float64 `json:"max_drawdown"`         // 最大回撤百分比（0表示不限制）
+	StopTradingMinutes int     `json:"stop_trading_minutes"` // 触发风控后暂停时长（分钟）
+
+	// 连续亏损冷却（独立于日亏损限制，只暂停开仓）
+	MaxConsecutiveLosses int     `json:"max_consecutive_losses"` // 连续亏损N笔后暂停开仓（0表示不限制）
+	LossWindowPct        float64 `json:"loss_window_pct"`        // loss_window_minutes内已平仓交易合计亏损达到净值的X%后暂停开仓（0表示不限制）
+	LossWindowMinutes    int     `json:"loss_window_minutes"`    // 亏损统计的时间窗口（分钟）
+	LossCooldownMinutes  int     `json:"loss_cooldown_minutes"`  // 暂停开仓时长（分钟）
+
+	// 相关性敞口限制（决策验证时拒绝超限的开仓）
+	MaxCorrelatedPositions int     `json:"max_correlated_positions"` // 同方向最多持有N个高度相关的仓位（含新仓位，0表示不限制）
+	CorrelationThreshold   float64 `json:"correlation_threshold"`    // 72小时收益率相关系数达到该值视为高度相关（0表示默认0.8）
+
+	// 集中度限制（下单前检查，按账户净值的倍数）
+	MaxSymbolExposure float64 `json:"max_symbol_exposure"` // 单个币种的名义价值上限（0表示不限制）
+	MaxNetExposure    float64 `json:"max_net_exposure"`    // 多头减空头的净名义价值上限（0表示不限制）
+
+	// 强平距离保护（下单前检查，止损离强平价过近时降低杠杆）
+	MinLiquidationBuffer float64 `json:"min_liquidation_buffer"` // 止损价与估算强平价之间的最小距离（入场价的%，0表示只要求止损在强平价之前）
+}
+
+// Validate 校验风控参数
+func (r RiskLimits) Validate() error {
+	if r.MaxDailyLoss < 0 || r.MaxDailyLoss > 100 {
+		return fmt.Errorf("max_daily_loss必须在0-100之间")
+	}
+	if r.MaxDrawdown < 0 || r.MaxDrawdown > 100 {
+		return fmt.Errorf("max_drawdown必须在0-100之间")
+	}
+	if r.StopTradingMinutes < 0 {
+		return fmt.Errorf("stop_trading_minutes不能为负数")
+	}
+	if r.MaxConsecutiveLosses < 0 || r.LossWindowPct < 0 || r.LossWindowMinutes < 0 || r.LossCooldownMinutes < 0 {
+		return fmt.Errorf("max_consecutive_losses/loss_window_pct/loss_window_minutes/loss_cooldown_minutes不能为负数")
+	}
+	if r.LossWindowPct > 0 && r.LossWindowMinutes == 0 {
+		return fmt.Errorf("设置loss_window_pct时必须设置loss_window_minutes")
+	}
+	if (r.MaxConsecutiveLosses > 0 || r.LossWindowPct > 0) && r.LossCooldownMinutes == 0 {
+		return fmt.Errorf("启用连续亏损冷却时必须设置loss_cooldown_minutes")
+	}
+	if r.MaxCorrelatedPositions < 0 {
+		return fmt.Errorf("max_correlated_positions不能为负数")
+	}
+	if r.CorrelationThreshold < 0 || r.CorrelationThreshold > 1 {
+		return fmt.Errorf("correlation_threshold必须在0-1之间")
+	}
+	if r.MaxSymbolExposure < 0 || r.MaxNetExposure < 0 {
+		return fmt.Errorf("max_symbol_exposure/max_net_exposure不能为负数")
+	}
+	if r.MinLiquidationBuffer < 0 || r.MinLiquidationBuffer >= 100 {
+		return fmt.Errorf("min_liquidation_buffer必须在0-100之间")
+	}
+	return nil
+}
+
+// GetRiskLimits 获取当前风控参数
+func (at *AutoTrader) GetRiskLimits() RiskLimits {
+	at.riskMu.RLock()
+	defer at.riskMu.RUnlock()
+	return RiskLimits{
+		MaxDailyLoss:       at.config.MaxDailyLoss,
+		MaxDrawdown:        at.config.MaxDrawdown,
+		StopTradingMinutes: int(at.config.StopTradingTime / time.Minute),
+
+		MaxConsecutiveLosses: at.config.MaxConsecutiveLosses,
+		LossWindowPct:        at.config.LossWindowPct,
+		LossWindowMinutes:    int(at.config.LossWindow / time.Minute),
+		LossCooldownMinutes:  int(at.config.LossCooldown / time.Minute),
+
+		MaxCorrelatedPositions: at.config.MaxCorrelatedPositions,
+		CorrelationThreshold:   at.config.CorrelationThreshold,
+
+		MaxSymbolExposure: at.config.MaxSymbolExposure,
+		MaxNetExposure:    at.config.MaxNetExposure,
+
+		MinLiquidationBuffer: at.config.MinLiquidationBuffer,
+	}
+}
+
+// SetRiskLimits 修改风控参数（下个周期生效，无需重启）
+func (at *AutoTrader) SetRiskLimits(limits RiskLimits) error {
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+	at.riskMu.Lock()
+	at.config.MaxDailyLoss = limits.MaxDailyLoss
+	at.config.MaxDrawdown = limits.MaxDrawdown
+	at.config.StopTradingTime = time.Duration(limits.StopTradingMinutes) * time.Minute
+	at.config.MaxConsecutiveLosses = limits.MaxConsecutiveLosses
+	at.config.LossWindowPct = limits.LossWindowPct
+	at.config.LossWindow = time.Duration(limits.LossWindowMinutes) * time.Minute
+	at.config.LossCooldown = time.Duration(limits.LossCooldownMinutes) * time.Minute
+	at.config.MaxCorrelatedPositions = limits.MaxCorrelatedPositions
+	at.config.CorrelationThreshold = limits.CorrelationThreshold
+	at.config.MaxSymbolExposure = limits.MaxSymbolExposure
+	at.config.MaxNetExposure = limits.MaxNetExposure
+	at.config.MinLiquidationBuffer = limits.MinLiquidationBuffer
+	at.riskMu.Unlock()
+
+	at.baseLog.Printf("🛡 风控参数已更新: 日亏损上限 %.1f%% | 回撤上限 %.1f%% | 暂停 %d 分钟",
+		limits.MaxDailyLoss, limits.MaxDrawdown, limits.StopTradingMinutes)
+	return nil
+}
+
+// checkRiskLimits 根据当前净值检查日亏损和回撤，超限时暂停交易并返回原因（未触发返回空字符串）
+func (at *AutoTrader) checkRiskLimits(equity float64) string {
+	if equity <= 0 {
+		return ""
+	}
+	if at.dayStartEquity == 0 {
+		at.dayStartEquity = equity
+	}
+	at.dailyPnL = equity - at.dayStartEquity
+	if equity > at.peakEquity {
+		at.peakEquity = equity
+	}
+
+	limits := at.GetRiskLimits()
+	if limits.StopTradingMinutes <= 0 {
+		return ""
+	}
+
+	reason := ""
+	dailyLossPct := -at.dailyPnL / at.dayStartEquity * 100
+	drawdownPct := (at.peakEquity - equity) / at.peakEquity * 100
+	if limits.MaxDailyLoss > 0 && dailyLossPct >= limits.MaxDailyLoss {
+		reason = fmt.Sprintf("日亏损 %.2f%% 超过上限 %.1f%%", dailyLossPct, limits.MaxDailyLoss)
+	} else if limits.MaxDrawdown > 0 && drawdownPct >= limits.MaxDrawdown {
+		reason = fmt.Sprintf("回撤 %.2f%% 超过上限 %.1f%%", drawdownPct, limits.MaxDrawdown)
+		// 回撤从当前净值重新计算，避免暂停结束后立即再次触发
+		at.peakEquity = equity
+	}
+	if reason == "" {
+		return ""
+	}
+
+	at.stopUntil = time.Now().Add(time.Duration(limits.StopTradingMinutes) * time.Minute)
+	return reason
+}
+
+// riskNotices 当前生效的风控限制（写入prompt，提示AI本周期不要给出会被拒绝的开仓决策）
+func (at *AutoTrader) riskNotices() []string {
+	var notices []string
+	if reason, active := at.entryCooldown(); active {
+		notices = append(notices, "亏损冷却: "+reason)
+	}
+	if reason, active := news.Blackout(time.Now()); active {
+		notices = append(notices, "重大事件暂停开仓: "+reason)
+	}
+	if limit := at.correlationLimit(); limit.MaxPositions > 0 {
+		notices = append(notices, fmt.Sprintf("相关性限制: 同方向最多持有%d个高度相关（72小时收益率相关系数≥%.2f）的仓位，超出的开仓会被拒绝",
+			limit.MaxPositions, limit.Threshold))
+	}
+	if limits := at.GetRiskLimits(); limits.MaxSymbolExposure > 0 || limits.MaxNetExposure > 0 {
+		var parts []string
+		if limits.MaxSymbolExposure > 0 {
+			parts = append(parts, fmt.Sprintf("单币种名义价值不超过净值的%.1f倍", limits.MaxSymbolExposure))
+		}
+		if limits.MaxNetExposure > 0 {
+			parts = append(parts, fmt.Sprintf("多空净敞口不超过净值的%.1f倍", limits.MaxNetExposure))
+		}
+		notices = append(notices, "集中度限制: "+strings.Join(parts, "，")+"（含已有持仓和未成交挂单），超出的开仓会被拒绝")
+	}
+	return notices
+}
+
+// correlationLimit 当前的相关性敞口限制（阈值为0时使用默认值）
+func (at *AutoTrader) correlationLimit() decision.CorrelationLimit {
+	limits := at.GetRiskLimits()
+	threshold := limits.CorrelationThreshold
+	if threshold == 0 {
+		threshold = defaultCorrelationThreshold
+	}
+	return decision.CorrelationLimit{MaxPositions: limits.MaxCorrelatedPositions, Threshold: threshold}
+}
@@ -0,0 +1,52 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/schedule"
+	"time"
+)
+
+// GetSchedule 获取当前的交易时段
+func (at *AutoTrader) GetSchedule() schedule.Schedule {
+	at.scheduleMu.RLock()
+	defer at.scheduleMu.RUnlock()
+	return at.config.Schedule
+}
+
+// SetSchedule 修改交易时段（下个周期生效）
+func (at *AutoTrader) SetSchedule(s schedule.Schedule) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	at.scheduleMu.Lock()
+	at.config.Schedule = s
+	at.scheduleMu.Unlock()
+
+	at.baseLog.Printf("🕒 交易时段已更新: %d 个时间窗口 | %d 个禁止时段", len(s.Windows), len(s.Blackouts))
+	return nil
+}
+
+// scheduleClosed 当前是否处于非交易时段（返回原因）
+func (at *AutoTrader) scheduleClosed() (string, bool) {
+	return at.GetSchedule().Closed(time.Now())
+}
+
+// checkSchedule 交易时段外禁止开仓：空仓时返回错误跳过本周期，有持仓时照常运行以便平仓（进入休市时推送一次通知）
+func (at *AutoTrader) checkSchedule() error {
+	reason, closed := at.scheduleClosed()
+	if !closed {
+		at.scheduleNotified = false
+		return nil
+	}
+	if !at.scheduleNotified {
+		at.scheduleNotified = true
+		at.notify("🕒 进入非交易时段，暂停开仓: %s", reason)
+	}
+
+	positions, err := at.exchange.GetPositions()
+	if err == nil && len(positions) == 0 {
+		return fmt.Errorf("非交易时段: %s", reason)
+	}
+	at.log.Printf("🕒 非交易时段（%s），本周期只允许平仓", reason)
+	return nil
+}
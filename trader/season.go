@@ -0,0 +1,97 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/decision"
+	"nofx/exchange"
+	"nofx/logger"
+	"strings"
+	"time"
+)
+
+// FlattenForSeasonEnd 赛季结束：撤销所有挂单并平掉全部持仓（trader须已停止，不锁定trader）
+func (at *AutoTrader) FlattenForSeasonEnd() (*PanicResult, error) {
+	if at.IsRunning() {
+		return nil, fmt.Errorf("trader '%s' 正在运行，请先停止", at.GetName())
+	}
+
+	result, err := at.closeAllPositions(logger.CloseReasonSeasonEnd)
+	if err != nil {
+		return result, err
+	}
+	at.baseLog.Printf("🏁 赛季结束平仓: 平仓 %d 个持仓，失败 %d 项", len(result.Closed), len(result.Errors))
+	if len(result.Errors) > 0 {
+		return result, fmt.Errorf("平仓未完成: %s", strings.Join(result.Errors, "; "))
+	}
+	return result, nil
+}
+
+// ResetSeason 以新的初始余额开始下一个赛季（trader须已停止且已平仓），返回新的初始余额
+// 模拟盘的账户恢复为当前设置的初始余额；实盘无法重置资金，以平仓后的净值作为新的初始余额
+// 运行时状态（周期计数、净值高点、亏损冷却、止损记录等）和买入持有基准全部清空
+func (at *AutoTrader) ResetSeason() (float64, error) {
+	if at.IsRunning() {
+		return 0, fmt.Errorf("trader '%s' 正在运行，请先停止", at.GetName())
+	}
+
+	balance := at.getInitialBalance()
+	if resetter, ok := at.exchange.(exchange.AccountResetter); ok {
+		if err := resetter.ResetAccount(balance); err != nil {
+			return 0, fmt.Errorf("重置账户失败: %w", err)
+		}
+	} else {
+		account, err := at.exchange.GetAccount()
+		if err != nil {
+			return 0, fmt.Errorf("获取账户余额失败: %w", err)
+		}
+		balance = account.WalletBalance + account.UnrealizedProfit
+	}
+
+	at.profileMu.Lock()
+	at.initialBalance = balance
+	at.profileMu.Unlock()
+
+	now := time.Now()
+	at.cycleMu.Lock()
+	at.callCount = 0
+	at.cycleMu.Unlock()
+	at.dailyPnL = 0
+	at.dayStartEquity = 0
+	at.peakEquity = balance
+	at.lastResetTime = now
+	at.stopUntil = time.Time{}
+	at.startTime = now
+	at.aiFailureCount = 0
+	at.positionFirstSeenTime = make(map[string]int64)
+	at.lastPositions = make(map[string]decision.PositionInfo)
+	at.stopOutTimes = make(map[string]time.Time)
+
+	at.riskMu.Lock()
+	at.lossStreak = 0
+	at.recentTrades = nil
+	at.entryCooldownUntil = time.Time{}
+	at.entryCooldownReason = ""
+	at.riskMu.Unlock()
+
+	at.trailingMu.Lock()
+	at.trailingStops = make(map[string]*TrailingStopState)
+	at.protection = make(map[string]protectiveOrders)
+	at.trailingMu.Unlock()
+
+	at.pendingMu.Lock()
+	at.pendingOrders = make(map[string]*PendingOrder)
+	at.pendingMu.Unlock()
+
+	// 买入持有基准从新赛季的第一个净值快照重新计算
+	at.benchmarkMu.Lock()
+	at.benchmark = nil
+	at.benchmarkMu.Unlock()
+	if err := logger.DeleteTraderSetting(at.id, benchmarkSetting); err != nil {
+		at.baseLog.Printf("⚠️  清除买入持有基准失败: %v", err)
+	}
+
+	at.saveRuntimeState()
+	at.baseLog.Printf("🏁 新赛季开始，初始余额 %.2f USDT", balance)
+	at.notify("🏁 新赛季开始，初始余额 %.2f USDT", balance)
+	return balance, nil
+}
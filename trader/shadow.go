@@ -0,0 +1,278 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/decision"
+	"nofx/logger"
+	"sort"
+	"time"
+)
+
+// shadowIDSuffix 影子trader的ID后缀（决策记录、交易和模拟盘状态都按该ID存储）
+const shadowIDSuffix = "_shadow"
+
+// ShadowConfig 影子trader与主trader的区别（为空的项沿用主trader）
+type ShadowConfig struct {
+	AIModel  string                   // AI模型
+	Model    string                   // 模型名称（openai/claude/custom）
+	Strategy string                   // 决策策略
+	Prompts  decision.PromptTemplates // 自定义prompt模板（为空时每个周期沿用主trader的模板）
+}
+
+// newShadow 创建影子trader：与主trader使用相同的配置和风控参数，只替换模型/策略/prompt，在模拟盘上执行
+func newShadow(config AutoTraderConfig) (*AutoTrader, error) {
+	s := config.Shadow
+	sc := config
+	sc.ID = config.ID + shadowIDSuffix
+	sc.Name = config.Name + " (影子)"
+	sc.Exchange = "paper"
+	sc.Shadow = nil
+	sc.DryRun = false
+	sc.OrderRetryAttempts = 0
+	sc.TelegramBotToken, sc.TelegramChatID = "", ""
+	sc.WebhookURL, sc.WebhookSecret = "", ""
+	if err := overrideModel(&sc, s.AIModel, s.Model); err != nil {
+		return nil, fmt.Errorf("影子trader配置无效: %w", err)
+	}
+	if s.Strategy != "" {
+		sc.Strategy = s.Strategy
+	}
+
+	shadow, err := NewAutoTrader(sc)
+	if err != nil {
+		return nil, fmt.Errorf("创建影子trader失败: %w", err)
+	}
+	if s.Prompts.System != "" || s.Prompts.User != "" {
+		if err := shadow.SetPromptTemplates(s.Prompts); err != nil {
+			return nil, fmt.Errorf("影子trader的prompt模板无效: %w", err)
+		}
+	}
+	if err := shadow.RestoreRuntimeState(); err != nil {
+		shadow.baseLog.Printf("⚠️  恢复影子trader运行时状态失败: %v", err)
+	}
+	return shadow, nil
+}
+
+// overrideModel 替换配置中的AI模型和模型名称（为空时沿用原配置）
+func overrideModel(config *AutoTraderConfig, aiModel, model string) error {
+	switch aiModel {
+	case "", "deepseek", "qwen", "openai", "claude", "custom":
+	default:
+		return fmt.Errorf("ai_model必须是 'qwen', 'deepseek', 'openai', 'claude' 或 'custom': %s", aiModel)
+	}
+	if aiModel != "" {
+		config.AIModel = aiModel
+		config.UseQwen = aiModel == "qwen"
+	}
+	if model != "" {
+		switch config.AIModel {
+		case "openai":
+			config.OpenAIModel = model
+		case "claude":
+			config.ClaudeModel = model
+		case "custom":
+			config.CustomModelName = model
+		default:
+			return fmt.Errorf("模型名称只适用于openai、claude和custom模型")
+		}
+	}
+	return nil
+}
+
+// HasShadow 是否挂载了影子trader
+func (at *AutoTrader) HasShadow() bool {
+	return at.shadow != nil
+}
+
+// startShadowCycle 用本周期的候选币种在后台运行影子trader的决策周期，返回等待其结束的函数（未配置影子trader时立即返回）
+func (at *AutoTrader) startShadowCycle(ctx *decision.Context) (wait func()) {
+	shadow := at.shadow
+	if shadow == nil {
+		return func() {}
+	}
+	// 未自定义prompt时与主trader使用相同的模板
+	if p := at.config.Shadow.Prompts; p.System == "" && p.User == "" {
+		shadow.promptMu.Lock()
+		shadow.prompts = at.GetPromptTemplates()
+		shadow.promptMu.Unlock()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		shadow.sharedCandidates = ctx.CandidateCoins
+		if err := shadow.runCycle(ctx.Ctx); err != nil {
+			shadow.log.Printf("❌ 影子trader执行失败: %v", err)
+		}
+	}()
+	return func() { <-done }
+}
+
+// ShadowSide 对比中一方的表现
+type ShadowSide struct {
+	TraderID     string  `json:"trader_id"`
+	AIModel      string  `json:"ai_model"`
+	Strategy     string  `json:"strategy"`
+	Equity       float64 `json:"equity"`  // 时间范围内最新的净值
+	PnLPct       float64 `json:"pnl_pct"` // 相对初始余额的收益率
+	ClosedTrades int     `json:"closed_trades"`
+	WinRate      float64 `json:"win_rate"`     // 已平仓交易胜率（%）
+	RealizedPnL  float64 `json:"realized_pnl"` // 已平仓交易的净盈亏合计
+}
+
+// ShadowBreakdown 按币种或市场状态对比已平仓交易的净盈亏（Diff为正表示影子trader更好）
+type ShadowBreakdown struct {
+	Name      string  `json:"name"`
+	LivePnL   float64 `json:"live_pnl"`
+	ShadowPnL float64 `json:"shadow_pnl"`
+	Diff      float64 `json:"diff"`
+}
+
+// ShadowPoint 同一时刻双方的收益率（影子trader取该时刻之前最近的快照）
+type ShadowPoint struct {
+	Timestamp    time.Time `json:"timestamp"`
+	LivePnLPct   float64   `json:"live_pnl_pct"`
+	ShadowPnLPct float64   `json:"shadow_pnl_pct"`
+}
+
+// ShadowComparison 主trader与影子trader的表现对比
+type ShadowComparison struct {
+	Live           ShadowSide        `json:"live"`
+	Shadow         ShadowSide        `json:"shadow"`
+	Outperformance float64           `json:"outperformance_pct"` // 影子trader收益率 - 主trader收益率
+	Symbols        []ShadowBreakdown `json:"symbols"`            // 按差值从大到小（影子trader表现更好的在前）
+	Regimes        []ShadowBreakdown `json:"regimes"`
+	Curve          []ShadowPoint     `json:"curve"`
+}
+
+// shadowData 一方在时间范围内的已平仓交易和净值快照
+type shadowData struct {
+	side      ShadowSide
+	trades    []logger.Trade
+	snapshots []logger.EquitySnapshot
+}
+
+// loadShadowData 读取trader在时间范围内的表现（f.Limit限制净值快照的数量，交易不受限制）
+func (at *AutoTrader) loadShadowData(f logger.RecordFilter) (*shadowData, error) {
+	snapshots, _, err := at.decisionLogger.QueryEquityHistory(f)
+	if err != nil {
+		return nil, err
+	}
+	trades, _, err := at.decisionLogger.QueryTrades(logger.RecordFilter{From: f.From, To: f.To}, "closed")
+	if err != nil {
+		return nil, err
+	}
+
+	initial := at.getInitialBalance()
+	data := &shadowData{
+		side: ShadowSide{
+			TraderID:     at.id,
+			AIModel:      at.aiModel,
+			Strategy:     at.GetStrategy().Name(),
+			ClosedTrades: len(trades),
+		},
+		trades:    trades,
+		snapshots: snapshots,
+	}
+	if len(snapshots) > 0 {
+		data.side.Equity = snapshots[len(snapshots)-1].TotalEquity
+		data.side.PnLPct = pnlPct(data.side.Equity, initial)
+	}
+	wins := 0
+	for _, t := range trades {
+		data.side.RealizedPnL += t.PnL
+		if t.PnL > 0 {
+			wins++
+		}
+	}
+	if len(trades) > 0 {
+		data.side.WinRate = float64(wins) / float64(len(trades)) * 100
+	}
+	return data, nil
+}
+
+// pnlPct 净值相对初始余额的收益率
+func pnlPct(equity, initial float64) float64 {
+	if initial <= 0 {
+		return 0
+	}
+	return (equity - initial) / initial * 100
+}
+
+// ShadowComparison 对比主trader和影子trader在时间范围内的表现
+func (at *AutoTrader) ShadowComparison(f logger.RecordFilter) (*ShadowComparison, error) {
+	if at.shadow == nil {
+		return nil, fmt.Errorf("trader '%s' 未配置影子trader", at.id)
+	}
+	live, err := at.loadShadowData(f)
+	if err != nil {
+		return nil, err
+	}
+	shadow, err := at.shadow.loadShadowData(f)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ShadowComparison{
+		Live:           live.side,
+		Shadow:         shadow.side,
+		Outperformance: shadow.side.PnLPct - live.side.PnLPct,
+		Symbols:        compareTrades(live.trades, shadow.trades, func(t logger.Trade) string { return t.Symbol }),
+		Regimes: compareTrades(live.trades, shadow.trades, func(t logger.Trade) string {
+			if t.Regime == "" {
+				return "unknown"
+			}
+			return t.Regime
+		}),
+		Curve: []ShadowPoint{},
+	}
+
+	liveInitial, shadowInitial := at.getInitialBalance(), at.shadow.getInitialBalance()
+	j := -1
+	for _, snap := range live.snapshots {
+		for j+1 < len(shadow.snapshots) && !shadow.snapshots[j+1].Timestamp.After(snap.Timestamp) {
+			j++
+		}
+		if j < 0 {
+			continue
+		}
+		result.Curve = append(result.Curve, ShadowPoint{
+			Timestamp:    snap.Timestamp,
+			LivePnLPct:   pnlPct(snap.TotalEquity, liveInitial),
+			ShadowPnLPct: pnlPct(shadow.snapshots[j].TotalEquity, shadowInitial),
+		})
+	}
+	return result, nil
+}
+
+// compareTrades 按key汇总双方已平仓交易的净盈亏，按差值从大到小排序
+func compareTrades(live, shadow []logger.Trade, key func(logger.Trade) string) []ShadowBreakdown {
+	byName := make(map[string]*ShadowBreakdown)
+	get := func(name string) *ShadowBreakdown {
+		b, ok := byName[name]
+		if !ok {
+			b = &ShadowBreakdown{Name: name}
+			byName[name] = b
+		}
+		return b
+	}
+	for _, t := range live {
+		get(key(t)).LivePnL += t.PnL
+	}
+	for _, t := range shadow {
+		get(key(t)).ShadowPnL += t.PnL
+	}
+
+	result := make([]ShadowBreakdown, 0, len(byName))
+	for _, b := range byName {
+		b.Diff = b.ShadowPnL - b.LivePnL
+		result = append(result, *b)
+	}
+	sort.Slice(result, func(i, k int) bool {
+		if result[i].Diff != result[k].Diff {
+			return result[i].Diff > result[k].Diff
+		}
+		return result[i].Name < result[k].Name
+	})
+	return result
+}
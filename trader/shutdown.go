@@ -0,0 +1,70 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/exchange"
+	"strings"
+	"time"
+)
+
+// Shutdown 停止trader并等待正在执行的决策周期和下单完成（最多等待timeout），
+// 之后保存运行时状态；cancelOrders为true时撤销交易所上的止损止盈单和未成交的限价开仓单
+func (at *AutoTrader) Shutdown(timeout time.Duration, cancelOrders bool) error {
+	at.runMu.Lock()
+	doneCh := at.doneCh
+	at.runMu.Unlock()
+
+	at.Stop()
+
+	var err error
+	if doneCh != nil {
+		select {
+		case <-doneCh:
+		case <-time.After(timeout):
+			err = fmt.Errorf("等待当前周期结束超时（%v）", timeout)
+		}
+	}
+
+	if cancelOrders {
+		at.cancelOpenOrders()
+	}
+	at.saveRuntimeState()
+	return err
+}
+
+// cancelOpenOrders 撤销交易所上的条件单和限价开仓单（退出时可选，持仓将不再受止损保护）
+func (at *AutoTrader) cancelOpenOrders() {
+	symbols := make(map[string]bool)
+	if ot, ok := at.exchange.(exchange.OpenOrderLister); ok {
+		orders, err := ot.GetOpenOrders()
+		if err != nil {
+			at.baseLog.Printf("⚠️  %v", err)
+		}
+		for _, o := range orders {
+			symbols[o.Symbol] = true
+		}
+	}
+	// 无法查询挂单时按内部记录的持仓保护和限价单撤销
+	at.trailingMu.Lock()
+	for key := range at.protection {
+		symbols[key[:strings.LastIndex(key, "_")]] = true
+	}
+	at.trailingMu.Unlock()
+	at.pendingMu.Lock()
+	for _, po := range at.pendingOrders {
+		symbols[po.Symbol] = true
+	}
+	at.pendingOrders = make(map[string]*PendingOrder)
+	at.pendingMu.Unlock()
+
+	for symbol := range symbols {
+		if err := at.exchange.CancelAllOrders(symbol); err != nil {
+			at.baseLog.Printf("  ⚠ 撤销 %s 挂单失败: %v", symbol, err)
+			continue
+		}
+		at.baseLog.Printf("  ✓ 已撤销 %s 的所有挂单", symbol)
+	}
+	if len(symbols) > 0 {
+		at.notify("⏹ 退出时已撤销 %d 个币种的挂单，持仓不再受止损止盈保护", len(symbols))
+	}
+}
@@ -0,0 +1,67 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"nofx/decision"
+	"nofx/market"
+)
+
+// ErrInvalidSimulation 测算参数无效（币种、方向、杠杆、仓位或止损止盈）
+var ErrInvalidSimulation = errors.New("测算参数无效")
+
+// Simulate 按最新行情和账户净值测算一笔假设的开仓（不下单）：保证金、强平价、风险金额和风险回报比
+// 执行时会被风控拒绝的情况（杠杆超限、敞口超限、现货做空等）写入Warnings，不返回错误
+func (at *AutoTrader) Simulate(o ManualOrder) (*decision.Simulation, error) {
+	d, err := o.decision(true, "simulate")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSimulation, err)
+	}
+	if d.Leverage <= 0 || d.PositionSizeUSD <= 0 {
+		return nil, fmt.Errorf("%w: 杠杆和position_size_usd必须大于0", ErrInvalidSimulation)
+	}
+	if d.StopLoss <= 0 || d.TakeProfit <= 0 {
+		return nil, fmt.Errorf("%w: 止损和止盈必须大于0", ErrInvalidSimulation)
+	}
+
+	marketData, err := market.GetWithTimeframes(d.Symbol, at.config.Timeframes)
+	if err != nil {
+		return nil, fmt.Errorf("获取 %s 行情失败: %w", d.Symbol, err)
+	}
+	spread := 0.0
+	if orderBook, err := market.GetOrderBook(d.Symbol); err == nil {
+		spread = orderBook.BestAsk - orderBook.BestBid
+	}
+	equity := 0.0
+	if account, err := at.exchange.GetAccount(); err == nil {
+		equity = account.WalletBalance + account.UnrealizedProfit
+	}
+
+	sim, err := decision.Simulate(d, marketData.CurrentPrice, spread, equity, estimatedFeeRate, at.liquidationRules(&d))
+	if err != nil {
+		return nil, err
+	}
+
+	maxLeverage := at.config.AltcoinLeverage
+	if d.Symbol == "BTCUSDT" || d.Symbol == "ETHUSDT" {
+		maxLeverage = at.config.BTCETHLeverage
+	}
+	if d.Leverage > maxLeverage {
+		sim.Warnings = append(sim.Warnings, fmt.Sprintf("杠杆 %dx 超过 %s 的上限 %dx", d.Leverage, d.Symbol, maxLeverage))
+	}
+	if d.Action == "open_short" && at.IsSpot() {
+		sim.Warnings = append(sim.Warnings, "现货交易不支持做空")
+	}
+	if equity <= 0 {
+		sim.Warnings = append(sim.Warnings, "无法获取账户净值，未计算风险占比")
+	} else if sim.MarginRequired > equity {
+		sim.Warnings = append(sim.Warnings, fmt.Sprintf("所需保证金 %.2f USDT 超过账户净值 %.2f USDT", sim.MarginRequired, equity))
+	}
+	if err := at.checkConcentration(&d, o.Side, sim.Quantity, sim.EntryPrice); err != nil {
+		sim.Warnings = append(sim.Warnings, err.Error())
+	}
+	if at.needsApproval(&d) {
+		sim.Warnings = append(sim.Warnings, fmt.Sprintf("仓位 %.2f USDT 达到审批阈值，AI开仓需人工审批", d.PositionSizeUSD))
+	}
+	return sim, nil
+}
@@ -0,0 +1,57 @@
+package trader
+
+import (
+	"nofx/exchange"
+	"nofx/logger"
+	"time"
+)
+
+// slippageWarnBps 近期平均滑点超过该值（基点）时在prompt中提醒AI
+const slippageWarnBps = 10.0
+
+// slippageWindow 统计平均滑点的时间窗口
+const slippageWindow = 7 * 24 * time.Hour
+
+// recordFill 记录成交价和相对决策价格的滑点（交易所不返回成交均价时保留下单前的最新价）
+func (at *AutoTrader) recordFill(actionRecord *logger.DecisionAction, order *exchange.Order) {
+	fillPrice := order.AvgPrice
+	if fillPrice <= 0 && order.OrderID != "" {
+		if ot, ok := at.exchange.(exchange.OrderTracker); ok {
+			if status, err := ot.GetOrder(actionRecord.Symbol, order.OrderID); err == nil {
+				fillPrice = status.AvgPrice
+			}
+		}
+	}
+	if fillPrice > 0 {
+		actionRecord.Price = fillPrice
+	}
+
+	if actionRecord.DecisionPrice <= 0 || actionRecord.Price <= 0 {
+		return
+	}
+	slippage := (actionRecord.Price - actionRecord.DecisionPrice) / actionRecord.DecisionPrice * 10000
+	// 买入（开多/平空）成交价越高越差，卖出相反
+	if actionRecord.Action == "open_short" || actionRecord.Action == "close_long" {
+		slippage = -slippage
+	}
+	actionRecord.SlippageBps = slippage
+	if slippage >= slippageWarnBps {
+		at.log.Printf("  ⚠ %s 滑点 %.1fbps（决策价 %.4f，成交价 %.4f）", actionRecord.Symbol, slippage, actionRecord.DecisionPrice, actionRecord.Price)
+	}
+}
+
+// slippageWarnings 近期平均滑点超过警告阈值的币种（写入prompt）
+func (at *AutoTrader) slippageWarnings() map[string]float64 {
+	stats, err := at.decisionLogger.SlippageBySymbol(time.Now().Add(-slippageWindow))
+	if err != nil {
+		at.log.Printf("⚠️  查询滑点统计失败: %v", err)
+		return nil
+	}
+	warnings := make(map[string]float64)
+	for _, s := range stats {
+		if s.AvgBps >= slippageWarnBps {
+			warnings[s.Symbol] = s.AvgBps
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,16 @@
+package trader
+
+import (
+	"encoding/json"
+	"nofx/decision"
+)
+
+// snapshotContext 序列化本周期决策使用的完整上下文（随决策记录保存，序列化失败时不保存）
+func (at *AutoTrader) snapshotContext(ctx *decision.Context) json.RawMessage {
+	data, err := json.Marshal(decision.NewContextSnapshot(ctx))
+	if err != nil {
+		at.log.Printf("⚠️  保存上下文快照失败: %v", err)
+		return nil
+	}
+	return data
+}
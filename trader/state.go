@@ -0,0 +1,152 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/decision"
+	"nofx/logger"
+	"time"
+)
+
+// runtimeStateSetting trader_settings中保存运行时状态的键名
+const runtimeStateSetting = "runtime_state"
+
+// runtimeState 每个周期持久化的运行时状态（进程重启后恢复，避免计数器和风控状态被重置）
+type runtimeState struct {
+	SavedAt        time.Time `json:"saved_at"`
+	CallCount      int       `json:"call_count"`
+	DayStartEquity float64   `json:"day_start_equity"`
+	PeakEquity     float64   `json:"peak_equity"`
+	LastResetTime  time.Time `json:"last_reset_time"`
+	StopUntil      time.Time `json:"stop_until"` // 风控暂停截止时间
+
+	LossStreak          int           `json:"loss_streak"`           // 当前连续亏损笔数
+	RecentTrades        []tradeResult `json:"recent_trades"`         // 亏损时间窗口内的已平仓交易
+	EntryCooldownUntil  time.Time     `json:"entry_cooldown_until"`  // 亏损冷却截止时间
+	EntryCooldownReason string        `json:"entry_cooldown_reason"` // 亏损冷却原因
+
+	StopOutTimes      map[string]time.Time             `json:"stop_out_times"`      // symbol_side -> 最近一次被止损的时间
+	PositionFirstSeen map[string]int64                 `json:"position_first_seen"` // symbol_side -> 开仓时间（毫秒）
+	LastPositions     map[string]decision.PositionInfo `json:"last_positions"`      // 上个周期的持仓快照（检测停机期间的止损）
+	TrailingStops     map[string]*TrailingStopState    `json:"trailing_stops"`
+	Protection        map[string]persistedProtection   `json:"protection"`
+	PendingOrders     []persistedPendingOrder          `json:"pending_orders"`
+}
+
+// persistedProtection 持仓的止损止盈价
+type persistedProtection struct {
+	StopLoss   float64 `json:"stop_loss"`
+	TakeProfit float64 `json:"take_profit"`
+}
+
+// persistedPendingOrder 未成交的限价开仓单（连同原始决策，成交后用于设置止损止盈）
+type persistedPendingOrder struct {
+	PendingOrder
+	Decision decision.Decision `json:"decision"`
+}
+
+// saveRuntimeState 保存运行时状态（每个周期结束时调用）
+func (at *AutoTrader) saveRuntimeState() {
+	state := runtimeState{
+		SavedAt:           time.Now(),
+		CallCount:         at.cycleNumber(),
+		DayStartEquity:    at.dayStartEquity,
+		PeakEquity:        at.peakEquity,
+		LastResetTime:     at.lastResetTime,
+		StopUntil:         at.stopUntil,
+		StopOutTimes:      at.stopOutTimes,
+		PositionFirstSeen: at.positionFirstSeenTime,
+		LastPositions:     at.lastPositions,
+		TrailingStops:     make(map[string]*TrailingStopState),
+		Protection:        make(map[string]persistedProtection),
+	}
+
+	at.riskMu.RLock()
+	state.LossStreak = at.lossStreak
+	state.RecentTrades = append([]tradeResult(nil), at.recentTrades...)
+	state.EntryCooldownUntil = at.entryCooldownUntil
+	state.EntryCooldownReason = at.entryCooldownReason
+	at.riskMu.RUnlock()
+
+	at.trailingMu.Lock()
+	for key, ts := range at.trailingStops {
+		copied := *ts
+		state.TrailingStops[key] = &copied
+	}
+	for key, p := range at.protection {
+		state.Protection[key] = persistedProtection{StopLoss: p.StopLoss, TakeProfit: p.TakeProfit}
+	}
+	at.trailingMu.Unlock()
+
+	at.pendingMu.Lock()
+	for _, po := range at.pendingOrders {
+		state.PendingOrders = append(state.PendingOrders, persistedPendingOrder{PendingOrder: *po, Decision: po.decision})
+	}
+	at.pendingMu.Unlock()
+
+	if err := logger.SaveTraderSetting(at.id, runtimeStateSetting, state); err != nil {
+		at.baseLog.Printf("⚠️  保存运行时状态失败: %v", err)
+	}
+}
+
+// RestoreRuntimeState 恢复上次保存的运行时状态（启动前调用，没有保存过时不做任何事）
+func (at *AutoTrader) RestoreRuntimeState() error {
+	var state runtimeState
+	found, err := logger.LoadTraderSetting(at.id, runtimeStateSetting, &state)
+	if err != nil {
+		return fmt.Errorf("读取运行时状态失败: %w", err)
+	}
+	if !found {
+		return nil
+	}
+
+	at.cycleMu.Lock()
+	at.callCount = state.CallCount
+	at.cycleMu.Unlock()
+	at.dayStartEquity = state.DayStartEquity
+	if state.PeakEquity > 0 {
+		at.peakEquity = state.PeakEquity
+	}
+	if !state.LastResetTime.IsZero() {
+		at.lastResetTime = state.LastResetTime
+	}
+	at.stopUntil = state.StopUntil
+	at.riskMu.Lock()
+	at.lossStreak = state.LossStreak
+	at.recentTrades = state.RecentTrades
+	at.entryCooldownUntil = state.EntryCooldownUntil
+	at.entryCooldownReason = state.EntryCooldownReason
+	at.riskMu.Unlock()
+	for key, t := range state.StopOutTimes {
+		at.stopOutTimes[key] = t
+	}
+	for key, t := range state.PositionFirstSeen {
+		at.positionFirstSeenTime[key] = t
+	}
+	for key, pos := range state.LastPositions {
+		at.lastPositions[key] = pos
+	}
+
+	at.trailingMu.Lock()
+	for key, ts := range state.TrailingStops {
+		at.trailingStops[key] = ts
+	}
+	for key, p := range state.Protection {
+		at.protection[key] = protectiveOrders{StopLoss: p.StopLoss, TakeProfit: p.TakeProfit}
+	}
+	at.trailingMu.Unlock()
+
+	at.pendingMu.Lock()
+	for _, p := range state.PendingOrders {
+		po := p.PendingOrder
+		po.decision = p.Decision
+		at.pendingOrders[po.OrderID] = &po
+	}
+	at.pendingMu.Unlock()
+
+	at.baseLog.Printf("♻️  已恢复 %s 保存的运行时状态: 周期 #%d | 持仓记录 %d | 限价单 %d | 移动止损 %d",
+		state.SavedAt.Format("2006-01-02 15:04:05"), state.CallCount, len(state.PositionFirstSeen), len(state.PendingOrders), len(state.TrailingStops))
+	if time.Now().Before(at.stopUntil) {
+		at.baseLog.Printf("⏸ 风控暂停仍在生效，暂停至 %s", at.stopUntil.Format("2006-01-02 15:04"))
+	}
+	return nil
+}
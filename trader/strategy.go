@@ -0,0 +1,24 @@
+package trader
+
+import "nofx/decision"
+
+// GetStrategy 获取当前决策策略
+func (at *AutoTrader) GetStrategy() decision.Strategy {
+	at.strategyMu.RLock()
+	defer at.strategyMu.RUnlock()
+	return at.strategy
+}
+
+// SetStrategy 切换决策策略（ai/rule/hybrid/ensemble，下个周期生效，无需重启）
+func (at *AutoTrader) SetStrategy(name string) error {
+	strategy, err := decision.NewStrategy(name, at.strategyOpts)
+	if err != nil {
+		return err
+	}
+	at.strategyMu.Lock()
+	at.strategy = strategy
+	at.strategyMu.Unlock()
+
+	at.baseLog.Printf("🧭 决策策略已切换为: %s", strategy.Name())
+	return nil
+}
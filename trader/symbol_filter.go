@@ -0,0 +1,24 @@
+package trader
+
+import "nofx/pool"
+
+// GetSymbolFilter 获取当前的币种黑白名单
+func (at *AutoTrader) GetSymbolFilter() pool.SymbolFilter {
+	at.symbolFilterMu.RLock()
+	defer at.symbolFilterMu.RUnlock()
+	return at.config.SymbolFilter
+}
+
+// SetSymbolFilter 修改币种黑白名单（下个周期生效，已有持仓不受影响，仍可平仓）
+func (at *AutoTrader) SetSymbolFilter(filter pool.SymbolFilter) error {
+	if err := filter.Validate(); err != nil {
+		return err
+	}
+	filter = filter.Normalize()
+	at.symbolFilterMu.Lock()
+	at.config.SymbolFilter = filter
+	at.symbolFilterMu.Unlock()
+
+	at.baseLog.Printf("🚦 币种黑白名单已更新: 白名单 %v | 黑名单 %v", filter.Whitelist, filter.Blacklist)
+	return nil
+}
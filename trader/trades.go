@@ -0,0 +1,141 @@
+package trader
+
+import (
+	"math"
+	"nofx/decision"
+	"nofx/exchange"
+	"nofx/logger"
+	"nofx/market"
+	"strings"
+	"time"
+)
+
+// thesisMaxRunes 写入prompt的开仓理由最大长度（字符）
+const thesisMaxRunes = 300
+
+// recordTradeEntry 开仓（或加仓）成交后记录交易，保存开仓决策的理由、信心度和开仓时的形态向量
+// marketData为nil时（如限价单成交）重新获取行情，获取失败时不保存形态
+func (at *AutoTrader) recordTradeEntry(d *decision.Decision, side string, quantity, price, fee float64, marketData *market.Data) {
+	if marketData == nil && at.config.SimilarSetups > 0 {
+		marketData, _ = market.GetWithTimeframes(d.Symbol, at.config.Timeframes)
+	}
+	err := at.decisionLogger.RecordTradeEntry(logger.TradeEntry{
+		Symbol:     d.Symbol,
+		Side:       side,
+		Time:       time.Now(),
+		Price:      price,
+		Quantity:   quantity,
+		Leverage:   d.Leverage,
+		AIModel:    at.aiModel,
+		Regime:     at.cycleRegime,
+		Confidence: d.Confidence,
+		Reasoning:  d.Reasoning,
+		StopLoss:   d.StopLoss,
+		TakeProfit: d.TakeProfit,
+		Fee:        fee,
+		Setup:      market.SetupVector(marketData),
+	})
+	if err != nil {
+		at.baseLog.Printf("⚠️  记录 %s %s 开仓交易失败: %v", d.Symbol, side, err)
+	}
+}
+
+// positionTheses 未平仓交易的开仓逻辑（symbol_side -> 开仓理由、止损止盈和信心度，读取失败时为nil）
+func (at *AutoTrader) positionTheses() map[string]*decision.PositionThesis {
+	trades, _, err := at.decisionLogger.QueryTrades(logger.RecordFilter{}, "open")
+	if err != nil {
+		at.log.Printf("⚠️  读取未平仓交易失败: %v", err)
+		return nil
+	}
+	theses := make(map[string]*decision.PositionThesis, len(trades))
+	for _, t := range trades {
+		reasoning := []rune(strings.Join(strings.Fields(t.Reasoning), " "))
+		if len(reasoning) > thesisMaxRunes {
+			reasoning = append(reasoning[:thesisMaxRunes], '…')
+		}
+		theses[t.Symbol+"_"+t.Side] = &decision.PositionThesis{
+			Reasoning:  string(reasoning),
+			StopLoss:   t.StopLoss,
+			TakeProfit: t.TakeProfit,
+			Confidence: t.Confidence,
+			OpenedAt:   t.OpenTime,
+		}
+	}
+	return theses
+}
+
+// recordTradeExit AI或操作员平仓（quantity为0表示全部平仓）后更新交易记录
+func (at *AutoTrader) recordTradeExit(d *decision.Decision, side string, quantity float64, actionRecord *logger.DecisionAction) {
+	reason := logger.CloseReasonAI
+	if actionRecord.Manual {
+		reason = logger.CloseReasonManual
+	}
+	at.saveTradeExit(logger.TradeExit{
+		Symbol:    d.Symbol,
+		Side:      side,
+		Time:      time.Now(),
+		Price:     actionRecord.Price,
+		Quantity:  quantity,
+		Fee:       actionRecord.Fee,
+		Funding:   actionRecord.Funding,
+		Reason:    reason,
+		Reasoning: d.Reasoning,
+	})
+}
+
+// recordTriggeredExit 持仓被交易所止损/止盈单平掉后结束交易记录
+// 按最后的标记价格离哪个挂单价更近判断触发的是止损还是止盈（移动止损在盈利时触发也算止损），成交价按触发价估算
+func (at *AutoTrader) recordTriggeredExit(pos decision.PositionInfo) {
+	at.trailingMu.Lock()
+	levels := at.protection[pos.Symbol+"_"+pos.Side]
+	at.trailingMu.Unlock()
+
+	stopLoss := pos.UnrealizedPnL < 0
+	if levels.StopLoss > 0 && levels.TakeProfit > 0 {
+		stopLoss = math.Abs(pos.MarkPrice-levels.StopLoss) < math.Abs(pos.MarkPrice-levels.TakeProfit)
+	} else if levels.StopLoss > 0 || levels.TakeProfit > 0 {
+		stopLoss = levels.StopLoss > 0
+	}
+
+	reason, price := logger.CloseReasonTakeProfit, levels.TakeProfit
+	if stopLoss {
+		reason, price = logger.CloseReasonStopLoss, levels.StopLoss
+	}
+	if price <= 0 {
+		price = pos.MarkPrice
+	}
+	at.saveTradeExit(logger.TradeExit{
+		Symbol: pos.Symbol,
+		Side:   pos.Side,
+		Time:   time.Now(),
+		Price:  price,
+		Fee:    pos.Quantity * price * estimatedFeeRate,
+		Reason: reason,
+	})
+}
+
+// recordForcedExit 紧急平仓或赛季结束平仓后结束交易记录（交易所不返回成交价时使用标记价格，手续费按估算费率）
+func (at *AutoTrader) recordForcedExit(pos exchange.Position, order *exchange.Order, reason string) {
+	price, fee := order.AvgPrice, order.Fee
+	if price <= 0 {
+		price = pos.MarkPrice
+	}
+	if fee <= 0 {
+		fee = pos.Quantity * price * estimatedFeeRate
+	}
+	at.saveTradeExit(logger.TradeExit{
+		Symbol: pos.Symbol,
+		Side:   pos.Side,
+		Time:   time.Now(),
+		Price:  price,
+		Fee:    fee,
+		Reason: reason,
+	})
+}
+
+// saveTradeExit 写入平仓记录（失败只记录日志，不影响交易流程）
+func (at *AutoTrader) saveTradeExit(exit logger.TradeExit) {
+	if err := at.decisionLogger.RecordTradeExit(exit); err != nil {
+		at.baseLog.Printf("⚠️  记录 %s %s 平仓交易失败: %v", exit.Symbol, exit.Side, err)
+	}
+}
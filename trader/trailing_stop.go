@@ -0,0 +1,187 @@
+package trader
+
+import (
+	"fmt"
+	"math"
+	"nofx/decision"
+	"nofx/market"
+	"time"
+)
+
+const (
+	trailingStopCheckInterval = 30 * time.Second // 移动止损检查间隔
+	trailingStopMinMovePct    = 0.1              // 止损价至少移动0.1%才更新交易所订单，避免频繁撤单重挂
+)
+
+// TrailingStopState 单个持仓的移动止损状态
+type TrailingStopState struct {
+	Symbol     string  `json:"symbol"`
+	Side       string  `json:"side"`        // long/short
+	Mode       string  `json:"mode"`        // percent/atr
+	Value      float64 `json:"value"`       // 百分比或ATR倍数
+	Distance   float64 `json:"distance"`    // 止损距最优价格的价格距离（atr模式下开仓时固定）
+	BestPrice  float64 `json:"best_price"`  // 开仓以来的最优价格（多仓最高价，空仓最低价）
+	StopPrice  float64 `json:"stop_price"`  // 当前交易所止损价
+	TakeProfit float64 `json:"take_profit"` // 止盈价（撤单后需要重新挂上）
+}
+
+// registerTrailingStop 开仓成功后登记移动止损（决策未要求时忽略；限价单成交时在监控协程中调用）
+func (at *AutoTrader) registerTrailingStop(d *decision.Decision, side string, entryPrice float64, marketData *market.Data) {
+	if d.TrailingStop == nil {
+		return
+	}
+
+	distance := 0.0
+	switch d.TrailingStop.Mode {
+	case "percent":
+		distance = entryPrice * d.TrailingStop.Value / 100
+	case "atr":
+		if marketData.LongerTermContext == nil || marketData.LongerTermContext.ATR14 <= 0 {
+			at.baseLog.Printf("  ⚠ %s 缺少ATR数据，移动止损未启用", d.Symbol)
+			return
+		}
+		distance = marketData.LongerTermContext.ATR14 * d.TrailingStop.Value
+	}
+	if distance <= 0 {
+		return
+	}
+
+	at.trailingMu.Lock()
+	at.trailingStops[d.Symbol+"_"+side] = &TrailingStopState{
+		Symbol:     d.Symbol,
+		Side:       side,
+		Mode:       d.TrailingStop.Mode,
+		Value:      d.TrailingStop.Value,
+		Distance:   distance,
+		BestPrice:  entryPrice,
+		StopPrice:  d.StopLoss,
+		TakeProfit: d.TakeProfit,
+	}
+	at.trailingMu.Unlock()
+
+	at.baseLog.Printf("  🎯 %s %s 已启用移动止损: %s %.2f（距离 %.4f）", d.Symbol, side, d.TrailingStop.Mode, d.TrailingStop.Value, distance)
+}
+
+// removeTrailingStop 平仓后移除移动止损
+func (at *AutoTrader) removeTrailingStop(symbol, side string) {
+	at.trailingMu.Lock()
+	delete(at.trailingStops, symbol+"_"+side)
+	at.trailingMu.Unlock()
+}
+
+// GetTrailingStops 获取当前生效的移动止损（用于API）
+func (at *AutoTrader) GetTrailingStops() []TrailingStopState {
+	at.trailingMu.Lock()
+	defer at.trailingMu.Unlock()
+
+	stops := make([]TrailingStopState, 0, len(at.trailingStops))
+	for _, ts := range at.trailingStops {
+		stops = append(stops, *ts)
+	}
+	return stops
+}
+
+// runTrailingStops 移动止损监控循环（独立于AI决策周期，直到stopCh关闭）
+func (at *AutoTrader) runTrailingStops(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(trailingStopCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			at.updateTrailingStops()
+		}
+	}
+}
+
+// updateTrailingStops 根据最新价格上移/下移止损单
+func (at *AutoTrader) updateTrailingStops() {
+	at.trailingMu.Lock()
+	if len(at.trailingStops) == 0 {
+		at.trailingMu.Unlock()
+		return
+	}
+	stops := make([]*TrailingStopState, 0, len(at.trailingStops))
+	for _, ts := range at.trailingStops {
+		stops = append(stops, ts)
+	}
+	at.trailingMu.Unlock()
+
+	// 以交易所持仓为准：已平仓（AI平仓、止损止盈触发、紧急平仓）的不再跟踪
+	positions, err := at.exchange.GetPositions()
+	if err != nil {
+		at.baseLog.Printf("⚠️ 移动止损: 获取持仓失败: %v", err)
+		return
+	}
+	quantities := make(map[string]float64)
+	for _, pos := range positions {
+		quantities[pos.Symbol+"_"+pos.Side] = pos.Quantity
+	}
+
+	for _, ts := range stops {
+		quantity, open := quantities[ts.Symbol+"_"+ts.Side]
+		if !open || quantity == 0 {
+			at.removeTrailingStop(ts.Symbol, ts.Side)
+			continue
+		}
+
+		price, err := at.exchange.GetMarketPrice(ts.Symbol)
+		if err != nil {
+			continue
+		}
+		if err := at.trailStop(ts, price, quantity); err != nil {
+			at.baseLog.Printf("⚠️ 移动止损: %s %s 更新失败: %v", ts.Symbol, ts.Side, err)
+		}
+	}
+}
+
+// trailStop 更新单个持仓的移动止损（止损只收紧不放宽）
+func (at *AutoTrader) trailStop(ts *TrailingStopState, price, quantity float64) error {
+	at.trailingMu.Lock()
+	var candidate float64
+	if ts.Side == "long" {
+		ts.BestPrice = math.Max(ts.BestPrice, price)
+		candidate = ts.BestPrice - ts.Distance
+	} else {
+		ts.BestPrice = math.Min(ts.BestPrice, price)
+		candidate = ts.BestPrice + ts.Distance
+	}
+	improved := (ts.Side == "long" && candidate > ts.StopPrice*(1+trailingStopMinMovePct/100)) ||
+		(ts.Side == "short" && candidate < ts.StopPrice*(1-trailingStopMinMovePct/100))
+	takeProfit := ts.TakeProfit
+	at.trailingMu.Unlock()
+
+	if !improved || candidate <= 0 {
+		return nil
+	}
+
+	// 交易所不支持修改止损单，只能撤销后重新挂止损和止盈
+	at.protectMu.Lock()
+	defer at.protectMu.Unlock()
+	if err := at.cancelSideOrders(ts.Symbol, ts.Side); err != nil {
+		return fmt.Errorf("撤销旧止损失败: %w", err)
+	}
+	if err := at.placeStopLoss(ts.Symbol, ts.Side, quantity, candidate); err != nil {
+		// 新止损挂单失败时恢复原止损，避免持仓裸奔
+		at.placeStopLoss(ts.Symbol, ts.Side, quantity, ts.StopPrice)
+		if takeProfit > 0 {
+			at.placeTakeProfit(ts.Symbol, ts.Side, quantity, takeProfit)
+		}
+		return fmt.Errorf("设置新止损失败: %w", err)
+	}
+	if takeProfit > 0 {
+		if err := at.placeTakeProfit(ts.Symbol, ts.Side, quantity, takeProfit); err != nil {
+			at.baseLog.Printf("⚠️ 移动止损: %s 重新设置止盈失败: %v", ts.Symbol, err)
+		}
+	}
+
+	at.trailingMu.Lock()
+	oldStop, bestPrice := ts.StopPrice, ts.BestPrice
+	ts.StopPrice = candidate
+	at.trailingMu.Unlock()
+
+	at.baseLog.Printf("🎯 %s %s 移动止损: %.4f → %.4f（最优价 %.4f）", ts.Symbol, ts.Side, oldStop, candidate, bestPrice)
+	return nil
+}
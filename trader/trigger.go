@@ -0,0 +1,30 @@
+package trader
+
+import (
+	"fmt"
+	"time"
+)
+
+// minTriggerInterval 两次手动触发决策周期的最小间隔（防止频繁调用AI）
+const minTriggerInterval = time.Minute
+
+// TriggerCycle 手动触发一次决策周期（不等下次计时，之后从周期结束时重新计时），by为操作人
+// trader须在运行中；距上次触发不足minTriggerInterval时返回需要等待的时长
+func (at *AutoTrader) TriggerCycle(by string) (retryAfter time.Duration, err error) {
+	if !at.IsRunning() {
+		return 0, fmt.Errorf("trader '%s' 未运行", at.GetName())
+	}
+
+	at.triggerMu.Lock()
+	defer at.triggerMu.Unlock()
+	if wait := minTriggerInterval - time.Since(at.lastTrigger); wait > 0 {
+		return wait, fmt.Errorf("触发过于频繁，请 %d 秒后再试", int(wait.Seconds())+1)
+	}
+	select {
+	case at.triggerCh <- by:
+	default:
+		return 0, fmt.Errorf("已有等待执行的手动触发")
+	}
+	at.lastTrigger = time.Now()
+	return 0, nil
+}